@@ -30,8 +30,8 @@ func InsertHandler(at *auth.Token, req *http.Request) error {
 		return err
 	}
 	return writeconcurrencylimiter.Do(func() error {
-		return parser.ParseStream(req.Body, func(tss []prompb.TimeSeries) error {
-			return insertRows(at, tss, extraLabels)
+		return parser.ParseStream(req.Body, func(tss []prompb.TimeSeries, mms []prompb.MetricMetadata) error {
+			return insertRows(at, tss, mms, extraLabels)
 		})
 	})
 }
@@ -39,13 +39,13 @@ func InsertHandler(at *auth.Token, req *http.Request) error {
 // InsertHandlerForReader processes metrics from given reader
 func InsertHandlerForReader(at *auth.Token, r io.Reader) error {
 	return writeconcurrencylimiter.Do(func() error {
-		return parser.ParseStream(r, func(tss []prompb.TimeSeries) error {
-			return insertRows(at, tss, nil)
+		return parser.ParseStream(r, func(tss []prompb.TimeSeries, mms []prompb.MetricMetadata) error {
+			return insertRows(at, tss, mms, nil)
 		})
 	})
 }
 
-func insertRows(at *auth.Token, timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Label) error {
+func insertRows(at *auth.Token, timeseries []prompb.TimeSeries, mms []prompb.MetricMetadata, extraLabels []prompbmarshal.Label) error {
 	ctx := common.GetPushCtx()
 	defer common.PutPushCtx(ctx)
 
@@ -53,6 +53,7 @@ func insertRows(at *auth.Token, timeseries []prompb.TimeSeries, extraLabels []pr
 	tssDst := ctx.WriteRequest.Timeseries[:0]
 	labels := ctx.Labels[:0]
 	samples := ctx.Samples[:0]
+	exemplars := ctx.Exemplars[:0]
 	for i := range timeseries {
 		ts := &timeseries[i]
 		rowsTotal += len(ts.Samples)
@@ -73,14 +74,45 @@ func insertRows(at *auth.Token, timeseries []prompb.TimeSeries, extraLabels []pr
 				Timestamp: sample.Timestamp,
 			})
 		}
+		seriesLabels := labels[labelsLen:]
+		exemplarsLen := len(exemplars)
+		for i := range ts.Exemplars {
+			e := &ts.Exemplars[i]
+			exemplarLabelsLen := len(labels)
+			for j := range e.Labels {
+				label := &e.Labels[j]
+				labels = append(labels, prompbmarshal.Label{
+					Name:  bytesutil.ToUnsafeString(label.Name),
+					Value: bytesutil.ToUnsafeString(label.Value),
+				})
+			}
+			exemplars = append(exemplars, prompbmarshal.Exemplar{
+				Labels:    labels[exemplarLabelsLen:],
+				Value:     e.Value,
+				Timestamp: e.Timestamp,
+			})
+		}
 		tssDst = append(tssDst, prompbmarshal.TimeSeries{
-			Labels:  labels[labelsLen:],
-			Samples: samples[samplesLen:],
+			Labels:    seriesLabels,
+			Samples:   samples[samplesLen:],
+			Exemplars: exemplars[exemplarsLen:],
 		})
 	}
 	ctx.WriteRequest.Timeseries = tssDst
 	ctx.Labels = labels
 	ctx.Samples = samples
+	ctx.Exemplars = exemplars
+	mmsDst := ctx.WriteRequest.Metadata[:0]
+	for i := range mms {
+		mm := &mms[i]
+		mmsDst = append(mmsDst, prompbmarshal.MetricMetadata{
+			Type:             prompbmarshal.MetricMetadata_MetricType(mm.Type),
+			MetricFamilyName: bytesutil.ToUnsafeString(mm.MetricFamilyName),
+			Help:             bytesutil.ToUnsafeString(mm.Help),
+			Unit:             bytesutil.ToUnsafeString(mm.Unit),
+		})
+	}
+	ctx.WriteRequest.Metadata = mmsDst
 	remotewrite.PushWithAuthToken(at, &ctx.WriteRequest)
 	rowsInserted.Add(rowsTotal)
 	if at != nil {
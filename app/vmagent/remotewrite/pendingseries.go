@@ -58,6 +58,12 @@ func (ps *pendingSeries) Push(tss []prompbmarshal.TimeSeries) {
 	ps.mu.Unlock()
 }
 
+func (ps *pendingSeries) PushMetadata(mms []prompbmarshal.MetricMetadata) {
+	ps.mu.Lock()
+	ps.wr.pushMetadata(mms)
+	ps.mu.Unlock()
+}
+
 func (ps *pendingSeries) periodicFlusher() {
 	flushSeconds := int64(flushInterval.Seconds())
 	if flushSeconds <= 0 {
@@ -98,20 +104,24 @@ type writeRequest struct {
 
 	tss []prompbmarshal.TimeSeries
 
-	labels  []prompbmarshal.Label
-	samples []prompbmarshal.Sample
-	buf     []byte
+	labels    []prompbmarshal.Label
+	samples   []prompbmarshal.Sample
+	exemplars []prompbmarshal.Exemplar
+	metadata  []prompbmarshal.MetricMetadata
+	buf       []byte
 }
 
 func (wr *writeRequest) reset() {
 	// Do not reset pushBlock, significantFigures and roundDigits, since they are re-used.
 
 	wr.wr.Timeseries = nil
+	wr.wr.Metadata = nil
 
 	for i := range wr.tss {
 		ts := &wr.tss[i]
 		ts.Labels = nil
 		ts.Samples = nil
+		ts.Exemplars = nil
 	}
 	wr.tss = wr.tss[:0]
 
@@ -119,11 +129,17 @@ func (wr *writeRequest) reset() {
 	wr.labels = wr.labels[:0]
 
 	wr.samples = wr.samples[:0]
+	for i := range wr.exemplars {
+		wr.exemplars[i] = prompbmarshal.Exemplar{}
+	}
+	wr.exemplars = wr.exemplars[:0]
+	wr.metadata = prompbmarshal.ResetMetadata(wr.metadata)
 	wr.buf = wr.buf[:0]
 }
 
 func (wr *writeRequest) flush() {
 	wr.wr.Timeseries = wr.tss
+	wr.wr.Metadata = wr.metadata
 	wr.adjustSampleValues()
 	atomic.StoreUint64(&wr.lastFlushTime, fasttime.UnixTimestamp())
 	pushWriteRequest(&wr.wr, wr.pushBlock)
@@ -163,6 +179,27 @@ func (wr *writeRequest) push(src []prompbmarshal.TimeSeries) {
 	wr.tss = tssDst
 }
 
+func (wr *writeRequest) pushMetadata(src []prompbmarshal.MetricMetadata) {
+	metadataDst := wr.metadata
+	buf := wr.buf
+	for i := range src {
+		srcMM := &src[i]
+		metadataDst = append(metadataDst, prompbmarshal.MetricMetadata{
+			Type: srcMM.Type,
+		})
+		dstMM := &metadataDst[len(metadataDst)-1]
+
+		buf = append(buf, srcMM.MetricFamilyName...)
+		dstMM.MetricFamilyName = bytesutil.ToUnsafeString(buf[len(buf)-len(srcMM.MetricFamilyName):])
+		buf = append(buf, srcMM.Help...)
+		dstMM.Help = bytesutil.ToUnsafeString(buf[len(buf)-len(srcMM.Help):])
+		buf = append(buf, srcMM.Unit...)
+		dstMM.Unit = bytesutil.ToUnsafeString(buf[len(buf)-len(srcMM.Unit):])
+	}
+	wr.metadata = metadataDst
+	wr.buf = buf
+}
+
 func (wr *writeRequest) copyTimeSeries(dst, src *prompbmarshal.TimeSeries) {
 	labelsDst := wr.labels
 	labelsLen := len(wr.labels)
@@ -183,13 +220,38 @@ func (wr *writeRequest) copyTimeSeries(dst, src *prompbmarshal.TimeSeries) {
 	samplesDst = append(samplesDst, src.Samples...)
 	dst.Samples = samplesDst[len(samplesDst)-len(src.Samples):]
 
+	if len(src.Exemplars) > 0 {
+		exemplarsDst := wr.exemplars
+		for i := range src.Exemplars {
+			srcExemplar := &src.Exemplars[i]
+			exemplarLabelsLen := len(labelsDst)
+			for j := range srcExemplar.Labels {
+				labelsDst = append(labelsDst, prompbmarshal.Label{})
+				dstLabel := &labelsDst[len(labelsDst)-1]
+				srcLabel := &srcExemplar.Labels[j]
+
+				buf = append(buf, srcLabel.Name...)
+				dstLabel.Name = bytesutil.ToUnsafeString(buf[len(buf)-len(srcLabel.Name):])
+				buf = append(buf, srcLabel.Value...)
+				dstLabel.Value = bytesutil.ToUnsafeString(buf[len(buf)-len(srcLabel.Value):])
+			}
+			exemplarsDst = append(exemplarsDst, prompbmarshal.Exemplar{
+				Labels:    labelsDst[exemplarLabelsLen:],
+				Value:     srcExemplar.Value,
+				Timestamp: srcExemplar.Timestamp,
+			})
+		}
+		dst.Exemplars = exemplarsDst[len(exemplarsDst)-len(src.Exemplars):]
+		wr.exemplars = exemplarsDst
+	}
+
 	wr.samples = samplesDst
 	wr.labels = labelsDst
 	wr.buf = buf
 }
 
 func pushWriteRequest(wr *prompbmarshal.WriteRequest, pushBlock func(block []byte)) {
-	if len(wr.Timeseries) == 0 {
+	if len(wr.Timeseries) == 0 && len(wr.Metadata) == 0 {
 		// Nothing to push
 		return
 	}
@@ -212,6 +274,26 @@ func pushWriteRequest(wr *prompbmarshal.WriteRequest, pushBlock func(block []byt
 	}
 
 	// Too big block. Recursively split it into smaller parts if possible.
+	if len(wr.Metadata) > 0 {
+		// Send metadata separately from time series.
+		mms := wr.Metadata
+		timeseries := wr.Timeseries
+		wr.Timeseries = nil
+		if len(mms) == 1 {
+			logger.Warnf("dropping metadata for metric %q exceeding -remoteWrite.maxBlockSize=%d bytes", mms[0].MetricFamilyName, maxUnpackedBlockSize.N)
+		} else {
+			n := len(mms) / 2
+			wr.Metadata = mms[:n]
+			pushWriteRequest(wr, pushBlock)
+			wr.Metadata = mms[n:]
+			pushWriteRequest(wr, pushBlock)
+		}
+		wr.Metadata = nil
+		wr.Timeseries = timeseries
+		pushWriteRequest(wr, pushBlock)
+		wr.Metadata = mms
+		return
+	}
 	if len(wr.Timeseries) == 1 {
 		// A single time series left. Recursively split its samples into smaller parts if possible.
 		samples := wr.Timeseries[0].Samples
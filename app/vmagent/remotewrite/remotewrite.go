@@ -20,6 +20,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/streamaggr"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/tenantmetrics"
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/cespare/xxhash/v2"
@@ -140,6 +141,11 @@ func Init() {
 	}
 	allRelabelConfigs.Store(rcs)
 
+	if len(*streamAggrConfig) > (len(*remoteWriteURLs) + len(*remoteWriteMultitenantURLs)) {
+		logger.Fatalf("too many -remoteWrite.streamAggr.config args: %d; it mustn't exceed the number of -remoteWrite.url or -remoteWrite.multitenantURL args: %d",
+			len(*streamAggrConfig), (len(*remoteWriteURLs) + len(*remoteWriteMultitenantURLs)))
+	}
+
 	if len(*remoteWriteURLs) > 0 {
 		rwctxsDefault = newRemoteWriteCtxs(nil, *remoteWriteURLs)
 	}
@@ -315,6 +321,15 @@ func PushWithAuthToken(at *auth.Token, wr *prompbmarshal.WriteRequest) {
 	if rctx != nil {
 		putRelabelCtx(rctx)
 	}
+	if len(wr.Metadata) > 0 {
+		pushMetadataToRemoteStorages(rwctxs, wr.Metadata)
+	}
+}
+
+func pushMetadataToRemoteStorages(rwctxs []*remoteWriteCtx, mms []prompbmarshal.MetricMetadata) {
+	for _, rwctx := range rwctxs {
+		rwctx.PushMetadata(mms)
+	}
 }
 
 func pushBlockToRemoteStorages(rwctxs []*remoteWriteCtx, tssBlock []prompbmarshal.TimeSeries) {
@@ -429,6 +444,9 @@ type remoteWriteCtx struct {
 	pss        []*pendingSeries
 	pssNextIdx uint64
 
+	sas                 *streamaggr.Aggregators
+	streamAggrKeepInput bool
+
 	rowsPushedAfterRelabel *metrics.Counter
 	rowsDroppedByRelabel   *metrics.Counter
 }
@@ -468,7 +486,7 @@ func newRemoteWriteCtx(argIdx int, at *auth.Token, remoteWriteURL *url.URL, maxI
 	for i := range pss {
 		pss[i] = newPendingSeries(fq.MustWriteBlock, sf, rd)
 	}
-	return &remoteWriteCtx{
+	rwctx := &remoteWriteCtx{
 		idx: argIdx,
 		fq:  fq,
 		c:   c,
@@ -477,9 +495,26 @@ func newRemoteWriteCtx(argIdx int, at *auth.Token, remoteWriteURL *url.URL, maxI
 		rowsPushedAfterRelabel: metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_rows_pushed_after_relabel_total{path=%q, url=%q}`, queuePath, sanitizedURL)),
 		rowsDroppedByRelabel:   metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_relabel_metrics_dropped_total{path=%q, url=%q}`, queuePath, sanitizedURL)),
 	}
+
+	// Initialize sas
+	sasFile := streamAggrConfig.GetOptionalArg(argIdx)
+	if sasFile != "" {
+		sas, err := streamaggr.LoadFromFile(sasFile, rwctx.pushInternal)
+		if err != nil {
+			logger.Fatalf("cannot initialize stream aggregators from -remoteWrite.streamAggr.config=%q: %s", sasFile, err)
+		}
+		rwctx.sas = sas
+		rwctx.streamAggrKeepInput = streamAggrKeepInput.GetOptionalArg(argIdx)
+	}
+
+	return rwctx
 }
 
 func (rwctx *remoteWriteCtx) MustStop() {
+	// Stop stream aggregators before pendingSeries, since they push the aggregated data to pendingSeries.
+	rwctx.sas.MustStop()
+	rwctx.sas = nil
+
 	for _, ps := range rwctx.pss {
 		ps.MustStop()
 	}
@@ -513,18 +548,58 @@ func (rwctx *remoteWriteCtx) Push(tss []prompbmarshal.TimeSeries) {
 		rowsCountAfterRelabel := getRowsCount(tss)
 		rwctx.rowsDroppedByRelabel.Add(rowsCountBeforeRelabel - rowsCountAfterRelabel)
 	}
-	pss := rwctx.pss
-	idx := atomic.AddUint64(&rwctx.pssNextIdx, 1) % uint64(len(pss))
 	rowsCount := getRowsCount(tss)
 	rwctx.rowsPushedAfterRelabel.Add(rowsCount)
-	pss[idx].Push(tss)
-	if rctx != nil {
+
+	// Apply stream aggregation if any
+	sas := rwctx.sas
+	if sas != nil {
+		matchIdxs := matchIdxsPool.Get()
+		matchIdxs.B = bytesutil.ResizeNoCopyNoOverallocate(matchIdxs.B, len(tss))
+		for i := range matchIdxs.B {
+			matchIdxs.B[i] = 0
+		}
+		sas.Push(tss, matchIdxs.B)
+		if !rwctx.streamAggrKeepInput {
+			if v == nil {
+				// Make a copy of tss before dropping the aggregated series in order to prevent
+				// from affecting time series for other remoteWrite.url configs.
+				v = tssRelabelPool.Get().(*[]prompbmarshal.TimeSeries)
+				tss = append(*v, tss...)
+			}
+			tss = dropAggregatedSeries(tss[:0], tss, matchIdxs.B)
+		}
+		matchIdxsPool.Put(matchIdxs)
+	}
+	rwctx.pushInternal(tss)
+
+	if v != nil {
 		*v = prompbmarshal.ResetTimeSeries(tss)
 		tssRelabelPool.Put(v)
+	}
+	if rctx != nil {
 		putRelabelCtx(rctx)
 	}
 }
 
+func (rwctx *remoteWriteCtx) pushInternal(tss []prompbmarshal.TimeSeries) {
+	if len(tss) == 0 {
+		return
+	}
+	pss := rwctx.pss
+	idx := atomic.AddUint64(&rwctx.pssNextIdx, 1) % uint64(len(pss))
+	pss[idx].Push(tss)
+}
+
+// PushMetadata pushes metric metadata mms to rwctx.
+//
+// Relabeling isn't applied to metadata, since it doesn't contain labels.
+func (rwctx *remoteWriteCtx) PushMetadata(mms []prompbmarshal.MetricMetadata) {
+	pss := rwctx.pss
+	idx := atomic.AddUint64(&rwctx.pssNextIdx, 1) % uint64(len(pss))
+	pss[idx].PushMetadata(mms)
+}
+
 var tssRelabelPool = &sync.Pool{
 	New: func() interface{} {
 		a := []prompbmarshal.TimeSeries{}
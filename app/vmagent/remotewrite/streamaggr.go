@@ -0,0 +1,56 @@
+package remotewrite
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/streamaggr"
+)
+
+var (
+	streamAggrConfig = flagutil.NewArray("remoteWrite.streamAggr.config", "Optional path to file with stream aggregation config for the corresponding -remoteWrite.url. "+
+		"The path can point either to local file or to http url. "+
+		"See https://docs.victoriametrics.com/vmagent.html#stream-aggregation . See also -remoteWrite.streamAggr.keepInput")
+	streamAggrKeepInput = flagutil.NewArrayBool("remoteWrite.streamAggr.keepInput", "Whether to keep input samples for the corresponding -remoteWrite.url "+
+		"after the aggregation with -remoteWrite.streamAggr.config. By default the input samples matching the aggregation config are dropped "+
+		"after the aggregation, so only the aggregate data is sent to the -remoteWrite.url. "+
+		"See https://docs.victoriametrics.com/vmagent.html#stream-aggregation")
+)
+
+// CheckStreamAggrConfigs checks -remoteWrite.streamAggr.config.
+func CheckStreamAggrConfigs() error {
+	if len(*streamAggrConfig) > (len(*remoteWriteURLs) + len(*remoteWriteMultitenantURLs)) {
+		return fmt.Errorf("too many -remoteWrite.streamAggr.config args: %d; it mustn't exceed the number of -remoteWrite.url or -remoteWrite.multitenantURL args: %d",
+			len(*streamAggrConfig), (len(*remoteWriteURLs) + len(*remoteWriteMultitenantURLs)))
+	}
+	pushNoop := func(tss []prompbmarshal.TimeSeries) {}
+	for _, path := range *streamAggrConfig {
+		if len(path) == 0 {
+			// Skip empty stream aggregation config.
+			continue
+		}
+		sas, err := streamaggr.LoadFromFile(path, pushNoop)
+		if err != nil {
+			return fmt.Errorf("cannot load -remoteWrite.streamAggr.config=%q: %w", path, err)
+		}
+		sas.MustStop()
+	}
+	return nil
+}
+
+var matchIdxsPool bytesutil.ByteBufferPool
+
+// dropAggregatedSeries drops series in src with non-zero matchIdxs.
+//
+// The result is stored to dst, which may point to src.
+func dropAggregatedSeries(dst, src []prompbmarshal.TimeSeries, matchIdxs []byte) []prompbmarshal.TimeSeries {
+	for i, match := range matchIdxs {
+		if match != 0 {
+			continue
+		}
+		dst = append(dst, src[i])
+	}
+	return dst
+}
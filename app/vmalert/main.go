@@ -86,6 +86,13 @@ func main() {
 		logger.Fatalf("failed to parse %q: %s", *ruleTemplatesPath, err)
 	}
 
+	if len(*unittestFiles) > 0 {
+		if unitRule(*unittestFiles...) {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	}
+
 	if *dryRun {
 		groups, err := config.Parse(*rulePath, true, true)
 		if err != nil {
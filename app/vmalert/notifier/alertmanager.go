@@ -26,25 +26,40 @@ type AlertManager struct {
 	// stores already parsed RelabelConfigs object
 	relabelConfigs *promrelabel.ParsedConfigs
 
+	// retrier retries failed requests. It is nil if requests mustn't be retried.
+	retrier *retrier
+
 	metrics *metrics
 }
 
 type metrics struct {
 	alertsSent       *utils.Counter
 	alertsSendErrors *utils.Counter
+
+	// alertsSendRetries is set only if retries are enabled.
+	alertsSendRetries *utils.Counter
 }
 
 func newMetrics(addr string) *metrics {
 	return &metrics{
-		alertsSent:       utils.GetOrCreateCounter(fmt.Sprintf("vmalert_alerts_sent_total{addr=%q}", addr)),
-		alertsSendErrors: utils.GetOrCreateCounter(fmt.Sprintf("vmalert_alerts_send_errors_total{addr=%q}", addr)),
+		alertsSent:       getSharedCounter(fmt.Sprintf("vmalert_alerts_sent_total{addr=%q}", addr)),
+		alertsSendErrors: getSharedCounter(fmt.Sprintf("vmalert_alerts_send_errors_total{addr=%q}", addr)),
 	}
 }
 
 // Close is a destructor method for AlertManager
 func (am *AlertManager) Close() {
-	am.metrics.alertsSent.Unregister()
-	am.metrics.alertsSendErrors.Unregister()
+	putSharedCounter(am.metrics.alertsSent)
+	putSharedCounter(am.metrics.alertsSendErrors)
+	if am.metrics.alertsSendRetries != nil {
+		putSharedCounter(am.metrics.alertsSendRetries)
+	}
+}
+
+// enableRetries enables retrying failed requests according to max_retries and retry_backoff options from cfg.
+func (am *AlertManager) enableRetries(cfg *NotifierConfig) {
+	am.metrics.alertsSendRetries = getSharedCounter(fmt.Sprintf("vmalert_alerts_send_retries_total{addr=%q}", am.addr))
+	am.retrier = newRetrier(cfg, am.metrics.alertsSendRetries)
 }
 
 // Addr returns address where alerts are sent.
@@ -53,7 +68,14 @@ func (am AlertManager) Addr() string { return am.addr }
 // Send an alert or resolve message
 func (am *AlertManager) Send(ctx context.Context, alerts []Alert) error {
 	am.metrics.alertsSent.Add(len(alerts))
-	err := am.send(ctx, alerts)
+	var err error
+	if am.retrier != nil {
+		err = am.retrier.do(ctx, func() error {
+			return am.send(ctx, alerts)
+		})
+	} else {
+		err = am.send(ctx, alerts)
+	}
 	if err != nil {
 		am.metrics.alertsSendErrors.Add(len(alerts))
 	}
@@ -93,7 +115,11 @@ func (am *AlertManager) send(ctx context.Context, alerts []Alert) error {
 		if err != nil {
 			return fmt.Errorf("failed to read response from %q: %w", am.addr, err)
 		}
-		return fmt.Errorf("invalid SC %d from %q; response body: %s", resp.StatusCode, am.addr, string(body))
+		err = fmt.Errorf("invalid SC %d from %q; response body: %s", resp.StatusCode, am.addr, string(body))
+		if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+			return &permanentError{err: err}
+		}
+		return err
 	}
 	return nil
 }
@@ -106,13 +132,32 @@ const alertManagerPath = "/api/v2/alerts"
 // NewAlertManager is a constructor for AlertManager
 func NewAlertManager(alertManagerURL string, fn AlertURLGenerator, authCfg promauth.HTTPClientConfig,
 	relabelCfg *promrelabel.ParsedConfigs, timeout time.Duration) (*AlertManager, error) {
+	client, aCfg, err := newHTTPClient(alertManagerURL, authCfg)
+	if err != nil {
+		return nil, err
+	}
+	return &AlertManager{
+		addr:           alertManagerURL,
+		argFunc:        fn,
+		authCfg:        aCfg,
+		relabelConfigs: relabelCfg,
+		client:         client,
+		timeout:        timeout,
+		metrics:        newMetrics(alertManagerURL),
+	}, nil
+}
+
+// newHTTPClient returns http client and auth config for sending requests to addr with the given authCfg.
+//
+// opts may contain additional auth options, which are applied after the options from authCfg.
+func newHTTPClient(addr string, authCfg promauth.HTTPClientConfig, opts ...utils.AuthConfigOptions) (*http.Client, *promauth.Config, error) {
 	tls := &promauth.TLSConfig{}
 	if authCfg.TLSConfig != nil {
 		tls = authCfg.TLSConfig
 	}
-	tr, err := utils.Transport(alertManagerURL, tls.CertFile, tls.KeyFile, tls.CAFile, tls.ServerName, tls.InsecureSkipVerify)
+	tr, err := utils.Transport(addr, tls.CertFile, tls.KeyFile, tls.CAFile, tls.ServerName, tls.InsecureSkipVerify)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create transport: %w", err)
+		return nil, nil, fmt.Errorf("failed to create transport: %w", err)
 	}
 
 	ba := new(promauth.BasicAuthConfig)
@@ -124,21 +169,14 @@ func NewAlertManager(alertManagerURL string, fn AlertURLGenerator, authCfg proma
 		oauth = authCfg.OAuth2
 	}
 
-	aCfg, err := utils.AuthConfig(
+	authOpts := []utils.AuthConfigOptions{
 		utils.WithBasicAuth(ba.Username, ba.Password.String(), ba.PasswordFile),
 		utils.WithBearer(authCfg.BearerToken.String(), authCfg.BearerTokenFile),
-		utils.WithOAuth(oauth.ClientID, oauth.ClientSecretFile, oauth.ClientSecretFile, oauth.TokenURL, strings.Join(oauth.Scopes, ";")))
+		utils.WithOAuth(oauth.ClientID, oauth.ClientSecretFile, oauth.ClientSecretFile, oauth.TokenURL, strings.Join(oauth.Scopes, ";")),
+	}
+	aCfg, err := utils.AuthConfig(append(authOpts, opts...)...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to configure auth: %w", err)
+		return nil, nil, fmt.Errorf("failed to configure auth: %w", err)
 	}
-
-	return &AlertManager{
-		addr:           alertManagerURL,
-		argFunc:        fn,
-		authCfg:        aCfg,
-		relabelConfigs: relabelCfg,
-		client:         &http.Client{Transport: tr},
-		timeout:        timeout,
-		metrics:        newMetrics(alertManagerURL),
-	}, nil
+	return &http.Client{Transport: tr}, aCfg, nil
 }
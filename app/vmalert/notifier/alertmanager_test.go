@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 )
 
 func TestAlertManager_Addr(t *testing.T) {
@@ -112,3 +113,54 @@ func TestAlertManager_Send(t *testing.T) {
 		t.Errorf("expected 2 calls(count from zero) to server got %d", c)
 	}
 }
+
+func TestAlertManager_SendRetries(t *testing.T) {
+	f := func(statusCodes []int, maxRetries, requestsExpected int, errExpected bool) {
+		t.Helper()
+		c := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			statusCode := http.StatusOK
+			if c < len(statusCodes) {
+				statusCode = statusCodes[c]
+			}
+			c++
+			w.WriteHeader(statusCode)
+		}))
+		defer srv.Close()
+
+		n, err := newNotifier(&NotifierConfig{
+			Type:         "alertmanager",
+			URL:          srv.URL + alertManagerPath,
+			MaxRetries:   &maxRetries,
+			RetryBackoff: promutils.NewDuration(time.Millisecond),
+		}, func(alert Alert) string {
+			return strconv.FormatUint(alert.GroupID, 10)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer n.Close()
+		err = n.Send(context.Background(), []Alert{{Name: "alert0"}})
+		if errExpected && err == nil {
+			t.Fatalf("expected to get non-nil error")
+		}
+		if !errExpected && err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if c != requestsExpected {
+			t.Fatalf("unexpected number of requests; got %d; want %d", c, requestsExpected)
+		}
+	}
+
+	// Temporary errors are retried
+	f([]int{http.StatusServiceUnavailable, http.StatusTooManyRequests}, 3, 3, false)
+
+	// Retries are limited by max_retries
+	f([]int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable}, 1, 2, true)
+
+	// Retries are disabled
+	f([]int{http.StatusServiceUnavailable}, 0, 1, true)
+
+	// 4xx errors aren't retried
+	f([]int{http.StatusBadRequest}, 3, 1, true)
+}
@@ -46,6 +46,10 @@ type Config struct {
 	// The timeout used when sending alerts.
 	Timeout *promutils.Duration `yaml:"timeout,omitempty"`
 
+	// Notifiers contains list of notifiers of arbitrary types.
+	// See RegisterNotifierType for the list of supported types.
+	Notifiers []NotifierConfig `yaml:"notifiers,omitempty"`
+
 	// Checksum stores the hash of yaml definition for the config.
 	// May be used to detect any changes to the config file.
 	Checksum string
@@ -69,6 +73,77 @@ type StaticConfig struct {
 	Targets []string `yaml:"targets"`
 }
 
+// NotifierConfig contains configuration for a single notifier
+// of the given Type from `notifiers` section.
+type NotifierConfig struct {
+	// Type is the notifier type. It must be registered via RegisterNotifierType.
+	Type string `yaml:"type"`
+	// Name is an optional human-readable name for the notifier.
+	Name string `yaml:"name,omitempty"`
+	// URL is the address alerts are sent to by HTTP-based notifiers.
+	URL string `yaml:"url,omitempty"`
+	// Path is the path to the file alerts are written to by file notifier.
+	Path string `yaml:"path,omitempty"`
+	// Template is an optional template for the notification body.
+	// Its meaning depends on the notifier type.
+	Template string `yaml:"template,omitempty"`
+	// MaxRetries is the maximum number of retries on failed attempt to send alerts.
+	MaxRetries *int `yaml:"max_retries,omitempty"`
+	// RetryBackoff is the initial delay between retries. It is doubled on every subsequent retry.
+	RetryBackoff *promutils.Duration `yaml:"retry_backoff,omitempty"`
+	// The timeout used when sending alerts.
+	Timeout *promutils.Duration `yaml:"timeout,omitempty"`
+
+	// HTTPClientConfig contains HTTP configuration for HTTP-based notifiers.
+	// It includes optional `headers` in the form `Name: value`.
+	HTTPClientConfig promauth.HTTPClientConfig `yaml:",inline"`
+	// AlertRelabelConfigs contains list of relabeling rules alert labels
+	AlertRelabelConfigs []promrelabel.RelabelConfig `yaml:"alert_relabel_configs,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+
+	// stores already parsed AlertRelabelConfigs object
+	parsedAlertRelabelConfigs *promrelabel.ParsedConfigs
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (nc *NotifierConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type notifierConfig NotifierConfig
+	if err := unmarshal((*notifierConfig)(nc)); err != nil {
+		return err
+	}
+	if len(nc.XXX) > 0 {
+		var keys []string
+		for k := range nc.XXX {
+			keys = append(keys, k)
+		}
+		return fmt.Errorf("unknown fields in notifier %q: %s", nc.Type, strings.Join(keys, ", "))
+	}
+	if !isRegisteredNotifierType(nc.Type) {
+		return fmt.Errorf("unsupported notifier type %q; supported types: %s", nc.Type, strings.Join(notifierTypes(), ", "))
+	}
+	if nc.MaxRetries == nil {
+		n := 3
+		nc.MaxRetries = &n
+	}
+	if *nc.MaxRetries < 0 {
+		return fmt.Errorf("max_retries for notifier %q cannot be negative; got %d", nc.Type, *nc.MaxRetries)
+	}
+	if nc.RetryBackoff.Duration() == 0 {
+		nc.RetryBackoff = promutils.NewDuration(time.Second)
+	}
+	if nc.Timeout.Duration() == 0 {
+		nc.Timeout = promutils.NewDuration(time.Second * 10)
+	}
+	arCfg, err := promrelabel.ParseRelabelConfigs(nc.AlertRelabelConfigs, false)
+	if err != nil {
+		return fmt.Errorf("failed to parse alert relabeling config for notifier %q: %w", nc.Type, err)
+	}
+	nc.parsedAlertRelabelConfigs = arCfg
+	return nil
+}
+
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
 func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	type config Config
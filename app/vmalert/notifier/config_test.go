@@ -14,6 +14,7 @@ func TestConfigParseGood(t *testing.T) {
 	f("testdata/consul.good.yaml")
 	f("testdata/dns.good.yaml")
 	f("testdata/static.good.yaml")
+	f("testdata/notifiers.good.yaml")
 }
 
 func TestConfigParseBad(t *testing.T) {
@@ -28,5 +29,7 @@ func TestConfigParseBad(t *testing.T) {
 	}
 
 	f("testdata/unknownFields.bad.yaml", "unknown field")
+	f("testdata/notifiersUnknownType.bad.yaml", "unsupported notifier type")
+	f("testdata/notifiersUnknownFields.bad.yaml", "unknown fields in notifier")
 	f("non-existing-file", "error reading")
 }
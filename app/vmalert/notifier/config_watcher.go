@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/consul"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/dns"
 )
@@ -164,10 +165,12 @@ func (cw *configWatcher) start() error {
 			for _, target := range cfg.Targets {
 				address, labels, err := parseLabels(target, nil, cw.cfg)
 				if err != nil {
+					closeTargets(targets)
 					return fmt.Errorf("failed to parse labels for target %q: %s", target, err)
 				}
 				notifier, err := NewAlertManager(address, cw.genFn, cw.cfg.HTTPClientConfig, cw.cfg.parsedAlertRelabelConfigs, cw.cfg.Timeout.Duration())
 				if err != nil {
+					closeTargets(targets)
 					return fmt.Errorf("failed to init alertmanager for addr %q: %s", address, err)
 				}
 				targets = append(targets, Target{
@@ -179,6 +182,36 @@ func (cw *configWatcher) start() error {
 		cw.setTargets(TargetStatic, targets)
 	}
 
+	if len(cw.cfg.Notifiers) > 0 {
+		targets := make(map[TargetType][]Target)
+		for i := range cw.cfg.Notifiers {
+			nc := &cw.cfg.Notifiers[i]
+			notifier, err := newNotifier(nc, cw.genFn)
+			if err != nil {
+				// Close already created notifiers, since they aren't registered in cw.targets yet.
+				for _, ts := range targets {
+					closeTargets(ts)
+				}
+				return fmt.Errorf("failed to init %s notifier %q: %s", nc.Type, nc.Name, err)
+			}
+			var labels []prompbmarshal.Label
+			if nc.Name != "" {
+				labels = append(labels, prompbmarshal.Label{
+					Name:  "name",
+					Value: nc.Name,
+				})
+			}
+			typeK := TargetType(nc.Type)
+			targets[typeK] = append(targets[typeK], Target{
+				Notifier: notifier,
+				Labels:   labels,
+			})
+		}
+		for typeK, ts := range targets {
+			cw.setTargets(typeK, ts)
+		}
+	}
+
 	if len(cw.cfg.ConsulSDConfigs) > 0 {
 		err := cw.add(TargetConsul, *consul.SDCheckInterval, func() ([]map[string]string, error) {
 			var labels []map[string]string
@@ -223,9 +256,7 @@ func (cw *configWatcher) mustStop() {
 
 	cw.targetsMu.Lock()
 	for _, targets := range cw.targets {
-		for _, t := range targets {
-			t.Close()
-		}
+		closeTargets(targets)
 	}
 	cw.targets = make(map[TargetType][]Target)
 	cw.targetsMu.Unlock()
@@ -236,6 +267,12 @@ func (cw *configWatcher) mustStop() {
 	cw.cfg = nil
 }
 
+func closeTargets(targets []Target) {
+	for _, t := range targets {
+		t.Close()
+	}
+}
+
 func (cw *configWatcher) setTargets(key TargetType, targets []Target) {
 	cw.targetsMu.Lock()
 	newT := make(map[string]Target)
@@ -1,6 +1,7 @@
 package notifier
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
@@ -98,6 +99,92 @@ consul_sd_configs:
 	}
 }
 
+// closeTrackingNotifier is used for verifying that notifiers are closed.
+type closeTrackingNotifier struct {
+	addr   string
+	closed bool
+}
+
+func (n *closeTrackingNotifier) Send(_ context.Context, _ []Alert) error { return nil }
+func (n *closeTrackingNotifier) Addr() string                            { return n.addr }
+func (n *closeTrackingNotifier) Close()                                  { n.closed = true }
+
+var closeTrackingNotifiers []*closeTrackingNotifier
+
+func init() {
+	RegisterNotifierType("close-tracking", func(cfg *NotifierConfig, _ AlertURLGenerator) (Notifier, error) {
+		if cfg.Name == "broken" {
+			return nil, fmt.Errorf("cannot create broken notifier")
+		}
+		n := &closeTrackingNotifier{
+			addr: cfg.Name,
+		}
+		closeTrackingNotifiers = append(closeTrackingNotifiers, n)
+		return n, nil
+	})
+}
+
+func TestConfigWatcherStartNotifiersFailure(t *testing.T) {
+	f, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	writeToFile(t, f.Name(), `
+notifiers:
+  - type: close-tracking
+    name: first
+  - type: close-tracking
+    name: second
+  - type: close-tracking
+    name: broken
+`)
+	closeTrackingNotifiers = nil
+	if _, err := newWatcher(f.Name(), nil); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if len(closeTrackingNotifiers) != 2 {
+		t.Fatalf("expected to create 2 notifiers; got %d", len(closeTrackingNotifiers))
+	}
+	for _, n := range closeTrackingNotifiers {
+		if !n.closed {
+			t.Fatalf("notifier %q must be closed after the failed start", n.addr)
+		}
+	}
+}
+
+func TestConfigWatcherStartNotifiers(t *testing.T) {
+	cw, err := newWatcher("testdata/notifiers.good.yaml", nil)
+	if err != nil {
+		t.Fatalf("failed to start config watcher: %s", err)
+	}
+	defer cw.mustStop()
+
+	if len(cw.notifiers()) != 4 {
+		t.Fatalf("expected to get 4 notifiers; got %d", len(cw.notifiers()))
+	}
+	f := func(typeK TargetType, expAddr string) {
+		t.Helper()
+		targets := cw.targets[typeK]
+		if len(targets) != 1 {
+			t.Fatalf("expected to get 1 target of type %q; got %d", typeK, len(targets))
+		}
+		if targets[0].Addr() != expAddr {
+			t.Fatalf("exp address %q; got %q", expAddr, targets[0].Addr())
+		}
+	}
+	f("webhook", "http://localhost:8080/hook")
+	f("file", "stdout")
+	f("vmalert", "http://localhost:8880/alerts")
+	f("alertmanager", "http://localhost:9093/api/v2/alerts")
+
+	labels := cw.targets["webhook"][0].Labels
+	if len(labels) != 1 || labels[0].Name != "name" || labels[0].Value != "team-webhook" {
+		t.Fatalf("unexpected labels for webhook target: %v", labels)
+	}
+}
+
 // TestConfigWatcherReloadConcurrent supposed to test concurrent
 // execution of configuration update.
 // Should be executed with -race flag
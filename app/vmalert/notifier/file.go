@@ -0,0 +1,141 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	textTpl "text/template"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+)
+
+// fileNotifier writes alerts to a local file or to stdout as JSON lines.
+//
+// Every alert is written on a separate line. The line is rendered
+// from the configured template if it is set.
+type fileNotifier struct {
+	path    string
+	argFunc AlertURLGenerator
+	tpl     *textTpl.Template
+	retrier *retrier
+
+	// stores already parsed RelabelConfigs object
+	relabelConfigs *promrelabel.ParsedConfigs
+
+	// mu protects w from concurrent writes
+	mu sync.Mutex
+	w  io.Writer
+	f  *os.File
+
+	metrics *notifierMetrics
+}
+
+func newFileNotifier(cfg *NotifierConfig, gen AlertURLGenerator) (Notifier, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("missing `path`")
+	}
+	var tpl *textTpl.Template
+	if cfg.Template != "" {
+		var err error
+		tpl, err = newBodyTemplate(cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+	}
+	fn := &fileNotifier{
+		path:           cfg.Path,
+		argFunc:        gen,
+		tpl:            tpl,
+		relabelConfigs: cfg.parsedAlertRelabelConfigs,
+	}
+	if isStdout(cfg.Path) {
+		fn.w = os.Stdout
+	} else {
+		f, err := os.OpenFile(cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open %q: %w", cfg.Path, err)
+		}
+		fn.f = f
+		fn.w = f
+	}
+	fn.metrics = newNotifierMetrics(fn.Addr())
+	fn.retrier = newRetrier(cfg, fn.metrics.alertsSendRetries)
+	return fn, nil
+}
+
+func isStdout(path string) bool {
+	return path == "stdout" || path == "-"
+}
+
+// Addr returns address where alerts are sent.
+func (fn *fileNotifier) Addr() string {
+	if isStdout(fn.path) {
+		return "stdout"
+	}
+	return "file://" + fn.path
+}
+
+// Close is a destructor method for fileNotifier
+func (fn *fileNotifier) Close() {
+	fn.metrics.unregister()
+	if fn.f != nil {
+		_ = fn.f.Close()
+	}
+}
+
+// Send writes alerts to the file
+func (fn *fileNotifier) Send(ctx context.Context, alerts []Alert) error {
+	fn.metrics.alertsSent.Add(len(alerts))
+	data, err := fn.lines(alerts)
+	if err == nil {
+		err = fn.retrier.do(ctx, func() error {
+			return fn.write(data)
+		})
+	}
+	if err != nil {
+		fn.metrics.alertsSendErrors.Add(len(alerts))
+	}
+	return err
+}
+
+func (fn *fileNotifier) lines(alerts []Alert) ([]byte, error) {
+	var bb bytes.Buffer
+	for i := range alerts {
+		payload := newAlertPayload(&alerts[i], fn.argFunc, fn.relabelConfigs)
+		if fn.tpl == nil {
+			line, err := json.Marshal(payload)
+			if err != nil {
+				return nil, fmt.Errorf("cannot marshal alert %q: %w", payload.Name, err)
+			}
+			bb.Write(line)
+		} else {
+			n := bb.Len()
+			if err := fn.tpl.Execute(&bb, payload); err != nil {
+				return nil, fmt.Errorf("cannot execute file template: %w", err)
+			}
+			// Make sure every alert occupies exactly one line.
+			line := bytes.ReplaceAll(bb.Bytes()[n:], []byte("\n"), []byte(" "))
+			bb.Truncate(n)
+			bb.Write(line)
+		}
+		bb.WriteByte('\n')
+	}
+	return bb.Bytes(), nil
+}
+
+func (fn *fileNotifier) write(data []byte) error {
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+	if _, err := fn.w.Write(data); err != nil {
+		return fmt.Errorf("cannot write alerts to %q: %w", fn.path, err)
+	}
+	return nil
+}
+
+func init() {
+	RegisterNotifierType("file", newFileNotifier)
+}
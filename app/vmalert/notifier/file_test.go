@@ -0,0 +1,86 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileNotifier_Send(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	f := func(template string, expLines []string) {
+		t.Helper()
+		path := filepath.Join(dir, "alerts.log")
+		defer func() { _ = os.Remove(path) }()
+		n, err := newFileNotifier(&NotifierConfig{
+			Path:     path,
+			Template: template,
+		}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		expAddr := "file://" + path
+		if n.Addr() != expAddr {
+			t.Fatalf("expected to have %q; got %q", expAddr, n.Addr())
+		}
+		alerts := []Alert{
+			{Name: "alert0", ID: 1, Labels: map[string]string{"job": "foo"}, State: StateFiring},
+			{Name: "alert1", ID: 2, State: StateInactive},
+		}
+		if err := n.Send(context.Background(), alerts); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		n.Close()
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("cannot read %q: %s", path, err)
+		}
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(lines) != len(expLines) {
+			t.Fatalf("unexpected number of lines; got %d; want %d:\n%s", len(lines), len(expLines), data)
+		}
+		for i, line := range lines {
+			if expLines[i] != "" && line != expLines[i] {
+				t.Fatalf("unexpected line #%d\ngot\n%s\nwant\n%s", i, line, expLines[i])
+			}
+		}
+		if template != "" {
+			return
+		}
+		var ap alertPayload
+		if err := json.Unmarshal([]byte(lines[0]), &ap); err != nil {
+			t.Fatalf("cannot unmarshal line %q: %s", lines[0], err)
+		}
+		if ap.ID != "1" || ap.Name != "alert0" || ap.Labels["job"] != "foo" || ap.State != "firing" {
+			t.Fatalf("unexpected alert %#v", ap)
+		}
+	}
+	f("", []string{"", ""})
+	f("{{ .Name }}\n{{ .State }}", []string{"alert0 firing", "alert1 inactive"})
+}
+
+func TestFileNotifier_Stdout(t *testing.T) {
+	f := func(path string) {
+		t.Helper()
+		n, err := newFileNotifier(&NotifierConfig{Path: path}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer n.Close()
+		if n.Addr() != "stdout" {
+			t.Fatalf("expected to have %q; got %q", "stdout", n.Addr())
+		}
+	}
+	f("stdout")
+	f("-")
+}
@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// NotifierFactory creates a Notifier from the given cfg.
+//
+// gen may be used for generating links to alerts.
+type NotifierFactory func(cfg *NotifierConfig, gen AlertURLGenerator) (Notifier, error)
+
+var (
+	notifierFactoriesLock sync.Mutex
+	notifierFactories     = make(map[string]NotifierFactory)
+)
+
+// RegisterNotifierType registers factory for notifiers of the given typ.
+//
+// Notifiers of the registered type may be configured in `notifiers` section
+// of the file passed to -notifier.config.
+// RegisterNotifierType must be called from init() functions.
+func RegisterNotifierType(typ string, factory NotifierFactory) {
+	notifierFactoriesLock.Lock()
+	defer notifierFactoriesLock.Unlock()
+
+	if _, ok := notifierFactories[typ]; ok {
+		panic(fmt.Errorf("BUG: notifier type %q is already registered", typ))
+	}
+	notifierFactories[typ] = factory
+}
+
+func isRegisteredNotifierType(typ string) bool {
+	notifierFactoriesLock.Lock()
+	_, ok := notifierFactories[typ]
+	notifierFactoriesLock.Unlock()
+	return ok
+}
+
+// notifierTypes returns sorted list of registered notifier types.
+func notifierTypes() []string {
+	notifierFactoriesLock.Lock()
+	defer notifierFactoriesLock.Unlock()
+
+	types := make([]string, 0, len(notifierFactories))
+	for typ := range notifierFactories {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func newNotifier(cfg *NotifierConfig, gen AlertURLGenerator) (Notifier, error) {
+	notifierFactoriesLock.Lock()
+	factory := notifierFactories[cfg.Type]
+	notifierFactoriesLock.Unlock()
+
+	if factory == nil {
+		return nil, fmt.Errorf("unsupported notifier type %q", cfg.Type)
+	}
+	return factory(cfg, gen)
+}
+
+func init() {
+	RegisterNotifierType("alertmanager", func(cfg *NotifierConfig, gen AlertURLGenerator) (Notifier, error) {
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("missing `url`")
+		}
+		am, err := NewAlertManager(cfg.URL, gen, cfg.HTTPClientConfig, cfg.parsedAlertRelabelConfigs, cfg.Timeout.Duration())
+		if err != nil {
+			return nil, err
+		}
+		am.enableRetries(cfg)
+		return am, nil
+	})
+}
@@ -0,0 +1,253 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/utils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+)
+
+// notifierMetrics contains metrics for notifiers configured via `notifiers` section.
+type notifierMetrics struct {
+	alertsSent        *utils.Counter
+	alertsSendErrors  *utils.Counter
+	alertsSendRetries *utils.Counter
+}
+
+func newNotifierMetrics(addr string) *notifierMetrics {
+	return &notifierMetrics{
+		alertsSent:        getSharedCounter(fmt.Sprintf("vmalert_alerts_sent_total{addr=%q}", addr)),
+		alertsSendErrors:  getSharedCounter(fmt.Sprintf("vmalert_alerts_send_errors_total{addr=%q}", addr)),
+		alertsSendRetries: getSharedCounter(fmt.Sprintf("vmalert_alerts_send_retries_total{addr=%q}", addr)),
+	}
+}
+
+func (nm *notifierMetrics) unregister() {
+	putSharedCounter(nm.alertsSent)
+	putSharedCounter(nm.alertsSendErrors)
+	putSharedCounter(nm.alertsSendRetries)
+}
+
+// sharedCounters contains counters, which may be shared among notifiers with the same address.
+//
+// A counter is unregistered when the last notifier using it is closed.
+var (
+	sharedCountersLock sync.Mutex
+	sharedCounters     = make(map[string]*sharedCounter)
+)
+
+type sharedCounter struct {
+	c    *utils.Counter
+	refs int
+}
+
+// getSharedCounter returns a counter with the given name.
+//
+// The returned counter must be released with putSharedCounter when it is no longer needed.
+func getSharedCounter(name string) *utils.Counter {
+	sharedCountersLock.Lock()
+	defer sharedCountersLock.Unlock()
+
+	sc := sharedCounters[name]
+	if sc == nil {
+		sc = &sharedCounter{
+			c: utils.GetOrCreateCounter(name),
+		}
+		sharedCounters[name] = sc
+	}
+	sc.refs++
+	return sc.c
+}
+
+// putSharedCounter releases c obtained via getSharedCounter.
+func putSharedCounter(c *utils.Counter) {
+	sharedCountersLock.Lock()
+	defer sharedCountersLock.Unlock()
+
+	sc := sharedCounters[c.Name]
+	if sc == nil {
+		return
+	}
+	sc.refs--
+	if sc.refs > 0 {
+		return
+	}
+	sc.c.Unregister()
+	delete(sharedCounters, c.Name)
+}
+
+// retrier retries failed attempts to send alerts with exponential backoff.
+type retrier struct {
+	maxRetries int
+	backoff    time.Duration
+	retries    *utils.Counter
+}
+
+func newRetrier(cfg *NotifierConfig, retries *utils.Counter) *retrier {
+	maxRetries := 0
+	if cfg.MaxRetries != nil {
+		maxRetries = *cfg.MaxRetries
+	}
+	return &retrier{
+		maxRetries: maxRetries,
+		backoff:    cfg.RetryBackoff.Duration(),
+		retries:    retries,
+	}
+}
+
+// do calls f until it succeeds, returns a permanent error, ctx is cancelled
+// or r.maxRetries retries are made.
+func (r *retrier) do(ctx context.Context, f func() error) error {
+	err := f()
+	backoff := r.backoff
+	for i := 0; err != nil && i < r.maxRetries; i++ {
+		var pe *permanentError
+		if errors.As(err, &pe) {
+			return err
+		}
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+		r.retries.Inc()
+		backoff *= 2
+		err = f()
+	}
+	return err
+}
+
+// permanentError is an error, which mustn't be retried.
+type permanentError struct {
+	err error
+}
+
+func (pe *permanentError) Error() string {
+	return pe.err.Error()
+}
+
+func (pe *permanentError) Unwrap() error {
+	return pe.err
+}
+
+// httpSender sends requests to the configured url.
+type httpSender struct {
+	addr    string
+	client  *http.Client
+	authCfg *promauth.Config
+	timeout time.Duration
+}
+
+func newHTTPSender(cfg *NotifierConfig) (*httpSender, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("missing `url`")
+	}
+	client, aCfg, err := newHTTPClient(cfg.URL, cfg.HTTPClientConfig, utils.WithHeaders(cfg.HTTPClientConfig.Headers))
+	if err != nil {
+		return nil, err
+	}
+	return &httpSender{
+		addr:    cfg.URL,
+		client:  client,
+		authCfg: aCfg,
+		timeout: cfg.Timeout.Duration(),
+	}, nil
+}
+
+// post sends body with the given contentType to hs.addr.
+//
+// It returns permanentError on 4xx responses, since they cannot be fixed by retrying the request.
+func (hs *httpSender) post(ctx context.Context, contentType string, body []byte) error {
+	req, err := http.NewRequest("POST", hs.addr, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	if hs.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hs.timeout)
+		defer cancel()
+	}
+	req = req.WithContext(ctx)
+
+	if hs.authCfg != nil {
+		hs.authCfg.SetHeaders(req, true)
+	}
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response from %q: %w", hs.addr, err)
+	}
+	err = fmt.Errorf("invalid SC %d from %q; response body: %s", resp.StatusCode, hs.addr, string(respBody))
+	if resp.StatusCode/100 == 4 && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err: err}
+	}
+	return err
+}
+
+// alertPayload is JSON representation of Alert sent by webhook, file and vmalert notifiers.
+//
+// It is compatible with alerts format returned by vmalert from /api/v1/alerts.
+type alertPayload struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	GroupID     string            `json:"group_id"`
+	State       string            `json:"state"`
+	Value       string            `json:"value"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations"`
+	ActiveAt    time.Time         `json:"activeAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+	Expression  string            `json:"expression"`
+	SourceLink  string            `json:"source"`
+	Restored    bool              `json:"restored"`
+}
+
+func newAlertPayload(a *Alert, gen AlertURLGenerator, relabelCfg *promrelabel.ParsedConfigs) alertPayload {
+	labels := make(map[string]string, len(a.Labels))
+	for _, l := range a.toPromLabels(relabelCfg) {
+		labels[l.Name] = l.Value
+	}
+	var sourceLink string
+	if gen != nil {
+		sourceLink = gen(*a)
+	}
+	endsAt := a.End
+	if !a.ResolvedAt.IsZero() {
+		endsAt = a.ResolvedAt
+	}
+	return alertPayload{
+		ID:          strconv.FormatUint(a.ID, 10),
+		Name:        a.Name,
+		GroupID:     strconv.FormatUint(a.GroupID, 10),
+		State:       a.State.String(),
+		Value:       strconv.FormatFloat(a.Value, 'f', -1, 64),
+		Labels:      labels,
+		Annotations: a.Annotations,
+		ActiveAt:    a.ActiveAt,
+		EndsAt:      endsAt,
+		Expression:  a.Expr,
+		SourceLink:  sourceLink,
+		Restored:    a.Restored,
+	}
+}
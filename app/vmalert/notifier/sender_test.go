@@ -0,0 +1,33 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/utils"
+)
+
+func TestSharedCounter(t *testing.T) {
+	const name = `vmalert_alerts_sent_total{addr="http://shared-counter-test"}`
+
+	c1 := getSharedCounter(name)
+	c2 := getSharedCounter(name)
+	if c1.Counter != c2.Counter {
+		t.Fatalf("expecting the same counter for the same name")
+	}
+	c1.Inc()
+
+	// The counter must remain registered while it is used by c2.
+	putSharedCounter(c1)
+	c2.Inc()
+	if n := utils.GetOrCreateCounter(name).Get(); n != 2 {
+		t.Fatalf("unexpected value for the registered counter; got %d; want 2", n)
+	}
+
+	// The counter must be unregistered after the last user releases it.
+	putSharedCounter(c2)
+	c := utils.GetOrCreateCounter(name)
+	defer c.Unregister()
+	if n := c.Get(); n != 0 {
+		t.Fatalf("expecting a new counter after unregistering the shared one; got value %d", n)
+	}
+}
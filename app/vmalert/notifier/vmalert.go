@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+)
+
+// vmalertNotifier sends alerts to vmalert-compatible endpoint as JSON array
+// in the format returned by vmalert from /api/v1/alerts.
+type vmalertNotifier struct {
+	sender  *httpSender
+	argFunc AlertURLGenerator
+	retrier *retrier
+
+	// stores already parsed RelabelConfigs object
+	relabelConfigs *promrelabel.ParsedConfigs
+
+	metrics *notifierMetrics
+}
+
+func newVMAlertNotifier(cfg *NotifierConfig, gen AlertURLGenerator) (Notifier, error) {
+	if cfg.Template != "" {
+		return nil, fmt.Errorf("`template` isn't supported by vmalert notifier, since it uses fixed alerts format")
+	}
+	sender, err := newHTTPSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	metrics := newNotifierMetrics(sender.addr)
+	return &vmalertNotifier{
+		sender:         sender,
+		argFunc:        gen,
+		retrier:        newRetrier(cfg, metrics.alertsSendRetries),
+		relabelConfigs: cfg.parsedAlertRelabelConfigs,
+		metrics:        metrics,
+	}, nil
+}
+
+// Addr returns address where alerts are sent.
+func (vn *vmalertNotifier) Addr() string { return vn.sender.addr }
+
+// Close is a destructor method for vmalertNotifier
+func (vn *vmalertNotifier) Close() {
+	vn.metrics.unregister()
+}
+
+// Send sends alerts to vmalert-compatible endpoint
+func (vn *vmalertNotifier) Send(ctx context.Context, alerts []Alert) error {
+	vn.metrics.alertsSent.Add(len(alerts))
+	payload := make([]alertPayload, 0, len(alerts))
+	for i := range alerts {
+		payload = append(payload, newAlertPayload(&alerts[i], vn.argFunc, vn.relabelConfigs))
+	}
+	body, err := json.Marshal(payload)
+	if err == nil {
+		err = vn.retrier.do(ctx, func() error {
+			return vn.sender.post(ctx, "application/json", body)
+		})
+	}
+	if err != nil {
+		vn.metrics.alertsSendErrors.Add(len(alerts))
+	}
+	return err
+}
+
+func init() {
+	RegisterNotifierType("vmalert", newVMAlertNotifier)
+}
@@ -0,0 +1,68 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+)
+
+func TestVMAlertNotifier_Send(t *testing.T) {
+	var payload []alertPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected Content-Type %q", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("cannot unmarshal alerts: %s", err)
+		}
+	}))
+	defer srv.Close()
+
+	relabelCfg, err := promrelabel.ParseRelabelConfigsData([]byte(`
+- target_label: env
+  replacement: prod
+`), false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	n, err := newVMAlertNotifier(&NotifierConfig{
+		URL:                       srv.URL,
+		parsedAlertRelabelConfigs: relabelCfg,
+	}, func(a Alert) string {
+		return "http://vmalert/alert"
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer n.Close()
+
+	alerts := []Alert{{
+		GroupID: 2,
+		Name:    "alert0",
+		Labels:  map[string]string{"alertname": "alert0"},
+		Expr:    "up == 0",
+		State:   StateFiring,
+		ID:      1,
+	}}
+	if err := n.Send(context.Background(), alerts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(payload) != 1 {
+		t.Fatalf("expected 1 alert; got %d", len(payload))
+	}
+	ap := payload[0]
+	if ap.ID != "1" || ap.GroupID != "2" || ap.Expression != "up == 0" || ap.SourceLink != "http://vmalert/alert" {
+		t.Fatalf("unexpected alert %#v", ap)
+	}
+	if ap.Labels["env"] != "prod" || ap.Labels["alertname"] != "alert0" {
+		t.Fatalf("unexpected alert labels %v", ap.Labels)
+	}
+
+	if _, err := newVMAlertNotifier(&NotifierConfig{URL: srv.URL, Template: "{{ .Alerts }}"}, nil); err == nil {
+		t.Fatalf("expected to get non-nil error for unsupported template")
+	}
+}
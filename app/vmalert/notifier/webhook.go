@@ -0,0 +1,124 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	textTpl "text/template"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/templates"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+)
+
+// webhookNotifier sends alerts to generic webhook as JSON body
+// rendered from the configured template.
+type webhookNotifier struct {
+	sender  *httpSender
+	argFunc AlertURLGenerator
+	tpl     *textTpl.Template
+	retrier *retrier
+
+	// stores already parsed RelabelConfigs object
+	relabelConfigs *promrelabel.ParsedConfigs
+
+	metrics *notifierMetrics
+}
+
+// webhookTplData is passed to webhook template.
+type webhookTplData struct {
+	Alerts         []alertPayload    `json:"alerts"`
+	ExternalURL    string            `json:"externalURL"`
+	ExternalLabels map[string]string `json:"externalLabels,omitempty"`
+}
+
+func newWebhookNotifier(cfg *NotifierConfig, gen AlertURLGenerator) (Notifier, error) {
+	sender, err := newHTTPSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var tpl *textTpl.Template
+	if cfg.Template != "" {
+		tpl, err = newBodyTemplate(cfg.Template)
+		if err != nil {
+			return nil, err
+		}
+	}
+	metrics := newNotifierMetrics(sender.addr)
+	return &webhookNotifier{
+		sender:         sender,
+		argFunc:        gen,
+		tpl:            tpl,
+		retrier:        newRetrier(cfg, metrics.alertsSendRetries),
+		relabelConfigs: cfg.parsedAlertRelabelConfigs,
+		metrics:        metrics,
+	}, nil
+}
+
+// Addr returns address where alerts are sent.
+func (wn *webhookNotifier) Addr() string { return wn.sender.addr }
+
+// Close is a destructor method for webhookNotifier
+func (wn *webhookNotifier) Close() {
+	wn.metrics.unregister()
+}
+
+// Send sends alerts to webhook
+func (wn *webhookNotifier) Send(ctx context.Context, alerts []Alert) error {
+	wn.metrics.alertsSent.Add(len(alerts))
+	body, err := wn.body(alerts)
+	if err == nil {
+		err = wn.retrier.do(ctx, func() error {
+			return wn.sender.post(ctx, "application/json", body)
+		})
+	}
+	if err != nil {
+		wn.metrics.alertsSendErrors.Add(len(alerts))
+	}
+	return err
+}
+
+func (wn *webhookNotifier) body(alerts []Alert) ([]byte, error) {
+	data := webhookTplData{
+		Alerts:         make([]alertPayload, 0, len(alerts)),
+		ExternalURL:    externalURL,
+		ExternalLabels: externalLabels,
+	}
+	for i := range alerts {
+		data.Alerts = append(data.Alerts, newAlertPayload(&alerts[i], wn.argFunc, wn.relabelConfigs))
+	}
+	if wn.tpl == nil {
+		return json.Marshal(data)
+	}
+	var bb bytes.Buffer
+	if err := wn.tpl.Execute(&bb, data); err != nil {
+		return nil, fmt.Errorf("cannot execute webhook template: %w", err)
+	}
+	if !json.Valid(bb.Bytes()) {
+		return nil, fmt.Errorf("webhook template must produce valid JSON; got %q", bb.String())
+	}
+	return bb.Bytes(), nil
+}
+
+// newBodyTemplate parses text into a template with functions available for alert annotations
+// plus `toJson` function for encoding arbitrary values to JSON.
+func newBodyTemplate(text string) (*textTpl.Template, error) {
+	tpl, err := templates.GetWithFuncs(textTpl.FuncMap{
+		"toJson": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error getting a template: %w", err)
+	}
+	tpl, err = tpl.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse template %q: %w", text, err)
+	}
+	return tpl, nil
+}
+
+func init() {
+	RegisterNotifierType("webhook", newWebhookNotifier)
+}
@@ -0,0 +1,136 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func TestWebhookNotifier_Send(t *testing.T) {
+	c := -1
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c++
+		if r.Header.Get("X-Team") != "ops" {
+			t.Errorf("expected X-Team header to be %q; got %q", "ops", r.Header.Get("X-Team"))
+		}
+		if c == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("cannot read request body: %s", err)
+		}
+		body = b
+	}))
+	defer srv.Close()
+
+	f := func(template, expBody string) {
+		t.Helper()
+		c = -1
+		maxRetries := 1
+		n, err := newWebhookNotifier(&NotifierConfig{
+			URL:      srv.URL,
+			Template: template,
+			HTTPClientConfig: promauth.HTTPClientConfig{
+				Headers: []string{"X-Team: ops"},
+			},
+			MaxRetries:   &maxRetries,
+			RetryBackoff: promutils.NewDuration(time.Millisecond),
+		}, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer n.Close()
+		alerts := []Alert{{
+			Name:   "alert0",
+			Labels: map[string]string{"alertname": "alert0", "job": "foo"},
+			State:  StateFiring,
+			Value:  1.5,
+		}}
+		if err := n.Send(context.Background(), alerts); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if c != 1 {
+			t.Fatalf("expected to get 2 requests; got %d", c+1)
+		}
+		if expBody != "" {
+			if string(body) != expBody {
+				t.Fatalf("unexpected body\ngot\n%s\nwant\n%s", body, expBody)
+			}
+			return
+		}
+		var data webhookTplData
+		if err := json.Unmarshal(body, &data); err != nil {
+			t.Fatalf("cannot unmarshal body %q: %s", body, err)
+		}
+		if len(data.Alerts) != 1 {
+			t.Fatalf("expected 1 alert; got %d", len(data.Alerts))
+		}
+		if data.Alerts[0].Labels["job"] != "foo" || data.Alerts[0].State != "firing" || data.Alerts[0].Value != "1.5" {
+			t.Fatalf("unexpected alert %#v", data.Alerts[0])
+		}
+	}
+	f("", "")
+	f(`{"text": [{{ range $i, $a := .Alerts }}{{ if $i }},{{ end }}{{ toJson (printf "%s is %s" $a.Name $a.State) }}{{ end }}]}`,
+		`{"text": ["alert0 is firing"]}`)
+}
+
+func TestWebhookNotifier_SendPermanentError(t *testing.T) {
+	c := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	maxRetries := 3
+	n, err := newWebhookNotifier(&NotifierConfig{
+		URL:          srv.URL,
+		MaxRetries:   &maxRetries,
+		RetryBackoff: promutils.NewDuration(time.Millisecond),
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer n.Close()
+	if err := n.Send(context.Background(), []Alert{{Name: "alert0"}}); err == nil {
+		t.Fatalf("expected to get non-nil error")
+	}
+	if c != 1 {
+		t.Fatalf("expected 4xx response not to be retried; got %d requests", c)
+	}
+}
+
+func TestWebhookNotifier_InvalidTemplate(t *testing.T) {
+	f := func(template string) {
+		t.Helper()
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("should not be called")
+		}))
+		defer srv.Close()
+		n, err := newWebhookNotifier(&NotifierConfig{
+			URL:      srv.URL,
+			Template: template,
+		}, nil)
+		if err != nil {
+			return
+		}
+		defer n.Close()
+		if err := n.Send(context.Background(), []Alert{{Name: "alert0"}}); err == nil {
+			t.Fatalf("expected to get non-nil error for template %q", template)
+		}
+	}
+	// invalid template syntax
+	f(`{{ range .Alerts }`)
+	// template produces invalid JSON
+	f(`{"text": {{ range .Alerts }}{{ .Name }}{{ end }}}`)
+}
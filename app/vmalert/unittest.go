@@ -0,0 +1,749 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/metricsql"
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+var unittestFiles = flagutil.NewArray("unittestFile", `Path to the unit test file. When set, vmalert starts in unit test mode and performs only tests on configured files.
+Flag can be specified multiple times.
+Examples:
+ -unittestFile="./unittest/testfile.yaml"
+See https://docs.victoriametrics.com/vmalert.html#unit-testing-for-rules`)
+
+// testStartTime is the time corresponding to zero offset in unit tests.
+// All the input series start at this time.
+//
+// It isn't set to Unix epoch, since the storage doesn't support negative timestamps,
+// so lookbehind windows for the first evaluations would be truncated.
+// It is aligned to a day, so -datasource.queryTimeAlignment doesn't shift evaluation times.
+var testStartTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// unitTestFile is the contents of a single file passed to -unittestFile.
+type unitTestFile struct {
+	RuleFiles          []string            `yaml:"rule_files"`
+	EvaluationInterval *promutils.Duration `yaml:"evaluation_interval"`
+	GroupEvalOrder     []string            `yaml:"group_eval_order"`
+	Tests              []testGroup         `yaml:"tests"`
+}
+
+// testGroup is a group of input series and tests on them.
+type testGroup struct {
+	Name               string              `yaml:"name"`
+	Interval           *promutils.Duration `yaml:"interval"`
+	InputSeries        []inputSeries       `yaml:"input_series"`
+	AlertRuleTests     []alertTestCase     `yaml:"alert_rule_test"`
+	MetricsqlExprTests []metricsqlTestCase `yaml:"metricsql_expr_test"`
+	ExternalLabels     map[string]string   `yaml:"external_labels"`
+}
+
+// inputSeries is a series with values in expanding notation.
+//
+// For example, `1+1x3 _ stale 5` is expanded to `1 2 3 4 _ stale 5`.
+type inputSeries struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+}
+
+// alertTestCase checks firing alerts for the given alertname at EvalTime.
+type alertTestCase struct {
+	EvalTime  *promutils.Duration `yaml:"eval_time"`
+	GroupName string              `yaml:"groupname"`
+	Alertname string              `yaml:"alertname"`
+	ExpAlerts []expAlert          `yaml:"exp_alerts"`
+}
+
+type expAlert struct {
+	ExpLabels      map[string]string `yaml:"exp_labels"`
+	ExpAnnotations map[string]string `yaml:"exp_annotations"`
+}
+
+// metricsqlTestCase checks the result of MetricsQL expression evaluated at EvalTime.
+type metricsqlTestCase struct {
+	Expr       string              `yaml:"expr"`
+	EvalTime   *promutils.Duration `yaml:"eval_time"`
+	ExpSamples []expSample         `yaml:"exp_samples"`
+}
+
+type expSample struct {
+	// Labels is a series in the `metric{label="value"}` notation.
+	Labels string  `yaml:"labels"`
+	Value  float64 `yaml:"value"`
+}
+
+// unitRule runs unit tests from the given files and returns false if at least a single test fails.
+func unitRule(files ...string) bool {
+	storagePath, err := ioutil.TempDir("", "vmalert-unittest")
+	if err != nil {
+		logger.Fatalf("cannot create temporary directory for unit tests storage: %s", err)
+	}
+	defer fs.MustRemoveAll(storagePath)
+
+	datasourceURL := setUpUnitTestStorage(storagePath)
+	defer tearDownUnitTestStorage()
+
+	passed := true
+	for _, f := range files {
+		fmt.Printf("\nUnit Testing: %s\n", f)
+		if errs := ruleUnitTest(f, datasourceURL); len(errs) > 0 {
+			fmt.Printf("  FAILED\n")
+			for _, err := range errs {
+				fmt.Printf("%s\n", err)
+			}
+			passed = false
+			continue
+		}
+		fmt.Printf("  SUCCESS\n")
+	}
+	return passed
+}
+
+var unitTestServer *http.Server
+
+// setUpUnitTestStorage starts in-process storage at storagePath and returns the url for querying it.
+func setUpUnitTestStorage(storagePath string) string {
+	for name, value := range map[string]string{
+		"storageDataPath": storagePath,
+		// Input series start at testStartTime, so they must fit retention.
+		"retentionPeriod": "100y",
+		// Rule results are written to the storage during tests, so cached responses may become stale.
+		"search.disableCache": "true",
+	} {
+		if err := flag.Set(name, value); err != nil {
+			logger.Fatalf("cannot set -%s=%q: %s", name, value, err)
+		}
+	}
+	vmstorage.Init(promql.ResetRollupResultCacheIfNeeded)
+	vmselect.Init()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		logger.Fatalf("cannot start listener for unit tests storage: %s", err)
+	}
+	unitTestServer = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !vmselect.RequestHandler(w, r) {
+				http.Error(w, fmt.Sprintf("unsupported path requested: %q", r.URL.Path), http.StatusBadRequest)
+			}
+		}),
+	}
+	go func() {
+		_ = unitTestServer.Serve(ln)
+	}()
+	return "http://" + ln.Addr().String()
+}
+
+func tearDownUnitTestStorage() {
+	_ = unitTestServer.Close()
+	vmselect.Stop()
+	vmstorage.Stop()
+}
+
+// ruleUnitTest runs tests from the given unit test file.
+func ruleUnitTest(filename string, datasourceURL string) []error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return []error{fmt.Errorf("failed to read file: %w", err)}
+	}
+	var utf unitTestFile
+	if err := yaml.UnmarshalStrict(b, &utf); err != nil {
+		return []error{fmt.Errorf("failed to unmarshal file: %w", err)}
+	}
+	baseDir := filepath.Dir(filename)
+	for i, rf := range utf.RuleFiles {
+		if !filepath.IsAbs(rf) {
+			utf.RuleFiles[i] = filepath.Join(baseDir, rf)
+		}
+	}
+	groupsCfg, err := config.Parse(utf.RuleFiles, true, true)
+	if err != nil {
+		return []error{fmt.Errorf("failed to parse rule files: %w", err)}
+	}
+	if len(groupsCfg) == 0 {
+		return []error{fmt.Errorf("no rules found in rule_files")}
+	}
+	// The in-process storage doesn't serve Graphite Render API, so graphite groups are skipped.
+	// Tests referring to alerts from the skipped groups fail.
+	skippedGroups := make(map[string]bool)
+	supportedGroups := groupsCfg[:0]
+	for _, g := range groupsCfg {
+		if g.Type.String() == datasource.NewGraphiteType().String() {
+			logger.Warnf("skipping group %q from %q, since rules with type %q aren't supported in unit tests", g.Name, g.File, g.Type.Get())
+			skippedGroups[g.Name] = true
+			continue
+		}
+		supportedGroups = append(supportedGroups, g)
+	}
+	groupsCfg = supportedGroups
+	evalInterval := utf.EvaluationInterval.Duration()
+	if evalInterval <= 0 {
+		evalInterval = time.Minute
+	}
+	groupOrder := make(map[string]int, len(utf.GroupEvalOrder))
+	for i, name := range utf.GroupEvalOrder {
+		if _, ok := groupOrder[name]; ok {
+			return []error{fmt.Errorf("group %q is listed multiple times in group_eval_order", name)}
+		}
+		groupOrder[name] = i
+	}
+	if len(groupOrder) > 0 {
+		for _, g := range groupsCfg {
+			if _, ok := groupOrder[g.Name]; !ok {
+				return []error{fmt.Errorf("group %q is missing in group_eval_order", g.Name)}
+			}
+		}
+		sort.SliceStable(groupsCfg, func(i, j int) bool {
+			return groupOrder[groupsCfg[i].Name] < groupOrder[groupsCfg[j].Name]
+		})
+	}
+
+	q := datasource.NewVMStorage(datasourceURL, nil, 0, 0, false, http.DefaultClient)
+	var errs []error
+	for i := range utf.Tests {
+		tg := &utf.Tests[i]
+		for _, err := range tg.test(groupsCfg, skippedGroups, q, evalInterval) {
+			name := tg.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			errs = append(errs, fmt.Errorf("    test %s: %w", name, err))
+		}
+	}
+	return errs
+}
+
+// test runs all the tests from tg against the given groupsCfg.
+//
+// Alert tests for groups from skippedGroups fail, since these groups cannot be evaluated.
+func (tg *testGroup) test(groupsCfg []config.Group, skippedGroups map[string]bool, q *datasource.VMStorage, evalInterval time.Duration) []error {
+	if err := cleanUnitTestStorage(); err != nil {
+		return []error{err}
+	}
+	interval := tg.Interval.Duration()
+	if interval <= 0 {
+		interval = evalInterval
+	}
+	if err := writeInputSeries(tg.InputSeries, interval); err != nil {
+		return []error{err}
+	}
+
+	groups := make([]*Group, 0, len(groupsCfg))
+	for _, cfg := range groupsCfg {
+		groups = append(groups, newGroup(cfg, q, evalInterval, tg.ExternalLabels))
+	}
+	defer func() {
+		for _, g := range groups {
+			closeUnitTestGroup(g)
+		}
+	}()
+
+	var maxEvalTime time.Duration
+	for _, at := range tg.AlertRuleTests {
+		if d := at.EvalTime.Duration(); d > maxEvalTime {
+			maxEvalTime = d
+		}
+	}
+	for _, mt := range tg.MetricsqlExprTests {
+		if d := mt.EvalTime.Duration(); d > maxEvalTime {
+			maxEvalTime = d
+		}
+	}
+
+	// Groups may have their own intervals, so step by the greatest common divisor of all the group intervals
+	// in order to evaluate every group as often as it is evaluated in production.
+	step := evalInterval
+	for _, g := range groups {
+		step = gcdDuration(step, g.Interval)
+	}
+
+	var errs []error
+	for _, at := range tg.AlertRuleTests {
+		if skippedGroups[at.GroupName] {
+			errs = append(errs, fmt.Errorf("cannot check alert %q from group %q, since graphite groups aren't supported in unit tests", at.Alertname, at.GroupName))
+		}
+	}
+	ctx := context.Background()
+	for ts := time.Duration(0); ts <= maxEvalTime; ts += step {
+		for _, g := range groups {
+			if ts%g.Interval != 0 {
+				continue
+			}
+			for _, rule := range g.Rules {
+				tss, err := rule.Exec(ctx, testStartTime.Add(ts), g.Limit)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("failed to execute rule %q from group %q at %s: %w", ruleName(rule), g.Name, ts, err))
+					continue
+				}
+				// Results of the rule must be visible to the subsequent rules.
+				if err := writeTimeSeries(tss); err != nil {
+					return append(errs, err)
+				}
+			}
+		}
+		// Check alerts at the last evaluation before eval_time.
+		for i := range tg.AlertRuleTests {
+			at := &tg.AlertRuleTests[i]
+			if skippedGroups[at.GroupName] {
+				continue
+			}
+			if d := at.EvalTime.Duration(); d >= ts && d < ts+step {
+				if err := at.check(groups); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	for i := range tg.MetricsqlExprTests {
+		if err := tg.MetricsqlExprTests[i].check(ctx, q); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func gcdDuration(a, b time.Duration) time.Duration {
+	if a <= 0 {
+		return b
+	}
+	for b > 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+func ruleName(rule Rule) string {
+	switch r := rule.(type) {
+	case *AlertingRule:
+		return r.Name
+	case *RecordingRule:
+		return r.Name
+	}
+	return ""
+}
+
+func closeUnitTestGroup(g *Group) {
+	g.metrics.iterationDuration.Unregister()
+	g.metrics.iterationTotal.Unregister()
+	g.metrics.iterationMissed.Unregister()
+	g.metrics.iterationInterval.Unregister()
+	for _, rule := range g.Rules {
+		rule.Close()
+	}
+}
+
+// check verifies that firing alerts for at.Alertname match at.ExpAlerts.
+func (at *alertTestCase) check(groups []*Group) error {
+	var ar *AlertingRule
+	for _, g := range groups {
+		if g.Name != at.GroupName {
+			continue
+		}
+		for _, rule := range g.Rules {
+			if r, ok := rule.(*AlertingRule); ok && r.Name == at.Alertname {
+				ar = r
+				break
+			}
+		}
+	}
+	if ar == nil {
+		return fmt.Errorf("alert %q isn't found in group %q", at.Alertname, at.GroupName)
+	}
+
+	var got []string
+	ar.mu.RLock()
+	for _, a := range ar.alerts {
+		if a.State == notifier.StateFiring {
+			got = append(got, alertString(a.Labels, a.Annotations))
+		}
+	}
+	ar.mu.RUnlock()
+
+	var exp []string
+	for _, ea := range at.ExpAlerts {
+		labels := make(map[string]string, len(ea.ExpLabels)+2)
+		for k, v := range ea.ExpLabels {
+			labels[k] = v
+		}
+		// alertname and alertgroup labels are added to alerts by vmalert,
+		// so add them to expected labels if they are missing.
+		if _, ok := labels[alertNameLabel]; !ok {
+			labels[alertNameLabel] = at.Alertname
+		}
+		if _, ok := labels[alertGroupNameLabel]; !ok && !*disableAlertGroupLabel {
+			labels[alertGroupNameLabel] = at.GroupName
+		}
+		exp = append(exp, alertString(labels, ea.ExpAnnotations))
+	}
+	sort.Strings(got)
+	sort.Strings(exp)
+	if strings.Join(got, "\n") == strings.Join(exp, "\n") {
+		return nil
+	}
+	return fmt.Errorf("alertname: %s, time: %s,\n%s", at.Alertname, at.EvalTime.Duration(), diffLines(exp, got))
+}
+
+func alertString(labels, annotations map[string]string) string {
+	return fmt.Sprintf("labels: %s annotations: %s", mapString(labels), mapString(annotations))
+}
+
+func mapString(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b []byte
+	b = append(b, '{')
+	for i, k := range keys {
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = append(b, k...)
+		b = append(b, '=')
+		b = strconv.AppendQuote(b, m[k])
+	}
+	b = append(b, '}')
+	return string(b)
+}
+
+// check verifies that mt.Expr evaluated at mt.EvalTime returns mt.ExpSamples.
+func (mt *metricsqlTestCase) check(ctx context.Context, q *datasource.VMStorage) error {
+	metrics, err := q.Query(ctx, mt.Expr, testStartTime.Add(mt.EvalTime.Duration()))
+	if err != nil {
+		return fmt.Errorf("expr: %q, time: %s, failed to execute query: %w", mt.Expr, mt.EvalTime.Duration(), err)
+	}
+	got := make([]sample, 0, len(metrics))
+	for _, m := range metrics {
+		labels := make(map[string]string, len(m.Labels))
+		for _, l := range m.Labels {
+			labels[l.Name] = l.Value
+		}
+		got = append(got, sample{
+			labels: seriesString(labels),
+			value:  m.Values[0],
+		})
+	}
+	exp := make([]sample, 0, len(mt.ExpSamples))
+	for _, es := range mt.ExpSamples {
+		labels, err := parseSeries(es.Labels)
+		if err != nil {
+			return fmt.Errorf("expr: %q, time: %s, cannot parse expected labels %q: %w", mt.Expr, mt.EvalTime.Duration(), es.Labels, err)
+		}
+		exp = append(exp, sample{
+			labels: seriesString(labels),
+			value:  es.Value,
+		})
+	}
+	sortSamples(got)
+	sortSamples(exp)
+	if equalSamples(exp, got) {
+		return nil
+	}
+	return fmt.Errorf("expr: %q, time: %s,\n%s", mt.Expr, mt.EvalTime.Duration(), diffLines(samplesStrings(exp), samplesStrings(got)))
+}
+
+type sample struct {
+	labels string
+	value  float64
+}
+
+func sortSamples(ss []sample) {
+	sort.Slice(ss, func(i, j int) bool {
+		if ss[i].labels != ss[j].labels {
+			return ss[i].labels < ss[j].labels
+		}
+		return ss[i].value < ss[j].value
+	})
+}
+
+func equalSamples(a, b []sample) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].labels != b[i].labels || !almostEqual(a[i].value, b[i].value) {
+			return false
+		}
+	}
+	return true
+}
+
+// almostEqual returns true if a and b are equal with the precision sufficient for float calculations in queries.
+func almostEqual(a, b float64) bool {
+	if math.IsNaN(a) || math.IsNaN(b) {
+		return math.IsNaN(a) && math.IsNaN(b)
+	}
+	if a == b {
+		return true
+	}
+	if math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return false
+	}
+	const epsilon = 1e-9
+	return math.Abs(a-b) <= epsilon*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func samplesStrings(ss []sample) []string {
+	a := make([]string, len(ss))
+	for i, s := range ss {
+		a[i] = s.labels + " " + strconv.FormatFloat(s.value, 'g', -1, 64)
+	}
+	return a
+}
+
+// seriesString returns `metric{label="value"}` representation of labels with sorted label names.
+func seriesString(labels map[string]string) string {
+	name := labels["__name__"]
+	m := make(map[string]string, len(labels))
+	for k, v := range labels {
+		if k != "__name__" {
+			m[k] = v
+		}
+	}
+	return name + mapString(m)
+}
+
+// diffLines returns the difference between sorted exp and got lines.
+//
+// Lines missing in got are prefixed with `-`, while unexpected lines in got are prefixed with `+`.
+func diffLines(exp, got []string) string {
+	var b strings.Builder
+	b.WriteString("    --- expected\n    +++ got\n")
+	i, j := 0, 0
+	for i < len(exp) || j < len(got) {
+		switch {
+		case j >= len(got) || (i < len(exp) && exp[i] < got[j]):
+			fmt.Fprintf(&b, "    - %s\n", exp[i])
+			i++
+		case i >= len(exp) || got[j] < exp[i]:
+			fmt.Fprintf(&b, "    + %s\n", got[j])
+			j++
+		default:
+			fmt.Fprintf(&b, "      %s\n", exp[i])
+			i++
+			j++
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// parseSeries parses series in the `metric{label="value"}` notation.
+func parseSeries(s string) (map[string]string, error) {
+	expr, err := metricsql.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	me, ok := expr.(*metricsql.MetricExpr)
+	if !ok {
+		return nil, fmt.Errorf("expecting series selector; got %q", expr.AppendString(nil))
+	}
+	labels := make(map[string]string, len(me.LabelFilters))
+	for _, lf := range me.LabelFilters {
+		if lf.IsNegative || lf.IsRegexp {
+			return nil, fmt.Errorf("unexpected label filter %q; only `=` filters are allowed", lf.AppendString(nil))
+		}
+		labels[lf.Label] = lf.Value
+	}
+	return labels, nil
+}
+
+// seriesValue is a single value of input series.
+type seriesValue struct {
+	value float64
+	// omitted is set for `_` values, which mean missing samples.
+	omitted bool
+}
+
+// parseInputValues expands the values notation used in input_series.
+//
+// The following items separated by whitespace are supported:
+//
+//	a       - the value a
+//	_       - missing value
+//	stale   - staleness marker
+//	a+bxn   - n+1 values starting from a and incrementing by b: a, a+b, ..., a+n*b
+//	a-bxn   - n+1 values starting from a and decrementing by b
+//	axn     - n+1 values equal to a
+//	_xn     - n missing values
+func parseInputValues(s string) ([]seriesValue, error) {
+	var values []seriesValue
+	for _, item := range strings.Fields(s) {
+		switch item {
+		case "_":
+			values = append(values, seriesValue{omitted: true})
+			continue
+		case "stale":
+			values = append(values, seriesValue{value: decimal.StaleNaN})
+			continue
+		}
+		n := strings.LastIndexByte(item, 'x')
+		if n < 0 {
+			v, err := parseInputValue(item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, seriesValue{value: v})
+			continue
+		}
+		base, countStr := item[:n], item[n+1:]
+		count, err := strconv.Atoi(countStr)
+		if err != nil || count < 0 {
+			return nil, fmt.Errorf("cannot parse repeat count in %q", item)
+		}
+		if base == "_" {
+			for i := 0; i < count; i++ {
+				values = append(values, seriesValue{omitted: true})
+			}
+			continue
+		}
+		start, step, err := parseInputValueStep(base)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q: %w", item, err)
+		}
+		for i := 0; i <= count; i++ {
+			values = append(values, seriesValue{value: start + float64(i)*step})
+		}
+	}
+	return values, nil
+}
+
+// parseInputValueStep parses `a+b`, `a-b` or `a`.
+func parseInputValueStep(s string) (float64, float64, error) {
+	// Skip the sign of the start value.
+	n := strings.LastIndexAny(s, "+-")
+	if n > 0 && s[n-1] != 'e' && s[n-1] != 'E' {
+		start, err := parseInputValue(s[:n])
+		if err != nil {
+			return 0, 0, err
+		}
+		step, err := parseInputValue(s[n+1:])
+		if err != nil {
+			return 0, 0, err
+		}
+		if s[n] == '-' {
+			step = -step
+		}
+		return start, step, nil
+	}
+	start, err := parseInputValue(s)
+	return start, 0, err
+}
+
+func parseInputValue(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse value %q: %w", s, err)
+	}
+	return v, nil
+}
+
+// writeInputSeries writes input series to the storage with the given interval between samples.
+func writeInputSeries(series []inputSeries, interval time.Duration) error {
+	var mrs []storage.MetricRow
+	for _, s := range series {
+		labels, err := parseSeries(s.Series)
+		if err != nil {
+			return fmt.Errorf("cannot parse input series %q: %w", s.Series, err)
+		}
+		values, err := parseInputValues(s.Values)
+		if err != nil {
+			return fmt.Errorf("cannot parse values for input series %q: %w", s.Series, err)
+		}
+		metricNameRaw := marshalMetricNameRaw(labels)
+		for i, v := range values {
+			if v.omitted {
+				continue
+			}
+			mrs = append(mrs, storage.MetricRow{
+				MetricNameRaw: metricNameRaw,
+				Timestamp:     testStartTime.Add(time.Duration(i)*interval).UnixNano() / 1e6,
+				Value:         v.value,
+			})
+		}
+	}
+	return addRows(mrs)
+}
+
+// writeTimeSeries writes rule results to the storage.
+func writeTimeSeries(tss []prompbmarshal.TimeSeries) error {
+	var mrs []storage.MetricRow
+	for _, ts := range tss {
+		labels := make(map[string]string, len(ts.Labels))
+		for _, l := range ts.Labels {
+			labels[l.Name] = l.Value
+		}
+		metricNameRaw := marshalMetricNameRaw(labels)
+		for _, s := range ts.Samples {
+			mrs = append(mrs, storage.MetricRow{
+				MetricNameRaw: metricNameRaw,
+				Timestamp:     s.Timestamp,
+				Value:         s.Value,
+			})
+		}
+	}
+	return addRows(mrs)
+}
+
+func marshalMetricNameRaw(labels map[string]string) []byte {
+	pls := make([]prompb.Label, 0, len(labels))
+	for k, v := range labels {
+		pls = append(pls, prompb.Label{
+			Name:  []byte(k),
+			Value: []byte(v),
+		})
+	}
+	return storage.MarshalMetricNameRaw(nil, pls)
+}
+
+func addRows(mrs []storage.MetricRow) error {
+	if len(mrs) == 0 {
+		return nil
+	}
+	if err := vmstorage.AddRows(mrs); err != nil {
+		return fmt.Errorf("cannot write series to storage: %w", err)
+	}
+	// Make the written data visible to search.
+	vmstorage.Storage.DebugFlush()
+	return nil
+}
+
+// cleanUnitTestStorage deletes all the series from the storage, so tests do not affect each other.
+func cleanUnitTestStorage() error {
+	tfs := storage.NewTagFilters()
+	if err := tfs.Add(nil, []byte(".+"), false, true); err != nil {
+		return fmt.Errorf("BUG: cannot create tag filter: %w", err)
+	}
+	if _, err := vmstorage.DeleteMetrics([]*storage.TagFilters{tfs}); err != nil {
+		return fmt.Errorf("cannot delete series from storage: %w", err)
+	}
+	return nil
+}
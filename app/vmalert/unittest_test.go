@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestRuleUnitTest(t *testing.T) {
+	storagePath, err := ioutil.TempDir("", "vmalert-unittest")
+	if err != nil {
+		t.Fatalf("cannot create temporary directory: %s", err)
+	}
+	defer fs.MustRemoveAll(storagePath)
+
+	// The in-process storage can be initialized only once per process,
+	// so it is shared among all the checked files.
+	datasourceURL := setUpUnitTestStorage(storagePath)
+	defer tearDownUnitTestStorage()
+
+	f := func(path string, expFailures int) {
+		t.Helper()
+		errs := ruleUnitTest(path, datasourceURL)
+		if len(errs) != expFailures {
+			t.Fatalf("unexpected number of failures for %q; got %d; want %d: %v", path, len(errs), expFailures, errs)
+		}
+	}
+	f("./unittest/testdata/test1.yaml", 0)
+	// wrong labels for the alert and wrong value for the metricsql expression
+	f("./unittest/testdata/failed.yaml", 2)
+	// the group is evaluated at its own interval, which is shorter than evaluation_interval
+	f("./unittest/testdata/interval.yaml", 0)
+	// graphite groups are skipped, while the alert from graphite group fails
+	f("./unittest/testdata/mixed.yaml", 1)
+}
+
+func TestParseInputValues(t *testing.T) {
+	f := func(s string, exp []seriesValue) {
+		t.Helper()
+		values, err := parseInputValues(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(values) != len(exp) {
+			t.Fatalf("unexpected number of values; got %v; want %v", values, exp)
+		}
+		for i := range values {
+			got, want := values[i], exp[i]
+			if got.omitted != want.omitted {
+				t.Fatalf("unexpected value #%d; got %v; want %v", i, got, want)
+			}
+			if decimal.IsStaleNaN(want.value) {
+				if !decimal.IsStaleNaN(got.value) {
+					t.Fatalf("expecting staleness marker at #%d; got %v", i, got.value)
+				}
+				continue
+			}
+			if got.value != want.value {
+				t.Fatalf("unexpected value #%d; got %v; want %v", i, got.value, want.value)
+			}
+		}
+	}
+	f("", nil)
+	f("1", []seriesValue{{value: 1}})
+	f("1 _ stale", []seriesValue{{value: 1}, {omitted: true}, {value: decimal.StaleNaN}})
+	f("1+1x3", []seriesValue{{value: 1}, {value: 2}, {value: 3}, {value: 4}})
+	f("5-2x2", []seriesValue{{value: 5}, {value: 3}, {value: 1}})
+	f("-1x2", []seriesValue{{value: -1}, {value: -1}, {value: -1}})
+	f("_x2 0.5", []seriesValue{{omitted: true}, {omitted: true}, {value: 0.5}})
+
+	fErr := func(s string) {
+		t.Helper()
+		if _, err := parseInputValues(s); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+	fErr("foo")
+	fErr("1+x2")
+	fErr("1+1xfoo")
+	fErr("_+1x2")
+}
+
+func TestDiffLines(t *testing.T) {
+	f := func(exp, got []string, expDiff string) {
+		t.Helper()
+		if diff := diffLines(exp, got); diff != expDiff {
+			t.Fatalf("unexpected diff\ngot\n%s\nwant\n%s", diff, expDiff)
+		}
+	}
+	f([]string{"a 1", "b 2"}, []string{"a 1", "c 3"}, `    --- expected
+    +++ got
+      a 1
+    - b 2
+    + c 3`)
+	f(nil, []string{"a 1"}, `    --- expected
+    +++ got
+    + a 1`)
+}
+
+func TestAlmostEqual(t *testing.T) {
+	f := func(a, b float64, exp bool) {
+		t.Helper()
+		if almostEqual(a, b) != exp {
+			t.Fatalf("unexpected result for %v and %v; want %v", a, b, exp)
+		}
+	}
+	f(1, 1, true)
+	f(1, 1+1e-12, true)
+	f(1, 1.1, false)
+	f(math.NaN(), math.NaN(), true)
+	f(math.Inf(1), math.Inf(1), true)
+	f(math.Inf(1), math.Inf(-1), false)
+}
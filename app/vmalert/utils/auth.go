@@ -58,3 +58,10 @@ func WithOAuth(clientID, clientSecret, clientSecretFile, tokenURL, scopes string
 		}
 	}
 }
+
+// WithHeaders returns AuthConfigOptions and set Headers based on the given params
+func WithHeaders(headers []string) AuthConfigOptions {
+	return func(config *promauth.HTTPClientConfig) {
+		config.Headers = headers
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"flag"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"regexp"
@@ -17,6 +18,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
 	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
 	"gopkg.in/yaml.v2"
 )
 
@@ -36,14 +38,32 @@ type UserInfo struct {
 	BearerToken string     `yaml:"bearer_token,omitempty"`
 	Username    string     `yaml:"username,omitempty"`
 	Password    string     `yaml:"password,omitempty"`
+	JWT         *JWTConfig `yaml:"jwt,omitempty"`
 	URLPrefix   *URLPrefix `yaml:"url_prefix,omitempty"`
 	URLMap      []URLMap   `yaml:"url_map,omitempty"`
 	Headers     []Header   `yaml:"headers,omitempty"`
 
+	DropHeaders         []string   `yaml:"drop_headers,omitempty"`
+	ResponseHeaders     []Header   `yaml:"response_headers,omitempty"`
+	DropResponseHeaders []string   `yaml:"drop_response_headers,omitempty"`
+	DropQueryArgs       []string   `yaml:"drop_query_args,omitempty"`
+	SetQueryArgs        []QueryArg `yaml:"set_query_args,omitempty"`
+	AddQueryArgs        []QueryArg `yaml:"add_query_args,omitempty"`
+
+	LoadBalancingPolicy string `yaml:"load_balancing_policy,omitempty"`
+
+	// Per-user limits. The corresponding -maxConcurrentPerUserRequests, -maxRequestsPerSecondPerUser
+	// and -maxBytesPerSecondPerUser flag values are used if these options aren't set.
+	// Zero value disables the corresponding limit.
+	MaxConcurrentRequests *int     `yaml:"max_concurrent_requests,omitempty"`
+	MaxRequestsPerSecond  *float64 `yaml:"max_requests_per_second,omitempty"`
+	MaxBytesPerSecond     *int     `yaml:"max_bytes_per_second,omitempty"`
+
 	requests *metrics.Counter
+	limiter  *userLimiter
 }
 
-// Header is `Name: Value` http header, which must be added to the proxied request.
+// Header is `Name: Value` http header, which must be added to the proxied request or response.
 type Header struct {
 	Name  string
 	Value string
@@ -70,11 +90,142 @@ func (h *Header) MarshalYAML() (interface{}, error) {
 	return s, nil
 }
 
-// URLMap is a mapping from source paths to target urls.
+// QueryArg is `name=value` query arg.
+type QueryArg struct {
+	Name  string
+	Value string
+}
+
+// UnmarshalYAML unmarshals qa from f.
+func (qa *QueryArg) UnmarshalYAML(f func(interface{}) error) error {
+	var s string
+	if err := f(&s); err != nil {
+		return err
+	}
+	n := strings.IndexByte(s, '=')
+	if n <= 0 {
+		return fmt.Errorf("missing query arg name in %q; expected format - 'name=value'", s)
+	}
+	qa.Name = s[:n]
+	qa.Value = s[n+1:]
+	return nil
+}
+
+// MarshalYAML marshals qa to yaml.
+func (qa *QueryArg) MarshalYAML() (interface{}, error) {
+	s := fmt.Sprintf("%s=%s", qa.Name, qa.Value)
+	return s, nil
+}
+
+// URLMap is a mapping from source paths, query args and headers to target urls.
 type URLMap struct {
-	SrcPaths  []*SrcPath `yaml:"src_paths,omitempty"`
-	URLPrefix *URLPrefix `yaml:"url_prefix,omitempty"`
-	Headers   []Header   `yaml:"headers,omitempty"`
+	SrcPaths     []*SrcPath     `yaml:"src_paths,omitempty"`
+	SrcQueryArgs []*SrcQueryArg `yaml:"src_query_args,omitempty"`
+	SrcHeaders   []*SrcHeader   `yaml:"src_headers,omitempty"`
+	URLPrefix    *URLPrefix     `yaml:"url_prefix,omitempty"`
+	Headers      []Header       `yaml:"headers,omitempty"`
+
+	DropHeaders         []string   `yaml:"drop_headers,omitempty"`
+	ResponseHeaders     []Header   `yaml:"response_headers,omitempty"`
+	DropResponseHeaders []string   `yaml:"drop_response_headers,omitempty"`
+	DropQueryArgs       []string   `yaml:"drop_query_args,omitempty"`
+	SetQueryArgs        []QueryArg `yaml:"set_query_args,omitempty"`
+	AddQueryArgs        []QueryArg `yaml:"add_query_args,omitempty"`
+
+	LoadBalancingPolicy string `yaml:"load_balancing_policy,omitempty"`
+}
+
+// SrcQueryArg represents `name=value` matcher for request query args, where value is a regexp.
+type SrcQueryArg struct {
+	sOriginal string
+	name      string
+	re        *regexp.Regexp
+}
+
+// match returns true if any value for sqa.name in args matches sqa.
+func (sqa *SrcQueryArg) match(args url.Values) bool {
+	for _, v := range args[sqa.name] {
+		if sqa.re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (sqa *SrcQueryArg) UnmarshalYAML(f func(interface{}) error) error {
+	var s string
+	if err := f(&s); err != nil {
+		return err
+	}
+	n := strings.IndexByte(s, '=')
+	if n <= 0 {
+		return fmt.Errorf("missing query arg name in `src_query_args` entry %q; expected format - 'name=value'", s)
+	}
+	re, err := compileAnchoredRegexp(s[n+1:])
+	if err != nil {
+		return err
+	}
+	sqa.sOriginal = s
+	sqa.name = s[:n]
+	sqa.re = re
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (sqa *SrcQueryArg) MarshalYAML() (interface{}, error) {
+	return sqa.sOriginal, nil
+}
+
+// SrcHeader represents `Name: value` matcher for request headers, where value is a regexp.
+type SrcHeader struct {
+	sOriginal string
+	name      string
+	re        *regexp.Regexp
+}
+
+// match returns true if any value for sh.name in h matches sh.
+func (sh *SrcHeader) match(h http.Header) bool {
+	for _, v := range h.Values(sh.name) {
+		if sh.re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (sh *SrcHeader) UnmarshalYAML(f func(interface{}) error) error {
+	var s string
+	if err := f(&s); err != nil {
+		return err
+	}
+	n := strings.IndexByte(s, ':')
+	if n <= 0 {
+		return fmt.Errorf("missing header name in `src_headers` entry %q; expected format - 'Name: value'", s)
+	}
+	re, err := compileAnchoredRegexp(strings.TrimSpace(s[n+1:]))
+	if err != nil {
+		return err
+	}
+	sh.sOriginal = s
+	sh.name = strings.TrimSpace(s[:n])
+	sh.re = re
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (sh *SrcHeader) MarshalYAML() (interface{}, error) {
+	return sh.sOriginal, nil
+}
+
+func compileAnchoredRegexp(s string) (*regexp.Regexp, error) {
+	sAnchored := "^(?:" + s + ")$"
+	re, err := regexp.Compile(sAnchored)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build regexp from %q: %w", s, err)
+	}
+	return re, nil
 }
 
 // SrcPath represents an src path
@@ -87,12 +238,12 @@ type SrcPath struct {
 type URLPrefix struct {
 	n    uint32
 	urls []*url.URL
-}
 
-func (up *URLPrefix) getNextURL() *url.URL {
-	n := atomic.AddUint32(&up.n, 1)
-	idx := n % uint32(len(up.urls))
-	return up.urls[idx]
+	// bus contains backends for urls. It is initialized in sanitize.
+	bus []*backendURL
+
+	// loadBalancingPolicy is either `round_robin` or `first_available`. Empty value means `round_robin`.
+	loadBalancingPolicy string
 }
 
 // UnmarshalYAML unmarshals up from yaml.
@@ -171,10 +322,9 @@ func (sp *SrcPath) UnmarshalYAML(f func(interface{}) error) error {
 	if err := f(&s); err != nil {
 		return err
 	}
-	sAnchored := "^(?:" + s + ")$"
-	re, err := regexp.Compile(sAnchored)
+	re, err := compileAnchoredRegexp(s)
 	if err != nil {
-		return fmt.Errorf("cannot build regexp from %q: %w", s, err)
+		return err
 	}
 	sp.sOriginal = s
 	sp.re = re
@@ -196,11 +346,11 @@ func initAuthConfig() {
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1240
 	sighupCh := procutil.NewSighupChan()
 
-	m, err := readAuthConfig(*authConfigPath)
+	as, err := readAuthConfig(*authConfigPath)
 	if err != nil {
 		logger.Fatalf("cannot load auth config from `-auth.config=%s`: %s", *authConfigPath, err)
 	}
-	authConfig.Store(m)
+	authConfig.Store(as)
 	stopCh = make(chan struct{})
 	authConfigWG.Add(1)
 	go func() {
@@ -221,12 +371,12 @@ func authConfigReloader(sighupCh <-chan os.Signal) {
 			return
 		case <-sighupCh:
 			logger.Infof("SIGHUP received; loading -auth.config=%q", *authConfigPath)
-			m, err := readAuthConfig(*authConfigPath)
+			as, err := readAuthConfig(*authConfigPath)
 			if err != nil {
 				logger.Errorf("failed to load -auth.config=%q; using the last successfully loaded config; error: %s", *authConfigPath, err)
 				continue
 			}
-			authConfig.Store(m)
+			authConfig.Store(as)
 			logger.Infof("Successfully reloaded -auth.config=%q", *authConfigPath)
 		}
 	}
@@ -236,20 +386,40 @@ var authConfig atomic.Value
 var authConfigWG sync.WaitGroup
 var stopCh chan struct{}
 
-func readAuthConfig(path string) (map[string]*UserInfo, error) {
+// authState contains users from the loaded -auth.config.
+type authState struct {
+	// byAuthToken contains users with static credentials keyed by `Authorization` header value.
+	byAuthToken map[string]*UserInfo
+
+	// jwtUsers contains users authenticated via JWT. They are matched in the order from -auth.config.
+	jwtUsers []*UserInfo
+}
+
+// getUsers returns all the users from as.
+//
+// The returned list may contain duplicate users.
+func (as *authState) getUsers() []*UserInfo {
+	uis := make([]*UserInfo, 0, len(as.byAuthToken)+len(as.jwtUsers))
+	for _, ui := range as.byAuthToken {
+		uis = append(uis, ui)
+	}
+	return append(uis, as.jwtUsers...)
+}
+
+func readAuthConfig(path string) (*authState, error) {
 	data, err := fs.ReadFileOrHTTP(path)
 	if err != nil {
 		return nil, err
 	}
-	m, err := parseAuthConfig(data)
+	as, err := parseAuthConfig(data)
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
 	}
-	logger.Infof("Loaded information about %d users from %q", len(m), path)
-	return m, nil
+	logger.Infof("Loaded information about %d users from %q", len(as.byAuthToken)+len(as.jwtUsers), path)
+	return as, nil
 }
 
-func parseAuthConfig(data []byte) (map[string]*UserInfo, error) {
+func parseAuthConfig(data []byte) (*authState, error) {
 	data = envtemplate.Replace(data)
 	var ac AuthConfig
 	if err := yaml.UnmarshalStrict(data, &ac); err != nil {
@@ -260,12 +430,25 @@ func parseAuthConfig(data []byte) (map[string]*UserInfo, error) {
 		return nil, fmt.Errorf("`users` section cannot be empty in AuthConfig")
 	}
 	byAuthToken := make(map[string]*UserInfo, len(uis))
+	var jwtUsers []*UserInfo
 	byUsername := make(map[string]bool, len(uis))
 	byBearerToken := make(map[string]bool, len(uis))
+	byJWTName := make(map[string]bool)
+	limiterNames := make([]string, len(uis))
 	for i := range uis {
 		ui := &uis[i]
-		if ui.BearerToken == "" && ui.Username == "" {
-			return nil, fmt.Errorf("either bearer_token or username must be set")
+		if ui.BearerToken == "" && ui.Username == "" && ui.JWT == nil {
+			return nil, fmt.Errorf("either bearer_token, username or jwt must be set")
+		}
+		if ui.JWT != nil && (ui.BearerToken != "" || ui.Username != "" || ui.Password != "") {
+			return nil, fmt.Errorf("jwt cannot be set simultaneously with bearer_token, username or password")
+		}
+		if ui.JWT != nil && ui.Name == "" {
+			// The name distinguishes jwt users in metrics and limits, since they have no static credentials.
+			return nil, fmt.Errorf("missing `name` for user with `jwt` section")
+		}
+		if ui.JWT != nil && byJWTName[ui.Name] {
+			return nil, fmt.Errorf("duplicate name found for user with `jwt` section; name: %q", ui.Name)
 		}
 		if ui.BearerToken != "" && ui.Username != "" {
 			return nil, fmt.Errorf("bearer_token=%q and username=%q cannot be set simultaneously", ui.BearerToken, ui.Username)
@@ -277,54 +460,122 @@ func parseAuthConfig(data []byte) (map[string]*UserInfo, error) {
 			return nil, fmt.Errorf("duplicate username found; username: %q", ui.Username)
 		}
 		at1, at2 := getAuthTokens(ui.BearerToken, ui.Username, ui.Password)
-		if byAuthToken[at1] != nil {
+		if ui.JWT == nil && byAuthToken[at1] != nil {
 			return nil, fmt.Errorf("duplicate auth token found for bearer_token=%q, username=%q: %q", ui.BearerToken, ui.Username, at1)
 		}
-		if byAuthToken[at2] != nil {
+		if ui.JWT == nil && byAuthToken[at2] != nil {
 			return nil, fmt.Errorf("duplicate auth token found for bearer_token=%q, username=%q: %q", ui.BearerToken, ui.Username, at2)
 		}
 		if ui.URLPrefix != nil {
-			if err := ui.URLPrefix.sanitize(); err != nil {
+			if err := ui.URLPrefix.sanitize(ui.LoadBalancingPolicy); err != nil {
 				return nil, err
 			}
 		}
 		for _, e := range ui.URLMap {
-			if len(e.SrcPaths) == 0 {
-				return nil, fmt.Errorf("missing `src_paths` in `url_map`")
+			if len(e.SrcPaths) == 0 && len(e.SrcQueryArgs) == 0 && len(e.SrcHeaders) == 0 {
+				return nil, fmt.Errorf("missing `src_paths`, `src_query_args` or `src_headers` in `url_map`")
 			}
 			if e.URLPrefix == nil {
 				return nil, fmt.Errorf("missing `url_prefix` in `url_map`")
 			}
-			if err := e.URLPrefix.sanitize(); err != nil {
+			loadBalancingPolicy := e.LoadBalancingPolicy
+			if loadBalancingPolicy == "" {
+				loadBalancingPolicy = ui.LoadBalancingPolicy
+			}
+			if err := e.URLPrefix.sanitize(loadBalancingPolicy); err != nil {
 				return nil, err
 			}
 		}
 		if len(ui.URLMap) == 0 && ui.URLPrefix == nil {
 			return nil, fmt.Errorf("missing `url_prefix`")
 		}
-		if ui.BearerToken != "" {
-			name := "bearer_token"
-			if ui.Name != "" {
-				name = ui.Name
+		if ui.JWT != nil {
+			if err := ui.JWT.init(); err != nil {
+				return nil, err
 			}
+		} else if err := ui.checkNoClaimPlaceholders(); err != nil {
+			return nil, err
+		}
+		if ui.MaxConcurrentRequests != nil && *ui.MaxConcurrentRequests < 0 {
+			return nil, fmt.Errorf("`max_concurrent_requests` cannot be negative; got %d", *ui.MaxConcurrentRequests)
+		}
+		if ui.MaxRequestsPerSecond != nil && *ui.MaxRequestsPerSecond < 0 {
+			return nil, fmt.Errorf("`max_requests_per_second` cannot be negative; got %g", *ui.MaxRequestsPerSecond)
+		}
+		if ui.MaxBytesPerSecond != nil && *ui.MaxBytesPerSecond < 0 {
+			return nil, fmt.Errorf("`max_bytes_per_second` cannot be negative; got %d", *ui.MaxBytesPerSecond)
+		}
+		var name string
+		if ui.JWT != nil {
+			byJWTName[ui.Name] = true
+		}
+		if ui.BearerToken != "" {
+			name = "bearer_token"
 			if ui.Password != "" {
 				return nil, fmt.Errorf("password shouldn't be set for bearer_token %q", ui.BearerToken)
 			}
-			ui.requests = metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_requests_total{username=%q}`, name))
 			byBearerToken[ui.BearerToken] = true
 		}
 		if ui.Username != "" {
-			name := ui.Username
-			if ui.Name != "" {
-				name = ui.Name
-			}
-			ui.requests = metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_requests_total{username=%q}`, name))
+			name = ui.Username
 			byUsername[ui.Username] = true
 		}
+		if ui.Name != "" {
+			name = ui.Name
+		}
+		ui.requests = metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_requests_total{username=%q}`, name))
+		limiterNames[i] = name
+		if ui.JWT != nil {
+			jwtUsers = append(jwtUsers, ui)
+			continue
+		}
 		byAuthToken[at1] = ui
 		byAuthToken[at2] = ui
 	}
-	return byAuthToken, nil
+
+	// Limiters are set after the config is successfully parsed, so limits for the currently used config
+	// aren't updated if the config contains errors.
+	usedLimiterKeys := make(map[string]bool, len(uis))
+	for i := range uis {
+		ui := &uis[i]
+		key := ui.getLimiterKey()
+		usedLimiterKeys[key] = true
+		ui.limiter = getUserLimiter(key, limiterNames[i], ui.getMaxConcurrentRequests(), ui.getMaxRequestsPerSecond(), ui.getMaxBytesPerSecond())
+	}
+	removeUnusedUserLimiters(usedLimiterKeys)
+
+	// Backend states are created while parsing url prefixes, so states for backends missing in the config
+	// can be removed only after the config is successfully parsed.
+	usedBackendURLs := make(map[string]bool)
+	for i := range uis {
+		for _, up := range uis[i].getURLPrefixes() {
+			for _, bu := range up.bus {
+				usedBackendURLs[bu.url.String()] = true
+			}
+		}
+	}
+	removeUnusedBackendStates(usedBackendURLs)
+
+	as := &authState{
+		byAuthToken: byAuthToken,
+		jwtUsers:    jwtUsers,
+	}
+	return as, nil
+}
+
+// getLimiterKey returns the key for the ui limiter.
+//
+// The key depends only on the user credentials, so it remains stable when users are added, removed or reordered in -auth.config.
+// Users with bearer_token or username don't share limits even if they have the same name.
+func (ui *UserInfo) getLimiterKey() string {
+	if ui.BearerToken != "" {
+		return fmt.Sprintf("bearer_token:%016x", xxhash.Sum64String(ui.BearerToken))
+	}
+	if ui.Username != "" {
+		return "username:" + ui.Username
+	}
+	// Users with jwt section must have unique names.
+	return "jwt:" + ui.Name
 }
 
 func getAuthTokens(bearerToken, username, password string) (string, string) {
@@ -347,17 +598,80 @@ func getAuthToken(bearerToken, username, password string) string {
 	return "Basic " + token64
 }
 
-func (up *URLPrefix) sanitize() error {
+func (up *URLPrefix) sanitize(loadBalancingPolicy string) error {
+	switch loadBalancingPolicy {
+	case "", "round_robin", "first_available":
+	default:
+		return fmt.Errorf("unsupported `load_balancing_policy: %q`; supported values: round_robin, first_available", loadBalancingPolicy)
+	}
+	up.loadBalancingPolicy = loadBalancingPolicy
+	bus := make([]*backendURL, len(up.urls))
 	for i, pu := range up.urls {
 		puNew, err := sanitizeURLPrefix(pu)
 		if err != nil {
 			return err
 		}
 		up.urls[i] = puNew
+		bus[i] = newBackendURL(puNew)
+	}
+	up.bus = bus
+	return nil
+}
+
+func (ui *UserInfo) getMaxConcurrentRequests() int {
+	if ui.MaxConcurrentRequests != nil {
+		return *ui.MaxConcurrentRequests
+	}
+	return *maxConcurrentPerUserRequests
+}
+
+func (ui *UserInfo) getMaxRequestsPerSecond() float64 {
+	if ui.MaxRequestsPerSecond != nil {
+		return *ui.MaxRequestsPerSecond
+	}
+	return *maxRequestsPerSecondPerUser
+}
+
+func (ui *UserInfo) getMaxBytesPerSecond() int {
+	if ui.MaxBytesPerSecond != nil {
+		return *ui.MaxBytesPerSecond
+	}
+	return maxBytesPerSecondPerUser.N
+}
+
+// checkNoClaimPlaceholders verifies that ui doesn't contain `{{claim_name}}` placeholders, since they are supported only for jwt users.
+func (ui *UserInfo) checkNoClaimPlaceholders() error {
+	for _, up := range ui.getURLPrefixes() {
+		for _, u := range up.urls {
+			if hasClaimPlaceholders(u.Path) || hasClaimPlaceholders(u.RawQuery) {
+				return fmt.Errorf("`url_prefix` %q cannot contain claim placeholders, since they are supported only for users with `jwt` section", u)
+			}
+		}
+	}
+	headers := append([]Header{}, ui.Headers...)
+	for _, e := range ui.URLMap {
+		headers = append(headers, e.Headers...)
+	}
+	for _, h := range headers {
+		if hasClaimPlaceholders(h.Value) {
+			return fmt.Errorf("header %q cannot contain claim placeholders, since they are supported only for users with `jwt` section", h.Name)
+		}
 	}
 	return nil
 }
 
+// getURLPrefixes returns all the url prefixes for ui.
+func (ui *UserInfo) getURLPrefixes() []*URLPrefix {
+	var ups []*URLPrefix
+	if ui.URLPrefix != nil {
+		ups = append(ups, ui.URLPrefix)
+	}
+	for _, e := range ui.URLMap {
+		ups = append(ups, e.URLPrefix)
+	}
+	return ups
+}
+
 func sanitizeURLPrefix(urlPrefix *url.URL) (*url.URL, error) {
 	// Remove trailing '/' from urlPrefix
 	for strings.HasSuffix(urlPrefix.Path, "/") {
@@ -84,6 +84,139 @@ users:
   url_prefix: []
 `)
 
+	// Invalid load_balancing_policy
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar
+  load_balancing_policy: foobar
+`)
+	f(`
+users:
+- username: foo
+  url_map:
+  - src_paths: ["/api/v1/query"]
+    url_prefix: http://foo.bar
+    load_balancing_policy: least_loaded
+`)
+
+	// Negative limits
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar
+  max_concurrent_requests: -1
+`)
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar
+  max_requests_per_second: -1.5
+`)
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar
+  max_bytes_per_second: -100
+`)
+
+	// Invalid src_query_args
+	f(`
+users:
+- username: foo
+  url_map:
+  - src_query_args: ["nocache"]
+    url_prefix: http://foo.bar
+`)
+	f(`
+users:
+- username: foo
+  url_map:
+  - src_query_args: ["nocache=("]
+    url_prefix: http://foo.bar
+`)
+
+	// Invalid src_headers
+	f(`
+users:
+- username: foo
+  url_map:
+  - src_headers: ["X-Foo"]
+    url_prefix: http://foo.bar
+`)
+	f(`
+users:
+- username: foo
+  url_map:
+  - src_headers: ["X-Foo: ("]
+    url_prefix: http://foo.bar
+`)
+
+	// Invalid set_query_args
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar
+  set_query_args: ["=bar"]
+`)
+
+	// Missing keys in jwt section
+	f(`
+users:
+- name: foo
+  jwt: {}
+  url_prefix: http://foo.bar
+`)
+
+	// Invalid public key in jwt section
+	f(`
+users:
+- name: foo
+  jwt:
+    public_keys: [foobar]
+  url_prefix: http://foo.bar
+`)
+
+	// Missing jwks_file
+	f(`
+users:
+- name: foo
+  jwt:
+    jwks_file: non-existing-file.json
+  url_prefix: http://foo.bar
+`)
+
+	// Missing name for user with jwt section
+	f(`
+users:
+- jwt:
+    jwks_file: non-existing-file.json
+  url_prefix: http://foo.bar
+`)
+
+	// Username and jwt in a single config
+	f(`
+users:
+- username: foo
+  jwt:
+    jwks_file: non-existing-file.json
+  url_prefix: http://foo.bar
+`)
+
+	// Claim placeholders for user without jwt
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar/select/{{tenant}}/prometheus
+`)
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar
+  headers:
+  - "X-Scope-OrgID: {{tenant}}"
+`)
+
 	// Username and bearer_token in a single config
 	f(`
 users:
@@ -190,10 +323,11 @@ users:
 func TestParseAuthConfigSuccess(t *testing.T) {
 	f := func(s string, expectedAuthConfig map[string]*UserInfo) {
 		t.Helper()
-		m, err := parseAuthConfig([]byte(s))
+		as, err := parseAuthConfig([]byte(s))
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
+		m := as.byAuthToken
 		removeMetrics(m)
 		if err := areEqualConfigs(m, expectedAuthConfig); err != nil {
 			t.Fatal(err)
@@ -233,6 +367,61 @@ users:
 		},
 	})
 
+	// Load balancing policy
+	f(`
+users:
+- username: foo
+  password: bar
+  load_balancing_policy: first_available
+  url_prefix:
+  - http://node1:343/bbb
+  - http://node2:343/bbb
+`, map[string]*UserInfo{
+		getAuthToken("", "foo", "bar"): {
+			Username:            "foo",
+			Password:            "bar",
+			LoadBalancingPolicy: "first_available",
+			URLPrefix: mustParseURLs([]string{
+				"http://node1:343/bbb",
+				"http://node2:343/bbb",
+			}),
+		},
+	})
+
+	// Per-user limits
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar
+  max_concurrent_requests: 10
+  max_requests_per_second: 5.5
+  max_bytes_per_second: 1048576
+`, map[string]*UserInfo{
+		getAuthToken("", "foo", ""): {
+			Username:              "foo",
+			URLPrefix:             mustParseURL("http://foo.bar"),
+			MaxConcurrentRequests: newInt(10),
+			MaxRequestsPerSecond:  newFloat64(5.5),
+			MaxBytesPerSecond:     newInt(1048576),
+		},
+	})
+
+	// Zero per-user limits
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo.bar
+  max_concurrent_requests: 0
+  max_requests_per_second: 0
+`, map[string]*UserInfo{
+		getAuthToken("", "foo", ""): {
+			Username:              "foo",
+			URLPrefix:             mustParseURL("http://foo.bar"),
+			MaxConcurrentRequests: newInt(0),
+			MaxRequestsPerSecond:  newFloat64(0),
+		},
+	})
+
 	// Multiple users
 	f(`
 users:
@@ -330,6 +519,14 @@ func getSrcPaths(paths []string) []*SrcPath {
 	return sps
 }
 
+func newInt(n int) *int {
+	return &n
+}
+
+func newFloat64(f float64) *float64 {
+	return &f
+}
+
 func removeMetrics(m map[string]*UserInfo) {
 	for _, info := range m {
 		info.requests = nil
@@ -364,7 +561,11 @@ func mustParseURLs(us []string) *URLPrefix {
 		}
 		pus[i] = pu
 	}
-	return &URLPrefix{
+	up := &URLPrefix{
 		urls: pus,
 	}
+	if err := up.sanitize(""); err != nil {
+		panic(fmt.Errorf("BUG: cannot sanitize %q: %w", us, err))
+	}
+	return up
 }
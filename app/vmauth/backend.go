@@ -0,0 +1,229 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	failTimeout = flag.Duration("failTimeout", 3*time.Second, "Sets a delay period for load balancing to skip a malfunctioning backend. "+
+		"See https://docs.victoriametrics.com/vmauth.html#load-balancing")
+	healthCheckInterval = flag.Duration("healthCheckInterval", 0, "Interval for active health checks of backends from -auth.config. "+
+		"Backends are probed via GET requests to /health path at their hosts. Backends failing the health check are excluded from load balancing until the next successful check. "+
+		"Active health checks are disabled if zero. See https://docs.victoriametrics.com/vmauth.html#load-balancing")
+	healthCheckTimeout = flag.Duration("healthCheckTimeout", 5*time.Second, "Timeout for active health checks of backends. See -healthCheckInterval")
+)
+
+// backendURL is a single url from `url_prefix`.
+type backendURL struct {
+	url *url.URL
+
+	// bs is the state of the backend shared among config reloads.
+	bs *backendState
+}
+
+func newBackendURL(u *url.URL) *backendURL {
+	return &backendURL{
+		url: u,
+		bs:  getBackendState(u.String()),
+	}
+}
+
+func (bu *backendURL) isBroken() bool {
+	return fasttime.UnixTimestamp() < atomic.LoadUint64(&bu.bs.brokenDeadline)
+}
+
+// setBroken excludes bu from load balancing for -failTimeout.
+func (bu *backendURL) setBroken() {
+	bu.bs.setBrokenFor(*failTimeout)
+}
+
+// backendState holds health state and metrics for a backend.
+//
+// The state is shared among all the `url_prefix` entries with the same url, so it is preserved during config reloads.
+type backendState struct {
+	// brokenDeadline is unix timestamp in seconds until the backend is excluded from load balancing.
+	brokenDeadline uint64
+
+	requests *metrics.Counter
+	errors   *metrics.Counter
+}
+
+func (bs *backendState) setBrokenFor(d time.Duration) {
+	deadline := fasttime.UnixTimestamp() + uint64(d.Seconds()+0.5)
+	atomic.StoreUint64(&bs.brokenDeadline, deadline)
+}
+
+func (bs *backendState) setHealthy() {
+	atomic.StoreUint64(&bs.brokenDeadline, 0)
+}
+
+var (
+	backendStatesLock sync.Mutex
+	backendStates     = make(map[string]*backendState)
+)
+
+func getBackendState(u string) *backendState {
+	backendStatesLock.Lock()
+	defer backendStatesLock.Unlock()
+
+	bs := backendStates[u]
+	if bs == nil {
+		bs = &backendState{
+			requests: metrics.GetOrCreateCounter(backendRequestsMetricName(u)),
+			errors:   metrics.GetOrCreateCounter(backendErrorsMetricName(u)),
+		}
+		metrics.GetOrCreateGauge(backendUpMetricName(u), func() float64 {
+			if fasttime.UnixTimestamp() < atomic.LoadUint64(&bs.brokenDeadline) {
+				return 0
+			}
+			return 1
+		})
+		backendStates[u] = bs
+	}
+	return bs
+}
+
+// removeUnusedBackendStates removes states for backends missing in usedURLs and unregisters their metrics.
+//
+// In-flight requests to the removed backends continue using their states.
+func removeUnusedBackendStates(usedURLs map[string]bool) {
+	backendStatesLock.Lock()
+	for u := range backendStates {
+		if usedURLs[u] {
+			continue
+		}
+		delete(backendStates, u)
+		metrics.UnregisterMetric(backendRequestsMetricName(u))
+		metrics.UnregisterMetric(backendErrorsMetricName(u))
+		metrics.UnregisterMetric(backendUpMetricName(u))
+	}
+	backendStatesLock.Unlock()
+}
+
+func backendRequestsMetricName(u string) string {
+	return fmt.Sprintf(`vmauth_backend_requests_total{backend=%q}`, u)
+}
+
+func backendErrorsMetricName(u string) string {
+	return fmt.Sprintf(`vmauth_backend_errors_total{backend=%q}`, u)
+}
+
+func backendUpMetricName(u string) string {
+	return fmt.Sprintf(`vmauth_backend_up{backend=%q}`, u)
+}
+
+// getBackendURL returns the next backend according to the load balancing policy of up.
+//
+// Broken backends are skipped. If all the backends are broken, then they are balanced as if they were healthy.
+func (up *URLPrefix) getBackendURL() *backendURL {
+	bus := up.bus
+	if len(bus) == 1 {
+		return bus[0]
+	}
+	if up.loadBalancingPolicy == "first_available" {
+		for _, bu := range bus {
+			if !bu.isBroken() {
+				return bu
+			}
+		}
+		return bus[0]
+	}
+	n := atomic.AddUint32(&up.n, 1)
+	for i := uint32(0); i < uint32(len(bus)); i++ {
+		bu := bus[(n+i)%uint32(len(bus))]
+		if !bu.isBroken() {
+			return bu
+		}
+	}
+	return bus[n%uint32(len(bus))]
+}
+
+// startHealthChecker starts periodic health checks for backends from the current -auth.config if -healthCheckInterval is set.
+func startHealthChecker() {
+	if *healthCheckInterval <= 0 {
+		return
+	}
+	hc := &http.Client{
+		Timeout: *healthCheckTimeout,
+	}
+	authConfigWG.Add(1)
+	go func() {
+		defer authConfigWG.Done()
+		t := time.NewTicker(*healthCheckInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-t.C:
+				checkBackendsHealth(hc, authConfig.Load().(*authState))
+			}
+		}
+	}()
+}
+
+// checkBackendsHealth probes all the backends from as.
+func checkBackendsHealth(hc *http.Client, as *authState) {
+	seen := make(map[*backendState]bool)
+	var wg sync.WaitGroup
+	for _, ui := range as.getUsers() {
+		for _, up := range ui.getURLPrefixes() {
+			for _, bu := range up.bus {
+				if seen[bu.bs] {
+					continue
+				}
+				seen[bu.bs] = true
+				wg.Add(1)
+				go func(bu *backendURL) {
+					defer wg.Done()
+					checkBackendHealth(hc, bu)
+				}(bu)
+			}
+		}
+	}
+	wg.Wait()
+}
+
+func checkBackendHealth(hc *http.Client, bu *backendURL) {
+	healthURL := url.URL{
+		Scheme: bu.url.Scheme,
+		Host:   bu.url.Host,
+		Path:   "/health",
+	}
+	err := probeURL(hc, healthURL.String())
+	if err == nil {
+		bu.bs.setHealthy()
+		return
+	}
+	logger.Warnf("health check for backend %q failed: %s; excluding it from load balancing", bu.url, err)
+	d := *failTimeout
+	if d < *healthCheckInterval {
+		d = *healthCheckInterval
+	}
+	bu.bs.setBrokenFor(d)
+}
+
+func probeURL(hc *http.Client, u string) error {
+	resp, err := hc.Get(u)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code at %q; got %d; want %d", u, resp.StatusCode, http.StatusOK)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+func TestGetBackendURL(t *testing.T) {
+	f := func(loadBalancingPolicy string, urls []string, brokenIdxs []int, expectedURLs []string) {
+		t.Helper()
+		up := &URLPrefix{}
+		for _, u := range urls {
+			up.urls = append(up.urls, mustParseURL(u).urls[0])
+		}
+		if err := up.sanitize(loadBalancingPolicy); err != nil {
+			t.Fatalf("cannot sanitize url prefix: %s", err)
+		}
+		for _, bu := range up.bus {
+			bu.bs.setHealthy()
+		}
+		for _, idx := range brokenIdxs {
+			up.bus[idx].bs.setBrokenFor(time.Hour)
+		}
+		defer func() {
+			for _, bu := range up.bus {
+				bu.bs.setHealthy()
+			}
+		}()
+		var result []string
+		for range expectedURLs {
+			result = append(result, up.getBackendURL().url.String())
+		}
+		if strings.Join(result, ",") != strings.Join(expectedURLs, ",") {
+			t.Fatalf("unexpected backend urls;\ngot\n%q\nwant\n%q", result, expectedURLs)
+		}
+	}
+
+	// Single backend is always returned
+	f("", []string{"http://lb-single"}, nil, []string{"http://lb-single", "http://lb-single"})
+	f("", []string{"http://lb-single"}, []int{0}, []string{"http://lb-single", "http://lb-single"})
+
+	// Round robin
+	f("", []string{"http://lb-a", "http://lb-b", "http://lb-c"}, nil, []string{"http://lb-b", "http://lb-c", "http://lb-a", "http://lb-b"})
+	f("round_robin", []string{"http://lb-a", "http://lb-b", "http://lb-c"}, []int{1}, []string{"http://lb-c", "http://lb-c", "http://lb-a", "http://lb-c"})
+
+	// All the backends are broken
+	f("", []string{"http://lb-a", "http://lb-b"}, []int{0, 1}, []string{"http://lb-b", "http://lb-a"})
+
+	// First available
+	f("first_available", []string{"http://lb-a", "http://lb-b", "http://lb-c"}, nil, []string{"http://lb-a", "http://lb-a"})
+	f("first_available", []string{"http://lb-a", "http://lb-b", "http://lb-c"}, []int{0}, []string{"http://lb-b", "http://lb-b"})
+	f("first_available", []string{"http://lb-a", "http://lb-b", "http://lb-c"}, []int{0, 1, 2}, []string{"http://lb-a"})
+}
+
+func TestRequestHandlerRetry(t *testing.T) {
+	brokenBackend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	brokenURL := brokenBackend.URL
+	// Close the backend, so connections to it fail.
+	brokenBackend.Close()
+
+	var backendRequests uint64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddUint64(&backendRequests, 1)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("cannot read request body: %s", err)
+		}
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, body)
+	}))
+	defer backend.Close()
+
+	as, err := parseAuthConfig([]byte(fmt.Sprintf(`
+users:
+- username: foo
+  load_balancing_policy: first_available
+  url_prefix: [%q, %q]
+`, brokenURL, backend.URL)))
+	if err != nil {
+		t.Fatalf("cannot parse auth config: %s", err)
+	}
+	authConfig.Store(as)
+	defer authConfig.Store(&authState{})
+
+	f := func(method, path, body string, statusCodeExpected int, responseExpected string) {
+		t.Helper()
+		requestsBefore := atomic.LoadUint64(&backendRequests)
+		r := httptest.NewRequest(method, "http://vmauth"+path, strings.NewReader(body))
+		r.SetBasicAuth("foo", "")
+		w := httptest.NewRecorder()
+		if !requestHandler(w, r) {
+			t.Fatalf("unexpected false returned from requestHandler")
+		}
+		if w.Code != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d; response: %q", w.Code, statusCodeExpected, w.Body.String())
+		}
+		if responseExpected != "" && w.Body.String() != responseExpected {
+			t.Fatalf("unexpected response; got %q; want %q", w.Body.String(), responseExpected)
+		}
+		requests := atomic.LoadUint64(&backendRequests) - requestsBefore
+		if statusCodeExpected == http.StatusOK && requests != 1 {
+			t.Fatalf("unexpected number of requests to the healthy backend; got %d; want 1", requests)
+		}
+		if statusCodeExpected != http.StatusOK && requests != 0 {
+			t.Fatalf("the request mustn't be re-sent to the healthy backend; got %d requests", requests)
+		}
+		// Reset the broken state, so the broken backend is tried first on the next request.
+		for _, bu := range as.byAuthToken[r.Header.Get("Authorization")].URLPrefix.bus {
+			bu.bs.setHealthy()
+		}
+	}
+
+	// Requests must be retried at the healthy backend
+	f(http.MethodGet, "/api/v1/query", "", http.StatusOK, "GET /api/v1/query ")
+	f(http.MethodPost, "/api/v1/query", "query=up", http.StatusOK, "POST /api/v1/query query=up")
+	f(http.MethodPost, "/select/0/prometheus/api/v1/label/job/values", "", http.StatusOK, "POST /select/0/prometheus/api/v1/label/job/values ")
+
+	// Non-idempotent requests mustn't be retried
+	f(http.MethodPut, "/api/v1/query", "query=up", http.StatusBadGateway, "")
+
+	// Data ingestion requests mustn't be retried in order to avoid duplicate data
+	f(http.MethodPost, "/api/v1/write", "foo", http.StatusBadGateway, "")
+	f(http.MethodPost, "/api/v1/import", "foo 1", http.StatusBadGateway, "")
+
+	// Request body, which wasn't read by the broken backend, can be retried regardless of its size
+	body := strings.Repeat("x", maxRequestBodySizeToRetry.N+1)
+	f(http.MethodPost, "/api/v1/query", body, http.StatusOK, "POST /api/v1/query "+body)
+}
+
+func TestRemoveUnusedBackendStates(t *testing.T) {
+	mustParse := func(s string) {
+		t.Helper()
+		if _, err := parseAuthConfig([]byte(s)); err != nil {
+			t.Fatalf("cannot parse auth config: %s", err)
+		}
+	}
+	hasBackendState := func(u string) bool {
+		backendStatesLock.Lock()
+		defer backendStatesLock.Unlock()
+		return backendStates[u] != nil
+	}
+	hasBackendMetric := func(u string) bool {
+		var bb bytes.Buffer
+		metrics.WritePrometheus(&bb, false)
+		return strings.Contains(bb.String(), backendUpMetricName(u))
+	}
+
+	mustParse(`
+users:
+- username: foo
+  url_prefix: [http://backend-a:8428, http://backend-b:8428]
+- username: bar
+  url_map:
+  - src_paths: ["/api/v1/write"]
+    url_prefix: http://backend-c:8480
+`)
+	for _, u := range []string{"http://backend-a:8428", "http://backend-b:8428", "http://backend-c:8480"} {
+		if !hasBackendState(u) {
+			t.Fatalf("missing state for backend %q", u)
+		}
+		if !hasBackendMetric(u) {
+			t.Fatalf("missing metrics for backend %q", u)
+		}
+	}
+
+	// States and metrics for backends removed from the config must be removed
+	mustParse(`
+users:
+- username: foo
+  url_prefix: http://backend-a:8428
+`)
+	if !hasBackendState("http://backend-a:8428") {
+		t.Fatalf("the state for backend-a must be preserved")
+	}
+	for _, u := range []string{"http://backend-b:8428", "http://backend-c:8480"} {
+		if hasBackendState(u) {
+			t.Fatalf("the state for backend %q must be removed", u)
+		}
+		if hasBackendMetric(u) {
+			t.Fatalf("metrics for backend %q must be unregistered", u)
+		}
+	}
+
+	// Invalid config mustn't remove backend states for the current config
+	if _, err := parseAuthConfig([]byte(`
+users:
+- username: foo
+  url_prefix: http://backend-d:8428
+- username: foo
+  url_prefix: http://backend-d:8428
+`)); err == nil {
+		t.Fatalf("expecting non-nil error for duplicate users")
+	}
+	if !hasBackendState("http://backend-a:8428") {
+		t.Fatalf("the state for backend-a must be preserved after invalid config")
+	}
+}
+
+func TestCanRetryRequest(t *testing.T) {
+	f := func(method, path string, resultExpected bool) {
+		t.Helper()
+		result := canRetryRequest(method, path)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %s %s; got %v; want %v", method, path, result, resultExpected)
+		}
+	}
+
+	// GET and HEAD requests can be always retried
+	f(http.MethodGet, "/api/v1/query", true)
+	f(http.MethodGet, "/api/v1/write", true)
+	f(http.MethodHead, "/", true)
+
+	// POST requests can be retried only for query endpoints
+	f(http.MethodPost, "/api/v1/query", true)
+	f(http.MethodPost, "/api/v1/query_range", true)
+	f(http.MethodPost, "/api/v1/series", true)
+	f(http.MethodPost, "/api/v1/labels", true)
+	f(http.MethodPost, "/api/v1/label/job/values", true)
+	f(http.MethodPost, "/federate", true)
+	f(http.MethodPost, "/select/0/prometheus/api/v1/query/", true)
+	f(http.MethodPost, "/api/v1/write", false)
+	f(http.MethodPost, "/api/v1/import", false)
+	f(http.MethodPost, "/api/v1/import/prometheus", false)
+	f(http.MethodPost, "/insert/0/prometheus/api/v1/write", false)
+	f(http.MethodPost, "/influx/write", false)
+	f(http.MethodPost, "/api/v1/label/job", false)
+
+	// Other methods mustn't be retried
+	f(http.MethodPut, "/api/v1/query", false)
+	f(http.MethodDelete, "/api/v1/admin/tsdb/delete_series", false)
+}
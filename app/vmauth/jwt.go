@@ -0,0 +1,591 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var jwksCheckInterval = flag.Duration("auth.jwksCheckInterval", time.Minute, "Interval for re-reading jwks_file from -auth.config. "+
+	"See https://docs.victoriametrics.com/vmauth.html#jwt-authentication")
+
+// JWTConfig contains options for authenticating users via JSON Web Tokens.
+type JWTConfig struct {
+	// PublicKeys contains PEM-encoded public keys for verifying token signatures.
+	PublicKeys []string `yaml:"public_keys,omitempty"`
+
+	// PublicKeyFiles contains paths to files with PEM-encoded public keys.
+	PublicKeyFiles []string `yaml:"public_key_files,omitempty"`
+
+	// JWKSFile is a path or http url to JSON Web Key Set. It is re-read every -auth.jwksCheckInterval.
+	JWKSFile string `yaml:"jwks_file,omitempty"`
+
+	// Issuer must match `iss` claim if set.
+	Issuer string `yaml:"issuer,omitempty"`
+
+	// Audience must be contained in `aud` claim if set.
+	Audience string `yaml:"audience,omitempty"`
+
+	// MatchClaims contains claim values, which must match the token claims.
+	MatchClaims map[string]string `yaml:"match_claims,omitempty"`
+
+	// staticKeys contains keys from PublicKeys and PublicKeyFiles.
+	staticKeys []jwtKey
+
+	// jwksKeys contains []jwtKey from JWKSFile.
+	jwksKeys atomic.Value
+}
+
+// jwtKey is a public key for verifying token signatures.
+type jwtKey struct {
+	// kid is an optional key id. It is matched against `kid` from token header.
+	kid string
+
+	key crypto.PublicKey
+}
+
+func (jc *JWTConfig) init() error {
+	if len(jc.PublicKeys) == 0 && len(jc.PublicKeyFiles) == 0 && jc.JWKSFile == "" {
+		return fmt.Errorf("missing `public_keys`, `public_key_files` or `jwks_file` in `jwt` section")
+	}
+	var keys []jwtKey
+	for _, s := range jc.PublicKeys {
+		key, err := parsePublicKeyPEM([]byte(s))
+		if err != nil {
+			return fmt.Errorf("cannot parse `public_keys` entry: %w", err)
+		}
+		keys = append(keys, jwtKey{key: key})
+	}
+	for _, path := range jc.PublicKeyFiles {
+		data, err := fs.ReadFileOrHTTP(path)
+		if err != nil {
+			return fmt.Errorf("cannot read `public_key_files` entry: %w", err)
+		}
+		key, err := parsePublicKeyPEM(data)
+		if err != nil {
+			return fmt.Errorf("cannot parse public key from %q: %w", path, err)
+		}
+		keys = append(keys, jwtKey{key: key})
+	}
+	jc.staticKeys = keys
+	jc.jwksKeys.Store([]jwtKey(nil))
+	if jc.JWKSFile != "" {
+		if err := jc.reloadJWKS(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reloadJWKS re-reads keys from jc.JWKSFile.
+func (jc *JWTConfig) reloadJWKS() error {
+	data, err := fs.ReadFileOrHTTP(jc.JWKSFile)
+	if err != nil {
+		return fmt.Errorf("cannot read `jwks_file`: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("cannot parse `jwks_file` %q: %w", jc.JWKSFile, err)
+	}
+	jc.jwksKeys.Store(keys)
+	return nil
+}
+
+// getKeys returns keys, which may be used for verifying the token with the given kid.
+func (jc *JWTConfig) getKeys(kid string) []jwtKey {
+	var keys []jwtKey
+	keys = append(keys, jc.staticKeys...)
+	for _, k := range jc.jwksKeys.Load().([]jwtKey) {
+		if kid == "" || k.kid == "" || k.kid == kid {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// verify verifies the signature and the claims for t.
+func (jc *JWTConfig) verify(t *jwtToken) error {
+	var err error
+	verified := false
+	for _, k := range jc.getKeys(t.kid) {
+		if err = verifyJWTSignature(t.alg, k.key, t.signingInput, t.signature); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		if err == nil {
+			err = fmt.Errorf("no keys found for kid=%q", t.kid)
+		}
+		return fmt.Errorf("cannot verify token signature: %w", err)
+	}
+	now := float64(time.Now().Unix())
+	if exp, ok := getNumericClaim(t.claims, "exp"); ok && now >= exp {
+		return fmt.Errorf("the token is expired")
+	}
+	if nbf, ok := getNumericClaim(t.claims, "nbf"); ok && now < nbf {
+		return fmt.Errorf("the token isn't valid yet")
+	}
+	if jc.Issuer != "" {
+		if iss, _ := getClaimValue(t.claims, "iss"); iss != jc.Issuer {
+			return fmt.Errorf("unexpected `iss` claim %q; want %q", iss, jc.Issuer)
+		}
+	}
+	if jc.Audience != "" && !hasAudience(t.claims["aud"], jc.Audience) {
+		return fmt.Errorf("`aud` claim doesn't contain %q", jc.Audience)
+	}
+	for name, value := range jc.MatchClaims {
+		if v, _ := getClaimValue(t.claims, name); v != value {
+			return fmt.Errorf("unexpected %q claim %q; want %q", name, v, value)
+		}
+	}
+	return nil
+}
+
+// getJWTUser returns the user from as.jwtUsers matching the given bearer token.
+//
+// nil user and nil error are returned if the token isn't a JWT.
+func (as *authState) getJWTUser(token string) (*UserInfo, map[string]interface{}, error) {
+	t, err := parseJWT(token)
+	if err != nil {
+		return nil, nil, nil
+	}
+	for _, ui := range as.jwtUsers {
+		err = ui.JWT.verify(t)
+		if err == nil {
+			return ui, t.claims, nil
+		}
+	}
+	return nil, nil, err
+}
+
+// jwtToken is a parsed JSON Web Token.
+type jwtToken struct {
+	alg          string
+	kid          string
+	claims       map[string]interface{}
+	signingInput []byte
+	signature    []byte
+}
+
+func parseJWT(s string) (*jwtToken, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("unexpected number of parts in JWT; got %d; want 3", len(parts))
+	}
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode JWT header: %w", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerData, &header); err != nil {
+		return nil, fmt.Errorf("cannot parse JWT header: %w", err)
+	}
+	claimsData, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode JWT claims: %w", err)
+	}
+	var claims map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(claimsData))
+	d.UseNumber()
+	if err := d.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("cannot parse JWT claims: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode JWT signature: %w", err)
+	}
+	return &jwtToken{
+		alg:          header.Alg,
+		kid:          header.Kid,
+		claims:       claims,
+		signingInput: []byte(s[:len(parts[0])+1+len(parts[1])]),
+		signature:    signature,
+	}, nil
+}
+
+func verifyJWTSignature(alg string, key crypto.PublicKey, signingInput, signature []byte) error {
+	switch alg {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		pk, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("unexpected key type for alg=%q: %T", alg, key)
+		}
+		h, digest := getJWTDigest(alg[2:], signingInput)
+		if alg[0] == 'P' {
+			return rsa.VerifyPSS(pk, h, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(pk, h, digest, signature)
+	case "ES256", "ES384", "ES512":
+		pk, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("unexpected key type for alg=%q: %T", alg, key)
+		}
+		keySize := (pk.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*keySize {
+			return fmt.Errorf("unexpected signature length for alg=%q; got %d; want %d", alg, len(signature), 2*keySize)
+		}
+		_, digest := getJWTDigest(alg[2:], signingInput)
+		r := new(big.Int).SetBytes(signature[:keySize])
+		s := new(big.Int).SetBytes(signature[keySize:])
+		if !ecdsa.Verify(pk, digest, r, s) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	case "EdDSA":
+		pk, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("unexpected key type for alg=%q: %T", alg, key)
+		}
+		if !ed25519.Verify(pk, signingInput, signature) {
+			return fmt.Errorf("invalid signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported alg=%q; supported algorithms: RS256, RS384, RS512, PS256, PS384, PS512, ES256, ES384, ES512, EdDSA", alg)
+	}
+}
+
+func getJWTDigest(bits string, data []byte) (crypto.Hash, []byte) {
+	switch bits {
+	case "384":
+		h := sha512.Sum384(data)
+		return crypto.SHA384, h[:]
+	case "512":
+		h := sha512.Sum512(data)
+		return crypto.SHA512, h[:]
+	default:
+		h := sha256.Sum256(data)
+		return crypto.SHA256, h[:]
+	}
+}
+
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("cannot find PEM block with public key")
+	}
+	var key crypto.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		k, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	case "RSA PUBLIC KEY":
+		k, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = k
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key = cert.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q; supported types: PUBLIC KEY, RSA PUBLIC KEY, CERTIFICATE", block.Type)
+	}
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+}
+
+// parseJWKS parses JSON Web Key Set from data.
+//
+// See https://datatracker.ietf.org/doc/html/rfc7517
+func parseJWKS(data []byte) ([]jwtKey, error) {
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, err
+	}
+	var keys []jwtKey
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var key crypto.PublicKey
+		switch k.Kty {
+		case "RSA":
+			n, err := decodeJWKSBigInt(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse `n` for kid=%q: %w", k.Kid, err)
+			}
+			e, err := decodeJWKSBigInt(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse `e` for kid=%q: %w", k.Kid, err)
+			}
+			if !e.IsInt64() || e.Int64() > 1<<31-1 {
+				return nil, fmt.Errorf("too big `e` for kid=%q", k.Kid)
+			}
+			key = &rsa.PublicKey{
+				N: n,
+				E: int(e.Int64()),
+			}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				return nil, fmt.Errorf("unsupported `crv` %q for kid=%q", k.Crv, k.Kid)
+			}
+			x, err := decodeJWKSBigInt(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse `x` for kid=%q: %w", k.Kid, err)
+			}
+			y, err := decodeJWKSBigInt(k.Y)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse `y` for kid=%q: %w", k.Kid, err)
+			}
+			if !curve.IsOnCurve(x, y) {
+				return nil, fmt.Errorf("invalid EC key for kid=%q", k.Kid)
+			}
+			key = &ecdsa.PublicKey{
+				Curve: curve,
+				X:     x,
+				Y:     y,
+			}
+		case "OKP":
+			if k.Crv != "Ed25519" {
+				return nil, fmt.Errorf("unsupported `crv` %q for kid=%q", k.Crv, k.Kid)
+			}
+			x, err := base64.RawURLEncoding.DecodeString(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse `x` for kid=%q: %w", k.Kid, err)
+			}
+			if len(x) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("unexpected `x` length for kid=%q; got %d; want %d", k.Kid, len(x), ed25519.PublicKeySize)
+			}
+			key = ed25519.PublicKey(x)
+		default:
+			// Skip unsupported key types such as symmetric keys.
+			continue
+		}
+		keys = append(keys, jwtKey{
+			kid: k.Kid,
+			key: key,
+		})
+	}
+	return keys, nil
+}
+
+func decodeJWKSBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func getNumericClaim(claims map[string]interface{}, name string) (float64, bool) {
+	n, ok := claims[name].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+func hasAudience(aud interface{}, audience string) bool {
+	switch x := aud.(type) {
+	case string:
+		return x == audience
+	case []interface{}:
+		for _, a := range x {
+			if s, ok := a.(string); ok && s == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getClaimValue returns string value for the claim with the given name.
+//
+// Nested claims may be referred via dots. For example, `vm_access.tenant`.
+func getClaimValue(claims map[string]interface{}, name string) (string, bool) {
+	var v interface{} = claims
+	for _, part := range strings.Split(name, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		v, ok = m[part]
+		if !ok {
+			return "", false
+		}
+	}
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case json.Number:
+		return x.String(), true
+	case bool:
+		if x {
+			return "true", true
+		}
+		return "false", true
+	default:
+		return "", false
+	}
+}
+
+// claimPlaceholderRe matches `{{claim_name}}` placeholders.
+var claimPlaceholderRe = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+func hasClaimPlaceholders(s string) bool {
+	return claimPlaceholderRe.MatchString(s)
+}
+
+// replaceClaimPlaceholders replaces `{{claim_name}}` placeholders in s with the corresponding claim values passed via escape func.
+func replaceClaimPlaceholders(s string, claims map[string]interface{}, escape func(s string) (string, error)) (string, error) {
+	var err error
+	result := claimPlaceholderRe.ReplaceAllStringFunc(s, func(placeholder string) string {
+		if err != nil {
+			return ""
+		}
+		name := claimPlaceholderRe.FindStringSubmatch(placeholder)[1]
+		v, ok := getClaimValue(claims, name)
+		if !ok {
+			err = fmt.Errorf("missing string claim %q in the token", name)
+			return ""
+		}
+		v, err = escape(v)
+		if err != nil {
+			err = fmt.Errorf("invalid value for claim %q: %w", name, err)
+		}
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// applyClaimsToURL returns a copy of u with `{{claim_name}}` placeholders in path and query args replaced by claims values.
+func applyClaimsToURL(u *url.URL, claims map[string]interface{}) (*url.URL, error) {
+	if claims == nil || (!hasClaimPlaceholders(u.Path) && !hasClaimPlaceholders(u.RawQuery)) {
+		return u, nil
+	}
+	uCopy := *u
+	path, err := replaceClaimPlaceholders(u.Path, claims, func(s string) (string, error) {
+		// Prevent from escaping the path prefix via claim values.
+		if strings.Contains(s, "/") || s == "." || s == ".." {
+			return "", fmt.Errorf("the value %q cannot be used in url path", s)
+		}
+		return s, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	uCopy.Path = path
+	uCopy.RawPath = ""
+	rawQuery, err := replaceClaimPlaceholders(u.RawQuery, claims, func(s string) (string, error) {
+		return url.QueryEscape(s), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	uCopy.RawQuery = rawQuery
+	return &uCopy, nil
+}
+
+// applyClaimsToHeaders returns headers with `{{claim_name}}` placeholders replaced by claims values.
+func applyClaimsToHeaders(headers []Header, claims map[string]interface{}) ([]Header, error) {
+	if claims == nil {
+		return headers, nil
+	}
+	result := make([]Header, len(headers))
+	for i, h := range headers {
+		value, err := replaceClaimPlaceholders(h.Value, claims, func(s string) (string, error) {
+			if strings.ContainsAny(s, "\r\n") {
+				return "", fmt.Errorf("the value %q cannot be used in http header", s)
+			}
+			return s, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		result[i] = Header{
+			Name:  h.Name,
+			Value: value,
+		}
+	}
+	return result, nil
+}
+
+// startJWKSReloader starts periodic re-reading of `jwks_file` for users from the current -auth.config.
+func startJWKSReloader() {
+	authConfigWG.Add(1)
+	go func() {
+		defer authConfigWG.Done()
+		t := time.NewTicker(*jwksCheckInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-t.C:
+				as := authConfig.Load().(*authState)
+				for _, ui := range as.jwtUsers {
+					if ui.JWT.JWKSFile == "" {
+						continue
+					}
+					if err := ui.JWT.reloadJWKS(); err != nil {
+						logger.Errorf("cannot reload `jwks_file`; using the last successfully loaded keys; error: %s", err)
+					}
+				}
+			}
+		}
+	}()
+}
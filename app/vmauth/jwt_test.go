@@ -0,0 +1,445 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signJWT(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{
+		"alg": alg,
+		"typ": "JWT",
+	}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("cannot marshal header: %s", err)
+	}
+	claimsData, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("cannot marshal claims: %s", err)
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(headerData) + "." + base64.RawURLEncoding.EncodeToString(claimsData)
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		h := sha256.Sum256([]byte(signingInput))
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, h[:])
+	case *ecdsa.PrivateKey:
+		h := sha256.Sum256([]byte(signingInput))
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, h[:])
+		if err == nil {
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			s.FillBytes(signature[32:])
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(signingInput))
+	default:
+		t.Fatalf("unsupported key type %T", key)
+	}
+	if err != nil {
+		t.Fatalf("cannot sign token: %s", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func marshalPublicKeyPEM(t *testing.T, key crypto.PublicKey) string {
+	t.Helper()
+	data, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatalf("cannot marshal public key: %s", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: data,
+	}))
+}
+
+func TestJWTConfigVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate RSA key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate EC key: %s", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate Ed25519 key: %s", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate RSA key: %s", err)
+	}
+
+	jc := &JWTConfig{
+		PublicKeys: []string{
+			marshalPublicKeyPEM(t, rsaKey.Public()),
+			marshalPublicKeyPEM(t, ecKey.Public()),
+			marshalPublicKeyPEM(t, edKey.Public()),
+		},
+		Issuer:   "https://sso.example.com",
+		Audience: "vmauth",
+		MatchClaims: map[string]string{
+			"vm_access.role": "reader",
+		},
+	}
+	if err := jc.init(); err != nil {
+		t.Fatalf("cannot init jwt config: %s", err)
+	}
+
+	now := time.Now().Unix()
+	validClaims := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss": "https://sso.example.com",
+			"aud": []string{"grafana", "vmauth"},
+			"exp": now + 3600,
+			"vm_access": map[string]interface{}{
+				"role": "reader",
+			},
+		}
+	}
+	f := func(token string, isValid bool) {
+		t.Helper()
+		jt, err := parseJWT(token)
+		if err != nil {
+			t.Fatalf("cannot parse token: %s", err)
+		}
+		err = jc.verify(jt)
+		if isValid && err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !isValid && err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// Valid tokens
+	f(signJWT(t, "RS256", "", rsaKey, validClaims()), true)
+	f(signJWT(t, "ES256", "", ecKey, validClaims()), true)
+	f(signJWT(t, "EdDSA", "", edKey, validClaims()), true)
+
+	// Unknown signing key
+	f(signJWT(t, "RS256", "", otherKey, validClaims()), false)
+
+	// Algorithm mismatch
+	f(signJWT(t, "ES256", "", rsaKey, validClaims()), false)
+
+	// Tampered token
+	token := signJWT(t, "RS256", "", rsaKey, validClaims())
+	parts := strings.Split(token, ".")
+	tamperedClaims := validClaims()
+	tamperedClaims["vm_access"] = map[string]interface{}{
+		"role": "admin",
+	}
+	claimsData, _ := json.Marshal(tamperedClaims)
+	f(parts[0]+"."+base64.RawURLEncoding.EncodeToString(claimsData)+"."+parts[2], false)
+
+	// Unsigned token
+	f(parts[0]+"."+parts[1]+".", false)
+
+	// Expired token
+	claims := validClaims()
+	claims["exp"] = now - 10
+	f(signJWT(t, "RS256", "", rsaKey, claims), false)
+
+	// Token isn't valid yet
+	claims = validClaims()
+	claims["nbf"] = now + 3600
+	f(signJWT(t, "RS256", "", rsaKey, claims), false)
+
+	// Issuer mismatch
+	claims = validClaims()
+	claims["iss"] = "https://evil.example.com"
+	f(signJWT(t, "RS256", "", rsaKey, claims), false)
+
+	// Audience mismatch
+	claims = validClaims()
+	claims["aud"] = "grafana"
+	f(signJWT(t, "RS256", "", rsaKey, claims), false)
+
+	// Claim mismatch
+	claims = validClaims()
+	delete(claims, "vm_access")
+	f(signJWT(t, "RS256", "", rsaKey, claims), false)
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate RSA key: %s", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("cannot generate EC key: %s", err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := fmt.Sprintf(`{"keys":[
+{"kty":"RSA","kid":"rsa1","use":"sig","alg":"RS256","n":%q,"e":%q},
+{"kty":"EC","kid":"ec1","crv":"P-256","x":%q,"y":%q},
+{"kty":"RSA","kid":"enc1","use":"enc","n":%q,"e":%q},
+{"kty":"oct","kid":"hmac1","k":"c2VjcmV0"}
+]}`, b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		b64(ecKey.X.FillBytes(make([]byte, 32))), b64(ecKey.Y.FillBytes(make([]byte, 32))),
+		b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()))
+
+	tmpDir, err := ioutil.TempDir("", "vmauth-jwks")
+	if err != nil {
+		t.Fatalf("cannot create temporary dir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+	jwksPath := filepath.Join(tmpDir, "jwks.json")
+	if err := ioutil.WriteFile(jwksPath, []byte(jwks), 0644); err != nil {
+		t.Fatalf("cannot write jwks file: %s", err)
+	}
+	jc := &JWTConfig{
+		JWKSFile: jwksPath,
+	}
+	if err := jc.init(); err != nil {
+		t.Fatalf("cannot init jwt config: %s", err)
+	}
+	keys := jc.jwksKeys.Load().([]jwtKey)
+	if len(keys) != 2 {
+		t.Fatalf("unexpected number of keys; got %d; want 2", len(keys))
+	}
+	f := func(token string, isValid bool) {
+		t.Helper()
+		jt, err := parseJWT(token)
+		if err != nil {
+			t.Fatalf("cannot parse token: %s", err)
+		}
+		err = jc.verify(jt)
+		if isValid && err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !isValid && err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	claims := map[string]interface{}{
+		"sub": "foo",
+	}
+	f(signJWT(t, "RS256", "rsa1", rsaKey, claims), true)
+	f(signJWT(t, "ES256", "ec1", ecKey, claims), true)
+	f(signJWT(t, "ES256", "", ecKey, claims), true)
+
+	// kid mismatch
+	f(signJWT(t, "RS256", "ec1", rsaKey, claims), false)
+	f(signJWT(t, "RS256", "unknown", rsaKey, claims), false)
+
+	// The key is removed from jwks file after reload
+	if err := ioutil.WriteFile(jwksPath, []byte(`{"keys":[]}`), 0644); err != nil {
+		t.Fatalf("cannot write jwks file: %s", err)
+	}
+	if err := jc.reloadJWKS(); err != nil {
+		t.Fatalf("cannot reload jwks: %s", err)
+	}
+	f(signJWT(t, "RS256", "rsa1", rsaKey, claims), false)
+}
+
+func TestApplyClaimsToURL(t *testing.T) {
+	claims := map[string]interface{}{
+		"team":      "dev",
+		"tenant_id": json.Number("42"),
+		"path":      "../admin",
+		"query":     "a&b=c",
+		"nested": map[string]interface{}{
+			"project": "foo",
+		},
+	}
+	f := func(urlPrefix, resultExpected string) {
+		t.Helper()
+		u := mustParseURL(urlPrefix).urls[0]
+		result, err := applyClaimsToURL(u, claims)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if s := result.String(); s != resultExpected {
+			t.Fatalf("unexpected url; got %q; want %q", s, resultExpected)
+		}
+	}
+	f("http://vmselect:8481/select/0/prometheus", "http://vmselect:8481/select/0/prometheus")
+	f("http://vmselect:8481/select/{{tenant_id}}/prometheus", "http://vmselect:8481/select/42/prometheus")
+	f("http://vmselect:8481/select/{{ nested.project }}/prometheus", "http://vmselect:8481/select/foo/prometheus")
+	f("http://vmsingle:8428?extra_label=team={{team}}", "http://vmsingle:8428?extra_label=team=dev")
+	f("http://vmsingle:8428?extra_label=team={{query}}", "http://vmsingle:8428?extra_label=team=a%26b%3Dc")
+
+	fFailure := func(urlPrefix string) {
+		t.Helper()
+		u := mustParseURL(urlPrefix).urls[0]
+		if _, err := applyClaimsToURL(u, claims); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// Missing claim
+	fFailure("http://vmselect:8481/select/{{missing}}/prometheus")
+
+	// Non-string claim
+	fFailure("http://vmselect:8481/select/{{nested}}/prometheus")
+
+	// Path traversal
+	fFailure("http://vmselect:8481/select/{{path}}/prometheus")
+}
+
+func TestParseAuthConfigJWTNames(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate RSA key: %s", err)
+	}
+	publicKey := strings.ReplaceAll(marshalPublicKeyPEM(t, rsaKey.Public()), "\n", "\n      ")
+
+	f := func(names []string, okExpected bool) {
+		t.Helper()
+		var sb strings.Builder
+		sb.WriteString("users:\n")
+		for _, name := range names {
+			fmt.Fprintf(&sb, `
+- name: %q
+  jwt:
+    public_keys:
+    - |
+      %s
+  url_prefix: http://foo.bar
+`, name, publicKey)
+		}
+		as, err := parseAuthConfig([]byte(sb.String()))
+		if !okExpected {
+			if err == nil {
+				t.Fatalf("expecting non-nil error for names %q", names)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("cannot parse auth config: %s", err)
+		}
+		limiters := make(map[*userLimiter]bool)
+		for _, ui := range as.jwtUsers {
+			limiters[ui.limiter] = true
+		}
+		if len(limiters) != len(names) {
+			t.Fatalf("jwt users mustn't share limiters; got %d limiters for %d users", len(limiters), len(names))
+		}
+	}
+
+	f([]string{"foo"}, true)
+	f([]string{"foo", "bar"}, true)
+
+	// Missing name
+	f([]string{""}, false)
+	f([]string{"foo", ""}, false)
+
+	// Duplicate names
+	f([]string{"foo", "foo"}, false)
+}
+
+func TestRequestHandlerJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate RSA key: %s", err)
+	}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s", r.URL.RequestURI(), r.Header.Get("X-Scope-OrgID"))
+	}))
+	defer backend.Close()
+
+	publicKey := strings.ReplaceAll(marshalPublicKeyPEM(t, rsaKey.Public()), "\n", "\n      ")
+	as, err := parseAuthConfig([]byte(fmt.Sprintf(`
+users:
+- bearer_token: static-token
+  url_prefix: %q
+- name: admin
+  jwt:
+    public_keys:
+    - |
+      %s
+    match_claims:
+      role: admin
+  url_prefix: %q
+- name: team
+  jwt:
+    public_keys:
+    - |
+      %s
+  url_prefix: "%s?extra_label=team={{team}}"
+  headers:
+  - "X-Scope-OrgID: {{org}}"
+`, backend.URL+"/static", publicKey, backend.URL+"/admin", publicKey, backend.URL)))
+	if err != nil {
+		t.Fatalf("cannot parse auth config: %s", err)
+	}
+	authConfig.Store(as)
+	defer authConfig.Store(&authState{})
+
+	f := func(authToken string, statusCodeExpected int, responseExpected string) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, "http://vmauth/api/v1/query?query=up", nil)
+		r.Header.Set("Authorization", "Bearer "+authToken)
+		w := httptest.NewRecorder()
+		if !requestHandler(w, r) {
+			t.Fatalf("unexpected false returned from requestHandler")
+		}
+		if w.Code != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d; response: %q", w.Code, statusCodeExpected, w.Body.String())
+		}
+		if responseExpected != "" && w.Body.String() != responseExpected {
+			t.Fatalf("unexpected response; got %q; want %q", w.Body.String(), responseExpected)
+		}
+	}
+
+	// Static bearer token
+	f("static-token", http.StatusOK, "/static/api/v1/query?query=up ")
+
+	// The first user with matching claims must be used
+	f(signJWT(t, "RS256", "", rsaKey, map[string]interface{}{
+		"role": "admin",
+	}), http.StatusOK, "/admin/api/v1/query?query=up ")
+
+	// Claims must be substituted into url_prefix and headers
+	f(signJWT(t, "RS256", "", rsaKey, map[string]interface{}{
+		"team": "dev",
+		"org":  "org-1",
+	}), http.StatusOK, "/api/v1/query?extra_label=team%3Ddev&query=up org-1")
+
+	// Missing claim for placeholder
+	f(signJWT(t, "RS256", "", rsaKey, map[string]interface{}{
+		"team": "dev",
+	}), http.StatusBadRequest, "")
+
+	// Expired token
+	f(signJWT(t, "RS256", "", rsaKey, map[string]interface{}{
+		"team": "dev",
+		"org":  "org-1",
+		"exp":  time.Now().Unix() - 10,
+	}), http.StatusUnauthorized, "")
+
+	// Unknown token
+	f("unknown-token", http.StatusBadRequest, "")
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	maxConcurrentPerUserRequests = flag.Int("maxConcurrentPerUserRequests", 0, "The default maximum number of concurrent requests per user. "+
+		"It can be overridden via max_concurrent_requests option in -auth.config. There is no limit if it is set to 0. See https://docs.victoriametrics.com/vmauth.html#rate-limiting")
+	maxRequestsPerSecondPerUser = flag.Float64("maxRequestsPerSecondPerUser", 0, "The default maximum number of requests per second per user. "+
+		"It can be overridden via max_requests_per_second option in -auth.config. There is no limit if it is set to 0. See https://docs.victoriametrics.com/vmauth.html#rate-limiting")
+	maxBytesPerSecondPerUser = flagutil.NewBytes("maxBytesPerSecondPerUser", 0, "The default maximum traffic in bytes per second per user, including request and response bodies. "+
+		"It can be overridden via max_bytes_per_second option in -auth.config. There is no limit if it is set to 0. See https://docs.victoriametrics.com/vmauth.html#rate-limiting")
+	maxQueueDuration = flag.Duration("maxQueueDuration", 10*time.Second, "The maximum duration the request waits in the queue when per-user limits are reached. "+
+		"Requests are rejected with 429 Too Many Requests status code after the timeout. See https://docs.victoriametrics.com/vmauth.html#rate-limiting")
+)
+
+// userLimiter limits the number of concurrent requests, the rate of requests and the traffic for a single user.
+//
+// The limits may be updated in place via setLimits, so the state of in-flight requests is preserved across -auth.config reloads.
+type userLimiter struct {
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// maxConcurrentRequests is the maximum number of concurrent requests. There is no limit if it is set to 0.
+	maxConcurrentRequests int
+
+	// concurrentRequests is the number of requests in flight.
+	concurrentRequests int
+
+	// slotReleasedCh is closed when a request slot is released or when maxConcurrentRequests is updated.
+	// It is nil if there are no requests waiting for a free slot.
+	slotReleasedCh chan struct{}
+
+	// requestsLimiter limits the rate of requests.
+	requestsLimiter *rateLimiter
+
+	// bytesLimiter limits the traffic.
+	bytesLimiter *rateLimiter
+
+	concurrencyLimitReached *metrics.Counter
+	concurrencyLimitTimeout *metrics.Counter
+	rateLimitReached        *metrics.Counter
+	rateLimitTimeout        *metrics.Counter
+}
+
+func newUserLimiter(name string, maxConcurrentRequests int, maxRequestsPerSecond float64, maxBytesPerSecond int) *userLimiter {
+	return &userLimiter{
+		maxConcurrentRequests: maxConcurrentRequests,
+		requestsLimiter:       newRateLimiter(maxRequestsPerSecond),
+		bytesLimiter:          newRateLimiter(float64(maxBytesPerSecond)),
+
+		concurrencyLimitReached: metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_concurrent_requests_limit_reached_total{username=%q}`, name)),
+		concurrencyLimitTimeout: metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_requests_rejected_total{username=%q,reason="concurrency_limit"}`, name)),
+		rateLimitReached:        metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_rate_limit_reached_total{username=%q}`, name)),
+		rateLimitTimeout:        metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_requests_rejected_total{username=%q,reason="rate_limit"}`, name)),
+	}
+}
+
+var (
+	userLimitersLock sync.Mutex
+	userLimiters     = make(map[string]*userLimiter)
+)
+
+// getUserLimiter returns limiter for the given key. The name is used in metric labels for the limiter.
+//
+// The limiter is re-used across -auth.config reloads, so in-flight requests and rate limiting state aren't lost on reload.
+// The limits of the re-used limiter are updated in place.
+func getUserLimiter(key, name string, maxConcurrentRequests int, maxRequestsPerSecond float64, maxBytesPerSecond int) *userLimiter {
+	userLimitersLock.Lock()
+	defer userLimitersLock.Unlock()
+
+	ul := userLimiters[key]
+	if ul == nil {
+		ul = newUserLimiter(name, maxConcurrentRequests, maxRequestsPerSecond, maxBytesPerSecond)
+		userLimiters[key] = ul
+		return ul
+	}
+	ul.setLimits(maxConcurrentRequests, maxRequestsPerSecond, maxBytesPerSecond)
+	return ul
+}
+
+// removeUnusedUserLimiters removes limiters with keys missing in usedKeys from the cache.
+//
+// In-flight requests for the removed users continue using their limiters.
+func removeUnusedUserLimiters(usedKeys map[string]bool) {
+	userLimitersLock.Lock()
+	for key := range userLimiters {
+		if !usedKeys[key] {
+			delete(userLimiters, key)
+		}
+	}
+	userLimitersLock.Unlock()
+}
+
+// setLimits updates ul limits in place.
+func (ul *userLimiter) setLimits(maxConcurrentRequests int, maxRequestsPerSecond float64, maxBytesPerSecond int) {
+	ul.mu.Lock()
+	if ul.maxConcurrentRequests != maxConcurrentRequests {
+		ul.maxConcurrentRequests = maxConcurrentRequests
+		// Wake up the waiting requests, since the new limit may allow them to proceed.
+		ul.notifySlotReleasedLocked()
+	}
+	ul.mu.Unlock()
+
+	ul.requestsLimiter.setLimit(maxRequestsPerSecond)
+	ul.bytesLimiter.setLimit(float64(maxBytesPerSecond))
+}
+
+// beginRequest must be called before proxying the request for the user.
+//
+// It waits for up to -maxQueueDuration until the request fits the user limits.
+// If nil error is returned, then endRequest must be called after the request is proxied.
+func (ul *userLimiter) beginRequest() error {
+	if !ul.requestsLimiter.tryWait(1, 0) {
+		ul.rateLimitReached.Inc()
+		if !ul.requestsLimiter.tryWait(1, *maxQueueDuration) {
+			ul.rateLimitTimeout.Inc()
+			return &httpserver.ErrorWithStatusCode{
+				Err: fmt.Errorf("cannot handle more than %g requests per second during %s; possible solutions: "+
+					"increase `max_requests_per_second` for the user in -auth.config, increase -maxQueueDuration", ul.requestsLimiter.getLimit(), *maxQueueDuration),
+				StatusCode: http.StatusTooManyRequests,
+			}
+		}
+	}
+	if !ul.bytesLimiter.tryWait(0, 0) {
+		// The user exceeded the traffic limit with the previous requests.
+		ul.rateLimitReached.Inc()
+		if !ul.bytesLimiter.tryWait(0, *maxQueueDuration) {
+			ul.rateLimitTimeout.Inc()
+			return &httpserver.ErrorWithStatusCode{
+				Err: fmt.Errorf("cannot handle more than %.0f bytes per second during %s; possible solutions: "+
+					"increase `max_bytes_per_second` for the user in -auth.config, increase -maxQueueDuration", ul.bytesLimiter.getLimit(), *maxQueueDuration),
+				StatusCode: http.StatusTooManyRequests,
+			}
+		}
+	}
+	ch := ul.tryAcquireSlot()
+	if ch == nil {
+		return nil
+	}
+
+	// All the request slots for the user are busy.
+	// Wait for up to *maxQueueDuration.
+	ul.concurrencyLimitReached.Inc()
+	t := timerpool.Get(*maxQueueDuration)
+	defer timerpool.Put(t)
+	for {
+		select {
+		case <-ch:
+		case <-t.C:
+			ul.concurrencyLimitTimeout.Inc()
+			return &httpserver.ErrorWithStatusCode{
+				Err: fmt.Errorf("cannot handle more than %d concurrent requests during %s; possible solutions: "+
+					"increase `max_concurrent_requests` for the user in -auth.config, increase -maxQueueDuration", ul.getMaxConcurrentRequests(), *maxQueueDuration),
+				StatusCode: http.StatusTooManyRequests,
+			}
+		}
+		ch = ul.tryAcquireSlot()
+		if ch == nil {
+			return nil
+		}
+	}
+}
+
+// tryAcquireSlot tries acquiring a request slot.
+//
+// It returns nil on success. Otherwise it returns a channel, which is closed when a slot may become available.
+func (ul *userLimiter) tryAcquireSlot() <-chan struct{} {
+	ul.mu.Lock()
+	defer ul.mu.Unlock()
+
+	if ul.maxConcurrentRequests <= 0 || ul.concurrentRequests < ul.maxConcurrentRequests {
+		ul.concurrentRequests++
+		return nil
+	}
+	if ul.slotReleasedCh == nil {
+		ul.slotReleasedCh = make(chan struct{})
+	}
+	return ul.slotReleasedCh
+}
+
+func (ul *userLimiter) notifySlotReleasedLocked() {
+	if ul.slotReleasedCh != nil {
+		close(ul.slotReleasedCh)
+		ul.slotReleasedCh = nil
+	}
+}
+
+func (ul *userLimiter) getMaxConcurrentRequests() int {
+	ul.mu.Lock()
+	n := ul.maxConcurrentRequests
+	ul.mu.Unlock()
+	return n
+}
+
+// endRequest must be called after the request started with successful beginRequest call is proxied.
+func (ul *userLimiter) endRequest() {
+	ul.mu.Lock()
+	ul.concurrentRequests--
+	ul.notifySlotReleasedLocked()
+	ul.mu.Unlock()
+}
+
+// limitTraffic wraps w and r.Body, so they are throttled according to the user traffic limit.
+//
+// The traffic isn't throttled if there is no traffic limit at the time of the call.
+func (ul *userLimiter) limitTraffic(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if ul.bytesLimiter.getLimit() <= 0 {
+		return w
+	}
+	if r.Body != nil {
+		r.Body = &limitedReadCloser{
+			rc: r.Body,
+			rl: ul.bytesLimiter,
+		}
+	}
+	return &limitedResponseWriter{
+		ResponseWriter: w,
+		rl:             ul.bytesLimiter,
+	}
+}
+
+type limitedReadCloser struct {
+	rc io.ReadCloser
+	rl *rateLimiter
+}
+
+// Read implements io.Reader interface.
+func (lrc *limitedReadCloser) Read(p []byte) (int, error) {
+	n, err := lrc.rc.Read(p)
+	lrc.rl.wait(n)
+	return n, err
+}
+
+// Close implements io.Closer interface.
+func (lrc *limitedReadCloser) Close() error {
+	return lrc.rc.Close()
+}
+
+type limitedResponseWriter struct {
+	http.ResponseWriter
+	rl *rateLimiter
+}
+
+// Write implements io.Writer interface.
+func (lrw *limitedResponseWriter) Write(p []byte) (int, error) {
+	lrw.rl.wait(len(p))
+	return lrw.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher interface.
+func (lrw *limitedResponseWriter) Flush() {
+	if f, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// rateLimiter limits the rate of events with up to one second burst.
+//
+// There is no limit if perSecondLimit is set to 0.
+type rateLimiter struct {
+	// mu protects perSecondLimit, budget and lastTime.
+	mu sync.Mutex
+
+	perSecondLimit float64
+
+	// budget is the number of events, which may be registered without waiting.
+	// It may become negative, when events are registered ahead of time.
+	budget float64
+
+	// lastTime is the last time budget was updated.
+	lastTime time.Time
+}
+
+func newRateLimiter(perSecondLimit float64) *rateLimiter {
+	return &rateLimiter{
+		perSecondLimit: perSecondLimit,
+		budget:         perSecondLimit,
+		lastTime:       time.Now(),
+	}
+}
+
+// setLimit updates the limit for rl.
+//
+// The accumulated budget is preserved, so the limit cannot be bypassed by reloading the config.
+func (rl *rateLimiter) setLimit(perSecondLimit float64) {
+	rl.mu.Lock()
+	if rl.perSecondLimit <= 0 {
+		// The limit wasn't enforced before, so start with the full budget.
+		rl.budget = perSecondLimit
+		rl.lastTime = time.Now()
+	}
+	rl.perSecondLimit = perSecondLimit
+	rl.mu.Unlock()
+}
+
+func (rl *rateLimiter) getLimit() float64 {
+	rl.mu.Lock()
+	n := rl.perSecondLimit
+	rl.mu.Unlock()
+	return n
+}
+
+// reserve registers n events and returns the duration to wait before the events fit the limit.
+//
+// Events aren't registered if the returned duration exceeds maxWait.
+func (rl *rateLimiter) reserve(n float64, maxWait time.Duration) (time.Duration, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if rl.perSecondLimit <= 0 {
+		return 0, true
+	}
+	now := time.Now()
+	rl.budget += now.Sub(rl.lastTime).Seconds() * rl.perSecondLimit
+	if rl.budget > rl.perSecondLimit {
+		rl.budget = rl.perSecondLimit
+	}
+	rl.lastTime = now
+	if rl.budget >= n {
+		rl.budget -= n
+		return 0, true
+	}
+	d := time.Duration((n - rl.budget) / rl.perSecondLimit * float64(time.Second))
+	if d > maxWait {
+		return d, false
+	}
+	rl.budget -= n
+	return d, true
+}
+
+// tryWait waits until n events fit the limit and registers them.
+//
+// It returns false without waiting if the events cannot be registered during maxWait.
+func (rl *rateLimiter) tryWait(n float64, maxWait time.Duration) bool {
+	d, ok := rl.reserve(n, maxWait)
+	if !ok {
+		return false
+	}
+	sleep(d)
+	return true
+}
+
+// wait registers n events and waits until they fit the limit.
+func (rl *rateLimiter) wait(n int) {
+	d, _ := rl.reserve(float64(n), math.MaxInt64)
+	sleep(d)
+}
+
+func sleep(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t := timerpool.Get(d)
+	<-t.C
+	timerpool.Put(t)
+}
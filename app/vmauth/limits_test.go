@@ -0,0 +1,199 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	rl := newRateLimiter(10)
+	f := func(n float64, maxWait time.Duration, okExpected bool) {
+		t.Helper()
+		d, ok := rl.reserve(n, maxWait)
+		if ok != okExpected {
+			t.Fatalf("unexpected ok for n=%g, maxWait=%s; got %v; want %v; wait duration: %s", n, maxWait, ok, okExpected, d)
+		}
+	}
+
+	// The whole budget for the first second is available
+	f(10, 0, true)
+
+	// The budget is exhausted
+	f(1, 0, false)
+	f(0, 0, true)
+
+	// Reserve the budget ahead of time
+	f(5, time.Second, true)
+	f(0, 0, false)
+	f(20, time.Second, false)
+}
+
+func TestUserLimiterConcurrency(t *testing.T) {
+	defer func(d time.Duration) {
+		*maxQueueDuration = d
+	}(*maxQueueDuration)
+	*maxQueueDuration = 10 * time.Millisecond
+
+	ul := newUserLimiter("test-concurrency", 2, 0, 0)
+	for i := 0; i < 2; i++ {
+		if err := ul.beginRequest(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	err := ul.beginRequest()
+	if err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	var esc *httpserver.ErrorWithStatusCode
+	if !errors.As(err, &esc) || esc.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("unexpected error: %v; want error with status code %d", err, http.StatusTooManyRequests)
+	}
+
+	// The request slot must become available after endRequest call
+	ul.endRequest()
+	if err := ul.beginRequest(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func TestUserLimiterRequestsPerSecond(t *testing.T) {
+	defer func(d time.Duration) {
+		*maxQueueDuration = d
+	}(*maxQueueDuration)
+	*maxQueueDuration = 0
+
+	ul := newUserLimiter("test-rate", 0, 3, 0)
+	for i := 0; i < 3; i++ {
+		if err := ul.beginRequest(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		ul.endRequest()
+	}
+	if err := ul.beginRequest(); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestUserLimiterZeroOverridesDefault(t *testing.T) {
+	defer func(n int) {
+		*maxConcurrentPerUserRequests = n
+	}(*maxConcurrentPerUserRequests)
+	*maxConcurrentPerUserRequests = 1
+
+	f := func(s string, maxConcurrentRequestsExpected int) {
+		t.Helper()
+		as, err := parseAuthConfig([]byte(s))
+		if err != nil {
+			t.Fatalf("cannot parse auth config: %s", err)
+		}
+		for _, ui := range as.byAuthToken {
+			if n := ui.limiter.getMaxConcurrentRequests(); n != maxConcurrentRequestsExpected {
+				t.Fatalf("unexpected max concurrent requests; got %d; want %d", n, maxConcurrentRequestsExpected)
+			}
+		}
+	}
+
+	// The default limit is used if the per-user limit isn't set
+	f(`
+users:
+- username: test-default-limit
+  url_prefix: http://foo.bar
+`, 1)
+
+	// Zero per-user limit disables the default limit
+	f(`
+users:
+- username: test-default-limit
+  url_prefix: http://foo.bar
+  max_concurrent_requests: 0
+`, 0)
+}
+
+func TestUserLimiterConfigReload(t *testing.T) {
+	defer func(d time.Duration) {
+		*maxQueueDuration = d
+	}(*maxQueueDuration)
+	*maxQueueDuration = 10 * time.Millisecond
+
+	getLimiter := func(maxConcurrentRequests int) *userLimiter {
+		t.Helper()
+		as, err := parseAuthConfig([]byte(fmt.Sprintf(`
+users:
+- username: test-reload
+  url_prefix: http://foo.bar
+  max_concurrent_requests: %d
+`, maxConcurrentRequests)))
+		if err != nil {
+			t.Fatalf("cannot parse auth config: %s", err)
+		}
+		return as.byAuthToken[getAuthToken("", "test-reload", "")].limiter
+	}
+
+	ul := getLimiter(1)
+	if err := ul.beginRequest(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The limiter must be preserved across config reloads, so the in-flight request occupies the slot
+	ulNew := getLimiter(1)
+	if ulNew != ul {
+		t.Fatalf("the limiter must be re-used after config reload")
+	}
+	if err := ulNew.beginRequest(); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+
+	// Increased limit must be applied in place
+	getLimiter(2)
+	if err := ul.beginRequest(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ul.endRequest()
+	ul.endRequest()
+}
+
+func TestUserLimiterConfigReorder(t *testing.T) {
+	getLimiters := func(s string) (*userLimiter, *userLimiter) {
+		t.Helper()
+		as, err := parseAuthConfig([]byte(s))
+		if err != nil {
+			t.Fatalf("cannot parse auth config: %s", err)
+		}
+		uiFoo := as.byAuthToken[getAuthToken("foo-token", "", "")]
+		uiBar := as.byAuthToken[getAuthToken("bar-token", "", "")]
+		return uiFoo.limiter, uiBar.limiter
+	}
+
+	ulFoo, ulBar := getLimiters(`
+users:
+- bearer_token: foo-token
+  url_prefix: http://foo.bar
+- bearer_token: bar-token
+  url_prefix: http://foo.bar
+`)
+	if ulFoo == ulBar {
+		t.Fatalf("users with distinct bearer tokens mustn't share the limiter")
+	}
+
+	// Users must keep their limiters after they are reordered and new users are added
+	ulFooNew, ulBarNew := getLimiters(`
+users:
+- bearer_token: baz-token
+  url_prefix: http://foo.bar
+- bearer_token: bar-token
+  url_prefix: http://foo.bar
+- bearer_token: foo-token
+  url_prefix: http://foo.bar
+`)
+	if ulFooNew != ulFoo {
+		t.Fatalf("the limiter for foo-token must be re-used after config reload")
+	}
+	if ulBarNew != ulBar {
+		t.Fatalf("the limiter for bar-token must be re-used after config reload")
+	}
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
@@ -26,6 +28,8 @@ var (
 	reloadAuthKey          = flag.String("reloadAuthKey", "", "Auth key for /-/reload http endpoint. It must be passed as authKey=...")
 	logInvalidAuthTokens   = flag.Bool("logInvalidAuthTokens", false, "Whether to log requests with invalid auth tokens. "+
 		`Such requests are always counted at vmauth_http_request_errors_total{reason="invalid_auth_token"} metric, which is exposed at /metrics page`)
+	maxRequestBodySizeToRetry = flagutil.NewBytes("maxRequestBodySizeToRetry", 16*1024, "The maximum request body size, which can be cached and re-tried at other backends. "+
+		"Bigger request bodies aren't retried on backend failures. See https://docs.victoriametrics.com/vmauth.html#load-balancing")
 )
 
 func main() {
@@ -38,6 +42,8 @@ func main() {
 	logger.Infof("starting vmauth at %q...", *httpListenAddr)
 	startTime := time.Now()
 	initAuthConfig()
+	startHealthChecker()
+	startJWKSReloader()
 	go httpserver.Serve(*httpListenAddr, requestHandler)
 	logger.Infof("started vmauth in %.3f seconds", time.Since(startTime).Seconds())
 
@@ -78,8 +84,22 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 		// See https://docs.influxdata.com/influxdb/v2.0/api/
 		authToken = strings.Replace(authToken, "Token", "Bearer", 1)
 	}
-	ac := authConfig.Load().(map[string]*UserInfo)
-	ui := ac[authToken]
+	as := authConfig.Load().(*authState)
+	ui := as.byAuthToken[authToken]
+	var claims map[string]interface{}
+	if ui == nil && len(as.jwtUsers) > 0 && strings.HasPrefix(authToken, "Bearer ") {
+		var err error
+		ui, claims, err = as.getJWTUser(strings.TrimPrefix(authToken, "Bearer "))
+		if err != nil {
+			invalidAuthTokenRequests.Inc()
+			err = &httpserver.ErrorWithStatusCode{
+				Err:        fmt.Errorf("cannot authenticate the request with JWT: %w", err),
+				StatusCode: http.StatusUnauthorized,
+			}
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+	}
 	if ui == nil {
 		invalidAuthTokenRequests.Inc()
 		if *logInvalidAuthTokens {
@@ -91,20 +111,113 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	}
 	ui.requests.Inc()
-	targetURL, headers, err := createTargetURL(ui, r.URL)
+	if err := ui.limiter.beginRequest(); err != nil {
+		httpserver.Errorf(w, r, "%s", err)
+		return true
+	}
+	defer ui.limiter.endRequest()
+	w = ui.limiter.limitTraffic(w, r)
+	u := normalizeURL(r.URL)
+	rt := ui.getRoute(u, r.Header)
+	if rt == nil {
+		missingRouteRequests.Inc()
+		httpserver.Errorf(w, r, "missing route for %q", u.String())
+		return true
+	}
+	headers, err := applyClaimsToHeaders(rt.headers, claims)
 	if err != nil {
-		httpserver.Errorf(w, r, "cannot determine targetURL: %s", err)
+		httpserver.Errorf(w, r, "cannot apply JWT claims to headers: %s", err)
 		return true
 	}
-	r.Header.Set("vm-target-url", targetURL.String())
-	for _, h := range headers {
-		r.Header.Set(h.Name, h.Value)
+	updateHeaders(r.Header, rt.dropHeaders, headers)
+	up := rt.urlPrefix
+	rtb := &readTrackingBody{
+		r:       r.Body,
+		maxSize: maxRequestBodySizeToRetry.N,
+	}
+	r.Body = rtb
+	canRetry := canRetryRequest(r.Method, u.Path)
+	var lastErr error
+	for i := 0; i < len(up.bus); i++ {
+		bu := up.getBackendURL()
+		prefixURL, err := applyClaimsToURL(bu.url, claims)
+		if err != nil {
+			httpserver.Errorf(w, r, "cannot apply JWT claims to `url_prefix`: %s", err)
+			return true
+		}
+		targetURL := rt.createTargetURL(prefixURL, u)
+		r.Header.Set("vm-target-url", targetURL.String())
+		bu.bs.requests.Inc()
+		err = proxyRequest(w, r, rt)
+		if err == nil {
+			return true
+		}
+		if r.Context().Err() != nil {
+			// The client closed the connection. There is no need in marking the backend as broken.
+			return true
+		}
+		bu.bs.errors.Inc()
+		bu.setBroken()
+		lastErr = fmt.Errorf("cannot proxy the request to %q: %w", targetURL, err)
+		if !canRetry || !rtb.canRetry() {
+			break
+		}
+		logger.Warnf("%s; retrying the request at the next backend", lastErr)
+		rtb.reset()
+	}
+	err = &httpserver.ErrorWithStatusCode{
+		Err:        lastErr,
+		StatusCode: http.StatusBadGateway,
 	}
-	proxyRequest(w, r)
+	httpserver.Errorf(w, r, "%s", err)
 	return true
 }
 
-func proxyRequest(w http.ResponseWriter, r *http.Request) {
+// canRetryRequest returns true if the request with the given method and path can be retried at another backend.
+//
+// POST requests are retried only for read-only query endpoints, since retrying data ingestion requests
+// such as /api/v1/write or /api/v1/import may result in duplicate data.
+func canRetryRequest(method, path string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPost:
+		return isQueryPath(path)
+	default:
+		return false
+	}
+}
+
+// queryPathSuffixes contains path suffixes for read-only query endpoints, which accept POST requests.
+//
+// Suffixes are used instead of full paths, since the path may contain a prefix such as /select/0/prometheus.
+var queryPathSuffixes = []string{
+	"/api/v1/query",
+	"/api/v1/query_range",
+	"/api/v1/query_exemplars",
+	"/api/v1/series",
+	"/api/v1/series/count",
+	"/api/v1/labels",
+	"/api/v1/export",
+	"/api/v1/read",
+	"/federate",
+}
+
+func isQueryPath(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	for _, suffix := range queryPathSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	// /api/v1/label/<labelName>/values
+	return strings.Contains(path, "/api/v1/label/") && strings.HasSuffix(path, "/values")
+}
+
+// proxyRequest proxies r to the url from `vm-target-url` header and updates the response headers according to rt.
+//
+// It returns non-nil error if the backend is unavailable. In this case nothing is written to w, so the request may be retried at another backend.
+func proxyRequest(w http.ResponseWriter, r *http.Request, rt *route) (err error) {
 	defer func() {
 		err := recover()
 		if err == nil || err == http.ErrAbortHandler {
@@ -115,7 +228,82 @@ func proxyRequest(w http.ResponseWriter, r *http.Request) {
 		// Forward other panics to the caller.
 		panic(err)
 	}()
+	ps := &proxyState{
+		responseHeaders:     rt.responseHeaders,
+		dropResponseHeaders: rt.dropResponseHeaders,
+	}
+	r = r.WithContext(context.WithValue(r.Context(), proxyStateKey{}, ps))
 	getReverseProxy().ServeHTTP(w, r)
+	return ps.err
+}
+
+type proxyStateKey struct{}
+
+// proxyState holds per-request state for the reverse proxy.
+type proxyState struct {
+	// responseHeaders must be set to the response from the backend.
+	responseHeaders []Header
+
+	// dropResponseHeaders must be removed from the response from the backend.
+	dropResponseHeaders []string
+
+	// err is an error returned by the backend transport for the proxied request.
+	err error
+}
+
+// readTrackingBody remembers up to maxSize bytes read from r, so they could be re-sent to another backend on retry.
+type readTrackingBody struct {
+	r       io.ReadCloser
+	maxSize int
+
+	// buf contains the data read from r.
+	buf []byte
+
+	// replayBuf contains the data from buf, which must be read before continuing reading from r.
+	replayBuf []byte
+
+	// bufOverflow is set if more than maxSize bytes were read from r.
+	bufOverflow bool
+}
+
+// Read implements io.Reader interface.
+func (rtb *readTrackingBody) Read(p []byte) (int, error) {
+	if len(rtb.replayBuf) > 0 {
+		n := copy(p, rtb.replayBuf)
+		rtb.replayBuf = rtb.replayBuf[n:]
+		return n, nil
+	}
+	if rtb.r == nil {
+		return 0, io.EOF
+	}
+	n, err := rtb.r.Read(p)
+	if !rtb.bufOverflow {
+		if len(rtb.buf)+n > rtb.maxSize {
+			rtb.bufOverflow = true
+			rtb.buf = nil
+		} else {
+			rtb.buf = append(rtb.buf, p[:n]...)
+		}
+	}
+	return n, err
+}
+
+// Close implements io.Closer interface.
+//
+// It doesn't close the underlying reader, since the request body may be needed for retries.
+// The underlying reader is closed by net/http server when the request is finished.
+func (rtb *readTrackingBody) Close() error {
+	return nil
+}
+
+// canRetry returns true if all the data read from rtb can be re-sent.
+func (rtb *readTrackingBody) canRetry() bool {
+	return !rtb.bufOverflow
+}
+
+// reset prepares rtb for reading from the start.
+func (rtb *readTrackingBody) reset() {
+	rtb.replayBuf = rtb.buf
 }
 
 var (
@@ -157,6 +345,20 @@ func initReverseProxy() {
 			}
 			return tr
 		}(),
+		ModifyResponse: func(resp *http.Response) error {
+			if ps, ok := resp.Request.Context().Value(proxyStateKey{}).(*proxyState); ok {
+				updateHeaders(resp.Header, ps.dropResponseHeaders, ps.responseHeaders)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if ps, ok := r.Context().Value(proxyStateKey{}).(*proxyState); ok {
+				// Postpone writing the error to the client, so the request could be retried at another backend.
+				ps.err = err
+				return
+			}
+			httpserver.Errorf(w, r, "cannot proxy the request: %s", err)
+		},
 		FlushInterval: time.Second,
 		ErrorLog:      logger.StdErrorLogger(),
 	}
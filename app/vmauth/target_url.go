@@ -1,17 +1,12 @@
 package main
 
 import (
-	"fmt"
+	"net/http"
 	"net/url"
 	"path"
 	"strings"
 )
 
-func (up *URLPrefix) mergeURLs(requestURI *url.URL) *url.URL {
-	pu := up.getNextURL()
-	return mergeURLs(pu, requestURI)
-}
-
 func mergeURLs(uiURL, requestURI *url.URL) *url.URL {
 	targetURL := *uiURL
 	targetURL.Path += requestURI.Path
@@ -35,7 +30,7 @@ func mergeURLs(uiURL, requestURI *url.URL) *url.URL {
 	return &targetURL
 }
 
-func createTargetURL(ui *UserInfo, uOrig *url.URL) (*url.URL, []Header, error) {
+func normalizeURL(uOrig *url.URL) *url.URL {
 	u := *uOrig
 	// Prevent from attacks with using `..` in r.URL.Path
 	u.Path = path.Clean(u.Path)
@@ -43,16 +38,115 @@ func createTargetURL(ui *UserInfo, uOrig *url.URL) (*url.URL, []Header, error) {
 		u.Path = "/" + u.Path
 	}
 	u.Path = strings.TrimSuffix(u.Path, "/")
-	for _, e := range ui.URLMap {
+	return &u
+}
+
+// route contains rules for proxying the request, which matches `url_map` entry or user-level `url_prefix`.
+type route struct {
+	urlPrefix           *URLPrefix
+	headers             []Header
+	dropHeaders         []string
+	responseHeaders     []Header
+	dropResponseHeaders []string
+	dropQueryArgs       []string
+	setQueryArgs        []QueryArg
+	addQueryArgs        []QueryArg
+}
+
+// getRoute returns the route for the given normalized u and request headers h.
+//
+// nil is returned if there is no route for the request.
+func (ui *UserInfo) getRoute(u *url.URL, h http.Header) *route {
+	for i := range ui.URLMap {
+		e := &ui.URLMap[i]
+		if e.match(u, h) {
+			return &route{
+				urlPrefix:           e.URLPrefix,
+				headers:             e.Headers,
+				dropHeaders:         e.DropHeaders,
+				responseHeaders:     e.ResponseHeaders,
+				dropResponseHeaders: e.DropResponseHeaders,
+				dropQueryArgs:       e.DropQueryArgs,
+				setQueryArgs:        e.SetQueryArgs,
+				addQueryArgs:        e.AddQueryArgs,
+			}
+		}
+	}
+	if ui.URLPrefix != nil {
+		return &route{
+			urlPrefix:           ui.URLPrefix,
+			headers:             ui.Headers,
+			dropHeaders:         ui.DropHeaders,
+			responseHeaders:     ui.ResponseHeaders,
+			dropResponseHeaders: ui.DropResponseHeaders,
+			dropQueryArgs:       ui.DropQueryArgs,
+			setQueryArgs:        ui.SetQueryArgs,
+			addQueryArgs:        ui.AddQueryArgs,
+		}
+	}
+	return nil
+}
+
+// match returns true if u and h match all the `src_*` matchers from e.
+//
+// Every `src_paths` entry is checked against u.Path, while all the `src_query_args` and `src_headers` entries must match.
+func (e *URLMap) match(u *url.URL, h http.Header) bool {
+	if len(e.SrcPaths) > 0 {
+		matched := false
 		for _, sp := range e.SrcPaths {
 			if sp.match(u.Path) {
-				return e.URLPrefix.mergeURLs(&u), e.Headers, nil
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(e.SrcQueryArgs) > 0 {
+		args := u.Query()
+		for _, sqa := range e.SrcQueryArgs {
+			if !sqa.match(args) {
+				return false
 			}
 		}
 	}
-	if ui.URLPrefix != nil {
-		return ui.URLPrefix.mergeURLs(&u), ui.Headers, nil
+	for _, sh := range e.SrcHeaders {
+		if !sh.match(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// createTargetURL returns the url for proxying the request with the given normalized u to the backend with prefixURL.
+//
+// Query args are rewritten according to rt rules.
+func (rt *route) createTargetURL(prefixURL, u *url.URL) *url.URL {
+	targetURL := mergeURLs(prefixURL, u)
+	if len(rt.dropQueryArgs) == 0 && len(rt.setQueryArgs) == 0 && len(rt.addQueryArgs) == 0 {
+		return targetURL
+	}
+	args := targetURL.Query()
+	for _, name := range rt.dropQueryArgs {
+		args.Del(name)
+	}
+	for _, qa := range rt.setQueryArgs {
+		args.Set(qa.Name, qa.Value)
+	}
+	for _, qa := range rt.addQueryArgs {
+		args.Add(qa.Name, qa.Value)
+	}
+	targetURL.RawQuery = args.Encode()
+	return targetURL
+}
+
+// updateHeaders removes headers with dropNames from h and then sets headers to h.
+func updateHeaders(h http.Header, dropNames []string, headers []Header) {
+	for _, name := range dropNames {
+		h.Del(name)
+	}
+	for _, hdr := range headers {
+		h.Set(hdr.Name, hdr.Value)
 	}
-	missingRouteRequests.Inc()
-	return nil, nil, fmt.Errorf("missing route for %q", u.String())
 }
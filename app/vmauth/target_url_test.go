@@ -2,7 +2,11 @@ package main
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -13,10 +17,14 @@ func TestCreateTargetURLSuccess(t *testing.T) {
 		if err != nil {
 			t.Fatalf("cannot parse %q: %s", requestURI, err)
 		}
-		target, headers, err := createTargetURL(ui, u)
-		if err != nil {
-			t.Fatalf("unexpected error: %s", err)
+		u = normalizeURL(u)
+		rt := ui.getRoute(u, nil)
+		if rt == nil {
+			t.Fatalf("missing route for %q", requestURI)
 		}
+		bu := rt.urlPrefix.getBackendURL()
+		target := rt.createTargetURL(bu.url, u)
+		headers := rt.headers
 		if target.String() != expectedTarget {
 			t.Fatalf("unexpected target; got %q; want %q", target, expectedTarget)
 		}
@@ -119,15 +127,9 @@ func TestCreateTargetURLFailure(t *testing.T) {
 		if err != nil {
 			t.Fatalf("cannot parse %q: %s", requestURI, err)
 		}
-		target, headers, err := createTargetURL(ui, u)
-		if err == nil {
-			t.Fatalf("expecting non-nil error")
-		}
-		if target != nil {
-			t.Fatalf("unexpected target=%q; want empty string", target)
-		}
-		if headers != nil {
-			t.Fatalf("unexpected headers=%q; want empty string", headers)
+		rt := ui.getRoute(normalizeURL(u), nil)
+		if rt != nil {
+			t.Fatalf("unexpected non-empty route: %+v", rt)
 		}
 	}
 	f(&UserInfo{}, "/foo/bar")
@@ -140,3 +142,188 @@ func TestCreateTargetURLFailure(t *testing.T) {
 		},
 	}, "/api/v1/write")
 }
+
+func TestCreateTargetURLRewriteRules(t *testing.T) {
+	f := func(authConfig, requestURI string, requestHeaders map[string]string, expectedTarget, expectedHeaders, expectedResponseHeaders string) {
+		t.Helper()
+		as, err := parseAuthConfig([]byte(authConfig))
+		if err != nil {
+			t.Fatalf("cannot parse auth config: %s", err)
+		}
+		ui := as.byAuthToken[getAuthToken("", "foo", "")]
+		u, err := url.Parse(requestURI)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", requestURI, err)
+		}
+		u = normalizeURL(u)
+		h := make(http.Header)
+		for k, v := range requestHeaders {
+			h.Set(k, v)
+		}
+		rt := ui.getRoute(u, h)
+		if rt == nil {
+			t.Fatalf("missing route for %q", requestURI)
+		}
+		target := rt.createTargetURL(rt.urlPrefix.getBackendURL().url, u)
+		if target.String() != expectedTarget {
+			t.Fatalf("unexpected target; got %q; want %q", target, expectedTarget)
+		}
+		if s := fmt.Sprintf("%q", rt.headers); s != expectedHeaders {
+			t.Fatalf("unexpected headers; got %s; want %s", s, expectedHeaders)
+		}
+		if s := fmt.Sprintf("%q", rt.responseHeaders); s != expectedResponseHeaders {
+			t.Fatalf("unexpected response headers; got %s; want %s", s, expectedResponseHeaders)
+		}
+	}
+
+	const routingConfig = `
+users:
+- username: foo
+  url_map:
+  - src_paths: ["/api/v1/query"]
+    src_query_args: ["nocache=1"]
+    url_prefix: http://vmselect-nocache
+  - src_paths: ["/api/v1/query"]
+    src_headers: ["X-Dashboard: (team-a|team-b)"]
+    url_prefix: http://vmselect-teams
+    headers:
+    - "X-Scope-OrgID: teams"
+  - src_query_args: ["debug=.+"]
+    url_prefix: http://vmselect-debug
+  url_prefix: http://vmselect-default
+`
+
+	// Match by query arg
+	f(routingConfig, "/api/v1/query?query=up&nocache=1", nil, "http://vmselect-nocache/api/v1/query?nocache=1&query=up", "[]", "[]")
+	f(routingConfig, "/api/v1/query?query=up&nocache=0", nil, "http://vmselect-default/api/v1/query?nocache=0&query=up", "[]", "[]")
+
+	// Query arg matcher doesn't match other paths
+	f(routingConfig, "/api/v1/query_range?nocache=1", nil, "http://vmselect-default/api/v1/query_range?nocache=1", "[]", "[]")
+
+	// Match by header
+	f(routingConfig, "/api/v1/query?query=up", map[string]string{
+		"X-Dashboard": "team-b",
+	}, "http://vmselect-teams/api/v1/query?query=up", `[{"X-Scope-OrgID" "teams"}]`, "[]")
+	f(routingConfig, "/api/v1/query?query=up", map[string]string{
+		"X-Dashboard": "team-c",
+	}, "http://vmselect-default/api/v1/query?query=up", "[]", "[]")
+
+	// Match by query arg without src_paths
+	f(routingConfig, "/api/v1/series?debug=true", nil, "http://vmselect-debug/api/v1/series?debug=true", "[]", "[]")
+	f(routingConfig, "/api/v1/series?debug=", nil, "http://vmselect-default/api/v1/series?debug=", "[]", "[]")
+
+	const rewriteConfig = `
+users:
+- username: foo
+  url_map:
+  - src_paths: ["/api/v1/query", "/api/v1/query_range"]
+    url_prefix: http://vmselect
+    drop_query_args: [nocache, trace]
+    set_query_args: ['extra_filters[]={env="prod"}']
+    add_query_args: ["extra_label=team=dev"]
+    response_headers:
+    - "Access-Control-Allow-Origin: *"
+    drop_response_headers: [Server]
+  url_prefix: http://vmselect-default?extra_label=team=dev
+  set_query_args: ["nocache=1"]
+  response_headers:
+  - "X-Served-By: vmauth"
+`
+
+	// Drop, set and add query args
+	f(rewriteConfig, "/api/v1/query?query=up&nocache=1&trace=1", nil,
+		"http://vmselect/api/v1/query?extra_filters%5B%5D=%7Benv%3D%22prod%22%7D&extra_label=team%3Ddev&query=up",
+		"[]", `[{"Access-Control-Allow-Origin" "*"}]`)
+
+	// Set query arg overrides the original query args
+	f(rewriteConfig, "/api/v1/query_range?query=up&extra_filters[]=foo&extra_filters[]=bar&extra_label=x=y", nil,
+		"http://vmselect/api/v1/query_range?extra_filters%5B%5D=%7Benv%3D%22prod%22%7D&extra_label=x%3Dy&extra_label=team%3Ddev&query=up",
+		"[]", `[{"Access-Control-Allow-Origin" "*"}]`)
+
+	// User-level rewrite rules
+	f(rewriteConfig, "/api/v1/series?match[]=up&nocache=0", nil,
+		"http://vmselect-default/api/v1/series?extra_label=team%3Ddev&match%5B%5D=up&nocache=1",
+		"[]", `[{"X-Served-By" "vmauth"}]`)
+}
+
+func TestUpdateHeaders(t *testing.T) {
+	f := func(dropNames []string, headers []Header, resultExpected string) {
+		t.Helper()
+		h := http.Header{
+			"Server":       []string{"VictoriaMetrics"},
+			"Content-Type": []string{"application/json"},
+		}
+		updateHeaders(h, dropNames, headers)
+		var b strings.Builder
+		if err := h.Write(&b); err != nil {
+			t.Fatalf("cannot write headers: %s", err)
+		}
+		if s := b.String(); s != resultExpected {
+			t.Fatalf("unexpected headers;\ngot\n%s\nwant\n%s", s, resultExpected)
+		}
+	}
+	f(nil, nil, "Content-Type: application/json\r\nServer: VictoriaMetrics\r\n")
+
+	// Headers with empty values are set to empty values
+	f(nil, []Header{{Name: "Server"}}, "Content-Type: application/json\r\nServer: \r\n")
+	f(nil, []Header{{Name: "Server", Value: "vmauth"}, {Name: "X-Foo", Value: "bar"}}, "Content-Type: application/json\r\nServer: vmauth\r\nX-Foo: bar\r\n")
+
+	// Headers are dropped before setting the headers
+	f([]string{"Server", "X-Missing"}, nil, "Content-Type: application/json\r\n")
+	f([]string{"Server", "X-Foo"}, []Header{{Name: "X-Foo", Value: "bar"}}, "Content-Type: application/json\r\nX-Foo: bar\r\n")
+}
+
+func TestRequestHandlerResponseHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "VictoriaMetrics")
+		w.Header().Set("X-Backend", "vmselect")
+		w.Header().Set("X-Request-Debug", r.Header.Get("X-Debug"))
+		_, hasEmpty := r.Header["X-Empty"]
+		w.Header().Set("X-Request-Has-Empty", strconv.FormatBool(hasEmpty))
+		fmt.Fprintf(w, "%s", r.URL.RequestURI())
+	}))
+	defer backend.Close()
+
+	as, err := parseAuthConfig([]byte(fmt.Sprintf(`
+users:
+- username: foo
+  url_prefix: %q
+  drop_query_args: [nocache]
+  drop_headers: [X-Debug]
+  headers:
+  - "X-Empty:"
+  drop_response_headers: [Server]
+  response_headers:
+  - "X-Served-By: vmauth"
+`, backend.URL)))
+	if err != nil {
+		t.Fatalf("cannot parse auth config: %s", err)
+	}
+	authConfig.Store(as)
+	defer authConfig.Store(&authState{})
+
+	r := httptest.NewRequest(http.MethodGet, "http://vmauth/api/v1/query?query=up&nocache=1", nil)
+	r.SetBasicAuth("foo", "")
+	r.Header.Set("X-Debug", "1")
+	w := httptest.NewRecorder()
+	if !requestHandler(w, r) {
+		t.Fatalf("unexpected false returned from requestHandler")
+	}
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status code; got %d; want %d; response: %q", w.Code, http.StatusOK, w.Body.String())
+	}
+	if s := w.Body.String(); s != "/api/v1/query?query=up" {
+		t.Fatalf("unexpected request uri at backend; got %q; want %q", s, "/api/v1/query?query=up")
+	}
+	f := func(name, valueExpected string) {
+		t.Helper()
+		if v := w.Header().Get(name); v != valueExpected {
+			t.Fatalf("unexpected value for response header %q; got %q; want %q", name, v, valueExpected)
+		}
+	}
+	f("Server", "")
+	f("X-Backend", "vmselect")
+	f("X-Served-By", "vmauth")
+	f("X-Request-Debug", "")
+	f("X-Request-Has-Empty", "true")
+}
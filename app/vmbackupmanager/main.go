@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envflag"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+)
+
+var (
+	httpListenAddr    = flag.String("httpListenAddr", ":8300", "Address to listen for http connections")
+	storageDataPath   = flag.String("storageDataPath", "victoria-metrics-data", "Path to VictoriaMetrics data. Must match -storageDataPath from VictoriaMetrics or vmstorage")
+	snapshotCreateURL = flag.String("snapshot.createURL", "", "VictoriaMetrics create snapshot url. When this is given a snapshot will automatically be created during backup. "+
+		"Example: http://victoriametrics:8428/snapshot/create")
+	snapshotDeleteURL = flag.String("snapshot.deleteURL", "", "VictoriaMetrics delete snapshot url. Optional. Will be generated from -snapshot.createURL if not provided. "+
+		"All created snapshots will be automatically deleted. Example: http://victoriametrics:8428/snapshot/delete")
+	dst = flag.String("dst", "", "The root folder of VictoriaMetrics backups. "+
+		"Example: gs://bucket/path/to/backup/dir, s3://bucket/path/to/backup/dir, azblob://container/path/to/backup/dir or fs:///path/to/local/backup/dir")
+	concurrency       = flag.Int("concurrency", 10, "The number of concurrent workers. Higher concurrency may reduce backup duration")
+	maxBytesPerSecond = flagutil.NewBytes("maxBytesPerSecond", 0, "The maximum upload speed. There is no limit if it is set to 0")
+	runOnStart        = flag.Bool("runOnStart", false, "Upload backups immediately after start of the service. Otherwise the backup starts on new hour")
+
+	disableHourly  = flag.Bool("disableHourly", false, "Disable hourly run. Default false")
+	disableDaily   = flag.Bool("disableDaily", false, "Disable daily run. Default false")
+	disableWeekly  = flag.Bool("disableWeekly", false, "Disable weekly run. Default false")
+	disableMonthly = flag.Bool("disableMonthly", false, "Disable monthly run. Default false")
+
+	keepLastHourly  = flag.Int("keepLastHourly", -1, "Keep last N hourly backups. If 0 is specified next retention cycle removes all backups for given time period.")
+	keepLastDaily   = flag.Int("keepLastDaily", -1, "Keep last N daily backups. If 0 is specified next retention cycle removes all backups for given time period.")
+	keepLastWeekly  = flag.Int("keepLastWeekly", -1, "Keep last N weekly backups. If 0 is specified next retention cycle removes all backups for given time period.")
+	keepLastMonthly = flag.Int("keepLastMonthly", -1, "Keep last N monthly backups. If 0 is specified next retention cycle removes all backups for given time period.")
+)
+
+func main() {
+	// Write flags and help message to stdout, since it is easier to grep or pipe.
+	flag.CommandLine.SetOutput(os.Stdout)
+	flag.Usage = usage
+	envflag.Parse()
+	buildinfo.Init()
+	logger.Init()
+
+	if len(*dst) == 0 {
+		logger.Fatalf("missing -dst command-line flag")
+	}
+	bm := newBackupManager()
+	if args := flag.Args(); len(args) > 0 {
+		if err := runCommand(bm, args); err != nil {
+			logger.Fatalf("%s", err)
+		}
+		return
+	}
+
+	if len(*snapshotCreateURL) == 0 {
+		logger.Fatalf("missing -snapshot.createURL command-line flag")
+	}
+	if len(*snapshotDeleteURL) == 0 {
+		if err := flag.Set("snapshot.deleteURL", strings.Replace(*snapshotCreateURL, "/create", "/delete", 1)); err != nil {
+			logger.Fatalf("cannot set -snapshot.deleteURL flag: %s", err)
+		}
+	}
+
+	logger.Infof("starting vmbackupmanager at %q with -dst=%q and periods %s", *httpListenAddr, *dst, bm.periods)
+	go httpserver.Serve(*httpListenAddr, func(w http.ResponseWriter, r *http.Request) bool {
+		return requestHandler(bm, w, r)
+	})
+	bm.start(*runOnStart)
+
+	sig := procutil.WaitForSigterm()
+	logger.Infof("received signal %s", sig)
+
+	startTime := time.Now()
+	logger.Infof("gracefully shutting down http server at %q", *httpListenAddr)
+	if err := httpserver.Stop(*httpListenAddr); err != nil {
+		logger.Fatalf("cannot stop http server: %s", err)
+	}
+	logger.Infof("successfully shut down http server in %.3f seconds", time.Since(startTime).Seconds())
+
+	logger.Infof("waiting for the current backup to finish")
+	bm.stop()
+	logger.Infof("the vmbackupmanager has been stopped in %.3f seconds", time.Since(startTime).Seconds())
+}
+
+// runCommand runs the command from args and returns.
+func runCommand(bm *backupManager, args []string) error {
+	switch args[0] {
+	case "restore":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: vmbackupmanager restore <backup>; for example, vmbackupmanager restore daily/2021-02-13")
+		}
+		return bm.restore(args[1])
+	case "backup":
+		if len(args) != 2 || args[1] != "list" {
+			return fmt.Errorf("usage: vmbackupmanager backup list")
+		}
+		backups, err := bm.listBackups()
+		if err != nil {
+			return err
+		}
+		for _, b := range backups {
+			fmt.Fprintf(os.Stdout, "%s\n", b.Name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q; supported commands: `restore <backup>`, `backup list`", args[0])
+	}
+}
+
+func requestHandler(bm *backupManager, w http.ResponseWriter, r *http.Request) bool {
+	switch r.URL.Path {
+	case "/":
+		if r.Method != http.MethodGet {
+			return false
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<h2>vmbackupmanager</h2>")
+		fmt.Fprintf(w, "See docs at <a href='https://docs.victoriametrics.com/vmbackupmanager.html'>https://docs.victoriametrics.com/vmbackupmanager.html</a></br>")
+		fmt.Fprintf(w, "Useful endpoints:</br>")
+		httpserver.WriteAPIHelp(w, [][2]string{
+			{"api/v1/backups", "list of available backups"},
+			{"api/v1/status", "status of the last backup run"},
+			{"metrics", "available service metrics"},
+		})
+		return true
+	case "/api/v1/backups":
+		switch r.Method {
+		case http.MethodGet:
+			backups, err := bm.listBackups()
+			if err != nil {
+				httpserver.Errorf(w, r, "cannot list backups: %s", err)
+				return true
+			}
+			writeJSONResponse(w, r, backups)
+			return true
+		case http.MethodPost:
+			if !bm.trigger() {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprintf(w, "backup is already in progress")
+				return true
+			}
+			w.WriteHeader(http.StatusAccepted)
+			return true
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return true
+		}
+	case "/api/v1/status":
+		writeJSONResponse(w, r, bm.status())
+		return true
+	default:
+		return false
+	}
+}
+
+func writeJSONResponse(w http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot marshal response: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func usage() {
+	const s = `
+vmbackupmanager performs regular backups according to the provided configs.
+
+vmbackupmanager restore <backup> restores the given backup to -storageDataPath.
+vmbackupmanager backup list lists the available backups at -dst.
+
+See the docs at https://docs.victoriametrics.com/vmbackupmanager.html .
+`
+	flagutil.Usage(s)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetBackupNames(t *testing.T) {
+	f := func(ts string, hourlyExpected, dailyExpected, weeklyExpected, monthlyExpected string) {
+		t.Helper()
+		tm, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", ts, err)
+		}
+		if s := getHourlyBackupName(tm); s != hourlyExpected {
+			t.Fatalf("unexpected hourly backup name for %s; got %q; want %q", ts, s, hourlyExpected)
+		}
+		if s := getDailyBackupName(tm); s != dailyExpected {
+			t.Fatalf("unexpected daily backup name for %s; got %q; want %q", ts, s, dailyExpected)
+		}
+		if s := getWeeklyBackupName(tm); s != weeklyExpected {
+			t.Fatalf("unexpected weekly backup name for %s; got %q; want %q", ts, s, weeklyExpected)
+		}
+		if s := getMonthlyBackupName(tm); s != monthlyExpected {
+			t.Fatalf("unexpected monthly backup name for %s; got %q; want %q", ts, s, monthlyExpected)
+		}
+	}
+	f("2021-02-13T05:43:12Z", "2021-02-13:05", "2021-02-13", "2021-06", "2021-02")
+	f("2020-08-18T20:19:59Z", "2020-08-18:20", "2020-08-18", "2020-34", "2020-08")
+
+	// ISO week of the first days of the year may belong to the previous year.
+	f("2021-01-01T00:00:00Z", "2021-01-01:00", "2021-01-01", "2020-53", "2021-01")
+}
+
+func TestGetBackupsToDelete(t *testing.T) {
+	f := func(names []string, keepLast int, resultExpected []string) {
+		t.Helper()
+		result := getBackupsToDelete(names, keepLast)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected backups to delete for keepLast=%d; got %q; want %q", keepLast, result, resultExpected)
+		}
+	}
+	f(nil, 0, nil)
+	f(nil, 3, nil)
+	f([]string{"2021-02-12", "2021-02-13"}, 3, nil)
+	f([]string{"2021-02-12", "2021-02-13"}, 2, nil)
+	f([]string{"2021-02-12", "2021-02-13"}, 0, []string{"2021-02-13", "2021-02-12"})
+	f([]string{"2021-02-09", "2021-02-10", "2021-02-11", "2021-02-12", "2021-02-13"}, 3, []string{"2021-02-10", "2021-02-09"})
+}
+
+func TestValidateBackupName(t *testing.T) {
+	bm := newBackupManager()
+	f := func(name string, isValid bool) {
+		t.Helper()
+		err := bm.validateBackupName(name)
+		if isValid && err != nil {
+			t.Fatalf("unexpected error for %q: %s", name, err)
+		}
+		if !isValid && err == nil {
+			t.Fatalf("expecting non-nil error for %q", name)
+		}
+	}
+	f("latest", true)
+	f("daily/2021-02-13", true)
+	f("hourly/2021-02-13:05", true)
+	f("", false)
+	f("foo", false)
+	f("daily", false)
+	f("daily/", false)
+	f("daily/..", false)
+	f("daily/2021-02-13/data", false)
+	f("yearly/2021", false)
+	f("/latest", false)
+}
+
+func TestBackupManager(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "vmbackupmanager")
+	if err != nil {
+		t.Fatalf("cannot create temporary dir: %s", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+	dataDir := filepath.Join(tmpDir, "data")
+	dstDir := filepath.Join(tmpDir, "backups")
+
+	// Fake snapshot API, which creates snapshots with a single file inside dataDir.
+	// Files get unique names per snapshot like parts in VictoriaMetrics data.
+	snapshotIdx := 0
+	snapshotsDir := filepath.Join(dataDir, "snapshots")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/snapshot/create":
+			snapshotIdx++
+			name := fmt.Sprintf("20210213000000-%08X", snapshotIdx)
+			dir := filepath.Join(snapshotsDir, name, "data")
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Errorf("cannot create snapshot dir: %s", err)
+			}
+			data := []byte(fmt.Sprintf("snapshot contents %d", snapshotIdx))
+			if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("part_%d", snapshotIdx)), data, 0644); err != nil {
+				t.Errorf("cannot write snapshot file: %s", err)
+			}
+			fmt.Fprintf(w, `{"status":"ok","snapshot":%q}`, name)
+		case "/snapshot/delete":
+			if err := os.RemoveAll(filepath.Join(snapshotsDir, r.FormValue("snapshot"))); err != nil {
+				t.Errorf("cannot delete snapshot: %s", err)
+			}
+			fmt.Fprintf(w, `{"status":"ok"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	*storageDataPath = dataDir
+	*dst = "fs://" + dstDir
+	*snapshotCreateURL = srv.URL + "/snapshot/create"
+	*snapshotDeleteURL = srv.URL + "/snapshot/delete"
+	*disableHourly = true
+	*disableWeekly = true
+	*keepLastDaily = 1
+	defer func() {
+		*disableHourly = false
+		*disableWeekly = false
+		*keepLastDaily = -1
+	}()
+	bm := newBackupManager()
+
+	// Make backups for two distinct days. Only the last daily backup must remain.
+	tm := time.Date(2021, 2, 12, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	if err := bm.runBackup(ctx, tm); err != nil {
+		t.Fatalf("cannot make the first backup: %s", err)
+	}
+	if err := bm.runBackup(ctx, tm.Add(24*time.Hour)); err != nil {
+		t.Fatalf("cannot make the second backup: %s", err)
+	}
+	backups, err := bm.listBackups()
+	if err != nil {
+		t.Fatalf("cannot list backups: %s", err)
+	}
+	size := uint64(len("snapshot contents 2"))
+	backupsExpected := []backupInfo{
+		{Name: "latest", SizeBytes: size},
+		{Name: "daily/2021-02-13", SizeBytes: size},
+		{Name: "monthly/2021-02", SizeBytes: size},
+	}
+	if !reflect.DeepEqual(backups, backupsExpected) {
+		t.Fatalf("unexpected backups;\ngot\n%+v\nwant\n%+v", backups, backupsExpected)
+	}
+
+	// Snapshots must be deleted after the backup.
+	if fis, err := ioutil.ReadDir(snapshotsDir); err != nil || len(fis) != 0 {
+		t.Fatalf("expecting empty snapshots dir; got %d entries; err: %v", len(fis), err)
+	}
+
+	// Restore the backup.
+	*storageDataPath = filepath.Join(tmpDir, "restored")
+	if err := bm.restore("daily/2021-02-13"); err != nil {
+		t.Fatalf("cannot restore backup: %s", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(*storageDataPath, "data", "part_2"))
+	if err != nil {
+		t.Fatalf("cannot read restored file: %s", err)
+	}
+	if string(data) != "snapshot contents 2" {
+		t.Fatalf("unexpected restored data; got %q; want %q", data, "snapshot contents 2")
+	}
+	if err := bm.restore("daily/2021-02-12"); err == nil {
+		t.Fatalf("expecting non-nil error when restoring deleted backup")
+	}
+
+	// The monthly backup must be created only once at the first backup during the month.
+	*storageDataPath = filepath.Join(tmpDir, "restored-monthly")
+	if err := bm.restore("monthly/2021-02"); err != nil {
+		t.Fatalf("cannot restore monthly backup: %s", err)
+	}
+	data, err = ioutil.ReadFile(filepath.Join(*storageDataPath, "data", "part_1"))
+	if err != nil {
+		t.Fatalf("cannot read restored file from monthly backup: %s", err)
+	}
+	if string(data) != "snapshot contents 1" {
+		t.Fatalf("unexpected data in monthly backup; got %q; want %q", data, "snapshot contents 1")
+	}
+
+	// Cancelled backup must fail.
+	cancelledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := bm.runBackup(cancelledCtx, tm.Add(48*time.Hour)); err == nil {
+		t.Fatalf("expecting non-nil error for cancelled backup")
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/actions"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/fscommon"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/fslocal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/snapshot"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// latestDir is the folder at -dst, which contains the latest backup.
+const latestDir = "latest"
+
+// backupPeriod describes backups for a single interval such as hourly, daily, weekly or monthly.
+type backupPeriod struct {
+	// name is the folder name at -dst for backups of the given period.
+	name string
+
+	// keepLast is the number of the last backups to keep. Retention is disabled if it is negative.
+	keepLast int
+
+	// disabled is set if new backups mustn't be created for the given period.
+	disabled bool
+
+	// backupName returns backup name for the given time.
+	//
+	// Backup names must be sorted in the same order as their times.
+	backupName func(t time.Time) string
+}
+
+func (bp *backupPeriod) String() string {
+	return bp.name
+}
+
+func getHourlyBackupName(t time.Time) string {
+	return t.Format("2006-01-02:15")
+}
+
+func getDailyBackupName(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func getWeeklyBackupName(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-%02d", year, week)
+}
+
+func getMonthlyBackupName(t time.Time) string {
+	return t.Format("2006-01")
+}
+
+// backupManager creates backups at -dst every hour and applies retention to them.
+type backupManager struct {
+	periods []*backupPeriod
+
+	triggerCh chan struct{}
+	wg        sync.WaitGroup
+
+	// ctx is cancelled on stop in order to interrupt the current backup.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	inProgress uint32
+
+	mu         sync.Mutex
+	lastStatus backupStatus
+}
+
+// backupStatus is the status of the last backup run.
+type backupStatus struct {
+	Status              string  `json:"status"`
+	LastRunStart        string  `json:"last_run_start,omitempty"`
+	LastRunDurationSecs float64 `json:"last_run_duration_seconds,omitempty"`
+	LastSuccess         string  `json:"last_success,omitempty"`
+	LastError           string  `json:"last_error,omitempty"`
+}
+
+func newBackupManager() *backupManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backupManager{
+		periods: []*backupPeriod{
+			{name: "hourly", keepLast: *keepLastHourly, disabled: *disableHourly, backupName: getHourlyBackupName},
+			{name: "daily", keepLast: *keepLastDaily, disabled: *disableDaily, backupName: getDailyBackupName},
+			{name: "weekly", keepLast: *keepLastWeekly, disabled: *disableWeekly, backupName: getWeeklyBackupName},
+			{name: "monthly", keepLast: *keepLastMonthly, disabled: *disableMonthly, backupName: getMonthlyBackupName},
+		},
+		triggerCh: make(chan struct{}, 1),
+		ctx:       ctx,
+		cancel:    cancel,
+		lastStatus: backupStatus{
+			Status: "never",
+		},
+	}
+}
+
+// start starts backup loop in background.
+//
+// The first backup is made immediately if runOnStart is set. Otherwise it is made at the start of the next hour.
+func (bm *backupManager) start(runOnStart bool) {
+	bm.wg.Add(1)
+	go func() {
+		defer bm.wg.Done()
+		if runOnStart {
+			bm.runOnce()
+		}
+		for {
+			now := time.Now()
+			t := time.NewTimer(now.Truncate(time.Hour).Add(time.Hour).Sub(now))
+			select {
+			case <-bm.ctx.Done():
+				t.Stop()
+				return
+			case <-bm.triggerCh:
+				t.Stop()
+			case <-t.C:
+			}
+			bm.runOnce()
+		}
+	}()
+}
+
+// stop stops the backup loop. It interrupts the current backup and waits until it is stopped.
+//
+// The interrupted backup is resumed by the next backup run.
+func (bm *backupManager) stop() {
+	bm.cancel()
+	bm.wg.Wait()
+}
+
+// trigger schedules immediate backup.
+//
+// It returns false if the backup is already in progress or scheduled.
+func (bm *backupManager) trigger() bool {
+	if atomic.LoadUint32(&bm.inProgress) != 0 {
+		return false
+	}
+	select {
+	case bm.triggerCh <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (bm *backupManager) status() backupStatus {
+	bm.mu.Lock()
+	bs := bm.lastStatus
+	bm.mu.Unlock()
+	if atomic.LoadUint32(&bm.inProgress) != 0 {
+		bs.Status = "in_progress"
+	}
+	return bs
+}
+
+func (bm *backupManager) runOnce() {
+	atomic.StoreUint32(&bm.inProgress, 1)
+	defer atomic.StoreUint32(&bm.inProgress, 0)
+
+	startTime := time.Now()
+	backupsTotal.Inc()
+	err := bm.runBackup(bm.ctx, startTime.UTC())
+	d := time.Since(startTime)
+	backupDuration.Update(d.Seconds())
+
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bs := &bm.lastStatus
+	bs.LastRunStart = startTime.UTC().Format(time.RFC3339)
+	bs.LastRunDurationSecs = d.Seconds()
+	if err != nil {
+		logger.Errorf("cannot create backup: %s", err)
+		backupErrors.Inc()
+		atomic.StoreUint64(&lastRunFailed, 1)
+		bs.Status = "error"
+		bs.LastError = err.Error()
+		return
+	}
+	logger.Infof("backup has been successfully created in %.3f seconds", d.Seconds())
+	atomic.StoreUint64(&lastRunFailed, 0)
+	atomic.StoreUint64(&lastSuccessTimestamp, uint64(startTime.Unix()))
+	bs.Status = "ok"
+	bs.LastSuccess = bs.LastRunStart
+	bs.LastError = ""
+}
+
+// runBackup creates a snapshot, uploads it to the latest folder at -dst and then copies it
+// to the folders of the enabled periods, which have no complete backup yet. Then retention is applied to the backups.
+//
+// The backup is interrupted when ctx is cancelled.
+func (bm *backupManager) runBackup(ctx context.Context, t time.Time) error {
+	snapshotName, err := snapshot.Create(*snapshotCreateURL)
+	if err != nil {
+		return fmt.Errorf("cannot create snapshot: %w", err)
+	}
+	defer func() {
+		if err := snapshot.Delete(*snapshotDeleteURL, snapshotName); err != nil {
+			logger.Errorf("cannot delete snapshot %q: %s", snapshotName, err)
+		}
+	}()
+	srcFS := &fslocal.FS{
+		Dir:               *storageDataPath + "/snapshots/" + snapshotName,
+		MaxBytesPerSecond: maxBytesPerSecond.N,
+	}
+	if err := srcFS.Init(); err != nil {
+		return fmt.Errorf("cannot initialize fs for snapshot %q: %w", snapshotName, err)
+	}
+	defer srcFS.MustStop()
+
+	latestFS, err := newRemoteFS(latestDir)
+	if err != nil {
+		return err
+	}
+	defer latestFS.MustStop()
+	if err := runBackupTo(ctx, srcFS, latestFS, nil); err != nil {
+		return err
+	}
+
+	// Copy the latest backup to periodic backups. This is performed with server-side copying.
+	for _, bp := range bm.periods {
+		if bp.disabled {
+			continue
+		}
+		if err := copyToPeriod(ctx, srcFS, latestFS, bp.name+"/"+bp.backupName(t)); err != nil {
+			return err
+		}
+	}
+
+	return bm.applyRetention()
+}
+
+// copyToPeriod copies the latest backup to the given period backup if it has no complete backup yet.
+//
+// This means the period backup is created by the first successful backup run during the period.
+func copyToPeriod(ctx context.Context, srcFS *fslocal.FS, latestFS common.RemoteFS, path string) error {
+	dstFS, err := newRemoteFS(path)
+	if err != nil {
+		return err
+	}
+	defer dstFS.MustStop()
+	ok, err := dstFS.HasFile(fscommon.BackupCompleteFilename)
+	if err != nil {
+		return fmt.Errorf("cannot check whether backup at %s is complete: %w", dstFS, err)
+	}
+	if ok {
+		return nil
+	}
+	return runBackupTo(ctx, srcFS, dstFS, latestFS)
+}
+
+func runBackupTo(ctx context.Context, srcFS *fslocal.FS, dstFS common.RemoteFS, originFS common.OriginFS) error {
+	a := &actions.Backup{
+		Concurrency: *concurrency,
+		Src:         srcFS,
+		Dst:         dstFS,
+		Origin:      originFS,
+	}
+	if err := a.RunWithContext(ctx); err != nil {
+		return fmt.Errorf("cannot create backup at %s: %w", dstFS, err)
+	}
+	return nil
+}
+
+// backupInfo describes a backup at -dst.
+type backupInfo struct {
+	Name      string `json:"name"`
+	SizeBytes uint64 `json:"size_bytes"`
+}
+
+// listBackups returns all the backups at -dst.
+//
+// The latest backup goes first, while the rest of backups are sorted by their names.
+func (bm *backupManager) listBackups() ([]backupInfo, error) {
+	backups := make([]backupInfo, 0)
+	latest, err := listBackupsAt(latestDir)
+	if err != nil {
+		return nil, err
+	}
+	if size, ok := latest[""]; ok {
+		backups = append(backups, backupInfo{
+			Name:      latestDir,
+			SizeBytes: size,
+		})
+	}
+	for _, bp := range bm.periods {
+		m, err := listBackupsAt(bp.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range getSortedBackupNames(m) {
+			backups = append(backups, backupInfo{
+				Name:      bp.name + "/" + name,
+				SizeBytes: m[name],
+			})
+		}
+	}
+	return backups, nil
+}
+
+// listBackupsAt returns backup sizes keyed by backup names at the given dir at -dst.
+//
+// Data directly in dir is returned under empty name.
+func listBackupsAt(dir string) (map[string]uint64, error) {
+	fs, err := newRemoteFS(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.MustStop()
+	parts, err := fs.ListParts()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list backups at %s: %w", fs, err)
+	}
+	m := make(map[string]uint64)
+	for _, p := range parts {
+		name := ""
+		if dir != latestDir {
+			n := strings.IndexByte(p.Path, '/')
+			if n < 0 {
+				continue
+			}
+			name = p.Path[:n]
+		}
+		m[name] += p.ActualSize
+	}
+	return m, nil
+}
+
+func getSortedBackupNames(m map[string]uint64) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// restore restores the backup with the given name to -storageDataPath.
+func (bm *backupManager) restore(name string) error {
+	if err := bm.validateBackupName(name); err != nil {
+		return err
+	}
+	srcFS, err := newRemoteFS(name)
+	if err != nil {
+		return err
+	}
+	defer srcFS.MustStop()
+	dstFS := &fslocal.FS{
+		Dir:               *storageDataPath,
+		MaxBytesPerSecond: maxBytesPerSecond.N,
+	}
+	if err := dstFS.Init(); err != nil {
+		return fmt.Errorf("cannot initialize local fs at -storageDataPath=%q: %w", *storageDataPath, err)
+	}
+	defer dstFS.MustStop()
+	a := &actions.Restore{
+		Concurrency: *concurrency,
+		Src:         srcFS,
+		Dst:         dstFS,
+	}
+	if err := a.Run(); err != nil {
+		return fmt.Errorf("cannot restore backup %q: %w", name, err)
+	}
+	return nil
+}
+
+// validateBackupName verifies whether name refers to the latest backup or to a periodic backup.
+func (bm *backupManager) validateBackupName(name string) error {
+	if name == latestDir {
+		return nil
+	}
+	n := strings.IndexByte(name, '/')
+	if n > 0 && !strings.Contains(name[n+1:], "/") && name[n+1:] != "" && name[n+1:] != "." && name[n+1:] != ".." {
+		for _, bp := range bm.periods {
+			if bp.name == name[:n] {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unexpected backup name %q; it must be either %q or in the form `period/name`, where `period` is one of %s",
+		name, latestDir, bm.periods)
+}
+
+func newRemoteFS(path string) (common.RemoteFS, error) {
+	fullPath := strings.TrimSuffix(*dst, "/") + "/" + path
+	fs, err := actions.NewRemoteFS(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize remote fs at %q: %w", fullPath, err)
+	}
+	return fs, nil
+}
+
+var (
+	backupsTotal   = metrics.NewCounter(`vm_backups_total`)
+	backupErrors   = metrics.NewCounter(`vm_backup_errors_total`)
+	backupDuration = metrics.NewSummary(`vm_backup_duration_seconds`)
+
+	lastRunFailed        uint64
+	lastSuccessTimestamp uint64
+
+	_ = metrics.NewGauge(`vm_backup_last_run_failed`, func() float64 {
+		return float64(atomic.LoadUint64(&lastRunFailed))
+	})
+	_ = metrics.NewGauge(`vm_backup_last_success_timestamp_seconds`, func() float64 {
+		return float64(atomic.LoadUint64(&lastSuccessTimestamp))
+	})
+)
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/fscommon"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// applyRetention deletes backups exceeding -keepLast* limits for every period.
+func (bm *backupManager) applyRetention() error {
+	for _, bp := range bm.periods {
+		if bp.keepLast < 0 {
+			continue
+		}
+		m, err := listBackupsAt(bp.name)
+		if err != nil {
+			return err
+		}
+		names := getBackupsToDelete(getSortedBackupNames(m), bp.keepLast)
+		if len(names) == 0 {
+			continue
+		}
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = bp.name + "/" + name
+		}
+		logger.Infof("%s backups to delete %s", bp.name, paths)
+		for _, path := range paths {
+			if err := deleteBackup(path); err != nil {
+				return err
+			}
+			metrics.GetOrCreateCounter(fmt.Sprintf(`vm_backup_retention_deleted_total{period=%q}`, bp.name)).Inc()
+		}
+	}
+	return nil
+}
+
+// getBackupsToDelete returns backups, which must be deleted in order to keep only keepLast backups.
+//
+// names must be sorted in ascending order. The returned names are sorted from the newest to the oldest.
+func getBackupsToDelete(names []string, keepLast int) []string {
+	if len(names) <= keepLast {
+		return nil
+	}
+	var result []string
+	for i := len(names) - keepLast - 1; i >= 0; i-- {
+		result = append(result, names[i])
+	}
+	return result
+}
+
+// deleteBackup deletes the backup at the given path at -dst.
+func deleteBackup(path string) error {
+	fs, err := newRemoteFS(path)
+	if err != nil {
+		return err
+	}
+	defer fs.MustStop()
+
+	// Delete `backup complete` file at first, so partially deleted backup isn't used for restore.
+	if err := fs.DeleteFile(fscommon.BackupCompleteFilename); err != nil {
+		return fmt.Errorf("cannot delete `backup complete` file at %s: %w", fs, err)
+	}
+	parts, err := fs.ListParts()
+	if err != nil {
+		return fmt.Errorf("cannot list parts at %s: %w", fs, err)
+	}
+	for _, p := range parts {
+		if err := fs.DeletePart(p); err != nil {
+			return err
+		}
+	}
+	if err := fs.RemoveEmptyDirs(); err != nil {
+		return fmt.Errorf("cannot remove empty dirs at %s: %w", fs, err)
+	}
+	return nil
+}
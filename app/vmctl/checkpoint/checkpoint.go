@@ -0,0 +1,154 @@
+// Package checkpoint persists the progress of vmctl migrations,
+// so interrupted or partially failed migrations could be resumed.
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// State contains the progress of a migration split into chunks.
+//
+// Every chunk is identified by a key, such as block ULID or time range.
+// The state is stored in a local file after every change.
+type State struct {
+	path string
+
+	mu   sync.Mutex
+	data stateData
+}
+
+type stateData struct {
+	// Fingerprint identifies the migration params the state belongs to.
+	Fingerprint string `json:"fingerprint"`
+	// Completed contains keys for successfully migrated chunks.
+	Completed map[string]bool `json:"completed"`
+	// Failed contains errors for chunks failed during the last run.
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+// Open returns the state stored at path for the migration with the given fingerprint.
+//
+// The previously stored progress is loaded only if resume is set.
+// Otherwise the migration starts from scratch and the file at path is overwritten on the first change.
+func Open(path, fingerprint string, resume bool) (*State, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path to state file cannot be empty")
+	}
+	s := &State{
+		path: path,
+		data: stateData{
+			Fingerprint: fingerprint,
+			Completed:   make(map[string]bool),
+			Failed:      make(map[string]string),
+		},
+	}
+	if !resume {
+		return s, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// Nothing to resume.
+			return s, nil
+		}
+		return nil, fmt.Errorf("cannot read state file: %w", err)
+	}
+	var sd stateData
+	if err := json.Unmarshal(data, &sd); err != nil {
+		return nil, fmt.Errorf("cannot parse state file %q: %w", path, err)
+	}
+	if sd.Fingerprint != fingerprint {
+		return nil, fmt.Errorf("state file %q belongs to another migration with params %q; "+
+			"remove the file or pass another file via --state-file flag", path, sd.Fingerprint)
+	}
+	for key := range sd.Completed {
+		s.data.Completed[key] = true
+	}
+	// Failed chunks are retried on resume, so there is no need in loading them.
+	return s, nil
+}
+
+// Path returns path to the state file.
+func (s *State) Path() string {
+	return s.path
+}
+
+// IsCompleted returns true if the chunk with the given key was already migrated.
+func (s *State) IsCompleted(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Completed[key]
+}
+
+// CompletedCount returns the number of migrated chunks.
+func (s *State) CompletedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.data.Completed)
+}
+
+// MarkCompleted marks the chunk with the given key as migrated and stores the state.
+func (s *State) MarkCompleted(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Completed[key] = true
+	delete(s.data.Failed, key)
+	return s.storeLocked()
+}
+
+// MarkFailed registers the error for the chunk with the given key and stores the state.
+func (s *State) MarkFailed(key string, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data.Completed, key)
+	s.data.Failed[key] = err.Error()
+	return s.storeLocked()
+}
+
+// FailedChunks returns a sorted list of failed chunks with their errors.
+func (s *State) FailedChunks() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var a []string
+	for key, errMsg := range s.data.Failed {
+		a = append(a, fmt.Sprintf("%s: %s", key, errMsg))
+	}
+	sort.Strings(a)
+	return a
+}
+
+// Summary returns a human-readable summary for chunks failed during the current run.
+//
+// It returns an empty string if there are no failed chunks.
+func (s *State) Summary() string {
+	failed := s.FailedChunks()
+	if len(failed) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d chunks failed to migrate:\n\t%s", len(failed), strings.Join(failed, "\n\t"))
+}
+
+func (s *State) storeLocked() error {
+	data, err := json.MarshalIndent(&s.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot marshal state: %w", err)
+	}
+	// Write the state into a temporary file and then atomically rename it,
+	// so the state file isn't corrupted if vmctl is killed in the middle of write.
+	tmpPath := filepath.Join(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp")
+	if err := ioutil.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("cannot write state file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("cannot update state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package checkpoint
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStateResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	s, err := Open(path, "foo", false)
+	if err != nil {
+		t.Fatalf("cannot open state: %s", err)
+	}
+	if err := s.MarkCompleted("chunk1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := s.MarkFailed("chunk2", fmt.Errorf("some error")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := s.MarkFailed("chunk3", fmt.Errorf("temporary error")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := s.MarkCompleted("chunk3"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	failed := s.FailedChunks()
+	expectedFailed := []string{"chunk2: some error"}
+	if !reflect.DeepEqual(failed, expectedFailed) {
+		t.Fatalf("unexpected failed chunks; got %q; want %q", failed, expectedFailed)
+	}
+	if s.Summary() == "" {
+		t.Fatalf("expecting non-empty summary")
+	}
+
+	f := func(resume bool, expectedCompleted map[string]bool) {
+		t.Helper()
+		s, err := Open(path, "foo", resume)
+		if err != nil {
+			t.Fatalf("cannot open state: %s", err)
+		}
+		for _, key := range []string{"chunk1", "chunk2", "chunk3"} {
+			if s.IsCompleted(key) != expectedCompleted[key] {
+				t.Fatalf("unexpected IsCompleted(%q); got %v; want %v", key, s.IsCompleted(key), expectedCompleted[key])
+			}
+		}
+		if n := s.CompletedCount(); n != len(expectedCompleted) {
+			t.Fatalf("unexpected number of completed chunks; got %d; want %d", n, len(expectedCompleted))
+		}
+		// Failed chunks must be retried after resume
+		if failed := s.FailedChunks(); len(failed) > 0 {
+			t.Fatalf("unexpected failed chunks after open: %q", failed)
+		}
+		if summary := s.Summary(); summary != "" {
+			t.Fatalf("unexpected non-empty summary: %q", summary)
+		}
+	}
+	f(true, map[string]bool{"chunk1": true, "chunk3": true})
+	f(false, map[string]bool{})
+}
+
+func TestOpenFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := Open(path, "foo", false)
+	if err != nil {
+		t.Fatalf("cannot open state: %s", err)
+	}
+	if err := s.MarkCompleted("chunk1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Fingerprint mismatch
+	if _, err := Open(path, "bar", true); err == nil {
+		t.Fatalf("expecting non-nil error on fingerprint mismatch")
+	}
+	// The state for another migration may be overwritten without resume
+	if _, err := Open(path, "bar", false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// Empty path
+	if _, err := Open("", "foo", false); err == nil {
+		t.Fatalf("expecting non-nil error for empty path")
+	}
+	// Missing state file on resume means starting from scratch
+	s, err = Open(filepath.Join(t.TempDir(), "missing.json"), "foo", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := s.CompletedCount(); n != 0 {
+		t.Fatalf("unexpected number of completed chunks; got %d; want 0", n)
+	}
+}
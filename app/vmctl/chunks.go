@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/checkpoint"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+	"github.com/urfave/cli/v2"
+)
+
+// chunkTracker tracks chunks of time series imported via vm.Importer
+// and stores their progress in the checkpoint state.
+//
+// A chunk is completed when all its time series were read from the source
+// and successfully delivered to VictoriaMetrics.
+type chunkTracker struct {
+	st *checkpoint.State
+
+	mu sync.Mutex
+	// series maps time series sent to the importer to chunks they belong to.
+	series map[*vm.TimeSeries]*chunk
+}
+
+type chunk struct {
+	key string
+
+	// pending is the number of time series sent to the importer, which weren't delivered yet.
+	pending int
+	// readDone is set when all the time series for the chunk were read from the source.
+	readDone bool
+	// err is the first error occurred during reading or importing the chunk.
+	err error
+}
+
+func newChunkTracker(st *checkpoint.State) *chunkTracker {
+	return &chunkTracker{
+		st:     st,
+		series: make(map[*vm.TimeSeries]*chunk),
+	}
+}
+
+// start returns a new chunk for the given key.
+func (ct *chunkTracker) start(key string) *chunk {
+	return &chunk{
+		key: key,
+	}
+}
+
+// input sends ts belonging to c to im.
+func (ct *chunkTracker) input(im *vm.Importer, c *chunk, ts *vm.TimeSeries) error {
+	ct.mu.Lock()
+	c.pending++
+	ct.series[ts] = c
+	ct.mu.Unlock()
+	if err := im.Input(ts); err != nil {
+		// ts won't be delivered, so stop tracking it.
+		ct.mu.Lock()
+		if _, ok := ct.series[ts]; ok {
+			delete(ct.series, ts)
+			c.pending--
+		}
+		ct.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// finishRead must be called when reading c from the source is finished with the given err.
+func (ct *chunkTracker) finishRead(c *chunk, err error) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	c.readDone = true
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	ct.maybeCompleteLocked(c)
+}
+
+// onBatchDone must be passed to vm.Config.OnBatchDone.
+func (ct *chunkTracker) onBatchDone(batch []*vm.TimeSeries, err error) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	for _, ts := range batch {
+		c, ok := ct.series[ts]
+		if !ok {
+			continue
+		}
+		delete(ct.series, ts)
+		c.pending--
+		if err != nil && c.err == nil {
+			c.err = fmt.Errorf("import failed: %w", err)
+		}
+		ct.maybeCompleteLocked(c)
+	}
+}
+
+func (ct *chunkTracker) maybeCompleteLocked(c *chunk) {
+	if !c.readDone || c.pending > 0 {
+		return
+	}
+	var err error
+	if c.err != nil {
+		err = ct.st.MarkFailed(c.key, c.err)
+	} else {
+		err = ct.st.MarkCompleted(c.key)
+	}
+	if err != nil {
+		log.Printf("cannot store the progress for chunk %q in %q: %s", c.key, ct.st.Path(), err)
+	}
+}
+
+// checkpointSummary returns an error with the summary for chunks failed during the migration.
+func checkpointSummary(st *checkpoint.State) error {
+	summary := st.Summary()
+	if summary == "" {
+		return nil
+	}
+	return fmt.Errorf("%s\nThe progress is saved to %q. Re-run the command with --%s flag in order to retry failed chunks",
+		summary, st.Path(), resume)
+}
+
+// openCheckpointState opens the checkpoint state for the given mode according to command-line flags.
+//
+// The fingerprint must contain migration params, which identify the migration.
+func openCheckpointState(c *cli.Context, mode, fingerprint string) (*checkpoint.State, error) {
+	path := c.String(stateFile)
+	if path == "" {
+		path = fmt.Sprintf("vmctl-%s-state.json", mode)
+	}
+	st, err := checkpoint.Open(path, mode+" "+fingerprint, c.Bool(resume))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state file: %s", err)
+	}
+	return st, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/checkpoint"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+)
+
+func TestChunkTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	st, err := checkpoint.Open(path, "test", false)
+	if err != nil {
+		t.Fatalf("cannot open state: %s", err)
+	}
+	ct := newChunkTracker(st)
+
+	// send registers ts for c in the same way as chunkTracker.input does.
+	send := func(c *chunk, ts *vm.TimeSeries) {
+		ct.mu.Lock()
+		c.pending++
+		ct.series[ts] = c
+		ct.mu.Unlock()
+	}
+
+	c1 := ct.start("chunk1")
+	c2 := ct.start("chunk2")
+	c3 := ct.start("chunk3")
+	ts1 := &vm.TimeSeries{Name: "foo"}
+	ts2 := &vm.TimeSeries{Name: "bar"}
+	ts3 := &vm.TimeSeries{Name: "baz"}
+	send(c1, ts1)
+	send(c2, ts2)
+	send(c2, ts3)
+
+	// The chunk isn't completed until all its series are delivered
+	ct.finishRead(c1, nil)
+	if st.IsCompleted("chunk1") {
+		t.Fatalf("chunk1 mustn't be completed before its series are delivered")
+	}
+	// The chunk isn't completed until it is read
+	ct.onBatchDone([]*vm.TimeSeries{ts1, ts2}, nil)
+	if !st.IsCompleted("chunk1") {
+		t.Fatalf("chunk1 must be completed")
+	}
+	ct.onBatchDone([]*vm.TimeSeries{ts3}, fmt.Errorf("cannot import"))
+	if st.IsCompleted("chunk2") {
+		t.Fatalf("chunk2 mustn't be completed before it is read")
+	}
+	ct.finishRead(c2, nil)
+	ct.finishRead(c3, fmt.Errorf("cannot read"))
+	if st.IsCompleted("chunk2") || st.IsCompleted("chunk3") {
+		t.Fatalf("failed chunks mustn't be completed")
+	}
+	if len(ct.series) != 0 {
+		t.Fatalf("unexpected tracked series left: %d", len(ct.series))
+	}
+
+	failed := st.FailedChunks()
+	expectedFailed := []string{
+		"chunk2: import failed: cannot import",
+		"chunk3: cannot read",
+	}
+	if !reflect.DeepEqual(failed, expectedFailed) {
+		t.Fatalf("unexpected failed chunks;\ngot\n%q\nwant\n%q", failed, expectedFailed)
+	}
+	if err := checkpointSummary(st); err == nil {
+		t.Fatalf("expecting non-nil summary error")
+	}
+}
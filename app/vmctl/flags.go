@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/stepper"
 	"github.com/urfave/cli/v2"
 )
 
@@ -11,6 +13,29 @@ const (
 	globalVerbose = "verbose"
 )
 
+const (
+	stateFile = "state-file"
+	resume    = "resume"
+)
+
+var (
+	// checkpointFlags are used by modes, which support resuming the migration
+	checkpointFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name: stateFile,
+			Usage: "Path to the local file for storing the migration progress. The file contains the list of already migrated chunks " +
+				"such as snapshot blocks or time ranges and errors for failed chunks. By default vmctl-<mode>-state.json file in the current directory is used",
+		},
+		&cli.BoolFlag{
+			Name: resume,
+			Usage: fmt.Sprintf("Whether to resume the previously interrupted migration from the file set via --%s. "+
+				"Already migrated chunks are skipped, while failed and not yet migrated chunks are retried. "+
+				"The migration params must match the params of the previous run", stateFile),
+			Value: false,
+		},
+	}
+)
+
 var (
 	globalFlags = []cli.Flag{
 		&cli.BoolFlag{
@@ -318,6 +343,7 @@ const (
 	vmNativeFilterMatch     = "vm-native-filter-match"
 	vmNativeFilterTimeStart = "vm-native-filter-time-start"
 	vmNativeFilterTimeEnd   = "vm-native-filter-time-end"
+	vmNativeStepInterval    = "vm-native-step-interval"
 
 	vmNativeSrcAddr     = "vm-native-src-addr"
 	vmNativeSrcUser     = "vm-native-src-user"
@@ -345,6 +371,13 @@ var (
 			Name:  vmNativeFilterTimeEnd,
 			Usage: "The time filter may contain either unix timestamp in seconds or RFC3339 values. E.g. '2020-01-01T20:07:00Z'",
 		},
+		&cli.StringFlag{
+			Name: vmNativeStepInterval,
+			Usage: fmt.Sprintf("Split the migration into chunks by the given time interval. Every chunk is migrated via a separate export and import request, "+
+				"so its progress may be saved via --%s and resumed via --%s. Requires setting --%s. Valid values are %q,%q,%q,%q. "+
+				"By default the whole time range is migrated as a single chunk", stateFile, resume, vmNativeFilterTimeStart,
+				stepper.StepMonth, stepper.StepDay, stepper.StepHour, stepper.StepMinute),
+		},
 		&cli.StringFlag{
 			Name: vmNativeSrcAddr,
 			Usage: "VictoriaMetrics address to perform export from. \n" +
@@ -393,6 +426,74 @@ var (
 	}
 )
 
+const (
+	remoteReadUseStream       = "remote-read-use-stream"
+	remoteReadConcurrency     = "remote-read-concurrency"
+	remoteReadFilterTimeStart = "remote-read-filter-time-start"
+	remoteReadFilterTimeEnd   = "remote-read-filter-time-end"
+	remoteReadFilterSelector  = "remote-read-filter"
+	remoteReadStepInterval    = "remote-read-step-interval"
+	remoteReadSrcAddr         = "remote-read-src-addr"
+	remoteReadUser            = "remote-read-user"
+	remoteReadPassword        = "remote-read-password"
+	remoteReadHTTPTimeout     = "remote-read-http-timeout"
+)
+
+var (
+	remoteReadFlags = []cli.Flag{
+		&cli.IntFlag{
+			Name:  remoteReadConcurrency,
+			Usage: "Number of concurrently running remote read readers",
+			Value: 1,
+		},
+		&cli.StringFlag{
+			Name:     remoteReadFilterTimeStart,
+			Usage:    "The time filter in RFC3339 format to select timeseries with timestamp equal or higher than provided value. E.g. '2020-01-01T20:07:00Z'",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  remoteReadFilterTimeEnd,
+			Usage: "The time filter in RFC3339 format to select timeseries with timestamp equal or lower than provided value. E.g. '2020-01-01T20:07:00Z'. Current time is used by default",
+		},
+		&cli.StringFlag{
+			Name: remoteReadFilterSelector,
+			Usage: "Prometheus series selector to filter timeseries by. It may contain multiple label matchers with '=', '!=', '=~' and '!~' operators. " +
+				"E.g. '{job=\"node\",instance!~\"test.*\"}'. See https://prometheus.io/docs/prometheus/latest/querying/basics/#time-series-selectors",
+			Value: `{__name__=~".*"}`,
+		},
+		&cli.BoolFlag{
+			Name:  remoteReadUseStream,
+			Usage: "Defines whether to use SAMPLES or STREAMED_XOR_CHUNKS mode. By default, it uses SAMPLES mode. See https://prometheus.io/docs/prometheus/latest/querying/remote_read_api/#streamed-chunks",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:     remoteReadStepInterval,
+			Usage:    fmt.Sprintf("The time interval to split the migration into steps, which are read in parallel. For example, to migrate 1y of data with '--%s=month' vmctl will execute 12 read requests. Valid values are %q,%q,%q,%q.", remoteReadStepInterval, stepper.StepMonth, stepper.StepDay, stepper.StepHour, stepper.StepMinute),
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:     remoteReadSrcAddr,
+			Usage:    "Remote read address to perform read from. E.g. 'http://prometheus:9090'. The '/api/v1/read' path is appended to the address.",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:    remoteReadUser,
+			Usage:   "Remote read username for basic auth",
+			EnvVars: []string{"REMOTE_READ_USERNAME"},
+		},
+		&cli.StringFlag{
+			Name:    remoteReadPassword,
+			Usage:   "Remote read password for basic auth",
+			EnvVars: []string{"REMOTE_READ_PASSWORD"},
+		},
+		&cli.DurationFlag{
+			Name:  remoteReadHTTPTimeout,
+			Usage: "Timeout for HTTP read requests to the remote read source",
+			Value: 5 * time.Minute,
+		},
+	}
+)
+
 func mergeFlags(flags ...[]cli.Flag) []cli.Flag {
 	var result []cli.Flag
 	for _, f := range flags {
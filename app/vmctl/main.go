@@ -14,6 +14,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/influx"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/opentsdb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/prometheus"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/remoteread"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
@@ -113,11 +114,20 @@ func main() {
 			{
 				Name:  "prometheus",
 				Usage: "Migrate timeseries from Prometheus",
-				Flags: mergeFlags(globalFlags, promFlags, vmFlags),
+				Flags: mergeFlags(globalFlags, promFlags, vmFlags, checkpointFlags),
 				Action: func(c *cli.Context) error {
 					fmt.Println("Prometheus import mode")
 
+					st, err := openCheckpointState(c, "prometheus", fmt.Sprintf("snapshot=%q time-start=%q time-end=%q label=%q label-value=%q vm-addr=%q vm-account-id=%q",
+						c.String(promSnapshot), c.String(promFilterTimeStart), c.String(promFilterTimeEnd),
+						c.String(promFilterLabel), c.String(promFilterLabelValue), c.String(vmAddr), c.String(vmAccountID)))
+					if err != nil {
+						return err
+					}
+					ct := newChunkTracker(st)
+
 					vmCfg := initConfigVM(c)
+					vmCfg.OnBatchDone = ct.onBatchDone
 					importer, err = vm.NewImporter(vmCfg)
 					if err != nil {
 						return fmt.Errorf("failed to create VM importer: %s", err)
@@ -140,14 +150,63 @@ func main() {
 						cl: cl,
 						im: importer,
 						cc: c.Int(promConcurrency),
+						ct: ct,
 					}
 					return pp.run(c.Bool(globalSilent), c.Bool(globalVerbose))
 				},
 			},
+			{
+				Name:  "remote-read",
+				Usage: "Migrate timeseries by Prometheus remote read protocol",
+				Flags: mergeFlags(globalFlags, remoteReadFlags, vmFlags, checkpointFlags),
+				Action: func(c *cli.Context) error {
+					fmt.Println("Remote-read import mode")
+
+					rr, err := remoteread.NewClient(remoteread.Config{
+						Addr:           c.String(remoteReadSrcAddr),
+						Username:       c.String(remoteReadUser),
+						Password:       c.String(remoteReadPassword),
+						Timeout:        c.Duration(remoteReadHTTPTimeout),
+						UseStream:      c.Bool(remoteReadUseStream),
+						SeriesSelector: c.String(remoteReadFilterSelector),
+					})
+					if err != nil {
+						return fmt.Errorf("failed to create remote read client: %s", err)
+					}
+
+					st, err := openCheckpointState(c, "remote-read", fmt.Sprintf("src-addr=%q time-start=%q time-end=%q step-interval=%q filter=%q vm-addr=%q vm-account-id=%q",
+						c.String(remoteReadSrcAddr), c.String(remoteReadFilterTimeStart), c.String(remoteReadFilterTimeEnd), c.String(remoteReadStepInterval),
+						c.String(remoteReadFilterSelector), c.String(vmAddr), c.String(vmAccountID)))
+					if err != nil {
+						return err
+					}
+					ct := newChunkTracker(st)
+
+					vmCfg := initConfigVM(c)
+					vmCfg.OnBatchDone = ct.onBatchDone
+					importer, err = vm.NewImporter(vmCfg)
+					if err != nil {
+						return fmt.Errorf("failed to create VM importer: %s", err)
+					}
+
+					rmp := remoteReadProcessor{
+						src: rr,
+						dst: importer,
+						filter: remoteReadFilter{
+							timeStart: c.String(remoteReadFilterTimeStart),
+							timeEnd:   c.String(remoteReadFilterTimeEnd),
+							chunk:     c.String(remoteReadStepInterval),
+						},
+						cc: c.Int(remoteReadConcurrency),
+						ct: ct,
+					}
+					return rmp.run(ctx, c.Bool(globalSilent), c.Bool(globalVerbose))
+				},
+			},
 			{
 				Name:  "vm-native",
 				Usage: "Migrate time series between VictoriaMetrics installations via native binary format",
-				Flags: vmNativeFlags,
+				Flags: mergeFlags(vmNativeFlags, checkpointFlags),
 				Action: func(c *cli.Context) error {
 					fmt.Println("VictoriaMetrics Native import mode")
 
@@ -155,12 +214,20 @@ func main() {
 						return fmt.Errorf("flag %q can't be empty", vmNativeFilterMatch)
 					}
 
+					st, err := openCheckpointState(c, "vm-native", fmt.Sprintf("match=%q time-start=%q time-end=%q step-interval=%q src-addr=%q dst-addr=%q",
+						c.String(vmNativeFilterMatch), c.String(vmNativeFilterTimeStart), c.String(vmNativeFilterTimeEnd),
+						c.String(vmNativeStepInterval), c.String(vmNativeSrcAddr), c.String(vmNativeDstAddr)))
+					if err != nil {
+						return err
+					}
+
 					p := vmNativeProcessor{
 						rateLimit: c.Int64(vmRateLimit),
 						filter: filter{
 							match:     c.String(vmNativeFilterMatch),
 							timeStart: c.String(vmNativeFilterTimeStart),
 							timeEnd:   c.String(vmNativeFilterTimeEnd),
+							chunk:     c.String(vmNativeStepInterval),
 						},
 						src: &vmNativeClient{
 							addr:     strings.Trim(c.String(vmNativeSrcAddr), "/"),
@@ -173,6 +240,7 @@ func main() {
 							password:    c.String(vmNativeDstPassword),
 							extraLabels: c.StringSlice(vmExtraLabel),
 						},
+						st: st,
 					}
 					return p.run(ctx)
 				},
@@ -23,6 +23,9 @@ type prometheusProcessor struct {
 	// and defines number of concurrently
 	// running snapshot block readers
 	cc int
+	// ct tracks the progress of imported blocks
+	// and stores it in the checkpoint state
+	ct *chunkTracker
 }
 
 func (pp *prometheusProcessor) run(silent, verbose bool) error {
@@ -33,19 +36,31 @@ func (pp *prometheusProcessor) run(silent, verbose bool) error {
 	if len(blocks) < 1 {
 		return fmt.Errorf("found no blocks to import")
 	}
-	question := fmt.Sprintf("Found %d blocks to import. Continue?", len(blocks))
+	var pendingBlocks []tsdb.BlockReader
+	for _, br := range blocks {
+		if !pp.ct.st.IsCompleted(blockKey(br)) {
+			pendingBlocks = append(pendingBlocks, br)
+		}
+	}
+	if len(pendingBlocks) < 1 {
+		log.Printf("All the %d blocks were already imported according to %q", len(blocks), pp.ct.st.Path())
+		return nil
+	}
+	question := fmt.Sprintf("Found %d blocks to import. Continue?", len(pendingBlocks))
+	if skipped := len(blocks) - len(pendingBlocks); skipped > 0 {
+		question = fmt.Sprintf("Found %d blocks to import (%d blocks were already imported). Continue?", len(pendingBlocks), skipped)
+	}
 	if !silent && !prompt(question) {
 		return nil
 	}
 
-	bar := barpool.AddWithTemplate(fmt.Sprintf(barTpl, "Processing blocks"), len(blocks))
+	bar := barpool.AddWithTemplate(fmt.Sprintf(barTpl, "Processing blocks"), len(pendingBlocks))
 
 	if err := barpool.Start(); err != nil {
 		return err
 	}
 
 	blockReadersCh := make(chan tsdb.BlockReader)
-	errCh := make(chan error, pp.cc)
 	pp.im.ResetStats()
 
 	var wg sync.WaitGroup
@@ -54,20 +69,20 @@ func (pp *prometheusProcessor) run(silent, verbose bool) error {
 		go func() {
 			defer wg.Done()
 			for br := range blockReadersCh {
-				if err := pp.do(br); err != nil {
-					errCh <- fmt.Errorf("read failed for block %q: %s", br.Meta().ULID, err)
-					return
+				c := pp.ct.start(blockKey(br))
+				err := pp.do(br, c)
+				if err != nil {
+					err = fmt.Errorf("read failed for block %q: %s", br.Meta().ULID, err)
 				}
+				// errors for the particular block don't break the import,
+				// they are reported in the summary after the import.
+				pp.ct.finishRead(c, err)
 				bar.Increment()
 			}
 		}()
 	}
-	// any error breaks the import
-	for _, br := range blocks {
+	for _, br := range pendingBlocks {
 		select {
-		case promErr := <-errCh:
-			close(blockReadersCh)
-			return fmt.Errorf("prometheus error: %s", promErr)
 		case vmErr := <-pp.im.Errors():
 			close(blockReadersCh)
 			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, verbose))
@@ -79,23 +94,24 @@ func (pp *prometheusProcessor) run(silent, verbose bool) error {
 	wg.Wait()
 	// wait for all buffers to flush
 	pp.im.Close()
-	close(errCh)
 	// drain import errors channel
 	for vmErr := range pp.im.Errors() {
 		if vmErr.Err != nil {
 			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, verbose))
 		}
 	}
-	for err := range errCh {
-		return fmt.Errorf("import process failed: %s", err)
-	}
 	barpool.Stop()
 	log.Println("Import finished!")
 	log.Print(pp.im.Stats())
-	return nil
+	return checkpointSummary(pp.ct.st)
+}
+
+// blockKey returns the key for b in the checkpoint state.
+func blockKey(b tsdb.BlockReader) string {
+	return "block " + b.Meta().ULID.String()
 }
 
-func (pp *prometheusProcessor) do(b tsdb.BlockReader) error {
+func (pp *prometheusProcessor) do(b tsdb.BlockReader, c *chunk) error {
 	ss, err := pp.cl.Read(b)
 	if err != nil {
 		return fmt.Errorf("failed to read block: %s", err)
@@ -136,7 +152,7 @@ func (pp *prometheusProcessor) do(b tsdb.BlockReader) error {
 			Timestamps: timestamps,
 			Values:     values,
 		}
-		if err := pp.im.Input(&ts); err != nil {
+		if err := pp.ct.input(pp.im, c, &ts); err != nil {
 			return err
 		}
 	}
@@ -2,9 +2,11 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/checkpoint"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/prometheus"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
 )
@@ -117,10 +119,15 @@ func Test_prometheusProcessor_run(t *testing.T) {
 			client := tt.fields.cl(tt.fields.cfg)
 			importer := tt.fields.im(tt.fields.vmCfg)
 
+			st, err := checkpoint.Open(filepath.Join(t.TempDir(), "state.json"), tt.name, false)
+			if err != nil {
+				t.Fatalf("cannot open checkpoint state: %s", err)
+			}
 			pp := &prometheusProcessor{
 				cl: client,
 				im: importer,
 				cc: tt.fields.cc,
+				ct: newChunkTracker(st),
 			}
 
 			// we should answer on prompt
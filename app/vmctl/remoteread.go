@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/barpool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/remoteread"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/stepper"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+)
+
+type remoteReadProcessor struct {
+	filter remoteReadFilter
+
+	dst *vm.Importer
+	src *remoteread.Client
+
+	// cc stands for concurrency
+	// and defines number of concurrently
+	// running remote read requests
+	cc int
+	// ct tracks the progress of imported time ranges
+	// and stores it in the checkpoint state
+	ct *chunkTracker
+}
+
+type remoteReadFilter struct {
+	timeStart string
+	timeEnd   string
+	chunk     string
+}
+
+func (rrp *remoteReadProcessor) run(ctx context.Context, silent, verbose bool) error {
+	rrp.dst.ResetStats()
+	if rrp.cc < 1 {
+		rrp.cc = 1
+	}
+
+	start, end, err := rrp.filter.timeRange()
+	if err != nil {
+		return err
+	}
+	ranges, err := stepper.SplitDateRange(start, end, rrp.filter.chunk)
+	if err != nil {
+		return fmt.Errorf("failed to create date ranges for the given time filters: %w", err)
+	}
+
+	var pendingRanges [][]time.Time
+	for _, r := range ranges {
+		if !rrp.ct.st.IsCompleted(timeRangeKey(r)) {
+			pendingRanges = append(pendingRanges, r)
+		}
+	}
+	if len(pendingRanges) < 1 {
+		log.Printf("All the %d ranges were already imported according to %q", len(ranges), rrp.ct.st.Path())
+		return nil
+	}
+
+	question := fmt.Sprintf("Selected time range %q - %q will be split into %d ranges according to %q step. Continue?",
+		start.Format(time.RFC3339), end.Format(time.RFC3339), len(ranges), rrp.filter.chunk)
+	if skipped := len(ranges) - len(pendingRanges); skipped > 0 {
+		question = fmt.Sprintf("Selected time range %q - %q will be split into %d ranges according to %q step, %d ranges were already imported. Continue?",
+			start.Format(time.RFC3339), end.Format(time.RFC3339), len(ranges), rrp.filter.chunk, skipped)
+	}
+	if !silent && !prompt(question) {
+		return nil
+	}
+
+	bar := barpool.AddWithTemplate(fmt.Sprintf(barTpl, "Processing ranges"), len(pendingRanges))
+	if err := barpool.Start(); err != nil {
+		return err
+	}
+
+	rangeCh := make(chan []time.Time)
+
+	var wg sync.WaitGroup
+	wg.Add(rrp.cc)
+	for i := 0; i < rrp.cc; i++ {
+		go func() {
+			defer wg.Done()
+			for r := range rangeCh {
+				c := rrp.ct.start(timeRangeKey(r))
+				filter := &remoteread.Filter{
+					StartTimestampMs: r[0].UnixMilli(),
+					EndTimestampMs:   r[1].UnixMilli(),
+				}
+				err := rrp.do(ctx, filter, c)
+				if err != nil {
+					err = fmt.Errorf("request failed: %s", err)
+				}
+				// errors for the particular range don't break the import,
+				// they are reported in the summary after the import.
+				rrp.ct.finishRead(c, err)
+				bar.Increment()
+			}
+		}()
+	}
+
+	for _, r := range pendingRanges {
+		select {
+		case <-ctx.Done():
+			close(rangeCh)
+			return fmt.Errorf("import process was cancelled")
+		case vmErr := <-rrp.dst.Errors():
+			close(rangeCh)
+			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, verbose))
+		case rangeCh <- r:
+		}
+	}
+
+	close(rangeCh)
+	wg.Wait()
+	// wait for all buffers to flush
+	rrp.dst.Close()
+	// drain import errors channel
+	for vmErr := range rrp.dst.Errors() {
+		if vmErr.Err != nil {
+			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, verbose))
+		}
+	}
+	barpool.Stop()
+	log.Println("Import finished!")
+	log.Print(rrp.dst.Stats())
+	return checkpointSummary(rrp.ct.st)
+}
+
+// timeRangeKey returns the key for the time range r in the checkpoint state.
+func timeRangeKey(r []time.Time) string {
+	return fmt.Sprintf("time range %s - %s", r[0].Format(time.RFC3339Nano), r[1].Format(time.RFC3339Nano))
+}
+
+func (rrp *remoteReadProcessor) do(ctx context.Context, filter *remoteread.Filter, c *chunk) error {
+	return rrp.src.Read(ctx, filter, func(series *vm.TimeSeries) error {
+		if err := rrp.ct.input(rrp.dst, c, series); err != nil {
+			return fmt.Errorf("failed to read data for time range start: %d, end: %d, %s",
+				filter.StartTimestampMs, filter.EndTimestampMs, err)
+		}
+		return nil
+	})
+}
+
+// timeRange parses the time filter.
+//
+// The current time is used as the end of the time range if timeEnd isn't set.
+func (f remoteReadFilter) timeRange() (time.Time, time.Time, error) {
+	if f.timeStart == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("flag %q can't be empty", remoteReadFilterTimeStart)
+	}
+	start, err := time.Parse(time.RFC3339, f.timeStart)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("failed to parse %q: %s", remoteReadFilterTimeStart, err)
+	}
+	end := time.Now().In(start.Location())
+	if f.timeEnd != "" {
+		end, err = time.Parse(time.RFC3339, f.timeEnd)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("failed to parse %q: %s", remoteReadFilterTimeEnd, err)
+		}
+	}
+	return start, end, nil
+}
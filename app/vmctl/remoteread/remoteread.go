@@ -0,0 +1,347 @@
+package remoteread
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/metricsql"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+const (
+	defaultReadTimeout = 5 * time.Minute
+	remoteReadPath     = "/api/v1/read"
+
+	// maxFrameSize is the maximum size of a single frame in streamed response.
+	// It matches the default limit used by Prometheus remote read client.
+	maxFrameSize = 50 * 1024 * 1024
+)
+
+// StreamCallback is a callback function for processing time series
+type StreamCallback func(series *vm.TimeSeries) error
+
+// Client is an HTTP client for reading
+// time series via remote read protocol.
+type Client struct {
+	addr      string
+	c         *http.Client
+	user      string
+	password  string
+	useStream bool
+	matchers  []prompbmarshal.LabelMatcher
+}
+
+// Config is config for remote read.
+type Config struct {
+	// Addr of remote storage
+	Addr string
+	// Timeout defines timeout for HTTP requests
+	// made by remote read client
+	Timeout time.Duration
+	// Username is the remote read username, optional.
+	Username string
+	// Password is the remote read password, optional.
+	Password string
+	// UseStream defines whether to use SAMPLES or STREAMED_XOR_CHUNKS mode
+	// see https://prometheus.io/docs/prometheus/latest/querying/remote_read_api/#samples
+	// or https://prometheus.io/docs/prometheus/latest/querying/remote_read_api/#streamed-chunks
+	UseStream bool
+	// SeriesSelector is a series selector used for read requests, e.g. `{job="foo",instance!~"bar.*"}`.
+	// Is optional.
+	SeriesSelector string
+}
+
+// Filter defines a list of filters applied to requested data
+type Filter struct {
+	StartTimestampMs int64
+	EndTimestampMs   int64
+}
+
+// NewClient returns client for
+// reading time series via remote read protocol.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("config.Addr can't be empty")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultReadTimeout
+	}
+
+	var m []prompbmarshal.LabelMatcher
+	if cfg.SeriesSelector != "" {
+		var err error
+		m, err = ParseSeriesSelector(cfg.SeriesSelector)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse series selector %q: %w", cfg.SeriesSelector, err)
+		}
+	}
+
+	c := &Client{
+		c: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		},
+		addr:      strings.TrimSuffix(cfg.Addr, "/"),
+		user:      cfg.Username,
+		password:  cfg.Password,
+		useStream: cfg.UseStream,
+		matchers:  m,
+	}
+	return c, nil
+}
+
+// ParseSeriesSelector parses series selector s into a list of label matchers for remote read requests.
+//
+// For example, `foo{job="bar",instance!~"baz.*"}` is parsed into `__name__="foo"`, `job="bar"` and `instance!~"baz.*"` matchers.
+func ParseSeriesSelector(s string) ([]prompbmarshal.LabelMatcher, error) {
+	expr, err := metricsql.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	me, ok := expr.(*metricsql.MetricExpr)
+	if !ok {
+		return nil, fmt.Errorf("expecting series selector; got %q", expr.AppendString(nil))
+	}
+	if len(me.LabelFilters) == 0 {
+		return nil, fmt.Errorf("series selector must contain at least one label matcher")
+	}
+	matchers := make([]prompbmarshal.LabelMatcher, 0, len(me.LabelFilters))
+	for _, lf := range me.LabelFilters {
+		var t prompbmarshal.LabelMatcher_Type
+		switch {
+		case lf.IsRegexp && lf.IsNegative:
+			t = prompbmarshal.LabelMatcher_NRE
+		case lf.IsRegexp:
+			t = prompbmarshal.LabelMatcher_RE
+		case lf.IsNegative:
+			t = prompbmarshal.LabelMatcher_NEQ
+		default:
+			t = prompbmarshal.LabelMatcher_EQ
+		}
+		matchers = append(matchers, prompbmarshal.LabelMatcher{
+			Type:  t,
+			Name:  lf.Label,
+			Value: lf.Value,
+		})
+	}
+	return matchers, nil
+}
+
+// Read fetches data from remote read source
+func (c *Client) Read(ctx context.Context, filter *Filter, streamCb StreamCallback) error {
+	req := &prompbmarshal.ReadRequest{
+		Queries: []prompbmarshal.Query{
+			{
+				StartTimestampMs: filter.StartTimestampMs,
+				EndTimestampMs:   filter.EndTimestampMs,
+				Matchers:         c.matchers,
+			},
+		},
+	}
+	if c.useStream {
+		req.AcceptedResponseTypes = []prompbmarshal.ReadRequest_ResponseType{prompbmarshal.ReadRequest_STREAMED_XOR_CHUNKS}
+	}
+	data, err := req.Marshal()
+	if err != nil {
+		return fmt.Errorf("unable to marshal read request: %w", err)
+	}
+
+	b := snappy.Encode(nil, data)
+	if err := c.fetch(ctx, b, filter, streamCb); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return fmt.Errorf("fetch request has been cancelled")
+		}
+		return fmt.Errorf("error while fetching data from remote storage: %s", err)
+	}
+	return nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+	return c.c.Do(req)
+}
+
+func (c *Client) fetch(ctx context.Context, data []byte, filter *Filter, streamCb StreamCallback) error {
+	r := bytes.NewReader(data)
+	url := c.addr + remoteReadPath
+	req, err := http.NewRequest("POST", url, r)
+	if err != nil {
+		return fmt.Errorf("failed to create new HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Read-Version", "0.1.0")
+
+	resp, err := c.do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("error while sending request to %s: %w; Data len %d(%d)",
+			req.URL.Redacted(), err, len(data), r.Size())
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response code %d for %s. Response body %q", resp.StatusCode, req.URL.Redacted(), body)
+	}
+
+	// The server may ignore the accepted response types and return sampled response,
+	// so the response format must be detected from Content-Type header.
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-streamed-protobuf") {
+		return processStreamResponse(resp.Body, filter, streamCb)
+	}
+	return processResponse(resp.Body, streamCb)
+}
+
+func processResponse(body io.Reader, callback StreamCallback) error {
+	compressed, err := ioutil.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	uncompressed, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return fmt.Errorf("error reading response: %w", err)
+	}
+	var readResp prompb.ReadResponse
+	if err := readResp.Unmarshal(uncompressed); err != nil {
+		return fmt.Errorf("unable to unmarshal response body: %w", err)
+	}
+	for _, res := range readResp.Results {
+		for i := range res.Timeseries {
+			ts := &res.Timeseries[i]
+			series, err := newTimeSeries(ts.Labels)
+			if err != nil {
+				return err
+			}
+			for _, s := range ts.Samples {
+				series.Timestamps = append(series.Timestamps, s.Timestamp)
+				series.Values = append(series.Values, s.Value)
+			}
+			if len(series.Timestamps) == 0 {
+				continue
+			}
+			if err := callback(series); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// processStreamResponse reads delimited ChunkedReadResponse messages from body.
+//
+// Every frame consists of uvarint-encoded message size, big-endian CRC32 Castagnoli checksum of the message and the message itself.
+func processStreamResponse(body io.Reader, filter *Filter, callback StreamCallback) error {
+	br := bufio.NewReader(body)
+	var buf []byte
+	var crcBuf [4]byte
+	for {
+		size, err := binary.ReadUvarint(br)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("cannot read frame size: %w", err)
+		}
+		if size > maxFrameSize {
+			return fmt.Errorf("frame size %d exceeds the maximum allowed size %d", size, maxFrameSize)
+		}
+		if _, err := io.ReadFull(br, crcBuf[:]); err != nil {
+			return fmt.Errorf("cannot read frame checksum: %w", err)
+		}
+		if uint64(cap(buf)) < size {
+			buf = make([]byte, size)
+		}
+		buf = buf[:size]
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return fmt.Errorf("cannot read frame data: %w", err)
+		}
+		if crc32.Checksum(buf, castagnoliTable) != binary.BigEndian.Uint32(crcBuf[:]) {
+			return fmt.Errorf("frame checksum mismatch")
+		}
+
+		var res prompb.ChunkedReadResponse
+		if err := res.Unmarshal(buf); err != nil {
+			return fmt.Errorf("cannot unmarshal ChunkedReadResponse: %w", err)
+		}
+		for i := range res.ChunkedSeries {
+			cs := &res.ChunkedSeries[i]
+			series, err := newTimeSeries(cs.Labels)
+			if err != nil {
+				return err
+			}
+			for _, chk := range cs.Chunks {
+				if err := appendChunkSamples(series, chk, filter); err != nil {
+					return err
+				}
+			}
+			if len(series.Timestamps) == 0 {
+				continue
+			}
+			if err := callback(series); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// appendChunkSamples appends samples from chk within the filter time range to series.
+func appendChunkSamples(series *vm.TimeSeries, chk prompb.Chunk, filter *Filter) error {
+	if chk.Type != prompb.Chunk_XOR {
+		return fmt.Errorf("unsupported chunk encoding %d for series %s", chk.Type, series)
+	}
+	c, err := chunkenc.FromData(chunkenc.EncXOR, chk.Data)
+	if err != nil {
+		return fmt.Errorf("cannot read chunk for series %s: %w", series, err)
+	}
+	it := c.Iterator(nil)
+	for it.Next() {
+		t, v := it.At()
+		// Chunks may contain samples outside the requested time range.
+		if t < filter.StartTimestampMs || t > filter.EndTimestampMs {
+			continue
+		}
+		series.Timestamps = append(series.Timestamps, t)
+		series.Values = append(series.Values, v)
+	}
+	if err := it.Err(); err != nil {
+		return fmt.Errorf("cannot iterate over chunk for series %s: %w", series, err)
+	}
+	return nil
+}
+
+func newTimeSeries(labels []prompb.Label) (*vm.TimeSeries, error) {
+	var series vm.TimeSeries
+	for _, label := range labels {
+		if string(label.Name) == "__name__" {
+			series.Name = string(label.Value)
+			continue
+		}
+		series.LabelPairs = append(series.LabelPairs, vm.LabelPair{
+			Name:  string(label.Name),
+			Value: string(label.Value),
+		})
+	}
+	if series.Name == "" {
+		return nil, fmt.Errorf("failed to find `__name__` label in labelset %s", series)
+	}
+	return &series, nil
+}
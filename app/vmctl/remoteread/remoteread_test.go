@@ -0,0 +1,283 @@
+package remoteread
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+// newRemoteReadServer returns a server, which responds with tss to remote read requests.
+//
+// The last received query is stored in lastQuery.
+func newRemoteReadServer(t *testing.T, tss []prompbmarshal.TimeSeries, lastQuery *prompb.Query) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/read" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("X-Prometheus-Remote-Read-Version") == "" {
+			http.Error(w, "unexpected request headers", http.StatusBadRequest)
+			return
+		}
+		compressed, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, err := snappy.Decode(nil, compressed)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req prompb.ReadRequest
+		if err := req.Unmarshal(data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(req.Queries) != 1 {
+			http.Error(w, "expecting a single query", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		*lastQuery = req.Queries[0]
+		mu.Unlock()
+
+		if len(req.AcceptedResponseTypes) > 0 && req.AcceptedResponseTypes[0] == prompb.ReadRequest_STREAMED_XOR_CHUNKS {
+			w.Header().Set("Content-Type", "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse")
+			for _, ts := range tss {
+				chunk := chunkenc.NewXORChunk()
+				app, err := chunk.Appender()
+				if err != nil {
+					t.Errorf("cannot create chunk appender: %s", err)
+					return
+				}
+				minTs, maxTs := ts.Samples[0].Timestamp, ts.Samples[len(ts.Samples)-1].Timestamp
+				for _, s := range ts.Samples {
+					app.Append(s.Timestamp, s.Value)
+				}
+				resp := &prompbmarshal.ChunkedReadResponse{
+					ChunkedSeries: []prompbmarshal.ChunkedSeries{{
+						Labels: ts.Labels,
+						Chunks: []prompbmarshal.Chunk{{
+							MinTimeMs: minTs,
+							MaxTimeMs: maxTs,
+							Type:      prompbmarshal.Chunk_XOR,
+							Data:      chunk.Bytes(),
+						}},
+					}},
+				}
+				msg, err := resp.Marshal()
+				if err != nil {
+					t.Errorf("cannot marshal ChunkedReadResponse: %s", err)
+					return
+				}
+				var frame []byte
+				var sizeBuf [binary.MaxVarintLen64]byte
+				n := binary.PutUvarint(sizeBuf[:], uint64(len(msg)))
+				frame = append(frame, sizeBuf[:n]...)
+				var crcBuf [4]byte
+				binary.BigEndian.PutUint32(crcBuf[:], crc32.Checksum(msg, crc32.MakeTable(crc32.Castagnoli)))
+				frame = append(frame, crcBuf[:]...)
+				frame = append(frame, msg...)
+				_, _ = w.Write(frame)
+			}
+			return
+		}
+
+		resp := &prompbmarshal.ReadResponse{
+			Results: []prompbmarshal.QueryResult{{Timeseries: tss}},
+		}
+		msg, err := resp.Marshal()
+		if err != nil {
+			t.Errorf("cannot marshal ReadResponse: %s", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.Header().Set("Content-Encoding", "snappy")
+		_, _ = w.Write(snappy.Encode(nil, msg))
+	}))
+}
+
+func TestClientRead(t *testing.T) {
+	tss := []prompbmarshal.TimeSeries{
+		{
+			Labels: []prompbmarshal.Label{
+				{Name: "__name__", Value: "foo"},
+				{Name: "job", Value: "bar"},
+			},
+			Samples: []prompbmarshal.Sample{
+				{Timestamp: 1000, Value: 1},
+				{Timestamp: 2000, Value: 2},
+				{Timestamp: 3000, Value: 3},
+			},
+		},
+		{
+			Labels: []prompbmarshal.Label{
+				{Name: "__name__", Value: "baz"},
+			},
+			Samples: []prompbmarshal.Sample{
+				{Timestamp: 1500, Value: 10.5},
+			},
+		},
+	}
+
+	f := func(useStream bool, filter *Filter, expected []vm.TimeSeries) {
+		t.Helper()
+		var lastQuery prompb.Query
+		s := newRemoteReadServer(t, tss, &lastQuery)
+		defer s.Close()
+
+		c, err := NewClient(Config{
+			Addr:           s.URL + "/",
+			UseStream:      useStream,
+			SeriesSelector: `{job=~"ba.*",instance!="x"}`,
+		})
+		if err != nil {
+			t.Fatalf("cannot create client: %s", err)
+		}
+		var result []vm.TimeSeries
+		err = c.Read(context.Background(), filter, func(series *vm.TimeSeries) error {
+			result = append(result, *series)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("unexpected time series;\ngot\n%v\nwant\n%v", result, expected)
+		}
+
+		if lastQuery.StartTimestampMs != filter.StartTimestampMs || lastQuery.EndTimestampMs != filter.EndTimestampMs {
+			t.Fatalf("unexpected time range in the query; got %d - %d; want %d - %d",
+				lastQuery.StartTimestampMs, lastQuery.EndTimestampMs, filter.StartTimestampMs, filter.EndTimestampMs)
+		}
+		if len(lastQuery.Matchers) != 2 {
+			t.Fatalf("unexpected number of matchers in the query; got %d; want 2", len(lastQuery.Matchers))
+		}
+		m := lastQuery.Matchers[0]
+		if m.Type != prompb.LabelMatcher_RE || string(m.Name) != "job" || string(m.Value) != "ba.*" {
+			t.Fatalf("unexpected matcher in the query: %s %d %s", m.Name, m.Type, m.Value)
+		}
+		m = lastQuery.Matchers[1]
+		if m.Type != prompb.LabelMatcher_NEQ || string(m.Name) != "instance" || string(m.Value) != "x" {
+			t.Fatalf("unexpected matcher in the query: %s %d %s", m.Name, m.Type, m.Value)
+		}
+	}
+
+	expected := []vm.TimeSeries{
+		{
+			Name:       "foo",
+			LabelPairs: []vm.LabelPair{{Name: "job", Value: "bar"}},
+			Timestamps: []int64{1000, 2000, 3000},
+			Values:     []float64{1, 2, 3},
+		},
+		{
+			Name:       "baz",
+			Timestamps: []int64{1500},
+			Values:     []float64{10.5},
+		},
+	}
+	filter := &Filter{StartTimestampMs: 1000, EndTimestampMs: 3000}
+	f(false, filter, expected)
+	f(true, filter, expected)
+
+	// Streamed chunks must be limited by the requested time range
+	f(true, &Filter{StartTimestampMs: 1500, EndTimestampMs: 2500}, []vm.TimeSeries{
+		{
+			Name:       "foo",
+			LabelPairs: []vm.LabelPair{{Name: "job", Value: "bar"}},
+			Timestamps: []int64{2000},
+			Values:     []float64{2},
+		},
+		{
+			Name:       "baz",
+			Timestamps: []int64{1500},
+			Values:     []float64{10.5},
+		},
+	})
+}
+
+func TestClientReadFailure(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	c, err := NewClient(Config{Addr: s.URL})
+	if err != nil {
+		t.Fatalf("cannot create client: %s", err)
+	}
+	err = c.Read(context.Background(), &Filter{StartTimestampMs: 0, EndTimestampMs: 1000}, func(series *vm.TimeSeries) error {
+		t.Fatalf("unexpected call for series %s", series)
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+
+	if _, err := NewClient(Config{}); err == nil {
+		t.Fatalf("expecting non-nil error for empty address")
+	}
+	if _, err := NewClient(Config{Addr: s.URL, SeriesSelector: "foo{"}); err == nil {
+		t.Fatalf("expecting non-nil error for invalid series selector")
+	}
+}
+
+func TestParseSeriesSelectorSuccess(t *testing.T) {
+	f := func(s string, expected []prompbmarshal.LabelMatcher) {
+		t.Helper()
+		result, err := ParseSeriesSelector(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("unexpected matchers;\ngot\n%v\nwant\n%v", result, expected)
+		}
+	}
+
+	f(`{__name__=~".*"}`, []prompbmarshal.LabelMatcher{
+		{Type: prompbmarshal.LabelMatcher_RE, Name: "__name__", Value: ".*"},
+	})
+	f(`foo`, []prompbmarshal.LabelMatcher{
+		{Type: prompbmarshal.LabelMatcher_EQ, Name: "__name__", Value: "foo"},
+	})
+	f(`foo{job="bar",instance!="baz",env=~"prod|dev",host!~"test.*"}`, []prompbmarshal.LabelMatcher{
+		{Type: prompbmarshal.LabelMatcher_EQ, Name: "__name__", Value: "foo"},
+		{Type: prompbmarshal.LabelMatcher_EQ, Name: "job", Value: "bar"},
+		{Type: prompbmarshal.LabelMatcher_NEQ, Name: "instance", Value: "baz"},
+		{Type: prompbmarshal.LabelMatcher_RE, Name: "env", Value: "prod|dev"},
+		{Type: prompbmarshal.LabelMatcher_NRE, Name: "host", Value: "test.*"},
+	})
+}
+
+func TestParseSeriesSelectorFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		result, err := ParseSeriesSelector(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error; got %v", result)
+		}
+	}
+
+	// Invalid syntax
+	f(`foo{`)
+	f(`{job="bar"`)
+
+	// Not a series selector
+	f(`sum(foo)`)
+	f(`rate(foo[5m])`)
+	f(`1`)
+}
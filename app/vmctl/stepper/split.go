@@ -0,0 +1,64 @@
+package stepper
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// StepMonth represents a one month interval
+	StepMonth string = "month"
+	// StepDay represents a one day interval
+	StepDay string = "day"
+	// StepHour represents a one hour interval
+	StepHour string = "hour"
+	// StepMinute represents a one minute interval
+	StepMinute string = "minute"
+)
+
+// SplitDateRange splits start-end range in a subset of ranges respecting the given step.
+//
+// Ranges don't overlap: every range except the last one ends a millisecond
+// before the start of the next range, while the last range ends at end.
+func SplitDateRange(start, end time.Time, step string) ([][]time.Time, error) {
+	if start.After(end) {
+		return nil, fmt.Errorf("start time %q should come before end time %q", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	var nextStep func(time.Time) time.Time
+	switch step {
+	case StepMonth:
+		nextStep = func(t time.Time) time.Time {
+			// Align the next range to the beginning of the next month.
+			y, m, _ := t.Date()
+			return time.Date(y, m+1, 1, 0, 0, 0, 0, t.Location())
+		}
+	case StepDay:
+		nextStep = func(t time.Time) time.Time {
+			return t.Add(24 * time.Hour)
+		}
+	case StepHour:
+		nextStep = func(t time.Time) time.Time {
+			return t.Add(time.Hour)
+		}
+	case StepMinute:
+		nextStep = func(t time.Time) time.Time {
+			return t.Add(time.Minute)
+		}
+	default:
+		return nil, fmt.Errorf("failed to parse step value, valid values are: %q, %q, %q, %q; got %q", StepMonth, StepDay, StepHour, StepMinute, step)
+	}
+
+	var ranges [][]time.Time
+	currentStart := start
+	for {
+		next := nextStep(currentStart)
+		if !next.Before(end) {
+			ranges = append(ranges, []time.Time{currentStart, end})
+			break
+		}
+		ranges = append(ranges, []time.Time{currentStart, next.Add(-time.Millisecond)})
+		currentStart = next
+	}
+	return ranges, nil
+}
@@ -0,0 +1,72 @@
+package stepper
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func mustParseTime(t *testing.T, s string) time.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		t.Fatalf("cannot parse %q: %s", s, err)
+	}
+	return ts
+}
+
+func TestSplitDateRangeFailure(t *testing.T) {
+	f := func(start, end, step string) {
+		t.Helper()
+		ranges, err := SplitDateRange(mustParseTime(t, start), mustParseTime(t, end), step)
+		if err == nil {
+			t.Fatalf("expecting non-nil error; got ranges %v", ranges)
+		}
+	}
+	// start after end
+	f("2022-01-02T00:00:00Z", "2022-01-01T00:00:00Z", StepDay)
+	// unsupported step
+	f("2022-01-01T00:00:00Z", "2022-01-02T00:00:00Z", "week")
+	f("2022-01-01T00:00:00Z", "2022-01-02T00:00:00Z", "")
+}
+
+func TestSplitDateRangeSuccess(t *testing.T) {
+	f := func(start, end, step string, expected [][]string) {
+		t.Helper()
+		ranges, err := SplitDateRange(mustParseTime(t, start), mustParseTime(t, end), step)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var got [][]string
+		for _, r := range ranges {
+			got = append(got, []string{r[0].Format(time.RFC3339Nano), r[1].Format(time.RFC3339Nano)})
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("unexpected ranges;\ngot\n%q\nwant\n%q", got, expected)
+		}
+	}
+
+	// The whole range fits a single step
+	f("2022-01-01T10:00:00Z", "2022-01-01T10:00:30Z", StepMinute, [][]string{
+		{"2022-01-01T10:00:00Z", "2022-01-01T10:00:30Z"},
+	})
+	// Zero-length range
+	f("2022-01-01T10:00:00Z", "2022-01-01T10:00:00Z", StepHour, [][]string{
+		{"2022-01-01T10:00:00Z", "2022-01-01T10:00:00Z"},
+	})
+	f("2022-01-01T10:00:00Z", "2022-01-01T12:30:00Z", StepHour, [][]string{
+		{"2022-01-01T10:00:00Z", "2022-01-01T10:59:59.999Z"},
+		{"2022-01-01T11:00:00Z", "2022-01-01T11:59:59.999Z"},
+		{"2022-01-01T12:00:00Z", "2022-01-01T12:30:00Z"},
+	})
+	f("2022-01-01T00:00:00Z", "2022-01-03T00:00:00Z", StepDay, [][]string{
+		{"2022-01-01T00:00:00Z", "2022-01-01T23:59:59.999Z"},
+		{"2022-01-02T00:00:00Z", "2022-01-03T00:00:00Z"},
+	})
+	// Months are aligned to the beginning of the month
+	f("2022-01-15T00:00:00Z", "2022-03-10T00:00:00Z", StepMonth, [][]string{
+		{"2022-01-15T00:00:00Z", "2022-01-31T23:59:59.999Z"},
+		{"2022-02-01T00:00:00Z", "2022-02-28T23:59:59.999Z"},
+		{"2022-03-01T00:00:00Z", "2022-03-10T00:00:00Z"},
+	})
+}
@@ -55,6 +55,11 @@ type Config struct {
 	RateLimit int64
 	// Whether to disable progress bar per VM worker
 	DisableProgressBar bool
+	// OnBatchDone is an optional callback, which is called after every batch
+	// import attempt with the import result.
+	// If set, failed batches are reported only via OnBatchDone,
+	// so they don't abort the import process via Errors channel.
+	OnBatchDone func(batch []*TimeSeries, err error)
 }
 
 // Importer performs insertion of timeseries
@@ -73,6 +78,8 @@ type Importer struct {
 
 	rl *limiter.Limiter
 
+	onBatchDone func(batch []*TimeSeries, err error)
+
 	wg   sync.WaitGroup
 	once sync.Once
 
@@ -128,15 +135,16 @@ func NewImporter(cfg Config) (*Importer, error) {
 	}
 
 	im := &Importer{
-		addr:       addr,
-		importPath: importPath,
-		compress:   cfg.Compress,
-		user:       cfg.User,
-		password:   cfg.Password,
-		rl:         limiter.NewLimiter(cfg.RateLimit),
-		close:      make(chan struct{}),
-		input:      make(chan *TimeSeries, cfg.Concurrency*4),
-		errors:     make(chan *ImportError, cfg.Concurrency),
+		addr:        addr,
+		importPath:  importPath,
+		compress:    cfg.Compress,
+		user:        cfg.User,
+		password:    cfg.Password,
+		rl:          limiter.NewLimiter(cfg.RateLimit),
+		onBatchDone: cfg.OnBatchDone,
+		close:       make(chan struct{}),
+		input:       make(chan *TimeSeries, cfg.Concurrency*4),
+		errors:      make(chan *ImportError, cfg.Concurrency),
 	}
 	if err := im.Ping(); err != nil {
 		return nil, fmt.Errorf("ping to %q failed: %s", addr, err)
@@ -213,7 +221,10 @@ func (im *Importer) startWorker(bar *pb.ProgressBar, batchSize, significantFigur
 			exitErr := &ImportError{
 				Batch: batch,
 			}
-			if err := im.Import(batch); err != nil {
+			err := im.Import(batch)
+			if im.onBatchDone != nil {
+				im.onBatchDone(batch, err)
+			} else if err != nil {
 				exitErr.Err = err
 			}
 			im.errors <- exitErr
@@ -250,7 +261,10 @@ func (im *Importer) startWorker(bar *pb.ProgressBar, batchSize, significantFigur
 			im.s.idleDuration += time.Since(waitForBatch)
 			im.s.Unlock()
 
-			if err := im.flush(batch); err != nil {
+			err := im.flush(batch)
+			if im.onBatchDone != nil {
+				im.onBatchDone(batch, err)
+			} else if err != nil {
 				im.errors <- &ImportError{
 					Batch: batch,
 					Err:   err,
@@ -7,10 +7,15 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/barpool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/checkpoint"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/limiter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/stepper"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+	"github.com/dmitryk-dk/pb/v3"
 )
 
 type vmNativeProcessor struct {
@@ -19,6 +24,9 @@ type vmNativeProcessor struct {
 
 	dst *vmNativeClient
 	src *vmNativeClient
+
+	// st stores the progress of migrated time ranges
+	st *checkpoint.State
 }
 
 type vmNativeClient struct {
@@ -32,6 +40,7 @@ type filter struct {
 	match     string
 	timeStart string
 	timeEnd   string
+	chunk     string
 }
 
 func (f filter) String() string {
@@ -45,6 +54,55 @@ func (f filter) String() string {
 	return s
 }
 
+// key returns the key for the time range of f in the checkpoint state.
+func (f filter) key() string {
+	return fmt.Sprintf("start=%q end=%q", f.timeStart, f.timeEnd)
+}
+
+// split splits f into filters by time ranges according to f.chunk.
+//
+// f is returned as is if f.chunk is empty.
+func (f filter) split() ([]filter, error) {
+	if f.chunk == "" {
+		return []filter{f}, nil
+	}
+	if f.timeStart == "" {
+		return nil, fmt.Errorf("flag %q must be set when %q is set", vmNativeFilterTimeStart, vmNativeStepInterval)
+	}
+	start, err := parseTime(f.timeStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %q: %s", vmNativeFilterTimeStart, err)
+	}
+	end := time.Now().In(start.Location())
+	if f.timeEnd != "" {
+		end, err = parseTime(f.timeEnd)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %s", vmNativeFilterTimeEnd, err)
+		}
+	}
+	ranges, err := stepper.SplitDateRange(start, end, f.chunk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create date ranges for the given time filters: %w", err)
+	}
+	filters := make([]filter, 0, len(ranges))
+	for _, r := range ranges {
+		filters = append(filters, filter{
+			match:     f.match,
+			timeStart: r[0].Format(time.RFC3339Nano),
+			timeEnd:   r[1].Format(time.RFC3339Nano),
+		})
+	}
+	return filters, nil
+}
+
+// parseTime parses s either as unix timestamp in seconds or as RFC3339 time.
+func parseTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Unix(0, int64(secs*1e9)).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
 const (
 	nativeExportAddr = "api/v1/export/native"
 	nativeImportAddr = "api/v1/import/native"
@@ -53,44 +111,90 @@ const (
 )
 
 func (p *vmNativeProcessor) run(ctx context.Context) error {
+	filters, err := p.pendingFilters()
+	if err != nil {
+		return err
+	}
+	if len(filters) < 1 {
+		return nil
+	}
+
+	fmt.Printf("Initing import process to %q:\n", p.dst.addr)
+	bar := barpool.AddWithTemplate(nativeBarTpl, 0)
+	if err := barpool.Start(); err != nil {
+		log.Printf("error start process bars pool: %s", err)
+		return err
+	}
+	defer barpool.Stop()
+	return p.migrate(ctx, filters, bar)
+}
+
+// pendingFilters returns filters for time ranges, which weren't migrated yet according to p.st.
+func (p *vmNativeProcessor) pendingFilters() ([]filter, error) {
+	filters, err := p.filter.split()
+	if err != nil {
+		return nil, err
+	}
+	var pending []filter
+	for _, f := range filters {
+		if !p.st.IsCompleted(f.key()) {
+			pending = append(pending, f)
+		}
+	}
+	if len(pending) < 1 {
+		log.Printf("All the %d time ranges were already migrated according to %q", len(filters), p.st.Path())
+		return nil, nil
+	}
+	if skipped := len(filters) - len(pending); skipped > 0 {
+		log.Printf("Skipping %d time ranges, which were already migrated according to %q", skipped, p.st.Path())
+	}
+	return pending, nil
+}
+
+// migrate migrates data for the given filters one by one and stores the progress in p.st.
+//
+// Errors for the particular filters don't break the migration - they are returned in the summary.
+func (p *vmNativeProcessor) migrate(ctx context.Context, filters []filter, bar *pb.ProgressBar) error {
+	for _, f := range filters {
+		err := p.runSingle(ctx, f, bar)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("migration was cancelled: %s", err)
+			}
+			err = p.st.MarkFailed(f.key(), err)
+		} else {
+			err = p.st.MarkCompleted(f.key())
+		}
+		if err != nil {
+			log.Printf("cannot store the progress for time range %s in %q: %s", f.key(), p.st.Path(), err)
+		}
+	}
+	log.Println("Import finished!")
+	return checkpointSummary(p.st)
+}
+
+// runSingle migrates data for the given f via a single export and import request.
+func (p *vmNativeProcessor) runSingle(ctx context.Context, f filter, bar *pb.ProgressBar) error {
 	pr, pw := io.Pipe()
 
-	fmt.Printf("Initing export pipe from %q with filters: %s\n", p.src.addr, p.filter)
-	exportReader, err := p.exportPipe(ctx)
+	fmt.Printf("Initing export pipe from %q with filters: %s\n", p.src.addr, f)
+	exportReader, err := p.exportPipe(ctx, f)
 	if err != nil {
 		return fmt.Errorf("failed to init export pipe: %s", err)
 	}
+	defer func() { _ = exportReader.Close() }()
 
 	nativeImportAddr, err := vm.AddExtraLabelsToImportPath(nativeImportAddr, p.dst.extraLabels)
 	if err != nil {
 		return err
 	}
 
-	sync := make(chan struct{})
+	importErrCh := make(chan error, 1)
 	go func() {
-		defer func() { close(sync) }()
-		u := fmt.Sprintf("%s/%s", p.dst.addr, nativeImportAddr)
-		req, err := http.NewRequestWithContext(ctx, "POST", u, pr)
-		if err != nil {
-			log.Fatalf("cannot create import request to %q: %s", p.dst.addr, err)
-		}
-		importResp, err := p.dst.do(req, http.StatusNoContent)
-		if err != nil {
-			log.Fatalf("import request failed: %s", err)
-		}
-		if err := importResp.Body.Close(); err != nil {
-			log.Fatalf("cannot close import response body: %s", err)
-		}
+		importErrCh <- p.importPipe(ctx, nativeImportAddr, pr)
 	}()
 
-	fmt.Printf("Initing import process to %q:\n", p.dst.addr)
-	bar := barpool.AddWithTemplate(nativeBarTpl, 0)
 	barReader := bar.NewProxyReader(exportReader)
-	if err := barpool.Start(); err != nil {
-		log.Printf("error start process bars pool: %s", err)
-		return err
-	}
-
 	w := io.Writer(pw)
 	if p.rateLimit > 0 {
 		rl := limiter.NewLimiter(p.rateLimit)
@@ -99,20 +203,40 @@ func (p *vmNativeProcessor) run(ctx context.Context) error {
 
 	_, err = io.Copy(w, barReader)
 	if err != nil {
+		// unblock the import request
+		_ = pw.CloseWithError(err)
+		if importErr := <-importErrCh; importErr != nil {
+			return importErr
+		}
 		return fmt.Errorf("failed to write into %q: %s", p.dst.addr, err)
 	}
 
 	if err := pw.Close(); err != nil {
 		return err
 	}
-	<-sync
+	return <-importErrCh
+}
 
-	barpool.Stop()
-	log.Println("Import finished!")
+func (p *vmNativeProcessor) importPipe(ctx context.Context, importAddr string, pr *io.PipeReader) error {
+	u := fmt.Sprintf("%s/%s", p.dst.addr, importAddr)
+	req, err := http.NewRequestWithContext(ctx, "POST", u, pr)
+	if err != nil {
+		_ = pr.CloseWithError(err)
+		return fmt.Errorf("cannot create import request to %q: %s", p.dst.addr, err)
+	}
+	importResp, err := p.dst.do(req, http.StatusNoContent)
+	if err != nil {
+		// unblock the export reader
+		_ = pr.CloseWithError(err)
+		return fmt.Errorf("import request failed: %s", err)
+	}
+	if err := importResp.Body.Close(); err != nil {
+		return fmt.Errorf("cannot close import response body: %s", err)
+	}
 	return nil
 }
 
-func (p *vmNativeProcessor) exportPipe(ctx context.Context) (io.ReadCloser, error) {
+func (p *vmNativeProcessor) exportPipe(ctx context.Context, f filter) (io.ReadCloser, error) {
 	u := fmt.Sprintf("%s/%s", p.src.addr, nativeExportAddr)
 	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
@@ -120,12 +244,12 @@ func (p *vmNativeProcessor) exportPipe(ctx context.Context) (io.ReadCloser, erro
 	}
 
 	params := req.URL.Query()
-	params.Set("match[]", p.filter.match)
-	if p.filter.timeStart != "" {
-		params.Set("start", p.filter.timeStart)
+	params.Set("match[]", f.match)
+	if f.timeStart != "" {
+		params.Set("start", f.timeStart)
 	}
-	if p.filter.timeEnd != "" {
-		params.Set("end", p.filter.timeEnd)
+	if f.timeEnd != "" {
+		params.Set("end", f.timeEnd)
 	}
 	req.URL.RawQuery = params.Encode()
 
@@ -2,8 +2,19 @@ package main
 
 import (
 	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/checkpoint"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/stepper"
+	"github.com/dmitryk-dk/pb/v3"
 )
 
 // If you want to run this test:
@@ -78,11 +89,16 @@ func Test_vmNativeProcessor_run(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx, cancelFn := context.WithCancel(context.Background())
+			st, err := checkpoint.Open(filepath.Join(t.TempDir(), "state.json"), tt.name, false)
+			if err != nil {
+				t.Fatalf("cannot open checkpoint state: %s", err)
+			}
 			p := &vmNativeProcessor{
 				filter:    tt.fields.filter,
 				rateLimit: tt.fields.rateLimit,
 				dst:       tt.fields.dst,
 				src:       tt.fields.src,
+				st:        st,
 			}
 
 			tt.closer(cancelFn)
@@ -93,3 +109,97 @@ func Test_vmNativeProcessor_run(t *testing.T) {
 		})
 	}
 }
+
+func TestVMNativeProcessorResume(t *testing.T) {
+	var mu sync.Mutex
+	failStart := "2022-01-02T00:00:00Z"
+	var imported []string
+
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/export/native" || r.FormValue("match[]") != `{job="foo"}` {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		// Return the time range as exported data, so it could be verified on import.
+		_, _ = w.Write([]byte(r.FormValue("start") + " " + r.FormValue("end")))
+	}))
+	defer src.Close()
+	dst := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failStart != "" && string(data[:len(failStart)]) == failStart {
+			http.Error(w, "cannot import data", http.StatusServiceUnavailable)
+			return
+		}
+		imported = append(imported, string(data))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer dst.Close()
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	f := func(resume bool, expectedImported []string, expectErr bool) {
+		t.Helper()
+		st, err := checkpoint.Open(path, "test", resume)
+		if err != nil {
+			t.Fatalf("cannot open checkpoint state: %s", err)
+		}
+		p := &vmNativeProcessor{
+			filter: filter{
+				match:     `{job="foo"}`,
+				timeStart: "2022-01-01T00:00:00Z",
+				timeEnd:   "1641168000", // 2022-01-03T00:00:00Z
+				chunk:     stepper.StepDay,
+			},
+			src: &vmNativeClient{addr: src.URL},
+			dst: &vmNativeClient{addr: dst.URL},
+			st:  st,
+		}
+		mu.Lock()
+		imported = nil
+		mu.Unlock()
+		filters, err := p.pendingFilters()
+		if err != nil {
+			t.Fatalf("cannot get pending filters: %s", err)
+		}
+		err = p.migrate(context.Background(), filters, pb.New(0))
+		if expectErr && err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if !expectErr && err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		mu.Lock()
+		sort.Strings(imported)
+		if !reflect.DeepEqual(imported, expectedImported) {
+			t.Fatalf("unexpected imported ranges;\ngot\n%q\nwant\n%q", imported, expectedImported)
+		}
+		mu.Unlock()
+	}
+
+	// The failed chunk doesn't break the migration
+	f(false, []string{
+		"2022-01-01T00:00:00Z 2022-01-01T23:59:59.999Z",
+	}, true)
+
+	// Only the failed chunk must be migrated on resume
+	mu.Lock()
+	failStart = ""
+	mu.Unlock()
+	f(true, []string{
+		"2022-01-02T00:00:00Z 2022-01-03T00:00:00Z",
+	}, false)
+
+	// Nothing to migrate
+	f(true, nil, false)
+
+	// Everything is migrated from scratch without resume
+	f(false, []string{
+		"2022-01-01T00:00:00Z 2022-01-01T23:59:59.999Z",
+		"2022-01-02T00:00:00Z 2022-01-03T00:00:00Z",
+	}, false)
+}
@@ -19,7 +19,15 @@ type InsertCtx struct {
 	mrs            []storage.MetricRow
 	metricNamesBuf []byte
 
+	ers          []storage.ExemplarRow
+	exemplarTags []storage.Tag
+
+	mms []storage.MetricMetadata
+
 	relabelCtx relabel.Ctx
+
+	// skipStreamAggr is set for rows produced by stream aggregation, so they aren't aggregated again.
+	skipStreamAggr bool
 }
 
 // Reset resets ctx for future fill with rowsLen rows.
@@ -41,7 +49,23 @@ func (ctx *InsertCtx) Reset(rowsLen int) {
 	}
 	ctx.mrs = ctx.mrs[:0]
 	ctx.metricNamesBuf = ctx.metricNamesBuf[:0]
+
+	for i := range ctx.ers {
+		ctx.ers[i] = storage.ExemplarRow{}
+	}
+	ctx.ers = ctx.ers[:0]
+	for i := range ctx.exemplarTags {
+		ctx.exemplarTags[i].Reset()
+	}
+	ctx.exemplarTags = ctx.exemplarTags[:0]
+
+	for i := range ctx.mms {
+		ctx.mms[i] = storage.MetricMetadata{}
+	}
+	ctx.mms = ctx.mms[:0]
+
 	ctx.relabelCtx.Reset()
+	ctx.skipStreamAggr = false
 }
 
 func (ctx *InsertCtx) marshalMetricNameRaw(prefix []byte, labels []prompb.Label) []byte {
@@ -69,6 +93,45 @@ func (ctx *InsertCtx) WriteDataPointExt(metricNameRaw []byte, labels []prompb.La
 	return metricNameRaw, err
 }
 
+// WriteExemplar writes exemplar e for the series with the given metricNameRaw and labels into ctx buffer.
+//
+// It returns metricNameRaw for the given labels if len(metricNameRaw) == 0.
+// e contents must exist until FlushBufs call.
+func (ctx *InsertCtx) WriteExemplar(metricNameRaw []byte, labels []prompb.Label, e *prompb.Exemplar) []byte {
+	if len(metricNameRaw) == 0 {
+		metricNameRaw = ctx.marshalMetricNameRaw(nil, labels)
+	}
+	tagsLen := len(ctx.exemplarTags)
+	for i := range e.Labels {
+		label := &e.Labels[i]
+		ctx.exemplarTags = append(ctx.exemplarTags, storage.Tag{
+			Key:   label.Name,
+			Value: label.Value,
+		})
+	}
+	ctx.ers = append(ctx.ers, storage.ExemplarRow{
+		MetricNameRaw: metricNameRaw,
+		Exemplar: storage.Exemplar{
+			Tags:      ctx.exemplarTags[tagsLen:],
+			Value:     e.Value,
+			Timestamp: e.Timestamp,
+		},
+	})
+	return metricNameRaw
+}
+
+// WriteMetricMetadata writes metadata for the given metric family into ctx buffer.
+//
+// metricFamilyName, typ, help and unit contents must exist until FlushBufs call.
+func (ctx *InsertCtx) WriteMetricMetadata(metricFamilyName, typ, help, unit string) {
+	ctx.mms = append(ctx.mms, storage.MetricMetadata{
+		MetricFamilyName: metricFamilyName,
+		Type:             typ,
+		Help:             help,
+		Unit:             unit,
+	})
+}
+
 func (ctx *InsertCtx) addRow(metricNameRaw []byte, timestamp int64, value float64) error {
 	mrs := ctx.mrs
 	if cap(mrs) > len(mrs) {
@@ -132,7 +195,28 @@ func (ctx *InsertCtx) ApplyRelabeling() {
 
 // FlushBufs flushes buffered rows to the underlying storage.
 func (ctx *InsertCtx) FlushBufs() error {
+	if sas := sasGlobal; sas != nil && !ctx.skipStreamAggr {
+		matchIdxs := matchIdxsPool.Get()
+		matchIdxs.B = bytesutil.ResizeNoCopyNoOverallocate(matchIdxs.B, len(ctx.mrs))
+		for i := range matchIdxs.B {
+			matchIdxs.B[i] = 0
+		}
+		sctx := getStreamAggrCtx()
+		sctx.push(sas, ctx.mrs, matchIdxs.B)
+		putStreamAggrCtx(sctx)
+		if !*streamAggrKeepInput {
+			ctx.dropAggregatedRows(matchIdxs.B)
+		}
+		matchIdxsPool.Put(matchIdxs)
+	}
 	err := vmstorage.AddRows(ctx.mrs)
+	if err == nil && len(ctx.ers) > 0 {
+		// Exemplars must be added after the rows, since they are attached only to already existing series.
+		err = vmstorage.AddExemplars(ctx.ers)
+	}
+	if err == nil && len(ctx.mms) > 0 {
+		err = vmstorage.AddMetricMetadata(ctx.mms)
+	}
 	ctx.Reset(0)
 	if err == nil {
 		return nil
@@ -0,0 +1,173 @@
+package common
+
+import (
+	"flag"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/streamaggr"
+)
+
+var (
+	streamAggrConfig = flag.String("streamAggr.config", "", "Optional path to file with stream aggregation config. "+
+		"The path can point either to local file or to http url. "+
+		"See https://docs.victoriametrics.com/vmagent.html#stream-aggregation . See also -streamAggr.keepInput")
+	streamAggrKeepInput = flag.Bool("streamAggr.keepInput", false, "Whether to keep input samples after the aggregation with -streamAggr.config. "+
+		"By default the input samples matching the aggregation config are dropped after the aggregation, so only the aggregate data is stored. "+
+		"See https://docs.victoriametrics.com/vmagent.html#stream-aggregation")
+)
+
+var sasGlobal *streamaggr.Aggregators
+
+// InitStreamAggr must be called after flag.Parse and before using the common package.
+//
+// MustStopStreamAggr must be called when stream aggr is no longer needed.
+func InitStreamAggr() {
+	if *streamAggrConfig == "" {
+		return
+	}
+	sas, err := streamaggr.LoadFromFile(*streamAggrConfig, pushAggregateSeries)
+	if err != nil {
+		logger.Fatalf("cannot load -streamAggr.config=%q: %s", *streamAggrConfig, err)
+	}
+	sasGlobal = sas
+}
+
+// MustStopStreamAggr stops stream aggregators.
+func MustStopStreamAggr() {
+	sasGlobal.MustStop()
+	sasGlobal = nil
+}
+
+type streamAggrCtx struct {
+	mn      storage.MetricName
+	tss     []prompbmarshal.TimeSeries
+	labels  []prompbmarshal.Label
+	samples []prompbmarshal.Sample
+}
+
+func (ctx *streamAggrCtx) Reset() {
+	ctx.mn.Reset()
+
+	tss := ctx.tss
+	for i := range tss {
+		tss[i] = prompbmarshal.TimeSeries{}
+	}
+	ctx.tss = tss[:0]
+
+	labels := ctx.labels
+	for i := range labels {
+		labels[i] = prompbmarshal.Label{}
+	}
+	ctx.labels = labels[:0]
+
+	ctx.samples = ctx.samples[:0]
+}
+
+// push pushes mrs to sas and sets matchIdxs[i] to 1 for mrs[i] matching the aggregation config.
+func (ctx *streamAggrCtx) push(sas *streamaggr.Aggregators, mrs []storage.MetricRow, matchIdxs []byte) {
+	mn := &ctx.mn
+	tss := ctx.tss[:0]
+	labels := ctx.labels[:0]
+	samples := ctx.samples[:0]
+	for _, mr := range mrs {
+		if err := mn.UnmarshalRaw(mr.MetricNameRaw); err != nil {
+			logger.Panicf("BUG: cannot unmarshal recently marshaled MetricName: %s", err)
+		}
+
+		labelsLen := len(labels)
+		labels = append(labels, prompbmarshal.Label{
+			Name:  "__name__",
+			Value: bytesutil.ToUnsafeString(mn.MetricGroup),
+		})
+		for _, tag := range mn.Tags {
+			labels = append(labels, prompbmarshal.Label{
+				Name:  bytesutil.ToUnsafeString(tag.Key),
+				Value: bytesutil.ToUnsafeString(tag.Value),
+			})
+		}
+
+		samplesLen := len(samples)
+		samples = append(samples, prompbmarshal.Sample{
+			Timestamp: mr.Timestamp,
+			Value:     mr.Value,
+		})
+
+		tss = append(tss, prompbmarshal.TimeSeries{
+			Labels:  labels[labelsLen:],
+			Samples: samples[samplesLen:],
+		})
+
+		// Push series one by one, since mn is re-used for every row,
+		// so the labels above refer to mn contents.
+		sas.Push(tss, matchIdxs[len(tss)-1:len(tss)])
+		tss = tss[:0]
+		labels = labels[:0]
+		samples = samples[:0]
+		matchIdxs = matchIdxs[1:]
+	}
+	ctx.tss = tss
+	ctx.labels = labels
+	ctx.samples = samples
+}
+
+func pushAggregateSeries(tss []prompbmarshal.TimeSeries) {
+	ctx := GetInsertCtx()
+	defer PutInsertCtx(ctx)
+	ctx.Reset(len(tss))
+	ctx.skipStreamAggr = true
+	for _, ts := range tss {
+		labels := ts.Labels
+		ctx.Labels = ctx.Labels[:0]
+		for _, label := range labels {
+			ctx.AddLabel(label.Name, label.Value)
+		}
+		value := ts.Samples[0].Value
+		if err := ctx.WriteDataPoint(nil, ctx.Labels, ts.Samples[0].Timestamp, value); err != nil {
+			logger.Errorf("cannot store aggregate series: %s", err)
+			// Do not continue pushing the remaining samples, since it is likely they will return the same error.
+			return
+		}
+	}
+	// There is no need in limiting the number of concurrent calls to vmstorage.AddRows() here,
+	// since the number of concurrent pushAggregateSeries() calls should be already limited by lib/streamaggr.
+	if err := ctx.FlushBufs(); err != nil {
+		logger.Errorf("cannot flush aggregate series: %s", err)
+	}
+}
+
+var matchIdxsPool bytesutil.ByteBufferPool
+
+func (ctx *InsertCtx) dropAggregatedRows(matchIdxs []byte) {
+	dst := ctx.mrs[:0]
+	src := ctx.mrs
+	for idx, match := range matchIdxs {
+		if match != 0 {
+			continue
+		}
+		dst = append(dst, src[idx])
+	}
+	tail := src[len(dst):]
+	for i := range tail {
+		tail[i] = storage.MetricRow{}
+	}
+	ctx.mrs = dst
+}
+
+var streamAggrCtxPool sync.Pool
+
+func getStreamAggrCtx() *streamAggrCtx {
+	v := streamAggrCtxPool.Get()
+	if v == nil {
+		return &streamAggrCtx{}
+	}
+	return v.(*streamAggrCtx)
+}
+
+func putStreamAggrCtx(ctx *streamAggrCtx) {
+	ctx.Reset()
+	streamAggrCtxPool.Put(ctx)
+}
@@ -2,7 +2,9 @@ package prompush
 
 import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/metrics"
 )
@@ -19,6 +21,10 @@ func Push(wr *prompbmarshal.WriteRequest) {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
+	if len(wr.Metadata) > 0 {
+		pushMetadata(ctx, wr.Metadata)
+	}
+
 	tss := wr.Timeseries
 	for len(tss) > 0 {
 		// Process big tss in smaller blocks in order to reduce maxmimum memory usage
@@ -42,6 +48,17 @@ func Push(wr *prompbmarshal.WriteRequest) {
 	}
 }
 
+func pushMetadata(ctx *common.InsertCtx, mms []prompbmarshal.MetricMetadata) {
+	ctx.Reset(0)
+	for i := range mms {
+		mm := &mms[i]
+		ctx.WriteMetricMetadata(mm.MetricFamilyName, mm.Type.String(), mm.Help, mm.Unit)
+	}
+	if err := ctx.FlushBufs(); err != nil {
+		logger.Errorf("cannot flush promscrape metadata to storage: %s", err)
+	}
+}
+
 func push(ctx *common.InsertCtx, tss []prompbmarshal.TimeSeries) {
 	rowsLen := 0
 	for i := range tss {
@@ -49,6 +66,7 @@ func push(ctx *common.InsertCtx, tss []prompbmarshal.TimeSeries) {
 	}
 	ctx.Reset(rowsLen)
 	rowsTotal := 0
+	var exemplar prompb.Exemplar
 	for i := range tss {
 		ts := &tss[i]
 		rowsTotal += len(ts.Samples)
@@ -73,6 +91,20 @@ func push(ctx *common.InsertCtx, tss []prompbmarshal.TimeSeries) {
 				return
 			}
 		}
+		for j := range ts.Exemplars {
+			e := &ts.Exemplars[j]
+			exemplar.Labels = exemplar.Labels[:0]
+			for k := range e.Labels {
+				label := &e.Labels[k]
+				exemplar.Labels = append(exemplar.Labels, prompb.Label{
+					Name:  bytesutil.ToUnsafeBytes(label.Name),
+					Value: bytesutil.ToUnsafeBytes(label.Value),
+				})
+			}
+			exemplar.Value = e.Value
+			exemplar.Timestamp = e.Timestamp
+			metricNameRaw = ctx.WriteExemplar(metricNameRaw, ctx.Labels, &exemplar)
+		}
 	}
 	rowsInserted.Add(rowsTotal)
 	rowsPerInsert.Update(float64(rowsTotal))
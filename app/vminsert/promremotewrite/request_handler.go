@@ -5,6 +5,7 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	parserCommon "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
@@ -25,13 +26,13 @@ func InsertHandler(req *http.Request) error {
 		return err
 	}
 	return writeconcurrencylimiter.Do(func() error {
-		return parser.ParseStream(req.Body, func(tss []prompb.TimeSeries) error {
-			return insertRows(tss, extraLabels)
+		return parser.ParseStream(req.Body, func(tss []prompb.TimeSeries, mms []prompb.MetricMetadata) error {
+			return insertRows(tss, mms, extraLabels)
 		})
 	})
 }
 
-func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Label) error {
+func insertRows(timeseries []prompb.TimeSeries, mms []prompb.MetricMetadata, extraLabels []prompbmarshal.Label) error {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
@@ -40,6 +41,13 @@ func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Labe
 		rowsLen += len(timeseries[i].Samples)
 	}
 	ctx.Reset(rowsLen)
+	for i := range mms {
+		mm := &mms[i]
+		// prompb and prompbmarshal share the same metric type values, so the name is obtained from prompbmarshal.
+		typ := prompbmarshal.MetricMetadata_MetricType(mm.Type).String()
+		ctx.WriteMetricMetadata(bytesutil.ToUnsafeString(mm.MetricFamilyName), typ,
+			bytesutil.ToUnsafeString(mm.Help), bytesutil.ToUnsafeString(mm.Unit))
+	}
 	rowsTotal := 0
 	hasRelabeling := relabel.HasRelabeling()
 	for i := range timeseries {
@@ -72,6 +80,9 @@ func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Labe
 				return err
 			}
 		}
+		for i := range ts.Exemplars {
+			metricNameRaw = ctx.WriteExemplar(metricNameRaw, ctx.Labels, &ts.Exemplars[i])
+		}
 	}
 	rowsInserted.Add(rowsTotal)
 	rowsPerInsert.Update(float64(rowsTotal))
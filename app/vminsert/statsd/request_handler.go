@@ -0,0 +1,80 @@
+package statsd
+
+import (
+	"io"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="statsd"}`)
+	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="statsd"}`)
+)
+
+var aggr *parser.Aggregator
+
+// Init starts StatsD aggregation.
+//
+// It must be called before InsertHandler.
+func Init() {
+	aggr = parser.MustStartAggregator(func(tss []prompbmarshal.TimeSeries) {
+		if err := insertRows(tss); err != nil {
+			logger.Errorf("cannot insert aggregated StatsD samples: %s", err)
+		}
+	})
+}
+
+// Stop stops StatsD aggregation and writes the remaining aggregated samples to storage.
+func Stop() {
+	aggr.MustStop()
+	aggr = nil
+}
+
+// InsertHandler processes StatsD and DogStatsD lines.
+//
+// See https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+func InsertHandler(r io.Reader) error {
+	return writeconcurrencylimiter.Do(func() error {
+		return parser.ParseStream(r, func(rows []parser.Row) error {
+			aggr.Push(rows)
+			return nil
+		})
+	})
+}
+
+func insertRows(tss []prompbmarshal.TimeSeries) error {
+	ctx := common.GetInsertCtx()
+	defer common.PutInsertCtx(ctx)
+
+	ctx.Reset(len(tss))
+	hasRelabeling := relabel.HasRelabeling()
+	for i := range tss {
+		ts := &tss[i]
+		ctx.Labels = ctx.Labels[:0]
+		for _, label := range ts.Labels {
+			ctx.AddLabel(label.Name, label.Value)
+		}
+		if hasRelabeling {
+			ctx.ApplyRelabeling()
+		}
+		if len(ctx.Labels) == 0 {
+			// Skip metric without labels.
+			continue
+		}
+		ctx.SortLabelsIfNeeded()
+		for _, sample := range ts.Samples {
+			if err := ctx.WriteDataPoint(nil, ctx.Labels, sample.Timestamp, sample.Value); err != nil {
+				return err
+			}
+		}
+	}
+	rowsInserted.Add(len(tss))
+	rowsPerInsert.Update(float64(len(tss)))
+	return ctx.FlushBufs()
+}
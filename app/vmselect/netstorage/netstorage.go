@@ -963,6 +963,49 @@ func SearchMetricNames(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline
 	return mns, nil
 }
 
+// SearchExemplars returns exemplars for series matching the given sq.
+func SearchExemplars(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline searchutils.Deadline) ([]storage.SeriesExemplars, error) {
+	qt = qt.NewChild("fetch exemplars: %s", sq)
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return nil, fmt.Errorf("timeout exceeded before starting to search exemplars: %s", deadline.String())
+	}
+
+	// Setup search.
+	tr := storage.TimeRange{
+		MinTimestamp: sq.MinTimestamp,
+		MaxTimestamp: sq.MaxTimestamp,
+	}
+	if err := vmstorage.CheckTimeRange(tr); err != nil {
+		return nil, err
+	}
+	tfss, err := setupTfss(tr, sq.TagFilterss, sq.MaxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	ses, err := vmstorage.SearchExemplars(qt, tfss, tr, sq.MaxMetrics, deadline.Deadline())
+	if err != nil {
+		return nil, fmt.Errorf("cannot find exemplars: %w", err)
+	}
+	return ses, nil
+}
+
+// SearchMetricMetadata returns metadata for the given metric family name.
+//
+// Metadata for all the metric families is returned if metric is empty.
+// Up to limit metric families are returned if limit > 0.
+func SearchMetricMetadata(qt *querytracer.Tracer, metric string, limit int, deadline searchutils.Deadline) ([]storage.MetricMetadata, error) {
+	qt = qt.NewChild("fetch metric metadata: metric=%q, limit=%d", metric, limit)
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return nil, fmt.Errorf("timeout exceeded before starting to search metric metadata: %s", deadline.String())
+	}
+	mms := vmstorage.SearchMetricMetadata(metric, limit)
+	qt.Printf("found metadata entries: %d", len(mms))
+	return mms, nil
+}
+
 // ProcessSearchQuery performs sq until the given deadline.
 //
 // Results.RunParallel or Results.Cancel must be called on the returned Results.
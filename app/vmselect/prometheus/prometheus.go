@@ -24,6 +24,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 	"github.com/VictoriaMetrics/metrics"
+	"github.com/VictoriaMetrics/metricsql"
 	"github.com/valyala/fastjson/fastfloat"
 	"github.com/valyala/quicktemplate"
 )
@@ -48,7 +49,7 @@ var (
 	maxFederateSeries   = flag.Int("search.maxFederateSeries", 1e6, "The maximum number of time series, which can be returned from /federate. This option allows limiting memory usage")
 	maxExportSeries     = flag.Int("search.maxExportSeries", 10e6, "The maximum number of time series, which can be returned from /api/v1/export* APIs. This option allows limiting memory usage")
 	maxTSDBStatusSeries = flag.Int("search.maxTSDBStatusSeries", 10e6, "The maximum number of time series, which can be processed during the call to /api/v1/status/tsdb. This option allows limiting memory usage")
-	maxSeriesLimit      = flag.Int("search.maxSeries", 100e3, "The maximum number of time series, which can be returned from /api/v1/series. This option allows limiting memory usage")
+	maxSeriesLimit      = flag.Int("search.maxSeries", 100e3, "The maximum number of time series, which can be returned from /api/v1/series and /api/v1/read. This option allows limiting memory usage")
 )
 
 // Default step used if not set.
@@ -676,6 +677,104 @@ func SeriesHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseW
 
 var seriesDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/series"}`)
 
+// QueryExemplarsHandler processes /api/v1/query_exemplars request.
+//
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-exemplars
+func QueryExemplarsHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer queryExemplarsDuration.UpdateDuration(startTime)
+
+	query := r.FormValue("query")
+	if len(query) == 0 {
+		return fmt.Errorf("missing `query` arg")
+	}
+	cp, err := getCommonParams(r, startTime, false)
+	if err != nil {
+		return err
+	}
+	// Limit the default time range in the same way as SeriesHandler does.
+	if cp.start == 0 {
+		cp.start = cp.end - defaultStep
+	}
+	tagFilterss, err := getTagFilterssFromQuery(query)
+	if err != nil {
+		return err
+	}
+	if len(tagFilterss) == 0 {
+		return fmt.Errorf("`query` arg must contain at least a single non-empty series selector; got %q", query)
+	}
+	etfs, err := searchutils.GetExtraTagFilters(r)
+	if err != nil {
+		return err
+	}
+	cp.filterss = searchutils.JoinTagFilterss(tagFilterss, etfs)
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxSeriesLimit)
+	ses, err := netstorage.SearchExemplars(qt, sq, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch exemplars for %q: %w", sq, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("start=%d, end=%d", cp.start, cp.end)
+	}
+	WriteQueryExemplarsResponse(bw, ses, qt, qtDone)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot flush query_exemplars response to remote client: %w", err)
+	}
+	return nil
+}
+
+var queryExemplarsDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/query_exemplars"}`)
+
+// MetadataHandler processes /api/v1/metadata request.
+//
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+func MetadataHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer metadataDuration.UpdateDuration(startTime)
+
+	deadline := searchutils.GetDeadlineForQuery(r, startTime)
+	metric := r.FormValue("metric")
+	limit, err := searchutils.GetInt(r, "limit")
+	if err != nil {
+		return err
+	}
+	mms, err := netstorage.SearchMetricMetadata(qt, metric, limit, deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch metric metadata: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("metric=%q, limit=%d", metric, limit)
+	}
+	WriteMetadataResponse(bw, mms, qt, qtDone)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot flush metadata response to remote client: %w", err)
+	}
+	return nil
+}
+
+var metadataDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/metadata"}`)
+
+// getTagFilterssFromQuery returns tag filters for all the series selectors in the given query.
+func getTagFilterssFromQuery(query string) ([][]storage.TagFilter, error) {
+	expr, err := metricsql.Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse query=%q: %w", query, err)
+	}
+	var tagFilterss [][]storage.TagFilter
+	metricsql.VisitAll(expr, func(expr metricsql.Expr) {
+		me, ok := expr.(*metricsql.MetricExpr)
+		if !ok || len(me.LabelFilters) == 0 {
+			return
+		}
+		tagFilterss = append(tagFilterss, searchutils.ToTagFilters(me.LabelFilters))
+	})
+	return tagFilterss, nil
+}
+
 // QueryHandler processes /api/v1/query request.
 //
 // See https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
@@ -0,0 +1,177 @@
+// Code generated by qtc from "query_exemplars_response.qtpl". DO NOT EDIT.
+// See https://github.com/valyala/quicktemplate for details.
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:1
+package prometheus
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:1
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// QueryExemplarsResponse generates response for /api/v1/query_exemplars.See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-exemplars
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:9
+import (
+	qtio422016 "io"
+
+	qt422016 "github.com/valyala/quicktemplate"
+)
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:9
+var (
+	_ = qtio422016.Copy
+	_ = qt422016.AcquireByteBuffer
+)
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:9
+func StreamQueryExemplarsResponse(qw422016 *qt422016.Writer, ses []storage.SeriesExemplars, qt *querytracer.Tracer, qtDone func()) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:9
+	qw422016.N().S(`{`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:11
+	exemplarsCount := 0
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:11
+	qw422016.N().S(`"status":"success","data":[`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:14
+	for i := range ses {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:16
+		se := &ses[i]
+		exemplarsCount += len(se.Exemplars)
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:18
+		qw422016.N().S(`{"seriesLabels":`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:20
+		streammetricNameObject(qw422016, &se.MetricName)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:20
+		qw422016.N().S(`,"exemplars":[`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:22
+		for j := range se.Exemplars {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:23
+			e := &se.Exemplars[j]
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:23
+			qw422016.N().S(`{"labels":`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:25
+			streamexemplarLabelsObject(qw422016, e.Tags)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:25
+			qw422016.N().S(`,"value":"`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:26
+			qw422016.N().F(e.Value)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:26
+			qw422016.N().S(`","timestamp":`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:27
+			qw422016.N().F(float64(e.Timestamp) / 1e3)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:27
+			qw422016.N().S(`}`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:29
+			if j+1 < len(se.Exemplars) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:29
+				qw422016.N().S(`,`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:29
+			}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:30
+		}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:30
+		qw422016.N().S(`]}`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:33
+		if i+1 < len(ses) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:33
+			qw422016.N().S(`,`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:33
+		}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:34
+	}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:34
+	qw422016.N().S(`]`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:37
+	qt.Printf("generate /api/v1/query_exemplars response for series=%d, exemplars=%d", len(ses), exemplarsCount)
+	qtDone()
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:40
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:40
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+func WriteQueryExemplarsResponse(qq422016 qtio422016.Writer, ses []storage.SeriesExemplars, qt *querytracer.Tracer, qtDone func()) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	StreamQueryExemplarsResponse(qw422016, ses, qt, qtDone)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+func QueryExemplarsResponse(ses []storage.SeriesExemplars, qt *querytracer.Tracer, qtDone func()) string {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	WriteQueryExemplarsResponse(qb422016, ses, qt, qtDone)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+	return qs422016
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:42
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:44
+func streamexemplarLabelsObject(qw422016 *qt422016.Writer, tags []storage.Tag) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:44
+	qw422016.N().S(`{`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:46
+	for i := range tags {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:47
+		tag := &tags[i]
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:48
+		qw422016.N().QZ(tag.Key)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:48
+		qw422016.N().S(`:`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:48
+		qw422016.N().QZ(tag.Value)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:48
+		if i+1 < len(tags) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:48
+			qw422016.N().S(`,`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:48
+		}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:49
+	}
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:49
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+func writeexemplarLabelsObject(qq422016 qtio422016.Writer, tags []storage.Tag) {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+	streamexemplarLabelsObject(qw422016, tags)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+}
+
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+func exemplarLabelsObject(tags []storage.Tag) string {
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+	writeexemplarLabelsObject(qb422016, tags)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+	return qs422016
+//line app/vmselect/prometheus/query_exemplars_response.qtpl:51
+}
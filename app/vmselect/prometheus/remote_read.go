@@ -0,0 +1,304 @@
+package prometheus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/bufferedwriter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+// maxRemoteReadRequestSize is the maximum size of compressed remote read request.
+const maxRemoteReadRequestSize = 32 * 1024 * 1024
+
+// maxSamplesPerChunk is the maximum number of samples per XOR chunk in streamed remote read response.
+//
+// This matches the number of samples per chunk in Prometheus TSDB.
+const maxSamplesPerChunk = 120
+
+// RemoteReadHandler processes /api/v1/read request.
+//
+// See https://prometheus.io/docs/prometheus/latest/querying/remote_read_api/
+func RemoteReadHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer remoteReadDuration.UpdateDuration(startTime)
+
+	req, err := readRemoteReadRequest(r)
+	if err != nil {
+		return err
+	}
+	etfs, err := searchutils.GetExtraTagFilters(r)
+	if err != nil {
+		return err
+	}
+	deadline := searchutils.GetDeadlineForExport(r, startTime)
+	if isStreamedRemoteReadResponse(req.AcceptedResponseTypes) {
+		return remoteReadStreamed(w, req, etfs, deadline)
+	}
+	return remoteReadSampled(w, req, etfs, deadline)
+}
+
+var remoteReadDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/read"}`)
+
+func readRemoteReadRequest(r *http.Request) (*prompb.ReadRequest, error) {
+	compressed, err := io.ReadAll(io.LimitReader(r.Body, maxRemoteReadRequestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read remote read request: %w", err)
+	}
+	if len(compressed) > maxRemoteReadRequestSize {
+		return nil, fmt.Errorf("too big remote read request; mustn't exceed %d bytes", maxRemoteReadRequestSize)
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress remote read request with length %d: %w", len(compressed), err)
+	}
+	var req prompb.ReadRequest
+	if err := req.Unmarshal(data); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal remote read request with size %d bytes: %w", len(data), err)
+	}
+	return &req, nil
+}
+
+// isStreamedRemoteReadResponse returns true if the first supported response type from accepted is STREAMED_XOR_CHUNKS.
+func isStreamedRemoteReadResponse(accepted []prompb.ReadRequest_ResponseType) bool {
+	for _, rt := range accepted {
+		switch rt {
+		case prompb.ReadRequest_SAMPLES:
+			return false
+		case prompb.ReadRequest_STREAMED_XOR_CHUNKS:
+			return true
+		}
+	}
+	return false
+}
+
+func remoteReadSampled(w http.ResponseWriter, req *prompb.ReadRequest, etfs [][]storage.TagFilter, deadline searchutils.Deadline) error {
+	var resp prompbmarshal.ReadResponse
+	for i := range req.Queries {
+		var tss []prompbmarshal.TimeSeries
+		var mu sync.Mutex
+		err := processRemoteReadQuery(&req.Queries[i], etfs, deadline, func(rs *netstorage.Result) error {
+			ts := prompbmarshal.TimeSeries{
+				Labels:  metricNameToLabels(&rs.MetricName),
+				Samples: make([]prompbmarshal.Sample, len(rs.Values)),
+			}
+			for j, v := range rs.Values {
+				ts.Samples[j] = prompbmarshal.Sample{
+					Value:     v,
+					Timestamp: rs.Timestamps[j],
+				}
+			}
+			mu.Lock()
+			tss = append(tss, ts)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sort.Slice(tss, func(i, j int) bool {
+			return lessLabels(tss[i].Labels, tss[j].Labels)
+		})
+		resp.Results = append(resp.Results, prompbmarshal.QueryResult{
+			Timeseries: tss,
+		})
+	}
+	data, err := resp.Marshal()
+	if err != nil {
+		return fmt.Errorf("BUG: cannot marshal remote read response: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.Header().Set("Content-Encoding", "snappy")
+	if _, err := w.Write(snappy.Encode(nil, data)); err != nil {
+		return fmt.Errorf("cannot send remote read response to remote client: %w", err)
+	}
+	return nil
+}
+
+func remoteReadStreamed(w http.ResponseWriter, req *prompb.ReadRequest, etfs [][]storage.TagFilter, deadline searchutils.Deadline) error {
+	w.Header().Set("Content-Type", "application/x-streamed-protobuf; proto=prometheus.ChunkedReadResponse")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	var frame []byte
+	for i := range req.Queries {
+		var css []prompbmarshal.ChunkedSeries
+		var mu sync.Mutex
+		err := processRemoteReadQuery(&req.Queries[i], etfs, deadline, func(rs *netstorage.Result) error {
+			chunks, err := encodeXORChunks(rs.Timestamps, rs.Values)
+			if err != nil {
+				return err
+			}
+			cs := prompbmarshal.ChunkedSeries{
+				Labels: metricNameToLabels(&rs.MetricName),
+				Chunks: chunks,
+			}
+			mu.Lock()
+			css = append(css, cs)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// Series must be streamed in sorted order, since Prometheus merges them with local series.
+		sort.Slice(css, func(i, j int) bool {
+			return lessLabels(css[i].Labels, css[j].Labels)
+		})
+		for j := range css {
+			resp := prompbmarshal.ChunkedReadResponse{
+				ChunkedSeries: css[j : j+1],
+				QueryIndex:    int64(i),
+			}
+			frame = marshalChunkedReadResponseFrame(frame[:0], &resp)
+			if _, err := bw.Write(frame); err != nil {
+				return fmt.Errorf("cannot send remote read response to remote client: %w", err)
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot flush remote read response to remote client: %w", err)
+	}
+	return nil
+}
+
+func processRemoteReadQuery(q *prompb.Query, etfs [][]storage.TagFilter, deadline searchutils.Deadline, f func(rs *netstorage.Result) error) error {
+	tfs, err := labelMatchersToTagFilters(q.Matchers)
+	if err != nil {
+		return err
+	}
+	filterss := searchutils.JoinTagFilterss([][]storage.TagFilter{tfs}, etfs)
+	sq := storage.NewSearchQuery(q.StartTimestampMs, q.EndTimestampMs, filterss, *maxSeriesLimit)
+	rss, err := netstorage.ProcessSearchQuery(nil, sq, true, deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch data for %q: %w", sq, err)
+	}
+	err = rss.RunParallel(nil, func(rs *netstorage.Result, workerID uint) error {
+		return f(rs)
+	})
+	if err != nil {
+		return fmt.Errorf("error when processing data for %q: %w", sq, err)
+	}
+	return nil
+}
+
+func labelMatchersToTagFilters(matchers []prompb.LabelMatcher) ([]storage.TagFilter, error) {
+	tfs := make([]storage.TagFilter, 0, len(matchers))
+	for i := range matchers {
+		m := &matchers[i]
+		var tf storage.TagFilter
+		if string(m.Name) != "__name__" {
+			tf.Key = append([]byte{}, m.Name...)
+		}
+		tf.Value = append([]byte{}, m.Value...)
+		switch m.Type {
+		case prompb.LabelMatcher_EQ:
+		case prompb.LabelMatcher_NEQ:
+			tf.IsNegative = true
+		case prompb.LabelMatcher_RE:
+			tf.IsRegexp = true
+		case prompb.LabelMatcher_NRE:
+			tf.IsNegative = true
+			tf.IsRegexp = true
+		default:
+			return nil, fmt.Errorf("unsupported label matcher type %d for label %q", m.Type, m.Name)
+		}
+		tfs = append(tfs, tf)
+	}
+	return tfs, nil
+}
+
+// metricNameToLabels returns labels sorted by name for mn.
+func metricNameToLabels(mn *storage.MetricName) []prompbmarshal.Label {
+	labels := make([]prompbmarshal.Label, 0, len(mn.Tags)+1)
+	if len(mn.MetricGroup) > 0 {
+		labels = append(labels, prompbmarshal.Label{
+			Name:  "__name__",
+			Value: string(mn.MetricGroup),
+		})
+	}
+	for i := range mn.Tags {
+		tag := &mn.Tags[i]
+		labels = append(labels, prompbmarshal.Label{
+			Name:  string(tag.Key),
+			Value: string(tag.Value),
+		})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
+}
+
+func lessLabels(a, b []prompbmarshal.Label) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i].Name != b[i].Name {
+			return a[i].Name < b[i].Name
+		}
+		if a[i].Value != b[i].Value {
+			return a[i].Value < b[i].Value
+		}
+	}
+	return len(a) < len(b)
+}
+
+// encodeXORChunks encodes the given samples into Prometheus XOR chunks.
+func encodeXORChunks(timestamps []int64, values []float64) ([]prompbmarshal.Chunk, error) {
+	var chunks []prompbmarshal.Chunk
+	for len(timestamps) > 0 {
+		n := maxSamplesPerChunk
+		if n > len(timestamps) {
+			n = len(timestamps)
+		}
+		c := chunkenc.NewXORChunk()
+		app, err := c.Appender()
+		if err != nil {
+			return nil, fmt.Errorf("BUG: cannot create XOR chunk appender: %w", err)
+		}
+		for i := 0; i < n; i++ {
+			app.Append(timestamps[i], values[i])
+		}
+		chunks = append(chunks, prompbmarshal.Chunk{
+			MinTimeMs: timestamps[0],
+			MaxTimeMs: timestamps[n-1],
+			Type:      prompbmarshal.Chunk_XOR,
+			Data:      c.Bytes(),
+		})
+		timestamps = timestamps[n:]
+		values = values[n:]
+	}
+	return chunks, nil
+}
+
+var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+// marshalChunkedReadResponseFrame appends resp frame to dst and returns the result.
+//
+// The frame consists of uvarint-encoded message size, big-endian CRC32 Castagnoli checksum of the message and the message itself.
+func marshalChunkedReadResponseFrame(dst []byte, resp *prompbmarshal.ChunkedReadResponse) []byte {
+	size := resp.Size()
+	var sizeBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(sizeBuf[:], uint64(size))
+	dst = append(dst, sizeBuf[:n]...)
+	crcOffset := len(dst)
+	dst = append(dst, 0, 0, 0, 0)
+	dataOffset := len(dst)
+	dst = bytesutil.ResizeWithCopyMayOverallocate(dst, dataOffset+size)
+	if _, err := resp.MarshalToSizedBuffer(dst[dataOffset:]); err != nil {
+		panic(fmt.Errorf("BUG: cannot marshal ChunkedReadResponse: %w", err))
+	}
+	binary.BigEndian.PutUint32(dst[crcOffset:], crc32.Checksum(dst[dataOffset:], castagnoliTable))
+	return dst
+}
@@ -0,0 +1,156 @@
+package prometheus
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+func TestLabelMatchersToTagFilters(t *testing.T) {
+	f := func(matchers []prompb.LabelMatcher, tfsExpected []storage.TagFilter) {
+		t.Helper()
+		tfs, err := labelMatchersToTagFilters(matchers)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(tfs, tfsExpected) {
+			t.Fatalf("unexpected tag filters\ngot\n%v\nwant\n%v", tfs, tfsExpected)
+		}
+	}
+	f([]prompb.LabelMatcher{}, []storage.TagFilter{})
+	f([]prompb.LabelMatcher{
+		{Type: prompb.LabelMatcher_EQ, Name: []byte("__name__"), Value: []byte("foo")},
+		{Type: prompb.LabelMatcher_NEQ, Name: []byte("job"), Value: []byte("bar")},
+		{Type: prompb.LabelMatcher_RE, Name: []byte("instance"), Value: []byte("a.+")},
+		{Type: prompb.LabelMatcher_NRE, Name: []byte("env"), Value: []byte("dev|test")},
+	}, []storage.TagFilter{
+		{Key: nil, Value: []byte("foo")},
+		{Key: []byte("job"), Value: []byte("bar"), IsNegative: true},
+		{Key: []byte("instance"), Value: []byte("a.+"), IsRegexp: true},
+		{Key: []byte("env"), Value: []byte("dev|test"), IsNegative: true, IsRegexp: true},
+	})
+
+	// unsupported matcher type
+	_, err := labelMatchersToTagFilters([]prompb.LabelMatcher{{Type: 10, Name: []byte("foo")}})
+	if err == nil {
+		t.Fatalf("expecting non-nil error for unsupported matcher type")
+	}
+}
+
+func TestIsStreamedRemoteReadResponse(t *testing.T) {
+	f := func(accepted []prompb.ReadRequest_ResponseType, resultExpected bool) {
+		t.Helper()
+		result := isStreamedRemoteReadResponse(accepted)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %v; got %v; want %v", accepted, result, resultExpected)
+		}
+	}
+	f(nil, false)
+	f([]prompb.ReadRequest_ResponseType{prompb.ReadRequest_SAMPLES}, false)
+	f([]prompb.ReadRequest_ResponseType{prompb.ReadRequest_STREAMED_XOR_CHUNKS}, true)
+	f([]prompb.ReadRequest_ResponseType{prompb.ReadRequest_STREAMED_XOR_CHUNKS, prompb.ReadRequest_SAMPLES}, true)
+	f([]prompb.ReadRequest_ResponseType{prompb.ReadRequest_SAMPLES, prompb.ReadRequest_STREAMED_XOR_CHUNKS}, false)
+
+	// unknown response types must be skipped
+	f([]prompb.ReadRequest_ResponseType{10, prompb.ReadRequest_STREAMED_XOR_CHUNKS}, true)
+}
+
+func TestEncodeXORChunks(t *testing.T) {
+	f := func(samplesCount, chunksCountExpected int) {
+		t.Helper()
+		timestamps := make([]int64, samplesCount)
+		values := make([]float64, samplesCount)
+		for i := range timestamps {
+			timestamps[i] = int64(i) * 15000
+			values[i] = float64(i) * 1.5
+		}
+		chunks, err := encodeXORChunks(timestamps, values)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(chunks) != chunksCountExpected {
+			t.Fatalf("unexpected number of chunks; got %d; want %d", len(chunks), chunksCountExpected)
+		}
+		var timestampsDecoded []int64
+		var valuesDecoded []float64
+		for _, c := range chunks {
+			if c.Type != prompbmarshal.Chunk_XOR {
+				t.Fatalf("unexpected chunk type; got %d; want %d", c.Type, prompbmarshal.Chunk_XOR)
+			}
+			xc, err := chunkenc.FromData(chunkenc.EncXOR, c.Data)
+			if err != nil {
+				t.Fatalf("cannot decode chunk: %s", err)
+			}
+			it := xc.Iterator(nil)
+			for it.Next() {
+				ts, v := it.At()
+				timestampsDecoded = append(timestampsDecoded, ts)
+				valuesDecoded = append(valuesDecoded, v)
+			}
+			if err := it.Err(); err != nil {
+				t.Fatalf("cannot iterate over chunk: %s", err)
+			}
+			if c.MinTimeMs != timestampsDecoded[len(timestampsDecoded)-xc.NumSamples()] {
+				t.Fatalf("unexpected MinTimeMs; got %d", c.MinTimeMs)
+			}
+			if c.MaxTimeMs != timestampsDecoded[len(timestampsDecoded)-1] {
+				t.Fatalf("unexpected MaxTimeMs; got %d", c.MaxTimeMs)
+			}
+		}
+		if samplesCount == 0 {
+			return
+		}
+		if !reflect.DeepEqual(timestampsDecoded, timestamps) {
+			t.Fatalf("unexpected timestamps\ngot\n%v\nwant\n%v", timestampsDecoded, timestamps)
+		}
+		if !reflect.DeepEqual(valuesDecoded, values) {
+			t.Fatalf("unexpected values\ngot\n%v\nwant\n%v", valuesDecoded, values)
+		}
+	}
+	f(0, 0)
+	f(1, 1)
+	f(maxSamplesPerChunk, 1)
+	f(maxSamplesPerChunk+1, 2)
+	f(3*maxSamplesPerChunk+5, 4)
+}
+
+func TestMarshalChunkedReadResponseFrame(t *testing.T) {
+	resp := &prompbmarshal.ChunkedReadResponse{
+		ChunkedSeries: []prompbmarshal.ChunkedSeries{{
+			Labels: []prompbmarshal.Label{{Name: "__name__", Value: "foo"}},
+			Chunks: []prompbmarshal.Chunk{{MinTimeMs: 1, MaxTimeMs: 2, Type: prompbmarshal.Chunk_XOR, Data: []byte("data")}},
+		}},
+		QueryIndex: 3,
+	}
+	data, err := resp.Marshal()
+	if err != nil {
+		t.Fatalf("cannot marshal response: %s", err)
+	}
+	frame := marshalChunkedReadResponseFrame([]byte("prefix"), resp)
+	if string(frame[:len("prefix")]) != "prefix" {
+		t.Fatalf("unexpected prefix in frame %q", frame)
+	}
+	frame = frame[len("prefix"):]
+	size, n := binary.Uvarint(frame)
+	if n <= 0 {
+		t.Fatalf("cannot unmarshal frame size")
+	}
+	if size != uint64(len(data)) {
+		t.Fatalf("unexpected frame size; got %d; want %d", size, len(data))
+	}
+	frame = frame[n:]
+	crc := binary.BigEndian.Uint32(frame)
+	frame = frame[4:]
+	if string(frame) != string(data) {
+		t.Fatalf("unexpected frame data\ngot\n%X\nwant\n%X", frame, data)
+	}
+	if crcExpected := crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)); crc != crcExpected {
+		t.Fatalf("unexpected crc; got %d; want %d", crc, crcExpected)
+	}
+}
@@ -52,6 +52,10 @@ var (
 		"Excess series are logged and dropped. This can be useful for limiting series cardinality. See also -storage.maxDailySeries")
 	maxDailySeries = flag.Int("storage.maxDailySeries", 0, "The maximum number of unique series can be added to the storage during the last 24 hours. "+
 		"Excess series are logged and dropped. This can be useful for limiting series churn rate. See also -storage.maxHourlySeries")
+	maxExemplars = flag.Int("storage.maxExemplars", 100000, "The maximum number of exemplars to keep in memory. The oldest exemplars are dropped when the limit is reached. "+
+		"Exemplars are returned via /api/v1/query_exemplars. Set to 0 for disabling exemplars storage")
+	maxMetricMetadataMetrics = flag.Int("storage.maxMetadataMetrics", 100000, "The maximum number of metric family names to keep metadata (TYPE, HELP and UNIT) for. "+
+		"Metadata for new metric families is dropped when the limit is reached. Metadata is returned via /api/v1/metadata")
 
 	minFreeDiskSpaceBytes = flagutil.NewBytes("storage.minFreeDiskSpaceBytes", 10e6, "The minimum free disk space at -storageDataPath after which the storage stops accepting new data")
 
@@ -99,12 +103,18 @@ func InitWithoutMetrics(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 	storage.SetFreeDiskSpaceLimit(minFreeDiskSpaceBytes.N)
 	storage.SetTSIDCacheSize(cacheSizeStorageTSID.N)
 	storage.SetTagFilterCacheSize(cacheSizeIndexDBTagFilters.N)
+	storage.SetMaxExemplars(*maxExemplars)
+	storage.SetMaxMetricMetadataMetrics(*maxMetricMetadataMetrics)
 	mergeset.SetIndexBlocksCacheSize(cacheSizeIndexDBIndexBlocks.N)
 	mergeset.SetDataBlocksCacheSize(cacheSizeIndexDBDataBlocks.N)
 
 	if retentionPeriod.Msecs < 24*3600*1000 {
 		logger.Fatalf("-retentionPeriod cannot be smaller than a day; got %s", retentionPeriod)
 	}
+	rfs, err := parseRetentionFilters(*retentionFilters)
+	if err != nil {
+		logger.Fatalf("%s", err)
+	}
 	logger.Infof("opening storage at %q with -retentionPeriod=%s", *DataPath, retentionPeriod)
 	startTime := time.Now()
 	WG = syncwg.WaitGroup{}
@@ -112,6 +122,9 @@ func InitWithoutMetrics(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 	if err != nil {
 		logger.Fatalf("cannot open a storage at %s with -retentionPeriod=%s: %s", *DataPath, retentionPeriod, err)
 	}
+	if err := strg.SetRetentionFilters(rfs); err != nil {
+		logger.Fatalf("cannot apply -retentionFilter: %s", err)
+	}
 	Storage = strg
 	initStaleSnapshotsRemover(strg)
 
@@ -154,6 +167,30 @@ func AddRows(mrs []storage.MetricRow) error {
 
 var errReadOnly = errors.New("the storage is in read-only mode; check -storage.minFreeDiskSpaceBytes command-line flag value")
 
+// AddExemplars adds ers to the storage.
+//
+// Exemplars for unknown series are ignored, so they must be added after the corresponding rows.
+func AddExemplars(ers []storage.ExemplarRow) error {
+	if Storage.IsReadOnly() {
+		return errReadOnly
+	}
+	WG.Add(1)
+	err := Storage.AddExemplars(ers)
+	WG.Done()
+	return err
+}
+
+// AddMetricMetadata adds mms to the storage.
+func AddMetricMetadata(mms []storage.MetricMetadata) error {
+	if Storage.IsReadOnly() {
+		return errReadOnly
+	}
+	WG.Add(1)
+	err := Storage.AddMetricMetadata(mms)
+	WG.Done()
+	return err
+}
+
 // RegisterMetricNames registers all the metrics from mrs in the storage.
 func RegisterMetricNames(mrs []storage.MetricRow) error {
 	WG.Add(1)
@@ -180,6 +217,24 @@ func SearchMetricNames(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr st
 	return mns, err
 }
 
+// SearchExemplars returns exemplars for series matching the given tfss on the given tr.
+func SearchExemplars(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr storage.TimeRange, maxMetrics int, deadline uint64) ([]storage.SeriesExemplars, error) {
+	WG.Add(1)
+	ses, err := Storage.SearchExemplars(qt, tfss, tr, maxMetrics, deadline)
+	WG.Done()
+	return ses, err
+}
+
+// SearchMetricMetadata returns metadata for the given metric family name.
+//
+// Metadata for all the metric families is returned if metric is empty.
+func SearchMetricMetadata(metric string, limit int) []storage.MetricMetadata {
+	WG.Add(1)
+	mms := Storage.SearchMetricMetadata(metric, limit)
+	WG.Done()
+	return mms
+}
+
 // SearchLabelNamesWithFiltersOnTimeRange searches for tag keys matching the given tfss on tr.
 func SearchLabelNamesWithFiltersOnTimeRange(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr storage.TimeRange, maxTagKeys, maxMetrics int, deadline uint64) ([]string, error) {
 	WG.Add(1)
@@ -419,6 +474,8 @@ func registerStorageMetrics(strg *storage.Storage) {
 		return &sm.IndexDBMetrics
 	}
 
+	registerRetentionFiltersMetrics(m)
+
 	metrics.NewGauge(fmt.Sprintf(`vm_free_disk_space_bytes{path=%q}`, *DataPath), func() float64 {
 		return float64(fs.MustGetFreeSpace(*DataPath))
 	})
@@ -633,6 +690,23 @@ func registerStorageMetrics(strg *storage.Storage) {
 		return float64(m().DailySeriesLimitRowsDropped)
 	})
 
+	metrics.NewGauge(`vm_exemplars_added_total`, func() float64 {
+		return float64(m().ExemplarsAdded)
+	})
+	metrics.NewGauge(`vm_exemplars_ignored_total`, func() float64 {
+		return float64(m().ExemplarsIgnored)
+	})
+	metrics.NewGauge(`vm_exemplars`, func() float64 {
+		return float64(m().ExemplarsCount)
+	})
+
+	metrics.NewGauge(`vm_metric_metadata_dropped_total`, func() float64 {
+		return float64(m().MetricMetadataDropped)
+	})
+	metrics.NewGauge(`vm_metric_metadata`, func() float64 {
+		return float64(m().MetricMetadataCount)
+	})
+
 	metrics.NewGauge(`vm_timestamps_blocks_merged_total`, func() float64 {
 		return float64(m().TimestampsBlocksMerged)
 	})
@@ -0,0 +1,75 @@
+package vmstorage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var retentionFilters = flagutil.NewArray("retentionFilter", "Retention filter in the format 'filter:period'. If a time series matches the given 'filter', "+
+	"then the given retention 'period' is applied to it instead of -retentionPeriod. The 'period' cannot exceed -retentionPeriod. "+
+	"The first matching filter is applied if multiple filters match the series. For example, -retentionFilter='{env=\"dev\"}:7d'. "+
+	"See https://docs.victoriametrics.com/#retention-filters")
+
+func parseRetentionFilters(filters []string) ([]storage.RetentionFilter, error) {
+	var rfs []storage.RetentionFilter
+	seen := make(map[string]bool, len(filters))
+	for _, s := range filters {
+		rf, err := parseRetentionFilter(s)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse -retentionFilter=%q: %w", s, err)
+		}
+		if seen[rf.Filter] {
+			return nil, fmt.Errorf("duplicate -retentionFilter=%q", s)
+		}
+		seen[rf.Filter] = true
+		rfs = append(rfs, *rf)
+	}
+	return rfs, nil
+}
+
+func parseRetentionFilter(s string) (*storage.RetentionFilter, error) {
+	// The filter may contain colons in metric names and label values, while the period cannot contain colons.
+	n := strings.LastIndexByte(s, ':')
+	if n < 0 {
+		return nil, fmt.Errorf("missing ':' delimiter between filter and retention period")
+	}
+	filter := strings.TrimSpace(s[:n])
+	period := strings.TrimSpace(s[n+1:])
+	tagFilters, err := searchutils.ParseMetricSelector(filter)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse filter %q: %w", filter, err)
+	}
+	tfs := storage.NewTagFilters()
+	for i := range tagFilters {
+		tf := &tagFilters[i]
+		if err := tfs.Add(tf.Key, tf.Value, tf.IsNegative, tf.IsRegexp); err != nil {
+			return nil, fmt.Errorf("cannot parse tag filter %s: %w", tf, err)
+		}
+	}
+	var d flagutil.Duration
+	if err := d.Set(period); err != nil {
+		return nil, fmt.Errorf("cannot parse retention period %q: %w", period, err)
+	}
+	return &storage.RetentionFilter{
+		Filter:         filter,
+		TagFilters:     tfs,
+		RetentionMsecs: d.Msecs,
+	}, nil
+}
+
+func registerRetentionFiltersMetrics(m func() *storage.Metrics) {
+	for i, rfm := range m().RetentionFilters {
+		idx := i
+		metrics.NewGauge(fmt.Sprintf(`vm_retention_filter_series{filter=%q}`, rfm.Filter), func() float64 {
+			return float64(m().RetentionFilters[idx].SeriesCount)
+		})
+		metrics.NewGauge(fmt.Sprintf(`vm_retention_filter_rows_deleted_total{filter=%q}`, rfm.Filter), func() float64 {
+			return float64(m().RetentionFilters[idx].RowsDeleted)
+		})
+	}
+}
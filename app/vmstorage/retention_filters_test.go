@@ -0,0 +1,56 @@
+package vmstorage
+
+import (
+	"testing"
+)
+
+func TestParseRetentionFilterFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		if _, err := parseRetentionFilter(s); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+	f("")
+	f(`{env="dev"}`)
+	f(`{env="dev"}:`)
+	f(`:7d`)
+	f(`{env="dev"}:foo`)
+	f(`{env="dev"}:5m`)
+	f(`sum(foo):7d`)
+	f(`{env=~"(dev"}:7d`)
+}
+
+func TestParseRetentionFilterSuccess(t *testing.T) {
+	f := func(s, filterExpected string, retentionMsecsExpected int64) {
+		t.Helper()
+		rf, err := parseRetentionFilter(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if rf.Filter != filterExpected {
+			t.Fatalf("unexpected filter; got %q; want %q", rf.Filter, filterExpected)
+		}
+		if rf.RetentionMsecs != retentionMsecsExpected {
+			t.Fatalf("unexpected retention; got %d; want %d", rf.RetentionMsecs, retentionMsecsExpected)
+		}
+	}
+	const day = 24 * 3600 * 1000
+	f(`{env="dev"}:7d`, `{env="dev"}`, 7*day)
+	f(`{env="dev", job=~"node.+"} : 2w`, `{env="dev", job=~"node.+"}`, 14*day)
+	f(`job:slo:ratio{team="a:b"}:2y`, `job:slo:ratio{team="a:b"}`, 2*365*day)
+	f(`debug_metric:1`, `debug_metric`, 31*day)
+}
+
+func TestParseRetentionFiltersDuplicate(t *testing.T) {
+	if _, err := parseRetentionFilters([]string{`{env="dev"}:7d`, `{env="dev"}:3d`}); err == nil {
+		t.Fatalf("expecting non-nil error for duplicate filters")
+	}
+	rfs, err := parseRetentionFilters([]string{`{env="dev"}:7d`, `{env="staging"}:3d`})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rfs) != 2 {
+		t.Fatalf("unexpected number of filters; got %d; want %d", len(rfs), 2)
+	}
+}
@@ -1,6 +1,7 @@
 package actions
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sync/atomic"
@@ -42,6 +43,14 @@ type Backup struct {
 
 // Run runs b with the provided settings.
 func (b *Backup) Run() error {
+	return b.RunWithContext(context.Background())
+}
+
+// RunWithContext runs b with the provided settings.
+//
+// The backup is interrupted with an error when ctx is cancelled. The interrupted backup
+// is resumed by the next backup to the same Dst.
+func (b *Backup) RunWithContext(ctx context.Context) error {
 	concurrency := b.Concurrency
 	src := b.Src
 	dst := b.Dst
@@ -57,7 +66,7 @@ func (b *Backup) Run() error {
 	if err := dst.DeleteFile(fscommon.BackupCompleteFilename); err != nil {
 		return fmt.Errorf("cannot delete `backup complete` file at %s: %w", dst, err)
 	}
-	if err := runBackup(src, dst, origin, concurrency); err != nil {
+	if err := runBackup(ctx, src, dst, origin, concurrency); err != nil {
 		return err
 	}
 	if err := dst.CreateFile(fscommon.BackupCompleteFilename, []byte("ok")); err != nil {
@@ -66,7 +75,7 @@ func (b *Backup) Run() error {
 	return nil
 }
 
-func runBackup(src *fslocal.FS, dst common.RemoteFS, origin common.OriginFS, concurrency int) error {
+func runBackup(ctx context.Context, src *fslocal.FS, dst common.RemoteFS, origin common.OriginFS, concurrency int) error {
 	startTime := time.Now()
 
 	logger.Infof("starting backup from %s to %s using origin %s", src, dst, origin)
@@ -97,6 +106,9 @@ func runBackup(src *fslocal.FS, dst common.RemoteFS, origin common.OriginFS, con
 		logger.Infof("deleting %d parts from dst %s", len(partsToDelete), dst)
 		deletedParts := uint64(0)
 		err = runParallel(concurrency, partsToDelete, func(p common.Part) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			logger.Infof("deleting %s from dst %s", &p, dst)
 			if err := dst.DeletePart(p); err != nil {
 				return fmt.Errorf("cannot delete %s from dst %s: %w", &p, dst, err)
@@ -122,6 +134,9 @@ func runBackup(src *fslocal.FS, dst common.RemoteFS, origin common.OriginFS, con
 		logger.Infof("server-side copying %d parts from origin %s to dst %s", len(originCopyParts), origin, dst)
 		copiedParts := uint64(0)
 		err = runParallel(concurrency, originCopyParts, func(p common.Part) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			logger.Infof("server-side copying %s from origin %s to dst %s", &p, origin, dst)
 			if err := dst.CopyPart(origin, p); err != nil {
 				return fmt.Errorf("cannot copy %s from origin %s to dst %s: %w", &p, origin, dst, err)
@@ -143,16 +158,21 @@ func runBackup(src *fslocal.FS, dst common.RemoteFS, origin common.OriginFS, con
 		logger.Infof("uploading %d parts from src %s to dst %s", len(srcCopyParts), src, dst)
 		bytesUploaded := uint64(0)
 		err = runParallel(concurrency, srcCopyParts, func(p common.Part) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			logger.Infof("uploading %s from src %s to dst %s", &p, src, dst)
 			rc, err := src.NewReadCloser(p)
 			if err != nil {
 				return fmt.Errorf("cannot create reader for %s from src %s: %w", &p, src, err)
 			}
 			sr := &statReader{
+				ctx:       ctx,
 				r:         rc,
 				bytesRead: &bytesUploaded,
 			}
 			if err := dst.UploadPart(p, sr); err != nil {
+				_ = rc.Close()
 				return fmt.Errorf("cannot upload %s to dst %s: %w", &p, dst, err)
 			}
 			if err = rc.Close(); err != nil {
@@ -175,11 +195,16 @@ func runBackup(src *fslocal.FS, dst common.RemoteFS, origin common.OriginFS, con
 }
 
 type statReader struct {
+	// ctx allows interrupting the upload in the middle of the part.
+	ctx       context.Context
 	r         io.Reader
 	bytesRead *uint64
 }
 
 func (sr *statReader) Read(p []byte) (int, error) {
+	if err := sr.ctx.Err(); err != nil {
+		return 0, err
+	}
 	n, err := sr.r.Read(p)
 	atomic.AddUint64(sr.bytesRead, uint64(n))
 	return n, err
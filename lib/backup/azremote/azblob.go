@@ -0,0 +1,353 @@
+package azremote
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/fscommon"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+const (
+	// blockSize is the size of a single block for uploading parts in parallel.
+	//
+	// Azure allows up to 50000 blocks per blob, so common.MaxPartSize fits in 4MiB blocks.
+	blockSize = 4 * 1024 * 1024
+
+	// uploadConcurrency is the maximum number of blocks uploaded in parallel per part.
+	uploadConcurrency = 4
+
+	// copyPollInterval is the interval for polling the status of server-side copy.
+	copyPollInterval = 100 * time.Millisecond
+)
+
+// FS represents filesystem for backups in Azure Blob Storage.
+//
+// Credentials are read from environment variables - see newClientFromEnv for details.
+//
+// Init must be called before calling other FS methods.
+type FS struct {
+	// Azure Blob Storage container to use.
+	Container string
+
+	// Directory in the container to write to.
+	Dir string
+
+	c *client
+}
+
+// Init initializes fs.
+//
+// The returned fs must be stopped when no long needed with MustStop call.
+func (fs *FS) Init() error {
+	if fs.c != nil {
+		logger.Panicf("BUG: Init is already called")
+	}
+	for strings.HasPrefix(fs.Dir, "/") {
+		fs.Dir = fs.Dir[1:]
+	}
+	if !strings.HasSuffix(fs.Dir, "/") {
+		fs.Dir += "/"
+	}
+	c, err := newClientFromEnv()
+	if err != nil {
+		return fmt.Errorf("cannot initialize Azure Blob Storage client: %w", err)
+	}
+	fs.c = c
+	return nil
+}
+
+// MustStop stops fs.
+func (fs *FS) MustStop() {
+	fs.c = nil
+}
+
+// String returns human-readable description for fs.
+func (fs *FS) String() string {
+	return fmt.Sprintf("AZBlob{container: %q, dir: %q}", fs.Container, fs.Dir)
+}
+
+// ListParts returns all the parts for fs.
+func (fs *FS) ListParts() ([]common.Part, error) {
+	dir := fs.Dir
+	var parts []common.Part
+	err := fs.c.listBlobs(fs.Container, dir, func(bi *blobItem) error {
+		file := bi.Name
+		if !strings.HasPrefix(file, dir) {
+			return fmt.Errorf("unexpected prefix for blob %q; want %q", file, dir)
+		}
+		if fscommon.IgnorePath(file) {
+			return nil
+		}
+		var p common.Part
+		if !p.ParseFromRemotePath(file[len(dir):]) {
+			logger.Infof("skipping unknown object %q", file)
+			return nil
+		}
+		p.ActualSize = uint64(bi.Size)
+		parts = append(parts, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when listing blobs inside dir %q at %s: %w", dir, fs, err)
+	}
+	return parts, nil
+}
+
+// DeletePart deletes part p from fs.
+func (fs *FS) DeletePart(p common.Part) error {
+	path := fs.path(p)
+	if _, err := fs.c.doAndClose(http.MethodDelete, fs.c.blobURL(fs.Container, path), nil, nil, nil); err != nil {
+		if isNotFound(err) {
+			// The part may be already deleted by the previous attempt, which has been retried.
+			return nil
+		}
+		return fmt.Errorf("cannot delete %q at %s (remote path %q): %w", p.Path, fs, path, err)
+	}
+	return nil
+}
+
+// RemoveEmptyDirs recursively removes empty dirs in fs.
+func (fs *FS) RemoveEmptyDirs() error {
+	// Azure Blob Storage has no directories, so nothing to remove.
+	return nil
+}
+
+// CopyPart copies p from srcFS to fs.
+//
+// The copying is performed on the server side.
+func (fs *FS) CopyPart(srcFS common.OriginFS, p common.Part) error {
+	src, ok := srcFS.(*FS)
+	if !ok {
+		return fmt.Errorf("cannot perform server-side copying from %s to %s: both of them must be Azure Blob Storage", srcFS, fs)
+	}
+	srcPath := src.path(p)
+	dstPath := fs.path(p)
+	copySource := src.c.blobURL(src.Container, srcPath)
+	if src.c.sasToken != "" {
+		copySource += "?" + src.c.sasToken
+	}
+	dstURL := fs.c.blobURL(fs.Container, dstPath)
+	header := http.Header{
+		"x-ms-copy-source": {copySource},
+	}
+	h, err := fs.c.doAndClose(http.MethodPut, dstURL, nil, header, nil)
+	if err != nil {
+		return fmt.Errorf("cannot copy %q from %s to %s (remote path %q): %w", p.Path, src, fs, srcPath, err)
+	}
+	for {
+		switch status := h.Get("x-ms-copy-status"); status {
+		case "", "success":
+			return nil
+		case "pending":
+			time.Sleep(copyPollInterval)
+			h, err = fs.c.doAndClose(http.MethodHead, dstURL, nil, nil, nil)
+			if err != nil {
+				return fmt.Errorf("cannot obtain copy status for %q from %s to %s (remote path %q): %w", p.Path, src, fs, srcPath, err)
+			}
+		default:
+			return fmt.Errorf("cannot copy %q from %s to %s (remote path %q): unexpected copy status %q; description: %q",
+				p.Path, src, fs, srcPath, status, h.Get("x-ms-copy-status-description"))
+		}
+	}
+}
+
+// DownloadPart downloads part p from fs to w.
+func (fs *FS) DownloadPart(p common.Part, w io.Writer) error {
+	path := fs.path(p)
+	resp, err := fs.c.do(http.MethodGet, fs.c.blobURL(fs.Container, path), nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("cannot open %q at %s (remote path %q): %w", p.Path, fs, path, err)
+	}
+	r := resp.Body
+	n, err := io.Copy(w, r)
+	if err1 := r.Close(); err1 != nil && err == nil {
+		err = err1
+	}
+	if err != nil {
+		return fmt.Errorf("cannot download %q from at %s (remote path %q): %w", p.Path, fs, path, err)
+	}
+	if uint64(n) != p.Size {
+		return fmt.Errorf("wrong data size downloaded from %q at %s; got %d bytes; want %d bytes", p.Path, fs, n, p.Size)
+	}
+	return nil
+}
+
+// UploadPart uploads part p from r to fs.
+//
+// Big parts are uploaded in blocks in parallel.
+func (fs *FS) UploadPart(p common.Part, r io.Reader) error {
+	path := fs.path(p)
+	n, err := fs.uploadBlob(path, r)
+	if err != nil {
+		return fmt.Errorf("cannot upload data to %q at %s (remote path %q): %w", p.Path, fs, path, err)
+	}
+	if uint64(n) != p.Size {
+		return fmt.Errorf("wrong data size uploaded to %q at %s; got %d bytes; want %d bytes", p.Path, fs, n, p.Size)
+	}
+	return nil
+}
+
+// DeleteFile deletes filePath from fs if it exists.
+//
+// The function does nothing if the file doesn't exist.
+func (fs *FS) DeleteFile(filePath string) error {
+	path := fs.Dir + filePath
+	if _, err := fs.c.doAndClose(http.MethodDelete, fs.c.blobURL(fs.Container, path), nil, nil, nil); err != nil {
+		if isNotFound(err) {
+			// Missing file - nothing to delete.
+			return nil
+		}
+		return fmt.Errorf("cannot delete %q at %s (remote path %q): %w", filePath, fs, path, err)
+	}
+	return nil
+}
+
+// CreateFile creates filePath at fs and puts data into it.
+//
+// The file is overwritten if it already exists.
+func (fs *FS) CreateFile(filePath string, data []byte) error {
+	path := fs.Dir + filePath
+	if err := fs.putBlob(path, data); err != nil {
+		return fmt.Errorf("cannot upload data to %q at %s (remote path %q): %w", filePath, fs, path, err)
+	}
+	return nil
+}
+
+// HasFile returns true if filePath exists at fs.
+func (fs *FS) HasFile(filePath string) (bool, error) {
+	path := fs.Dir + filePath
+	if _, err := fs.c.doAndClose(http.MethodHead, fs.c.blobURL(fs.Container, path), nil, nil, nil); err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("cannot open %q at %s (remote path %q): %w", filePath, fs, path, err)
+	}
+	return true, nil
+}
+
+func (fs *FS) path(p common.Part) string {
+	return p.RemotePath(fs.Dir)
+}
+
+func (fs *FS) putBlob(path string, data []byte) error {
+	header := http.Header{
+		"x-ms-blob-type": {"BlockBlob"},
+	}
+	_, err := fs.c.doAndClose(http.MethodPut, fs.c.blobURL(fs.Container, path), nil, header, data)
+	return err
+}
+
+// uploadBlob uploads data from r to the blob at the given path and returns the number of uploaded bytes.
+//
+// Data fitting a single block is uploaded with a single Put Blob request.
+// Bigger data is split into blocks, which are uploaded in parallel and then committed with Put Block List request.
+func (fs *FS) uploadBlob(path string, r io.Reader) (int64, error) {
+	buf := make([]byte, blockSize)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		if err := fs.putBlob(path, buf[:n]); err != nil {
+			return 0, err
+		}
+		return int64(n), nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot read data: %w", err)
+	}
+
+	type block struct {
+		id   string
+		data []byte
+	}
+	blockURL := fs.c.blobURL(fs.Container, path)
+	blocksCh := make(chan block)
+	var wg sync.WaitGroup
+	var errOnce sync.Once
+	var errGlobal error
+	stopCh := make(chan struct{})
+	for i := 0; i < uploadConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range blocksCh {
+				query := url.Values{
+					"comp":    {"block"},
+					"blockid": {b.id},
+				}
+				if _, err := fs.c.doAndClose(http.MethodPut, blockURL, query, nil, b.data); err != nil {
+					errOnce.Do(func() {
+						errGlobal = fmt.Errorf("cannot upload block %q: %w", b.id, err)
+						close(stopCh)
+					})
+				}
+			}
+		}()
+	}
+
+	var blockIDs []string
+	size := int64(0)
+	for {
+		id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%010d", len(blockIDs))))
+		blockIDs = append(blockIDs, id)
+		size += int64(n)
+		select {
+		case blocksCh <- block{id: id, data: buf[:n]}:
+		case <-stopCh:
+		}
+		if err != nil || isStopped(stopCh) {
+			break
+		}
+		buf = make([]byte, blockSize)
+		n, err = io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			close(blocksCh)
+			wg.Wait()
+			return 0, fmt.Errorf("cannot read data: %w", err)
+		}
+	}
+	close(blocksCh)
+	wg.Wait()
+	if errGlobal != nil {
+		return 0, errGlobal
+	}
+
+	var bl blockList
+	bl.Latest = blockIDs
+	data, err := xml.Marshal(&bl)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal block list: %s", err)
+	}
+	data = append([]byte(xml.Header), data...)
+	query := url.Values{
+		"comp": {"blocklist"},
+	}
+	if _, err := fs.c.doAndClose(http.MethodPut, blockURL, query, nil, data); err != nil {
+		return 0, fmt.Errorf("cannot commit %d blocks: %w", len(blockIDs), err)
+	}
+	return size, nil
+}
+
+type blockList struct {
+	XMLName xml.Name `xml:"BlockList"`
+	Latest  []string `xml:"Latest"`
+}
+
+func isStopped(stopCh <-chan struct{}) bool {
+	select {
+	case <-stopCh:
+		return true
+	default:
+		return false
+	}
+}
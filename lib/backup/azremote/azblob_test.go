@@ -0,0 +1,458 @@
+package azremote
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/common"
+)
+
+const (
+	testAccountName = "devstoreaccount1"
+	testAccountKey  = "dGVzdC1hY2NvdW50LWtleQ=="
+)
+
+// fakeAzurite is an in-memory stand-in for Azure Blob Storage, which accepts path-style urls like Azurite does.
+type fakeAzurite struct {
+	t *testing.T
+	c *client
+
+	mu           sync.Mutex
+	blobs        map[string][]byte
+	blocks       map[string]map[string][]byte
+	pendingCopy  map[string]bool
+	blockUploads int
+
+	// failRequests is the number of the next requests, which must fail with the failStatusCode.
+	failRequests   int
+	failStatusCode int
+	requests       int
+}
+
+func newFakeAzurite(t *testing.T) (*fakeAzurite, *httptest.Server) {
+	key, err := base64.StdEncoding.DecodeString(testAccountKey)
+	if err != nil {
+		t.Fatalf("cannot decode account key: %s", err)
+	}
+	fa := &fakeAzurite{
+		t: t,
+		c: &client{
+			accountName: testAccountName,
+			accountKey:  key,
+		},
+		blobs:       make(map[string][]byte),
+		blocks:      make(map[string]map[string][]byte),
+		pendingCopy: make(map[string]bool),
+	}
+	return fa, httptest.NewServer(fa)
+}
+
+func (fa *fakeAzurite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fa.mu.Lock()
+	fa.requests++
+	if fa.failRequests > 0 {
+		fa.failRequests--
+		fa.mu.Unlock()
+		fa.writeError(w, fa.failStatusCode, "ServerBusy", "The server is busy.")
+		return
+	}
+	fa.mu.Unlock()
+
+	auth := r.Header.Get("Authorization")
+	if expected := "SharedKey " + testAccountName + ":" + fa.c.sign(r); auth != expected {
+		fa.writeError(w, http.StatusForbidden, "AuthenticationFailed", fmt.Sprintf("unexpected Authorization header %q; want %q", auth, expected))
+		return
+	}
+	if r.Header.Get("x-ms-version") == "" || r.Header.Get("x-ms-date") == "" {
+		fa.writeError(w, http.StatusBadRequest, "MissingRequiredHeader", "missing x-ms-version or x-ms-date header")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/"+testAccountName+"/")
+	q := r.URL.Query()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		fa.t.Errorf("cannot read request body: %s", err)
+		return
+	}
+
+	fa.mu.Lock()
+	defer fa.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && q.Get("comp") == "list":
+		fa.listBlobs(w, path, q.Get("prefix"), q.Get("marker"))
+	case r.Method == http.MethodGet:
+		data, ok := fa.blobs[path]
+		if !ok {
+			fa.writeError(w, http.StatusNotFound, "BlobNotFound", "The specified blob does not exist.")
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodHead:
+		if _, ok := fa.blobs[path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if fa.pendingCopy[path] {
+			// Report the pending status only once.
+			delete(fa.pendingCopy, path)
+			w.Header().Set("x-ms-copy-status", "pending")
+		} else {
+			w.Header().Set("x-ms-copy-status", "success")
+		}
+	case r.Method == http.MethodDelete:
+		if _, ok := fa.blobs[path]; !ok {
+			fa.writeError(w, http.StatusNotFound, "BlobNotFound", "The specified blob does not exist.")
+			return
+		}
+		delete(fa.blobs, path)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && q.Get("comp") == "block":
+		m := fa.blocks[path]
+		if m == nil {
+			m = make(map[string][]byte)
+			fa.blocks[path] = m
+		}
+		m[q.Get("blockid")] = body
+		fa.blockUploads++
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && q.Get("comp") == "blocklist":
+		var bl blockList
+		if err := xml.Unmarshal(body, &bl); err != nil {
+			fa.writeError(w, http.StatusBadRequest, "InvalidXmlDocument", err.Error())
+			return
+		}
+		var data []byte
+		for _, id := range bl.Latest {
+			b, ok := fa.blocks[path][id]
+			if !ok {
+				fa.writeError(w, http.StatusBadRequest, "InvalidBlockList", fmt.Sprintf("missing block %q", id))
+				return
+			}
+			data = append(data, b...)
+		}
+		delete(fa.blocks, path)
+		fa.blobs[path] = data
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && r.Header.Get("x-ms-copy-source") != "":
+		u, err := url.Parse(r.Header.Get("x-ms-copy-source"))
+		if err != nil {
+			fa.writeError(w, http.StatusBadRequest, "InvalidHeaderValue", err.Error())
+			return
+		}
+		srcPath := strings.TrimPrefix(u.Path, "/"+testAccountName+"/")
+		data, ok := fa.blobs[srcPath]
+		if !ok {
+			fa.writeError(w, http.StatusNotFound, "CannotVerifyCopySource", "The specified blob does not exist.")
+			return
+		}
+		fa.blobs[path] = append([]byte{}, data...)
+		fa.pendingCopy[path] = true
+		w.Header().Set("x-ms-copy-status", "pending")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut:
+		if r.Header.Get("x-ms-blob-type") != "BlockBlob" {
+			fa.writeError(w, http.StatusBadRequest, "MissingRequiredHeader", "missing x-ms-blob-type header")
+			return
+		}
+		fa.blobs[path] = body
+		w.WriteHeader(http.StatusCreated)
+	default:
+		fa.writeError(w, http.StatusBadRequest, "UnsupportedHttpVerb", r.Method)
+	}
+}
+
+func (fa *fakeAzurite) listBlobs(w http.ResponseWriter, container, prefix, marker string) {
+	var names []string
+	for name := range fa.blobs {
+		if strings.HasPrefix(name, container+"/"+prefix) {
+			names = append(names, strings.TrimPrefix(name, container+"/"))
+		}
+	}
+	sort.Strings(names)
+	n := sort.SearchStrings(names, marker)
+	names = names[n:]
+
+	// Return at most 2 blobs per page in order to verify pagination.
+	var lbr listBlobsResponse
+	if len(names) > 2 {
+		lbr.NextMarker = names[2]
+		names = names[:2]
+	}
+	for _, name := range names {
+		lbr.Blobs = append(lbr.Blobs, blobItem{
+			Name: name,
+			Size: int64(len(fa.blobs[container+"/"+name])),
+		})
+	}
+	data, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"EnumerationResults"`
+		listBlobsResponse
+	}{listBlobsResponse: lbr})
+	if err != nil {
+		fa.t.Errorf("cannot marshal list blobs response: %s", err)
+		return
+	}
+	_, _ = w.Write(data)
+}
+
+func (fa *fakeAzurite) writeError(w http.ResponseWriter, statusCode int, code, msg string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.WriteHeader(statusCode)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?><Error><Code>%s</Code><Message>%s</Message></Error>", code, msg)
+}
+
+func newTestFS(t *testing.T, endpoint, container, dir string) *FS {
+	t.Helper()
+	t.Setenv("AZURE_STORAGE_ACCOUNT_CONNECTION_STRING", fmt.Sprintf("DefaultEndpointsProtocol=http;AccountName=%s;AccountKey=%s;BlobEndpoint=%s/%s;",
+		testAccountName, testAccountKey, endpoint, testAccountName))
+	fs := &FS{
+		Container: container,
+		Dir:       dir,
+	}
+	if err := fs.Init(); err != nil {
+		t.Fatalf("cannot initialize fs: %s", err)
+	}
+	return fs
+}
+
+func TestFS(t *testing.T) {
+	fa, srv := newFakeAzurite(t)
+	defer srv.Close()
+
+	fs := newTestFS(t, srv.URL, "backups", "/foo/bar")
+	defer fs.MustStop()
+	if s := fs.String(); s != `AZBlob{container: "backups", dir: "foo/bar/"}` {
+		t.Fatalf("unexpected fs description: %s", s)
+	}
+
+	// Small part is uploaded with a single request, while big part is uploaded in blocks.
+	smallData := []byte("small part contents")
+	bigData := bytes.Repeat([]byte("0123456789abcdef"), (2*blockSize+1234)/16)
+	parts := []common.Part{
+		{
+			Path:     "data/small/file",
+			FileSize: uint64(len(smallData)),
+			Size:     uint64(len(smallData)),
+		},
+		{
+			Path:     "data/big/file",
+			FileSize: uint64(len(bigData)),
+			Size:     uint64(len(bigData)),
+		},
+	}
+	if err := fs.UploadPart(parts[0], bytes.NewReader(smallData)); err != nil {
+		t.Fatalf("cannot upload small part: %s", err)
+	}
+	if fa.blockUploads != 0 {
+		t.Fatalf("unexpected number of uploaded blocks for small part; got %d; want 0", fa.blockUploads)
+	}
+	if err := fs.UploadPart(parts[1], bytes.NewReader(bigData)); err != nil {
+		t.Fatalf("cannot upload big part: %s", err)
+	}
+	if fa.blockUploads != 3 {
+		t.Fatalf("unexpected number of uploaded blocks for big part; got %d; want 3", fa.blockUploads)
+	}
+
+	// Wrong part size must be detected.
+	brokenPart := parts[0]
+	brokenPart.Path = "data/broken/file"
+	brokenPart.Size++
+	if err := fs.UploadPart(brokenPart, bytes.NewReader(smallData)); err == nil {
+		t.Fatalf("expecting non-nil error when uploading part with wrong size")
+	}
+	if err := fs.DeletePart(brokenPart); err != nil {
+		t.Fatalf("cannot delete broken part: %s", err)
+	}
+
+	// Create and verify files. Put more files than a single page of list response can hold.
+	for _, name := range []string{"backup_complete.ignore", "backup_metadata.ignore"} {
+		if err := fs.CreateFile(name, []byte("ok")); err != nil {
+			t.Fatalf("cannot create file %q: %s", name, err)
+		}
+		ok, err := fs.HasFile(name)
+		if err != nil {
+			t.Fatalf("unexpected error in HasFile(%q): %s", name, err)
+		}
+		if !ok {
+			t.Fatalf("missing file %q", name)
+		}
+	}
+	ok, err := fs.HasFile("missing")
+	if err != nil {
+		t.Fatalf("unexpected error in HasFile for missing file: %s", err)
+	}
+	if ok {
+		t.Fatalf("unexpected HasFile result for missing file")
+	}
+
+	checkParts := func(fs *FS, partsExpected []common.Part) {
+		t.Helper()
+		ps, err := fs.ListParts()
+		if err != nil {
+			t.Fatalf("cannot list parts at %s: %s", fs, err)
+		}
+		if len(ps) != len(partsExpected) {
+			t.Fatalf("unexpected number of parts at %s; got %d; want %d", fs, len(ps), len(partsExpected))
+		}
+		m := make(map[string]bool)
+		for _, p := range ps {
+			if p.ActualSize != p.Size {
+				t.Fatalf("unexpected actual size for part %s; got %d; want %d", &p, p.ActualSize, p.Size)
+			}
+			m[p.String()] = true
+		}
+		for _, p := range partsExpected {
+			if !m[p.String()] {
+				t.Fatalf("missing part %s at %s", &p, fs)
+			}
+		}
+		for _, p := range partsExpected {
+			var bb bytes.Buffer
+			if err := fs.DownloadPart(p, &bb); err != nil {
+				t.Fatalf("cannot download part %s from %s: %s", &p, fs, err)
+			}
+			dataExpected := smallData
+			if p.Size == uint64(len(bigData)) {
+				dataExpected = bigData
+			}
+			if !bytes.Equal(bb.Bytes(), dataExpected) {
+				t.Fatalf("unexpected data downloaded for part %s from %s", &p, fs)
+			}
+		}
+	}
+	checkParts(fs, parts)
+
+	// Server-side copy to another fs.
+	dstFS := newTestFS(t, srv.URL, "backups", "baz")
+	defer dstFS.MustStop()
+	for _, p := range parts {
+		if err := dstFS.CopyPart(fs, p); err != nil {
+			t.Fatalf("cannot copy part %s: %s", &p, err)
+		}
+	}
+	checkParts(dstFS, parts)
+	if err := dstFS.CopyPart(fs, brokenPart); err == nil {
+		t.Fatalf("expecting non-nil error when copying missing part")
+	}
+
+	// Delete files. Deleting missing file mustn't return error.
+	for _, name := range []string{"backup_complete.ignore", "backup_complete.ignore", "missing"} {
+		if err := fs.DeleteFile(name); err != nil {
+			t.Fatalf("cannot delete file %q: %s", name, err)
+		}
+	}
+	ok, err = fs.HasFile("backup_complete.ignore")
+	if err != nil {
+		t.Fatalf("unexpected error in HasFile for deleted file: %s", err)
+	}
+	if ok {
+		t.Fatalf("unexpected HasFile result for deleted file")
+	}
+
+	// Requests with invalid credentials must fail.
+	fs.c.accountKey = []byte("invalid key")
+	if _, err := fs.ListParts(); err == nil {
+		t.Fatalf("expecting non-nil error for invalid credentials")
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	defer func(d time.Duration) {
+		retryBackoff = d
+	}(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	fa, srv := newFakeAzurite(t)
+	defer srv.Close()
+
+	fs := newTestFS(t, srv.URL, "backups", "foo")
+	defer fs.MustStop()
+	data := []byte("part contents")
+	p := common.Part{
+		Path:     "data/file",
+		FileSize: uint64(len(data)),
+		Size:     uint64(len(data)),
+	}
+
+	f := func(failRequests, failStatusCode, requestsExpected int, resultExpected bool) {
+		t.Helper()
+		fa.mu.Lock()
+		fa.failRequests = failRequests
+		fa.failStatusCode = failStatusCode
+		fa.requests = 0
+		fa.mu.Unlock()
+
+		err := fs.UploadPart(p, bytes.NewReader(data))
+		if result := err == nil; result != resultExpected {
+			t.Fatalf("unexpected result for failRequests=%d, statusCode=%d; got %v; want %v; err: %v",
+				failRequests, failStatusCode, result, resultExpected, err)
+		}
+		fa.mu.Lock()
+		requests := fa.requests
+		fa.failRequests = 0
+		fa.mu.Unlock()
+		if requests != requestsExpected {
+			t.Fatalf("unexpected number of requests for failRequests=%d, statusCode=%d; got %d; want %d",
+				failRequests, failStatusCode, requests, requestsExpected)
+		}
+	}
+
+	// Transient errors are retried.
+	f(2, http.StatusServiceUnavailable, 3, true)
+	f(1, http.StatusTooManyRequests, 2, true)
+	f(1, http.StatusInternalServerError, 2, true)
+
+	// Too many transient errors.
+	f(maxRetries+1, http.StatusServiceUnavailable, maxRetries+1, false)
+
+	// Client errors aren't retried.
+	f(1, http.StatusBadRequest, 1, false)
+}
+
+func TestNewClientFromConnectionStringFailure(t *testing.T) {
+	f := func(cs string) {
+		t.Helper()
+		if _, err := newClientFromConnectionString(cs); err == nil {
+			t.Fatalf("expecting non-nil error for %q", cs)
+		}
+	}
+	f("")
+	f("foobar")
+	f("AccountName=foo")
+	f("AccountName=foo;AccountKey=invalid-base64!")
+	f("BlobEndpoint=http://localhost:10000/foo;AccountKey=" + testAccountKey)
+}
+
+func TestNewClientFromConnectionStringSuccess(t *testing.T) {
+	f := func(cs, endpointExpected, sasTokenExpected string) {
+		t.Helper()
+		c, err := newClientFromConnectionString(cs)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if c.endpoint != endpointExpected {
+			t.Fatalf("unexpected endpoint; got %q; want %q", c.endpoint, endpointExpected)
+		}
+		if c.sasToken != sasTokenExpected {
+			t.Fatalf("unexpected sas token; got %q; want %q", c.sasToken, sasTokenExpected)
+		}
+	}
+	f("AccountName=foo;AccountKey="+testAccountKey, "https://foo.blob.core.windows.net", "")
+	f("DefaultEndpointsProtocol=http;AccountName=foo;AccountKey="+testAccountKey+";EndpointSuffix=core.chinacloudapi.cn",
+		"http://foo.blob.core.chinacloudapi.cn", "")
+	f("AccountName=devstoreaccount1;AccountKey="+testAccountKey+";BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1/",
+		"http://127.0.0.1:10000/devstoreaccount1", "")
+	f("BlobEndpoint=https://foo.blob.core.windows.net;SharedAccessSignature=?sv=2020-10-02&sig=abc",
+		"https://foo.blob.core.windows.net", "sv=2020-10-02&sig=abc")
+}
@@ -0,0 +1,505 @@
+package azremote
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// apiVersion is the Azure Blob Storage REST API version used by client.
+const apiVersion = "2020-10-02"
+
+const (
+	// requestIdleTimeout is the maximum duration without progress for a single request.
+	//
+	// The request is cancelled if it cannot obtain response headers or cannot read the next chunk of response body during this time.
+	requestIdleTimeout = time.Minute
+
+	// maxRetries is the maximum number of retries for failed requests.
+	maxRetries = 5
+)
+
+// retryBackoff is the initial delay between retries. It is doubled after every retry.
+//
+// It is a variable, so tests could reduce it.
+var retryBackoff = time.Second
+
+// newHTTPClient returns http client with timeouts suitable for Azure Blob Storage.
+func newHTTPClient() *http.Client {
+	tr := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: requestIdleTimeout,
+		ExpectContinueTimeout: time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   100,
+	}
+	return &http.Client{
+		Transport: tr,
+	}
+}
+
+// client is a minimal client for Azure Blob Storage REST API.
+//
+// See https://docs.microsoft.com/en-us/rest/api/storageservices/blob-service-rest-api
+type client struct {
+	// endpoint is the blob service endpoint without trailing slash.
+	// For example, https://account.blob.core.windows.net or http://127.0.0.1:10000/devstoreaccount1 for Azurite.
+	endpoint    string
+	accountName string
+
+	// accountKey is the decoded shared key. It is used for signing requests if set.
+	accountKey []byte
+
+	// sasToken is shared access signature without leading '?'. It is added to every request if set.
+	sasToken string
+
+	// tokenSource is used for obtaining OAuth tokens if set.
+	tokenSource *tokenSource
+
+	hc *http.Client
+}
+
+// newClientFromEnv creates new client from environment variables.
+//
+// The following credentials are supported in the order of priority:
+//
+//   - AZURE_STORAGE_ACCOUNT_CONNECTION_STRING - connection string with AccountName, AccountKey,
+//     SharedAccessSignature and BlobEndpoint fields.
+//   - AZURE_STORAGE_ACCOUNT_NAME and AZURE_STORAGE_ACCOUNT_KEY - shared key.
+//   - AZURE_STORAGE_ACCOUNT_NAME and AZURE_STORAGE_SAS_TOKEN - shared access signature.
+//   - AZURE_STORAGE_ACCOUNT_NAME, AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET - service principal.
+func newClientFromEnv() (*client, error) {
+	if cs := os.Getenv("AZURE_STORAGE_ACCOUNT_CONNECTION_STRING"); cs != "" {
+		c, err := newClientFromConnectionString(cs)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse AZURE_STORAGE_ACCOUNT_CONNECTION_STRING: %w", err)
+		}
+		return c, nil
+	}
+	accountName := os.Getenv("AZURE_STORAGE_ACCOUNT_NAME")
+	if accountName == "" {
+		return nil, fmt.Errorf("either AZURE_STORAGE_ACCOUNT_CONNECTION_STRING or AZURE_STORAGE_ACCOUNT_NAME environment variable must be set")
+	}
+	c := &client{
+		endpoint:    fmt.Sprintf("https://%s.blob.core.windows.net", accountName),
+		accountName: accountName,
+		hc:          newHTTPClient(),
+	}
+	if accountKey := os.Getenv("AZURE_STORAGE_ACCOUNT_KEY"); accountKey != "" {
+		key, err := base64.StdEncoding.DecodeString(accountKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode AZURE_STORAGE_ACCOUNT_KEY: %w", err)
+		}
+		c.accountKey = key
+		return c, nil
+	}
+	if sasToken := os.Getenv("AZURE_STORAGE_SAS_TOKEN"); sasToken != "" {
+		c.sasToken = strings.TrimPrefix(sasToken, "?")
+		return c, nil
+	}
+	tenantID := os.Getenv("AZURE_TENANT_ID")
+	clientID := os.Getenv("AZURE_CLIENT_ID")
+	clientSecret := os.Getenv("AZURE_CLIENT_SECRET")
+	if tenantID != "" && clientID != "" && clientSecret != "" {
+		authorityHost := os.Getenv("AZURE_AUTHORITY_HOST")
+		if authorityHost == "" {
+			authorityHost = "https://login.microsoftonline.com"
+		}
+		c.tokenSource = &tokenSource{
+			tokenURL:     strings.TrimSuffix(authorityHost, "/") + "/" + url.PathEscape(tenantID) + "/oauth2/v2.0/token",
+			clientID:     clientID,
+			clientSecret: clientSecret,
+			hc:           c.hc,
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("missing credentials for Azure storage account %q; set either AZURE_STORAGE_ACCOUNT_KEY, AZURE_STORAGE_SAS_TOKEN "+
+		"or AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET environment variables", accountName)
+}
+
+func newClientFromConnectionString(cs string) (*client, error) {
+	fields := make(map[string]string)
+	for _, kv := range strings.Split(cs, ";") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		n := strings.IndexByte(kv, '=')
+		if n < 0 {
+			return nil, fmt.Errorf("missing '=' in %q", kv)
+		}
+		fields[kv[:n]] = kv[n+1:]
+	}
+	accountName := fields["AccountName"]
+	endpoint := fields["BlobEndpoint"]
+	if endpoint == "" {
+		if accountName == "" {
+			return nil, fmt.Errorf("missing AccountName and BlobEndpoint")
+		}
+		protocol := fields["DefaultEndpointsProtocol"]
+		if protocol == "" {
+			protocol = "https"
+		}
+		suffix := fields["EndpointSuffix"]
+		if suffix == "" {
+			suffix = "core.windows.net"
+		}
+		endpoint = fmt.Sprintf("%s://%s.blob.%s", protocol, accountName, suffix)
+	}
+	c := &client{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		accountName: accountName,
+		hc:          newHTTPClient(),
+	}
+	if accountKey := fields["AccountKey"]; accountKey != "" {
+		if accountName == "" {
+			return nil, fmt.Errorf("missing AccountName for AccountKey")
+		}
+		key, err := base64.StdEncoding.DecodeString(accountKey)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode AccountKey: %w", err)
+		}
+		c.accountKey = key
+		return c, nil
+	}
+	if sasToken := fields["SharedAccessSignature"]; sasToken != "" {
+		c.sasToken = strings.TrimPrefix(sasToken, "?")
+		return c, nil
+	}
+	return nil, fmt.Errorf("missing AccountKey or SharedAccessSignature")
+}
+
+// apiError is an error returned by Azure Blob Storage API.
+type apiError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("unexpected status code %d; code: %q; message: %q", e.StatusCode, e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	ae, ok := err.(*apiError)
+	return ok && ae.StatusCode == http.StatusNotFound
+}
+
+// blobURL returns url for the given blob in the given container.
+func (c *client) blobURL(container, blob string) string {
+	u := &url.URL{
+		Path: "/" + container + "/" + blob,
+	}
+	return c.endpoint + u.EscapedPath()
+}
+
+// do performs the request with the given method at the given url.
+//
+// Failed requests are retried with exponential backoff on network errors, 429 and 5xx responses.
+// Non-2xx responses are returned as *apiError. The caller must close response body.
+func (c *client) do(method, u string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	backoff := retryBackoff
+	for i := 0; ; i++ {
+		resp, err := c.doOnce(method, u, query, header, body)
+		if err == nil || i >= maxRetries || !isRetriable(err) {
+			return resp, err
+		}
+		logger.Warnf("retrying %s request to %q in %s after error: %s", method, u, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func isRetriable(err error) bool {
+	ae, ok := err.(*apiError)
+	if !ok {
+		// Network error or timeout.
+		return true
+	}
+	return ae.StatusCode == http.StatusTooManyRequests || ae.StatusCode >= 500
+}
+
+func (c *client) doOnce(method, u string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	if c.sasToken != "" {
+		if len(query) > 0 {
+			u += "&" + c.sasToken
+		} else {
+			u += "?" + c.sasToken
+		}
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request for %s %q: %w", method, u, err)
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", apiVersion)
+	switch {
+	case c.accountKey != nil:
+		req.Header.Set("Authorization", "SharedKey "+c.accountName+":"+c.sign(req))
+	case c.tokenSource != nil:
+		token, err := c.tokenSource.getToken()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t := time.AfterFunc(requestIdleTimeout, cancel)
+	resp, err := c.hc.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Stop()
+		cancel()
+		return nil, err
+	}
+	resp.Body = &idleTimeoutBody{
+		rc:     resp.Body,
+		t:      t,
+		cancel: cancel,
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	ae := &apiError{}
+	_ = xml.Unmarshal(data, ae)
+	ae.StatusCode = resp.StatusCode
+	if ae.Code == "" {
+		ae.Code = resp.Header.Get("x-ms-error-code")
+	}
+	return nil, ae
+}
+
+// idleTimeoutBody cancels the request if no data is read from response body during requestIdleTimeout.
+type idleTimeoutBody struct {
+	rc     io.ReadCloser
+	t      *time.Timer
+	cancel context.CancelFunc
+}
+
+func (b *idleTimeoutBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.t.Reset(requestIdleTimeout)
+	return n, err
+}
+
+func (b *idleTimeoutBody) Close() error {
+	b.t.Stop()
+	err := b.rc.Close()
+	b.cancel()
+	return err
+}
+
+// doAndClose performs the request and closes the response body.
+func (c *client) doAndClose(method, u string, query url.Values, header http.Header, body []byte) (http.Header, error) {
+	resp, err := c.do(method, u, query, header, body)
+	if err != nil {
+		return nil, err
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp.Header, nil
+}
+
+// sign returns Shared Key signature for req.
+//
+// See https://docs.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func (c *client) sign(req *http.Request) string {
+	h := req.Header
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+	var sb strings.Builder
+	for _, s := range []string{
+		req.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		contentLength,
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		"", // Date is passed via x-ms-date header
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+	} {
+		sb.WriteString(s)
+		sb.WriteByte('\n')
+	}
+
+	// Canonicalized headers
+	var msHeaders []string
+	for k := range h {
+		k = strings.ToLower(k)
+		if strings.HasPrefix(k, "x-ms-") {
+			msHeaders = append(msHeaders, k)
+		}
+	}
+	sort.Strings(msHeaders)
+	for _, k := range msHeaders {
+		sb.WriteString(k)
+		sb.WriteByte(':')
+		sb.WriteString(strings.TrimSpace(h.Get(k)))
+		sb.WriteByte('\n')
+	}
+
+	// Canonicalized resource
+	sb.WriteByte('/')
+	sb.WriteString(c.accountName)
+	sb.WriteString(req.URL.EscapedPath())
+	q := req.URL.Query()
+	qKeys := make([]string, 0, len(q))
+	for k := range q {
+		qKeys = append(qKeys, k)
+	}
+	sort.Strings(qKeys)
+	for _, k := range qKeys {
+		vs := append([]string{}, q[k]...)
+		sort.Strings(vs)
+		sb.WriteByte('\n')
+		sb.WriteString(strings.ToLower(k))
+		sb.WriteByte(':')
+		sb.WriteString(strings.Join(vs, ","))
+	}
+
+	mac := hmac.New(sha256.New, c.accountKey)
+	mac.Write([]byte(sb.String()))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+type blobItem struct {
+	Name string `xml:"Name"`
+	Size int64  `xml:"Properties>Content-Length"`
+}
+
+type listBlobsResponse struct {
+	Blobs      []blobItem `xml:"Blobs>Blob"`
+	NextMarker string     `xml:"NextMarker"`
+}
+
+// listBlobs calls f for every blob with the given prefix in the given container.
+func (c *client) listBlobs(container, prefix string, f func(bi *blobItem) error) error {
+	u := c.endpoint + "/" + url.PathEscape(container)
+	marker := ""
+	for {
+		query := url.Values{
+			"restype": {"container"},
+			"comp":    {"list"},
+			"prefix":  {prefix},
+		}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		resp, err := c.do(http.MethodGet, u, query, nil, nil)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("cannot read list blobs response: %w", err)
+		}
+		var lbr listBlobsResponse
+		if err := xml.Unmarshal(data, &lbr); err != nil {
+			return fmt.Errorf("cannot parse list blobs response %q: %w", data, err)
+		}
+		for i := range lbr.Blobs {
+			if err := f(&lbr.Blobs[i]); err != nil {
+				return err
+			}
+		}
+		if lbr.NextMarker == "" {
+			return nil
+		}
+		marker = lbr.NextMarker
+	}
+}
+
+// tokenSource obtains OAuth tokens for service principal via client credentials flow.
+//
+// See https://docs.microsoft.com/en-us/azure/active-directory/develop/v2-oauth2-client-creds-grant-flow
+type tokenSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	hc           *http.Client
+
+	mu       sync.Mutex
+	token    string
+	deadline time.Time
+}
+
+func (ts *tokenSource) getToken() (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token != "" && time.Now().Before(ts.deadline) {
+		return ts.token, nil
+	}
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {ts.clientID},
+		"client_secret": {ts.clientSecret},
+		"scope":         {"https://storage.azure.com/.default"},
+	}
+	resp, err := ts.hc.PostForm(ts.tokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("cannot obtain OAuth token from %q: %w", ts.tokenURL, err)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("cannot read OAuth token response from %q: %w", ts.tokenURL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code when obtaining OAuth token from %q; got %d; want %d; response: %q",
+			ts.tokenURL, resp.StatusCode, http.StatusOK, data)
+	}
+	var tr struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &tr); err != nil {
+		return "", fmt.Errorf("cannot parse OAuth token response from %q: %w", ts.tokenURL, err)
+	}
+	if tr.AccessToken == "" {
+		return "", fmt.Errorf("missing access_token in OAuth token response from %q", ts.tokenURL)
+	}
+	ts.token = tr.AccessToken
+	// Refresh the token a minute before its expiration.
+	ts.deadline = time.Now().Add(time.Duration(tr.ExpiresIn)*time.Second - time.Minute)
+	return ts.token, nil
+}
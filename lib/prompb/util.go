@@ -6,9 +6,15 @@ func (wr *WriteRequest) Reset() {
 		ts := &wr.Timeseries[i]
 		ts.Labels = nil
 		ts.Samples = nil
+		ts.Exemplars = nil
 	}
 	wr.Timeseries = wr.Timeseries[:0]
 
+	for i := range wr.Metadata {
+		wr.Metadata[i] = MetricMetadata{}
+	}
+	wr.Metadata = wr.Metadata[:0]
+
 	for i := range wr.labelsPool {
 		lb := &wr.labelsPool[i]
 		lb.Name = nil
@@ -22,4 +28,19 @@ func (wr *WriteRequest) Reset() {
 		s.Timestamp = 0
 	}
 	wr.samplesPool = wr.samplesPool[:0]
+
+	for i := range wr.exemplarsPool {
+		e := &wr.exemplarsPool[i]
+		e.Labels = nil
+		e.Value = 0
+		e.Timestamp = 0
+	}
+	wr.exemplarsPool = wr.exemplarsPool[:0]
+
+	for i := range wr.exemplarLabelsPool {
+		lb := &wr.exemplarLabelsPool[i]
+		lb.Name = nil
+		lb.Value = nil
+	}
+	wr.exemplarLabelsPool = wr.exemplarLabelsPool[:0]
 }
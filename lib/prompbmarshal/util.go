@@ -22,6 +22,15 @@ func MarshalWriteRequest(dst []byte, wr *WriteRequest) []byte {
 // ResetWriteRequest resets wr.
 func ResetWriteRequest(wr *WriteRequest) {
 	wr.Timeseries = ResetTimeSeries(wr.Timeseries)
+	wr.Metadata = ResetMetadata(wr.Metadata)
+}
+
+// ResetMetadata clears all the GC references from mms and returns an empty mms ready for further use.
+func ResetMetadata(mms []MetricMetadata) []MetricMetadata {
+	for i := range mms {
+		mms[i] = MetricMetadata{}
+	}
+	return mms[:0]
 }
 
 // ResetTimeSeries clears all the GC references from tss and returns an empty tss ready for further use.
@@ -30,6 +39,53 @@ func ResetTimeSeries(tss []TimeSeries) []TimeSeries {
 		ts := tss[i]
 		ts.Labels = nil
 		ts.Samples = nil
+		ts.Exemplars = nil
 	}
 	return tss[:0]
 }
+
+// String returns Prometheus name for the given metric type such as counter, gauge, histogram, etc.
+func (t MetricMetadata_MetricType) String() string {
+	switch t {
+	case MetricMetadata_COUNTER:
+		return "counter"
+	case MetricMetadata_GAUGE:
+		return "gauge"
+	case MetricMetadata_HISTOGRAM:
+		return "histogram"
+	case MetricMetadata_GAUGEHISTOGRAM:
+		return "gaugehistogram"
+	case MetricMetadata_SUMMARY:
+		return "summary"
+	case MetricMetadata_INFO:
+		return "info"
+	case MetricMetadata_STATESET:
+		return "stateset"
+	default:
+		return "unknown"
+	}
+}
+
+// MetricTypeFromString returns metric type for the given Prometheus name such as counter, gauge, histogram, etc.
+//
+// MetricMetadata_UNKNOWN is returned for unsupported names.
+func MetricTypeFromString(s string) MetricMetadata_MetricType {
+	switch s {
+	case "counter":
+		return MetricMetadata_COUNTER
+	case "gauge":
+		return MetricMetadata_GAUGE
+	case "histogram":
+		return MetricMetadata_HISTOGRAM
+	case "gaugehistogram":
+		return MetricMetadata_GAUGEHISTOGRAM
+	case "summary":
+		return MetricMetadata_SUMMARY
+	case "info":
+		return MetricMetadata_INFO
+	case "stateset":
+		return MetricMetadata_STATESET
+	default:
+		return MetricMetadata_UNKNOWN
+	}
+}
@@ -2,9 +2,12 @@ package promrelabel
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 )
 
 var graphiteMatchesPool = &sync.Pool{
@@ -46,6 +49,60 @@ func newGraphiteLabelRules(m map[string]string) []graphiteLabelRule {
 	return a
 }
 
+// GraphiteMatcher matches Graphite-like dotted names against `match` template with `*` placeholders
+// and builds labels from the matched parts according to `labels` templates.
+//
+// See https://docs.victoriametrics.com/vmagent.html#graphite-relabeling
+type GraphiteMatcher struct {
+	gmt        *graphiteMatchTemplate
+	labelRules []graphiteLabelRule
+}
+
+// NewGraphiteMatcher returns GraphiteMatcher for the given match template and labels templates.
+//
+// labels must contain label name -> replace template mapping. For example, {"job": "$1", "instance": "${2}:8080"}.
+func NewGraphiteMatcher(match string, labels map[string]string) *GraphiteMatcher {
+	labelRules := newGraphiteLabelRules(labels)
+	sort.Slice(labelRules, func(i, j int) bool {
+		return labelRules[i].targetLabel < labelRules[j].targetLabel
+	})
+	return &GraphiteMatcher{
+		gmt:        newGraphiteMatchTemplate(match),
+		labelRules: labelRules,
+	}
+}
+
+// String returns string representation for gm.
+func (gm *GraphiteMatcher) String() string {
+	return fmt.Sprintf("match=%s, labels=%s", gm.gmt, gm.labelRules)
+}
+
+// Match matches name against gm.
+//
+// On success it appends labels built from gm labels templates to dst and returns it with true.
+// On failure it returns dst with false.
+// Labels are appended in the sorted order of their names.
+func (gm *GraphiteMatcher) Match(dst []prompbmarshal.Label, name string) ([]prompbmarshal.Label, bool) {
+	m := graphiteMatchesPool.Get().(*graphiteMatches)
+	var ok bool
+	m.a, ok = gm.gmt.Match(m.a[:0], name)
+	if !ok {
+		graphiteMatchesPool.Put(m)
+		return dst, false
+	}
+	bb := relabelBufPool.Get()
+	for _, gl := range gm.labelRules {
+		bb.B = gl.grt.Expand(bb.B[:0], m.a)
+		dst = append(dst, prompbmarshal.Label{
+			Name:  gl.targetLabel,
+			Value: string(bb.B),
+		})
+	}
+	relabelBufPool.Put(bb)
+	graphiteMatchesPool.Put(m)
+	return dst, true
+}
+
 func newGraphiteMatchTemplate(s string) *graphiteMatchTemplate {
 	sOrig := s
 	var parts []string
@@ -3,6 +3,8 @@ package promrelabel
 import (
 	"reflect"
 	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 )
 
 func TestGraphiteTemplateMatchExpand(t *testing.T) {
@@ -27,6 +29,41 @@ func TestGraphiteTemplateMatchExpand(t *testing.T) {
 	f("*.signup.*.*", "foo.signup.bar.baz", "$1-${3}_$2_total", "foo-baz_bar_total")
 }
 
+func TestGraphiteMatcherMatch(t *testing.T) {
+	f := func(match string, labels map[string]string, s string, labelsExpected []prompbmarshal.Label, okExpected bool) {
+		t.Helper()
+		gm := NewGraphiteMatcher(match, labels)
+		result, ok := gm.Match(nil, s)
+		if ok != okExpected {
+			t.Fatalf("unexpected ok result for match=%q, s=%q; got %v; want %v", match, s, ok, okExpected)
+		}
+		if !reflect.DeepEqual(result, labelsExpected) {
+			t.Fatalf("unexpected labels for match=%q, s=%q; got\n%v\nwant\n%v", match, s, result, labelsExpected)
+		}
+	}
+	labels := map[string]string{
+		"__name__": "${2}_total",
+		"job":      "$1",
+		"instance": "${1}:$2",
+	}
+	f("foo.*.*", labels, "bar.baz", nil, false)
+	f("foo.*.*", labels, "foo.bar.baz.x", nil, false)
+	f("foo.*.*", labels, "foo.bar.baz", []prompbmarshal.Label{
+		{
+			Name:  "__name__",
+			Value: "baz_total",
+		},
+		{
+			Name:  "instance",
+			Value: "bar:baz",
+		},
+		{
+			Name:  "job",
+			Value: "bar",
+		},
+	}, true)
+}
+
 func TestGraphiteMatchTemplateMatch(t *testing.T) {
 	f := func(tpl, s string, matchesExpected []string, okExpected bool) {
 		t.Helper()
@@ -1,6 +1,7 @@
 package promscrape
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"flag"
@@ -15,6 +16,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
 	"github.com/VictoriaMetrics/fasthttp"
 	"github.com/VictoriaMetrics/metrics"
@@ -34,6 +36,20 @@ var (
 	streamParse = flag.Bool("promscrape.streamParse", false, "Whether to enable stream parsing for metrics obtained from scrape targets. This may be useful "+
 		"for reducing memory usage when millions of metrics are exposed per each scrape target. "+
 		"It is posible to set 'stream_parse: true' individually per each 'scrape_config' section in '-promscrape.config' for fine grained control")
+	enableProtobufNegotiation = flag.Bool("promscrape.enableProtobufNegotiation", false, "Whether to request Prometheus protobuf exposition format from all the scrape targets. "+
+		"This allows scraping native histograms, which are exposed only in protobuf format. "+
+		"It is possible to set 'enable_protobuf_negotiation: true' individually per each 'scrape_config' section in '-promscrape.config' for fine grained control. "+
+		"See also -promscrape.nativeHistogramsFormat")
+	nativeHistogramsFormat = flag.String("promscrape.nativeHistogramsFormat", "vmrange", "The format for storing native histograms scraped in Prometheus protobuf exposition format. "+
+		"Supported values: vmrange - store native histogram buckets as VictoriaMetrics buckets with 'vmrange' label; "+
+		"le - store native histogram buckets as Prometheus buckets with 'le' label. "+
+		"See https://docs.victoriametrics.com/vmagent.html#native-histograms")
+	openMetricsStrictMode = flag.Bool("promscrape.openMetricsStrictMode", false, "Whether to enable strict validation of responses in OpenMetrics text format for all the scrape targets. "+
+		"In this mode responses with 'Content-Type: application/openmetrics-text' must end with '# EOF' line, otherwise the scrape is considered failed, "+
+		"since the response may be truncated. It is possible to set 'openmetrics_strict_mode: true' individually per each 'scrape_config' section in '-promscrape.config' for fine grained control. "+
+		"See https://docs.victoriametrics.com/vmagent.html#openmetrics")
+	dropOpenMetricsCreatedSamples = flag.Bool("promscrape.dropOpenMetricsCreatedSamples", false, "Whether to drop '<name>_created' samples for counters, summaries and histograms "+
+		"from responses in OpenMetrics text format. See https://docs.victoriametrics.com/vmagent.html#openmetrics")
 )
 
 type client struct {
@@ -55,6 +71,9 @@ type client struct {
 	denyRedirects           bool
 	disableCompression      bool
 	disableKeepAlive        bool
+	acceptHeader            string
+	nativeHistogramsFormat  parser.NativeHistogramsFormat
+	openMetricsStrictMode   bool
 }
 
 func newClient(sw *ScrapeWork) *client {
@@ -101,6 +120,19 @@ func newClient(sw *ScrapeWork) *client {
 	if err != nil {
 		logger.Fatalf("cannot create dial func: %s", err)
 	}
+	nhf, err := parser.ParseNativeHistogramsFormat(*nativeHistogramsFormat)
+	if err != nil {
+		logger.Fatalf("cannot parse -promscrape.nativeHistogramsFormat: %s", err)
+	}
+	// The following `Accept` header has been copied from Prometheus sources.
+	// See https://github.com/prometheus/prometheus/blob/f9d21f10ecd2a343a381044f131ea4e46381ce09/scrape/scrape.go#L532 .
+	// This is needed as a workaround for scraping stupid Java-based servers such as Spring Boot.
+	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/608 for details.
+	// Do not bloat the `Accept` header with OpenMetrics shit, since it looks like dead standard now.
+	acceptHeader := "text/plain;version=0.0.4;q=1,*/*;q=0.1"
+	if *enableProtobufNegotiation || sw.EnableProtobufNegotiation {
+		acceptHeader = parser.ProtobufAcceptHeader
+	}
 	hc := &fasthttp.HostClient{
 		Addr:                         host,
 		Name:                         "vm_promscrape",
@@ -161,6 +193,9 @@ func newClient(sw *ScrapeWork) *client {
 		denyRedirects:           sw.DenyRedirects,
 		disableCompression:      sw.DisableCompression,
 		disableKeepAlive:        sw.DisableKeepAlive,
+		acceptHeader:            acceptHeader,
+		nativeHistogramsFormat:  nhf,
+		openMetricsStrictMode:   *openMetricsStrictMode || sw.OpenMetricsStrictMode,
 	}
 }
 
@@ -172,12 +207,7 @@ func (c *client) GetStreamReader() (*streamReader, error) {
 		cancel()
 		return nil, fmt.Errorf("cannot create request for %q: %w", c.scrapeURL, err)
 	}
-	// The following `Accept` header has been copied from Prometheus sources.
-	// See https://github.com/prometheus/prometheus/blob/f9d21f10ecd2a343a381044f131ea4e46381ce09/scrape/scrape.go#L532 .
-	// This is needed as a workaround for scraping stupid Java-based servers such as Spring Boot.
-	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/608 for details.
-	// Do not bloat the `Accept` header with OpenMetrics shit, since it looks like dead standard now.
-	req.Header.Set("Accept", "text/plain;version=0.0.4;q=1,*/*;q=0.1")
+	req.Header.Set("Accept", c.acceptHeader)
 	// Set X-Prometheus-Scrape-Timeout-Seconds like Prometheus does, since it is used by some exporters such as PushProx.
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1179#issuecomment-813117162
 	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", c.scrapeTimeoutSecondsStr)
@@ -197,11 +227,46 @@ func (c *client) GetStreamReader() (*streamReader, error) {
 			c.scrapeURL, resp.StatusCode, http.StatusOK, respBody)
 	}
 	scrapesOK.Inc()
-	return &streamReader{
+	sr := &streamReader{
 		r:           resp.Body,
 		cancel:      cancel,
 		scrapeURL:   c.scrapeURL,
 		maxBodySize: int64(c.hc.MaxResponseBodySize),
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if parser.IsOpenMetricsContentType(contentType) && c.mustFilterOpenMetrics() {
+		// Validate OpenMetrics response while it is read. The validation error is returned at the end of the response.
+		sr.r = struct {
+			io.Reader
+			io.Closer
+		}{
+			Reader: parser.NewOpenMetricsReader(resp.Body, c.openMetricsStrictMode, *dropOpenMetricsCreatedSamples),
+			Closer: resp.Body,
+		}
+		scrapesOpenMetrics.Inc()
+		return sr, nil
+	}
+	if !parser.IsProtobufContentType(contentType) {
+		return sr, nil
+	}
+	// Prometheus protobuf exposition format cannot be parsed in a streaming manner,
+	// so read the whole response and convert it to Prometheus text exposition format.
+	data, err := ioutil.ReadAll(sr)
+	sr.MustClose()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response from %q: %w", c.scrapeURL, err)
+	}
+	text, err := parser.AppendProtobufAsText(nil, data, c.nativeHistogramsFormat)
+	if err != nil {
+		scrapesProtobufFailed.Inc()
+		return nil, fmt.Errorf("cannot parse Prometheus protobuf response from %q: %w", c.scrapeURL, err)
+	}
+	scrapesProtobuf.Inc()
+	return &streamReader{
+		r:           ioutil.NopCloser(bytes.NewReader(text)),
+		cancel:      func() {},
+		scrapeURL:   c.scrapeURL,
+		maxBodySize: int64(len(text)),
 	}, nil
 }
 
@@ -216,15 +281,11 @@ func isStatusRedirect(statusCode int) bool {
 
 func (c *client) ReadData(dst []byte) ([]byte, error) {
 	deadline := time.Now().Add(c.hc.ReadTimeout)
+	dstLen := len(dst)
 	req := fasthttp.AcquireRequest()
 	req.SetRequestURI(c.requestURI)
 	req.Header.SetHost(c.host)
-	// The following `Accept` header has been copied from Prometheus sources.
-	// See https://github.com/prometheus/prometheus/blob/f9d21f10ecd2a343a381044f131ea4e46381ce09/scrape/scrape.go#L532 .
-	// This is needed as a workaround for scraping stupid Java-based servers such as Spring Boot.
-	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/608 for details.
-	// Do not bloat the `Accept` header with OpenMetrics shit, since it looks like dead standard now.
-	req.Header.Set("Accept", "text/plain;version=0.0.4;q=1,*/*;q=0.1")
+	req.Header.Set("Accept", c.acceptHeader)
 	// Set X-Prometheus-Scrape-Timeout-Seconds like Prometheus does, since it is used by some exporters such as PushProx.
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1179#issuecomment-813117162
 	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", c.scrapeTimeoutSecondsStr)
@@ -303,6 +364,7 @@ func (c *client) ReadData(dst []byte) ([]byte, error) {
 	} else if !swapResponseBodies {
 		dst = append(dst, resp.Body()...)
 	}
+	contentType := string(resp.Header.ContentType())
 	fasthttp.ReleaseResponse(resp)
 	if statusCode != fasthttp.StatusOK {
 		metrics.GetOrCreateCounter(fmt.Sprintf(`vm_promscrape_scrapes_total{status_code="%d"}`, statusCode)).Inc()
@@ -310,18 +372,56 @@ func (c *client) ReadData(dst []byte) ([]byte, error) {
 			c.scrapeURL, statusCode, fasthttp.StatusOK, dst)
 	}
 	scrapesOK.Inc()
+	if parser.IsProtobufContentType(contentType) {
+		// Convert Prometheus protobuf exposition format to text exposition format,
+		// since the rest of the scrape code works with the text format.
+		bb := protobufBufPool.Get()
+		var err error
+		bb.B, err = parser.AppendProtobufAsText(bb.B[:0], dst[dstLen:], c.nativeHistogramsFormat)
+		dst = append(dst[:dstLen], bb.B...)
+		protobufBufPool.Put(bb)
+		if err != nil {
+			scrapesProtobufFailed.Inc()
+			return dst, fmt.Errorf("cannot parse Prometheus protobuf response from %q: %w", c.scrapeURL, err)
+		}
+		scrapesProtobuf.Inc()
+	} else if parser.IsOpenMetricsContentType(contentType) && c.mustFilterOpenMetrics() {
+		bb := protobufBufPool.Get()
+		var err error
+		bb.B, err = parser.AppendOpenMetricsText(bb.B[:0], dst[dstLen:], c.openMetricsStrictMode, *dropOpenMetricsCreatedSamples)
+		dst = append(dst[:dstLen], bb.B...)
+		protobufBufPool.Put(bb)
+		if err != nil {
+			scrapesOpenMetricsFailed.Inc()
+			return dst, fmt.Errorf("invalid OpenMetrics response from %q: %w", c.scrapeURL, err)
+		}
+		scrapesOpenMetrics.Inc()
+	}
 	return dst, nil
 }
 
-var gunzipBufPool bytesutil.ByteBufferPool
+func (c *client) mustFilterOpenMetrics() bool {
+	return c.openMetricsStrictMode || *dropOpenMetricsCreatedSamples
+}
+
+var (
+	gunzipBufPool bytesutil.ByteBufferPool
+
+	// protobufBufPool is used for converting Prometheus protobuf and OpenMetrics responses.
+	protobufBufPool bytesutil.ByteBufferPool
+)
 
 var (
-	maxScrapeSizeExceeded = metrics.NewCounter(`vm_promscrape_max_scrape_size_exceeded_errors_total`)
-	scrapesTimedout       = metrics.NewCounter(`vm_promscrape_scrapes_timed_out_total`)
-	scrapesOK             = metrics.NewCounter(`vm_promscrape_scrapes_total{status_code="200"}`)
-	scrapesGunzipped      = metrics.NewCounter(`vm_promscrape_scrapes_gunziped_total`)
-	scrapesGunzipFailed   = metrics.NewCounter(`vm_promscrape_scrapes_gunzip_failed_total`)
-	scrapeRetries         = metrics.NewCounter(`vm_promscrape_scrape_retries_total`)
+	maxScrapeSizeExceeded    = metrics.NewCounter(`vm_promscrape_max_scrape_size_exceeded_errors_total`)
+	scrapesTimedout          = metrics.NewCounter(`vm_promscrape_scrapes_timed_out_total`)
+	scrapesOK                = metrics.NewCounter(`vm_promscrape_scrapes_total{status_code="200"}`)
+	scrapesGunzipped         = metrics.NewCounter(`vm_promscrape_scrapes_gunziped_total`)
+	scrapesGunzipFailed      = metrics.NewCounter(`vm_promscrape_scrapes_gunzip_failed_total`)
+	scrapesProtobuf          = metrics.NewCounter(`vm_promscrape_scrapes_protobuf_total`)
+	scrapesProtobufFailed    = metrics.NewCounter(`vm_promscrape_scrapes_protobuf_failed_total`)
+	scrapesOpenMetrics       = metrics.NewCounter(`vm_promscrape_scrapes_openmetrics_total`)
+	scrapesOpenMetricsFailed = metrics.NewCounter(`vm_promscrape_scrapes_openmetrics_failed_total`)
+	scrapeRetries            = metrics.NewCounter(`vm_promscrape_scrape_retries_total`)
 )
 
 func doRequestWithPossibleRetry(hc *fasthttp.HostClient, req *fasthttp.Request, resp *fasthttp.Response, deadline time.Time) error {
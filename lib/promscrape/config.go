@@ -21,6 +21,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/azure"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/consul"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/digitalocean"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/dns"
@@ -31,7 +32,10 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/gce"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/http"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/kubernetes"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/kuma"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/nomad"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/openstack"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/serverset"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
 	"github.com/VictoriaMetrics/metrics"
@@ -228,6 +232,7 @@ type ScrapeConfig struct {
 	MetricRelabelConfigs []promrelabel.RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
 	SampleLimit          int                         `yaml:"sample_limit,omitempty"`
 
+	AzureSDConfigs        []azure.SDConfig        `yaml:"azure_sd_configs,omitempty"`
 	ConsulSDConfigs       []consul.SDConfig       `yaml:"consul_sd_configs,omitempty"`
 	DigitaloceanSDConfigs []digitalocean.SDConfig `yaml:"digitalocean_sd_configs,omitempty"`
 	DNSSDConfigs          []dns.SDConfig          `yaml:"dns_sd_configs,omitempty"`
@@ -239,19 +244,24 @@ type ScrapeConfig struct {
 	GCESDConfigs          []gce.SDConfig          `yaml:"gce_sd_configs,omitempty"`
 	HTTPSDConfigs         []http.SDConfig         `yaml:"http_sd_configs,omitempty"`
 	KubernetesSDConfigs   []kubernetes.SDConfig   `yaml:"kubernetes_sd_configs,omitempty"`
+	KumaSDConfigs         []kuma.SDConfig         `yaml:"kuma_sd_configs,omitempty"`
+	NomadSDConfigs        []nomad.SDConfig        `yaml:"nomad_sd_configs,omitempty"`
 	OpenStackSDConfigs    []openstack.SDConfig    `yaml:"openstack_sd_configs,omitempty"`
+	ServersetSDConfigs    []serverset.SDConfig    `yaml:"serverset_sd_configs,omitempty"`
 	StaticConfigs         []StaticConfig          `yaml:"static_configs,omitempty"`
 
 	// These options are supported only by lib/promscrape.
-	RelabelDebug        bool                       `yaml:"relabel_debug,omitempty"`
-	MetricRelabelDebug  bool                       `yaml:"metric_relabel_debug,omitempty"`
-	DisableCompression  bool                       `yaml:"disable_compression,omitempty"`
-	DisableKeepAlive    bool                       `yaml:"disable_keepalive,omitempty"`
-	StreamParse         bool                       `yaml:"stream_parse,omitempty"`
-	ScrapeAlignInterval *promutils.Duration        `yaml:"scrape_align_interval,omitempty"`
-	ScrapeOffset        *promutils.Duration        `yaml:"scrape_offset,omitempty"`
-	SeriesLimit         int                        `yaml:"series_limit,omitempty"`
-	ProxyClientConfig   promauth.ProxyClientConfig `yaml:",inline"`
+	RelabelDebug              bool                       `yaml:"relabel_debug,omitempty"`
+	MetricRelabelDebug        bool                       `yaml:"metric_relabel_debug,omitempty"`
+	DisableCompression        bool                       `yaml:"disable_compression,omitempty"`
+	DisableKeepAlive          bool                       `yaml:"disable_keepalive,omitempty"`
+	EnableProtobufNegotiation bool                       `yaml:"enable_protobuf_negotiation,omitempty"`
+	OpenMetricsStrictMode     bool                       `yaml:"openmetrics_strict_mode,omitempty"`
+	StreamParse               bool                       `yaml:"stream_parse,omitempty"`
+	ScrapeAlignInterval       *promutils.Duration        `yaml:"scrape_align_interval,omitempty"`
+	ScrapeOffset              *promutils.Duration        `yaml:"scrape_offset,omitempty"`
+	SeriesLimit               int                        `yaml:"series_limit,omitempty"`
+	ProxyClientConfig         promauth.ProxyClientConfig `yaml:",inline"`
 
 	// This is set in loadConfig
 	swc *scrapeWorkConfig
@@ -273,6 +283,9 @@ func (sc *ScrapeConfig) mustStart(baseDir string) {
 }
 
 func (sc *ScrapeConfig) mustStop() {
+	for i := range sc.AzureSDConfigs {
+		sc.AzureSDConfigs[i].MustStop()
+	}
 	for i := range sc.ConsulSDConfigs {
 		sc.ConsulSDConfigs[i].MustStop()
 	}
@@ -303,9 +316,18 @@ func (sc *ScrapeConfig) mustStop() {
 	for i := range sc.KubernetesSDConfigs {
 		sc.KubernetesSDConfigs[i].MustStop()
 	}
+	for i := range sc.KumaSDConfigs {
+		sc.KumaSDConfigs[i].MustStop()
+	}
+	for i := range sc.NomadSDConfigs {
+		sc.NomadSDConfigs[i].MustStop()
+	}
 	for i := range sc.OpenStackSDConfigs {
 		sc.OpenStackSDConfigs[i].MustStop()
 	}
+	for i := range sc.ServersetSDConfigs {
+		sc.ServersetSDConfigs[i].MustStop()
+	}
 }
 
 // FileSDConfig represents file-based service discovery config.
@@ -450,6 +472,33 @@ func getSWSByJob(sws []*ScrapeWork) map[string][]*ScrapeWork {
 	return m
 }
 
+// getAzureSDScrapeWork returns `azure_sd_configs` ScrapeWork from cfg.
+func (cfg *Config) getAzureSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
+	swsPrevByJob := getSWSByJob(prev)
+	dst := make([]*ScrapeWork, 0, len(prev))
+	for _, sc := range cfg.ScrapeConfigs {
+		dstLen := len(dst)
+		ok := true
+		for j := range sc.AzureSDConfigs {
+			sdc := &sc.AzureSDConfigs[j]
+			var okLocal bool
+			dst, okLocal = appendSDScrapeWork(dst, sdc, cfg.baseDir, sc.swc, "azure_sd_config")
+			if ok {
+				ok = okLocal
+			}
+		}
+		if ok {
+			continue
+		}
+		swsPrev := swsPrevByJob[sc.swc.jobName]
+		if len(swsPrev) > 0 {
+			logger.Errorf("there were errors when discovering azure targets for job %q, so preserving the previous targets", sc.swc.jobName)
+			dst = append(dst[:dstLen], swsPrev...)
+		}
+	}
+	return dst
+}
+
 // getConsulSDScrapeWork returns `consul_sd_configs` ScrapeWork from cfg.
 func (cfg *Config) getConsulSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	swsPrevByJob := getSWSByJob(prev)
@@ -747,6 +796,60 @@ func (cfg *Config) getKubernetesSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	return dst
 }
 
+// getKumaSDScrapeWork returns `kuma_sd_configs` ScrapeWork from cfg.
+func (cfg *Config) getKumaSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
+	swsPrevByJob := getSWSByJob(prev)
+	dst := make([]*ScrapeWork, 0, len(prev))
+	for _, sc := range cfg.ScrapeConfigs {
+		dstLen := len(dst)
+		ok := true
+		for j := range sc.KumaSDConfigs {
+			sdc := &sc.KumaSDConfigs[j]
+			var okLocal bool
+			dst, okLocal = appendSDScrapeWork(dst, sdc, cfg.baseDir, sc.swc, "kuma_sd_config")
+			if ok {
+				ok = okLocal
+			}
+		}
+		if ok {
+			continue
+		}
+		swsPrev := swsPrevByJob[sc.swc.jobName]
+		if len(swsPrev) > 0 {
+			logger.Errorf("there were errors when discovering kuma targets for job %q, so preserving the previous targets", sc.swc.jobName)
+			dst = append(dst[:dstLen], swsPrev...)
+		}
+	}
+	return dst
+}
+
+// getNomadSDScrapeWork returns `nomad_sd_configs` ScrapeWork from cfg.
+func (cfg *Config) getNomadSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
+	swsPrevByJob := getSWSByJob(prev)
+	dst := make([]*ScrapeWork, 0, len(prev))
+	for _, sc := range cfg.ScrapeConfigs {
+		dstLen := len(dst)
+		ok := true
+		for j := range sc.NomadSDConfigs {
+			sdc := &sc.NomadSDConfigs[j]
+			var okLocal bool
+			dst, okLocal = appendSDScrapeWork(dst, sdc, cfg.baseDir, sc.swc, "nomad_sd_config")
+			if ok {
+				ok = okLocal
+			}
+		}
+		if ok {
+			continue
+		}
+		swsPrev := swsPrevByJob[sc.swc.jobName]
+		if len(swsPrev) > 0 {
+			logger.Errorf("there were errors when discovering nomad targets for job %q, so preserving the previous targets", sc.swc.jobName)
+			dst = append(dst[:dstLen], swsPrev...)
+		}
+	}
+	return dst
+}
+
 // getOpenStackSDScrapeWork returns `openstack_sd_configs` ScrapeWork from cfg.
 func (cfg *Config) getOpenStackSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	swsPrevByJob := getSWSByJob(prev)
@@ -774,6 +877,33 @@ func (cfg *Config) getOpenStackSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	return dst
 }
 
+// getServersetSDScrapeWork returns `serverset_sd_configs` ScrapeWork from cfg.
+func (cfg *Config) getServersetSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
+	swsPrevByJob := getSWSByJob(prev)
+	dst := make([]*ScrapeWork, 0, len(prev))
+	for _, sc := range cfg.ScrapeConfigs {
+		dstLen := len(dst)
+		ok := true
+		for j := range sc.ServersetSDConfigs {
+			sdc := &sc.ServersetSDConfigs[j]
+			var okLocal bool
+			dst, okLocal = appendSDScrapeWork(dst, sdc, cfg.baseDir, sc.swc, "serverset_sd_config")
+			if ok {
+				ok = okLocal
+			}
+		}
+		if ok {
+			continue
+		}
+		swsPrev := swsPrevByJob[sc.swc.jobName]
+		if len(swsPrev) > 0 {
+			logger.Errorf("there were errors when discovering serverset targets for job %q, so preserving the previous targets", sc.swc.jobName)
+			dst = append(dst[:dstLen], swsPrev...)
+		}
+	}
+	return dst
+}
+
 // getStaticScrapeWork returns `static_configs` ScrapeWork from from cfg.
 func (cfg *Config) getStaticScrapeWork() []*ScrapeWork {
 	var dst []*ScrapeWork
@@ -855,59 +985,63 @@ func getScrapeWorkConfig(sc *ScrapeConfig, baseDir string, globalCfg *GlobalConf
 		return nil, fmt.Errorf("cannot use stream parsing mode when `series_limit` is set for `job_name` %q", jobName)
 	}
 	swc := &scrapeWorkConfig{
-		scrapeInterval:       scrapeInterval,
-		scrapeIntervalString: scrapeInterval.String(),
-		scrapeTimeout:        scrapeTimeout,
-		scrapeTimeoutString:  scrapeTimeout.String(),
-		jobName:              jobName,
-		metricsPath:          metricsPath,
-		scheme:               scheme,
-		params:               params,
-		proxyURL:             sc.ProxyURL,
-		proxyAuthConfig:      proxyAC,
-		authConfig:           ac,
-		honorLabels:          honorLabels,
-		honorTimestamps:      honorTimestamps,
-		denyRedirects:        denyRedirects,
-		externalLabels:       globalCfg.ExternalLabels,
-		relabelConfigs:       relabelConfigs,
-		metricRelabelConfigs: metricRelabelConfigs,
-		sampleLimit:          sc.SampleLimit,
-		disableCompression:   sc.DisableCompression,
-		disableKeepAlive:     sc.DisableKeepAlive,
-		streamParse:          sc.StreamParse,
-		scrapeAlignInterval:  sc.ScrapeAlignInterval.Duration(),
-		scrapeOffset:         sc.ScrapeOffset.Duration(),
-		seriesLimit:          sc.SeriesLimit,
+		scrapeInterval:            scrapeInterval,
+		scrapeIntervalString:      scrapeInterval.String(),
+		scrapeTimeout:             scrapeTimeout,
+		scrapeTimeoutString:       scrapeTimeout.String(),
+		jobName:                   jobName,
+		metricsPath:               metricsPath,
+		scheme:                    scheme,
+		params:                    params,
+		proxyURL:                  sc.ProxyURL,
+		proxyAuthConfig:           proxyAC,
+		authConfig:                ac,
+		honorLabels:               honorLabels,
+		honorTimestamps:           honorTimestamps,
+		denyRedirects:             denyRedirects,
+		externalLabels:            globalCfg.ExternalLabels,
+		relabelConfigs:            relabelConfigs,
+		metricRelabelConfigs:      metricRelabelConfigs,
+		sampleLimit:               sc.SampleLimit,
+		disableCompression:        sc.DisableCompression,
+		disableKeepAlive:          sc.DisableKeepAlive,
+		enableProtobufNegotiation: sc.EnableProtobufNegotiation,
+		openMetricsStrictMode:     sc.OpenMetricsStrictMode,
+		streamParse:               sc.StreamParse,
+		scrapeAlignInterval:       sc.ScrapeAlignInterval.Duration(),
+		scrapeOffset:              sc.ScrapeOffset.Duration(),
+		seriesLimit:               sc.SeriesLimit,
 	}
 	return swc, nil
 }
 
 type scrapeWorkConfig struct {
-	scrapeInterval       time.Duration
-	scrapeIntervalString string
-	scrapeTimeout        time.Duration
-	scrapeTimeoutString  string
-	jobName              string
-	metricsPath          string
-	scheme               string
-	params               map[string][]string
-	proxyURL             *proxy.URL
-	proxyAuthConfig      *promauth.Config
-	authConfig           *promauth.Config
-	honorLabels          bool
-	honorTimestamps      bool
-	denyRedirects        bool
-	externalLabels       map[string]string
-	relabelConfigs       *promrelabel.ParsedConfigs
-	metricRelabelConfigs *promrelabel.ParsedConfigs
-	sampleLimit          int
-	disableCompression   bool
-	disableKeepAlive     bool
-	streamParse          bool
-	scrapeAlignInterval  time.Duration
-	scrapeOffset         time.Duration
-	seriesLimit          int
+	scrapeInterval            time.Duration
+	scrapeIntervalString      string
+	scrapeTimeout             time.Duration
+	scrapeTimeoutString       string
+	jobName                   string
+	metricsPath               string
+	scheme                    string
+	params                    map[string][]string
+	proxyURL                  *proxy.URL
+	proxyAuthConfig           *promauth.Config
+	authConfig                *promauth.Config
+	honorLabels               bool
+	honorTimestamps           bool
+	denyRedirects             bool
+	externalLabels            map[string]string
+	relabelConfigs            *promrelabel.ParsedConfigs
+	metricRelabelConfigs      *promrelabel.ParsedConfigs
+	sampleLimit               int
+	disableCompression        bool
+	disableKeepAlive          bool
+	enableProtobufNegotiation bool
+	openMetricsStrictMode     bool
+	streamParse               bool
+	scrapeAlignInterval       time.Duration
+	scrapeOffset              time.Duration
+	seriesLimit               int
 }
 
 type targetLabelsGetter interface {
@@ -1210,25 +1344,27 @@ func (swc *scrapeWorkConfig) getScrapeWork(target string, extraLabels, metaLabel
 	// Reduce memory usage by interning all the strings in labels.
 	internLabelStrings(labels)
 	sw := &ScrapeWork{
-		ScrapeURL:            scrapeURL,
-		ScrapeInterval:       scrapeInterval,
-		ScrapeTimeout:        scrapeTimeout,
-		HonorLabels:          swc.honorLabels,
-		HonorTimestamps:      swc.honorTimestamps,
-		DenyRedirects:        swc.denyRedirects,
-		OriginalLabels:       originalLabels,
-		Labels:               labels,
-		ProxyURL:             swc.proxyURL,
-		ProxyAuthConfig:      swc.proxyAuthConfig,
-		AuthConfig:           swc.authConfig,
-		MetricRelabelConfigs: swc.metricRelabelConfigs,
-		SampleLimit:          swc.sampleLimit,
-		DisableCompression:   swc.disableCompression,
-		DisableKeepAlive:     swc.disableKeepAlive,
-		StreamParse:          streamParse,
-		ScrapeAlignInterval:  swc.scrapeAlignInterval,
-		ScrapeOffset:         swc.scrapeOffset,
-		SeriesLimit:          seriesLimit,
+		ScrapeURL:                 scrapeURL,
+		ScrapeInterval:            scrapeInterval,
+		ScrapeTimeout:             scrapeTimeout,
+		HonorLabels:               swc.honorLabels,
+		HonorTimestamps:           swc.honorTimestamps,
+		DenyRedirects:             swc.denyRedirects,
+		OriginalLabels:            originalLabels,
+		Labels:                    labels,
+		ProxyURL:                  swc.proxyURL,
+		ProxyAuthConfig:           swc.proxyAuthConfig,
+		AuthConfig:                swc.authConfig,
+		MetricRelabelConfigs:      swc.metricRelabelConfigs,
+		SampleLimit:               swc.sampleLimit,
+		DisableCompression:        swc.disableCompression,
+		DisableKeepAlive:          swc.disableKeepAlive,
+		EnableProtobufNegotiation: swc.enableProtobufNegotiation,
+		OpenMetricsStrictMode:     swc.openMetricsStrictMode,
+		StreamParse:               streamParse,
+		ScrapeAlignInterval:       swc.scrapeAlignInterval,
+		ScrapeOffset:              swc.scrapeOffset,
+		SeriesLimit:               seriesLimit,
 
 		jobNameOriginal: swc.jobName,
 	}
@@ -0,0 +1,273 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/fasthttp"
+)
+
+var configMap = discoveryutils.NewConfigMap()
+
+// managedIdentityEndpoint is the Azure Instance Metadata Service endpoint used for obtaining managed identity tokens.
+//
+// See https://docs.microsoft.com/en-us/azure/active-directory/managed-identities-azure-resources/how-to-use-vm-token
+var managedIdentityEndpoint = "http://169.254.169.254"
+
+type apiConfig struct {
+	c *discoveryutils.Client
+
+	port           int
+	subscriptionID string
+	resourceGroup  string
+
+	refreshToken refreshTokenFunc
+	// tokenLock guards token and tokenExpireDeadline
+	tokenLock           sync.Mutex
+	token               string
+	tokenExpireDeadline time.Time
+}
+
+// refreshTokenFunc returns new access token for Azure Resource Manager API and the duration until the token expiration.
+type refreshTokenFunc func() (string, time.Duration, error)
+
+func getAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	v, err := configMap.Get(sdc, func() (interface{}, error) { return newAPIConfig(sdc, baseDir) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*apiConfig), nil
+}
+
+func newAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	if sdc.SubscriptionID == "" {
+		return nil, fmt.Errorf("missing `subscription_id` config option")
+	}
+	port := sdc.Port
+	if port == 0 {
+		port = 80
+	}
+	ac, err := sdc.HTTPClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse auth config: %w", err)
+	}
+	proxyAC, err := sdc.ProxyClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse proxy auth config: %w", err)
+	}
+	env, err := getCloudEnvByName(sdc.Environment)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read configs for `environment: %q`: %w", sdc.Environment, err)
+	}
+	refreshToken, err := getRefreshTokenFunc(sdc, ac, proxyAC, env)
+	if err != nil {
+		return nil, err
+	}
+	apiServer := strings.TrimSuffix(env.ResourceManagerEndpoint, "/")
+	client, err := discoveryutils.NewClient(apiServer, ac, sdc.ProxyURL, proxyAC)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create HTTP client for %q: %w", apiServer, err)
+	}
+	cfg := &apiConfig{
+		c:              client,
+		port:           port,
+		subscriptionID: sdc.SubscriptionID,
+		resourceGroup:  sdc.ResourceGroup,
+		refreshToken:   refreshToken,
+	}
+	return cfg, nil
+}
+
+// cloudEnvironment holds Azure API endpoints for the given cloud.
+//
+// The json representation matches the format of the file pointed by AZURE_ENVIRONMENT_FILEPATH env var,
+// which is used by Azure SDK for AzureStackCloud environment.
+type cloudEnvironment struct {
+	Name                    string `json:"name"`
+	ActiveDirectoryEndpoint string `json:"activeDirectoryEndpoint"`
+	ResourceManagerEndpoint string `json:"resourceManagerEndpoint"`
+}
+
+var cloudEnvironments = []*cloudEnvironment{
+	{
+		Name:                    "AzurePublicCloud",
+		ActiveDirectoryEndpoint: "https://login.microsoftonline.com/",
+		ResourceManagerEndpoint: "https://management.azure.com/",
+	},
+	{
+		Name:                    "AzureChinaCloud",
+		ActiveDirectoryEndpoint: "https://login.chinacloudapi.cn/",
+		ResourceManagerEndpoint: "https://management.chinacloudapi.cn/",
+	},
+	{
+		Name:                    "AzureGermanCloud",
+		ActiveDirectoryEndpoint: "https://login.microsoftonline.de/",
+		ResourceManagerEndpoint: "https://management.microsoftazure.de/",
+	},
+	{
+		Name:                    "AzureUSGovernmentCloud",
+		ActiveDirectoryEndpoint: "https://login.microsoftonline.us/",
+		ResourceManagerEndpoint: "https://management.usgovcloudapi.net/",
+	},
+}
+
+// getCloudEnvByName returns Azure API endpoints for the given environment name.
+//
+// AzureStackCloud endpoints are read from the file pointed by AZURE_ENVIRONMENT_FILEPATH env var.
+func getCloudEnvByName(name string) (*cloudEnvironment, error) {
+	if name == "" {
+		name = "AzurePublicCloud"
+	}
+	if strings.EqualFold(name, "AzureStackCloud") {
+		return readCloudEnvFromFile(os.Getenv("AZURE_ENVIRONMENT_FILEPATH"))
+	}
+	for _, env := range cloudEnvironments {
+		if strings.EqualFold(name, env.Name) {
+			return env, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported environment %q; supported values: AzurePublicCloud, AzureChinaCloud, AzureGermanCloud, AzureUSGovernmentCloud, AzureStackCloud", name)
+}
+
+func readCloudEnvFromFile(path string) (*cloudEnvironment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("AZURE_ENVIRONMENT_FILEPATH env var must point to a file with AzureStackCloud endpoints")
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read AzureStackCloud endpoints: %w", err)
+	}
+	var env cloudEnvironment
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("cannot parse AzureStackCloud endpoints from %q: %w", path, err)
+	}
+	if env.ActiveDirectoryEndpoint == "" || env.ResourceManagerEndpoint == "" {
+		return nil, fmt.Errorf("missing activeDirectoryEndpoint or resourceManagerEndpoint in %q", path)
+	}
+	return &env, nil
+}
+
+func getRefreshTokenFunc(sdc *SDConfig, ac, proxyAC *promauth.Config, env *cloudEnvironment) (refreshTokenFunc, error) {
+	proxyURL := sdc.ProxyURL
+	var apiServer, tokenPath string
+	var modifyRequest func(req *fasthttp.Request)
+	switch strings.ToLower(sdc.AuthenticationMethod) {
+	case "", "oauth":
+		if sdc.TenantID == "" {
+			return nil, fmt.Errorf("missing `tenant_id` config option for `authentication_method: OAuth`")
+		}
+		if sdc.ClientID == "" {
+			return nil, fmt.Errorf("missing `client_id` config option for `authentication_method: OAuth`")
+		}
+		if sdc.ClientSecret == nil || sdc.ClientSecret.String() == "" {
+			return nil, fmt.Errorf("missing `client_secret` config option for `authentication_method: OAuth`")
+		}
+		// See https://docs.microsoft.com/en-us/azure/active-directory/azuread-dev/v1-oauth2-client-creds-grant-flow
+		q := url.Values{
+			"grant_type":    []string{"client_credentials"},
+			"client_id":     []string{sdc.ClientID},
+			"client_secret": []string{sdc.ClientSecret.String()},
+			"resource":      []string{env.ResourceManagerEndpoint},
+		}
+		body := q.Encode()
+		apiServer = strings.TrimSuffix(env.ActiveDirectoryEndpoint, "/")
+		tokenPath = "/" + url.PathEscape(sdc.TenantID) + "/oauth2/token"
+		modifyRequest = func(req *fasthttp.Request) {
+			req.Header.SetMethod("POST")
+			req.Header.SetContentType("application/x-www-form-urlencoded")
+			req.SetBodyString(body)
+		}
+	case "managedidentity":
+		// See https://docs.microsoft.com/en-us/azure/active-directory/managed-identities-azure-resources/how-to-use-vm-token#get-a-token-using-http
+		q := url.Values{
+			"api-version": []string{"2018-02-01"},
+			"resource":    []string{env.ResourceManagerEndpoint},
+		}
+		if sdc.ClientID != "" {
+			q.Set("client_id", sdc.ClientID)
+		}
+		apiServer = managedIdentityEndpoint
+		tokenPath = "/metadata/identity/oauth2/token?" + q.Encode()
+		modifyRequest = func(req *fasthttp.Request) {
+			req.Header.Set("Metadata", "true")
+		}
+		// Requests to Instance Metadata Service mustn't go via proxy.
+		ac = nil
+		proxyURL = nil
+		proxyAC = nil
+	default:
+		return nil, fmt.Errorf("unsupported `authentication_method: %q`; supported values: OAuth, ManagedIdentity", sdc.AuthenticationMethod)
+	}
+	client, err := discoveryutils.NewClient(apiServer, ac, proxyURL, proxyAC)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create HTTP client for %q: %w", apiServer, err)
+	}
+	return func() (string, time.Duration, error) {
+		data, err := client.GetAPIResponseWithReqParams(tokenPath, modifyRequest)
+		if err != nil {
+			return "", 0, err
+		}
+		return parseTokenResponse(data)
+	}, nil
+}
+
+// tokenResponse represents response from Azure token endpoints.
+//
+// expires_in may be either a number or a string with a number, so it is parsed into json.Number.
+type tokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+func parseTokenResponse(data []byte) (string, time.Duration, error) {
+	var tr tokenResponse
+	if err := json.Unmarshal(data, &tr); err != nil {
+		return "", 0, fmt.Errorf("cannot parse token response %q: %w", data, err)
+	}
+	if tr.AccessToken == "" {
+		return "", 0, fmt.Errorf("missing access_token in token response %q", data)
+	}
+	expiresIn, err := tr.ExpiresIn.Int64()
+	if err != nil {
+		return "", 0, fmt.Errorf("cannot parse expires_in in token response %q: %w", data, err)
+	}
+	return tr.AccessToken, time.Duration(expiresIn) * time.Second, nil
+}
+
+func (cfg *apiConfig) getFreshToken() (string, error) {
+	cfg.tokenLock.Lock()
+	defer cfg.tokenLock.Unlock()
+
+	if time.Until(cfg.tokenExpireDeadline) > 10*time.Second {
+		// The token isn't expired yet.
+		return cfg.token, nil
+	}
+	token, expiresIn, err := cfg.refreshToken()
+	if err != nil {
+		return "", fmt.Errorf("cannot refresh Azure API token: %w", err)
+	}
+	cfg.token = token
+	cfg.tokenExpireDeadline = time.Now().Add(expiresIn)
+	logger.Infof("successfully refreshed Azure API token; expiration: %.3f seconds", expiresIn.Seconds())
+	return token, nil
+}
+
+// getAPIResponse returns response for the given Azure Resource Manager API path.
+func (cfg *apiConfig) getAPIResponse(path string) ([]byte, error) {
+	token, err := cfg.getFreshToken()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.c.GetAPIResponseWithReqParams(path, func(req *fasthttp.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	})
+}
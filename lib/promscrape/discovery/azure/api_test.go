@@ -0,0 +1,146 @@
+package azure
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+)
+
+func TestParseTokenResponse(t *testing.T) {
+	f := func(data, tokenExpected string, expiresInExpected time.Duration) {
+		t.Helper()
+		token, expiresIn, err := parseTokenResponse([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if token != tokenExpected {
+			t.Fatalf("unexpected token; got %q; want %q", token, tokenExpected)
+		}
+		if expiresIn != expiresInExpected {
+			t.Fatalf("unexpected expires_in; got %s; want %s", expiresIn, expiresInExpected)
+		}
+	}
+	f(`{"access_token":"foo","expires_in":"3599","token_type":"Bearer"}`, "foo", 3599*time.Second)
+	f(`{"access_token":"bar","expires_in":60}`, "bar", time.Minute)
+}
+
+func TestParseTokenResponseFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, _, err := parseTokenResponse([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error for %q", data)
+		}
+	}
+	f(`foobar`)
+	f(`{"expires_in":"3599"}`)
+	f(`{"access_token":"foo","expires_in":"abc"}`)
+	f(`{"access_token":"foo"}`)
+}
+
+func TestGetCloudEnvByName(t *testing.T) {
+	f := func(name, resourceManagerEndpointExpected string) {
+		t.Helper()
+		env, err := getCloudEnvByName(name)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if env.ResourceManagerEndpoint != resourceManagerEndpointExpected {
+			t.Fatalf("unexpected resourceManagerEndpoint; got %q; want %q", env.ResourceManagerEndpoint, resourceManagerEndpointExpected)
+		}
+	}
+	f("", "https://management.azure.com/")
+	f("AzurePublicCloud", "https://management.azure.com/")
+	f("azurechinacloud", "https://management.chinacloudapi.cn/")
+	f("AzureUSGovernmentCloud", "https://management.usgovcloudapi.net/")
+
+	// AzureStackCloud endpoints are read from AZURE_ENVIRONMENT_FILEPATH
+	envFile := filepath.Join(t.TempDir(), "env.json")
+	data := `{"name":"AzureStackCloud","activeDirectoryEndpoint":"https://login.local/","resourceManagerEndpoint":"https://management.local/"}`
+	if err := ioutil.WriteFile(envFile, []byte(data), 0644); err != nil {
+		t.Fatalf("cannot write env file: %s", err)
+	}
+	t.Setenv("AZURE_ENVIRONMENT_FILEPATH", envFile)
+	f("AzureStackCloud", "https://management.local/")
+
+	// Unsupported environment
+	if _, err := getCloudEnvByName("foobar"); err == nil {
+		t.Fatalf("expecting non-nil error for unsupported environment")
+	}
+}
+
+func TestGetRefreshTokenFuncFailure(t *testing.T) {
+	f := func(sdc *SDConfig) {
+		t.Helper()
+		if _, err := getRefreshTokenFunc(sdc, nil, nil, cloudEnvironments[0]); err == nil {
+			t.Fatalf("expecting non-nil error for %+v", sdc)
+		}
+	}
+	// Missing OAuth options
+	f(&SDConfig{})
+	f(&SDConfig{
+		TenantID: "tenant",
+	})
+	f(&SDConfig{
+		TenantID: "tenant",
+		ClientID: "client",
+	})
+	f(&SDConfig{
+		AuthenticationMethod: "OAuth",
+		ClientID:             "client",
+		ClientSecret:         promauth.NewSecret("secret"),
+	})
+
+	// Unsupported authentication method
+	f(&SDConfig{
+		AuthenticationMethod: "foobar",
+	})
+}
+
+func TestGetRefreshTokenFuncManagedIdentity(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata/identity/oauth2/token" {
+			http.Error(w, "unexpected path", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Metadata") != "true" {
+			http.Error(w, "missing Metadata header", http.StatusBadRequest)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("resource") != "https://management.azure.com/" || q.Get("client_id") != "client" {
+			http.Error(w, "unexpected query args", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"managed-token","expires_in":"86399"}`))
+	}))
+	defer s.Close()
+
+	defer func(endpoint string) {
+		managedIdentityEndpoint = endpoint
+	}(managedIdentityEndpoint)
+	managedIdentityEndpoint = s.URL
+
+	sdc := &SDConfig{
+		AuthenticationMethod: "ManagedIdentity",
+		ClientID:             "client",
+	}
+	refreshToken, err := getRefreshTokenFunc(sdc, nil, nil, cloudEnvironments[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	token, expiresIn, err := refreshToken()
+	if err != nil {
+		t.Fatalf("cannot obtain token: %s", err)
+	}
+	if token != "managed-token" {
+		t.Fatalf("unexpected token; got %q; want %q", token, "managed-token")
+	}
+	if expiresIn != 86399*time.Second {
+		t.Fatalf("unexpected expires_in; got %s; want %s", expiresIn, 86399*time.Second)
+	}
+}
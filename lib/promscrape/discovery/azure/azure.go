@@ -0,0 +1,92 @@
+package azure
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
+)
+
+// SDCheckInterval defines interval for targets refresh.
+var SDCheckInterval = flag.Duration("promscrape.azureSDCheckInterval", time.Minute, "Interval for checking for changes in Azure. "+
+	"This works only if azure_sd_configs is configured in '-promscrape.config' file. "+
+	"See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#azure_sd_config for details")
+
+// SDConfig represents service discovery config for Azure.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#azure_sd_config
+type SDConfig struct {
+	Environment          string           `yaml:"environment,omitempty"`
+	AuthenticationMethod string           `yaml:"authentication_method,omitempty"`
+	SubscriptionID       string           `yaml:"subscription_id"`
+	TenantID             string           `yaml:"tenant_id,omitempty"`
+	ClientID             string           `yaml:"client_id,omitempty"`
+	ClientSecret         *promauth.Secret `yaml:"client_secret,omitempty"`
+	ResourceGroup        string           `yaml:"resource_group,omitempty"`
+	// RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// refresh_interval is obtained from `-promscrape.azureSDCheckInterval` command-line option.
+	Port int `yaml:"port,omitempty"`
+
+	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
+	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
+}
+
+// GetLabels returns Azure labels according to sdc.
+func (sdc *SDConfig) GetLabels(baseDir string) ([]map[string]string, error) {
+	cfg, err := getAPIConfig(sdc, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get API config: %w", err)
+	}
+	vms, err := getVirtualMachines(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return appendMachineLabels(vms, cfg.port, sdc), nil
+}
+
+// MustStop stops further usage for sdc.
+func (sdc *SDConfig) MustStop() {
+	configMap.Delete(sdc)
+}
+
+func appendMachineLabels(vms []virtualMachine, port int, sdc *SDConfig) []map[string]string {
+	var ms []map[string]string
+	for i := range vms {
+		vm := &vms[i]
+		for _, ips := range vm.ipAddresses {
+			if ips.privateIP == "" {
+				continue
+			}
+			m := map[string]string{
+				"__address__":                         discoveryutils.JoinHostPort(ips.privateIP, port),
+				"__meta_azure_subscription_id":        sdc.SubscriptionID,
+				"__meta_azure_machine_id":             vm.ID,
+				"__meta_azure_machine_name":           vm.Name,
+				"__meta_azure_machine_location":       vm.Location,
+				"__meta_azure_machine_private_ip":     ips.privateIP,
+				"__meta_azure_machine_resource_group": vm.resourceGroup,
+				"__meta_azure_machine_os_type":        vm.Properties.StorageProfile.OsDisk.OsType,
+				"__meta_azure_machine_computer_name":  vm.Properties.OsProfile.ComputerName,
+				"__meta_azure_machine_size":           vm.Properties.HardwareProfile.VMSize,
+			}
+			if sdc.TenantID != "" {
+				m["__meta_azure_tenant_id"] = sdc.TenantID
+			}
+			if ips.publicIP != "" {
+				m["__meta_azure_machine_public_ip"] = ips.publicIP
+			}
+			if vm.scaleSet != "" {
+				m["__meta_azure_machine_scale_set"] = vm.scaleSet
+			}
+			for k, v := range vm.Tags {
+				m["__meta_azure_machine_tag_"+discoveryutils.SanitizeLabelName(k)] = v
+			}
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
@@ -0,0 +1,230 @@
+package azure
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+// newARMStub returns a stub for Azure token endpoint and Azure Resource Manager API.
+func newARMStub() *httptest.Server {
+	var s *httptest.Server
+	responses := map[string]string{
+		"/subscriptions/sub/providers/Microsoft.Compute/virtualMachines": `{
+  "value": [{
+    "id": "/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Compute/virtualMachines/vm-1",
+    "name": "vm-1",
+    "location": "eastus",
+    "tags": {"env": "prod", "team-name": "db"},
+    "properties": {
+      "hardwareProfile": {"vmSize": "Standard_B1s"},
+      "storageProfile": {"osDisk": {"osType": "Linux"}},
+      "osProfile": {"computerName": "host-1"},
+      "networkProfile": {"networkInterfaces": [
+        {"id": "/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Network/networkInterfaces/nic-1"},
+        {"id": "/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Network/networkInterfaces/nic-2"}
+      ]}
+    }
+  }],
+  "nextLink": "{{server}}/subscriptions/sub/providers/Microsoft.Compute/virtualMachines?api-version=2022-03-01&$skiptoken=page2"
+}`,
+		"/subscriptions/sub/providers/Microsoft.Compute/virtualMachines?page2": `{
+  "value": [{
+    "id": "/subscriptions/sub/resourceGroups/rg-2/providers/Microsoft.Compute/virtualMachines/vm-2",
+    "name": "vm-2",
+    "location": "westus",
+    "properties": {
+      "hardwareProfile": {"vmSize": "Standard_D2s_v3"},
+      "storageProfile": {"osDisk": {"osType": "Windows"}},
+      "osProfile": {"computerName": "host-2"},
+      "networkProfile": {"networkInterfaces": [
+        {"id": "/subscriptions/sub/resourceGroups/rg-2/providers/Microsoft.Network/networkInterfaces/nic-3"}
+      ]}
+    }
+  }]
+}`,
+		"/subscriptions/sub/providers/Microsoft.Compute/virtualMachineScaleSets": `{
+  "value": [{
+    "id": "/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Compute/virtualMachineScaleSets/ss-1",
+    "name": "ss-1"
+  }]
+}`,
+		"/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Compute/virtualMachineScaleSets/ss-1/virtualMachines": `{
+  "value": [{
+    "id": "/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Compute/virtualMachineScaleSets/ss-1/virtualMachines/0",
+    "name": "ss-1_0",
+    "location": "eastus",
+    "properties": {
+      "hardwareProfile": {"vmSize": "Standard_B2s"},
+      "storageProfile": {"osDisk": {"osType": "Linux"}},
+      "osProfile": {"computerName": "ss-1000000"},
+      "networkProfile": {"networkInterfaces": [
+        {"id": "/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Compute/virtualMachineScaleSets/ss-1/virtualMachines/0/networkInterfaces/nic-ss"}
+      ]}
+    }
+  }]
+}`,
+		"/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Network/networkInterfaces/nic-1": `{
+  "properties": {
+    "primary": true,
+    "ipConfigurations": [{
+      "properties": {
+        "privateIPAddress": "10.0.0.4",
+        "publicIPAddress": {"properties": {"ipAddress": "20.1.2.3"}}
+      }
+    }]
+  }
+}`,
+		"/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Network/networkInterfaces/nic-2": `{
+  "properties": {
+    "primary": false,
+    "ipConfigurations": [{"properties": {"privateIPAddress": "10.0.1.4"}}]
+  }
+}`,
+		"/subscriptions/sub/resourceGroups/rg-2/providers/Microsoft.Network/networkInterfaces/nic-3": `{
+  "properties": {
+    "primary": true,
+    "ipConfigurations": [{"properties": {"privateIPAddress": "10.1.0.4"}}]
+  }
+}`,
+		"/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Compute/virtualMachineScaleSets/ss-1/virtualMachines/0/networkInterfaces/nic-ss": `{
+  "properties": {
+    "ipConfigurations": [{"properties": {"privateIPAddress": "10.2.0.5"}}]
+  }
+}`,
+	}
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tenant/oauth2/token" {
+			if r.Method != "POST" {
+				http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+				return
+			}
+			if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != "client" || r.FormValue("client_secret") != "secret" {
+				http.Error(w, "invalid credentials", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"access_token":"test-token","expires_in":"3599"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("api-version") == "" {
+			http.Error(w, "missing api-version", http.StatusBadRequest)
+			return
+		}
+		key := r.URL.Path
+		if r.URL.Query().Get("$skiptoken") != "" {
+			key += "?" + r.URL.Query().Get("$skiptoken")
+		}
+		resp, ok := responses[key]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(strings.ReplaceAll(resp, "{{server}}", s.URL)))
+	}))
+	return s
+}
+
+func TestGetLabels(t *testing.T) {
+	s := newARMStub()
+	defer s.Close()
+
+	envFile := filepath.Join(t.TempDir(), "env.json")
+	data := fmt.Sprintf(`{"name":"AzureStackCloud","activeDirectoryEndpoint":"%s/","resourceManagerEndpoint":"%s/"}`, s.URL, s.URL)
+	if err := ioutil.WriteFile(envFile, []byte(data), 0644); err != nil {
+		t.Fatalf("cannot write env file: %s", err)
+	}
+	t.Setenv("AZURE_ENVIRONMENT_FILEPATH", envFile)
+
+	sdc := &SDConfig{
+		Environment:    "AzureStackCloud",
+		SubscriptionID: "sub",
+		TenantID:       "tenant",
+		ClientID:       "client",
+		ClientSecret:   promauth.NewSecret("secret"),
+		Port:           9100,
+	}
+	defer sdc.MustStop()
+	ms, err := sdc.GetLabels("")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var sortedLabelss [][]prompbmarshal.Label
+	for _, m := range ms {
+		sortedLabelss = append(sortedLabelss, discoveryutils.GetSortedLabels(m))
+	}
+	expectedLabels := [][]prompbmarshal.Label{
+		discoveryutils.GetSortedLabels(map[string]string{
+			"__address__":                         "10.0.0.4:9100",
+			"__meta_azure_subscription_id":        "sub",
+			"__meta_azure_tenant_id":              "tenant",
+			"__meta_azure_machine_id":             "/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Compute/virtualMachines/vm-1",
+			"__meta_azure_machine_name":           "vm-1",
+			"__meta_azure_machine_computer_name":  "host-1",
+			"__meta_azure_machine_location":       "eastus",
+			"__meta_azure_machine_os_type":        "Linux",
+			"__meta_azure_machine_private_ip":     "10.0.0.4",
+			"__meta_azure_machine_public_ip":      "20.1.2.3",
+			"__meta_azure_machine_resource_group": "rg-1",
+			"__meta_azure_machine_size":           "Standard_B1s",
+			"__meta_azure_machine_tag_env":        "prod",
+			"__meta_azure_machine_tag_team_name":  "db",
+		}),
+		discoveryutils.GetSortedLabels(map[string]string{
+			"__address__":                         "10.1.0.4:9100",
+			"__meta_azure_subscription_id":        "sub",
+			"__meta_azure_tenant_id":              "tenant",
+			"__meta_azure_machine_id":             "/subscriptions/sub/resourceGroups/rg-2/providers/Microsoft.Compute/virtualMachines/vm-2",
+			"__meta_azure_machine_name":           "vm-2",
+			"__meta_azure_machine_computer_name":  "host-2",
+			"__meta_azure_machine_location":       "westus",
+			"__meta_azure_machine_os_type":        "Windows",
+			"__meta_azure_machine_private_ip":     "10.1.0.4",
+			"__meta_azure_machine_resource_group": "rg-2",
+			"__meta_azure_machine_size":           "Standard_D2s_v3",
+		}),
+		discoveryutils.GetSortedLabels(map[string]string{
+			"__address__":                         "10.2.0.5:9100",
+			"__meta_azure_subscription_id":        "sub",
+			"__meta_azure_tenant_id":              "tenant",
+			"__meta_azure_machine_id":             "/subscriptions/sub/resourceGroups/rg-1/providers/Microsoft.Compute/virtualMachineScaleSets/ss-1/virtualMachines/0",
+			"__meta_azure_machine_name":           "ss-1_0",
+			"__meta_azure_machine_computer_name":  "ss-1000000",
+			"__meta_azure_machine_location":       "eastus",
+			"__meta_azure_machine_os_type":        "Linux",
+			"__meta_azure_machine_private_ip":     "10.2.0.5",
+			"__meta_azure_machine_resource_group": "rg-1",
+			"__meta_azure_machine_scale_set":      "ss-1",
+			"__meta_azure_machine_size":           "Standard_B2s",
+		}),
+	}
+	if !reflect.DeepEqual(sortedLabelss, expectedLabels) {
+		t.Fatalf("unexpected labels;\ngot\n%v\nwant\n%v", sortedLabelss, expectedLabels)
+	}
+}
+
+func TestGetResourceGroup(t *testing.T) {
+	f := func(id, resourceGroupExpected string) {
+		t.Helper()
+		resourceGroup := getResourceGroup(id)
+		if resourceGroup != resourceGroupExpected {
+			t.Fatalf("unexpected resource group for %q; got %q; want %q", id, resourceGroup, resourceGroupExpected)
+		}
+	}
+	f("", "")
+	f("/subscriptions/sub/providers/Microsoft.Compute/virtualMachines/vm", "")
+	f("/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/virtualMachines/vm", "rg")
+	f("/subscriptions/sub/resourcegroups/RG/providers/Microsoft.Compute/virtualMachines/vm", "RG")
+}
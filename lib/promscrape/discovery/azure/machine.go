@@ -0,0 +1,302 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// virtualMachine represents Azure virtual machine or scale set virtual machine.
+//
+// See https://docs.microsoft.com/en-us/rest/api/compute/virtual-machines/list-all
+// and https://docs.microsoft.com/en-us/rest/api/compute/virtual-machine-scale-set-vms/list
+type virtualMachine struct {
+	ID         string                   `json:"id"`
+	Name       string                   `json:"name"`
+	Location   string                   `json:"location"`
+	Tags       map[string]string        `json:"tags"`
+	Properties virtualMachineProperties `json:"properties"`
+
+	// The following fields are populated during discovery.
+	resourceGroup string
+	scaleSet      string
+	ipAddresses   []vmIPAddress
+}
+
+type virtualMachineProperties struct {
+	NetworkProfile  networkProfile  `json:"networkProfile"`
+	OsProfile       osProfile       `json:"osProfile"`
+	StorageProfile  storageProfile  `json:"storageProfile"`
+	HardwareProfile hardwareProfile `json:"hardwareProfile"`
+}
+
+type networkProfile struct {
+	NetworkInterfaces []networkInterfaceReference `json:"networkInterfaces"`
+}
+
+type networkInterfaceReference struct {
+	ID string `json:"id"`
+}
+
+type osProfile struct {
+	ComputerName string `json:"computerName"`
+}
+
+type storageProfile struct {
+	OsDisk osDisk `json:"osDisk"`
+}
+
+type osDisk struct {
+	OsType string `json:"osType"`
+}
+
+type hardwareProfile struct {
+	VMSize string `json:"vmSize"`
+}
+
+type vmIPAddress struct {
+	privateIP string
+	publicIP  string
+}
+
+// scaleSet represents Azure virtual machine scale set.
+//
+// See https://docs.microsoft.com/en-us/rest/api/compute/virtual-machine-scale-sets/list-all
+type scaleSet struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// networkInterface represents Azure network interface.
+//
+// See https://docs.microsoft.com/en-us/rest/api/virtualnetwork/network-interfaces/get
+type networkInterface struct {
+	ID         string                     `json:"id"`
+	Properties networkInterfaceProperties `json:"properties"`
+}
+
+type networkInterfaceProperties struct {
+	// Primary may be missing for scale set network interfaces.
+	Primary          *bool             `json:"primary"`
+	IPConfigurations []ipConfiguration `json:"ipConfigurations"`
+}
+
+type ipConfiguration struct {
+	Properties ipConfigurationProperties `json:"properties"`
+}
+
+type ipConfigurationProperties struct {
+	PrivateIPAddress string           `json:"privateIPAddress"`
+	PublicIPAddress  *publicIPAddress `json:"publicIPAddress"`
+}
+
+type publicIPAddress struct {
+	Properties struct {
+		IPAddress string `json:"ipAddress"`
+	} `json:"properties"`
+}
+
+// listAPIResponse represents a page returned from Azure list API.
+//
+// See https://docs.microsoft.com/en-us/rest/api/azure/#async-operations-throttling-and-paging
+type listAPIResponse struct {
+	Value    json.RawMessage `json:"value"`
+	NextLink string          `json:"nextLink"`
+}
+
+// getVirtualMachines returns virtual machines and scale set virtual machines with populated ip addresses.
+func getVirtualMachines(cfg *apiConfig) ([]virtualMachine, error) {
+	vms, err := listVMs(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list virtual machines: %w", err)
+	}
+	ssVMs, err := listScaleSetVMs(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list scale set virtual machines: %w", err)
+	}
+	vms = append(vms, ssVMs...)
+	if err := populateIPAddresses(cfg, vms); err != nil {
+		return nil, err
+	}
+	return vms, nil
+}
+
+func listVMs(cfg *apiConfig) ([]virtualMachine, error) {
+	// See https://docs.microsoft.com/en-us/rest/api/compute/virtual-machines/list-all
+	// and https://docs.microsoft.com/en-us/rest/api/compute/virtual-machines/list
+	path := cfg.getProviderPath("Microsoft.Compute/virtualMachines") + "?api-version=2022-03-01"
+	var vms []virtualMachine
+	err := visitAllPages(cfg, path, func(data []byte) error {
+		var vmsPage []virtualMachine
+		if err := json.Unmarshal(data, &vmsPage); err != nil {
+			return fmt.Errorf("cannot parse virtual machines: %w", err)
+		}
+		vms = append(vms, vmsPage...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range vms {
+		vms[i].resourceGroup = getResourceGroup(vms[i].ID)
+	}
+	return vms, nil
+}
+
+func listScaleSetVMs(cfg *apiConfig) ([]virtualMachine, error) {
+	// See https://docs.microsoft.com/en-us/rest/api/compute/virtual-machine-scale-sets/list-all
+	// and https://docs.microsoft.com/en-us/rest/api/compute/virtual-machine-scale-sets/list
+	path := cfg.getProviderPath("Microsoft.Compute/virtualMachineScaleSets") + "?api-version=2022-03-01"
+	var sss []scaleSet
+	err := visitAllPages(cfg, path, func(data []byte) error {
+		var sssPage []scaleSet
+		if err := json.Unmarshal(data, &sssPage); err != nil {
+			return fmt.Errorf("cannot parse scale sets: %w", err)
+		}
+		sss = append(sss, sssPage...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var vms []virtualMachine
+	for _, ss := range sss {
+		// See https://docs.microsoft.com/en-us/rest/api/compute/virtual-machine-scale-set-vms/list
+		path := ss.ID + "/virtualMachines?api-version=2022-03-01"
+		var ssVMs []virtualMachine
+		err := visitAllPages(cfg, path, func(data []byte) error {
+			var vmsPage []virtualMachine
+			if err := json.Unmarshal(data, &vmsPage); err != nil {
+				return fmt.Errorf("cannot parse virtual machines for scale set %q: %w", ss.Name, err)
+			}
+			ssVMs = append(ssVMs, vmsPage...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := range ssVMs {
+			ssVMs[i].resourceGroup = getResourceGroup(ssVMs[i].ID)
+			ssVMs[i].scaleSet = ss.Name
+		}
+		vms = append(vms, ssVMs...)
+	}
+	return vms, nil
+}
+
+// getProviderPath returns API path for the given resource provider with the optional resource_group filter.
+func (cfg *apiConfig) getProviderPath(provider string) string {
+	path := "/subscriptions/" + url.PathEscape(cfg.subscriptionID)
+	if cfg.resourceGroup != "" {
+		path += "/resourceGroups/" + url.PathEscape(cfg.resourceGroup)
+	}
+	return path + "/providers/" + provider
+}
+
+// visitAllPages calls f for the value of every page returned from Azure list API starting from the given path.
+func visitAllPages(cfg *apiConfig, path string, f func(data []byte) error) error {
+	for path != "" {
+		data, err := cfg.getAPIResponse(path)
+		if err != nil {
+			return err
+		}
+		var lar listAPIResponse
+		if err := json.Unmarshal(data, &lar); err != nil {
+			return fmt.Errorf("cannot parse response from %q: %w", path, err)
+		}
+		if len(lar.Value) > 0 {
+			if err := f(lar.Value); err != nil {
+				return err
+			}
+		}
+		path, err = getNextLinkPath(lar.NextLink)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getNextLinkPath(nextLink string) (string, error) {
+	if nextLink == "" {
+		return "", nil
+	}
+	u, err := url.Parse(nextLink)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse nextLink %q: %w", nextLink, err)
+	}
+	return u.RequestURI(), nil
+}
+
+// getResourceGroup returns resource group name from the given Azure resource id.
+//
+// Resource id has the following format: /subscriptions/<subscription_id>/resourceGroups/<resource_group>/providers/...
+func getResourceGroup(id string) string {
+	a := strings.Split(id, "/")
+	for i := 0; i+1 < len(a); i++ {
+		if strings.EqualFold(a[i], "resourceGroups") {
+			return a[i+1]
+		}
+	}
+	return ""
+}
+
+// populateIPAddresses fetches network interfaces for vms and populates ip addresses for them.
+func populateIPAddresses(cfg *apiConfig, vms []virtualMachine) error {
+	errs := make([]error, len(vms))
+	var wg sync.WaitGroup
+	for i := range vms {
+		wg.Add(1)
+		go func(vm *virtualMachine, errp *error) {
+			defer wg.Done()
+			for _, nicRef := range vm.Properties.NetworkProfile.NetworkInterfaces {
+				nic, err := getNetworkInterface(cfg, nicRef.ID, vm.scaleSet != "")
+				if err != nil {
+					*errp = err
+					return
+				}
+				if nic.Properties.Primary != nil && !*nic.Properties.Primary {
+					// Only the primary network interface is used for scraping like Prometheus does.
+					continue
+				}
+				for _, ipc := range nic.Properties.IPConfigurations {
+					ips := vmIPAddress{
+						privateIP: ipc.Properties.PrivateIPAddress,
+					}
+					if pip := ipc.Properties.PublicIPAddress; pip != nil {
+						ips.publicIP = pip.Properties.IPAddress
+					}
+					vm.ipAddresses = append(vm.ipAddresses, ips)
+				}
+			}
+		}(&vms[i], &errs[i])
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getNetworkInterface(cfg *apiConfig, id string, isScaleSetVM bool) (*networkInterface, error) {
+	// See https://docs.microsoft.com/en-us/rest/api/virtualnetwork/network-interfaces/get
+	// and https://docs.microsoft.com/en-us/rest/api/virtualnetwork/network-interfaces/get-virtual-machine-scale-set-network-interface
+	apiVersion := "2022-01-01"
+	if isScaleSetVM {
+		apiVersion = "2018-10-01"
+	}
+	path := id + "?api-version=" + apiVersion + "&$expand=ipConfigurations/publicIPAddress"
+	data, err := cfg.getAPIResponse(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain network interface %q: %w", id, err)
+	}
+	var nic networkInterface
+	if err := json.Unmarshal(data, &nic); err != nil {
+		return nil, fmt.Errorf("cannot parse network interface %q: %w", id, err)
+	}
+	return &nic, nil
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -73,11 +74,13 @@ func newAPIWatcher(apiServer string, ac *promauth.Config, sdc *SDConfig, swcFunc
 			namespaces = []string{string(namespace)}
 		}
 	}
-	selectors := sdc.Selectors
-	attachNodeMetadata := sdc.AttachMetadata.Node
+	role := sdc.role()
+	// Node metadata can be attached only to pod, endpoints and endpointslice targets.
+	// Drop it for other roles, so they could share groupWatcher with scrape configs without attach_metadata.
+	attachNodeMetadata := sdc.AttachMetadata.Node && roleSupportsNodeMetadata(role)
+	selectors := getSelectorsForRole(role, sdc.Selectors, attachNodeMetadata)
 	proxyURL := sdc.ProxyURL.GetURL()
 	gw := getGroupWatcher(apiServer, ac, namespaces, selectors, attachNodeMetadata, proxyURL)
-	role := sdc.role()
 	return &apiWatcher{
 		role:             role,
 		swcFunc:          swcFunc,
@@ -87,6 +90,49 @@ func newAPIWatcher(apiServer string, ac *promauth.Config, sdc *SDConfig, swcFunc
 	}
 }
 
+func roleSupportsNodeMetadata(role string) bool {
+	return role == "pod" || role == "endpoints" || role == "endpointslice"
+}
+
+// getSelectorsForRole returns a sorted copy of selectors, which are applied to objects watched for the given role.
+//
+// Selectors for other roles are dropped and the remaining selectors are sorted,
+// so scrape configs with identical effective selectors share the same groupWatcher.
+// Selectors with unknown roles are skipped with a warning.
+func getSelectorsForRole(role string, selectors []Selector, attachNodeMetadata bool) []Selector {
+	var result []Selector
+	for _, s := range selectors {
+		switch s.Role {
+		case role:
+		case "pod", "service":
+			if role != "endpoints" && role != "endpointslice" {
+				continue
+			}
+		case "node":
+			if !attachNodeMetadata || !roleSupportsNodeMetadata(role) {
+				continue
+			}
+		case "endpoints", "endpointslice", "ingress":
+			continue
+		default:
+			logger.Warnf("skipping selector with unexpected `role`: %q in `selectors`; must be one of `node`, `pod`, `service`, `endpoints`, `endpointslice` or `ingress`", s.Role)
+			continue
+		}
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := &result[i], &result[j]
+		if a.Role != b.Role {
+			return a.Role < b.Role
+		}
+		if a.Label != b.Label {
+			return a.Label < b.Label
+		}
+		return a.Field < b.Field
+	})
+	return result
+}
+
 func (aw *apiWatcher) mustStart() {
 	aw.gw.startWatchersForRole(aw.role, aw)
 }
@@ -345,7 +391,8 @@ func (gw *groupWatcher) startWatchersForRole(role string, aw *apiWatcher) {
 		gw.startWatchersForRole("pod", nil)
 		gw.startWatchersForRole("service", nil)
 	}
-	if gw.attachNodeMetadata && role == "pod" {
+	if gw.attachNodeMetadata && roleSupportsNodeMetadata(role) {
+		// Node metadata is attached to targets from the cached node objects. So start watcher for nodes as well.
 		gw.startWatchersForRole("node", nil)
 	}
 	paths := getAPIPathsWithNamespaces(role, gw.namespaces, gw.selectors)
@@ -803,8 +850,8 @@ func (uw *urlWatcher) maybeUpdateDependedScrapeWorksLocked() {
 			uwx.needRecreateScrapeWorks = true
 			continue
 		}
-		if attachNodeMetadata && role == "node" && uwx.role == "pod" {
-			// pod objects depend on node objects if attachNodeMetadata is set
+		if attachNodeMetadata && role == "node" && roleSupportsNodeMetadata(uwx.role) {
+			// pod, endpoints and endpointslice objects depend on node objects if attachNodeMetadata is set
 			uwx.needRecreateScrapeWorks = true
 			continue
 		}
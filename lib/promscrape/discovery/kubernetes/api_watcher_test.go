@@ -166,6 +166,127 @@ func TestGetAPIPathsWithNamespaces(t *testing.T) {
 	})
 }
 
+func TestGetSelectorsForRole(t *testing.T) {
+	f := func(role string, selectors []Selector, attachNodeMetadata bool, expectedSelectors []Selector) {
+		t.Helper()
+		result := getSelectorsForRole(role, selectors, attachNodeMetadata)
+		if !reflect.DeepEqual(result, expectedSelectors) {
+			t.Fatalf("unexpected selectors; got\n%v\nwant\n%v", result, expectedSelectors)
+		}
+	}
+
+	selectors := []Selector{
+		{
+			Role:  "service",
+			Label: "app=foo",
+		},
+		{
+			Role:  "pod",
+			Field: "spec.nodeName=x",
+		},
+		{
+			Role:  "node",
+			Label: "zone=a",
+		},
+		{
+			Role:  "pod",
+			Label: "app=bar",
+		},
+	}
+
+	// Selectors with unknown roles are skipped
+	f("pod", []Selector{
+		{
+			Role:  "unknown",
+			Label: "app=foo",
+		},
+		{
+			Role:  "pod",
+			Label: "app=bar",
+		},
+	}, false, []Selector{
+		{
+			Role:  "pod",
+			Label: "app=bar",
+		},
+	})
+
+	// Empty selectors
+	f("pod", nil, false, nil)
+	f("pod", nil, true, nil)
+
+	// Selectors for other roles are dropped
+	f("ingress", selectors, false, nil)
+	f("service", selectors, true, []Selector{
+		{
+			Role:  "service",
+			Label: "app=foo",
+		},
+	})
+
+	// Selectors are sorted
+	f("pod", selectors, false, []Selector{
+		{
+			Role:  "pod",
+			Field: "spec.nodeName=x",
+		},
+		{
+			Role:  "pod",
+			Label: "app=bar",
+		},
+	})
+
+	// Node selectors are preserved only if node metadata must be attached
+	f("pod", selectors, true, []Selector{
+		{
+			Role:  "node",
+			Label: "zone=a",
+		},
+		{
+			Role:  "pod",
+			Field: "spec.nodeName=x",
+		},
+		{
+			Role:  "pod",
+			Label: "app=bar",
+		},
+	})
+
+	// endpoints and endpointslice roles use pod and service selectors
+	f("endpoints", selectors, false, []Selector{
+		{
+			Role:  "pod",
+			Field: "spec.nodeName=x",
+		},
+		{
+			Role:  "pod",
+			Label: "app=bar",
+		},
+		{
+			Role:  "service",
+			Label: "app=foo",
+		},
+	})
+	f("endpointslice", selectors, true, []Selector{
+		{
+			Role:  "node",
+			Label: "zone=a",
+		},
+		{
+			Role:  "pod",
+			Field: "spec.nodeName=x",
+		},
+		{
+			Role:  "pod",
+			Label: "app=bar",
+		},
+		{
+			Role:  "service",
+			Label: "app=foo",
+		},
+	})
+}
+
 func TestParseBookmark(t *testing.T) {
 	data := `{"kind": "Pod", "apiVersion": "v1", "metadata": {"resourceVersion": "12746"} }`
 	bm, err := parseBookmark([]byte(data))
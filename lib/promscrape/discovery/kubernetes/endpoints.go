@@ -167,6 +167,9 @@ func getEndpointLabelsForAddressAndPort(gw *groupWatcher, podPortsSeen map[*Pod]
 	}
 	// See https://github.com/prometheus/prometheus/issues/10284
 	eps.Metadata.registerLabelsAndAnnotations("__meta_kubernetes_endpoints", m)
+	if ea.NodeName != "" {
+		gw.appendNodeMetadataLabels(m, ea.NodeName)
+	}
 	if ea.TargetRef.Kind != "Pod" || p == nil {
 		return m
 	}
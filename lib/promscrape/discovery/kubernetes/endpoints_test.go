@@ -107,6 +107,9 @@ func TestParseEndpointsListSuccess(t *testing.T) {
 			"__meta_kubernetes_endpoints_label_foo":           "bar",
 			"__meta_kubernetes_endpoints_labelpresent_foo":    "true",
 			"__meta_kubernetes_namespace":                     "default",
+			"__meta_kubernetes_node_name":                     "test-node",
+			"__meta_kubernetes_node_label_node_label":         "xyz",
+			"__meta_kubernetes_node_labelpresent_node_label":  "true",
 		}),
 	}
 	if !areEqualLabelss(sortedLabelss, expectedLabelss) {
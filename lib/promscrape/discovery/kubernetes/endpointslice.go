@@ -102,6 +102,9 @@ func getEndpointSliceLabelsForAddressAndPort(gw *groupWatcher, podPortsSeen map[
 	}
 	// See https://github.com/prometheus/prometheus/issues/10284
 	eps.Metadata.registerLabelsAndAnnotations("__meta_kubernetes_endpointslice", m)
+	if ea.NodeName != "" {
+		gw.appendNodeMetadataLabels(m, ea.NodeName)
+	}
 	if ea.TargetRef.Kind != "Pod" || p == nil {
 		return m
 	}
@@ -147,6 +150,9 @@ func getEndpointSliceLabels(eps *EndpointSlice, addr string, ea Endpoint, epp En
 	if ea.Hostname != "" {
 		m["__meta_kubernetes_endpointslice_endpoint_hostname"] = ea.Hostname
 	}
+	if ea.NodeName != "" {
+		m["__meta_kubernetes_endpointslice_endpoint_node_name"] = ea.NodeName
+	}
 	for k, v := range ea.Topology {
 		m["__meta_kubernetes_endpointslice_endpoint_topology_"+discoveryutils.SanitizeLabelName(k)] = v
 		m["__meta_kubernetes_endpointslice_endpoint_topology_present_"+discoveryutils.SanitizeLabelName(k)] = "true"
@@ -179,6 +185,7 @@ type Endpoint struct {
 	Addresses  []string
 	Conditions EndpointConditions
 	Hostname   string
+	NodeName   string
 	TargetRef  ObjectReference
 	Topology   map[string]string
 }
@@ -69,7 +69,8 @@ func TestParseEndpointSliceListSuccess(t *testing.T) {
           ],
           "conditions": {
             "ready": true
-          }
+          },
+          "nodeName": "test-node"
         }
       ],
       "ports": [
@@ -210,6 +211,7 @@ func TestParseEndpointSliceListSuccess(t *testing.T) {
 			"__address__": "172.18.0.2:6443",
 			"__meta_kubernetes_endpointslice_address_type":                            "IPv4",
 			"__meta_kubernetes_endpointslice_endpoint_conditions_ready":               "true",
+			"__meta_kubernetes_endpointslice_endpoint_node_name":                      "test-node",
 			"__meta_kubernetes_endpointslice_label_kubernetes_io_service_name":        "kubernetes",
 			"__meta_kubernetes_endpointslice_labelpresent_kubernetes_io_service_name": "true",
 			"__meta_kubernetes_endpointslice_name":                                    "kubernetes",
@@ -217,6 +219,9 @@ func TestParseEndpointSliceListSuccess(t *testing.T) {
 			"__meta_kubernetes_endpointslice_port_name":                               "https",
 			"__meta_kubernetes_endpointslice_port_protocol":                           "TCP",
 			"__meta_kubernetes_namespace":                                             "default",
+			"__meta_kubernetes_node_name":                                             "test-node",
+			"__meta_kubernetes_node_label_node_label":                                 "xyz",
+			"__meta_kubernetes_node_labelpresent_node_label":                          "true",
 		}),
 	}
 	if !areEqualLabelss(sortedLabelss, expectedLabelss) {
@@ -110,6 +110,22 @@ func (n *Node) getTargetLabels(gw *groupWatcher) []map[string]string {
 	return []map[string]string{m}
 }
 
+// appendNodeMetadataLabels appends labels for the node with the given nodeName to m if attach_metadata.node is set.
+//
+// The node is looked up in objects already cached by the node watcher, so this doesn't result in additional requests to apiServer.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#kubernetes_sd_config
+func (gw *groupWatcher) appendNodeMetadataLabels(m map[string]string, nodeName string) {
+	if !gw.attachNodeMetadata {
+		return
+	}
+	m["__meta_kubernetes_node_name"] = nodeName
+	if o := gw.getObjectByRoleLocked("node", "", nodeName); o != nil {
+		n := o.(*Node)
+		n.Metadata.registerLabelsAndAnnotations("__meta_kubernetes_node", m)
+	}
+}
+
 func getNodeAddr(nas []NodeAddress) string {
 	if addr := getAddrByType(nas, "InternalIP"); len(addr) > 0 {
 		return addr
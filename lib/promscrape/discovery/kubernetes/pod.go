@@ -144,14 +144,7 @@ func (p *Pod) appendContainerLabels(m map[string]string, c Container, cp *Contai
 }
 
 func (p *Pod) appendCommonLabels(m map[string]string, gw *groupWatcher) {
-	if gw.attachNodeMetadata {
-		m["__meta_kubernetes_node_name"] = p.Spec.NodeName
-		o := gw.getObjectByRoleLocked("node", p.Metadata.Namespace, p.Spec.NodeName)
-		if o != nil {
-			n := o.(*Node)
-			n.Metadata.registerLabelsAndAnnotations("__meta_kubernetes_node", m)
-		}
-	}
+	gw.appendNodeMetadataLabels(m, p.Spec.NodeName)
 	m["__meta_kubernetes_pod_name"] = p.Metadata.Name
 	m["__meta_kubernetes_pod_ip"] = p.Status.PodIP
 	m["__meta_kubernetes_pod_ready"] = getPodReadyStatus(p.Status.Conditions)
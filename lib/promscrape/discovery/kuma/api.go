@@ -0,0 +1,225 @@
+package kuma
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/fasthttp"
+)
+
+// SDCheckInterval is check interval for Kuma service discovery.
+var SDCheckInterval = flag.Duration("promscrape.kumaSDCheckInterval", 30*time.Second, "Interval for checking for changes in Kuma service discovery. "+
+	"This works only if kuma_sd_configs is configured in '-promscrape.config' file. "+
+	"See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#kuma_sd_config for details")
+
+// monitoringAssignmentTypeURL is the type of resources requested from Kuma Monitoring Assignment Discovery Service (MADS).
+const monitoringAssignmentTypeURL = "type.googleapis.com/kuma.observability.v1.MonitoringAssignment"
+
+// apiConfig contains config for Kuma MADS API.
+type apiConfig struct {
+	client       *discoveryutils.Client
+	path         string
+	clientID     string
+	fetchTimeout time.Duration
+
+	// versionInfo and nonce are obtained from the last successful response.
+	// They are accessed only by the goroutine performing requests to MADS.
+	versionInfo string
+	nonce       string
+
+	// labelssLock protects labelss
+	labelssLock sync.Mutex
+	labelss     []map[string]string
+
+	stopCh chan struct{}
+}
+
+func (cfg *apiConfig) mustStop() {
+	close(cfg.stopCh)
+	// Do not wait for the watcher to stop, since it may take
+	// up to fetchTimeout to complete.
+}
+
+var configMap = discoveryutils.NewConfigMap()
+
+func getAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	v, err := configMap.Get(sdc, func() (interface{}, error) { return newAPIConfig(sdc, baseDir) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*apiConfig), nil
+}
+
+func newAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	if sdc.Server == "" {
+		return nil, fmt.Errorf("missing `server` in `kuma_sd_config`")
+	}
+	u, err := url.Parse(sdc.Server)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `server` %q in `kuma_sd_config`: %w", sdc.Server, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme in `server` %q in `kuma_sd_config`; must be http or https", sdc.Server)
+	}
+	apiServer := u.Scheme + "://" + u.Host
+	ac, err := sdc.HTTPClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse auth config: %w", err)
+	}
+	proxyAC, err := sdc.ProxyClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse proxy auth config: %w", err)
+	}
+	client, err := discoveryutils.NewClient(apiServer, ac, sdc.ProxyURL, proxyAC)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create HTTP client for %q: %w", apiServer, err)
+	}
+	clientID := sdc.ClientID
+	if clientID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("cannot determine hostname for `client_id` in `kuma_sd_config`: %w", err)
+		}
+		clientID = hostname
+	}
+	fetchTimeout := sdc.FetchTimeout.Duration()
+	if fetchTimeout <= 0 {
+		fetchTimeout = 2 * time.Minute
+	}
+	// The response must be received before discoveryutils.BlockingClientReadTimeout.
+	if maxFetchTimeout := discoveryutils.BlockingClientReadTimeout - discoveryutils.BlockingClientReadTimeout/8; fetchTimeout > maxFetchTimeout {
+		fetchTimeout = maxFetchTimeout
+	}
+	// See https://github.com/kumahq/kuma/blob/master/pkg/mads/v1/service/http.go
+	path := strings.TrimSuffix(u.Path, "/") + "/v3/discovery:monitoringassignments?fetch-timeout=" + url.QueryEscape(fetchTimeout.String())
+	cfg := &apiConfig{
+		client:       client,
+		path:         path,
+		clientID:     clientID,
+		fetchTimeout: fetchTimeout,
+		stopCh:       make(chan struct{}),
+	}
+	// The first request returns immediately, since versionInfo is empty.
+	if err := cfg.updateTargets(); err != nil {
+		return nil, err
+	}
+	go cfg.watchForUpdates()
+	return cfg, nil
+}
+
+// watchForUpdates polls Kuma MADS for updates every SDCheckInterval.
+//
+// Every request blocks on the server side for up to fetchTimeout until monitoring assignments are changed.
+func (cfg *apiConfig) watchForUpdates() {
+	ticker := time.NewTicker(getCheckInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := cfg.updateTargets(); err != nil {
+				logger.Errorf("%s", err)
+			}
+		case <-cfg.stopCh:
+			return
+		}
+	}
+}
+
+// updateTargets fetches monitoring assignments from Kuma MADS and updates labelss for the discovered targets.
+func (cfg *apiConfig) updateTargets() error {
+	resp, err := cfg.fetchMonitoringAssignments()
+	if err != nil {
+		return fmt.Errorf("cannot fetch Kuma monitoring assignments from %q: %w", cfg.client.Addr(), err)
+	}
+	if resp == nil {
+		// Nothing changed.
+		return nil
+	}
+	var ms []map[string]string
+	for i := range resp.Resources {
+		ms = resp.Resources[i].appendTargetLabels(ms)
+	}
+	cfg.labelssLock.Lock()
+	cfg.labelss = ms
+	cfg.labelssLock.Unlock()
+	cfg.versionInfo = resp.VersionInfo
+	cfg.nonce = resp.Nonce
+	return nil
+}
+
+// fetchMonitoringAssignments performs xDS request to Kuma MADS via HTTP.
+//
+// It returns nil response if monitoring assignments weren't changed since the previous request.
+//
+// See https://www.envoyproxy.io/docs/envoy/latest/api-docs/xds_protocol#rest-json-polling-subscriptions
+func (cfg *apiConfig) fetchMonitoringAssignments() (*discoveryResponse, error) {
+	req := &discoveryRequest{
+		VersionInfo:   cfg.versionInfo,
+		Node:          discoveryNode{ID: cfg.clientID},
+		ResourceNames: []string{},
+		TypeURL:       monitoringAssignmentTypeURL,
+		ResponseNonce: cfg.nonce,
+	}
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal DiscoveryRequest: %s", err)
+	}
+	modifyRequest := func(req *fasthttp.Request) {
+		req.Header.SetMethod("POST")
+		req.Header.SetContentType("application/json")
+		req.Header.Set("Accept", "application/json")
+		req.SetBody(reqBody)
+	}
+	notModified := false
+	inspectResponse := func(resp *fasthttp.Response) {
+		if resp.StatusCode() == fasthttp.StatusNotModified {
+			// Kuma returns 304 Not Modified if monitoring assignments weren't changed during fetch-timeout.
+			// Convert it to 200 OK, so it isn't treated as an error.
+			notModified = true
+			resp.SetStatusCode(fasthttp.StatusOK)
+		}
+	}
+	data, err := cfg.client.GetBlockingAPIResponseWithReqParams(cfg.path, modifyRequest, inspectResponse)
+	if err != nil {
+		return nil, err
+	}
+	if notModified {
+		return nil, nil
+	}
+	return parseDiscoveryResponse(data)
+}
+
+func parseDiscoveryResponse(data []byte) (*discoveryResponse, error) {
+	var resp discoveryResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal DiscoveryResponse from %q: %w", data, err)
+	}
+	if resp.TypeURL != monitoringAssignmentTypeURL {
+		return nil, fmt.Errorf("unexpected type_url in DiscoveryResponse; got %q; want %q", resp.TypeURL, monitoringAssignmentTypeURL)
+	}
+	return &resp, nil
+}
+
+// getLabelsSnapshot returns labels for the discovered targets.
+func (cfg *apiConfig) getLabelsSnapshot() []map[string]string {
+	cfg.labelssLock.Lock()
+	labelss := cfg.labelss
+	cfg.labelssLock.Unlock()
+	return labelss
+}
+
+func getCheckInterval() time.Duration {
+	d := *SDCheckInterval
+	if d <= time.Second {
+		return time.Second
+	}
+	return d
+}
@@ -0,0 +1,142 @@
+package kuma
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func TestNewAPIConfigFailure(t *testing.T) {
+	f := func(sdc *SDConfig) {
+		t.Helper()
+		cfg, err := newAPIConfig(sdc, "")
+		if err == nil {
+			cfg.mustStop()
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// Missing server
+	f(&SDConfig{})
+	// Unsupported scheme
+	f(&SDConfig{
+		Server: "grpc://localhost:5676",
+	})
+	// Unavailable server
+	f(&SDConfig{
+		Server: "http://127.0.0.1:1",
+	})
+}
+
+func TestGetLabels(t *testing.T) {
+	var mu sync.Mutex
+	version := 1
+	targetAddr := "10.0.0.1:5670"
+	var lastRequest discoveryRequest
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, "unexpected method", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.URL.Path != "/kuma/v3/discovery:monitoringassignments" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("fetch-timeout") != "5s" {
+			http.Error(w, "unexpected fetch-timeout", http.StatusBadRequest)
+			return
+		}
+		var req discoveryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.TypeURL != monitoringAssignmentTypeURL || req.Node.ID != "vmagent-test" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		lastRequest = req
+		versionInfo := fmt.Sprintf("v%d", version)
+		if req.VersionInfo == versionInfo {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprintf(w, `{
+  "versionInfo": %q,
+  "resources": [
+    {
+      "@type": "type.googleapis.com/kuma.observability.v1.MonitoringAssignment",
+      "mesh": "default",
+      "service": "web",
+      "targets": [{"name": "web-1", "scheme": "http", "address": %q, "metricsPath": "/metrics"}]
+    }
+  ],
+  "typeUrl": "type.googleapis.com/kuma.observability.v1.MonitoringAssignment",
+  "nonce": "nonce-%d"
+}`, versionInfo, targetAddr, version)
+	}))
+	defer s.Close()
+
+	sdc := &SDConfig{
+		Server:       s.URL + "/kuma/",
+		ClientID:     "vmagent-test",
+		FetchTimeout: promutils.NewDuration(5 * time.Second),
+	}
+	defer sdc.MustStop()
+	getAddrs := func() []string {
+		t.Helper()
+		ms, err := sdc.GetLabels("")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var addrs []string
+		for _, m := range ms {
+			addrs = append(addrs, m["__address__"]+" "+m["__meta_kuma_service"]+" "+m["__meta_kuma_dataplane"])
+		}
+		return addrs
+	}
+	expectedAddrs := []string{"10.0.0.1:5670 web web-1"}
+	if addrs := getAddrs(); !reflect.DeepEqual(addrs, expectedAddrs) {
+		t.Fatalf("unexpected targets;\ngot\n%q\nwant\n%q", addrs, expectedAddrs)
+	}
+	cfg, err := getAPIConfig(sdc, "")
+	if err != nil {
+		t.Fatalf("cannot get API config: %s", err)
+	}
+
+	// Not modified response must leave the targets untouched.
+	if err := cfg.updateTargets(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mu.Lock()
+	req := lastRequest
+	mu.Unlock()
+	if req.VersionInfo != "v1" || req.ResponseNonce != "nonce-1" {
+		t.Fatalf("unexpected versionInfo and nonce in the request; got %q, %q; want %q, %q", req.VersionInfo, req.ResponseNonce, "v1", "nonce-1")
+	}
+	if addrs := getAddrs(); !reflect.DeepEqual(addrs, expectedAddrs) {
+		t.Fatalf("unexpected targets after not modified response;\ngot\n%q\nwant\n%q", addrs, expectedAddrs)
+	}
+
+	// Updated monitoring assignments must be applied.
+	mu.Lock()
+	version++
+	targetAddr = "10.0.0.2:5670"
+	mu.Unlock()
+	if err := cfg.updateTargets(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedAddrs = []string{"10.0.0.2:5670 web web-1"}
+	if addrs := getAddrs(); !reflect.DeepEqual(addrs, expectedAddrs) {
+		t.Fatalf("unexpected targets after update;\ngot\n%q\nwant\n%q", addrs, expectedAddrs)
+	}
+}
@@ -0,0 +1,44 @@
+package kuma
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
+)
+
+// SDConfig represents service discovery config for Kuma Service Mesh.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#kuma_sd_config
+type SDConfig struct {
+	Server string `yaml:"server"`
+	// ClientID is sent to Kuma control plane as node id. The hostname is used by default.
+	ClientID          string                     `yaml:"client_id,omitempty"`
+	FetchTimeout      *promutils.Duration        `yaml:"fetch_timeout,omitempty"`
+	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
+	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
+	// RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// refresh_interval is obtained from `-promscrape.kumaSDCheckInterval` command-line option.
+}
+
+// GetLabels returns Kuma labels according to sdc.
+func (sdc *SDConfig) GetLabels(baseDir string) ([]map[string]string, error) {
+	cfg, err := getAPIConfig(sdc, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get API config: %w", err)
+	}
+	ms := cfg.getLabelsSnapshot()
+	return ms, nil
+}
+
+// MustStop stops further usage for sdc.
+func (sdc *SDConfig) MustStop() {
+	v := configMap.Delete(sdc)
+	if v != nil {
+		// v can be nil if GetLabels wasn't called yet.
+		cfg := v.(*apiConfig)
+		cfg.mustStop()
+	}
+}
@@ -0,0 +1,74 @@
+package kuma
+
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+// discoveryRequest is xDS DiscoveryRequest in JSON representation.
+//
+// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/service/discovery/v3/discovery.proto#service-discovery-v3-discoveryrequest
+type discoveryRequest struct {
+	VersionInfo   string        `json:"versionInfo,omitempty"`
+	Node          discoveryNode `json:"node"`
+	ResourceNames []string      `json:"resourceNames"`
+	TypeURL       string        `json:"typeUrl"`
+	ResponseNonce string        `json:"responseNonce,omitempty"`
+}
+
+type discoveryNode struct {
+	ID string `json:"id"`
+}
+
+// discoveryResponse is xDS DiscoveryResponse with MonitoringAssignment resources in JSON representation.
+//
+// See https://www.envoyproxy.io/docs/envoy/latest/api-v3/service/discovery/v3/discovery.proto#service-discovery-v3-discoveryresponse
+type discoveryResponse struct {
+	VersionInfo string
+	Resources   []monitoringAssignment
+	TypeURL     string `json:"typeUrl"`
+	Nonce       string
+}
+
+// monitoringAssignment is Kuma MonitoringAssignment.
+//
+// See https://github.com/kumahq/kuma/blob/master/api/observability/v1/mads.proto
+type monitoringAssignment struct {
+	Mesh    string
+	Service string
+	Targets []monitoringAssignmentTarget
+	Labels  map[string]string
+}
+
+type monitoringAssignmentTarget struct {
+	Name        string
+	Scheme      string
+	Address     string
+	MetricsPath string
+	Labels      map[string]string
+}
+
+// appendTargetLabels appends labels for ma targets to ms.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#kuma_sd_config
+func (ma *monitoringAssignment) appendTargetLabels(ms []map[string]string) []map[string]string {
+	for _, t := range ma.Targets {
+		m := map[string]string{
+			"__address__":           t.Address,
+			"__scheme__":            t.Scheme,
+			"__metrics_path__":      t.MetricsPath,
+			"instance":              t.Name,
+			"__meta_kuma_mesh":      ma.Mesh,
+			"__meta_kuma_service":   ma.Service,
+			"__meta_kuma_dataplane": t.Name,
+		}
+		for k, v := range ma.Labels {
+			m["__meta_kuma_label_"+discoveryutils.SanitizeLabelName(k)] = v
+		}
+		// Target labels override the common labels for the monitoring assignment.
+		for k, v := range t.Labels {
+			m["__meta_kuma_label_"+discoveryutils.SanitizeLabelName(k)] = v
+		}
+		ms = append(ms, m)
+	}
+	return ms
+}
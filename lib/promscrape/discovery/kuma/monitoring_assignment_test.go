@@ -0,0 +1,110 @@
+package kuma
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+func TestParseDiscoveryResponseFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		resp, err := parseDiscoveryResponse([]byte(s))
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if resp != nil {
+			t.Fatalf("unexpected non-nil response: %v", resp)
+		}
+	}
+	f(``)
+	f(`[1,23]`)
+	f(`{"versionInfo":"1","resources":[]}`)
+	f(`{"versionInfo":"1","resources":[],"typeUrl":"type.googleapis.com/envoy.config.cluster.v3.Cluster"}`)
+}
+
+func TestParseDiscoveryResponseSuccess(t *testing.T) {
+	data := `{
+  "versionInfo": "5dc9a5dd-2091-4426-a886-dfdc24fc5e77",
+  "resources": [
+    {
+      "@type": "type.googleapis.com/kuma.observability.v1.MonitoringAssignment",
+      "mesh": "default",
+      "service": "redis",
+      "labels": {
+        "kuma.io/zone": "us-east",
+        "team": "db"
+      },
+      "targets": [
+        {
+          "name": "redis-1",
+          "scheme": "http",
+          "address": "10.1.4.32:5670",
+          "metricsPath": "/metrics",
+          "labels": {
+            "commit_hash": "620506a88",
+            "team": "cache"
+          }
+        },
+        {
+          "name": "redis-2",
+          "scheme": "https",
+          "address": "10.1.4.33:5670",
+          "metricsPath": "/stats"
+        }
+      ]
+    }
+  ],
+  "typeUrl": "type.googleapis.com/kuma.observability.v1.MonitoringAssignment",
+  "nonce": "1"
+}`
+	resp, err := parseDiscoveryResponse([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.VersionInfo != "5dc9a5dd-2091-4426-a886-dfdc24fc5e77" {
+		t.Fatalf("unexpected versionInfo: %q", resp.VersionInfo)
+	}
+	if resp.Nonce != "1" {
+		t.Fatalf("unexpected nonce: %q", resp.Nonce)
+	}
+	if len(resp.Resources) != 1 {
+		t.Fatalf("unexpected number of resources; got %d; want %d", len(resp.Resources), 1)
+	}
+
+	labelss := resp.Resources[0].appendTargetLabels(nil)
+	var sortedLabelss [][]prompbmarshal.Label
+	for _, labels := range labelss {
+		sortedLabelss = append(sortedLabelss, discoveryutils.GetSortedLabels(labels))
+	}
+	expectedLabelss := [][]prompbmarshal.Label{
+		discoveryutils.GetSortedLabels(map[string]string{
+			"__address__":                    "10.1.4.32:5670",
+			"__scheme__":                     "http",
+			"__metrics_path__":               "/metrics",
+			"instance":                       "redis-1",
+			"__meta_kuma_mesh":               "default",
+			"__meta_kuma_service":            "redis",
+			"__meta_kuma_dataplane":          "redis-1",
+			"__meta_kuma_label_kuma_io_zone": "us-east",
+			"__meta_kuma_label_team":         "cache",
+			"__meta_kuma_label_commit_hash":  "620506a88",
+		}),
+		discoveryutils.GetSortedLabels(map[string]string{
+			"__address__":                    "10.1.4.33:5670",
+			"__scheme__":                     "https",
+			"__metrics_path__":               "/stats",
+			"instance":                       "redis-2",
+			"__meta_kuma_mesh":               "default",
+			"__meta_kuma_service":            "redis",
+			"__meta_kuma_dataplane":          "redis-2",
+			"__meta_kuma_label_kuma_io_zone": "us-east",
+			"__meta_kuma_label_team":         "db",
+		}),
+	}
+	if !reflect.DeepEqual(sortedLabelss, expectedLabelss) {
+		t.Fatalf("unexpected labels:\ngot\n%v\nwant\n%v", sortedLabelss, expectedLabelss)
+	}
+}
@@ -0,0 +1,48 @@
+package nomad
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
+)
+
+// SDConfig represents service discovery config for Nomad.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#nomad_sd_config
+type SDConfig struct {
+	Server string `yaml:"server,omitempty"`
+	// Token is Nomad ACL token. It is sent in X-Nomad-Token header.
+	// See https://developer.hashicorp.com/nomad/api-docs#authentication
+	Token     *promauth.Secret `yaml:"token,omitempty"`
+	Namespace string           `yaml:"namespace,omitempty"`
+	Region    string           `yaml:"region,omitempty"`
+	// AllowStale is set to true by default like Prometheus does.
+	AllowStale        *bool                      `yaml:"allow_stale,omitempty"`
+	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
+	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
+	TagSeparator      *string                    `yaml:"tag_separator,omitempty"`
+	// RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// refresh_interval is obtained from `-promscrape.nomadSDCheckInterval` command-line option.
+}
+
+// GetLabels returns Nomad labels according to sdc.
+func (sdc *SDConfig) GetLabels(baseDir string) ([]map[string]string, error) {
+	cfg, err := getAPIConfig(sdc, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get API config: %w", err)
+	}
+	ms := getServiceLabels(cfg)
+	return ms, nil
+}
+
+// MustStop stops further usage for sdc.
+func (sdc *SDConfig) MustStop() {
+	v := configMap.Delete(sdc)
+	if v != nil {
+		// v can be nil if GetLabels wasn't called yet.
+		cfg := v.(*apiConfig)
+		cfg.mustStop()
+	}
+}
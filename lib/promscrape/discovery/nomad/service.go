@@ -0,0 +1,65 @@
+package nomad
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+// getServiceLabels returns labels for Nomad services with given cfg.
+func getServiceLabels(cfg *apiConfig) []map[string]string {
+	services := cfg.nomadWatcher.getServicesSnapshot()
+	var ms []map[string]string
+	for i := range services {
+		ms = services[i].appendTargetLabels(ms, cfg.tagSeparator)
+	}
+	return ms
+}
+
+// Service is Nomad service registration.
+//
+// See https://developer.hashicorp.com/nomad/api-docs/services#read-service
+type Service struct {
+	ID          string
+	ServiceName string
+	Namespace   string
+	NodeID      string
+	Datacenter  string
+	JobID       string
+	AllocID     string
+	Tags        []string
+	Address     string
+	Port        int
+}
+
+func parseServices(data []byte) ([]Service, error) {
+	var services []Service
+	if err := json.Unmarshal(data, &services); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal Services from %q: %w", data, err)
+	}
+	return services, nil
+}
+
+func (svc *Service) appendTargetLabels(ms []map[string]string, tagSeparator string) []map[string]string {
+	m := map[string]string{
+		"__address__":                   discoveryutils.JoinHostPort(svc.Address, svc.Port),
+		"__meta_nomad_address":          svc.Address,
+		"__meta_nomad_dc":               svc.Datacenter,
+		"__meta_nomad_namespace":        svc.Namespace,
+		"__meta_nomad_node_id":          svc.NodeID,
+		"__meta_nomad_service":          svc.ServiceName,
+		"__meta_nomad_service_address":  svc.Address,
+		"__meta_nomad_service_alloc_id": svc.AllocID,
+		"__meta_nomad_service_id":       svc.ID,
+		"__meta_nomad_service_job_id":   svc.JobID,
+		"__meta_nomad_service_port":     strconv.Itoa(svc.Port),
+	}
+	// We surround the separated list with the separator as well. This way regular expressions
+	// in relabeling rules don't have to consider tag positions.
+	m["__meta_nomad_tags"] = tagSeparator + strings.Join(svc.Tags, tagSeparator) + tagSeparator
+	ms = append(ms, m)
+	return ms
+}
@@ -0,0 +1,81 @@
+package nomad
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+func TestParseServicesFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		services, err := parseServices([]byte(s))
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if services != nil {
+			t.Fatalf("unexpected non-nil Services: %v", services)
+		}
+	}
+	f(``)
+	f(`[1,23]`)
+	f(`{"items":[{"metadata":1}]}`)
+}
+
+func TestParseServicesSuccess(t *testing.T) {
+	data := `
+[
+  {
+    "Address": "127.0.0.1",
+    "AllocID": "177160af-26f6-619f-9c9f-5e46d1104395",
+    "CreateIndex": 14,
+    "Datacenter": "dc1",
+    "ID": "_nomad-task-177160af-26f6-619f-9c9f-5e46d1104395-redis-example-cache-redis-db",
+    "JobID": "example",
+    "ModifyIndex": 24,
+    "Namespace": "default",
+    "NodeID": "7406e90b-de16-d118-80fe-60d0f2730cb3",
+    "Port": 29702,
+    "ServiceName": "example-cache-redis",
+    "Tags": ["db", "cache"]
+  }
+]
+`
+	services, err := parseServices([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(services) != 1 {
+		t.Fatalf("unexpected length of Services; got %d; want %d", len(services), 1)
+	}
+	svc := services[0]
+
+	// Check svc.appendTargetLabels()
+	tagSeparator := ","
+	labelss := svc.appendTargetLabels(nil, tagSeparator)
+	var sortedLabelss [][]prompbmarshal.Label
+	for _, labels := range labelss {
+		sortedLabelss = append(sortedLabelss, discoveryutils.GetSortedLabels(labels))
+	}
+	expectedLabelss := [][]prompbmarshal.Label{
+		discoveryutils.GetSortedLabels(map[string]string{
+			"__address__":                   "127.0.0.1:29702",
+			"__meta_nomad_address":          "127.0.0.1",
+			"__meta_nomad_dc":               "dc1",
+			"__meta_nomad_namespace":        "default",
+			"__meta_nomad_node_id":          "7406e90b-de16-d118-80fe-60d0f2730cb3",
+			"__meta_nomad_service":          "example-cache-redis",
+			"__meta_nomad_service_address":  "127.0.0.1",
+			"__meta_nomad_service_alloc_id": "177160af-26f6-619f-9c9f-5e46d1104395",
+			"__meta_nomad_service_id":       "_nomad-task-177160af-26f6-619f-9c9f-5e46d1104395-redis-example-cache-redis-db",
+			"__meta_nomad_service_job_id":   "example",
+			"__meta_nomad_service_port":     "29702",
+			"__meta_nomad_tags":             ",db,cache,",
+		}),
+	}
+	if !reflect.DeepEqual(sortedLabelss, expectedLabelss) {
+		t.Fatalf("unexpected labels:\ngot\n%v\nwant\n%v", sortedLabelss, expectedLabelss)
+	}
+}
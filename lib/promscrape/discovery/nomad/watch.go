@@ -0,0 +1,283 @@
+package nomad
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// SDCheckInterval is check interval for Nomad service discovery.
+var SDCheckInterval = flag.Duration("promscrape.nomadSDCheckInterval", 30*time.Second, "Interval for checking for changes in Nomad. "+
+	"This works only if nomad_sd_configs is configured in '-promscrape.config' file. "+
+	"See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#nomad_sd_config for details")
+
+// nomadWatcher is a watcher for nomad api, updates services map in background with long-polling.
+type nomadWatcher struct {
+	client *discoveryutils.Client
+
+	serviceNamesQueryArgs string
+	serviceNodesQueryArgs string
+
+	// servicesLock protects services
+	servicesLock sync.Mutex
+	services     map[serviceKey]*serviceWatcher
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+// serviceKey identifies Nomad service, since services with the same name may exist in distinct namespaces.
+type serviceKey struct {
+	namespace   string
+	serviceName string
+}
+
+type serviceWatcher struct {
+	key      serviceKey
+	services []Service
+	stopCh   chan struct{}
+}
+
+// newNomadWatcher creates new watcher and starts background service discovery for Nomad.
+func newNomadWatcher(client *discoveryutils.Client, sdc *SDConfig, namespace, region string) *nomadWatcher {
+	baseQueryArgs := "?region=" + url.QueryEscape(region)
+	if sdc.AllowStale == nil || *sdc.AllowStale {
+		baseQueryArgs += "&stale"
+	}
+	serviceNamesQueryArgs := baseQueryArgs
+	if namespace != "" {
+		serviceNamesQueryArgs += "&namespace=" + url.QueryEscape(namespace)
+	}
+	nw := &nomadWatcher{
+		client:                client,
+		serviceNamesQueryArgs: serviceNamesQueryArgs,
+		serviceNodesQueryArgs: baseQueryArgs,
+		services:              make(map[serviceKey]*serviceWatcher),
+		stopCh:                make(chan struct{}),
+	}
+	initCh := make(chan struct{})
+	go nw.watchForServicesUpdates(initCh)
+	// wait for initialization to complete
+	<-initCh
+	return nw
+}
+
+func (nw *nomadWatcher) mustStop() {
+	close(nw.stopCh)
+	// Do not wait for the watcher to stop, since it may take
+	// up to discoveryutils.BlockingClientReadTimeout to complete.
+}
+
+func (nw *nomadWatcher) updateServices(keys []serviceKey) {
+	var initWG sync.WaitGroup
+	// Start watchers for new services.
+	nw.servicesLock.Lock()
+	for _, key := range keys {
+		if _, ok := nw.services[key]; ok {
+			// The watcher for the service already exists.
+			continue
+		}
+		sw := &serviceWatcher{
+			key:    key,
+			stopCh: make(chan struct{}),
+		}
+		nw.services[key] = sw
+		nw.wg.Add(1)
+		serviceWatchersCreated.Inc()
+		initWG.Add(1)
+		go func() {
+			serviceWatchersCount.Inc()
+			sw.watchForServiceUpdates(nw, &initWG)
+			serviceWatchersCount.Dec()
+			nw.wg.Done()
+		}()
+	}
+
+	// Stop watchers for removed services.
+	newKeysMap := make(map[serviceKey]struct{}, len(keys))
+	for _, key := range keys {
+		newKeysMap[key] = struct{}{}
+	}
+	for key, sw := range nw.services {
+		if _, ok := newKeysMap[key]; ok {
+			continue
+		}
+		close(sw.stopCh)
+		delete(nw.services, key)
+		serviceWatchersStopped.Inc()
+
+		// Do not wait for the watcher goroutine to exit, since this may take for up to maxWaitTime
+		// if it is blocked in Nomad API request.
+	}
+	nw.servicesLock.Unlock()
+
+	// Wait for initialization to complete.
+	initWG.Wait()
+}
+
+// watchForServicesUpdates watches for new services and updates it in nw.
+//
+// watchForServicesUpdates closes the initCh once the initialization is complete and first discovery iteration is done.
+func (nw *nomadWatcher) watchForServicesUpdates(initCh chan struct{}) {
+	index := int64(0)
+	clientAddr := nw.client.Addr()
+	f := func() {
+		keys, newIndex, err := nw.getBlockingServiceKeys(index)
+		if err != nil {
+			logger.Errorf("cannot obtain Nomad services from %q: %s", clientAddr, err)
+			return
+		}
+		if index == newIndex {
+			// Nothing changed.
+			return
+		}
+		nw.updateServices(keys)
+		index = newIndex
+	}
+
+	logger.Infof("started Nomad service watcher for %q", clientAddr)
+	f()
+
+	// send signal that initialization is complete
+	close(initCh)
+
+	checkInterval := getCheckInterval()
+	ticker := time.NewTicker(checkInterval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f()
+		case <-nw.stopCh:
+			logger.Infof("stopping Nomad service watchers for %q", clientAddr)
+			startTime := time.Now()
+			nw.servicesLock.Lock()
+			for _, sw := range nw.services {
+				close(sw.stopCh)
+			}
+			nw.servicesLock.Unlock()
+			nw.wg.Wait()
+			logger.Infof("stopped Nomad service watcher for %q in %.3f seconds", clientAddr, time.Since(startTime).Seconds())
+			return
+		}
+	}
+}
+
+var (
+	serviceWatchersCreated = metrics.NewCounter("vm_promscrape_discovery_nomad_service_watchers_created_total")
+	serviceWatchersStopped = metrics.NewCounter("vm_promscrape_discovery_nomad_service_watchers_stopped_total")
+	serviceWatchersCount   = metrics.NewCounter("vm_promscrape_discovery_nomad_service_watchers")
+)
+
+// serviceStubsByNamespace is an item in the response from /v1/services
+//
+// See https://developer.hashicorp.com/nomad/api-docs/services#list-services
+type serviceStubsByNamespace struct {
+	Namespace string
+	Services  []struct {
+		ServiceName string
+	}
+}
+
+// getBlockingServiceKeys obtains service keys via blocking request to Nomad.
+//
+// It returns an empty keys list if response contains the same index.
+func (nw *nomadWatcher) getBlockingServiceKeys(index int64) ([]serviceKey, int64, error) {
+	path := "/v1/services" + nw.serviceNamesQueryArgs
+	data, newIndex, err := getBlockingAPIResponse(nw.client, path, index)
+	if err != nil {
+		return nil, index, err
+	}
+	if index == newIndex {
+		// Nothing changed - return an empty keys list.
+		return nil, index, nil
+	}
+	var sss []serviceStubsByNamespace
+	if err := json.Unmarshal(data, &sss); err != nil {
+		return nil, index, fmt.Errorf("cannot parse response from %q: %w; data=%q", path, err, data)
+	}
+	var keys []serviceKey
+	for _, ss := range sss {
+		for _, s := range ss.Services {
+			keys = append(keys, serviceKey{
+				namespace:   ss.Namespace,
+				serviceName: s.ServiceName,
+			})
+		}
+	}
+	return keys, newIndex, nil
+}
+
+// watchForServiceUpdates watches for Nomad service registrations changes for the given service.
+//
+// watchForServiceUpdates calls initWG.Done() once the initialization is complete and the first discovery iteration is done.
+func (sw *serviceWatcher) watchForServiceUpdates(nw *nomadWatcher, initWG *sync.WaitGroup) {
+	clientAddr := nw.client.Addr()
+	index := int64(0)
+	// See https://developer.hashicorp.com/nomad/api-docs/services#read-service
+	path := "/v1/service/" + url.PathEscape(sw.key.serviceName) + nw.serviceNodesQueryArgs + "&namespace=" + url.QueryEscape(sw.key.namespace)
+	f := func() {
+		data, newIndex, err := getBlockingAPIResponse(nw.client, path, index)
+		if err != nil {
+			logger.Errorf("cannot obtain Nomad services for serviceName=%q, namespace=%q from %q: %s", sw.key.serviceName, sw.key.namespace, clientAddr, err)
+			return
+		}
+		if index == newIndex {
+			// Nothing changed.
+			return
+		}
+		services, err := parseServices(data)
+		if err != nil {
+			logger.Errorf("cannot parse Nomad services response for serviceName=%q, namespace=%q from %q: %s", sw.key.serviceName, sw.key.namespace, clientAddr, err)
+			return
+		}
+
+		nw.servicesLock.Lock()
+		sw.services = services
+		nw.servicesLock.Unlock()
+
+		index = newIndex
+	}
+
+	f()
+	// Notify caller that initialization is complete
+	initWG.Done()
+
+	checkInterval := getCheckInterval()
+	ticker := time.NewTicker(checkInterval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f()
+		case <-sw.stopCh:
+			return
+		}
+	}
+}
+
+// getServicesSnapshot returns a snapshot of discovered Services.
+func (nw *nomadWatcher) getServicesSnapshot() []Service {
+	nw.servicesLock.Lock()
+	var services []Service
+	for _, sw := range nw.services {
+		services = append(services, sw.services...)
+	}
+	nw.servicesLock.Unlock()
+	return services
+}
+
+func getCheckInterval() time.Duration {
+	d := *SDCheckInterval
+	if d <= time.Second {
+		return time.Second
+	}
+	return d
+}
@@ -0,0 +1,83 @@
+package nomad
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+)
+
+func TestGetLabels(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Nomad-Token") != "secret-token" {
+			http.Error(w, "Permission denied", http.StatusForbidden)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("region") != "eu" {
+			http.Error(w, "unexpected region", http.StatusBadRequest)
+			return
+		}
+		if _, ok := q["stale"]; !ok {
+			http.Error(w, "missing stale query arg", http.StatusBadRequest)
+			return
+		}
+		if q.Get("index") == "" || q.Get("wait") == "" {
+			http.Error(w, "missing blocking query args", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("X-Nomad-Index", "42")
+		switch r.URL.Path {
+		case "/v1/services":
+			if q.Get("namespace") != "*" {
+				http.Error(w, "unexpected namespace", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`[
+  {"Namespace": "default", "Services": [{"ServiceName": "api", "Tags": ["http"]}]},
+  {"Namespace": "dev", "Services": [{"ServiceName": "api", "Tags": []}]}
+]`))
+		case "/v1/service/api":
+			switch q.Get("namespace") {
+			case "default":
+				w.Write([]byte(`[{"ID": "api-1", "ServiceName": "api", "Namespace": "default", "Datacenter": "dc1", "Address": "10.0.0.1", "Port": 8080, "Tags": ["http"]}]`))
+			case "dev":
+				w.Write([]byte(`[{"ID": "api-2", "ServiceName": "api", "Namespace": "dev", "Datacenter": "dc1", "Address": "10.0.0.2", "Port": 9090}]`))
+			default:
+				http.Error(w, "unexpected namespace", http.StatusBadRequest)
+			}
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	tagSeparator := "|"
+	sdc := &SDConfig{
+		Server:       s.URL,
+		Token:        promauth.NewSecret("secret-token"),
+		Namespace:    "*",
+		Region:       "eu",
+		TagSeparator: &tagSeparator,
+	}
+	defer sdc.MustStop()
+	ms, err := sdc.GetLabels("")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var result []string
+	for _, m := range ms {
+		result = append(result, m["__address__"]+" "+m["__meta_nomad_namespace"]+" "+m["__meta_nomad_tags"])
+	}
+	sort.Strings(result)
+	expected := []string{
+		"10.0.0.1:8080 default |http|",
+		"10.0.0.2:9090 dev ||",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("unexpected targets;\ngot\n%q\nwant\n%q", result, expected)
+	}
+}
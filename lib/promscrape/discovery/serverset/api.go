@@ -0,0 +1,60 @@
+package serverset
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+// apiConfig contains config for ZooKeeper serverset discovery.
+type apiConfig struct {
+	zkWatcher *zkWatcher
+}
+
+func (ac *apiConfig) mustStop() {
+	ac.zkWatcher.mustStop()
+}
+
+var configMap = discoveryutils.NewConfigMap()
+
+func getAPIConfig(sdc *SDConfig) (*apiConfig, error) {
+	v, err := configMap.Get(sdc, func() (interface{}, error) { return newAPIConfig(sdc) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*apiConfig), nil
+}
+
+func newAPIConfig(sdc *SDConfig) (*apiConfig, error) {
+	if len(sdc.Servers) == 0 {
+		return nil, fmt.Errorf("`servers` cannot be empty in `serverset_sd_config`")
+	}
+	if len(sdc.Paths) == 0 {
+		return nil, fmt.Errorf("`paths` cannot be empty in `serverset_sd_config`")
+	}
+	paths := make([]string, len(sdc.Paths))
+	for i, path := range sdc.Paths {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("`paths` in `serverset_sd_config` must start with `/`; got %q", path)
+		}
+		// ZooKeeper doesn't accept paths with trailing slash.
+		if len(path) > 1 {
+			path = strings.TrimSuffix(path, "/")
+		}
+		paths[i] = path
+	}
+	timeout := sdc.Timeout.Duration()
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	zw, err := newZKWatcher(sdc.Servers, paths, timeout)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &apiConfig{
+		zkWatcher: zw,
+	}
+	return cfg, nil
+}
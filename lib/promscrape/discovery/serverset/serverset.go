@@ -0,0 +1,79 @@
+package serverset
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// SDConfig represents service discovery config for ZooKeeper serverset.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#serverset_sd_config
+type SDConfig struct {
+	Servers []string            `yaml:"servers"`
+	Paths   []string            `yaml:"paths"`
+	Timeout *promutils.Duration `yaml:"timeout,omitempty"`
+}
+
+// GetLabels returns serverset labels according to sdc.
+func (sdc *SDConfig) GetLabels(baseDir string) ([]map[string]string, error) {
+	cfg, err := getAPIConfig(sdc)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get API config: %w", err)
+	}
+	ms := cfg.zkWatcher.getLabelsSnapshot()
+	return ms, nil
+}
+
+// MustStop stops further usage for sdc.
+func (sdc *SDConfig) MustStop() {
+	v := configMap.Delete(sdc)
+	if v != nil {
+		// v can be nil if GetLabels wasn't called yet.
+		cfg := v.(*apiConfig)
+		cfg.mustStop()
+	}
+}
+
+// serversetMember is Finagle/Aurora serverset member stored in ZooKeeper znode.
+//
+// See https://github.com/twitter/finagle/blob/develop/finagle-serversets/src/main/scala/com/twitter/finagle/serverset2/Entry.scala
+type serversetMember struct {
+	ServiceEndpoint     serversetEndpoint
+	AdditionalEndpoints map[string]serversetEndpoint
+	Status              string
+	Shard               int
+}
+
+type serversetEndpoint struct {
+	Host string
+	Port int
+}
+
+func parseServersetMember(data []byte) (*serversetMember, error) {
+	var m serversetMember
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal serverset member from %q: %w", data, err)
+	}
+	return &m, nil
+}
+
+func (m *serversetMember) appendTargetLabels(ms []map[string]string, path string) []map[string]string {
+	labels := map[string]string{
+		"__address__":                    discoveryutils.JoinHostPort(m.ServiceEndpoint.Host, m.ServiceEndpoint.Port),
+		"__meta_serverset_path":          path,
+		"__meta_serverset_endpoint_host": m.ServiceEndpoint.Host,
+		"__meta_serverset_endpoint_port": strconv.Itoa(m.ServiceEndpoint.Port),
+		"__meta_serverset_status":        m.Status,
+		"__meta_serverset_shard":         strconv.Itoa(m.Shard),
+	}
+	for name, ep := range m.AdditionalEndpoints {
+		name = discoveryutils.SanitizeLabelName(name)
+		labels["__meta_serverset_endpoint_host_"+name] = ep.Host
+		labels["__meta_serverset_endpoint_port_"+name] = strconv.Itoa(ep.Port)
+	}
+	return append(ms, labels)
+}
@@ -0,0 +1,152 @@
+package serverset
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func TestParseServersetMemberFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		m, err := parseServersetMember([]byte(s))
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if m != nil {
+			t.Fatalf("unexpected non-nil member: %v", m)
+		}
+	}
+	f(``)
+	f(`[1,23]`)
+	f(`{"serviceEndpoint":{"host":1}}`)
+}
+
+func TestParseServersetMemberSuccess(t *testing.T) {
+	data := `{
+  "serviceEndpoint": {"host": "10.0.0.1", "port": 8080},
+  "additionalEndpoints": {
+    "admin": {"host": "10.0.0.1", "port": 9990},
+    "http-alt": {"host": "10.0.0.2", "port": 8081}
+  },
+  "status": "ALIVE",
+  "shard": 2
+}`
+	m, err := parseServersetMember([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	labelss := m.appendTargetLabels(nil, "/aurora/jobs/web/member_0000000001")
+	var sortedLabelss [][]prompbmarshal.Label
+	for _, labels := range labelss {
+		sortedLabelss = append(sortedLabelss, discoveryutils.GetSortedLabels(labels))
+	}
+	expectedLabelss := [][]prompbmarshal.Label{
+		discoveryutils.GetSortedLabels(map[string]string{
+			"__address__":                             "10.0.0.1:8080",
+			"__meta_serverset_path":                   "/aurora/jobs/web/member_0000000001",
+			"__meta_serverset_endpoint_host":          "10.0.0.1",
+			"__meta_serverset_endpoint_port":          "8080",
+			"__meta_serverset_endpoint_host_admin":    "10.0.0.1",
+			"__meta_serverset_endpoint_port_admin":    "9990",
+			"__meta_serverset_endpoint_host_http_alt": "10.0.0.2",
+			"__meta_serverset_endpoint_port_http_alt": "8081",
+			"__meta_serverset_status":                 "ALIVE",
+			"__meta_serverset_shard":                  "2",
+		}),
+	}
+	if !reflect.DeepEqual(sortedLabelss, expectedLabelss) {
+		t.Fatalf("unexpected labels:\ngot\n%v\nwant\n%v", sortedLabelss, expectedLabelss)
+	}
+}
+
+func TestNewAPIConfigFailure(t *testing.T) {
+	f := func(sdc *SDConfig) {
+		t.Helper()
+		cfg, err := newAPIConfig(sdc)
+		if err == nil {
+			cfg.mustStop()
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// Missing servers
+	f(&SDConfig{
+		Paths: []string{"/foo"},
+	})
+	// Missing paths
+	f(&SDConfig{
+		Servers: []string{"localhost:2181"},
+	})
+	// Relative path
+	f(&SDConfig{
+		Servers: []string{"localhost:2181"},
+		Paths:   []string{"foo"},
+	})
+	// Unavailable server
+	f(&SDConfig{
+		Servers: []string{"127.0.0.1:1"},
+		Paths:   []string{"/foo"},
+		Timeout: promutils.NewDuration(time.Second),
+	})
+}
+
+func TestGetLabels(t *testing.T) {
+	s := newZKStub(t, map[string]string{
+		"/web":                   "",
+		"/web/member_0000000001": `{"serviceEndpoint":{"host":"10.0.0.1","port":80},"status":"ALIVE","shard":0}`,
+		"/web/member_0000000002": `invalid json`,
+		"/web/member_0000000003": ``,
+		"/api":                   "",
+		"/api/member_0000000001": `{"serviceEndpoint":{"host":"10.0.0.3","port":81},"status":"STARTING","shard":1}`,
+	})
+	defer s.close()
+
+	sdc := &SDConfig{
+		Servers: []string{s.addr()},
+		Paths:   []string{"/web", "/api/", "/missing"},
+	}
+	defer sdc.MustStop()
+	getAddrs := func() []string {
+		t.Helper()
+		ms, err := sdc.GetLabels("")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var addrs []string
+		for _, m := range ms {
+			addrs = append(addrs, m["__address__"]+" "+m["__meta_serverset_path"]+" "+m["__meta_serverset_status"])
+		}
+		return addrs
+	}
+	addrs := getAddrs()
+	expectedAddrs := []string{
+		"10.0.0.1:80 /web/member_0000000001 ALIVE",
+		"10.0.0.3:81 /api/member_0000000001 STARTING",
+	}
+	if !reflect.DeepEqual(addrs, expectedAddrs) {
+		t.Fatalf("unexpected targets;\ngot\n%q\nwant\n%q", addrs, expectedAddrs)
+	}
+
+	// Verify that the targets are updated after watch events.
+	s.setNode("/web/member_0000000004", `{"serviceEndpoint":{"host":"10.0.0.4","port":80},"status":"ALIVE","shard":1}`)
+	s.deleteNode("/api/member_0000000001")
+	expectedAddrs = []string{
+		"10.0.0.1:80 /web/member_0000000001 ALIVE",
+		"10.0.0.4:80 /web/member_0000000004 ALIVE",
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		addrs = getAddrs()
+		if reflect.DeepEqual(addrs, expectedAddrs) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected targets after update;\ngot\n%q\nwant\n%q", addrs, expectedAddrs)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
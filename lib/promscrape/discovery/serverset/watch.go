@@ -0,0 +1,160 @@
+package serverset
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// SDCheckInterval is check interval for serverset service discovery.
+var SDCheckInterval = flag.Duration("promscrape.serversetSDCheckInterval", 30*time.Second, "Interval for re-reading serverset members from ZooKeeper "+
+	"in addition to ZooKeeper watches. This works only if serverset_sd_configs is configured in '-promscrape.config' file. "+
+	"See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#serverset_sd_config for details")
+
+// zkWatcher watches for serverset members at the given ZooKeeper paths.
+//
+// Watches are set on the watched znodes and on all their children, so members are re-read
+// as soon as they are added, updated or removed.
+type zkWatcher struct {
+	servers []string
+	paths   []string
+	timeout time.Duration
+
+	// labelssLock protects labelss
+	labelssLock sync.Mutex
+	labelss     []map[string]string
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+// newZKWatcher connects to ZooKeeper, reads serverset members at paths and starts watching for their updates.
+func newZKWatcher(servers, paths []string, timeout time.Duration) (*zkWatcher, error) {
+	c, err := dialZK(servers, timeout)
+	if err != nil {
+		return nil, err
+	}
+	zw := &zkWatcher{
+		servers: servers,
+		paths:   paths,
+		timeout: timeout,
+		stopCh:  make(chan struct{}),
+	}
+	if err := zw.refresh(c); err != nil {
+		c.close()
+		return nil, err
+	}
+	zw.wg.Add(1)
+	go func() {
+		defer zw.wg.Done()
+		zw.watchForUpdates(c)
+	}()
+	return zw, nil
+}
+
+func (zw *zkWatcher) mustStop() {
+	close(zw.stopCh)
+	zw.wg.Wait()
+}
+
+// watchForUpdates re-reads serverset members when ZooKeeper watches are triggered and every SDCheckInterval.
+//
+// It re-establishes ZooKeeper session if the connection is lost.
+func (zw *zkWatcher) watchForUpdates(c *zkConn) {
+	ticker := time.NewTicker(getCheckInterval())
+	defer ticker.Stop()
+	for {
+		var eventCh, doneCh chan struct{}
+		if c != nil {
+			eventCh = c.eventCh
+			doneCh = c.doneCh
+		}
+		select {
+		case <-zw.stopCh:
+			if c != nil {
+				c.close()
+			}
+			return
+		case <-eventCh:
+		case <-doneCh:
+			logger.Errorf("lost connection to ZooKeeper at %q: %s; reconnecting", c.addr, c.closeErr)
+			c.close()
+			c = nil
+		case <-ticker.C:
+		}
+		if c == nil {
+			cNew, err := dialZK(zw.servers, zw.timeout)
+			if err != nil {
+				logger.Errorf("%s", err)
+				continue
+			}
+			c = cNew
+		}
+		if err := zw.refresh(c); err != nil {
+			logger.Errorf("cannot update serverset members from ZooKeeper at %q: %s", c.addr, err)
+			c.close()
+			c = nil
+		}
+	}
+}
+
+// refresh reads serverset members from ZooKeeper via c and sets watches on the read znodes.
+func (zw *zkWatcher) refresh(c *zkConn) error {
+	var ms []map[string]string
+	for _, path := range zw.paths {
+		children, err := c.getChildren(path, true)
+		if err != nil {
+			if err == errNoNode {
+				// The path may be created later. It will be checked again after SDCheckInterval.
+				continue
+			}
+			return fmt.Errorf("cannot obtain children for %q: %w", path, err)
+		}
+		sort.Strings(children)
+		for _, child := range children {
+			childPath := strings.TrimSuffix(path, "/") + "/" + child
+			data, err := c.getData(childPath, true)
+			if err != nil {
+				if err == errNoNode {
+					// The member has been removed after reading the children.
+					continue
+				}
+				return fmt.Errorf("cannot obtain data for %q: %w", childPath, err)
+			}
+			if len(data) == 0 {
+				continue
+			}
+			m, err := parseServersetMember(data)
+			if err != nil {
+				logger.Errorf("skipping serverset member at %q: %s", childPath, err)
+				continue
+			}
+			ms = m.appendTargetLabels(ms, childPath)
+		}
+	}
+	zw.labelssLock.Lock()
+	zw.labelss = ms
+	zw.labelssLock.Unlock()
+	return nil
+}
+
+// getLabelsSnapshot returns labels for the discovered serverset members.
+func (zw *zkWatcher) getLabelsSnapshot() []map[string]string {
+	zw.labelssLock.Lock()
+	labelss := zw.labelss
+	zw.labelssLock.Unlock()
+	return labelss
+}
+
+func getCheckInterval() time.Duration {
+	d := *SDCheckInterval
+	if d <= time.Second {
+		return time.Second
+	}
+	return d
+}
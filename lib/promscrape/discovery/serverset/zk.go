@@ -0,0 +1,416 @@
+package serverset
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// This file contains a minimal ZooKeeper client, which supports only the operations needed for serverset discovery:
+// reading znode children and znode data with optional watches.
+//
+// See https://zookeeper.apache.org/doc/current/zookeeperProgrammers.html
+// and https://github.com/apache/zookeeper/blob/master/zookeeper-jute/src/main/resources/zookeeper.jute for the wire protocol.
+
+// ZooKeeper operation codes.
+const (
+	opGetData     = 4
+	opGetChildren = 8
+	opPing        = 11
+)
+
+// Special xid values used by ZooKeeper.
+const (
+	xidWatchEvent = -1
+	xidPing       = -2
+)
+
+// errNoNode is returned when the requested znode doesn't exist.
+var errNoNode = errors.New("znode doesn't exist")
+
+// zkErrNoNode is ZooKeeper error code for missing znode.
+const zkErrNoNode = -101
+
+// maxPacketSize limits the size of a single packet received from ZooKeeper.
+const maxPacketSize = 4 * 1024 * 1024
+
+type zkResponse struct {
+	errCode int32
+	data    []byte
+}
+
+// zkConn is a connection to ZooKeeper server with a single session.
+type zkConn struct {
+	addr           string
+	conn           net.Conn
+	sessionTimeout time.Duration
+
+	// wmu protects conn writes and xid.
+	wmu sync.Mutex
+	xid int32
+
+	pendingLock sync.Mutex
+	pending     map[int32]chan zkResponse
+
+	// eventCh is notified when any watch set via the connection is triggered.
+	eventCh chan struct{}
+
+	// doneCh is closed when the connection is closed.
+	doneCh    chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+
+	wg sync.WaitGroup
+}
+
+// dialZK establishes a new session with one of the given ZooKeeper servers.
+func dialZK(servers []string, timeout time.Duration) (*zkConn, error) {
+	var errs []string
+	for _, server := range servers {
+		c, err := dialZKServer(server, timeout)
+		if err == nil {
+			return c, nil
+		}
+		errs = append(errs, err.Error())
+	}
+	return nil, fmt.Errorf("cannot connect to any of ZooKeeper servers %q: %s", servers, errs)
+}
+
+func dialZKServer(addr string, timeout time.Duration) (*zkConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to %q: %w", addr, err)
+	}
+	sessionTimeout, err := zkHandshake(conn, timeout)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("cannot establish ZooKeeper session with %q: %w", addr, err)
+	}
+	c := &zkConn{
+		addr:           addr,
+		conn:           conn,
+		sessionTimeout: sessionTimeout,
+		pending:        make(map[int32]chan zkResponse),
+		eventCh:        make(chan struct{}, 1),
+		doneCh:         make(chan struct{}),
+	}
+	c.wg.Add(2)
+	go func() {
+		defer c.wg.Done()
+		c.readLoop()
+	}()
+	go func() {
+		defer c.wg.Done()
+		c.pingLoop()
+	}()
+	return c, nil
+}
+
+// zkHandshake sends ConnectRequest and reads ConnectResponse. It returns the session timeout negotiated with the server.
+func zkHandshake(conn net.Conn, timeout time.Duration) (time.Duration, error) {
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return 0, err
+	}
+	var b []byte
+	b = appendInt32(b, 0) // protocolVersion
+	b = appendInt64(b, 0) // lastZxidSeen
+	b = appendInt32(b, int32(timeout/time.Millisecond))
+	b = appendInt64(b, 0)                 // sessionId
+	b = appendBuffer(b, make([]byte, 16)) // passwd
+	b = append(b, 0)                      // readOnly
+	if err := writePacket(conn, b); err != nil {
+		return 0, err
+	}
+	data, err := readPacket(conn)
+	if err != nil {
+		return 0, err
+	}
+	r := &zkReader{b: data}
+	_ = r.int32() // protocolVersion
+	sessionTimeoutMsecs := r.int32()
+	if r.err != nil {
+		return 0, fmt.Errorf("cannot parse connect response: %w", r.err)
+	}
+	if sessionTimeoutMsecs <= 0 {
+		return 0, fmt.Errorf("the server refused to create a session")
+	}
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return 0, err
+	}
+	return time.Duration(sessionTimeoutMsecs) * time.Millisecond, nil
+}
+
+// close closes c and waits until its background goroutines are stopped.
+func (c *zkConn) close() {
+	c.closeWithError(errors.New("connection is closed"))
+	c.wg.Wait()
+}
+
+func (c *zkConn) closeWithError(err error) {
+	c.closeOnce.Do(func() {
+		c.pendingLock.Lock()
+		c.closeErr = err
+		close(c.doneCh)
+		c.pendingLock.Unlock()
+		_ = c.conn.Close()
+	})
+}
+
+// getChildren returns names of children for the znode at the given path.
+//
+// If watch is set, then c.eventCh is notified when the list of children changes.
+func (c *zkConn) getChildren(path string, watch bool) ([]string, error) {
+	data, err := c.doRequest(opGetChildren, path, watch)
+	if err != nil {
+		return nil, err
+	}
+	r := &zkReader{b: data}
+	n := r.int32()
+	var children []string
+	for i := int32(0); i < n && r.err == nil; i++ {
+		children = append(children, r.string())
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("cannot parse children for %q: %w", path, r.err)
+	}
+	return children, nil
+}
+
+// getData returns data for the znode at the given path.
+//
+// If watch is set, then c.eventCh is notified when the znode data changes or the znode is deleted.
+func (c *zkConn) getData(path string, watch bool) ([]byte, error) {
+	data, err := c.doRequest(opGetData, path, watch)
+	if err != nil {
+		return nil, err
+	}
+	r := &zkReader{b: data}
+	// The Stat structure follows the data. It isn't needed, so it is ignored.
+	b := r.buffer()
+	if r.err != nil {
+		return nil, fmt.Errorf("cannot parse data for %q: %w", path, r.err)
+	}
+	return b, nil
+}
+
+func (c *zkConn) doRequest(opCode int32, path string, watch bool) ([]byte, error) {
+	ch := make(chan zkResponse, 1)
+	c.wmu.Lock()
+	c.xid++
+	xid := c.xid
+	c.pendingLock.Lock()
+	if c.closeErr != nil {
+		err := c.closeErr
+		c.pendingLock.Unlock()
+		c.wmu.Unlock()
+		return nil, err
+	}
+	c.pending[xid] = ch
+	c.pendingLock.Unlock()
+	var b []byte
+	b = appendInt32(b, xid)
+	b = appendInt32(b, opCode)
+	b = appendString(b, path)
+	b = appendBool(b, watch)
+	err := c.writePacket(b)
+	c.wmu.Unlock()
+	if err != nil {
+		c.closeWithError(err)
+	}
+
+	t := time.NewTimer(c.sessionTimeout)
+	defer t.Stop()
+	select {
+	case resp := <-ch:
+		switch resp.errCode {
+		case 0:
+			return resp.data, nil
+		case zkErrNoNode:
+			return nil, errNoNode
+		default:
+			return nil, fmt.Errorf("ZooKeeper at %q returned error code %d for %q", c.addr, resp.errCode, path)
+		}
+	case <-c.doneCh:
+		return nil, fmt.Errorf("ZooKeeper connection to %q is closed: %w", c.addr, c.closeErr)
+	case <-t.C:
+		err := fmt.Errorf("timeout when waiting for response from ZooKeeper at %q for %q", c.addr, path)
+		c.closeWithError(err)
+		return nil, err
+	}
+}
+
+func (c *zkConn) writePacket(b []byte) error {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(c.sessionTimeout)); err != nil {
+		return err
+	}
+	return writePacket(c.conn, b)
+}
+
+func (c *zkConn) readLoop() {
+	br := bufio.NewReader(c.conn)
+	for {
+		// ZooKeeper server responds to pings sent every sessionTimeout/3, so the connection must be broken
+		// if nothing is received during sessionTimeout.
+		if err := c.conn.SetReadDeadline(time.Now().Add(c.sessionTimeout)); err != nil {
+			c.closeWithError(err)
+			return
+		}
+		data, err := readPacket(br)
+		if err != nil {
+			c.closeWithError(fmt.Errorf("cannot read packet: %w", err))
+			return
+		}
+		r := &zkReader{b: data}
+		xid := r.int32()
+		_ = r.int64() // zxid
+		errCode := r.int32()
+		if r.err != nil {
+			c.closeWithError(fmt.Errorf("cannot parse reply header: %w", r.err))
+			return
+		}
+		switch xid {
+		case xidPing:
+		case xidWatchEvent:
+			select {
+			case c.eventCh <- struct{}{}:
+			default:
+			}
+		default:
+			c.pendingLock.Lock()
+			ch := c.pending[xid]
+			delete(c.pending, xid)
+			c.pendingLock.Unlock()
+			if ch != nil {
+				ch <- zkResponse{
+					errCode: errCode,
+					data:    r.b,
+				}
+			}
+		}
+	}
+}
+
+func (c *zkConn) pingLoop() {
+	ticker := time.NewTicker(c.sessionTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var b []byte
+			b = appendInt32(b, xidPing)
+			b = appendInt32(b, opPing)
+			c.wmu.Lock()
+			err := c.writePacket(b)
+			c.wmu.Unlock()
+			if err != nil {
+				c.closeWithError(fmt.Errorf("cannot send ping: %w", err))
+				return
+			}
+		case <-c.doneCh:
+			return
+		}
+	}
+}
+
+func writePacket(w io.Writer, b []byte) error {
+	packet := appendInt32(make([]byte, 0, 4+len(b)), int32(len(b)))
+	packet = append(packet, b...)
+	_, err := w.Write(packet)
+	return err
+}
+
+func readPacket(r io.Reader) ([]byte, error) {
+	var sizeBuf [4]byte
+	if _, err := io.ReadFull(r, sizeBuf[:]); err != nil {
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(sizeBuf[:])
+	if size > maxPacketSize {
+		return nil, fmt.Errorf("too big packet size: %d bytes; mustn't exceed %d bytes", size, maxPacketSize)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func appendInt32(b []byte, n int32) []byte {
+	return append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendInt64(b []byte, n int64) []byte {
+	return append(b, byte(n>>56), byte(n>>48), byte(n>>40), byte(n>>32), byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendInt32(b, int32(len(s)))
+	return append(b, s...)
+}
+
+func appendBuffer(b, buf []byte) []byte {
+	b = appendInt32(b, int32(len(buf)))
+	return append(b, buf...)
+}
+
+// zkReader reads values encoded with ZooKeeper jute serialization.
+//
+// The first error is stored in err, while the subsequent reads return zero values.
+type zkReader struct {
+	b   []byte
+	err error
+}
+
+func (r *zkReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = fmt.Errorf("unexpected end of packet; want %d bytes; got %d bytes", n, len(r.b))
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *zkReader) int32() int32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(b))
+}
+
+func (r *zkReader) int64() int64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(b))
+}
+
+func (r *zkReader) buffer() []byte {
+	n := r.int32()
+	if n < 0 {
+		// null buffer
+		return nil
+	}
+	return r.next(int(n))
+}
+
+func (r *zkReader) string() string {
+	return string(r.buffer())
+}
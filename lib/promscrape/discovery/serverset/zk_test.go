@@ -0,0 +1,260 @@
+package serverset
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// zkStub is a stub ZooKeeper server, which supports only the operations used by zkConn.
+type zkStub struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	nodes map[string][]byte
+	conns []*zkStubConn
+}
+
+type zkStubConn struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (sc *zkStubConn) writePacket(b []byte) {
+	sc.mu.Lock()
+	_ = writePacket(sc.conn, b)
+	sc.mu.Unlock()
+}
+
+func newZKStub(t *testing.T, nodes map[string]string) *zkStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start ZooKeeper stub: %s", err)
+	}
+	s := &zkStub{
+		ln:    ln,
+		nodes: make(map[string][]byte),
+	}
+	for path, data := range nodes {
+		s.nodes[path] = []byte(data)
+	}
+	go s.serve()
+	return s
+}
+
+func (s *zkStub) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *zkStub) close() {
+	_ = s.ln.Close()
+	s.mu.Lock()
+	for _, sc := range s.conns {
+		_ = sc.conn.Close()
+	}
+	s.mu.Unlock()
+}
+
+// setNode creates or updates the node at the given path and notifies all the clients.
+func (s *zkStub) setNode(path, data string) {
+	s.mu.Lock()
+	s.nodes[path] = []byte(data)
+	s.mu.Unlock()
+	s.notify(path)
+}
+
+// deleteNode deletes the node at the given path and notifies all the clients.
+func (s *zkStub) deleteNode(path string) {
+	s.mu.Lock()
+	delete(s.nodes, path)
+	s.mu.Unlock()
+	s.notify(path)
+}
+
+func (s *zkStub) notify(path string) {
+	var b []byte
+	b = appendInt32(b, xidWatchEvent)
+	b = appendInt64(b, -1)
+	b = appendInt32(b, 0)
+	b = appendInt32(b, 4) // NodeChildrenChanged
+	b = appendInt32(b, 3) // SyncConnected
+	b = appendString(b, path)
+	s.mu.Lock()
+	conns := append([]*zkStubConn{}, s.conns...)
+	s.mu.Unlock()
+	for _, sc := range conns {
+		sc.writePacket(b)
+	}
+}
+
+func (s *zkStub) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		sc := &zkStubConn{
+			conn: conn,
+		}
+		s.mu.Lock()
+		s.conns = append(s.conns, sc)
+		s.mu.Unlock()
+		go s.handleConn(sc)
+	}
+}
+
+func (s *zkStub) handleConn(sc *zkStubConn) {
+	if _, err := readPacket(sc.conn); err != nil {
+		return
+	}
+	var b []byte
+	b = appendInt32(b, 0)    // protocolVersion
+	b = appendInt32(b, 3000) // timeOut
+	b = appendInt64(b, 1)    // sessionId
+	b = appendBuffer(b, make([]byte, 16))
+	sc.writePacket(b)
+	for {
+		data, err := readPacket(sc.conn)
+		if err != nil {
+			return
+		}
+		r := &zkReader{b: data}
+		xid := r.int32()
+		opCode := r.int32()
+		if opCode == opPing {
+			sc.writePacket(s.replyHeader(nil, xid, 0))
+			continue
+		}
+		path := r.string()
+		s.mu.Lock()
+		nodeData, ok := s.nodes[path]
+		var children []string
+		prefix := strings.TrimSuffix(path, "/") + "/"
+		for p := range s.nodes {
+			if strings.HasPrefix(p, prefix) && !strings.Contains(p[len(prefix):], "/") {
+				children = append(children, p[len(prefix):])
+			}
+		}
+		s.mu.Unlock()
+		if !ok {
+			sc.writePacket(s.replyHeader(nil, xid, zkErrNoNode))
+			continue
+		}
+		b := s.replyHeader(nil, xid, 0)
+		switch opCode {
+		case opGetChildren:
+			sort.Strings(children)
+			b = appendInt32(b, int32(len(children)))
+			for _, child := range children {
+				b = appendString(b, child)
+			}
+		case opGetData:
+			b = appendBuffer(b, nodeData)
+			b = append(b, make([]byte, 68)...) // Stat
+		default:
+			b = s.replyHeader(nil, xid, -6) // Unimplemented
+		}
+		sc.writePacket(b)
+	}
+}
+
+func (s *zkStub) replyHeader(b []byte, xid, errCode int32) []byte {
+	b = appendInt32(b, xid)
+	b = appendInt64(b, 1)
+	return appendInt32(b, errCode)
+}
+
+func TestZKConn(t *testing.T) {
+	s := newZKStub(t, map[string]string{
+		"/services":       "",
+		"/services/foo":   "foo-data",
+		"/services/bar":   "",
+		"/services/bar/x": "x-data",
+	})
+	defer s.close()
+
+	c, err := dialZK([]string{"127.0.0.1:1", s.addr()}, time.Second)
+	if err != nil {
+		t.Fatalf("cannot connect to ZooKeeper stub: %s", err)
+	}
+	defer c.close()
+	if c.sessionTimeout != 3*time.Second {
+		t.Fatalf("unexpected session timeout; got %s; want %s", c.sessionTimeout, 3*time.Second)
+	}
+
+	children, err := c.getChildren("/services", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(children, []string{"bar", "foo"}) {
+		t.Fatalf("unexpected children: %q", children)
+	}
+	data, err := c.getData("/services/foo", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(data) != "foo-data" {
+		t.Fatalf("unexpected data; got %q; want %q", data, "foo-data")
+	}
+	if _, err := c.getData("/missing", false); err != errNoNode {
+		t.Fatalf("unexpected error for missing znode; got %v; want %v", err, errNoNode)
+	}
+
+	// Verify that watch events are delivered.
+	s.deleteNode("/services/foo")
+	select {
+	case <-c.eventCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout when waiting for watch event")
+	}
+
+	// Verify that requests fail after the connection is closed.
+	s.close()
+	select {
+	case <-c.doneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout when waiting for connection close")
+	}
+	if _, err := c.getChildren("/services", false); err == nil {
+		t.Fatalf("expecting non-nil error for closed connection")
+	}
+}
+
+func TestDialZKFailure(t *testing.T) {
+	if _, err := dialZK([]string{"127.0.0.1:1"}, time.Second); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestZKReader(t *testing.T) {
+	var b []byte
+	b = appendInt32(b, -5)
+	b = appendInt64(b, 1<<40)
+	b = appendString(b, "foo")
+	b = appendInt32(b, -1) // null buffer
+	r := &zkReader{b: b}
+	if n := r.int32(); n != -5 {
+		t.Fatalf("unexpected int32; got %d; want %d", n, -5)
+	}
+	if n := r.int64(); n != 1<<40 {
+		t.Fatalf("unexpected int64; got %d; want %d", n, int64(1<<40))
+	}
+	if s := r.string(); s != "foo" {
+		t.Fatalf("unexpected string; got %q; want %q", s, "foo")
+	}
+	if buf := r.buffer(); buf != nil {
+		t.Fatalf("unexpected non-nil buffer: %q", buf)
+	}
+	if r.err != nil {
+		t.Fatalf("unexpected error: %s", r.err)
+	}
+	_ = r.int32()
+	if r.err == nil {
+		t.Fatalf("expecting non-nil error when reading past the end of packet")
+	}
+}
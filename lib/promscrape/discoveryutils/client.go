@@ -189,6 +189,12 @@ func (c *Client) GetBlockingAPIResponse(path string, inspectResponse func(resp *
 	return c.getAPIResponseWithParamsAndClient(c.blockingClient, path, nil, inspectResponse)
 }
 
+// GetBlockingAPIResponseWithReqParams returns response for given absolute path with blocking client and optional callbacks for api request and response,
+// modifyRequest - should never reference data from request, inspectResponse - should never reference data from response.
+func (c *Client) GetBlockingAPIResponseWithReqParams(path string, modifyRequest func(req *fasthttp.Request), inspectResponse func(resp *fasthttp.Response)) ([]byte, error) {
+	return c.getAPIResponseWithParamsAndClient(c.blockingClient, path, modifyRequest, inspectResponse)
+}
+
 // getAPIResponseWithParamsAndClient returns response for the given absolute path with optional callback for request and for response.
 func (c *Client) getAPIResponseWithParamsAndClient(client *fasthttp.HostClient, path string, modifyRequest func(req *fasthttp.Request), inspectResponse func(resp *fasthttp.Response)) ([]byte, error) {
 	requestURL := c.apiServer + path
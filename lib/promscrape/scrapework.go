@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bloomfilter"
@@ -107,6 +108,12 @@ type ScrapeWork struct {
 	// Whether to disable HTTP keep-alive when querying ScrapeURL.
 	DisableKeepAlive bool
 
+	// Whether to request Prometheus protobuf exposition format when querying ScrapeURL.
+	EnableProtobufNegotiation bool
+
+	// Whether to strictly validate responses in OpenMetrics text format.
+	OpenMetricsStrictMode bool
+
 	// Whether to parse target responses in a streaming manner.
 	StreamParse bool
 
@@ -136,11 +143,12 @@ func (sw *ScrapeWork) key() string {
 	// Do not take into account OriginalLabels, since they can be changed with relabeling.
 	// Take into account JobNameOriginal in order to capture the case when the original job_name is changed via relabeling.
 	key := fmt.Sprintf("JobNameOriginal=%s, ScrapeURL=%s, ScrapeInterval=%s, ScrapeTimeout=%s, HonorLabels=%v, HonorTimestamps=%v, DenyRedirects=%v, Labels=%s, "+
-		"ProxyURL=%s, ProxyAuthConfig=%s, AuthConfig=%s, MetricRelabelConfigs=%s, SampleLimit=%d, DisableCompression=%v, DisableKeepAlive=%v, StreamParse=%v, "+
-		"ScrapeAlignInterval=%s, ScrapeOffset=%s, SeriesLimit=%d",
+		"ProxyURL=%s, ProxyAuthConfig=%s, AuthConfig=%s, MetricRelabelConfigs=%s, SampleLimit=%d, DisableCompression=%v, DisableKeepAlive=%v, "+
+		"EnableProtobufNegotiation=%v, OpenMetricsStrictMode=%v, StreamParse=%v, ScrapeAlignInterval=%s, ScrapeOffset=%s, SeriesLimit=%d",
 		sw.jobNameOriginal, sw.ScrapeURL, sw.ScrapeInterval, sw.ScrapeTimeout, sw.HonorLabels, sw.HonorTimestamps, sw.DenyRedirects, sw.LabelsString(),
 		sw.ProxyURL.String(), sw.ProxyAuthConfig.String(),
-		sw.AuthConfig.String(), sw.MetricRelabelConfigs.String(), sw.SampleLimit, sw.DisableCompression, sw.DisableKeepAlive, sw.StreamParse,
+		sw.AuthConfig.String(), sw.MetricRelabelConfigs.String(), sw.SampleLimit, sw.DisableCompression, sw.DisableKeepAlive,
+		sw.EnableProtobufNegotiation, sw.OpenMetricsStrictMode, sw.StreamParse,
 		sw.ScrapeAlignInterval, sw.ScrapeOffset, sw.SeriesLimit)
 	return key
 }
@@ -231,6 +239,9 @@ type scrapeWork struct {
 
 	// errsSuppressedCount is the number of suppressed scrape errors since lastErrLogTimestamp
 	errsSuppressedCount int
+
+	// lastMetadataPushTime is the timestamp in unix seconds of the last metric metadata push for the given scrape target.
+	lastMetadataPushTime uint64
 }
 
 func (sw *scrapeWork) loadLastScrape() string {
@@ -441,11 +452,15 @@ func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error
 	lastScrape := sw.loadLastScrape()
 	bodyString := bytesutil.ToUnsafeString(body.B)
 	areIdenticalSeries := *noStaleMarkers || parser.AreIdenticalSeriesFast(lastScrape, bodyString)
+	parseErrors := 0
 	if err != nil {
 		up = 0
 		scrapesFailed.Inc()
 	} else {
-		wc.rows.UnmarshalWithErrLogger(bodyString, sw.logError)
+		wc.rows.UnmarshalWithErrLogger(bodyString, func(s string) {
+			parseErrors++
+			sw.logError(s)
+		})
 	}
 	srcRows := wc.rows.Rows
 	samplesScraped := len(srcRows)
@@ -482,6 +497,9 @@ func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error
 	sw.addAutoTimeseries(wc, "scrape_samples_post_metric_relabeling", float64(samplesPostRelabeling), scrapeTimestamp)
 	sw.addAutoTimeseries(wc, "scrape_series_added", float64(seriesAdded), scrapeTimestamp)
 	sw.addAutoTimeseries(wc, "scrape_timeout_seconds", sw.Config.ScrapeTimeout.Seconds(), scrapeTimestamp)
+	if up == 1 {
+		sw.addMetadata(wc, bodyString, areIdenticalSeries)
+	}
 	sw.pushData(&wc.writeRequest)
 	sw.prevLabelsLen = len(wc.labels)
 	sw.prevBodyLen = len(bodyString)
@@ -505,7 +523,7 @@ func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error
 		// This should reduce memory usage when scraping targets which return big responses.
 		leveledbytebufferpool.Put(body)
 	}
-	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(duration*1000), samplesScraped, err)
+	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(duration*1000), samplesScraped, parseErrors, err)
 	return err
 }
 
@@ -534,6 +552,7 @@ func (sbr *streamBodyReader) Read(b []byte) (int, error) {
 func (sw *scrapeWork) scrapeStream(scrapeTimestamp, realTimestamp int64) error {
 	samplesScraped := 0
 	samplesPostRelabeling := 0
+	parseErrors := uint64(0)
 	wc := writeRequestCtxPool.Get(sw.prevLabelsLen)
 	// Do not pool sbr and do not pre-allocate sbr.body in order to reduce memory usage when scraping big responses.
 	sbr := &streamBodyReader{
@@ -566,7 +585,11 @@ func (sw *scrapeWork) scrapeStream(scrapeTimestamp, realTimestamp int64) error {
 			sw.pushData(&wc.writeRequest)
 			wc.resetNoRows()
 			return nil
-		}, sw.logError)
+		}, func(s string) {
+			// The callback may be called concurrently from multiple goroutines.
+			atomic.AddUint64(&parseErrors, 1)
+			sw.logError(s)
+		})
 		sr.MustClose()
 	}
 	lastScrape := sw.loadLastScrape()
@@ -610,7 +633,7 @@ func (sw *scrapeWork) scrapeStream(scrapeTimestamp, realTimestamp int64) error {
 		sw.storeLastScrape(sbr.body)
 	}
 	sw.finalizeLastScrape()
-	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(duration*1000), samplesScraped, err)
+	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(duration*1000), samplesScraped, int(atomic.LoadUint64(&parseErrors)), err)
 	// Do not track active series in streaming mode, since this may need too big amounts of memory
 	// when the target exports too big number of metrics.
 	return err
@@ -667,6 +690,11 @@ type writeRequestCtx struct {
 	writeRequest prompbmarshal.WriteRequest
 	labels       []prompbmarshal.Label
 	samples      []prompbmarshal.Sample
+
+	exemplars      []prompbmarshal.Exemplar
+	exemplarLabels []prompbmarshal.Label
+
+	metadata []parser.Metadata
 }
 
 func (wc *writeRequestCtx) reset() {
@@ -678,10 +706,50 @@ func (wc *writeRequestCtx) resetNoRows() {
 	prompbmarshal.ResetWriteRequest(&wc.writeRequest)
 	wc.labels = wc.labels[:0]
 	wc.samples = wc.samples[:0]
+	wc.exemplars = wc.exemplars[:0]
+	wc.exemplarLabels = wc.exemplarLabels[:0]
+	for i := range wc.metadata {
+		wc.metadata[i] = parser.Metadata{}
+	}
+	wc.metadata = wc.metadata[:0]
 }
 
 var writeRequestCtxPool leveledWriteRequestCtxPool
 
+// metadataPushInterval is the interval for re-sending metric metadata for scrape targets with unchanged set of series.
+//
+// Metadata is sent on every scrape when the set of series changes, since this may indicate new metric families.
+const metadataPushInterval = 3600
+
+// addMetadata adds metric metadata from `# TYPE`, `# HELP` and `# UNIT` comments in bodyString to wc.
+//
+// Metadata isn't collected in stream parsing mode.
+func (sw *scrapeWork) addMetadata(wc *writeRequestCtx, bodyString string, areIdenticalSeries bool) {
+	currentTime := fasttime.UnixTimestamp()
+	if areIdenticalSeries && currentTime-sw.lastMetadataPushTime < metadataPushInterval {
+		return
+	}
+	wc.metadata = parser.ParseMetadata(wc.metadata[:0], bodyString)
+	if len(wc.metadata) == 0 {
+		return
+	}
+	sw.lastMetadataPushTime = currentTime
+	mms := wc.writeRequest.Metadata[:0]
+	for i := range wc.metadata {
+		md := &wc.metadata[i]
+		mms = append(mms, prompbmarshal.MetricMetadata{
+			Type:             prompbmarshal.MetricTypeFromString(md.Type),
+			MetricFamilyName: md.Metric,
+			Help:             md.Help,
+			Unit:             md.Unit,
+		})
+	}
+	wc.writeRequest.Metadata = mms
+	metadataScraped.Add(len(mms))
+}
+
+var metadataScraped = metrics.NewCounter(`vm_promscrape_metadata_scraped_total`)
+
 func (sw *scrapeWork) getSeriesAdded(lastScrape, currScrape string) int {
 	if currScrape == "" {
 		return 0
@@ -758,11 +826,14 @@ func (sw *scrapeWork) sendStaleSeries(lastScrape, currScrape string, timestamp i
 		return
 	}
 	// Substitute all the values with Prometheus stale markers.
-	for _, tss := range series {
-		samples := tss.Samples
-		for i := range samples {
-			samples[i].Value = decimal.StaleNaN
+	// Stale markers mustn't contain exemplars.
+	for i := range series {
+		ts := &series[i]
+		samples := ts.Samples
+		for j := range samples {
+			samples[j].Value = decimal.StaleNaN
 		}
+		ts.Exemplars = nil
 		staleSamplesCreated.Add(len(samples))
 	}
 	sw.pushData(&wc.writeRequest)
@@ -819,6 +890,26 @@ func (sw *scrapeWork) addRowToTimeseries(wc *writeRequestCtx, r *parser.Row, tim
 		Labels:  wc.labels[labelsLen:],
 		Samples: wc.samples[len(wc.samples)-1:],
 	})
+	if e := &r.Exemplar; len(e.Tags) > 0 {
+		exemplarLabelsLen := len(wc.exemplarLabels)
+		for i := range e.Tags {
+			tag := &e.Tags[i]
+			wc.exemplarLabels = append(wc.exemplarLabels, prompbmarshal.Label{
+				Name:  tag.Key,
+				Value: tag.Value,
+			})
+		}
+		exemplarTimestamp := e.Timestamp
+		if exemplarTimestamp == 0 {
+			exemplarTimestamp = sampleTimestamp
+		}
+		wc.exemplars = append(wc.exemplars, prompbmarshal.Exemplar{
+			Labels:    wc.exemplarLabels[exemplarLabelsLen:],
+			Value:     e.Value,
+			Timestamp: exemplarTimestamp,
+		})
+		wr.Timeseries[len(wr.Timeseries)-1].Exemplars = wc.exemplars[len(wc.exemplars)-1:]
+	}
 }
 
 func appendLabels(dst []prompbmarshal.Label, metric string, src []parser.Tag, extraLabels []prompbmarshal.Label, honorLabels bool) []prompbmarshal.Label {
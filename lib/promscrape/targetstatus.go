@@ -122,7 +122,7 @@ func (tsm *targetStatusMap) Unregister(sw *scrapeWork) {
 	tsm.mu.Unlock()
 }
 
-func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDuration int64, samplesScraped int, err error) {
+func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDuration int64, samplesScraped, parseErrors int, err error) {
 	tsm.mu.Lock()
 	ts := tsm.m[sw]
 	if ts == nil {
@@ -135,6 +135,8 @@ func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDu
 	ts.scrapeTime = scrapeTime
 	ts.scrapeDuration = scrapeDuration
 	ts.samplesScraped = samplesScraped
+	ts.parseErrors = parseErrors
+	ts.parseErrorsTotal += parseErrors
 	ts.scrapesTotal++
 	if !up {
 		ts.scrapesFailed++
@@ -208,6 +210,8 @@ func (tsm *targetStatusMap) WriteActiveTargetsJSON(w io.Writer) {
 		fmt.Fprintf(w, `,"lastScrape":%q`, time.Unix(ts.scrapeTime/1000, (ts.scrapeTime%1000)*1e6).Format(time.RFC3339Nano))
 		fmt.Fprintf(w, `,"lastScrapeDuration":%g`, (time.Millisecond * time.Duration(ts.scrapeDuration)).Seconds())
 		fmt.Fprintf(w, `,"lastSamplesScraped":%d`, ts.samplesScraped)
+		fmt.Fprintf(w, `,"lastParseErrors":%d`, ts.parseErrors)
+		fmt.Fprintf(w, `,"parseErrorsTotal":%d`, ts.parseErrorsTotal)
 		state := "up"
 		if !ts.up {
 			state = "down"
@@ -240,6 +244,12 @@ type targetStatus struct {
 	scrapesTotal   int
 	scrapesFailed  int
 	err            error
+
+	// parseErrors contains the number of lines, which couldn't be parsed during the last scrape.
+	parseErrors int
+
+	// parseErrorsTotal contains the number of lines, which couldn't be parsed during all the scrapes.
+	parseErrorsTotal int
 }
 
 func (ts *targetStatus) getDurationFromLastScrape() time.Duration {
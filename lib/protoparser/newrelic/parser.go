@@ -0,0 +1,202 @@
+package newrelic
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/valyala/fastjson"
+)
+
+// Rows contains rows parsed from New Relic infrastructure agent request to /infra/v2/metrics/events/bulk
+type Rows struct {
+	Rows []Row
+}
+
+// Reset resets rs.
+func (rs *Rows) Reset() {
+	for i := range rs.Rows {
+		rs.Rows[i].reset()
+	}
+	rs.Rows = rs.Rows[:0]
+}
+
+// Unmarshal unmarshals New Relic infrastructure agent request from b to rs.
+//
+// b must contain JSON array of objects with `Events` arrays. Every event is converted into a Row.
+func (rs *Rows) Unmarshal(b []byte) error {
+	rs.Reset()
+	p := parserPool.Get()
+	defer parserPool.Put(p)
+	v, err := p.ParseBytes(b)
+	if err != nil {
+		return fmt.Errorf("cannot parse JSON: %w", err)
+	}
+	items, err := v.Array()
+	if err != nil {
+		return fmt.Errorf("unexpected JSON type %s; want array", v.Type())
+	}
+	currentTimestamp := int64(fasttime.UnixTimestamp()) * 1000
+	for _, item := range items {
+		events := item.GetArray("Events")
+		for _, event := range events {
+			o, err := event.Object()
+			if err != nil {
+				return fmt.Errorf("unexpected event type %s; want object", event.Type())
+			}
+			if cap(rs.Rows) > len(rs.Rows) {
+				rs.Rows = rs.Rows[:len(rs.Rows)+1]
+			} else {
+				rs.Rows = append(rs.Rows, Row{})
+			}
+			r := &rs.Rows[len(rs.Rows)-1]
+			if err := r.unmarshal(o); err != nil {
+				rs.Rows = rs.Rows[:len(rs.Rows)-1]
+				return fmt.Errorf("cannot unmarshal event %s: %w", event, err)
+			}
+			if r.Timestamp == 0 {
+				r.Timestamp = currentTimestamp
+			}
+		}
+	}
+	return nil
+}
+
+var parserPool fastjson.ParserPool
+
+// Row represents a single New Relic event.
+//
+// See https://docs.newrelic.com/docs/infrastructure/manage-your-data/data-instrumentation/default-infrastructure-monitoring-data/
+type Row struct {
+	// Tags contains string attributes of the event.
+	Tags []Tag
+
+	// Samples contains numeric attributes of the event.
+	Samples []Sample
+
+	// Timestamp is the event timestamp in milliseconds.
+	Timestamp int64
+}
+
+func (r *Row) reset() {
+	for i := range r.Tags {
+		r.Tags[i].reset()
+	}
+	r.Tags = r.Tags[:0]
+
+	for i := range r.Samples {
+		r.Samples[i].reset()
+	}
+	r.Samples = r.Samples[:0]
+
+	r.Timestamp = 0
+}
+
+func (r *Row) unmarshal(o *fastjson.Object) error {
+	r.reset()
+	v := o.Get("eventType")
+	if v == nil {
+		return fmt.Errorf("missing `eventType` field")
+	}
+	eventType, err := v.StringBytes()
+	if err != nil {
+		return fmt.Errorf("unexpected `eventType` type %s; want string", v.Type())
+	}
+	if len(eventType) == 0 {
+		return fmt.Errorf("`eventType` cannot be empty")
+	}
+	namePrefix := appendSnakeCase(nil, eventType)
+	namePrefix = append(namePrefix, '_')
+	o.Visit(func(k []byte, v *fastjson.Value) {
+		if err != nil {
+			return
+		}
+		switch string(k) {
+		case "eventType":
+			return
+		case "timestamp":
+			var ts float64
+			ts, err = v.Float64()
+			if err != nil {
+				err = fmt.Errorf("cannot parse `timestamp`: %w", err)
+				return
+			}
+			// New Relic timestamps are in seconds.
+			r.Timestamp = int64(ts * 1000)
+			return
+		}
+		switch v.Type() {
+		case fastjson.TypeString:
+			value := v.GetStringBytes()
+			if len(value) == 0 {
+				// Skip empty values, since they cannot be converted to labels.
+				return
+			}
+			r.Tags = append(r.Tags, Tag{
+				Key:   string(appendSnakeCase(nil, k)),
+				Value: string(value),
+			})
+		case fastjson.TypeNumber:
+			r.Samples = append(r.Samples, Sample{
+				Name:  string(appendSnakeCase(namePrefix, k)),
+				Value: v.GetFloat64(),
+			})
+		default:
+			// Skip attributes of other types, since they cannot be converted to samples or labels.
+		}
+	})
+	return err
+}
+
+// Tag is a string attribute of New Relic event.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+func (t *Tag) reset() {
+	t.Key = ""
+	t.Value = ""
+}
+
+// Sample is a numeric attribute of New Relic event.
+type Sample struct {
+	// Name is the metric name for the sample in the form `<event_type>_<attribute>`,
+	// where event type and attribute names are converted into snake case.
+	// For example, `cpuPercent` attribute of `SystemSample` event has `system_sample_cpu_percent` name.
+	Name string
+
+	Value float64
+}
+
+func (s *Sample) reset() {
+	s.Name = ""
+	s.Value = 0
+}
+
+// appendSnakeCase appends snake case representation of camel case s to dst and returns the result.
+//
+// For example, `cpuIOWaitPercent` is converted to `cpu_io_wait_percent`.
+func appendSnakeCase(dst, s []byte) []byte {
+	for i, c := range s {
+		if !isUpper(c) {
+			dst = append(dst, c)
+			continue
+		}
+		if i > 0 {
+			prev := s[i-1]
+			if (!isUpper(prev) && prev != '_') || (isUpper(prev) && i+1 < len(s) && isLower(s[i+1])) {
+				dst = append(dst, '_')
+			}
+		}
+		dst = append(dst, c+'a'-'A')
+	}
+	return dst
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func isLower(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
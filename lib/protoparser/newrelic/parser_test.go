@@ -0,0 +1,160 @@
+package newrelic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppendSnakeCase(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+		result := appendSnakeCase(nil, []byte(s))
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result for %q; got %q; want %q", s, result, resultExpected)
+		}
+	}
+	f("", "")
+	f("foo", "foo")
+	f("cpuPercent", "cpu_percent")
+	f("SystemSample", "system_sample")
+	f("cpuIOWaitPercent", "cpu_io_wait_percent")
+	f("loadAverageFiveMinute", "load_average_five_minute")
+	f("diskUsedBytes", "disk_used_bytes")
+	f("hostID", "host_id")
+	f("already_snake", "already_snake")
+	f("foo_Bar", "foo_bar")
+	f("memory5Free", "memory5_free")
+}
+
+func TestRowsUnmarshalFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		var rs Rows
+		if err := rs.Unmarshal([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// Invalid JSON
+	f(`[`)
+	// Not an array
+	f(`{}`)
+	// Invalid event type
+	f(`[{"Events":["foo"]}]`)
+	// Missing eventType
+	f(`[{"Events":[{"cpuPercent":1}]}]`)
+	// Invalid eventType
+	f(`[{"Events":[{"eventType":1,"cpuPercent":1}]}]`)
+	f(`[{"Events":[{"eventType":"","cpuPercent":1}]}]`)
+	// Invalid timestamp
+	f(`[{"Events":[{"eventType":"SystemSample","timestamp":"foo","cpuPercent":1}]}]`)
+}
+
+func TestRowsUnmarshalSuccess(t *testing.T) {
+	f := func(data string, rowsExpected []Row) {
+		t.Helper()
+		var rs Rows
+		if err := rs.Unmarshal([]byte(data)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(rs.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows;\ngot\n%+v\nwant\n%+v", rs.Rows, rowsExpected)
+		}
+
+		// Try unmarshaling again
+		if err := rs.Unmarshal([]byte(data)); err != nil {
+			t.Fatalf("unexpected error on the second unmarshal: %s", err)
+		}
+		if !reflect.DeepEqual(rs.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows on the second unmarshal;\ngot\n%+v\nwant\n%+v", rs.Rows, rowsExpected)
+		}
+	}
+
+	// Empty request
+	f(`[]`, nil)
+	f(`[{"EntityID":123,"Events":[]}]`, nil)
+
+	// Single event
+	f(`[{"EntityID":123,"IsAgent":true,"Events":[{
+		"eventType":"SystemSample",
+		"timestamp":1690286061,
+		"entityKey":"macbook-pro.local",
+		"cpuPercent":25.05,
+		"cpuIOWaitPercent":0,
+		"operatingSystem":"macOS",
+		"emptyValue":"",
+		"isVirtual":false,
+		"nested":{"foo":"bar"}
+	}]}]`, []Row{
+		{
+			Tags: []Tag{
+				{
+					Key:   "entity_key",
+					Value: "macbook-pro.local",
+				},
+				{
+					Key:   "operating_system",
+					Value: "macOS",
+				},
+			},
+			Samples: []Sample{
+				{
+					Name:  "system_sample_cpu_percent",
+					Value: 25.05,
+				},
+				{
+					Name:  "system_sample_cpu_io_wait_percent",
+					Value: 0,
+				},
+			},
+			Timestamp: 1690286061000,
+		},
+	})
+
+	// Multiple events in multiple items
+	f(`[{"Events":[
+		{"eventType":"SystemSample","timestamp":1,"memoryFreeBytes":100},
+		{"eventType":"NetworkSample","timestamp":2,"interfaceName":"eth0","receiveBytesPerSecond":10}
+	]},{"Events":[
+		{"eventType":"ProcessSample","timestamp":3.5,"processDisplayName":"vm","cpuPercent":1.5}
+	]}]`, []Row{
+		{
+			Samples: []Sample{
+				{
+					Name:  "system_sample_memory_free_bytes",
+					Value: 100,
+				},
+			},
+			Timestamp: 1000,
+		},
+		{
+			Tags: []Tag{
+				{
+					Key:   "interface_name",
+					Value: "eth0",
+				},
+			},
+			Samples: []Sample{
+				{
+					Name:  "network_sample_receive_bytes_per_second",
+					Value: 10,
+				},
+			},
+			Timestamp: 2000,
+		},
+		{
+			Tags: []Tag{
+				{
+					Key:   "process_display_name",
+					Value: "vm",
+				},
+			},
+			Samples: []Sample{
+				{
+					Name:  "process_sample_cpu_percent",
+					Value: 1.5,
+				},
+			},
+			Timestamp: 3500,
+		},
+	})
+}
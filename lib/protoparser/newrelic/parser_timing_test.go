@@ -0,0 +1,39 @@
+package newrelic
+
+import (
+	"fmt"
+	"testing"
+)
+
+func BenchmarkRowsUnmarshal(b *testing.B) {
+	reqBody := []byte(`[{"EntityID":28257883748326179,"IsAgent":true,"Events":[{
+		"eventType":"SystemSample",
+		"timestamp":1690286061,
+		"entityKey":"macbook-pro.local",
+		"cpuPercent":25.056660790748904,
+		"cpuUserPercent":8.687987912389374,
+		"cpuSystemPercent":16.36867287835953,
+		"cpuIOWaitPercent":0,
+		"cpuIdlePercent":74.94333920925109,
+		"loadAverageOneMinute":5.42333984375,
+		"memoryTotalBytes":17179869184,
+		"memoryFreeBytes":3782705152,
+		"memoryUsedBytes":13397164032,
+		"diskUsedBytes":0,
+		"hostname":"macbook-pro.local",
+		"operatingSystem":"macOS"
+	}],"ReportingAgentID":28257883748326179}]`)
+	b.SetBytes(int64(len(reqBody)))
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		var rs Rows
+		for pb.Next() {
+			if err := rs.Unmarshal(reqBody); err != nil {
+				panic(fmt.Errorf("cannot unmarshal request: %w", err))
+			}
+			if len(rs.Rows) != 1 {
+				panic(fmt.Errorf("unexpected number of rows unmarshaled: got %d; want 1", len(rs.Rows)))
+			}
+		}
+	})
+}
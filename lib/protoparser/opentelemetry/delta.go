@@ -0,0 +1,76 @@
+package opentelemetry
+
+import (
+	"math"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// deltas contains cumulative values for series received with delta aggregation temporality.
+//
+// The state is kept in memory of the current process, so all the delta samples for a particular series
+// must be sent to the same process in order to get correct cumulative values.
+var deltas = newDeltaState()
+
+// deltaStateTTL is the duration in seconds after which the cumulative value for the series without new samples is dropped.
+const deltaStateTTL = 3600
+
+type deltaState struct {
+	mu              sync.Mutex
+	m               map[string]*deltaEntry
+	lastCleanupTime uint64
+}
+
+type deltaEntry struct {
+	value        float64
+	lastSeenTime uint64
+}
+
+func newDeltaState() *deltaState {
+	ds := &deltaState{
+		m:               make(map[string]*deltaEntry),
+		lastCleanupTime: fasttime.UnixTimestamp(),
+	}
+	_ = metrics.NewGauge(`vm_protoparser_opentelemetry_delta_series`, func() float64 {
+		ds.mu.Lock()
+		n := len(ds.m)
+		ds.mu.Unlock()
+		return float64(n)
+	})
+	return ds
+}
+
+// add adds delta to the cumulative value for the series with the given key and returns the updated cumulative value.
+//
+// NaN delta doesn't change the cumulative value.
+func (ds *deltaState) add(key []byte, delta float64) float64 {
+	currentTime := fasttime.UnixTimestamp()
+
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	if currentTime-ds.lastCleanupTime > 60 {
+		ds.cleanupLocked(currentTime)
+		ds.lastCleanupTime = currentTime
+	}
+	e := ds.m[string(key)]
+	if e == nil {
+		e = &deltaEntry{}
+		ds.m[string(key)] = e
+	}
+	if !math.IsNaN(delta) {
+		e.value += delta
+	}
+	e.lastSeenTime = currentTime
+	return e.value
+}
+
+func (ds *deltaState) cleanupLocked(currentTime uint64) {
+	for key, e := range ds.m {
+		if currentTime-e.lastSeenTime > deltaStateTTL {
+			delete(ds.m, key)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package opentelemetry
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/opentelemetry/pb"
+)
+
+// writeContext converts OpenTelemetry metrics into Prometheus-style time series.
+type writeContext struct {
+	tss     []prompbmarshal.TimeSeries
+	mms     []prompbmarshal.MetricMetadata
+	labels  []prompbmarshal.Label
+	samples []prompbmarshal.Sample
+
+	// baseLabels contains labels obtained from resource and scope attributes for the currently processed metrics.
+	baseLabels []prompbmarshal.Label
+
+	// keyBuf is used for building keys for delta states.
+	keyBuf []byte
+
+	// rows contains the number of samples added to tss.
+	rows int
+
+	// currentTimestamp is used for data points without timestamps.
+	currentTimestamp int64
+}
+
+func (wctx *writeContext) reset() {
+	tss := wctx.tss
+	for i := range tss {
+		ts := &tss[i]
+		ts.Labels = nil
+		ts.Samples = nil
+	}
+	wctx.tss = tss[:0]
+	wctx.mms = prompbmarshal.ResetMetadata(wctx.mms)
+
+	promrelabel.CleanLabels(wctx.labels)
+	wctx.labels = wctx.labels[:0]
+	wctx.samples = wctx.samples[:0]
+
+	promrelabel.CleanLabels(wctx.baseLabels)
+	wctx.baseLabels = wctx.baseLabels[:0]
+
+	wctx.keyBuf = wctx.keyBuf[:0]
+	wctx.rows = 0
+	wctx.currentTimestamp = 0
+}
+
+// parseRequest converts metrics from req into time series and metadata at wctx.
+func (wctx *writeContext) parseRequest(req *pb.ExportMetricsServiceRequest) error {
+	wctx.currentTimestamp = time.Now().UnixMilli()
+	for i := range req.ResourceMetrics {
+		rm := &req.ResourceMetrics[i]
+		for j := range rm.ScopeMetrics {
+			sm := &rm.ScopeMetrics[j]
+			wctx.baseLabels = appendAttributesToLabels(wctx.baseLabels[:0], rm.Resource.Attributes)
+			wctx.baseLabels = appendAttributesToLabels(wctx.baseLabels, sm.Scope.Attributes)
+			for k := range sm.Metrics {
+				m := &sm.Metrics[k]
+				if err := wctx.appendMetric(m); err != nil {
+					return fmt.Errorf("cannot process metric %q: %w", m.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (wctx *writeContext) appendMetric(m *pb.Metric) error {
+	if m.Name == "" {
+		return fmt.Errorf("metric name cannot be empty")
+	}
+	switch {
+	case m.Gauge != nil:
+		wctx.appendMetadata(m, prompbmarshal.MetricMetadata_GAUGE)
+		for i := range m.Gauge.DataPoints {
+			wctx.appendNumberDataPoint(m.Name, &m.Gauge.DataPoints[i], false)
+		}
+	case m.Sum != nil:
+		typ := prompbmarshal.MetricMetadata_GAUGE
+		if m.Sum.IsMonotonic {
+			typ = prompbmarshal.MetricMetadata_COUNTER
+		}
+		wctx.appendMetadata(m, typ)
+		isDelta := m.Sum.AggregationTemporality == pb.AggregationTemporalityDelta
+		for i := range m.Sum.DataPoints {
+			wctx.appendNumberDataPoint(m.Name, &m.Sum.DataPoints[i], isDelta)
+		}
+	case m.Histogram != nil:
+		wctx.appendMetadata(m, prompbmarshal.MetricMetadata_HISTOGRAM)
+		isDelta := m.Histogram.AggregationTemporality == pb.AggregationTemporalityDelta
+		for i := range m.Histogram.DataPoints {
+			if err := wctx.appendHistogramDataPoint(m.Name, &m.Histogram.DataPoints[i], isDelta); err != nil {
+				return err
+			}
+		}
+	case m.Summary != nil:
+		wctx.appendMetadata(m, prompbmarshal.MetricMetadata_SUMMARY)
+		for i := range m.Summary.DataPoints {
+			wctx.appendSummaryDataPoint(m.Name, &m.Summary.DataPoints[i])
+		}
+	case m.ExponentialHistogram:
+		skippedExponentialHistograms.Inc()
+	}
+	return nil
+}
+
+func (wctx *writeContext) appendMetadata(m *pb.Metric, typ prompbmarshal.MetricMetadata_MetricType) {
+	if m.Description == "" && m.Unit == "" {
+		return
+	}
+	wctx.mms = append(wctx.mms, prompbmarshal.MetricMetadata{
+		Type:             typ,
+		MetricFamilyName: m.Name,
+		Help:             m.Description,
+		Unit:             m.Unit,
+	})
+}
+
+func (wctx *writeContext) appendNumberDataPoint(name string, dp *pb.NumberDataPoint, isDelta bool) {
+	var v float64
+	switch {
+	case dp.Flags&pb.DataPointFlagNoRecordedValue != 0:
+		v = decimal.StaleNaN
+	case dp.DoubleValue != nil:
+		v = *dp.DoubleValue
+	case dp.IntValue != nil:
+		v = float64(*dp.IntValue)
+	default:
+		// Skip data point without value.
+		return
+	}
+	timestamp := wctx.getTimestamp(dp.TimeUnixNano)
+	wctx.appendSample(name, dp.Attributes, "", "", timestamp, v, isDelta)
+}
+
+// appendHistogramDataPoint converts dp into Prometheus-style histogram with name_bucket{le="..."}, name_count and name_sum series.
+func (wctx *writeContext) appendHistogramDataPoint(name string, dp *pb.HistogramDataPoint, isDelta bool) error {
+	if len(dp.BucketCounts) > 0 && len(dp.BucketCounts) != len(dp.ExplicitBounds)+1 {
+		return fmt.Errorf("the number of bucket counts must exceed the number of explicit bounds by one; got %d bucket counts and %d explicit bounds",
+			len(dp.BucketCounts), len(dp.ExplicitBounds))
+	}
+	isStale := dp.Flags&pb.DataPointFlagNoRecordedValue != 0
+	value := func(v float64) float64 {
+		if isStale {
+			return decimal.StaleNaN
+		}
+		return v
+	}
+	timestamp := wctx.getTimestamp(dp.TimeUnixNano)
+	wctx.appendSample(name+"_count", dp.Attributes, "", "", timestamp, value(float64(dp.Count)), isDelta)
+	if dp.Sum != nil {
+		wctx.appendSample(name+"_sum", dp.Attributes, "", "", timestamp, value(*dp.Sum), isDelta)
+	}
+	if len(dp.BucketCounts) == 0 {
+		return nil
+	}
+	// OpenTelemetry bucket counts aren't cumulative, while Prometheus buckets are cumulative.
+	bucketName := name + "_bucket"
+	var cumulative uint64
+	for i, n := range dp.BucketCounts {
+		cumulative += n
+		le := "+Inf"
+		if i < len(dp.ExplicitBounds) {
+			le = strconv.FormatFloat(dp.ExplicitBounds[i], 'g', -1, 64)
+		}
+		wctx.appendSample(bucketName, dp.Attributes, "le", le, timestamp, value(float64(cumulative)), isDelta)
+	}
+	return nil
+}
+
+// appendSummaryDataPoint converts dp into Prometheus-style summary with name{quantile="..."}, name_count and name_sum series.
+func (wctx *writeContext) appendSummaryDataPoint(name string, dp *pb.SummaryDataPoint) {
+	isStale := dp.Flags&pb.DataPointFlagNoRecordedValue != 0
+	value := func(v float64) float64 {
+		if isStale {
+			return decimal.StaleNaN
+		}
+		return v
+	}
+	timestamp := wctx.getTimestamp(dp.TimeUnixNano)
+	wctx.appendSample(name+"_count", dp.Attributes, "", "", timestamp, value(float64(dp.Count)), false)
+	wctx.appendSample(name+"_sum", dp.Attributes, "", "", timestamp, value(dp.Sum), false)
+	for _, vq := range dp.QuantileValues {
+		quantile := strconv.FormatFloat(vq.Quantile, 'g', -1, 64)
+		wctx.appendSample(name, dp.Attributes, "quantile", quantile, timestamp, value(vq.Value), false)
+	}
+}
+
+// appendSample appends a time series with a single sample to wctx.
+//
+// The series labels are built from name, wctx.baseLabels, attrs and optional extraName=extraValue label.
+// The value is added to the previously received values for the series if isDelta is set,
+// so the series contains cumulative value.
+func (wctx *writeContext) appendSample(name string, attrs []pb.KeyValue, extraName, extraValue string, timestamp int64, value float64, isDelta bool) {
+	labelsLen := len(wctx.labels)
+	wctx.labels = append(wctx.labels, prompbmarshal.Label{
+		Name:  "__name__",
+		Value: name,
+	})
+	wctx.labels = append(wctx.labels, wctx.baseLabels...)
+	wctx.labels = appendAttributesToLabels(wctx.labels, attrs)
+	if extraName != "" {
+		wctx.labels = append(wctx.labels, prompbmarshal.Label{
+			Name:  extraName,
+			Value: extraValue,
+		})
+	}
+	labels := wctx.labels[labelsLen:]
+	if isDelta && !decimal.IsStaleNaN(value) {
+		wctx.keyBuf = marshalLabels(wctx.keyBuf[:0], labels)
+		value = deltas.add(wctx.keyBuf, value)
+	}
+	samplesLen := len(wctx.samples)
+	wctx.samples = append(wctx.samples, prompbmarshal.Sample{
+		Timestamp: timestamp,
+		Value:     value,
+	})
+	wctx.tss = append(wctx.tss, prompbmarshal.TimeSeries{
+		Labels:  labels,
+		Samples: wctx.samples[samplesLen:],
+	})
+	wctx.rows++
+}
+
+// getTimestamp converts timeUnixNano into Unix timestamp in milliseconds.
+//
+// The current time is returned for zero timeUnixNano.
+func (wctx *writeContext) getTimestamp(timeUnixNano uint64) int64 {
+	if timeUnixNano == 0 {
+		return wctx.currentTimestamp
+	}
+	return int64(timeUnixNano / 1e6)
+}
+
+func appendAttributesToLabels(dst []prompbmarshal.Label, attrs []pb.KeyValue) []prompbmarshal.Label {
+	for i := range attrs {
+		kv := &attrs[i]
+		dst = append(dst, prompbmarshal.Label{
+			Name:  kv.Key,
+			Value: kv.Value.FormatString(),
+		})
+	}
+	return dst
+}
+
+func marshalLabels(dst []byte, labels []prompbmarshal.Label) []byte {
+	for _, label := range labels {
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(label.Name))
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(label.Value))
+	}
+	return dst
+}
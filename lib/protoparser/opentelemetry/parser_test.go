@@ -0,0 +1,236 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/klauspost/compress/gzip"
+)
+
+func TestParseStreamSuccess(t *testing.T) {
+	f := func(data string, rowsExpected []string, metadataExpected []string) {
+		t.Helper()
+		rows, metadata, err := parseJSON(data, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(rows, rowsExpected) {
+			t.Fatalf("unexpected rows;\ngot\n%s\nwant\n%s", strings.Join(rows, "\n"), strings.Join(rowsExpected, "\n"))
+		}
+		if !reflect.DeepEqual(metadata, metadataExpected) {
+			t.Fatalf("unexpected metadata;\ngot\n%s\nwant\n%s", strings.Join(metadata, "\n"), strings.Join(metadataExpected, "\n"))
+		}
+
+		// Verify gzipped request
+		var bb bytes.Buffer
+		zw := gzip.NewWriter(&bb)
+		if _, err := zw.Write([]byte(data)); err != nil {
+			t.Fatalf("cannot compress data: %s", err)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatalf("cannot close gzip writer: %s", err)
+		}
+		rows, _, err = parseJSON(bb.String(), "gzip")
+		if err != nil {
+			t.Fatalf("unexpected error for gzipped request: %s", err)
+		}
+		if !reflect.DeepEqual(rows, rowsExpected) {
+			t.Fatalf("unexpected rows for gzipped request;\ngot\n%s\nwant\n%s", strings.Join(rows, "\n"), strings.Join(rowsExpected, "\n"))
+		}
+	}
+
+	// Empty request
+	f(`{}`, nil, nil)
+
+	// Gauge with resource, scope and data point attributes
+	f(`{"resourceMetrics":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"svc"}}]},"scopeMetrics":[{
+		"scope":{"name":"lib","attributes":[{"key":"scope.attr","value":{"boolValue":true}}]},
+		"metrics":[{"name":"temperature","unit":"C","description":"Room temperature","gauge":{"dataPoints":[
+			{"timeUnixNano":"1000000000000","asDouble":21.5,"attributes":[{"key":"room","value":{"stringValue":"kitchen"}}]},
+			{"timeUnixNano":"2000000000000","asInt":"22","attributes":[{"key":"floor","value":{"intValue":"2"}}]},
+			{"timeUnixNano":"3000000000000","flags":1}
+		]}}]
+	}]}]}`, []string{
+		`temperature{service.name="svc",scope.attr="true",room="kitchen"} 21.5 1000000`,
+		`temperature{service.name="svc",scope.attr="true",floor="2"} 22 2000000`,
+		`temperature{service.name="svc",scope.attr="true"} stale 3000000`,
+	}, []string{
+		`gauge temperature help="Room temperature" unit="C"`,
+	})
+
+	// Cumulative sums
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"requests_total","sum":{"aggregationTemporality":2,"isMonotonic":true,"dataPoints":[
+			{"timeUnixNano":"1000000000000","asInt":"10"},
+			{"timeUnixNano":"2000000000000","asInt":"15"}
+		]}},
+		{"name":"queue_size","description":"Queue size","sum":{"aggregationTemporality":2,"dataPoints":[
+			{"timeUnixNano":"1000000000000","asDouble":3}
+		]}}
+	]}]}]}`, []string{
+		`requests_total 10 1000000`,
+		`requests_total 15 2000000`,
+		`queue_size 3 1000000`,
+	}, []string{
+		`gauge queue_size help="Queue size" unit=""`,
+	})
+
+	// Histogram
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"latency","unit":"s","histogram":{"aggregationTemporality":2,"dataPoints":[
+			{"timeUnixNano":"1000000000000","count":"3","sum":1.5,"bucketCounts":["1","2","0"],"explicitBounds":[0.1,1],
+			 "attributes":[{"key":"path","value":{"stringValue":"/"}}]},
+			{"timeUnixNano":"2000000000000","count":"4"}
+		]}}
+	]}]}]}`, []string{
+		`latency_count{path="/"} 3 1000000`,
+		`latency_sum{path="/"} 1.5 1000000`,
+		`latency_bucket{path="/",le="0.1"} 1 1000000`,
+		`latency_bucket{path="/",le="1"} 3 1000000`,
+		`latency_bucket{path="/",le="+Inf"} 3 1000000`,
+		`latency_count 4 2000000`,
+	}, []string{
+		`histogram latency help="" unit="s"`,
+	})
+
+	// Summary
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"rpc_duration","summary":{"dataPoints":[
+			{"timeUnixNano":"1000000000000","count":"2","sum":3,"quantileValues":[{"quantile":0.5,"value":1},{"quantile":0.99,"value":2}]}
+		]}}
+	]}]}]}`, []string{
+		`rpc_duration_count 2 1000000`,
+		`rpc_duration_sum 3 1000000`,
+		`rpc_duration{quantile="0.5"} 1 1000000`,
+		`rpc_duration{quantile="0.99"} 2 1000000`,
+	}, nil)
+
+	// Exponential histograms are skipped
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"exp","exponentialHistogram":{"dataPoints":[{"timeUnixNano":"1000000000000","count":"2"}]}}
+	]}]}]}`, nil, nil)
+}
+
+func TestParseStreamDeltaTemporality(t *testing.T) {
+	f := func(data string, rowsExpected []string) {
+		t.Helper()
+		rows, _, err := parseJSON(data, "")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(rows, rowsExpected) {
+			t.Fatalf("unexpected rows;\ngot\n%s\nwant\n%s", strings.Join(rows, "\n"), strings.Join(rowsExpected, "\n"))
+		}
+	}
+
+	// Delta sums are converted into cumulative sums per series
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"delta_requests","sum":{"aggregationTemporality":1,"isMonotonic":true,"dataPoints":[
+			{"timeUnixNano":"1000000000000","asInt":"5","attributes":[{"key":"code","value":{"stringValue":"200"}}]},
+			{"timeUnixNano":"1000000000000","asInt":"1","attributes":[{"key":"code","value":{"stringValue":"500"}}]},
+			{"timeUnixNano":"2000000000000","asInt":"3","attributes":[{"key":"code","value":{"stringValue":"200"}}]}
+		]}}
+	]}]}]}`, []string{
+		`delta_requests{code="200"} 5 1000000`,
+		`delta_requests{code="500"} 1 1000000`,
+		`delta_requests{code="200"} 8 2000000`,
+	})
+	// The cumulative value is preserved between requests, while staleness markers don't change it
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"delta_requests","sum":{"aggregationTemporality":"AGGREGATION_TEMPORALITY_DELTA","isMonotonic":true,"dataPoints":[
+			{"timeUnixNano":"3000000000000","asInt":"2","attributes":[{"key":"code","value":{"stringValue":"200"}}]},
+			{"timeUnixNano":"3000000000000","flags":1,"attributes":[{"key":"code","value":{"stringValue":"500"}}]},
+			{"timeUnixNano":"4000000000000","asInt":"1","attributes":[{"key":"code","value":{"stringValue":"500"}}]}
+		]}}
+	]}]}]}`, []string{
+		`delta_requests{code="200"} 10 3000000`,
+		`delta_requests{code="500"} stale 3000000`,
+		`delta_requests{code="500"} 2 4000000`,
+	})
+
+	// Delta histograms
+	data := `{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"delta_latency","histogram":{"aggregationTemporality":1,"dataPoints":[
+			{"timeUnixNano":"%d000000000","count":"3","sum":1.5,"bucketCounts":["1","2"],"explicitBounds":[0.1]}
+		]}}
+	]}]}]}`
+	f(fmt.Sprintf(data, 1), []string{
+		`delta_latency_count 3 1000`,
+		`delta_latency_sum 1.5 1000`,
+		`delta_latency_bucket{le="0.1"} 1 1000`,
+		`delta_latency_bucket{le="+Inf"} 3 1000`,
+	})
+	f(fmt.Sprintf(data, 2), []string{
+		`delta_latency_count 6 2000`,
+		`delta_latency_sum 3 2000`,
+		`delta_latency_bucket{le="0.1"} 2 2000`,
+		`delta_latency_bucket{le="+Inf"} 6 2000`,
+	})
+}
+
+func TestParseStreamFailure(t *testing.T) {
+	f := func(data, contentEncoding string) {
+		t.Helper()
+		if _, _, err := parseJSON(data, contentEncoding); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// Invalid JSON
+	f(`{`, "")
+	// Invalid gzip
+	f(`{}`, "gzip")
+	// Missing metric name
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[{"gauge":{"dataPoints":[{"asInt":"1"}]}}]}]}]}`, "")
+	// Invalid number of histogram buckets
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[{"name":"foo","histogram":{"dataPoints":[
+		{"count":"1","bucketCounts":["1"],"explicitBounds":[0.1]}
+	]}}]}]}]}`, "")
+
+	// Invalid protobuf
+	err := ParseStream(strings.NewReader("foobar"), "application/x-protobuf", "", func(tss []prompbmarshal.TimeSeries, mms []prompbmarshal.MetricMetadata) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("expecting non-nil error for invalid protobuf")
+	}
+}
+
+// parseJSON parses OpenTelemetry JSON request from data and returns the parsed rows and metadata in human-readable form.
+func parseJSON(data, contentEncoding string) ([]string, []string, error) {
+	var rows, metadata []string
+	err := ParseStream(strings.NewReader(data), "application/json", contentEncoding, func(tss []prompbmarshal.TimeSeries, mms []prompbmarshal.MetricMetadata) error {
+		for _, ts := range tss {
+			var name string
+			var labels []string
+			for _, label := range ts.Labels {
+				if label.Name == "__name__" {
+					name = label.Value
+					continue
+				}
+				labels = append(labels, fmt.Sprintf("%s=%q", label.Name, label.Value))
+			}
+			if len(labels) > 0 {
+				name += "{" + strings.Join(labels, ",") + "}"
+			}
+			for _, s := range ts.Samples {
+				value := fmt.Sprintf("%v", s.Value)
+				if decimal.IsStaleNaN(s.Value) {
+					value = "stale"
+				}
+				rows = append(rows, fmt.Sprintf("%s %s %d", name, value, s.Timestamp))
+			}
+		}
+		for _, mm := range mms {
+			metadata = append(metadata, fmt.Sprintf("%s %s help=%q unit=%q", mm.Type, mm.MetricFamilyName, mm.Help, mm.Unit))
+		}
+		sort.Strings(metadata)
+		return nil
+	})
+	return rows, metadata, err
+}
@@ -0,0 +1,41 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func BenchmarkParseStream(b *testing.B) {
+	reqBody := []byte(`{"resourceMetrics":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"svc"}}]},"scopeMetrics":[{
+	"scope":{"name":"lib"},
+	"metrics":[
+		{"name":"temperature","gauge":{"dataPoints":[
+			{"timeUnixNano":"1000000000000","asDouble":21.5,"attributes":[{"key":"room","value":{"stringValue":"kitchen"}}]}
+		]}},
+		{"name":"requests_total","sum":{"aggregationTemporality":2,"isMonotonic":true,"dataPoints":[
+			{"timeUnixNano":"1000000000000","asInt":"10","attributes":[{"key":"code","value":{"stringValue":"200"}}]}
+		]}},
+		{"name":"latency","histogram":{"aggregationTemporality":2,"dataPoints":[
+			{"timeUnixNano":"1000000000000","count":"3","sum":1.5,"bucketCounts":["1","2","0"],"explicitBounds":[0.1,1]}
+		]}}
+	]
+}]}]}`)
+	b.SetBytes(int64(len(reqBody)))
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			err := ParseStream(bytes.NewReader(reqBody), "application/json", "", func(tss []prompbmarshal.TimeSeries, mms []prompbmarshal.MetricMetadata) error {
+				if len(tss) != 7 {
+					return fmt.Errorf("unexpected number of series; got %d; want 7", len(tss))
+				}
+				return nil
+			})
+			if err != nil {
+				panic(fmt.Errorf("unexpected error: %w", err))
+			}
+		}
+	})
+}
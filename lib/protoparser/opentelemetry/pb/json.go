@@ -0,0 +1,496 @@
+package pb
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/valyala/fastjson"
+)
+
+// UnmarshalJSON unmarshals req from OTLP JSON at src.
+//
+// See https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md#json-protobuf-encoding
+func (req *ExportMetricsServiceRequest) UnmarshalJSON(src []byte) error {
+	req.Reset()
+	p := parserPool.Get()
+	defer parserPool.Put(p)
+	v, err := p.ParseBytes(src)
+	if err != nil {
+		return fmt.Errorf("cannot parse JSON: %w", err)
+	}
+	if v.Type() != fastjson.TypeObject {
+		return fmt.Errorf("unexpected JSON type %s; want object", v.Type())
+	}
+	return forEachItem(v, "resourceMetrics", func(v *fastjson.Value) error {
+		req.ResourceMetrics = append(req.ResourceMetrics, ResourceMetrics{})
+		rm := &req.ResourceMetrics[len(req.ResourceMetrics)-1]
+		if err := rm.unmarshalJSON(v); err != nil {
+			return fmt.Errorf("cannot unmarshal resourceMetrics: %w", err)
+		}
+		return nil
+	})
+}
+
+var parserPool fastjson.ParserPool
+
+func (rm *ResourceMetrics) unmarshalJSON(v *fastjson.Value) error {
+	if r := v.Get("resource"); r != nil {
+		if err := unmarshalAttributesJSON(&rm.Resource.Attributes, r); err != nil {
+			return fmt.Errorf("cannot unmarshal resource: %w", err)
+		}
+	}
+	f := func(v *fastjson.Value) error {
+		rm.ScopeMetrics = append(rm.ScopeMetrics, ScopeMetrics{})
+		sm := &rm.ScopeMetrics[len(rm.ScopeMetrics)-1]
+		if err := sm.unmarshalJSON(v); err != nil {
+			return fmt.Errorf("cannot unmarshal scopeMetrics: %w", err)
+		}
+		return nil
+	}
+	if err := forEachItem(v, "scopeMetrics", f); err != nil {
+		return err
+	}
+	// Deprecated instrumentationLibraryMetrics is still sent by old OpenTelemetry SDKs.
+	return forEachItem(v, "instrumentationLibraryMetrics", f)
+}
+
+func (sm *ScopeMetrics) unmarshalJSON(v *fastjson.Value) error {
+	scope := v.Get("scope")
+	if scope == nil {
+		scope = v.Get("instrumentationLibrary")
+	}
+	if scope != nil {
+		if err := sm.Scope.unmarshalJSON(scope); err != nil {
+			return fmt.Errorf("cannot unmarshal scope: %w", err)
+		}
+	}
+	return forEachItem(v, "metrics", func(v *fastjson.Value) error {
+		sm.Metrics = append(sm.Metrics, Metric{})
+		m := &sm.Metrics[len(sm.Metrics)-1]
+		if err := m.unmarshalJSON(v); err != nil {
+			return fmt.Errorf("cannot unmarshal metric: %w", err)
+		}
+		return nil
+	})
+}
+
+func (is *InstrumentationScope) unmarshalJSON(v *fastjson.Value) error {
+	var err error
+	if is.Name, err = getString(v, "name"); err != nil {
+		return err
+	}
+	if is.Version, err = getString(v, "version"); err != nil {
+		return err
+	}
+	return unmarshalAttributesJSON(&is.Attributes, v)
+}
+
+func unmarshalAttributesJSON(dst *[]KeyValue, v *fastjson.Value) error {
+	return forEachItem(v, "attributes", func(v *fastjson.Value) error {
+		return appendKeyValueJSON(dst, v)
+	})
+}
+
+func appendKeyValueJSON(dst *[]KeyValue, v *fastjson.Value) error {
+	*dst = append(*dst, KeyValue{})
+	kv := &(*dst)[len(*dst)-1]
+	var err error
+	if kv.Key, err = getString(v, "key"); err != nil {
+		return err
+	}
+	if value := v.Get("value"); value != nil {
+		if err := kv.Value.unmarshalJSON(value); err != nil {
+			return fmt.Errorf("cannot unmarshal value for key %q: %w", kv.Key, err)
+		}
+	}
+	return nil
+}
+
+func (av *AnyValue) unmarshalJSON(v *fastjson.Value) error {
+	if x := v.Get("stringValue"); x != nil {
+		s, err := getString(v, "stringValue")
+		if err != nil {
+			return err
+		}
+		av.StringValue = &s
+	}
+	if x := v.Get("boolValue"); x != nil {
+		b, err := x.Bool()
+		if err != nil {
+			return fmt.Errorf("cannot parse boolValue: %w", err)
+		}
+		av.BoolValue = &b
+	}
+	if x := v.Get("intValue"); x != nil {
+		n, err := getInt64(v, "intValue")
+		if err != nil {
+			return err
+		}
+		av.IntValue = &n
+	}
+	if x := v.Get("doubleValue"); x != nil {
+		f, err := getFloat64(v, "doubleValue")
+		if err != nil {
+			return err
+		}
+		av.DoubleValue = &f
+	}
+	if x := v.Get("arrayValue"); x != nil {
+		av.ArrayValue = []AnyValue{}
+		err := forEachItem(x, "values", func(v *fastjson.Value) error {
+			av.ArrayValue = append(av.ArrayValue, AnyValue{})
+			return av.ArrayValue[len(av.ArrayValue)-1].unmarshalJSON(v)
+		})
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal arrayValue: %w", err)
+		}
+	}
+	if x := v.Get("kvlistValue"); x != nil {
+		av.KeyValueList = []KeyValue{}
+		err := forEachItem(x, "values", func(v *fastjson.Value) error {
+			return appendKeyValueJSON(&av.KeyValueList, v)
+		})
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal kvlistValue: %w", err)
+		}
+	}
+	if x := v.Get("bytesValue"); x != nil {
+		s, err := getString(v, "bytesValue")
+		if err != nil {
+			return err
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("cannot decode bytesValue: %w", err)
+		}
+		av.BytesValue = b
+	}
+	return nil
+}
+
+func (m *Metric) unmarshalJSON(v *fastjson.Value) error {
+	var err error
+	if m.Name, err = getString(v, "name"); err != nil {
+		return err
+	}
+	if m.Description, err = getString(v, "description"); err != nil {
+		return err
+	}
+	if m.Unit, err = getString(v, "unit"); err != nil {
+		return err
+	}
+	if x := v.Get("gauge"); x != nil {
+		m.Gauge = &Gauge{}
+		if err := unmarshalNumberDataPointsJSON(&m.Gauge.DataPoints, x); err != nil {
+			return fmt.Errorf("cannot unmarshal gauge: %w", err)
+		}
+	}
+	if x := v.Get("sum"); x != nil {
+		m.Sum = &Sum{}
+		if err := unmarshalNumberDataPointsJSON(&m.Sum.DataPoints, x); err != nil {
+			return fmt.Errorf("cannot unmarshal sum: %w", err)
+		}
+		if m.Sum.AggregationTemporality, err = getAggregationTemporality(x); err != nil {
+			return err
+		}
+		if y := x.Get("isMonotonic"); y != nil {
+			if m.Sum.IsMonotonic, err = y.Bool(); err != nil {
+				return fmt.Errorf("cannot parse isMonotonic: %w", err)
+			}
+		}
+	}
+	if x := v.Get("histogram"); x != nil {
+		m.Histogram = &Histogram{}
+		err := forEachItem(x, "dataPoints", func(v *fastjson.Value) error {
+			m.Histogram.DataPoints = append(m.Histogram.DataPoints, HistogramDataPoint{})
+			return m.Histogram.DataPoints[len(m.Histogram.DataPoints)-1].unmarshalJSON(v)
+		})
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal histogram: %w", err)
+		}
+		if m.Histogram.AggregationTemporality, err = getAggregationTemporality(x); err != nil {
+			return err
+		}
+	}
+	if x := v.Get("exponentialHistogram"); x != nil {
+		m.ExponentialHistogram = true
+	}
+	if x := v.Get("summary"); x != nil {
+		m.Summary = &Summary{}
+		err := forEachItem(x, "dataPoints", func(v *fastjson.Value) error {
+			m.Summary.DataPoints = append(m.Summary.DataPoints, SummaryDataPoint{})
+			return m.Summary.DataPoints[len(m.Summary.DataPoints)-1].unmarshalJSON(v)
+		})
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal summary: %w", err)
+		}
+	}
+	return nil
+}
+
+func unmarshalNumberDataPointsJSON(dst *[]NumberDataPoint, v *fastjson.Value) error {
+	return forEachItem(v, "dataPoints", func(v *fastjson.Value) error {
+		*dst = append(*dst, NumberDataPoint{})
+		return (*dst)[len(*dst)-1].unmarshalJSON(v)
+	})
+}
+
+func (dp *NumberDataPoint) unmarshalJSON(v *fastjson.Value) error {
+	if err := unmarshalAttributesJSON(&dp.Attributes, v); err != nil {
+		return err
+	}
+	var err error
+	if dp.StartTimeUnixNano, err = getUint64(v, "startTimeUnixNano"); err != nil {
+		return err
+	}
+	if dp.TimeUnixNano, err = getUint64(v, "timeUnixNano"); err != nil {
+		return err
+	}
+	if x := v.Get("asDouble"); x != nil {
+		f, err := getFloat64(v, "asDouble")
+		if err != nil {
+			return err
+		}
+		dp.DoubleValue = &f
+	}
+	if x := v.Get("asInt"); x != nil {
+		n, err := getInt64(v, "asInt")
+		if err != nil {
+			return err
+		}
+		dp.IntValue = &n
+	}
+	flags, err := getUint64(v, "flags")
+	if err != nil {
+		return err
+	}
+	dp.Flags = uint32(flags)
+	return nil
+}
+
+func (dp *HistogramDataPoint) unmarshalJSON(v *fastjson.Value) error {
+	if err := unmarshalAttributesJSON(&dp.Attributes, v); err != nil {
+		return err
+	}
+	var err error
+	if dp.StartTimeUnixNano, err = getUint64(v, "startTimeUnixNano"); err != nil {
+		return err
+	}
+	if dp.TimeUnixNano, err = getUint64(v, "timeUnixNano"); err != nil {
+		return err
+	}
+	if dp.Count, err = getUint64(v, "count"); err != nil {
+		return err
+	}
+	if x := v.Get("sum"); x != nil {
+		f, err := getFloat64(v, "sum")
+		if err != nil {
+			return err
+		}
+		dp.Sum = &f
+	}
+	err = forEachItem(v, "bucketCounts", func(x *fastjson.Value) error {
+		n, err := parseUint64(x)
+		if err != nil {
+			return fmt.Errorf("cannot parse bucketCounts: %w", err)
+		}
+		dp.BucketCounts = append(dp.BucketCounts, n)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = forEachItem(v, "explicitBounds", func(x *fastjson.Value) error {
+		f, err := parseFloat64(x)
+		if err != nil {
+			return fmt.Errorf("cannot parse explicitBounds: %w", err)
+		}
+		dp.ExplicitBounds = append(dp.ExplicitBounds, f)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	flags, err := getUint64(v, "flags")
+	if err != nil {
+		return err
+	}
+	dp.Flags = uint32(flags)
+	return nil
+}
+
+func (dp *SummaryDataPoint) unmarshalJSON(v *fastjson.Value) error {
+	if err := unmarshalAttributesJSON(&dp.Attributes, v); err != nil {
+		return err
+	}
+	var err error
+	if dp.StartTimeUnixNano, err = getUint64(v, "startTimeUnixNano"); err != nil {
+		return err
+	}
+	if dp.TimeUnixNano, err = getUint64(v, "timeUnixNano"); err != nil {
+		return err
+	}
+	if dp.Count, err = getUint64(v, "count"); err != nil {
+		return err
+	}
+	if dp.Sum, err = getFloat64(v, "sum"); err != nil {
+		return err
+	}
+	err = forEachItem(v, "quantileValues", func(x *fastjson.Value) error {
+		var vq ValueAtQuantile
+		var err error
+		if vq.Quantile, err = getFloat64(x, "quantile"); err != nil {
+			return err
+		}
+		if vq.Value, err = getFloat64(x, "value"); err != nil {
+			return err
+		}
+		dp.QuantileValues = append(dp.QuantileValues, vq)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	flags, err := getUint64(v, "flags")
+	if err != nil {
+		return err
+	}
+	dp.Flags = uint32(flags)
+	return nil
+}
+
+// forEachItem calls f for every item in the array at v[key].
+//
+// f isn't called if v[key] is missing or null.
+func forEachItem(v *fastjson.Value, key string, f func(v *fastjson.Value) error) error {
+	x := v.Get(key)
+	if x == nil || x.Type() == fastjson.TypeNull {
+		return nil
+	}
+	a, err := x.Array()
+	if err != nil {
+		return fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	for _, item := range a {
+		if err := f(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getString(v *fastjson.Value, key string) (string, error) {
+	x := v.Get(key)
+	if x == nil || x.Type() == fastjson.TypeNull {
+		return "", nil
+	}
+	b, err := x.StringBytes()
+	if err != nil {
+		return "", fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	return string(b), nil
+}
+
+// getUint64 returns uint64 value for v[key].
+//
+// OTLP JSON encodes 64-bit integers as strings, while numbers are accepted too.
+func getUint64(v *fastjson.Value, key string) (uint64, error) {
+	x := v.Get(key)
+	if x == nil || x.Type() == fastjson.TypeNull {
+		return 0, nil
+	}
+	n, err := parseUint64(x)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	return n, nil
+}
+
+func parseUint64(x *fastjson.Value) (uint64, error) {
+	if x.Type() == fastjson.TypeString {
+		return strconv.ParseUint(string(x.GetStringBytes()), 10, 64)
+	}
+	return x.Uint64()
+}
+
+// getInt64 returns int64 value for v[key].
+//
+// OTLP JSON encodes 64-bit integers as strings, while numbers are accepted too.
+func getInt64(v *fastjson.Value, key string) (int64, error) {
+	x := v.Get(key)
+	if x == nil || x.Type() == fastjson.TypeNull {
+		return 0, nil
+	}
+	var n int64
+	var err error
+	if x.Type() == fastjson.TypeString {
+		n, err = strconv.ParseInt(string(x.GetStringBytes()), 10, 64)
+	} else {
+		n, err = x.Int64()
+	}
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// getFloat64 returns float64 value for v[key].
+func getFloat64(v *fastjson.Value, key string) (float64, error) {
+	x := v.Get(key)
+	if x == nil || x.Type() == fastjson.TypeNull {
+		return 0, nil
+	}
+	f, err := parseFloat64(x)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// parseFloat64 parses float64 value from x.
+//
+// Special values are encoded as "NaN", "Infinity" and "-Infinity" strings in OTLP JSON.
+func parseFloat64(x *fastjson.Value) (float64, error) {
+	if x.Type() != fastjson.TypeString {
+		return x.Float64()
+	}
+	s := string(x.GetStringBytes())
+	switch s {
+	case "NaN":
+		return math.NaN(), nil
+	case "Infinity":
+		return math.Inf(1), nil
+	case "-Infinity":
+		return math.Inf(-1), nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// getAggregationTemporality returns aggregationTemporality from v.
+//
+// The value may be encoded either as integer or as enum name.
+func getAggregationTemporality(v *fastjson.Value) (AggregationTemporality, error) {
+	x := v.Get("aggregationTemporality")
+	if x == nil || x.Type() == fastjson.TypeNull {
+		return AggregationTemporalityUnspecified, nil
+	}
+	if x.Type() == fastjson.TypeString {
+		s := string(x.GetStringBytes())
+		switch s {
+		case "AGGREGATION_TEMPORALITY_UNSPECIFIED":
+			return AggregationTemporalityUnspecified, nil
+		case "AGGREGATION_TEMPORALITY_DELTA":
+			return AggregationTemporalityDelta, nil
+		case "AGGREGATION_TEMPORALITY_CUMULATIVE":
+			return AggregationTemporalityCumulative, nil
+		}
+		return 0, fmt.Errorf("unsupported aggregationTemporality %q", s)
+	}
+	n, err := x.Int()
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse aggregationTemporality: %w", err)
+	}
+	return AggregationTemporality(n), nil
+}
@@ -0,0 +1,227 @@
+// Package pb contains OpenTelemetry metrics data model.
+//
+// It is a subset of https://github.com/open-telemetry/opentelemetry-proto/tree/main/opentelemetry/proto ,
+// which is needed for parsing OTLP metrics exported via OTLP/HTTP protocol in protobuf and JSON formats.
+package pb
+
+import (
+	"encoding/base64"
+	"strconv"
+)
+
+// ExportMetricsServiceRequest represents the request for OTLP metrics export.
+//
+// See https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/collector/metrics/v1/metrics_service.proto
+type ExportMetricsServiceRequest struct {
+	ResourceMetrics []ResourceMetrics
+}
+
+// Reset resets req, so it could be re-used.
+func (req *ExportMetricsServiceRequest) Reset() {
+	req.ResourceMetrics = nil
+}
+
+// ResourceMetrics is a collection of ScopeMetrics from a Resource.
+type ResourceMetrics struct {
+	Resource     Resource
+	ScopeMetrics []ScopeMetrics
+}
+
+// Resource contains information about the entity producing metrics.
+type Resource struct {
+	Attributes []KeyValue
+}
+
+// ScopeMetrics is a collection of Metrics produced by an InstrumentationScope.
+type ScopeMetrics struct {
+	Scope   InstrumentationScope
+	Metrics []Metric
+}
+
+// InstrumentationScope is a message representing the instrumentation scope information such as the library name.
+type InstrumentationScope struct {
+	Name       string
+	Version    string
+	Attributes []KeyValue
+}
+
+// KeyValue is a key-value pair used as attribute.
+type KeyValue struct {
+	Key   string
+	Value AnyValue
+}
+
+// AnyValue contains a value of attribute.
+//
+// Only a single field must be set in AnyValue.
+type AnyValue struct {
+	StringValue  *string
+	BoolValue    *bool
+	IntValue     *int64
+	DoubleValue  *float64
+	ArrayValue   []AnyValue
+	KeyValueList []KeyValue
+	BytesValue   []byte
+}
+
+// FormatString returns string representation for av.
+//
+// Arrays and key-value lists are represented in JSON format, while bytes are represented in base64 encoding.
+func (av *AnyValue) FormatString() string {
+	if av.StringValue != nil {
+		return *av.StringValue
+	}
+	return string(av.appendString(nil, false))
+}
+
+func (av *AnyValue) appendString(dst []byte, quote bool) []byte {
+	switch {
+	case av.StringValue != nil:
+		if quote {
+			return strconv.AppendQuote(dst, *av.StringValue)
+		}
+		return append(dst, *av.StringValue...)
+	case av.BoolValue != nil:
+		return strconv.AppendBool(dst, *av.BoolValue)
+	case av.IntValue != nil:
+		return strconv.AppendInt(dst, *av.IntValue, 10)
+	case av.DoubleValue != nil:
+		return strconv.AppendFloat(dst, *av.DoubleValue, 'g', -1, 64)
+	case av.ArrayValue != nil:
+		dst = append(dst, '[')
+		for i := range av.ArrayValue {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = av.ArrayValue[i].appendString(dst, true)
+		}
+		return append(dst, ']')
+	case av.KeyValueList != nil:
+		dst = append(dst, '{')
+		for i := range av.KeyValueList {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			kv := &av.KeyValueList[i]
+			dst = strconv.AppendQuote(dst, kv.Key)
+			dst = append(dst, ':')
+			dst = kv.Value.appendString(dst, true)
+		}
+		return append(dst, '}')
+	case av.BytesValue != nil:
+		s := base64.StdEncoding.EncodeToString(av.BytesValue)
+		if quote {
+			return strconv.AppendQuote(dst, s)
+		}
+		return append(dst, s...)
+	default:
+		if quote {
+			return append(dst, "null"...)
+		}
+		return dst
+	}
+}
+
+// Metric represents a single metric with data points.
+//
+// Only a single data field must be set in Metric.
+type Metric struct {
+	Name        string
+	Description string
+	Unit        string
+
+	Gauge     *Gauge
+	Sum       *Sum
+	Histogram *Histogram
+	Summary   *Summary
+
+	// ExponentialHistogram is set to true if the metric contains exponential histogram.
+	//
+	// Exponential histograms aren't supported yet, so their data points are skipped during parsing.
+	ExponentialHistogram bool
+}
+
+// Gauge represents the type of a scalar metric that always exports the current value for every data point.
+type Gauge struct {
+	DataPoints []NumberDataPoint
+}
+
+// Sum represents the type of a scalar metric that is calculated as a sum of all reported measurements over a time interval.
+type Sum struct {
+	DataPoints             []NumberDataPoint
+	AggregationTemporality AggregationTemporality
+	IsMonotonic            bool
+}
+
+// Histogram represents the type of a metric that is calculated by aggregating as a Histogram of all reported measurements over a time interval.
+type Histogram struct {
+	DataPoints             []HistogramDataPoint
+	AggregationTemporality AggregationTemporality
+}
+
+// Summary represents the type of a metric that is calculated by aggregating as a Summary of all reported double measurements over a time interval.
+type Summary struct {
+	DataPoints []SummaryDataPoint
+}
+
+// AggregationTemporality defines how a metric aggregator reports aggregated values.
+type AggregationTemporality int32
+
+// Supported AggregationTemporality values.
+const (
+	AggregationTemporalityUnspecified AggregationTemporality = 0
+	AggregationTemporalityDelta       AggregationTemporality = 1
+	AggregationTemporalityCumulative  AggregationTemporality = 2
+)
+
+// DataPointFlagNoRecordedValue is set in data point flags when the data point doesn't contain a recorded value.
+//
+// It is used as an equivalent of Prometheus staleness marker.
+const DataPointFlagNoRecordedValue = 1
+
+// NumberDataPoint is a single data point in a timeseries that describes the time-varying scalar value of a metric.
+type NumberDataPoint struct {
+	Attributes        []KeyValue
+	StartTimeUnixNano uint64
+	TimeUnixNano      uint64
+
+	// Only a single value field must be set.
+	DoubleValue *float64
+	IntValue    *int64
+
+	Flags uint32
+}
+
+// HistogramDataPoint is a single data point in a timeseries that describes the time-varying values of a Histogram.
+type HistogramDataPoint struct {
+	Attributes        []KeyValue
+	StartTimeUnixNano uint64
+	TimeUnixNano      uint64
+	Count             uint64
+	Sum               *float64
+
+	// BucketCounts contains non-cumulative counts for buckets defined by ExplicitBounds.
+	//
+	// len(BucketCounts) must be equal to len(ExplicitBounds)+1 if BucketCounts isn't empty.
+	BucketCounts   []uint64
+	ExplicitBounds []float64
+
+	Flags uint32
+}
+
+// SummaryDataPoint is a single data point in a timeseries that describes the time-varying values of a Summary metric.
+type SummaryDataPoint struct {
+	Attributes        []KeyValue
+	StartTimeUnixNano uint64
+	TimeUnixNano      uint64
+	Count             uint64
+	Sum               float64
+	QuantileValues    []ValueAtQuantile
+	Flags             uint32
+}
+
+// ValueAtQuantile represents the value at a given quantile of a distribution.
+type ValueAtQuantile struct {
+	Quantile float64
+	Value    float64
+}
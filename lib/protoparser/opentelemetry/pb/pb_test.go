@@ -0,0 +1,259 @@
+package pb
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestUnmarshalProtobufAndJSON(t *testing.T) {
+	f := func(data []byte, jsonData string) {
+		t.Helper()
+		var reqProtobuf ExportMetricsServiceRequest
+		if err := reqProtobuf.UnmarshalProtobuf(data); err != nil {
+			t.Fatalf("cannot unmarshal protobuf: %s", err)
+		}
+		var reqJSON ExportMetricsServiceRequest
+		if err := reqJSON.UnmarshalJSON([]byte(jsonData)); err != nil {
+			t.Fatalf("cannot unmarshal JSON: %s", err)
+		}
+		if !reflect.DeepEqual(&reqProtobuf, &reqJSON) {
+			t.Fatalf("protobuf and JSON requests mismatch;\nprotobuf:\n%#v\nJSON:\n%#v", &reqProtobuf, &reqJSON)
+		}
+	}
+
+	// Empty request
+	f(nil, `{}`)
+	f(nil, `{"resourceMetrics":[]}`)
+
+	// Resource attributes of all the types
+	f(appendMessage(nil, 1, appendMessage(nil, 1, concat(
+		appendKeyValue(nil, 1, "service.name", appendString(nil, 1, "foo")),
+		appendKeyValue(nil, 1, "bool", appendVarint(nil, 2, 1)),
+		appendKeyValue(nil, 1, "int", appendVarint(nil, 3, uint64(1<<64-5))),
+		appendKeyValue(nil, 1, "double", appendDouble(nil, 4, 1.5)),
+		appendKeyValue(nil, 1, "array", appendMessage(nil, 5, concat(
+			appendMessage(nil, 1, appendString(nil, 1, "a")),
+			appendMessage(nil, 1, appendVarint(nil, 3, 2)),
+		))),
+		appendKeyValue(nil, 1, "kvlist", appendMessage(nil, 6, appendKeyValue(nil, 1, "k", appendString(nil, 1, "v")))),
+		appendKeyValue(nil, 1, "bytes", appendMessage(nil, 7, []byte("ab"))),
+		// unknown field must be ignored
+		appendVarint(nil, 15, 123),
+	))), `{"resourceMetrics":[{"resource":{"attributes":[
+		{"key":"service.name","value":{"stringValue":"foo"}},
+		{"key":"bool","value":{"boolValue":true}},
+		{"key":"int","value":{"intValue":"-5"}},
+		{"key":"double","value":{"doubleValue":1.5}},
+		{"key":"array","value":{"arrayValue":{"values":[{"stringValue":"a"},{"intValue":2}]}}},
+		{"key":"kvlist","value":{"kvlistValue":{"values":[{"key":"k","value":{"stringValue":"v"}}]}}},
+		{"key":"bytes","value":{"bytesValue":"YWI="}}
+	]},"unknownField":123}]}`)
+
+	// All the metric types
+	numberDataPoint := concat(
+		appendFixed64(nil, 2, 1e12),
+		appendFixed64(nil, 3, 2e12),
+		appendDouble(nil, 4, 21.5),
+		appendKeyValue(nil, 7, "room", appendString(nil, 1, "kitchen")),
+	)
+	intDataPoint := concat(
+		appendFixed64(nil, 3, 2e12),
+		appendFixed64(nil, 6, uint64(1<<64-10)),
+		appendVarint(nil, 8, 1),
+	)
+	histogramDataPoint := concat(
+		appendFixed64(nil, 3, 2e12),
+		appendFixed64(nil, 4, 3),
+		appendDouble(nil, 5, 1.5),
+		// packed bucket_counts
+		appendMessage(nil, 6, concat(
+			protowire.AppendFixed64(nil, 1),
+			protowire.AppendFixed64(nil, 2),
+		)),
+		// non-packed bucket_counts
+		appendFixed64(nil, 6, 0),
+		appendMessage(nil, 7, concat(
+			protowire.AppendFixed64(nil, math.Float64bits(0.1)),
+			protowire.AppendFixed64(nil, math.Float64bits(1)),
+		)),
+		appendKeyValue(nil, 9, "path", appendString(nil, 1, "/")),
+	)
+	summaryDataPoint := concat(
+		appendFixed64(nil, 3, 2e12),
+		appendFixed64(nil, 4, 2),
+		appendDouble(nil, 5, 3),
+		appendMessage(nil, 6, concat(appendDouble(nil, 1, 0.5), appendDouble(nil, 2, 1))),
+		appendMessage(nil, 6, concat(appendDouble(nil, 1, 0.99), appendDouble(nil, 2, 2))),
+	)
+	scopeMetrics := concat(
+		appendMessage(nil, 1, concat(
+			appendString(nil, 1, "lib"),
+			appendString(nil, 2, "1.0"),
+			appendKeyValue(nil, 3, "scope.attr", appendString(nil, 1, "x")),
+		)),
+		appendMessage(nil, 2, concat(
+			appendString(nil, 1, "temperature"),
+			appendString(nil, 2, "Room temperature"),
+			appendString(nil, 3, "C"),
+			appendMessage(nil, 5, appendMessage(nil, 1, numberDataPoint)),
+		)),
+		appendMessage(nil, 2, concat(
+			appendString(nil, 1, "requests"),
+			appendMessage(nil, 7, concat(
+				appendMessage(nil, 1, intDataPoint),
+				appendVarint(nil, 2, uint64(AggregationTemporalityDelta)),
+				appendVarint(nil, 3, 1),
+			)),
+		)),
+		appendMessage(nil, 2, concat(
+			appendString(nil, 1, "latency"),
+			appendMessage(nil, 9, concat(
+				appendMessage(nil, 1, histogramDataPoint),
+				appendVarint(nil, 2, uint64(AggregationTemporalityCumulative)),
+			)),
+		)),
+		appendMessage(nil, 2, concat(
+			appendString(nil, 1, "rpc"),
+			appendMessage(nil, 11, appendMessage(nil, 1, summaryDataPoint)),
+		)),
+		appendMessage(nil, 2, concat(
+			appendString(nil, 1, "exp"),
+			appendMessage(nil, 10, nil),
+		)),
+	)
+	f(appendMessage(nil, 1, appendMessage(nil, 2, scopeMetrics)), `{"resourceMetrics":[{"scopeMetrics":[{
+		"scope":{"name":"lib","version":"1.0","attributes":[{"key":"scope.attr","value":{"stringValue":"x"}}]},
+		"metrics":[
+			{"name":"temperature","description":"Room temperature","unit":"C","gauge":{"dataPoints":[
+				{"startTimeUnixNano":1000000000000,"timeUnixNano":"2000000000000","asDouble":21.5,"attributes":[{"key":"room","value":{"stringValue":"kitchen"}}]}
+			]}},
+			{"name":"requests","sum":{"aggregationTemporality":"AGGREGATION_TEMPORALITY_DELTA","isMonotonic":true,"dataPoints":[
+				{"timeUnixNano":"2000000000000","asInt":"-10","flags":1}
+			]}},
+			{"name":"latency","histogram":{"aggregationTemporality":2,"dataPoints":[
+				{"timeUnixNano":"2000000000000","count":"3","sum":1.5,"bucketCounts":["1",2,"0"],"explicitBounds":[0.1,1],
+				 "attributes":[{"key":"path","value":{"stringValue":"/"}}]}
+			]}},
+			{"name":"rpc","summary":{"dataPoints":[
+				{"timeUnixNano":"2000000000000","count":"2","sum":3,"quantileValues":[{"quantile":0.5,"value":1},{"quantile":0.99,"value":2}]}
+			]}},
+			{"name":"exp","exponentialHistogram":{}}
+		]
+	}]}]}`)
+
+	// Deprecated instrumentation_library_metrics
+	f(appendMessage(nil, 1, appendMessage(nil, 1000, concat(
+		appendMessage(nil, 1, appendString(nil, 1, "lib")),
+		appendMessage(nil, 2, concat(
+			appendString(nil, 1, "temperature"),
+			appendMessage(nil, 5, appendMessage(nil, 1, numberDataPoint)),
+		)),
+	))), `{"resourceMetrics":[{"instrumentationLibraryMetrics":[{
+		"instrumentationLibrary":{"name":"lib"},
+		"metrics":[{"name":"temperature","gauge":{"dataPoints":[
+			{"startTimeUnixNano":"1000000000000","timeUnixNano":"2000000000000","asDouble":21.5,"attributes":[{"key":"room","value":{"stringValue":"kitchen"}}]}
+		]}}]
+	}]}]}`)
+}
+
+func TestUnmarshalProtobufFailure(t *testing.T) {
+	f := func(data []byte) {
+		t.Helper()
+		var req ExportMetricsServiceRequest
+		if err := req.UnmarshalProtobuf(data); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// Truncated tag
+	f([]byte{0xff})
+	// Truncated message
+	f(appendMessage(nil, 1, []byte("foo"))[:3])
+	// Invalid wire type for resource_metrics
+	f(appendVarint(nil, 1, 123))
+	// Invalid wire type for metric name
+	f(appendMessage(nil, 1, appendMessage(nil, 2, appendMessage(nil, 2, appendVarint(nil, 1, 1)))))
+	// Truncated packed bucket counts
+	f(appendMessage(nil, 1, appendMessage(nil, 2, appendMessage(nil, 2, appendMessage(nil, 9,
+		appendMessage(nil, 1, appendMessage(nil, 6, []byte{1, 2, 3})))))))
+}
+
+func TestUnmarshalJSONFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		var req ExportMetricsServiceRequest
+		if err := req.UnmarshalJSON([]byte(s)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	f(``)
+	f(`[]`)
+	f(`{"resourceMetrics":{}}`)
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[{"name":123}]}]}]}`)
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[{"name":"foo","gauge":{"dataPoints":[{"timeUnixNano":"bar"}]}}]}]}]}`)
+	f(`{"resourceMetrics":[{"scopeMetrics":[{"metrics":[{"name":"foo","sum":{"aggregationTemporality":"foo"}}]}]}]}`)
+	f(`{"resourceMetrics":[{"resource":{"attributes":[{"key":"foo","value":{"bytesValue":"!"}}]}}]}`)
+}
+
+func TestAnyValueFormatString(t *testing.T) {
+	f := func(av *AnyValue, resultExpected string) {
+		t.Helper()
+		result := av.FormatString()
+		if result != resultExpected {
+			t.Fatalf("unexpected result; got %q; want %q", result, resultExpected)
+		}
+	}
+	s := "foo"
+	b := true
+	n := int64(-12)
+	d := 1.25
+	f(&AnyValue{}, "")
+	f(&AnyValue{StringValue: &s}, "foo")
+	f(&AnyValue{BoolValue: &b}, "true")
+	f(&AnyValue{IntValue: &n}, "-12")
+	f(&AnyValue{DoubleValue: &d}, "1.25")
+	f(&AnyValue{BytesValue: []byte("ab")}, "YWI=")
+	f(&AnyValue{ArrayValue: []AnyValue{{StringValue: &s}, {IntValue: &n}, {}}}, `["foo",-12,null]`)
+	f(&AnyValue{KeyValueList: []KeyValue{
+		{Key: "a", Value: AnyValue{StringValue: &s}},
+		{Key: "b", Value: AnyValue{ArrayValue: []AnyValue{{BoolValue: &b}}}},
+	}}, `{"a":"foo","b":[true]}`)
+}
+
+func concat(a ...[]byte) []byte {
+	var dst []byte
+	for _, b := range a {
+		dst = append(dst, b...)
+	}
+	return dst
+}
+
+func appendMessage(dst []byte, num protowire.Number, data []byte) []byte {
+	dst = protowire.AppendTag(dst, num, protowire.BytesType)
+	return protowire.AppendBytes(dst, data)
+}
+
+func appendString(dst []byte, num protowire.Number, s string) []byte {
+	return appendMessage(dst, num, []byte(s))
+}
+
+func appendVarint(dst []byte, num protowire.Number, n uint64) []byte {
+	dst = protowire.AppendTag(dst, num, protowire.VarintType)
+	return protowire.AppendVarint(dst, n)
+}
+
+func appendFixed64(dst []byte, num protowire.Number, n uint64) []byte {
+	dst = protowire.AppendTag(dst, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(dst, n)
+}
+
+func appendDouble(dst []byte, num protowire.Number, f float64) []byte {
+	return appendFixed64(dst, num, math.Float64bits(f))
+}
+
+// appendKeyValue appends KeyValue field num with the given key and AnyValue message at value to dst.
+func appendKeyValue(dst []byte, num protowire.Number, key string, value []byte) []byte {
+	return appendMessage(dst, num, concat(appendString(nil, 1, key), appendMessage(nil, 2, value)))
+}
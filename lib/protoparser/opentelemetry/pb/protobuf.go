@@ -0,0 +1,556 @@
+package pb
+
+import (
+	"fmt"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// UnmarshalProtobuf unmarshals req from protobuf message at src.
+func (req *ExportMetricsServiceRequest) UnmarshalProtobuf(src []byte) error {
+	req.Reset()
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			data, err := fld.message("resource_metrics")
+			if err != nil {
+				return err
+			}
+			req.ResourceMetrics = append(req.ResourceMetrics, ResourceMetrics{})
+			rm := &req.ResourceMetrics[len(req.ResourceMetrics)-1]
+			if err := rm.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal resource_metrics: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (rm *ResourceMetrics) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			data, err := fld.message("resource")
+			if err != nil {
+				return err
+			}
+			if err := rm.Resource.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal resource: %w", err)
+			}
+		case 2, 1000:
+			// The field 1000 contains deprecated instrumentation_library_metrics, which is still sent by old OpenTelemetry SDKs.
+			// It is wire-compatible with scope_metrics.
+			data, err := fld.message("scope_metrics")
+			if err != nil {
+				return err
+			}
+			rm.ScopeMetrics = append(rm.ScopeMetrics, ScopeMetrics{})
+			sm := &rm.ScopeMetrics[len(rm.ScopeMetrics)-1]
+			if err := sm.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal scope_metrics: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (r *Resource) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			return fld.appendKeyValue(&r.Attributes)
+		}
+		return nil
+	})
+}
+
+func (sm *ScopeMetrics) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			data, err := fld.message("scope")
+			if err != nil {
+				return err
+			}
+			if err := sm.Scope.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal scope: %w", err)
+			}
+		case 2:
+			data, err := fld.message("metrics")
+			if err != nil {
+				return err
+			}
+			sm.Metrics = append(sm.Metrics, Metric{})
+			m := &sm.Metrics[len(sm.Metrics)-1]
+			if err := m.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal metric: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (is *InstrumentationScope) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			return fld.string("name", &is.Name)
+		case 2:
+			return fld.string("version", &is.Version)
+		case 3:
+			return fld.appendKeyValue(&is.Attributes)
+		}
+		return nil
+	})
+}
+
+func (kv *KeyValue) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			return fld.string("key", &kv.Key)
+		case 2:
+			data, err := fld.message("value")
+			if err != nil {
+				return err
+			}
+			if err := kv.Value.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal value for key %q: %w", kv.Key, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (av *AnyValue) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			var s string
+			if err := fld.string("string_value", &s); err != nil {
+				return err
+			}
+			av.StringValue = &s
+		case 2:
+			n, err := fld.varint("bool_value")
+			if err != nil {
+				return err
+			}
+			b := n != 0
+			av.BoolValue = &b
+		case 3:
+			n, err := fld.varint("int_value")
+			if err != nil {
+				return err
+			}
+			v := int64(n)
+			av.IntValue = &v
+		case 4:
+			n, err := fld.fixed64("double_value")
+			if err != nil {
+				return err
+			}
+			f := math.Float64frombits(n)
+			av.DoubleValue = &f
+		case 5:
+			data, err := fld.message("array_value")
+			if err != nil {
+				return err
+			}
+			av.ArrayValue = []AnyValue{}
+			return forEachField(data, func(fld *field) error {
+				if fld.num != 1 {
+					return nil
+				}
+				data, err := fld.message("values")
+				if err != nil {
+					return err
+				}
+				av.ArrayValue = append(av.ArrayValue, AnyValue{})
+				return av.ArrayValue[len(av.ArrayValue)-1].unmarshalProtobuf(data)
+			})
+		case 6:
+			data, err := fld.message("kvlist_value")
+			if err != nil {
+				return err
+			}
+			av.KeyValueList = []KeyValue{}
+			return forEachField(data, func(fld *field) error {
+				if fld.num != 1 {
+					return nil
+				}
+				return fld.appendKeyValue(&av.KeyValueList)
+			})
+		case 7:
+			data, err := fld.message("bytes_value")
+			if err != nil {
+				return err
+			}
+			av.BytesValue = append([]byte{}, data...)
+		}
+		return nil
+	})
+}
+
+func (m *Metric) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			return fld.string("name", &m.Name)
+		case 2:
+			return fld.string("description", &m.Description)
+		case 3:
+			return fld.string("unit", &m.Unit)
+		case 5:
+			data, err := fld.message("gauge")
+			if err != nil {
+				return err
+			}
+			m.Gauge = &Gauge{}
+			return forEachField(data, func(fld *field) error {
+				if fld.num != 1 {
+					return nil
+				}
+				return fld.appendNumberDataPoint(&m.Gauge.DataPoints)
+			})
+		case 7:
+			data, err := fld.message("sum")
+			if err != nil {
+				return err
+			}
+			m.Sum = &Sum{}
+			return m.Sum.unmarshalProtobuf(data)
+		case 9:
+			data, err := fld.message("histogram")
+			if err != nil {
+				return err
+			}
+			m.Histogram = &Histogram{}
+			return m.Histogram.unmarshalProtobuf(data)
+		case 10:
+			m.ExponentialHistogram = true
+		case 11:
+			data, err := fld.message("summary")
+			if err != nil {
+				return err
+			}
+			m.Summary = &Summary{}
+			return forEachField(data, func(fld *field) error {
+				if fld.num != 1 {
+					return nil
+				}
+				data, err := fld.message("data_points")
+				if err != nil {
+					return err
+				}
+				m.Summary.DataPoints = append(m.Summary.DataPoints, SummaryDataPoint{})
+				dp := &m.Summary.DataPoints[len(m.Summary.DataPoints)-1]
+				if err := dp.unmarshalProtobuf(data); err != nil {
+					return fmt.Errorf("cannot unmarshal summary data point: %w", err)
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+}
+
+func (s *Sum) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			return fld.appendNumberDataPoint(&s.DataPoints)
+		case 2:
+			n, err := fld.varint("aggregation_temporality")
+			if err != nil {
+				return err
+			}
+			s.AggregationTemporality = AggregationTemporality(n)
+		case 3:
+			n, err := fld.varint("is_monotonic")
+			if err != nil {
+				return err
+			}
+			s.IsMonotonic = n != 0
+		}
+		return nil
+	})
+}
+
+func (h *Histogram) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			data, err := fld.message("data_points")
+			if err != nil {
+				return err
+			}
+			h.DataPoints = append(h.DataPoints, HistogramDataPoint{})
+			dp := &h.DataPoints[len(h.DataPoints)-1]
+			if err := dp.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal histogram data point: %w", err)
+			}
+		case 2:
+			n, err := fld.varint("aggregation_temporality")
+			if err != nil {
+				return err
+			}
+			h.AggregationTemporality = AggregationTemporality(n)
+		}
+		return nil
+	})
+}
+
+func (dp *NumberDataPoint) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		var err error
+		switch fld.num {
+		case 2:
+			dp.StartTimeUnixNano, err = fld.fixed64("start_time_unix_nano")
+		case 3:
+			dp.TimeUnixNano, err = fld.fixed64("time_unix_nano")
+		case 4:
+			var n uint64
+			n, err = fld.fixed64("as_double")
+			f := math.Float64frombits(n)
+			dp.DoubleValue = &f
+		case 6:
+			var n uint64
+			n, err = fld.fixed64("as_int")
+			v := int64(n)
+			dp.IntValue = &v
+		case 7:
+			err = fld.appendKeyValue(&dp.Attributes)
+		case 8:
+			var n uint64
+			n, err = fld.varint("flags")
+			dp.Flags = uint32(n)
+		}
+		return err
+	})
+}
+
+func (dp *HistogramDataPoint) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		var err error
+		switch fld.num {
+		case 2:
+			dp.StartTimeUnixNano, err = fld.fixed64("start_time_unix_nano")
+		case 3:
+			dp.TimeUnixNano, err = fld.fixed64("time_unix_nano")
+		case 4:
+			dp.Count, err = fld.fixed64("count")
+		case 5:
+			var n uint64
+			n, err = fld.fixed64("sum")
+			f := math.Float64frombits(n)
+			dp.Sum = &f
+		case 6:
+			err = fld.appendFixed64s("bucket_counts", func(n uint64) {
+				dp.BucketCounts = append(dp.BucketCounts, n)
+			})
+		case 7:
+			err = fld.appendFixed64s("explicit_bounds", func(n uint64) {
+				dp.ExplicitBounds = append(dp.ExplicitBounds, math.Float64frombits(n))
+			})
+		case 9:
+			err = fld.appendKeyValue(&dp.Attributes)
+		case 10:
+			var n uint64
+			n, err = fld.varint("flags")
+			dp.Flags = uint32(n)
+		}
+		return err
+	})
+}
+
+func (dp *SummaryDataPoint) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		var err error
+		switch fld.num {
+		case 2:
+			dp.StartTimeUnixNano, err = fld.fixed64("start_time_unix_nano")
+		case 3:
+			dp.TimeUnixNano, err = fld.fixed64("time_unix_nano")
+		case 4:
+			dp.Count, err = fld.fixed64("count")
+		case 5:
+			var n uint64
+			n, err = fld.fixed64("sum")
+			dp.Sum = math.Float64frombits(n)
+		case 6:
+			var data []byte
+			data, err = fld.message("quantile_values")
+			if err != nil {
+				return err
+			}
+			dp.QuantileValues = append(dp.QuantileValues, ValueAtQuantile{})
+			err = dp.QuantileValues[len(dp.QuantileValues)-1].unmarshalProtobuf(data)
+		case 7:
+			err = fld.appendKeyValue(&dp.Attributes)
+		case 8:
+			var n uint64
+			n, err = fld.varint("flags")
+			dp.Flags = uint32(n)
+		}
+		return err
+	})
+}
+
+func (vq *ValueAtQuantile) unmarshalProtobuf(src []byte) error {
+	return forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			n, err := fld.fixed64("quantile")
+			if err != nil {
+				return err
+			}
+			vq.Quantile = math.Float64frombits(n)
+		case 2:
+			n, err := fld.fixed64("value")
+			if err != nil {
+				return err
+			}
+			vq.Value = math.Float64frombits(n)
+		}
+		return nil
+	})
+}
+
+// field is a single protobuf field.
+type field struct {
+	num protowire.Number
+	typ protowire.Type
+
+	// data contains the field value for protowire.BytesType
+	data []byte
+
+	// n contains the field value for protowire.VarintType, protowire.Fixed32Type and protowire.Fixed64Type
+	n uint64
+}
+
+// forEachField calls f for every field in the protobuf message at src.
+//
+// Unknown fields must be ignored by f for forward compatibility.
+func forEachField(src []byte, f func(fld *field) error) error {
+	for len(src) > 0 {
+		num, typ, n := protowire.ConsumeTag(src)
+		if n < 0 {
+			return fmt.Errorf("cannot read field tag: %w", protowire.ParseError(n))
+		}
+		src = src[n:]
+		fld := field{
+			num: num,
+			typ: typ,
+		}
+		switch typ {
+		case protowire.VarintType:
+			fld.n, n = protowire.ConsumeVarint(src)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(src)
+			fld.n = uint64(v)
+		case protowire.Fixed64Type:
+			fld.n, n = protowire.ConsumeFixed64(src)
+		case protowire.BytesType:
+			fld.data, n = protowire.ConsumeBytes(src)
+		default:
+			// Skip deprecated groups.
+			n = protowire.ConsumeFieldValue(num, typ, src)
+		}
+		if n < 0 {
+			return fmt.Errorf("cannot read field #%d: %w", num, protowire.ParseError(n))
+		}
+		src = src[n:]
+		if err := f(&fld); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fld *field) checkType(name string, typ protowire.Type) error {
+	if fld.typ != typ {
+		return fmt.Errorf("unexpected wire type %d for field %s; want %d", fld.typ, name, typ)
+	}
+	return nil
+}
+
+func (fld *field) message(name string) ([]byte, error) {
+	if err := fld.checkType(name, protowire.BytesType); err != nil {
+		return nil, err
+	}
+	return fld.data, nil
+}
+
+func (fld *field) string(name string, dst *string) error {
+	if err := fld.checkType(name, protowire.BytesType); err != nil {
+		return err
+	}
+	*dst = string(fld.data)
+	return nil
+}
+
+func (fld *field) varint(name string) (uint64, error) {
+	if err := fld.checkType(name, protowire.VarintType); err != nil {
+		return 0, err
+	}
+	return fld.n, nil
+}
+
+func (fld *field) fixed64(name string) (uint64, error) {
+	if err := fld.checkType(name, protowire.Fixed64Type); err != nil {
+		return 0, err
+	}
+	return fld.n, nil
+}
+
+// appendFixed64s calls f for every value in repeated fixed64 or double field.
+//
+// Both packed and non-packed encodings are supported.
+func (fld *field) appendFixed64s(name string, f func(n uint64)) error {
+	if fld.typ == protowire.Fixed64Type {
+		f(fld.n)
+		return nil
+	}
+	if err := fld.checkType(name, protowire.BytesType); err != nil {
+		return err
+	}
+	src := fld.data
+	for len(src) > 0 {
+		v, n := protowire.ConsumeFixed64(src)
+		if n < 0 {
+			return fmt.Errorf("cannot read packed %s: %w", name, protowire.ParseError(n))
+		}
+		src = src[n:]
+		f(v)
+	}
+	return nil
+}
+
+func (fld *field) appendKeyValue(dst *[]KeyValue) error {
+	data, err := fld.message("attributes")
+	if err != nil {
+		return err
+	}
+	*dst = append(*dst, KeyValue{})
+	kv := &(*dst)[len(*dst)-1]
+	if err := kv.unmarshalProtobuf(data); err != nil {
+		return fmt.Errorf("cannot unmarshal attribute: %w", err)
+	}
+	return nil
+}
+
+func (fld *field) appendNumberDataPoint(dst *[]NumberDataPoint) error {
+	data, err := fld.message("data_points")
+	if err != nil {
+		return err
+	}
+	*dst = append(*dst, NumberDataPoint{})
+	dp := &(*dst)[len(*dst)-1]
+	if err := dp.unmarshalProtobuf(data); err != nil {
+		return fmt.Errorf("cannot unmarshal number data point: %w", err)
+	}
+	return nil
+}
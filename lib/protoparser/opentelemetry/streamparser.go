@@ -0,0 +1,176 @@
+package opentelemetry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/opentelemetry/pb"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var maxRequestSize = flagutil.NewBytes("opentelemetry.maxRequestSize", 64*1024*1024, "The maximum size in bytes of a single OpenTelemetry request to /opentelemetry/api/v1/push")
+
+// ParseStream parses OpenTelemetry metrics export request from r and calls callback for the parsed time series and metric metadata.
+//
+// The request is parsed in OTLP/HTTP JSON format if contentType is application/json. Otherwise it is parsed in OTLP/HTTP protobuf format.
+// See https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md#otlphttp
+//
+// Metrics are converted into Prometheus-style series:
+//   - resource, scope and data point attributes are converted into labels;
+//   - histograms are converted into name_bucket{le="..."}, name_count and name_sum series;
+//   - summaries are converted into name{quantile="..."}, name_count and name_sum series;
+//   - sums and histograms with delta aggregation temporality are converted into cumulative series.
+//
+// callback shouldn't hold tss and mms after returning.
+func ParseStream(r io.Reader, contentType, contentEncoding string, callback func(tss []prompbmarshal.TimeSeries, mms []prompbmarshal.MetricMetadata) error) error {
+	switch contentEncoding {
+	case "gzip":
+		zr, err := common.GetGzipReader(r)
+		if err != nil {
+			return fmt.Errorf("cannot read gzipped OpenTelemetry data: %w", err)
+		}
+		defer common.PutGzipReader(zr)
+		r = zr
+	case "deflate":
+		zlr, err := common.GetZlibReader(r)
+		if err != nil {
+			return fmt.Errorf("cannot read deflated OpenTelemetry data: %w", err)
+		}
+		defer common.PutZlibReader(zlr)
+		r = zlr
+	}
+	ctx := getPushCtx(r)
+	defer putPushCtx(ctx)
+	if err := ctx.Read(); err != nil {
+		return err
+	}
+	req := getRequest()
+	defer putRequest(req)
+	if strings.HasPrefix(contentType, "application/json") {
+		if err := req.UnmarshalJSON(ctx.reqBuf.B); err != nil {
+			unmarshalErrors.Inc()
+			return fmt.Errorf("cannot unmarshal OpenTelemetry JSON request with size %d bytes: %w", len(ctx.reqBuf.B), err)
+		}
+	} else {
+		if err := req.UnmarshalProtobuf(ctx.reqBuf.B); err != nil {
+			unmarshalErrors.Inc()
+			return fmt.Errorf("cannot unmarshal OpenTelemetry protobuf request with size %d bytes: %w", len(ctx.reqBuf.B), err)
+		}
+	}
+
+	wctx := getWriteContext()
+	defer putWriteContext(wctx)
+	if err := wctx.parseRequest(req); err != nil {
+		unmarshalErrors.Inc()
+		return fmt.Errorf("cannot convert OpenTelemetry request: %w", err)
+	}
+	rowsRead.Add(wctx.rows)
+
+	if err := callback(wctx.tss, wctx.mms); err != nil {
+		return fmt.Errorf("error when processing imported data: %w", err)
+	}
+	return nil
+}
+
+type pushCtx struct {
+	br     *bufio.Reader
+	reqBuf bytesutil.ByteBuffer
+}
+
+func (ctx *pushCtx) reset() {
+	ctx.br.Reset(nil)
+	ctx.reqBuf.Reset()
+}
+
+func (ctx *pushCtx) Read() error {
+	readCalls.Inc()
+	lr := io.LimitReader(ctx.br, int64(maxRequestSize.N)+1)
+	startTime := fasttime.UnixTimestamp()
+	reqLen, err := ctx.reqBuf.ReadFrom(lr)
+	if err != nil {
+		readErrors.Inc()
+		return fmt.Errorf("cannot read request in %d seconds: %w", fasttime.UnixTimestamp()-startTime, err)
+	}
+	if reqLen > int64(maxRequestSize.N) {
+		readErrors.Inc()
+		return fmt.Errorf("too big request; mustn't exceed `-opentelemetry.maxRequestSize=%d` bytes", maxRequestSize.N)
+	}
+	return nil
+}
+
+var (
+	readCalls       = metrics.NewCounter(`vm_protoparser_read_calls_total{type="opentelemetry"}`)
+	readErrors      = metrics.NewCounter(`vm_protoparser_read_errors_total{type="opentelemetry"}`)
+	rowsRead        = metrics.NewCounter(`vm_protoparser_rows_read_total{type="opentelemetry"}`)
+	unmarshalErrors = metrics.NewCounter(`vm_protoparser_unmarshal_errors_total{type="opentelemetry"}`)
+
+	skippedExponentialHistograms = metrics.NewCounter(`vm_protoparser_opentelemetry_skipped_exponential_histograms_total`)
+)
+
+func getPushCtx(r io.Reader) *pushCtx {
+	select {
+	case ctx := <-pushCtxPoolCh:
+		ctx.br.Reset(r)
+		return ctx
+	default:
+		if v := pushCtxPool.Get(); v != nil {
+			ctx := v.(*pushCtx)
+			ctx.br.Reset(r)
+			return ctx
+		}
+		return &pushCtx{
+			br: bufio.NewReaderSize(r, 64*1024),
+		}
+	}
+}
+
+func putPushCtx(ctx *pushCtx) {
+	ctx.reset()
+	select {
+	case pushCtxPoolCh <- ctx:
+	default:
+		pushCtxPool.Put(ctx)
+	}
+}
+
+var pushCtxPool sync.Pool
+var pushCtxPoolCh = make(chan *pushCtx, cgroup.AvailableCPUs())
+
+func getRequest() *pb.ExportMetricsServiceRequest {
+	v := requestPool.Get()
+	if v == nil {
+		return &pb.ExportMetricsServiceRequest{}
+	}
+	return v.(*pb.ExportMetricsServiceRequest)
+}
+
+func putRequest(req *pb.ExportMetricsServiceRequest) {
+	req.Reset()
+	requestPool.Put(req)
+}
+
+var requestPool sync.Pool
+
+func getWriteContext() *writeContext {
+	v := writeContextPool.Get()
+	if v == nil {
+		return &writeContext{}
+	}
+	return v.(*writeContext)
+}
+
+func putWriteContext(wctx *writeContext) {
+	wctx.reset()
+	writeContextPool.Put(wctx)
+}
+
+var writeContextPool sync.Pool
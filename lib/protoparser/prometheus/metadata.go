@@ -0,0 +1,95 @@
+package prometheus
+
+import (
+	"strings"
+)
+
+// Metadata contains metadata for the metric family exposed via `# TYPE`, `# HELP` and `# UNIT` comments.
+//
+// See https://github.com/prometheus/docs/blob/master/content/docs/instrumenting/exposition_formats.md#comments-help-text-and-type-information
+// and https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#metricfamily
+type Metadata struct {
+	// Metric is the metric family name.
+	Metric string
+
+	// Type is the metric family type such as counter, gauge, histogram, summary, etc.
+	Type string
+
+	// Help is the help text for the metric family.
+	Help string
+
+	// Unit is the unit for the metric family.
+	Unit string
+}
+
+// ParseMetadata appends metadata for metric families found in Prometheus exposition text s to dst and returns the result.
+//
+// Lines other than `# TYPE`, `# HELP` and `# UNIT` comments are ignored.
+// Subsequent comments for the same metric family are merged into a single Metadata entry.
+//
+// The returned metadata refers to s, so s shouldn't be modified while the metadata is in use.
+func ParseMetadata(dst []Metadata, s string) []Metadata {
+	dstLen := len(dst)
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '\n')
+		line := s
+		if n >= 0 {
+			line = s[:n]
+			s = s[n+1:]
+		} else {
+			s = ""
+		}
+		dst = parseMetadataLine(dst, dstLen, line)
+	}
+	return dst
+}
+
+func parseMetadataLine(dst []Metadata, dstLen int, s string) []Metadata {
+	if len(s) > 0 && s[len(s)-1] == '\r' {
+		s = s[:len(s)-1]
+	}
+	s = skipLeadingWhitespace(s)
+	if len(s) == 0 || s[0] != '#' {
+		return dst
+	}
+	s = skipLeadingWhitespace(s[1:])
+	n := nextWhitespace(s)
+	if n < 0 {
+		return dst
+	}
+	kind := s[:n]
+	if kind != "TYPE" && kind != "HELP" && kind != "UNIT" {
+		return dst
+	}
+	s = skipLeadingWhitespace(s[n:])
+	n = nextWhitespace(s)
+	metric := s
+	value := ""
+	if n >= 0 {
+		metric = s[:n]
+		value = skipLeadingWhitespace(s[n:])
+	}
+	if len(metric) == 0 {
+		return dst
+	}
+
+	// Metadata comments for the same metric family are usually located next to each other.
+	var md *Metadata
+	if len(dst) > dstLen && dst[len(dst)-1].Metric == metric {
+		md = &dst[len(dst)-1]
+	} else {
+		dst = append(dst, Metadata{
+			Metric: metric,
+		})
+		md = &dst[len(dst)-1]
+	}
+	switch kind {
+	case "TYPE":
+		md.Type = skipTrailingWhitespace(value)
+	case "HELP":
+		md.Help = unescapeValue(value)
+	case "UNIT":
+		md.Unit = skipTrailingWhitespace(value)
+	}
+	return dst
+}
@@ -0,0 +1,51 @@
+package prometheus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMetadata(t *testing.T) {
+	f := func(s string, resultExpected []Metadata) {
+		t.Helper()
+		result := ParseMetadata(nil, s)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result for ParseMetadata(%q)\ngot\n%+v\nwant\n%+v", s, result, resultExpected)
+		}
+	}
+
+	// Empty input
+	f("", nil)
+	f("foo 123\nbar{baz=\"x\"} 34\n", nil)
+
+	// Ordinary comments must be ignored
+	f("# foo bar\n#\n# TYPE\n", nil)
+
+	// Single metric family
+	f(`# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+`, []Metadata{{
+		Metric: "http_requests_total",
+		Type:   "counter",
+		Help:   "The total number of HTTP requests.",
+	}})
+
+	// Multiple metric families with escaped help and unit
+	f("# TYPE foo_seconds histogram\r\n# UNIT foo_seconds seconds\r\n# HELP foo_seconds Line1\\nLine2 \\\\ end\r\nfoo_seconds_count 3\r\n"+
+		"#\tTYPE\tbar gauge \nbar 1\n# HELP baz\n", []Metadata{
+		{
+			Metric: "foo_seconds",
+			Type:   "histogram",
+			Help:   "Line1\nLine2 \\ end",
+			Unit:   "seconds",
+		},
+		{
+			Metric: "bar",
+			Type:   "gauge",
+		},
+		{
+			Metric: "baz",
+		},
+	})
+}
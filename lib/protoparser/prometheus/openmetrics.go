@@ -0,0 +1,217 @@
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+)
+
+// IsOpenMetricsContentType returns true if contentType corresponds to OpenMetrics text exposition format.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#overall-structure
+func IsOpenMetricsContentType(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	return strings.HasPrefix(contentType, "application/openmetrics-text")
+}
+
+// AppendOpenMetricsText validates OpenMetrics text exposition at src and appends it to dst.
+//
+// If strict is set, then an error is returned if src doesn't end with `# EOF` line, if `# EOF` line is followed by other lines
+// or if `# TYPE` comment contains unsupported metric type. Missing `# EOF` line usually means the response has been truncated.
+//
+// If dropCreated is set, then `<name>_created` samples are dropped for counter, summary, histogram and gaugehistogram metric families.
+//
+// The `# EOF` line isn't appended to dst, so the result can be parsed with Rows.Unmarshal.
+func AppendOpenMetricsText(dst, src []byte, strict, dropCreated bool) ([]byte, error) {
+	f := openMetricsFilter{
+		strict:      strict,
+		dropCreated: dropCreated,
+	}
+	s := bytesutil.ToUnsafeString(src)
+	for len(s) > 0 {
+		line := s
+		n := strings.IndexByte(s, '\n')
+		if n >= 0 {
+			line = s[:n]
+			s = s[n+1:]
+		} else {
+			s = ""
+		}
+		var err error
+		dst, err = f.appendLine(dst, line)
+		if err != nil {
+			return dst, err
+		}
+	}
+	return dst, f.finish()
+}
+
+// NewOpenMetricsReader returns a reader, which validates OpenMetrics text exposition read from r in the same way as AppendOpenMetricsText does.
+//
+// The returned reader returns an error at the end of r if the validation fails.
+func NewOpenMetricsReader(r io.Reader, strict, dropCreated bool) io.Reader {
+	return &openMetricsReader{
+		br: bufio.NewReaderSize(r, 64*1024),
+		f: openMetricsFilter{
+			strict:      strict,
+			dropCreated: dropCreated,
+		},
+	}
+}
+
+type openMetricsReader struct {
+	br *bufio.Reader
+	f  openMetricsFilter
+
+	// line contains the last line read from br
+	line []byte
+
+	// buf contains the filtered data, which isn't returned to the caller yet.
+	buf       []byte
+	bufOffset int
+
+	err error
+}
+
+// Read implements io.Reader interface.
+func (omr *openMetricsReader) Read(p []byte) (int, error) {
+	for omr.bufOffset >= len(omr.buf) {
+		if omr.err != nil {
+			return 0, omr.err
+		}
+		err := omr.readLine()
+		omr.buf = omr.buf[:0]
+		omr.bufOffset = 0
+		line := omr.line
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			line = line[:len(line)-1]
+		}
+		if len(line) > 0 {
+			var ferr error
+			omr.buf, ferr = omr.f.appendLine(omr.buf, bytesutil.ToUnsafeString(line))
+			if ferr != nil {
+				omr.err = ferr
+				continue
+			}
+		}
+		if err == io.EOF {
+			if ferr := omr.f.finish(); ferr != nil {
+				err = ferr
+			}
+		}
+		omr.err = err
+	}
+	n := copy(p, omr.buf[omr.bufOffset:])
+	omr.bufOffset += n
+	return n, nil
+}
+
+func (omr *openMetricsReader) readLine() error {
+	omr.line = omr.line[:0]
+	for {
+		b, err := omr.br.ReadSlice('\n')
+		omr.line = append(omr.line, b...)
+		if err != bufio.ErrBufferFull {
+			return err
+		}
+	}
+}
+
+// openMetricsFilter validates and filters OpenMetrics text exposition line by line.
+type openMetricsFilter struct {
+	strict      bool
+	dropCreated bool
+
+	// family and familyType contain the name and the type of the current metric family from the last `# TYPE` comment.
+	family     []byte
+	familyType []byte
+
+	eofSeen bool
+}
+
+// appendLine appends line to dst if it passes the validation and it mustn't be dropped.
+func (f *openMetricsFilter) appendLine(dst []byte, line string) ([]byte, error) {
+	s := line
+	if len(s) > 0 && s[len(s)-1] == '\r' {
+		s = s[:len(s)-1]
+	}
+	s = skipTrailingWhitespace(skipLeadingWhitespace(s))
+	if f.eofSeen {
+		if f.strict && len(s) > 0 {
+			return dst, fmt.Errorf("unexpected line after `# EOF`: %q", line)
+		}
+		return dst, nil
+	}
+	if len(s) > 0 && s[0] == '#' {
+		if s == "# EOF" {
+			f.eofSeen = true
+			return dst, nil
+		}
+		if err := f.processComment(s[1:]); err != nil {
+			return dst, err
+		}
+	} else if f.dropCreated && f.isCreatedSample(s) {
+		return dst, nil
+	}
+	dst = append(dst, line...)
+	dst = append(dst, '\n')
+	return dst, nil
+}
+
+func (f *openMetricsFilter) processComment(s string) error {
+	s = skipLeadingWhitespace(s)
+	if !strings.HasPrefix(s, "TYPE") {
+		return nil
+	}
+	s = skipLeadingWhitespace(s[len("TYPE"):])
+	n := nextWhitespace(s)
+	if n < 0 {
+		if f.strict {
+			return fmt.Errorf("missing metric type in `# TYPE %s` comment", s)
+		}
+		return nil
+	}
+	family := s[:n]
+	typ := skipLeadingWhitespace(s[n+1:])
+	if f.strict && !isOpenMetricsType(typ) {
+		return fmt.Errorf("unsupported metric type %q in `# TYPE` comment for metric family %q", typ, family)
+	}
+	f.family = append(f.family[:0], family...)
+	f.familyType = append(f.familyType[:0], typ...)
+	return nil
+}
+
+// isCreatedSample returns true if s contains `<family>_created` sample for the current metric family.
+func (f *openMetricsFilter) isCreatedSample(s string) bool {
+	switch string(f.familyType) {
+	case "counter", "summary", "histogram", "gaugehistogram":
+	default:
+		return false
+	}
+	n := strings.IndexAny(s, "{ \t")
+	if n < 0 {
+		return false
+	}
+	metric := skipTrailingWhitespace(s[:n])
+	return strings.HasSuffix(metric, "_created") && metric[:len(metric)-len("_created")] == string(f.family)
+}
+
+func (f *openMetricsFilter) finish() error {
+	if f.strict && !f.eofSeen {
+		return fmt.Errorf("missing `# EOF` line at the end of OpenMetrics response; the response may be truncated")
+	}
+	return nil
+}
+
+func isOpenMetricsType(typ string) bool {
+	// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#metric-types
+	switch typ {
+	case "counter", "gauge", "histogram", "gaugehistogram", "stateset", "info", "summary", "unknown":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,175 @@
+package prometheus
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestIsOpenMetricsContentType(t *testing.T) {
+	f := func(contentType string, resultExpected bool) {
+		t.Helper()
+		result := IsOpenMetricsContentType(contentType)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", contentType, result, resultExpected)
+		}
+	}
+	f("", false)
+	f("text/plain; version=0.0.4; charset=utf-8", false)
+	f("application/openmetrics-text; version=1.0.0; charset=utf-8", true)
+	f("Application/OpenMetrics-Text", true)
+}
+
+func TestAppendOpenMetricsTextFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		if _, err := AppendOpenMetricsText(nil, []byte(s), true, false); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if _, err := ioutil.ReadAll(NewOpenMetricsReader(strings.NewReader(s), true, false)); err == nil {
+			t.Fatalf("expecting non-nil error from reader")
+		}
+
+		// Non-strict mode must accept the data.
+		if _, err := AppendOpenMetricsText(nil, []byte(s), false, false); err != nil {
+			t.Fatalf("unexpected error in non-strict mode: %s", err)
+		}
+	}
+	// Empty response
+	f("")
+
+	// Missing `# EOF`
+	f("foo 1\n")
+	f("# TYPE foo counter\nfoo_total 1\nfoo_created 123\n")
+
+	// Truncated `# EOF`
+	f("foo 1\n# EO")
+
+	// Data after `# EOF`
+	f("foo 1\n# EOF\nbar 2\n")
+
+	// Invalid metric type
+	f("# TYPE foo untyped\nfoo 1\n# EOF\n")
+	f("# TYPE foo\nfoo 1\n# EOF\n")
+}
+
+func TestAppendOpenMetricsTextSuccess(t *testing.T) {
+	f := func(s string, dropCreated bool, resultExpected string) {
+		t.Helper()
+		result, err := AppendOpenMetricsText(nil, []byte(s), true, dropCreated)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+
+		// Verify the reader returns the same result when reading the data by a single byte.
+		result, err = ioutil.ReadAll(iotest.OneByteReader(NewOpenMetricsReader(iotest.OneByteReader(strings.NewReader(s)), true, dropCreated)))
+		if err != nil {
+			t.Fatalf("unexpected error from reader: %s", err)
+		}
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result from reader;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// Only `# EOF`
+	f("# EOF", false, "")
+	f("# EOF\n", false, "")
+
+	// Empty lines after `# EOF` are allowed
+	f("foo 1\n# EOF\n\n", false, "foo 1\n")
+
+	// Windows line endings
+	f("foo 1\r\n# EOF\r\n", false, "foo 1\r\n")
+
+	// All the metric types with `_created` samples
+	data := `# HELP requests Total requests.
+# TYPE requests counter
+# UNIT requests requests
+requests_total{path="/"} 10 # {trace_id="abc"} 1 1660000000.123
+requests_created{path="/"} 1660000000
+# TYPE temperature gauge
+temperature 12.5
+temperature_created 1660000000
+# TYPE rpc_seconds summary
+rpc_seconds{quantile="0.5"} 0.1
+rpc_seconds_sum 1.5
+rpc_seconds_count 10
+rpc_seconds_created 1660000000
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 2.5
+latency_seconds_count 3
+latency_seconds_created 1660000000
+# TYPE queue_size gaugehistogram
+queue_size_bucket{le="+Inf"} 3
+queue_size_gcount 3
+queue_size_gsum 10
+queue_size_created 1660000000
+# TYPE build info
+build_info{version="1.2.3"} 1
+# TYPE state stateset
+state{state="a"} 1
+state{state="b"} 0
+# TYPE foo unknown
+foo 1
+# EOF
+`
+	f(data, false, strings.TrimSuffix(data, "# EOF\n"))
+	f(data, true, `# HELP requests Total requests.
+# TYPE requests counter
+# UNIT requests requests
+requests_total{path="/"} 10 # {trace_id="abc"} 1 1660000000.123
+# TYPE temperature gauge
+temperature 12.5
+temperature_created 1660000000
+# TYPE rpc_seconds summary
+rpc_seconds{quantile="0.5"} 0.1
+rpc_seconds_sum 1.5
+rpc_seconds_count 10
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 2.5
+latency_seconds_count 3
+# TYPE queue_size gaugehistogram
+queue_size_bucket{le="+Inf"} 3
+queue_size_gcount 3
+queue_size_gsum 10
+# TYPE build info
+build_info{version="1.2.3"} 1
+# TYPE state stateset
+state{state="a"} 1
+state{state="b"} 0
+# TYPE foo unknown
+foo 1
+`)
+
+	// `_created` samples for other metric families are preserved
+	f(`# TYPE foo counter
+foo_total 1
+bar_created 123
+# EOF
+`, true, `# TYPE foo counter
+foo_total 1
+bar_created 123
+`)
+}
+
+func TestOpenMetricsReaderLongLines(t *testing.T) {
+	longValue := strings.Repeat("x", 200*1024)
+	s := `foo{bar="` + longValue + `"} 1` + "\n# EOF\n"
+	result, err := ioutil.ReadAll(NewOpenMetricsReader(bytes.NewBufferString(s), true, true))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resultExpected := strings.TrimSuffix(s, "# EOF\n")
+	if string(result) != resultExpected {
+		t.Fatalf("unexpected result; got %d bytes; want %d bytes", len(result), len(resultExpected))
+	}
+}
@@ -63,6 +63,9 @@ type Row struct {
 	Tags      []Tag
 	Value     float64
 	Timestamp int64
+
+	// Exemplar is an optional exemplar attached to the row.
+	Exemplar Exemplar
 }
 
 func (r *Row) reset() {
@@ -70,14 +73,71 @@ func (r *Row) reset() {
 	r.Tags = nil
 	r.Value = 0
 	r.Timestamp = 0
+	r.Exemplar.reset()
+}
+
+// Exemplar is an OpenMetrics exemplar.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#exemplars
+type Exemplar struct {
+	// Tags contains exemplar labels such as trace_id.
+	//
+	// Exemplar is missing if Tags is empty.
+	Tags []Tag
+
+	Value float64
+
+	// Timestamp in milliseconds. It is set to 0 if the exemplar has no timestamp.
+	Timestamp int64
 }
 
-func skipTrailingComment(s string) string {
-	n := strings.IndexByte(s, '#')
+func (e *Exemplar) reset() {
+	e.Tags = nil
+	e.Value = 0
+	e.Timestamp = 0
+}
+
+// unmarshal unmarshals exemplar from the trailing comment s after the sample.
+//
+// The comment is ignored if it doesn't look like an exemplar.
+func (e *Exemplar) unmarshal(s string, tagsPool []Tag, noEscapes bool) ([]Tag, error) {
+	s = skipLeadingWhitespace(s)
+	if len(s) == 0 || s[0] != '{' {
+		// Ordinary comment.
+		return tagsPool, nil
+	}
+	tagsStart := len(tagsPool)
+	var err error
+	s, tagsPool, err = unmarshalTags(tagsPool, s[1:], noEscapes)
+	if err != nil {
+		return tagsPool, fmt.Errorf("cannot unmarshal exemplar tags: %w", err)
+	}
+	s = skipTrailingWhitespace(skipLeadingWhitespace(s))
+	if len(s) == 0 {
+		return tagsPool, fmt.Errorf("exemplar value cannot be empty")
+	}
+	n := nextWhitespace(s)
 	if n < 0 {
-		return s
+		n = len(s)
 	}
-	return s[:n]
+	v, err := fastfloat.Parse(s[:n])
+	if err != nil {
+		return tagsPool, fmt.Errorf("cannot parse exemplar value %q: %w", s[:n], err)
+	}
+	s = skipLeadingWhitespace(s[n:])
+	if len(s) > 0 {
+		// Exemplar timestamps are always in Unix seconds.
+		// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#exemplars
+		ts, err := fastfloat.Parse(s)
+		if err != nil {
+			return tagsPool, fmt.Errorf("cannot parse exemplar timestamp %q: %w", s, err)
+		}
+		e.Timestamp = int64(ts * 1000)
+	}
+	e.Value = v
+	tags := tagsPool[tagsStart:]
+	e.Tags = tags[:len(tags):len(tags)]
+	return tagsPool, nil
 }
 
 func skipLeadingWhitespace(s string) string {
@@ -143,7 +203,15 @@ func (r *Row) unmarshal(s string, tagsPool []Tag, noEscapes bool) ([]Tag, error)
 		return tagsPool, fmt.Errorf("metric cannot be empty")
 	}
 	s = skipLeadingWhitespace(s)
-	s = skipTrailingComment(s)
+	if n := strings.IndexByte(s, '#'); n >= 0 {
+		// Trailing comment may contain an exemplar.
+		var err error
+		tagsPool, err = r.Exemplar.unmarshal(s[n+1:], tagsPool, noEscapes)
+		if err != nil {
+			return tagsPool, err
+		}
+		s = s[:n]
+	}
 	if len(s) == 0 {
 		return tagsPool, fmt.Errorf("value cannot be empty")
 	}
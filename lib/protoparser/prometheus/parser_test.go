@@ -236,6 +236,13 @@ func TestRowsUnmarshalFailure(t *testing.T) {
 
 	// Invalid timestamp
 	f("foo 123 bar")
+
+	// Invalid exemplars
+	f(`foo 123 # {`)
+	f(`foo 123 # {trace_id="x"}`)
+	f(`foo 123 # {trace_id="x"} bar`)
+	f(`foo 123 # {trace_id="x"} 1 bar`)
+	f(`foo 123 # {trace_id=x} 1`)
 }
 
 func TestRowsUnmarshalSuccess(t *testing.T) {
@@ -376,6 +383,14 @@ cassandra_token_ownership_ratio 78.9`, &Rows{
 					},
 				},
 				Value: 17,
+				Exemplar: Exemplar{
+					Tags: []Tag{{
+						Key:   "trace_id",
+						Value: "oHg5SJ#YRHA0",
+					}},
+					Value:     9.8,
+					Timestamp: 1520879607789,
+				},
 			},
 			{
 				Metric:    "abc",
@@ -389,6 +404,32 @@ cassandra_token_ownership_ratio 78.9`, &Rows{
 		},
 	})
 
+	// Exemplar without timestamp
+	f(`foo_bucket{le="+Inf"} 3 123 #  {trace_id="abc",span_id="x"}   1.5  `, &Rows{
+		Rows: []Row{{
+			Metric: "foo_bucket",
+			Tags: []Tag{{
+				Key:   "le",
+				Value: "+Inf",
+			}},
+			Value:     3,
+			Timestamp: 123000,
+			Exemplar: Exemplar{
+				Tags: []Tag{
+					{
+						Key:   "trace_id",
+						Value: "abc",
+					},
+					{
+						Key:   "span_id",
+						Value: "x",
+					},
+				},
+				Value: 1.5,
+			},
+		}},
+	})
+
 	// "Infinity" word - this has been added in OpenMetrics.
 	// See https://github.com/OpenObservability/OpenMetrics/blob/master/OpenMetrics.md
 	// Checks for https://github.com/VictoriaMetrics/VictoriaMetrics/issues/924
@@ -0,0 +1,861 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ProtobufAcceptHeader is the `Accept` header value, which prefers Prometheus protobuf exposition format over text exposition format
+// in the same way as Prometheus does when native histograms are enabled.
+const ProtobufAcceptHeader = "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3,*/*;q=0.1"
+
+// IsProtobufContentType returns true if contentType corresponds to Prometheus protobuf exposition format.
+func IsProtobufContentType(contentType string) bool {
+	contentType = strings.ToLower(strings.TrimSpace(contentType))
+	return strings.HasPrefix(contentType, "application/vnd.google.protobuf")
+}
+
+// NativeHistogramsFormat is the format for converting Prometheus native histograms into buckets.
+type NativeHistogramsFormat int
+
+const (
+	// NativeHistogramsFormatVMRange converts native histogram buckets into VictoriaMetrics `<name>_bucket{vmrange="<start>...<end>"}` buckets.
+	//
+	// See https://valyala.medium.com/improving-histogram-usability-for-prometheus-and-grafana-bc7e5df0e350
+	NativeHistogramsFormatVMRange = NativeHistogramsFormat(iota)
+
+	// NativeHistogramsFormatLE converts native histogram buckets into Prometheus `<name>_bucket{le="<upper_bound>"}` buckets.
+	NativeHistogramsFormatLE
+)
+
+// ParseNativeHistogramsFormat parses NativeHistogramsFormat from s.
+//
+// s may be either `vmrange` or `le`.
+func ParseNativeHistogramsFormat(s string) (NativeHistogramsFormat, error) {
+	switch s {
+	case "vmrange":
+		return NativeHistogramsFormatVMRange, nil
+	case "le":
+		return NativeHistogramsFormatLE, nil
+	default:
+		return 0, fmt.Errorf("unsupported native histograms format %q; supported values: vmrange, le", s)
+	}
+}
+
+// AppendProtobufAsText converts Prometheus protobuf exposition format at src into Prometheus text exposition format and appends it to dst.
+//
+// src must contain io.prometheus.client.MetricFamily messages prefixed with varint-encoded message lengths.
+// See https://github.com/prometheus/client_model/blob/master/io/prometheus/client/metrics.proto
+//
+// Native histograms are converted into buckets according to nhf. Classic buckets are dropped for histograms
+// with native buckets, since otherwise they would clash with the converted native buckets.
+func AppendProtobufAsText(dst, src []byte, nhf NativeHistogramsFormat) ([]byte, error) {
+	var mf metricFamily
+	for len(src) > 0 {
+		data, n := protowire.ConsumeBytes(src)
+		if n < 0 {
+			return dst, fmt.Errorf("cannot read MetricFamily message: %w", protowire.ParseError(n))
+		}
+		src = src[n:]
+		var err error
+		dst, err = mf.appendText(dst, data, nhf)
+		if err != nil {
+			return dst, fmt.Errorf("cannot unmarshal MetricFamily: %w", err)
+		}
+	}
+	return dst, nil
+}
+
+// Metric types from io.prometheus.client.MetricType
+const (
+	pbMetricTypeCounter        = 0
+	pbMetricTypeGauge          = 1
+	pbMetricTypeSummary        = 2
+	pbMetricTypeUntyped        = 3
+	pbMetricTypeHistogram      = 4
+	pbMetricTypeGaugeHistogram = 5
+)
+
+type metricFamily struct {
+	name string
+	help string
+	unit string
+	typ  uint64
+
+	m metric
+}
+
+func (mf *metricFamily) appendText(dst, src []byte, nhf NativeHistogramsFormat) ([]byte, error) {
+	mf.name = ""
+	mf.help = ""
+	mf.unit = ""
+	mf.typ = pbMetricTypeCounter
+
+	// Read the metric family header at first, since the metrics cannot be converted without it.
+	err := forEachField(src, func(fld *field) error {
+		switch fld.num {
+		case 1:
+			return fld.string("name", &mf.name)
+		case 2:
+			return fld.string("help", &mf.help)
+		case 3:
+			n, err := fld.varint("type")
+			if err != nil {
+				return err
+			}
+			mf.typ = n
+		case 5:
+			return fld.string("unit", &mf.unit)
+		}
+		return nil
+	})
+	if err != nil {
+		return dst, err
+	}
+	if len(mf.name) == 0 {
+		return dst, fmt.Errorf("missing metric family name")
+	}
+	typ := ""
+	switch mf.typ {
+	case pbMetricTypeCounter:
+		typ = "counter"
+	case pbMetricTypeGauge:
+		typ = "gauge"
+	case pbMetricTypeSummary:
+		typ = "summary"
+	case pbMetricTypeUntyped:
+		typ = "untyped"
+	case pbMetricTypeHistogram, pbMetricTypeGaugeHistogram:
+		typ = "histogram"
+	default:
+		return dst, fmt.Errorf("unsupported type %d for metric family %q", mf.typ, mf.name)
+	}
+	if len(mf.help) > 0 {
+		dst = append(dst, "# HELP "...)
+		dst = append(dst, mf.name...)
+		dst = append(dst, ' ')
+		dst = appendEscapedHelp(dst, mf.help)
+		dst = append(dst, '\n')
+	}
+	dst = append(dst, "# TYPE "...)
+	dst = append(dst, mf.name...)
+	dst = append(dst, ' ')
+	dst = append(dst, typ...)
+	dst = append(dst, '\n')
+	if len(mf.unit) > 0 {
+		dst = append(dst, "# UNIT "...)
+		dst = append(dst, mf.name...)
+		dst = append(dst, ' ')
+		dst = append(dst, mf.unit...)
+		dst = append(dst, '\n')
+	}
+
+	err = forEachField(src, func(fld *field) error {
+		if fld.num != 4 {
+			return nil
+		}
+		data, err := fld.message("metric")
+		if err != nil {
+			return err
+		}
+		m := &mf.m
+		if err := m.unmarshalProtobuf(data); err != nil {
+			return fmt.Errorf("cannot unmarshal metric for metric family %q: %w", mf.name, err)
+		}
+		dst, err = m.appendText(dst, mf.name, mf.typ, nhf)
+		if err != nil {
+			return fmt.Errorf("cannot convert metric for metric family %q: %w", mf.name, err)
+		}
+		return nil
+	})
+	return dst, err
+}
+
+type metric struct {
+	labels []labelPair
+
+	// The following fields contain raw protobuf messages for the corresponding metric types.
+	gauge     []byte
+	counter   []byte
+	summary   []byte
+	untyped   []byte
+	histogram []byte
+
+	timestamp int64
+
+	s summary
+	h histogram
+}
+
+type labelPair struct {
+	name  string
+	value string
+}
+
+func (m *metric) reset() {
+	m.labels = m.labels[:0]
+	m.gauge = nil
+	m.counter = nil
+	m.summary = nil
+	m.untyped = nil
+	m.histogram = nil
+	m.timestamp = 0
+}
+
+func (m *metric) unmarshalProtobuf(src []byte) error {
+	m.reset()
+	return forEachField(src, func(fld *field) error {
+		var err error
+		switch fld.num {
+		case 1:
+			data, err := fld.message("label")
+			if err != nil {
+				return err
+			}
+			m.labels = append(m.labels, labelPair{})
+			lp := &m.labels[len(m.labels)-1]
+			err = forEachField(data, func(fld *field) error {
+				switch fld.num {
+				case 1:
+					return fld.string("name", &lp.name)
+				case 2:
+					return fld.string("value", &lp.value)
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("cannot unmarshal label: %w", err)
+			}
+		case 2:
+			m.gauge, err = fld.message("gauge")
+		case 3:
+			m.counter, err = fld.message("counter")
+		case 4:
+			m.summary, err = fld.message("summary")
+		case 5:
+			m.untyped, err = fld.message("untyped")
+		case 6:
+			var n uint64
+			n, err = fld.varint("timestamp_ms")
+			m.timestamp = int64(n)
+		case 7:
+			m.histogram, err = fld.message("histogram")
+		}
+		return err
+	})
+}
+
+func (m *metric) appendText(dst []byte, name string, typ uint64, nhf NativeHistogramsFormat) ([]byte, error) {
+	switch typ {
+	case pbMetricTypeCounter, pbMetricTypeGauge, pbMetricTypeUntyped:
+		data := m.untyped
+		if typ == pbMetricTypeCounter {
+			data = m.counter
+		} else if typ == pbMetricTypeGauge {
+			data = m.gauge
+		}
+		var v float64
+		err := forEachField(data, func(fld *field) error {
+			if fld.num != 1 {
+				return nil
+			}
+			n, err := fld.fixed64("value")
+			v = math.Float64frombits(n)
+			return err
+		})
+		if err != nil {
+			return dst, err
+		}
+		return m.appendSample(dst, name, "", "", "", v), nil
+	case pbMetricTypeSummary:
+		s := &m.s
+		if err := s.unmarshalProtobuf(m.summary); err != nil {
+			return dst, fmt.Errorf("cannot unmarshal summary: %w", err)
+		}
+		for _, q := range s.quantiles {
+			dst = m.appendSample(dst, name, "", "quantile", formatFloat(q.quantile), q.value)
+		}
+		dst = m.appendSample(dst, name, "_sum", "", "", s.sampleSum)
+		dst = m.appendSample(dst, name, "_count", "", "", float64(s.sampleCount))
+		return dst, nil
+	default:
+		h := &m.h
+		if err := h.unmarshalProtobuf(m.histogram); err != nil {
+			return dst, fmt.Errorf("cannot unmarshal histogram: %w", err)
+		}
+		return m.appendHistogram(dst, name, nhf)
+	}
+}
+
+func (m *metric) appendHistogram(dst []byte, name string, nhf NativeHistogramsFormat) ([]byte, error) {
+	h := &m.h
+	count := h.getCount()
+	if !h.isNative() {
+		hasInfBucket := false
+		for _, b := range h.buckets {
+			bucketCount := float64(b.cumulativeCount)
+			if b.cumulativeCountFloat > 0 {
+				bucketCount = b.cumulativeCountFloat
+			}
+			dst = m.appendSample(dst, name, "_bucket", "le", formatFloat(b.upperBound), bucketCount)
+			if math.IsInf(b.upperBound, 1) {
+				hasInfBucket = true
+			}
+		}
+		if !hasInfBucket {
+			dst = m.appendSample(dst, name, "_bucket", "le", "+Inf", count)
+		}
+	} else {
+		if h.schema < -4 || h.schema > 8 {
+			return dst, fmt.Errorf("unsupported native histogram schema %d; it must be in the range [-4..8]", h.schema)
+		}
+		var err error
+		switch nhf {
+		case NativeHistogramsFormatLE:
+			dst, err = m.appendNativeBucketsLE(dst, name)
+		default:
+			dst, err = m.appendNativeBucketsVMRange(dst, name)
+		}
+		if err != nil {
+			return dst, err
+		}
+	}
+	dst = m.appendSample(dst, name, "_sum", "", "", h.sampleSum)
+	dst = m.appendSample(dst, name, "_count", "", "", count)
+	return dst, nil
+}
+
+func (m *metric) appendNativeBucketsVMRange(dst []byte, name string) ([]byte, error) {
+	h := &m.h
+	var err error
+	var buf []byte
+	appendBucket := func(start, end, count float64) {
+		if count <= 0 {
+			// Skip empty buckets like VictoriaMetrics histograms do.
+			return
+		}
+		buf = strconv.AppendFloat(buf[:0], start, 'g', -1, 64)
+		buf = append(buf, "..."...)
+		buf = strconv.AppendFloat(buf, end, 'g', -1, 64)
+		dst = m.appendSample(dst, name, "_bucket", "vmrange", string(buf), count)
+	}
+	err = h.visitNativeBuckets(h.negativeSpans, h.negativeDeltas, h.negativeCounts, func(idx int32, count float64) {
+		appendBucket(-nativeBucketUpperBound(idx, h.schema), -nativeBucketUpperBound(idx-1, h.schema), count)
+	})
+	if err != nil {
+		return dst, fmt.Errorf("cannot read negative buckets: %w", err)
+	}
+	zeroStart := float64(0)
+	if h.zeroThreshold > 0 {
+		zeroStart = -h.zeroThreshold
+	}
+	appendBucket(zeroStart, h.zeroThreshold, h.getZeroCount())
+	err = h.visitNativeBuckets(h.positiveSpans, h.positiveDeltas, h.positiveCounts, func(idx int32, count float64) {
+		appendBucket(nativeBucketUpperBound(idx-1, h.schema), nativeBucketUpperBound(idx, h.schema), count)
+	})
+	if err != nil {
+		return dst, fmt.Errorf("cannot read positive buckets: %w", err)
+	}
+	return dst, nil
+}
+
+func (m *metric) appendNativeBucketsLE(dst []byte, name string) ([]byte, error) {
+	h := &m.h
+	type bucket struct {
+		le    float64
+		count float64
+	}
+	var negativeBuckets []bucket
+	err := h.visitNativeBuckets(h.negativeSpans, h.negativeDeltas, h.negativeCounts, func(idx int32, count float64) {
+		negativeBuckets = append(negativeBuckets, bucket{
+			le:    -nativeBucketUpperBound(idx-1, h.schema),
+			count: count,
+		})
+	})
+	if err != nil {
+		return dst, fmt.Errorf("cannot read negative buckets: %w", err)
+	}
+	// Negative buckets are ordered by increasing absolute values, so they must be visited in reverse order
+	// for obtaining cumulative counts for increasing le values.
+	cumulativeCount := float64(0)
+	for i := len(negativeBuckets) - 1; i >= 0; i-- {
+		b := &negativeBuckets[i]
+		cumulativeCount += b.count
+		dst = m.appendSample(dst, name, "_bucket", "le", formatFloat(b.le), cumulativeCount)
+	}
+	cumulativeCount += h.getZeroCount()
+	dst = m.appendSample(dst, name, "_bucket", "le", formatFloat(h.zeroThreshold), cumulativeCount)
+	err = h.visitNativeBuckets(h.positiveSpans, h.positiveDeltas, h.positiveCounts, func(idx int32, count float64) {
+		cumulativeCount += count
+		dst = m.appendSample(dst, name, "_bucket", "le", formatFloat(nativeBucketUpperBound(idx, h.schema)), cumulativeCount)
+	})
+	if err != nil {
+		return dst, fmt.Errorf("cannot read positive buckets: %w", err)
+	}
+	dst = m.appendSample(dst, name, "_bucket", "le", "+Inf", h.getCount())
+	return dst, nil
+}
+
+// nativeBucketUpperBound returns the upper bound for native histogram bucket with the given idx and schema.
+//
+// The upper bound equals to base^idx, where base = 2^(2^-schema).
+// See https://github.com/prometheus/prometheus/blob/main/model/histogram/generic.go
+func nativeBucketUpperBound(idx, schema int32) float64 {
+	if schema <= 0 {
+		return math.Ldexp(1, int(idx)<<uint(-schema))
+	}
+	// Split idx into the integer power of two and the fractional part in order to reduce rounding errors.
+	whole := idx >> uint(schema)
+	frac := idx - whole<<uint(schema)
+	return math.Ldexp(math.Pow(2, float64(frac)/float64(int32(1)<<uint(schema))), int(whole))
+}
+
+func (m *metric) appendSample(dst []byte, name, suffix, extraLabelName, extraLabelValue string, value float64) []byte {
+	dst = append(dst, name...)
+	dst = append(dst, suffix...)
+	if len(m.labels) > 0 || len(extraLabelName) > 0 {
+		dst = append(dst, '{')
+		for i, lp := range m.labels {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, lp.name...)
+			dst = append(dst, `="`...)
+			dst = appendEscapedValue(dst, lp.value)
+			dst = append(dst, '"')
+		}
+		if len(extraLabelName) > 0 {
+			if len(m.labels) > 0 {
+				dst = append(dst, ',')
+			}
+			dst = append(dst, extraLabelName...)
+			dst = append(dst, `="`...)
+			dst = append(dst, extraLabelValue...)
+			dst = append(dst, '"')
+		}
+		dst = append(dst, '}')
+	}
+	dst = append(dst, ' ')
+	dst = strconv.AppendFloat(dst, value, 'g', -1, 64)
+	if m.timestamp != 0 {
+		dst = append(dst, ' ')
+		dst = strconv.AppendInt(dst, m.timestamp, 10)
+	}
+	dst = append(dst, '\n')
+	return dst
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func appendEscapedHelp(dst []byte, s string) []byte {
+	// Help text may contain any sequence of UTF-8 characters, but the backslash (\) and line feed (\n)
+	// characters have to be escaped as \\ and \n, respectively.
+	// See https://github.com/prometheus/docs/blob/master/content/docs/instrumenting/exposition_formats.md
+	for {
+		n := strings.IndexAny(s, "\\\n")
+		if n < 0 {
+			return append(dst, s...)
+		}
+		dst = append(dst, s[:n]...)
+		switch s[n] {
+		case '\\':
+			dst = append(dst, "\\\\"...)
+		case '\n':
+			dst = append(dst, "\\n"...)
+		}
+		s = s[n+1:]
+	}
+}
+
+type summary struct {
+	sampleCount uint64
+	sampleSum   float64
+	quantiles   []quantile
+}
+
+type quantile struct {
+	quantile float64
+	value    float64
+}
+
+func (s *summary) unmarshalProtobuf(src []byte) error {
+	s.sampleCount = 0
+	s.sampleSum = 0
+	s.quantiles = s.quantiles[:0]
+	return forEachField(src, func(fld *field) error {
+		var err error
+		var n uint64
+		switch fld.num {
+		case 1:
+			s.sampleCount, err = fld.varint("sample_count")
+		case 2:
+			n, err = fld.fixed64("sample_sum")
+			s.sampleSum = math.Float64frombits(n)
+		case 3:
+			data, err := fld.message("quantile")
+			if err != nil {
+				return err
+			}
+			s.quantiles = append(s.quantiles, quantile{})
+			q := &s.quantiles[len(s.quantiles)-1]
+			return forEachField(data, func(fld *field) error {
+				var err error
+				var n uint64
+				switch fld.num {
+				case 1:
+					n, err = fld.fixed64("quantile")
+					q.quantile = math.Float64frombits(n)
+				case 2:
+					n, err = fld.fixed64("value")
+					q.value = math.Float64frombits(n)
+				}
+				return err
+			})
+		}
+		return err
+	})
+}
+
+type histogram struct {
+	sampleCount      uint64
+	sampleCountFloat float64
+	sampleSum        float64
+
+	// buckets contains classic histogram buckets.
+	buckets []classicBucket
+
+	// The following fields contain native histogram buckets.
+	schema         int32
+	zeroThreshold  float64
+	zeroCount      uint64
+	zeroCountFloat float64
+	negativeSpans  []bucketSpan
+	negativeDeltas []int64
+	negativeCounts []float64
+	positiveSpans  []bucketSpan
+	positiveDeltas []int64
+	positiveCounts []float64
+}
+
+type classicBucket struct {
+	cumulativeCount      uint64
+	cumulativeCountFloat float64
+	upperBound           float64
+}
+
+type bucketSpan struct {
+	offset int32
+	length uint32
+}
+
+func (h *histogram) reset() {
+	h.sampleCount = 0
+	h.sampleCountFloat = 0
+	h.sampleSum = 0
+	h.buckets = h.buckets[:0]
+	h.schema = 0
+	h.zeroThreshold = 0
+	h.zeroCount = 0
+	h.zeroCountFloat = 0
+	h.negativeSpans = h.negativeSpans[:0]
+	h.negativeDeltas = h.negativeDeltas[:0]
+	h.negativeCounts = h.negativeCounts[:0]
+	h.positiveSpans = h.positiveSpans[:0]
+	h.positiveDeltas = h.positiveDeltas[:0]
+	h.positiveCounts = h.positiveCounts[:0]
+}
+
+// isNative returns true if h contains native histogram.
+//
+// It is detected in the same way as Prometheus does - see https://github.com/prometheus/prometheus/blob/main/model/textparse/protobufparse.go
+func (h *histogram) isNative() bool {
+	return h.zeroThreshold > 0 || h.zeroCount > 0 || h.zeroCountFloat > 0 || len(h.negativeSpans) > 0 || len(h.positiveSpans) > 0
+}
+
+// isFloat returns true if h contains float counts instead of integer counts.
+func (h *histogram) isFloat() bool {
+	return h.sampleCountFloat > 0 || h.zeroCountFloat > 0 || len(h.negativeCounts) > 0 || len(h.positiveCounts) > 0
+}
+
+func (h *histogram) getCount() float64 {
+	if h.isFloat() {
+		return h.sampleCountFloat
+	}
+	return float64(h.sampleCount)
+}
+
+func (h *histogram) getZeroCount() float64 {
+	if h.isFloat() {
+		return h.zeroCountFloat
+	}
+	return float64(h.zeroCount)
+}
+
+// visitNativeBuckets calls f for every native histogram bucket defined by the given spans, deltas and counts.
+//
+// deltas are used for integer histograms, while counts are used for float histograms.
+func (h *histogram) visitNativeBuckets(spans []bucketSpan, deltas []int64, counts []float64, f func(idx int32, count float64)) error {
+	isFloat := h.isFloat()
+	idx := int32(0)
+	bucketsCount := 0
+	currentCount := int64(0)
+	for _, span := range spans {
+		idx += span.offset
+		for j := uint32(0); j < span.length; j++ {
+			var count float64
+			if isFloat {
+				if bucketsCount >= len(counts) {
+					return fmt.Errorf("missing counts for buckets defined by spans; got %d counts", len(counts))
+				}
+				count = counts[bucketsCount]
+			} else {
+				if bucketsCount >= len(deltas) {
+					return fmt.Errorf("missing deltas for buckets defined by spans; got %d deltas", len(deltas))
+				}
+				currentCount += deltas[bucketsCount]
+				count = float64(currentCount)
+			}
+			f(idx, count)
+			idx++
+			bucketsCount++
+		}
+	}
+	return nil
+}
+
+func (h *histogram) unmarshalProtobuf(src []byte) error {
+	h.reset()
+	return forEachField(src, func(fld *field) error {
+		var err error
+		var n uint64
+		switch fld.num {
+		case 1:
+			h.sampleCount, err = fld.varint("sample_count")
+		case 2:
+			n, err = fld.fixed64("sample_sum")
+			h.sampleSum = math.Float64frombits(n)
+		case 3:
+			data, err := fld.message("bucket")
+			if err != nil {
+				return err
+			}
+			h.buckets = append(h.buckets, classicBucket{})
+			b := &h.buckets[len(h.buckets)-1]
+			return forEachField(data, func(fld *field) error {
+				var err error
+				var n uint64
+				switch fld.num {
+				case 1:
+					b.cumulativeCount, err = fld.varint("cumulative_count")
+				case 2:
+					n, err = fld.fixed64("upper_bound")
+					b.upperBound = math.Float64frombits(n)
+				case 4:
+					n, err = fld.fixed64("cumulative_count_float")
+					b.cumulativeCountFloat = math.Float64frombits(n)
+				}
+				return err
+			})
+		case 4:
+			n, err = fld.fixed64("sample_count_float")
+			h.sampleCountFloat = math.Float64frombits(n)
+		case 5:
+			n, err = fld.varint("schema")
+			h.schema = int32(protowire.DecodeZigZag(n))
+		case 6:
+			n, err = fld.fixed64("zero_threshold")
+			h.zeroThreshold = math.Float64frombits(n)
+		case 7:
+			h.zeroCount, err = fld.varint("zero_count")
+		case 8:
+			n, err = fld.fixed64("zero_count_float")
+			h.zeroCountFloat = math.Float64frombits(n)
+		case 9:
+			return fld.appendBucketSpan("negative_span", &h.negativeSpans)
+		case 10:
+			return fld.appendVarints("negative_delta", func(n uint64) {
+				h.negativeDeltas = append(h.negativeDeltas, protowire.DecodeZigZag(n))
+			})
+		case 11:
+			return fld.appendFixed64s("negative_count", func(n uint64) {
+				h.negativeCounts = append(h.negativeCounts, math.Float64frombits(n))
+			})
+		case 12:
+			return fld.appendBucketSpan("positive_span", &h.positiveSpans)
+		case 13:
+			return fld.appendVarints("positive_delta", func(n uint64) {
+				h.positiveDeltas = append(h.positiveDeltas, protowire.DecodeZigZag(n))
+			})
+		case 14:
+			return fld.appendFixed64s("positive_count", func(n uint64) {
+				h.positiveCounts = append(h.positiveCounts, math.Float64frombits(n))
+			})
+		}
+		return err
+	})
+}
+
+// field is a single protobuf field.
+type field struct {
+	num protowire.Number
+	typ protowire.Type
+
+	// data contains the field value for protowire.BytesType
+	data []byte
+
+	// n contains the field value for protowire.VarintType and protowire.Fixed64Type
+	n uint64
+}
+
+// forEachField calls f for every field in the protobuf message at src.
+//
+// Unknown fields must be ignored by f for forward compatibility.
+func forEachField(src []byte, f func(fld *field) error) error {
+	for len(src) > 0 {
+		num, typ, n := protowire.ConsumeTag(src)
+		if n < 0 {
+			return fmt.Errorf("cannot read field tag: %w", protowire.ParseError(n))
+		}
+		src = src[n:]
+		fld := field{
+			num: num,
+			typ: typ,
+		}
+		switch typ {
+		case protowire.VarintType:
+			fld.n, n = protowire.ConsumeVarint(src)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(src)
+			fld.n = uint64(v)
+		case protowire.Fixed64Type:
+			fld.n, n = protowire.ConsumeFixed64(src)
+		case protowire.BytesType:
+			fld.data, n = protowire.ConsumeBytes(src)
+		default:
+			// Skip deprecated groups.
+			n = protowire.ConsumeFieldValue(num, typ, src)
+		}
+		if n < 0 {
+			return fmt.Errorf("cannot read field #%d: %w", num, protowire.ParseError(n))
+		}
+		src = src[n:]
+		if err := f(&fld); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fld *field) checkType(name string, typ protowire.Type) error {
+	if fld.typ != typ {
+		return fmt.Errorf("unexpected wire type %d for field %s; want %d", fld.typ, name, typ)
+	}
+	return nil
+}
+
+func (fld *field) message(name string) ([]byte, error) {
+	if err := fld.checkType(name, protowire.BytesType); err != nil {
+		return nil, err
+	}
+	return fld.data, nil
+}
+
+func (fld *field) string(name string, dst *string) error {
+	if err := fld.checkType(name, protowire.BytesType); err != nil {
+		return err
+	}
+	*dst = string(fld.data)
+	return nil
+}
+
+func (fld *field) varint(name string) (uint64, error) {
+	if err := fld.checkType(name, protowire.VarintType); err != nil {
+		return 0, err
+	}
+	return fld.n, nil
+}
+
+func (fld *field) fixed64(name string) (uint64, error) {
+	if err := fld.checkType(name, protowire.Fixed64Type); err != nil {
+		return 0, err
+	}
+	return fld.n, nil
+}
+
+// appendVarints calls f for every value in repeated varint field.
+//
+// Both packed and non-packed encodings are supported.
+func (fld *field) appendVarints(name string, f func(n uint64)) error {
+	if fld.typ == protowire.VarintType {
+		f(fld.n)
+		return nil
+	}
+	if err := fld.checkType(name, protowire.BytesType); err != nil {
+		return err
+	}
+	src := fld.data
+	for len(src) > 0 {
+		v, n := protowire.ConsumeVarint(src)
+		if n < 0 {
+			return fmt.Errorf("cannot read packed %s: %w", name, protowire.ParseError(n))
+		}
+		src = src[n:]
+		f(v)
+	}
+	return nil
+}
+
+// appendFixed64s calls f for every value in repeated fixed64 or double field.
+//
+// Both packed and non-packed encodings are supported.
+func (fld *field) appendFixed64s(name string, f func(n uint64)) error {
+	if fld.typ == protowire.Fixed64Type {
+		f(fld.n)
+		return nil
+	}
+	if err := fld.checkType(name, protowire.BytesType); err != nil {
+		return err
+	}
+	src := fld.data
+	for len(src) > 0 {
+		v, n := protowire.ConsumeFixed64(src)
+		if n < 0 {
+			return fmt.Errorf("cannot read packed %s: %w", name, protowire.ParseError(n))
+		}
+		src = src[n:]
+		f(v)
+	}
+	return nil
+}
+
+func (fld *field) appendBucketSpan(name string, dst *[]bucketSpan) error {
+	data, err := fld.message(name)
+	if err != nil {
+		return err
+	}
+	*dst = append(*dst, bucketSpan{})
+	bs := &(*dst)[len(*dst)-1]
+	return forEachField(data, func(fld *field) error {
+		var err error
+		var n uint64
+		switch fld.num {
+		case 1:
+			n, err = fld.varint("offset")
+			bs.offset = int32(protowire.DecodeZigZag(n))
+		case 2:
+			n, err = fld.varint("length")
+			bs.length = uint32(n)
+		}
+		return err
+	})
+}
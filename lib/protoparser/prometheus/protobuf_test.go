@@ -0,0 +1,341 @@
+package prometheus
+
+import (
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestParseNativeHistogramsFormat(t *testing.T) {
+	f := func(s string, nhfExpected NativeHistogramsFormat, okExpected bool) {
+		t.Helper()
+		nhf, err := ParseNativeHistogramsFormat(s)
+		if ok := err == nil; ok != okExpected {
+			t.Fatalf("unexpected error state for %q; got %v; want %v; err: %v", s, ok, okExpected, err)
+		}
+		if nhf != nhfExpected {
+			t.Fatalf("unexpected format for %q; got %d; want %d", s, nhf, nhfExpected)
+		}
+	}
+	f("vmrange", NativeHistogramsFormatVMRange, true)
+	f("le", NativeHistogramsFormatLE, true)
+	f("", 0, false)
+	f("foo", 0, false)
+}
+
+func TestIsProtobufContentType(t *testing.T) {
+	f := func(contentType string, resultExpected bool) {
+		t.Helper()
+		result := IsProtobufContentType(contentType)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", contentType, result, resultExpected)
+		}
+	}
+	f("", false)
+	f("text/plain; version=0.0.4; charset=utf-8", false)
+	f("application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited", true)
+	f(" Application/Vnd.Google.Protobuf", true)
+}
+
+func TestAppendProtobufAsTextFailure(t *testing.T) {
+	f := func(data []byte) {
+		t.Helper()
+		if _, err := AppendProtobufAsText(nil, data, NativeHistogramsFormatVMRange); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// Invalid message length
+	f([]byte{10})
+	f([]byte{0x80})
+
+	// Missing metric family name
+	f(delimited(pbMetricFamily("", pbMetricTypeGauge, pbMetric(nil, 0, 2, pbValue(1)))))
+
+	// Unsupported metric type
+	f(delimited(pbMetricFamily("foo", 123, pbMetric(nil, 0, 2, pbValue(1)))))
+
+	// Invalid metric message
+	f(delimited(pbMetricFamily("foo", pbMetricTypeGauge, []byte("foobar"))))
+
+	// Unsupported native histogram schema
+	f(delimited(pbMetricFamily("foo", pbMetricTypeHistogram, pbMetric(nil, 0, 7, pbNativeHistogram(9, 0, 0, []int64{0, 1}, []int64{1})))))
+
+	// Missing deltas for native histogram buckets
+	f(delimited(pbMetricFamily("foo", pbMetricTypeHistogram, pbMetric(nil, 0, 7, pbNativeHistogram(0, 0, 0, []int64{0, 2}, []int64{1})))))
+}
+
+func TestAppendProtobufAsTextSuccess(t *testing.T) {
+	f := func(data []byte, nhf NativeHistogramsFormat, resultExpected string) {
+		t.Helper()
+		result, err := AppendProtobufAsText(nil, data, nhf)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+
+		// Verify that the result can be parsed by Prometheus text parser.
+		var rows Rows
+		rows.UnmarshalWithErrLogger(string(result), func(s string) {
+			t.Fatalf("cannot parse the result: %s", s)
+		})
+	}
+
+	// Empty response
+	f(nil, NativeHistogramsFormatVMRange, "")
+
+	// Counter with labels, help and timestamp
+	f(delimited(pbMetricFamilyWithHelp("http_requests_total", "Total \"number\" of\nrequests", pbMetricTypeCounter,
+		pbMetric([]string{"path", "/foo", "code", "200"}, 1660000000123, 3, pbValue(123)),
+		pbMetric([]string{"path", "a\"b\\c"}, 0, 3, pbValue(0.5)),
+	)), NativeHistogramsFormatVMRange, `# HELP http_requests_total Total "number" of\nrequests
+# TYPE http_requests_total counter
+http_requests_total{path="/foo",code="200"} 123 1660000000123
+http_requests_total{path="a\"b\\c"} 0.5
+`)
+
+	// Multiple metric families
+	f(delimited(
+		pbMetricFamily("temperature", pbMetricTypeGauge, pbMetric(nil, 0, 2, pbValue(-12.5))),
+		pbMetricFamily("foo", pbMetricTypeUntyped, pbMetric([]string{"x", "y"}, 0, 5, pbValue(math.Inf(1)))),
+	), NativeHistogramsFormatVMRange, `# TYPE temperature gauge
+temperature -12.5
+# TYPE foo untyped
+foo{x="y"} +Inf
+`)
+
+	// Summary
+	f(delimited(pbMetricFamily("rpc_duration_seconds", pbMetricTypeSummary,
+		pbMetric([]string{"service", "foo"}, 0, 4, pbSummary(10, 1.5, 0.5, 0.1, 0.99, 0.3)),
+	)), NativeHistogramsFormatVMRange, `# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{service="foo",quantile="0.5"} 0.1
+rpc_duration_seconds{service="foo",quantile="0.99"} 0.3
+rpc_duration_seconds_sum{service="foo"} 1.5
+rpc_duration_seconds_count{service="foo"} 10
+`)
+
+	// Classic histogram without +Inf bucket
+	f(delimited(pbMetricFamily("request_duration_seconds", pbMetricTypeHistogram,
+		pbMetric(nil, 0, 7, pbClassicHistogram(5, 2.5, 0.1, 2, 1, 4)),
+	)), NativeHistogramsFormatVMRange, `# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 2
+request_duration_seconds_bucket{le="1"} 4
+request_duration_seconds_bucket{le="+Inf"} 5
+request_duration_seconds_sum 2.5
+request_duration_seconds_count 5
+`)
+
+	// Native histogram with integer counts:
+	//   - negative bucket [-2..-1) with count 1
+	//   - zero bucket [-0.001..0.001] with count 1
+	//   - positive buckets (0.5..1], (1..2] and (4..8] with counts 2, 1 and 4
+	// Classic buckets must be ignored.
+	nativeHistogram := append(pbNativeHistogram(0, 0.001, 1, []int64{0, 2, 1, 1}, []int64{2, -1, 3}),
+		pbNegativeBuckets([]int64{1, 1}, []int64{1})...)
+	nativeHistogram = appendMessageField(nativeHistogram, 3, pbBucket(100, 10))
+	nativeHistogram = appendVarintField(nativeHistogram, 1, 9)
+	nativeHistogram = appendDoubleField(nativeHistogram, 2, 20)
+	data := delimited(pbMetricFamily("latency_seconds", pbMetricTypeHistogram,
+		pbMetric([]string{"job", "foo"}, 0, 7, nativeHistogram),
+	))
+	f(data, NativeHistogramsFormatVMRange, `# TYPE latency_seconds histogram
+latency_seconds_bucket{job="foo",vmrange="-2...-1"} 1
+latency_seconds_bucket{job="foo",vmrange="-0.001...0.001"} 1
+latency_seconds_bucket{job="foo",vmrange="0.5...1"} 2
+latency_seconds_bucket{job="foo",vmrange="1...2"} 1
+latency_seconds_bucket{job="foo",vmrange="4...8"} 4
+latency_seconds_sum{job="foo"} 20
+latency_seconds_count{job="foo"} 9
+`)
+	f(data, NativeHistogramsFormatLE, `# TYPE latency_seconds histogram
+latency_seconds_bucket{job="foo",le="-1"} 1
+latency_seconds_bucket{job="foo",le="0.001"} 2
+latency_seconds_bucket{job="foo",le="1"} 4
+latency_seconds_bucket{job="foo",le="2"} 5
+latency_seconds_bucket{job="foo",le="8"} 9
+latency_seconds_bucket{job="foo",le="+Inf"} 9
+latency_seconds_sum{job="foo"} 20
+latency_seconds_count{job="foo"} 9
+`)
+
+	// Native float histogram with schema 1, zero threshold and empty buckets
+	var floatHistogram []byte
+	floatHistogram = appendDoubleField(floatHistogram, 4, 3.5)
+	floatHistogram = appendDoubleField(floatHistogram, 2, 10)
+	floatHistogram = appendVarintField(floatHistogram, 5, protowire.EncodeZigZag(1))
+	floatHistogram = appendMessageField(floatHistogram, 12, pbSpan(2, 2))
+	floatHistogram = appendPackedDoubles(floatHistogram, 14, 0, 3.5)
+	f(delimited(pbMetricFamily("size_bytes", pbMetricTypeGaugeHistogram, pbMetric(nil, 0, 7, floatHistogram))), NativeHistogramsFormatVMRange, `# TYPE size_bytes histogram
+size_bytes_bucket{vmrange="2...2.8284271247461903"} 3.5
+size_bytes_sum 10
+size_bytes_count 3.5
+`)
+}
+
+func delimited(msgs ...[]byte) []byte {
+	var dst []byte
+	for _, msg := range msgs {
+		dst = protowire.AppendBytes(dst, msg)
+	}
+	return dst
+}
+
+func pbMetricFamily(name string, typ uint64, metrics ...[]byte) []byte {
+	return pbMetricFamilyWithHelp(name, "", typ, metrics...)
+}
+
+func pbMetricFamilyWithHelp(name, help string, typ uint64, metrics ...[]byte) []byte {
+	var dst []byte
+	// Put metrics before the header in order to verify that the field order doesn't matter.
+	for _, m := range metrics {
+		dst = appendMessageField(dst, 4, m)
+	}
+	if len(name) > 0 {
+		dst = appendStringField(dst, 1, name)
+	}
+	if len(help) > 0 {
+		dst = appendStringField(dst, 2, help)
+	}
+	return appendVarintField(dst, 3, typ)
+}
+
+// pbMetric returns Metric message with the given labels, timestamp and value message stored in the valueFieldNum field.
+//
+// labels must contain name, value pairs.
+func pbMetric(labels []string, timestamp int64, valueFieldNum protowire.Number, value []byte) []byte {
+	var dst []byte
+	for i := 0; i < len(labels); i += 2 {
+		var lp []byte
+		lp = appendStringField(lp, 1, labels[i])
+		lp = appendStringField(lp, 2, labels[i+1])
+		dst = appendMessageField(dst, 1, lp)
+	}
+	dst = appendMessageField(dst, valueFieldNum, value)
+	if timestamp != 0 {
+		dst = appendVarintField(dst, 6, uint64(timestamp))
+	}
+	return dst
+}
+
+func pbValue(v float64) []byte {
+	return appendDoubleField(nil, 1, v)
+}
+
+// pbSummary returns Summary message with the given count, sum and quantile, value pairs.
+func pbSummary(count uint64, sum float64, quantiles ...float64) []byte {
+	var dst []byte
+	dst = appendVarintField(dst, 1, count)
+	dst = appendDoubleField(dst, 2, sum)
+	for i := 0; i < len(quantiles); i += 2 {
+		var q []byte
+		q = appendDoubleField(q, 1, quantiles[i])
+		q = appendDoubleField(q, 2, quantiles[i+1])
+		dst = appendMessageField(dst, 3, q)
+	}
+	return dst
+}
+
+// pbClassicHistogram returns Histogram message with the given count, sum and upper_bound, cumulative_count pairs.
+func pbClassicHistogram(count uint64, sum float64, buckets ...float64) []byte {
+	var dst []byte
+	dst = appendVarintField(dst, 1, count)
+	dst = appendDoubleField(dst, 2, sum)
+	for i := 0; i < len(buckets); i += 2 {
+		dst = appendMessageField(dst, 3, pbBucket(buckets[i], uint64(buckets[i+1])))
+	}
+	return dst
+}
+
+func pbBucket(upperBound float64, cumulativeCount uint64) []byte {
+	var dst []byte
+	dst = appendVarintField(dst, 1, cumulativeCount)
+	return appendDoubleField(dst, 2, upperBound)
+}
+
+// pbNativeHistogram returns Histogram message with the given positive buckets.
+//
+// spans must contain offset, length pairs.
+func pbNativeHistogram(schema int32, zeroThreshold float64, zeroCount uint64, spans, deltas []int64) []byte {
+	var dst []byte
+	dst = appendVarintField(dst, 5, protowire.EncodeZigZag(int64(schema)))
+	dst = appendDoubleField(dst, 6, zeroThreshold)
+	dst = appendVarintField(dst, 7, zeroCount)
+	for i := 0; i < len(spans); i += 2 {
+		dst = appendMessageField(dst, 12, pbSpan(spans[i], spans[i+1]))
+	}
+	var packed []byte
+	for _, d := range deltas {
+		packed = protowire.AppendVarint(packed, protowire.EncodeZigZag(d))
+	}
+	return appendMessageField(dst, 13, packed)
+}
+
+// pbNegativeBuckets returns negative buckets for Histogram message.
+//
+// spans must contain offset, length pairs. deltas are encoded in non-packed form.
+func pbNegativeBuckets(spans, deltas []int64) []byte {
+	var dst []byte
+	for i := 0; i < len(spans); i += 2 {
+		dst = appendMessageField(dst, 9, pbSpan(spans[i], spans[i+1]))
+	}
+	for _, d := range deltas {
+		dst = appendVarintField(dst, 10, protowire.EncodeZigZag(d))
+	}
+	return dst
+}
+
+func pbSpan(offset, length int64) []byte {
+	var dst []byte
+	dst = appendVarintField(dst, 1, protowire.EncodeZigZag(offset))
+	return appendVarintField(dst, 2, uint64(length))
+}
+
+func appendStringField(dst []byte, num protowire.Number, s string) []byte {
+	dst = protowire.AppendTag(dst, num, protowire.BytesType)
+	return protowire.AppendString(dst, s)
+}
+
+func appendMessageField(dst []byte, num protowire.Number, msg []byte) []byte {
+	dst = protowire.AppendTag(dst, num, protowire.BytesType)
+	return protowire.AppendBytes(dst, msg)
+}
+
+func appendVarintField(dst []byte, num protowire.Number, n uint64) []byte {
+	dst = protowire.AppendTag(dst, num, protowire.VarintType)
+	return protowire.AppendVarint(dst, n)
+}
+
+func appendDoubleField(dst []byte, num protowire.Number, f float64) []byte {
+	dst = protowire.AppendTag(dst, num, protowire.Fixed64Type)
+	return protowire.AppendFixed64(dst, math.Float64bits(f))
+}
+
+func appendPackedDoubles(dst []byte, num protowire.Number, fs ...float64) []byte {
+	var packed []byte
+	for _, f := range fs {
+		packed = protowire.AppendFixed64(packed, math.Float64bits(f))
+	}
+	return appendMessageField(dst, num, packed)
+}
+
+func TestNativeBucketUpperBound(t *testing.T) {
+	f := func(idx, schema int32, resultExpected float64) {
+		t.Helper()
+		result := nativeBucketUpperBound(idx, schema)
+		if result != resultExpected {
+			t.Fatalf("unexpected upper bound for idx=%d, schema=%d; got %v; want %v", idx, schema, result, resultExpected)
+		}
+	}
+	f(0, 0, 1)
+	f(1, 0, 2)
+	f(-1, 0, 0.5)
+	f(1, -2, 16)
+	f(-1, -2, 1.0/16)
+	f(3, 1, 2*math.Sqrt2)
+	f(-3, 1, 1/(2*math.Sqrt2))
+	f(8, 3, 2)
+	f(-8, 3, 0.5)
+	f(2000, -4, math.Inf(1))
+}
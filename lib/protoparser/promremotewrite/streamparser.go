@@ -17,10 +17,10 @@ import (
 
 var maxInsertRequestSize = flagutil.NewBytes("maxInsertRequestSize", 32*1024*1024, "The maximum size in bytes of a single Prometheus remote_write API request")
 
-// ParseStream parses Prometheus remote_write message from reader and calls callback for the parsed timeseries.
+// ParseStream parses Prometheus remote_write message from reader and calls callback for the parsed timeseries and metric metadata.
 //
-// callback shouldn't hold tss after returning.
-func ParseStream(r io.Reader, callback func(tss []prompb.TimeSeries) error) error {
+// callback shouldn't hold tss and mms after returning.
+func ParseStream(r io.Reader, callback func(tss []prompb.TimeSeries, mms []prompb.MetricMetadata) error) error {
 	ctx := getPushCtx(r)
 	defer putPushCtx(ctx)
 	if err := ctx.Read(); err != nil {
@@ -54,7 +54,7 @@ func ParseStream(r io.Reader, callback func(tss []prompb.TimeSeries) error) erro
 	}
 	rowsRead.Add(rows)
 
-	if err := callback(tss); err != nil {
+	if err := callback(tss, wr.Metadata); err != nil {
 		return fmt.Errorf("error when processing imported data: %w", err)
 	}
 	return nil
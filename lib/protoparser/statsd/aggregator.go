@@ -0,0 +1,328 @@
+package statsd
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/histogram"
+)
+
+var (
+	flushInterval = flag.Duration("statsd.flushInterval", 10*time.Second, "The interval for aggregating StatsD samples before sending them to storage. "+
+		"See https://docs.victoriametrics.com/#how-to-send-data-from-statsd-compatible-agents")
+	percentiles = flag.String("statsd.percentiles", "0.5,0.9,0.99", "Comma-separated list of percentiles to calculate for StatsD timers and histograms. "+
+		"See https://docs.victoriametrics.com/#how-to-send-data-from-statsd-compatible-agents")
+	mappingConfig = flag.String("statsd.mappingConfig", "", "Optional path to file with rules for converting dotted StatsD metric names into metric names with labels. "+
+		"See https://docs.victoriametrics.com/#statsd-mapping-rules")
+)
+
+// stateTTL is the duration in seconds for keeping the state for StatsD series without new samples.
+const stateTTL = 3600
+
+// Aggregator aggregates StatsD rows into Prometheus-style time series over -statsd.flushInterval.
+//
+// Every flush interval the aggregated series are passed to flushFunc:
+//   - counters are converted into series with cumulative values;
+//   - gauges are converted into series with the last value;
+//   - timers and histograms are converted into name{quantile="..."}, name_count and name_sum series,
+//     where quantiles are calculated over the flush interval, while name_count and name_sum are cumulative;
+//   - sets are converted into series with the number of unique values seen during the flush interval.
+//
+// Only series, which received samples during the flush interval, are passed to flushFunc.
+type Aggregator struct {
+	flushFunc   func(tss []prompbmarshal.TimeSeries)
+	percentiles []float64
+	mappings    *mappings
+
+	mu sync.Mutex
+	m  map[string]*aggrState
+
+	// labels and keyBuf are used in Push for building series keys. They are protected by mu.
+	labels []prompbmarshal.Label
+	keyBuf []byte
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+// MustStartAggregator starts StatsD aggregator, which calls flushFunc with the aggregated series every -statsd.flushInterval.
+//
+// flushFunc shouldn't hold tss after returning.
+//
+// MustStop must be called on the returned aggregator when it is no longer needed.
+func MustStartAggregator(flushFunc func(tss []prompbmarshal.TimeSeries)) *Aggregator {
+	phis, err := parsePercentiles(*percentiles)
+	if err != nil {
+		logger.Fatalf("cannot parse -statsd.percentiles=%q: %s", *percentiles, err)
+	}
+	var ms *mappings
+	if *mappingConfig != "" {
+		ms, err = loadMappings(*mappingConfig)
+		if err != nil {
+			logger.Fatalf("cannot load -statsd.mappingConfig: %s", err)
+		}
+		logger.Infof("loaded %d StatsD mapping rules from -statsd.mappingConfig=%q", len(ms.matchers), *mappingConfig)
+	}
+	a := newAggregator(flushFunc, phis, ms)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.runFlusher(*flushInterval)
+	}()
+	return a
+}
+
+func newAggregator(flushFunc func(tss []prompbmarshal.TimeSeries), phis []float64, ms *mappings) *Aggregator {
+	return &Aggregator{
+		flushFunc:   flushFunc,
+		percentiles: phis,
+		mappings:    ms,
+		m:           make(map[string]*aggrState),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// MustStop stops a and flushes the remaining aggregated series.
+func (a *Aggregator) MustStop() {
+	close(a.stopCh)
+	a.wg.Wait()
+}
+
+func (a *Aggregator) runFlusher(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-a.stopCh:
+			a.flush(time.Now().UnixMilli())
+			return
+		case <-t.C:
+			a.flush(time.Now().UnixMilli())
+		}
+	}
+}
+
+// Push adds rows to a.
+func (a *Aggregator) Push(rows []Row) {
+	currentTime := fasttime.UnixTimestamp()
+	a.mu.Lock()
+	for i := range rows {
+		r := &rows[i]
+		labels := a.mappings.appendLabels(a.labels[:0], r.Metric)
+		for j := range r.Tags {
+			tag := &r.Tags[j]
+			labels = append(labels, prompbmarshal.Label{
+				Name:  tag.Key,
+				Value: tag.Value,
+			})
+		}
+		promrelabel.SortLabels(labels)
+		a.labels = labels
+		a.keyBuf = marshalKey(a.keyBuf[:0], r.Type, labels)
+		as := a.m[string(a.keyBuf)]
+		if as == nil {
+			as = &aggrState{
+				typ:    r.Type,
+				labels: cloneLabels(labels),
+			}
+			a.m[string(a.keyBuf)] = as
+			seriesCreated.Inc()
+		}
+		as.update(r)
+		as.lastUpdateTime = currentTime
+	}
+	promrelabel.CleanLabels(a.labels)
+	a.mu.Unlock()
+}
+
+var seriesCreated = metrics.NewCounter(`vm_protoparser_statsd_series_created_total`)
+
+// flush passes series updated since the previous flush to a.flushFunc with the given timestamp in milliseconds.
+func (a *Aggregator) flush(timestamp int64) {
+	var fc flushCtx
+	currentTime := fasttime.UnixTimestamp()
+	a.mu.Lock()
+	for k, as := range a.m {
+		if !as.updated {
+			if currentTime > as.lastUpdateTime+stateTTL {
+				delete(a.m, k)
+			}
+			continue
+		}
+		as.appendTimeSeries(&fc, a.percentiles, timestamp)
+	}
+	a.mu.Unlock()
+	if len(fc.tss) > 0 {
+		a.flushFunc(fc.tss)
+	}
+}
+
+// aggrState contains aggregation state for a single StatsD series.
+type aggrState struct {
+	typ    MetricType
+	labels []prompbmarshal.Label
+
+	// value is the cumulative value for counters and the last value for gauges.
+	value float64
+
+	// count and sum are cumulative count and sum for timers.
+	count float64
+	sum   float64
+
+	// h contains timer values received during the current flush interval.
+	h *histogram.Fast
+
+	// set contains unique set values received during the current flush interval.
+	set map[string]struct{}
+
+	// updated is set if samples were received during the current flush interval.
+	updated bool
+
+	// lastUpdateTime is the last time in seconds when samples were received.
+	lastUpdateTime uint64
+}
+
+func (as *aggrState) update(r *Row) {
+	as.updated = true
+	switch as.typ {
+	case MetricTypeCounter:
+		for _, v := range r.Values {
+			as.value += v / r.SampleRate
+		}
+	case MetricTypeGauge:
+		for _, v := range r.Values {
+			if r.IsRelative {
+				as.value += v
+			} else {
+				as.value = v
+			}
+		}
+	case MetricTypeTimer:
+		if as.h == nil {
+			as.h = histogram.GetFast()
+		}
+		for _, v := range r.Values {
+			as.count += 1 / r.SampleRate
+			as.sum += v / r.SampleRate
+			as.h.Update(v)
+		}
+	case MetricTypeSet:
+		if as.set == nil {
+			as.set = make(map[string]struct{})
+		}
+		for _, v := range r.SetValues {
+			if _, ok := as.set[v]; !ok {
+				as.set[cloneString(v)] = struct{}{}
+			}
+		}
+	}
+}
+
+// appendTimeSeries appends series for as to fc and resets the per-interval state for as.
+func (as *aggrState) appendTimeSeries(fc *flushCtx, phis []float64, timestamp int64) {
+	as.updated = false
+	switch as.typ {
+	case MetricTypeCounter, MetricTypeGauge:
+		fc.appendSample(as.labels, "", "", "", timestamp, as.value)
+	case MetricTypeTimer:
+		for _, phi := range phis {
+			quantile := strconv.FormatFloat(phi, 'g', -1, 64)
+			fc.appendSample(as.labels, "", "quantile", quantile, timestamp, as.h.Quantile(phi))
+		}
+		fc.appendSample(as.labels, "_count", "", "", timestamp, as.count)
+		fc.appendSample(as.labels, "_sum", "", "", timestamp, as.sum)
+		histogram.PutFast(as.h)
+		as.h = nil
+	case MetricTypeSet:
+		fc.appendSample(as.labels, "", "", "", timestamp, float64(len(as.set)))
+		as.set = nil
+	}
+}
+
+type flushCtx struct {
+	tss     []prompbmarshal.TimeSeries
+	labels  []prompbmarshal.Label
+	samples []prompbmarshal.Sample
+}
+
+// appendSample appends a time series with labels to fc.
+//
+// nameSuffix is added to the `__name__` label value, while optional extraName=extraValue label is appended to labels.
+func (fc *flushCtx) appendSample(labels []prompbmarshal.Label, nameSuffix, extraName, extraValue string, timestamp int64, value float64) {
+	labelsLen := len(fc.labels)
+	for _, label := range labels {
+		if nameSuffix != "" && label.Name == "__name__" {
+			label.Value += nameSuffix
+		}
+		fc.labels = append(fc.labels, label)
+	}
+	if extraName != "" {
+		fc.labels = append(fc.labels, prompbmarshal.Label{
+			Name:  extraName,
+			Value: extraValue,
+		})
+	}
+	samplesLen := len(fc.samples)
+	fc.samples = append(fc.samples, prompbmarshal.Sample{
+		Timestamp: timestamp,
+		Value:     value,
+	})
+	fc.tss = append(fc.tss, prompbmarshal.TimeSeries{
+		Labels:  fc.labels[labelsLen:],
+		Samples: fc.samples[samplesLen:],
+	})
+}
+
+func marshalKey(dst []byte, typ MetricType, labels []prompbmarshal.Label) []byte {
+	dst = append(dst, byte(typ))
+	for _, label := range labels {
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(label.Name))
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(label.Value))
+	}
+	return dst
+}
+
+func cloneLabels(labels []prompbmarshal.Label) []prompbmarshal.Label {
+	dst := make([]prompbmarshal.Label, len(labels))
+	for i, label := range labels {
+		dst[i] = prompbmarshal.Label{
+			Name:  cloneString(label.Name),
+			Value: cloneString(label.Value),
+		}
+	}
+	return dst
+}
+
+// cloneString returns a copy of s, which doesn't refer to the memory of s.
+func cloneString(s string) string {
+	return string(append([]byte{}, s...))
+}
+
+func parsePercentiles(s string) ([]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var phis []float64
+	for _, part := range strings.Split(s, ",") {
+		phi, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse percentile %q: %w", part, err)
+		}
+		if phi < 0 || phi > 1 {
+			return nil, fmt.Errorf("percentile must be in the range [0..1]; got %v", phi)
+		}
+		phis = append(phis, phi)
+	}
+	return phis, nil
+}
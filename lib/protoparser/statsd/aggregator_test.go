@@ -0,0 +1,175 @@
+package statsd
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestAggregator(t *testing.T) {
+	var result []string
+	flushFunc := func(tss []prompbmarshal.TimeSeries) {
+		for _, ts := range tss {
+			var name string
+			var labels []string
+			for _, label := range ts.Labels {
+				if label.Name == "__name__" {
+					name = label.Value
+					continue
+				}
+				labels = append(labels, fmt.Sprintf("%s=%q", label.Name, label.Value))
+			}
+			if len(labels) > 0 {
+				name += "{" + strings.Join(labels, ",") + "}"
+			}
+			for _, s := range ts.Samples {
+				result = append(result, fmt.Sprintf("%s %v %d", name, s.Value, s.Timestamp))
+			}
+		}
+	}
+	ms, err := parseMappingsData([]byte(`
+mappings:
+- match: "app.*.requests"
+  name: "app_requests_total"
+  labels:
+    instance: "$1"
+- match: "app.*.*"
+  labels:
+    job: "$2"
+`))
+	if err != nil {
+		t.Fatalf("cannot parse mappings: %s", err)
+	}
+	a := newAggregator(flushFunc, []float64{0, 0.5, 1}, ms)
+
+	f := func(s string, timestamp int64, resultExpected []string) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		a.Push(rows.Rows)
+		result = result[:0]
+		a.flush(timestamp)
+		sort.Strings(result)
+		sort.Strings(resultExpected)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", strings.Join(result, "\n"), strings.Join(resultExpected, "\n"))
+		}
+	}
+
+	// Counters are cumulative, while tags are converted to labels
+	f(`requests:1|c|#code:200
+requests:2|c|@0.5|#code:200
+requests:1|c|#code:500`, 1000, []string{
+		`requests{code="200"} 5 1000`,
+		`requests{code="500"} 1 1000`,
+	})
+	f(`requests:1|c|#code:200`, 2000, []string{
+		`requests{code="200"} 6 2000`,
+	})
+
+	// Gauges
+	f(`temperature:10|g
+temperature:+5|g
+temperature:-3|g`, 3000, []string{
+		`temperature 12 3000`,
+	})
+	f(`temperature:7|g`, 4000, []string{
+		`temperature 7 4000`,
+	})
+
+	// Timers
+	f(`latency:1:2:3|ms
+latency:4|ms|@0.5`, 5000, []string{
+		`latency{quantile="0"} 1 5000`,
+		`latency{quantile="0.5"} 3 5000`,
+		`latency{quantile="1"} 4 5000`,
+		`latency_count 5 5000`,
+		`latency_sum 14 5000`,
+	})
+	f(`latency:10|h`, 6000, []string{
+		`latency{quantile="0"} 10 6000`,
+		`latency{quantile="0.5"} 10 6000`,
+		`latency{quantile="1"} 10 6000`,
+		`latency_count 6 6000`,
+		`latency_sum 24 6000`,
+	})
+
+	// Sets count unique values per flush interval
+	f(`users:alice:bob|s
+users:alice|s`, 7000, []string{
+		`users 2 7000`,
+	})
+	f(`users:carol|s`, 8000, []string{
+		`users 1 8000`,
+	})
+
+	// Mapping rules
+	f(`app.foo.requests:1|c
+app.foo.errors:2|c|#code:500
+app.foo.bar.baz:3|g`, 9000, []string{
+		`app_requests_total{instance="foo"} 1 9000`,
+		`app.foo.errors{code="500",job="errors"} 2 9000`,
+		`app.foo.bar.baz 3 9000`,
+	})
+
+	// Nothing is flushed without new samples
+	f(``, 10000, []string{})
+}
+
+func TestParseMappingsFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := parseMappingsData([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// Invalid yaml
+	f(`foo`)
+	// Unknown field
+	f(`
+mappings:
+- match: "foo.*"
+  name: "foo"
+  foo: bar
+`)
+	// Missing match
+	f(`
+mappings:
+- name: "foo"
+`)
+	// Missing name and labels
+	f(`
+mappings:
+- match: "foo.*"
+`)
+	// __name__ in labels
+	f(`
+mappings:
+- match: "foo.*"
+  labels:
+    __name__: "$1"
+`)
+}
+
+func TestParsePercentiles(t *testing.T) {
+	f := func(s string, phisExpected []float64, okExpected bool) {
+		t.Helper()
+		phis, err := parsePercentiles(s)
+		if ok := err == nil; ok != okExpected {
+			t.Fatalf("unexpected error state for %q; got %v; want %v; err: %v", s, ok, okExpected, err)
+		}
+		if !reflect.DeepEqual(phis, phisExpected) {
+			t.Fatalf("unexpected percentiles for %q; got %v; want %v", s, phis, phisExpected)
+		}
+	}
+	f("", nil, true)
+	f("0.5", []float64{0.5}, true)
+	f("0, 0.9,1", []float64{0, 0.9, 1}, true)
+	f("foo", nil, false)
+	f("1.5", nil, false)
+	f("-0.1", nil, false)
+}
@@ -0,0 +1,115 @@
+package statsd
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"gopkg.in/yaml.v2"
+)
+
+// MappingConfig represents StatsD mapping config.
+//
+// Mapping rules convert dotted StatsD metric names into metric names with labels. For example:
+//
+//	mappings:
+//	- match: 'app.*.requests.*'
+//	  name: 'app_requests_total'
+//	  labels:
+//	    instance: '$1'
+//	    code: '$2'
+type MappingConfig struct {
+	Mappings []MappingRule `yaml:"mappings"`
+}
+
+// MappingRule is a single mapping rule from MappingConfig.
+type MappingRule struct {
+	// Match is a template for matching StatsD metric names. It may contain `*` placeholders,
+	// which match any substring without dots.
+	Match string `yaml:"match"`
+
+	// Name is an optional template for the metric name. The original metric name is preserved if it is empty.
+	// It may refer to `*` matches from Match via $N or ${N}.
+	Name string `yaml:"name,omitempty"`
+
+	// Labels contains templates for labels to add to the matching metric.
+	// The templates may refer to `*` matches from Match via $N or ${N}.
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// mappings contains parsed mapping rules.
+type mappings struct {
+	matchers []*promrelabel.GraphiteMatcher
+}
+
+// loadMappings loads mapping rules from the given path.
+func loadMappings(path string) (*mappings, error) {
+	data, err := fs.ReadFileOrHTTP(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read StatsD mapping config from %q: %w", path, err)
+	}
+	data = envtemplate.Replace(data)
+	ms, err := parseMappingsData(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse StatsD mapping config from %q: %w", path, err)
+	}
+	return ms, nil
+}
+
+func parseMappingsData(data []byte) (*mappings, error) {
+	var cfg MappingConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	return parseMappings(cfg.Mappings)
+}
+
+func parseMappings(rules []MappingRule) (*mappings, error) {
+	var ms mappings
+	for i := range rules {
+		rule := &rules[i]
+		if rule.Match == "" {
+			return nil, fmt.Errorf("missing `match` in mapping rule #%d", i+1)
+		}
+		if rule.Name == "" && len(rule.Labels) == 0 {
+			return nil, fmt.Errorf("mapping rule #%d must contain `name` or `labels`", i+1)
+		}
+		labels := make(map[string]string, len(rule.Labels)+1)
+		for name, value := range rule.Labels {
+			if name == "__name__" {
+				return nil, fmt.Errorf("mapping rule #%d cannot contain `__name__` label; use `name` instead", i+1)
+			}
+			labels[name] = value
+		}
+		if rule.Name != "" {
+			labels["__name__"] = rule.Name
+		}
+		ms.matchers = append(ms.matchers, promrelabel.NewGraphiteMatcher(rule.Match, labels))
+	}
+	return &ms, nil
+}
+
+// appendLabels appends labels for the given StatsD metric name to dst and returns the result.
+//
+// The first matching rule is applied to name. The `__name__` label is set to name if it isn't set by the matching rule.
+func (ms *mappings) appendLabels(dst []prompbmarshal.Label, name string) []prompbmarshal.Label {
+	dstLen := len(dst)
+	if ms != nil {
+		for _, gm := range ms.matchers {
+			var ok bool
+			dst, ok = gm.Match(dst, name)
+			if ok {
+				break
+			}
+		}
+	}
+	if promrelabel.GetLabelByName(dst[dstLen:], "__name__") == nil {
+		dst = append(dst, prompbmarshal.Label{
+			Name:  "__name__",
+			Value: name,
+		})
+	}
+	return dst
+}
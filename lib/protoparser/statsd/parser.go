@@ -0,0 +1,306 @@
+package statsd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+// MetricType is StatsD metric type.
+type MetricType byte
+
+const (
+	// MetricTypeCounter is StatsD counter (`c` type).
+	MetricTypeCounter = MetricType(iota)
+
+	// MetricTypeGauge is StatsD gauge (`g` type).
+	MetricTypeGauge
+
+	// MetricTypeTimer is StatsD timer (`ms` type), histogram (`h` type) or DogStatsD distribution (`d` type).
+	MetricTypeTimer
+
+	// MetricTypeSet is StatsD set (`s` type).
+	MetricTypeSet
+)
+
+// String returns string representation of mt.
+func (mt MetricType) String() string {
+	switch mt {
+	case MetricTypeCounter:
+		return "counter"
+	case MetricTypeGauge:
+		return "gauge"
+	case MetricTypeTimer:
+		return "timer"
+	case MetricTypeSet:
+		return "set"
+	default:
+		return fmt.Sprintf("unknown(%d)", byte(mt))
+	}
+}
+
+// Rows contains parsed StatsD rows.
+type Rows struct {
+	Rows []Row
+
+	tagsPool      []Tag
+	valuesPool    []float64
+	setValuesPool []string
+}
+
+// Reset resets rs.
+func (rs *Rows) Reset() {
+	// Reset items, so they can be GC'ed
+
+	for i := range rs.Rows {
+		rs.Rows[i].reset()
+	}
+	rs.Rows = rs.Rows[:0]
+
+	for i := range rs.tagsPool {
+		rs.tagsPool[i].reset()
+	}
+	rs.tagsPool = rs.tagsPool[:0]
+
+	rs.valuesPool = rs.valuesPool[:0]
+
+	for i := range rs.setValuesPool {
+		rs.setValuesPool[i] = ""
+	}
+	rs.setValuesPool = rs.setValuesPool[:0]
+}
+
+// Unmarshal unmarshals StatsD and DogStatsD lines from s.
+//
+// See https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+// and https://docs.datadoghq.com/developers/dogstatsd/datagram_shell/?tab=metrics
+//
+// s shouldn't be modified when rs is in use.
+func (rs *Rows) Unmarshal(s string) {
+	rs.Rows = rs.Rows[:0]
+	rs.tagsPool = rs.tagsPool[:0]
+	rs.valuesPool = rs.valuesPool[:0]
+	rs.setValuesPool = rs.setValuesPool[:0]
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '\n')
+		if n < 0 {
+			// The last line.
+			rs.unmarshalRow(s)
+			return
+		}
+		rs.unmarshalRow(s[:n])
+		s = s[n+1:]
+	}
+}
+
+func (rs *Rows) unmarshalRow(s string) {
+	if len(s) > 0 && s[len(s)-1] == '\r' {
+		s = s[:len(s)-1]
+	}
+	if len(s) == 0 {
+		// Skip empty line
+		return
+	}
+	if strings.HasPrefix(s, "_e{") || strings.HasPrefix(s, "_sc|") {
+		// Skip DogStatsD events and service checks, since they cannot be converted to samples.
+		return
+	}
+	if cap(rs.Rows) > len(rs.Rows) {
+		rs.Rows = rs.Rows[:len(rs.Rows)+1]
+	} else {
+		rs.Rows = append(rs.Rows, Row{})
+	}
+	r := &rs.Rows[len(rs.Rows)-1]
+	if err := rs.unmarshal(r, s); err != nil {
+		rs.Rows = rs.Rows[:len(rs.Rows)-1]
+		logger.Errorf("cannot unmarshal StatsD line %q: %s", s, err)
+		invalidLines.Inc()
+	}
+}
+
+var invalidLines = metrics.NewCounter(`vm_rows_invalid_total{type="statsd"}`)
+
+// Row is a single StatsD row.
+type Row struct {
+	Metric string
+	Tags   []Tag
+	Type   MetricType
+
+	// Values contains values for counters, gauges and timers.
+	Values []float64
+
+	// SetValues contains values for sets.
+	SetValues []string
+
+	// SampleRate is the sample rate from `|@rate` field. It equals to 1 if the field is missing.
+	SampleRate float64
+
+	// IsRelative is set for gauges with values starting with `+` or `-`.
+	// Such values must be added to the current gauge value.
+	IsRelative bool
+}
+
+func (r *Row) reset() {
+	r.Metric = ""
+	r.Tags = nil
+	r.Type = 0
+	r.Values = nil
+	r.SetValues = nil
+	r.SampleRate = 0
+	r.IsRelative = false
+}
+
+// unmarshal unmarshals StatsD line in the format `metric:value[:value...]|type[|@sample_rate][|#tag:value,...]` into r.
+func (rs *Rows) unmarshal(r *Row, s string) error {
+	r.reset()
+	n := strings.IndexByte(s, ':')
+	if n < 0 {
+		return fmt.Errorf("cannot find ':' between metric name and value")
+	}
+	r.Metric = s[:n]
+	if len(r.Metric) == 0 {
+		return fmt.Errorf("metric name cannot be empty")
+	}
+	s = s[n+1:]
+	n = strings.IndexByte(s, '|')
+	if n < 0 {
+		return fmt.Errorf("cannot find '|' between value and metric type")
+	}
+	valuesStr := s[:n]
+	s = s[n+1:]
+	typeStr := s
+	n = strings.IndexByte(s, '|')
+	if n >= 0 {
+		typeStr = s[:n]
+		s = s[n+1:]
+	} else {
+		s = ""
+	}
+	switch typeStr {
+	case "c":
+		r.Type = MetricTypeCounter
+	case "g":
+		r.Type = MetricTypeGauge
+	case "ms", "h", "d":
+		r.Type = MetricTypeTimer
+	case "s":
+		r.Type = MetricTypeSet
+	default:
+		return fmt.Errorf("unsupported metric type %q", typeStr)
+	}
+	if err := rs.unmarshalValues(r, valuesStr); err != nil {
+		return err
+	}
+
+	r.SampleRate = 1
+	for len(s) > 0 {
+		field := s
+		n := strings.IndexByte(s, '|')
+		if n >= 0 {
+			field = s[:n]
+			s = s[n+1:]
+		} else {
+			s = ""
+		}
+		if len(field) == 0 {
+			continue
+		}
+		switch field[0] {
+		case '@':
+			rate, err := fastfloat.Parse(field[1:])
+			if err != nil {
+				return fmt.Errorf("cannot parse sample rate from %q: %w", field, err)
+			}
+			if rate <= 0 || rate > 1 {
+				return fmt.Errorf("sample rate must be in the range (0..1]; got %q", field)
+			}
+			r.SampleRate = rate
+		case '#':
+			tagsStart := len(rs.tagsPool)
+			rs.tagsPool = unmarshalTags(rs.tagsPool, field[1:])
+			tags := rs.tagsPool[tagsStart:]
+			r.Tags = tags[:len(tags):len(tags)]
+		default:
+			// Ignore unsupported DogStatsD fields such as container id (`c:...`) or timestamp (`T...`).
+		}
+	}
+	return nil
+}
+
+func (rs *Rows) unmarshalValues(r *Row, s string) error {
+	if r.Type == MetricTypeSet {
+		valuesStart := len(rs.setValuesPool)
+		for {
+			n := strings.IndexByte(s, ':')
+			if n < 0 {
+				rs.setValuesPool = append(rs.setValuesPool, s)
+				break
+			}
+			rs.setValuesPool = append(rs.setValuesPool, s[:n])
+			s = s[n+1:]
+		}
+		values := rs.setValuesPool[valuesStart:]
+		r.SetValues = values[:len(values):len(values)]
+		return nil
+	}
+	if r.Type == MetricTypeGauge && len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		r.IsRelative = true
+	}
+	valuesStart := len(rs.valuesPool)
+	for {
+		v := s
+		n := strings.IndexByte(s, ':')
+		if n >= 0 {
+			v = s[:n]
+			s = s[n+1:]
+		}
+		f, err := fastfloat.Parse(strings.TrimPrefix(v, "+"))
+		if err != nil {
+			return fmt.Errorf("cannot parse value from %q: %w", v, err)
+		}
+		rs.valuesPool = append(rs.valuesPool, f)
+		if n < 0 {
+			break
+		}
+	}
+	values := rs.valuesPool[valuesStart:]
+	r.Values = values[:len(values):len(values)]
+	return nil
+}
+
+func unmarshalTags(dst []Tag, s string) []Tag {
+	for len(s) > 0 {
+		tagStr := s
+		n := strings.IndexByte(s, ',')
+		if n >= 0 {
+			tagStr = s[:n]
+			s = s[n+1:]
+		} else {
+			s = ""
+		}
+		n = strings.IndexByte(tagStr, ':')
+		if n <= 0 || n == len(tagStr)-1 {
+			// Skip tags without names or values, since they cannot be converted to labels.
+			continue
+		}
+		dst = append(dst, Tag{
+			Key:   tagStr[:n],
+			Value: tagStr[n+1:],
+		})
+	}
+	return dst
+}
+
+// Tag is a DogStatsD tag.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+func (t *Tag) reset() {
+	t.Key = ""
+	t.Value = ""
+}
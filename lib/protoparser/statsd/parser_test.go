@@ -0,0 +1,202 @@
+package statsd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowsUnmarshalFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if len(rows.Rows) != 0 {
+			t.Fatalf("expecting zero rows; got %d rows", len(rows.Rows))
+		}
+
+		// Try again
+		rows.Unmarshal(s)
+		if len(rows.Rows) != 0 {
+			t.Fatalf("expecting zero rows; got %d rows", len(rows.Rows))
+		}
+	}
+
+	// Missing value
+	f("foo")
+	f("foo|c")
+
+	// Missing metric name
+	f(":1|c")
+
+	// Missing type
+	f("foo:1")
+
+	// Invalid type
+	f("foo:1|x")
+
+	// Invalid value
+	f("foo:bar|c")
+	f("foo:1:|c")
+
+	// Invalid sample rate
+	f("foo:1|c|@bar")
+	f("foo:1|c|@0")
+	f("foo:1|c|@2")
+
+	// DogStatsD events and service checks
+	f("_e{5,4}:title|text")
+	f("_sc|check|0")
+}
+
+func TestRowsUnmarshalSuccess(t *testing.T) {
+	f := func(s string, rowsExpected *Rows) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected.Rows) {
+			t.Fatalf("unexpected rows;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected.Rows)
+		}
+
+		// Try unmarshaling again
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected.Rows) {
+			t.Fatalf("unexpected rows on the second unmarshal;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected.Rows)
+		}
+
+		rows.Reset()
+		if len(rows.Rows) != 0 {
+			t.Fatalf("non-empty rows after reset: %+v", rows.Rows)
+		}
+	}
+
+	// Empty line
+	f("", &Rows{})
+	f("\r", &Rows{})
+	f("\n\n", &Rows{})
+
+	// Counter
+	f("foo.bar:1|c", &Rows{
+		Rows: []Row{{
+			Metric:     "foo.bar",
+			Type:       MetricTypeCounter,
+			Values:     []float64{1},
+			SampleRate: 1,
+		}},
+	})
+
+	// Counter with sample rate and tags
+	f("foo:2|c|@0.5|#env:prod,host:abc,novalue", &Rows{
+		Rows: []Row{{
+			Metric: "foo",
+			Tags: []Tag{
+				{
+					Key:   "env",
+					Value: "prod",
+				},
+				{
+					Key:   "host",
+					Value: "abc",
+				},
+			},
+			Type:       MetricTypeCounter,
+			Values:     []float64{2},
+			SampleRate: 0.5,
+		}},
+	})
+
+	// Gauges
+	f("foo:-1.5|g\nbar:+2|g\r\nbaz:3|g", &Rows{
+		Rows: []Row{
+			{
+				Metric:     "foo",
+				Type:       MetricTypeGauge,
+				Values:     []float64{-1.5},
+				SampleRate: 1,
+				IsRelative: true,
+			},
+			{
+				Metric:     "bar",
+				Type:       MetricTypeGauge,
+				Values:     []float64{2},
+				SampleRate: 1,
+				IsRelative: true,
+			},
+			{
+				Metric:     "baz",
+				Type:       MetricTypeGauge,
+				Values:     []float64{3},
+				SampleRate: 1,
+			},
+		},
+	})
+
+	// Timers, histograms and distributions with multiple values
+	f("foo:1:2:3|ms\nbar:4|h|#a:b\nbaz:5|d", &Rows{
+		Rows: []Row{
+			{
+				Metric:     "foo",
+				Type:       MetricTypeTimer,
+				Values:     []float64{1, 2, 3},
+				SampleRate: 1,
+			},
+			{
+				Metric: "bar",
+				Tags: []Tag{{
+					Key:   "a",
+					Value: "b",
+				}},
+				Type:       MetricTypeTimer,
+				Values:     []float64{4},
+				SampleRate: 1,
+			},
+			{
+				Metric:     "baz",
+				Type:       MetricTypeTimer,
+				Values:     []float64{5},
+				SampleRate: 1,
+			},
+		},
+	})
+
+	// Sets
+	f("users:alice:bob|s", &Rows{
+		Rows: []Row{{
+			Metric:     "users",
+			Type:       MetricTypeSet,
+			SetValues:  []string{"alice", "bob"},
+			SampleRate: 1,
+		}},
+	})
+
+	// Unsupported DogStatsD fields are ignored
+	f("foo:1|c|#a:b|c:container123|T1656581400", &Rows{
+		Rows: []Row{{
+			Metric: "foo",
+			Tags: []Tag{{
+				Key:   "a",
+				Value: "b",
+			}},
+			Type:       MetricTypeCounter,
+			Values:     []float64{1},
+			SampleRate: 1,
+		}},
+	})
+
+	// Invalid lines are skipped
+	f("foo:1|c\nbar\n_e{1,1}:a|b\nbaz:2|g", &Rows{
+		Rows: []Row{
+			{
+				Metric:     "foo",
+				Type:       MetricTypeCounter,
+				Values:     []float64{1},
+				SampleRate: 1,
+			},
+			{
+				Metric:     "baz",
+				Type:       MetricTypeGauge,
+				Values:     []float64{2},
+				SampleRate: 1,
+			},
+		},
+	})
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// SetDownsamplingPeriods sets downsampling periods, which are applied to samples during background merges.
+//
+// Every period must be in the form `offset:interval`. For example, `30d:5m` leaves only the last sample
+// per each 5 minutes interval for samples older than 30 days. Zero offset is equivalent to SetDedupInterval.
+//
+// Downsampling is disabled if periods is empty.
+//
+// This function must be called before initializing the storage.
+func SetDownsamplingPeriods(periods []string) error {
+	dps, err := parseDownsamplingPeriods(periods)
+	if err != nil {
+		return err
+	}
+	downsamplingPeriods = dps
+	return nil
+}
+
+// downsamplingPeriods contains periods sorted by offset in descending order.
+var downsamplingPeriods []downsamplingPeriod
+
+type downsamplingPeriod struct {
+	// offset in milliseconds. Downsampling is applied to samples older than offset.
+	offset int64
+
+	// interval in milliseconds. Only the last sample is left per each interval.
+	interval int64
+}
+
+func parseDownsamplingPeriods(periods []string) ([]downsamplingPeriod, error) {
+	var dps []downsamplingPeriod
+	for _, period := range periods {
+		dp, err := parseDownsamplingPeriod(period)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse downsampling period %q: %w", period, err)
+		}
+		dps = append(dps, dp)
+	}
+	sort.Slice(dps, func(i, j int) bool {
+		return dps[i].offset > dps[j].offset
+	})
+	for i := 1; i < len(dps); i++ {
+		prev, dp := &dps[i-1], &dps[i]
+		if prev.offset == dp.offset {
+			return nil, fmt.Errorf("duplicate downsampling offset %dms", dp.offset)
+		}
+		if prev.interval <= dp.interval {
+			return nil, fmt.Errorf("downsampling interval for offset %dms must exceed the interval for offset %dms; got %dms vs %dms",
+				prev.offset, dp.offset, prev.interval, dp.interval)
+		}
+	}
+	return dps, nil
+}
+
+func parseDownsamplingPeriod(s string) (downsamplingPeriod, error) {
+	var dp downsamplingPeriod
+	n := strings.IndexByte(s, ':')
+	if n < 0 {
+		return dp, fmt.Errorf("missing ':' delimiter between offset and interval; the period must be in the form `offset:interval`")
+	}
+	offset, err := promutils.ParseDuration(s[:n])
+	if err != nil {
+		return dp, fmt.Errorf("cannot parse offset: %w", err)
+	}
+	if offset < 0 {
+		return dp, fmt.Errorf("offset cannot be negative; got %s", offset)
+	}
+	interval, err := promutils.ParseDuration(s[n+1:])
+	if err != nil {
+		return dp, fmt.Errorf("cannot parse interval: %w", err)
+	}
+	if interval <= 0 {
+		return dp, fmt.Errorf("interval must be positive; got %s", interval)
+	}
+	dp.offset = offset.Milliseconds()
+	dp.interval = interval.Milliseconds()
+	return dp, nil
+}
+
+func isDownsamplingEnabled() bool {
+	return len(downsamplingPeriods) > 0
+}
+
+// getDedupIntervalForTimestamp returns the interval in milliseconds, which must be used
+// for de-duplicating samples with the given timestamp at currentTimestamp.
+//
+// The interval takes into account both the global dedup interval and downsampling periods.
+func getDedupIntervalForTimestamp(timestamp, currentTimestamp int64) int64 {
+	dedupInterval := GetDedupInterval()
+	for _, dp := range downsamplingPeriods {
+		if timestamp < currentTimestamp-dp.offset {
+			if dp.interval > dedupInterval {
+				dedupInterval = dp.interval
+			}
+			break
+		}
+	}
+	return dedupInterval
+}
+
+// downsampleSamplesDuringMerge applies downsampling periods and the global dedup interval to samples.
+//
+// Samples in every downsampling period are de-duplicated with the period interval,
+// so only the last sample is left per each interval. This keeps rate() and increase() results
+// over counters unchanged.
+func downsampleSamplesDuringMerge(srcTimestamps, srcValues []int64, currentTimestamp int64) ([]int64, []int64) {
+	dstTimestamps := srcTimestamps[:0]
+	dstValues := srcValues[:0]
+	i := 0
+	for _, dp := range downsamplingPeriods {
+		deadline := currentTimestamp - dp.offset
+		n := i + sort.Search(len(srcTimestamps)-i, func(j int) bool {
+			return srcTimestamps[i+j] >= deadline
+		})
+		if n == i {
+			continue
+		}
+		interval := dp.interval
+		if dedupInterval := GetDedupInterval(); dedupInterval > interval {
+			interval = dedupInterval
+		}
+		timestamps, values := deduplicateSamplesDuringMerge(srcTimestamps[i:n], srcValues[i:n], interval)
+		dstTimestamps = append(dstTimestamps, timestamps...)
+		dstValues = append(dstValues, values...)
+		i = n
+	}
+	timestamps, values := deduplicateSamplesDuringMerge(srcTimestamps[i:], srcValues[i:], GetDedupInterval())
+	dstTimestamps = append(dstTimestamps, timestamps...)
+	dstValues = append(dstValues, values...)
+	return dstTimestamps, dstValues
+}
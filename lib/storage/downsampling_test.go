@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDownsamplingPeriodsFailure(t *testing.T) {
+	f := func(periods []string) {
+		t.Helper()
+		if _, err := parseDownsamplingPeriods(periods); err == nil {
+			t.Fatalf("expecting non-nil error for %q", periods)
+		}
+	}
+	f([]string{""})
+	f([]string{"30d"})
+	f([]string{"30d:"})
+	f([]string{":5m"})
+	f([]string{"foo:5m"})
+	f([]string{"30d:bar"})
+	f([]string{"-1d:5m"})
+	f([]string{"30d:0s"})
+
+	// Duplicate offsets
+	f([]string{"30d:5m", "30d:1h"})
+
+	// Intervals must increase with offsets
+	f([]string{"30d:1h", "180d:5m"})
+	f([]string{"30d:1h", "180d:1h"})
+}
+
+func TestParseDownsamplingPeriodsSuccess(t *testing.T) {
+	f := func(periods []string, dpsExpected []downsamplingPeriod) {
+		t.Helper()
+		dps, err := parseDownsamplingPeriods(periods)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(dps, dpsExpected) {
+			t.Fatalf("unexpected periods for %q;\ngot\n%+v\nwant\n%+v", periods, dps, dpsExpected)
+		}
+	}
+	const day = 24 * 3600 * 1000
+	f(nil, nil)
+	f([]string{"30d:5m"}, []downsamplingPeriod{
+		{offset: 30 * day, interval: 5 * 60 * 1000},
+	})
+	f([]string{"30d:5m", "180d:1h"}, []downsamplingPeriod{
+		{offset: 180 * day, interval: 3600 * 1000},
+		{offset: 30 * day, interval: 5 * 60 * 1000},
+	})
+	f([]string{"1y:1d", "1w:1m", "0s:10s"}, []downsamplingPeriod{
+		{offset: 365 * day, interval: day},
+		{offset: 7 * day, interval: 60 * 1000},
+		{offset: 0, interval: 10 * 1000},
+	})
+}
+
+func TestGetDedupIntervalForTimestamp(t *testing.T) {
+	// Disable downsampling before exit, since the rest of tests expect disabled downsampling.
+	defer func() {
+		downsamplingPeriods = nil
+	}()
+	downsamplingPeriods = []downsamplingPeriod{
+		{offset: 50, interval: 10},
+		{offset: 20, interval: 5},
+	}
+
+	f := func(timestamp, intervalExpected int64) {
+		t.Helper()
+		interval := getDedupIntervalForTimestamp(timestamp, 100)
+		if interval != intervalExpected {
+			t.Fatalf("unexpected dedup interval for timestamp=%d; got %d; want %d", timestamp, interval, intervalExpected)
+		}
+	}
+	f(0, 10)
+	f(49, 10)
+	f(50, 5)
+	f(79, 5)
+	f(80, 0)
+	f(150, 0)
+}
+
+func TestDownsampleSamplesDuringMerge(t *testing.T) {
+	// Disable downsampling before exit, since the rest of tests expect disabled downsampling.
+	defer func() {
+		downsamplingPeriods = nil
+	}()
+	downsamplingPeriods = []downsamplingPeriod{
+		{offset: 50, interval: 10},
+		{offset: 20, interval: 5},
+	}
+
+	f := func(timestamps, timestampsExpected, valuesExpected []int64) {
+		t.Helper()
+		timestampsCopy := append([]int64{}, timestamps...)
+		values := make([]int64, len(timestamps))
+		for i := range values {
+			values[i] = int64(i)
+		}
+		timestampsCopy, values = downsampleSamplesDuringMerge(timestampsCopy, values, 100)
+		if !reflect.DeepEqual(timestampsCopy, timestampsExpected) {
+			t.Fatalf("invalid downsampleSamplesDuringMerge(%v) timestamps;\ngot\n%v\nwant\n%v", timestamps, timestampsCopy, timestampsExpected)
+		}
+		if !reflect.DeepEqual(values, valuesExpected) {
+			t.Fatalf("invalid downsampleSamplesDuringMerge(%v) values;\ngot\n%v\nwant\n%v", timestamps, values, valuesExpected)
+		}
+
+		// Verify that the second call to downsampleSamplesDuringMerge doesn't modify samples.
+		valuesCopy := append([]int64{}, values...)
+		timestampsCopy, valuesCopy = downsampleSamplesDuringMerge(timestampsCopy, valuesCopy, 100)
+		if !reflect.DeepEqual(timestampsCopy, timestampsExpected) {
+			t.Fatalf("invalid downsampleSamplesDuringMerge(%v) timestamps for the second call;\ngot\n%v\nwant\n%v", timestamps, timestampsCopy, timestampsExpected)
+		}
+		if !reflect.DeepEqual(valuesCopy, values) {
+			t.Fatalf("invalid downsampleSamplesDuringMerge(%v) values for the second call;\ngot\n%v\nwant\n%v", timestamps, valuesCopy, values)
+		}
+	}
+	f(nil, []int64{}, []int64{})
+	f([]int64{10}, []int64{10}, []int64{0})
+
+	// Samples newer than all the offsets remain untouched
+	f([]int64{80, 81, 82}, []int64{80, 81, 82}, []int64{0, 1, 2})
+
+	// Samples are downsampled according to their offsets. The last sample per each interval is left,
+	// so increase() over counters remains the same.
+	f([]int64{0, 3, 7, 10, 14, 18, 22, 49, 50, 51, 60, 61, 62, 63, 78, 79, 80, 81, 82, 83},
+		[]int64{0, 10, 18, 22, 49, 50, 51, 60, 63, 79, 80, 81, 82, 83},
+		[]int64{0, 3, 5, 6, 7, 8, 9, 10, 13, 15, 16, 17, 18, 19})
+
+	// The global dedup interval is applied to samples newer than all the offsets
+	// and it overrides smaller downsampling intervals.
+	SetDedupInterval(7 * time.Millisecond)
+	defer SetDedupInterval(0)
+	f([]int64{50, 51, 60, 61, 62, 63, 78, 79, 80, 81, 82, 83},
+		[]int64{51, 63, 79, 83},
+		[]int64{1, 5, 7, 11})
+}
@@ -0,0 +1,452 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/uint64set"
+)
+
+// maxExemplarLabelSetLength is the maximum length in runes for exemplar label names plus values.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#exemplars
+const maxExemplarLabelSetLength = 128
+
+var maxExemplars = 100000
+
+// SetMaxExemplars sets the maximum number of exemplars to keep in memory.
+//
+// Exemplars are stored in a circular buffer, so the oldest exemplars are dropped when the buffer becomes full.
+// Zero value disables exemplars storage.
+//
+// This function must be called before OpenStorage.
+func SetMaxExemplars(n int) {
+	maxExemplars = n
+}
+
+// Exemplar is an exemplar attached to a sample.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#exemplars
+type Exemplar struct {
+	// Tags contains exemplar labels such as trace_id.
+	Tags []Tag
+
+	// Value is exemplar value.
+	Value float64
+
+	// Timestamp is exemplar timestamp in milliseconds.
+	Timestamp int64
+}
+
+// ExemplarRow is an exemplar for the series with the given MetricNameRaw.
+type ExemplarRow struct {
+	// MetricNameRaw contains raw metric name, which must be decoded
+	// with MetricName.UnmarshalRaw.
+	MetricNameRaw []byte
+
+	Exemplar Exemplar
+}
+
+// SeriesExemplars contains exemplars for the series with the given MetricName.
+type SeriesExemplars struct {
+	MetricName MetricName
+	Exemplars  []Exemplar
+}
+
+type exemplarEntry struct {
+	metricID  uint64
+	timestamp int64
+	value     float64
+
+	// tags contains marshaled exemplar tags.
+	tags []byte
+}
+
+// exemplarStorage holds up to len(entries) the most recently added exemplars.
+type exemplarStorage struct {
+	mu sync.Mutex
+
+	entries []exemplarEntry
+
+	// next is the index in entries for the next exemplar.
+	next int
+
+	// count is the number of valid entries.
+	count int
+
+	// lastIdx contains metricID -> index of the last added exemplar for the given metricID.
+	// It is used for detecting duplicate and out of order exemplars.
+	lastIdx map[uint64]int
+}
+
+func newExemplarStorage(capacity int) *exemplarStorage {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &exemplarStorage{
+		entries: make([]exemplarEntry, capacity),
+		lastIdx: make(map[uint64]int),
+	}
+}
+
+func (es *exemplarStorage) Len() int {
+	es.mu.Lock()
+	n := es.count
+	es.mu.Unlock()
+	return n
+}
+
+// add adds exemplar for the given metricID to es.
+//
+// It returns false if the exemplar is a duplicate or is older than the last exemplar for the given metricID.
+func (es *exemplarStorage) add(metricID uint64, timestamp int64, value float64, tags []byte) bool {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if len(es.entries) == 0 {
+		return false
+	}
+	if idx, ok := es.lastIdx[metricID]; ok {
+		last := &es.entries[idx]
+		if timestamp < last.timestamp {
+			return false
+		}
+		if timestamp == last.timestamp && math.Float64bits(value) == math.Float64bits(last.value) && string(tags) == string(last.tags) {
+			return false
+		}
+	}
+	e := &es.entries[es.next]
+	if es.count == len(es.entries) {
+		// Drop the oldest exemplar.
+		if idx, ok := es.lastIdx[e.metricID]; ok && idx == es.next {
+			delete(es.lastIdx, e.metricID)
+		}
+	} else {
+		es.count++
+	}
+	e.metricID = metricID
+	e.timestamp = timestamp
+	e.value = value
+	e.tags = append(e.tags[:0], tags...)
+	es.lastIdx[metricID] = es.next
+	es.next++
+	if es.next >= len(es.entries) {
+		es.next = 0
+	}
+	return true
+}
+
+// forEach calls f for all the entries in es in the order they were added.
+//
+// f mustn't hold references to e after returning.
+func (es *exemplarStorage) forEach(f func(e *exemplarEntry)) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	start := es.next - es.count
+	if start < 0 {
+		start += len(es.entries)
+	}
+	for i := 0; i < es.count; i++ {
+		idx := start + i
+		if idx >= len(es.entries) {
+			idx -= len(es.entries)
+		}
+		f(&es.entries[idx])
+	}
+}
+
+func marshalExemplarTags(dst []byte, tags []Tag) []byte {
+	dst = encoding.MarshalVarUint64(dst, uint64(len(tags)))
+	for i := range tags {
+		dst = tags[i].Marshal(dst)
+	}
+	return dst
+}
+
+func unmarshalExemplarTags(dst []Tag, src []byte) ([]Tag, error) {
+	tail, n, err := encoding.UnmarshalVarUint64(src)
+	if err != nil {
+		return dst, fmt.Errorf("cannot unmarshal tags count: %w", err)
+	}
+	src = tail
+	for i := uint64(0); i < n; i++ {
+		dst = append(dst, Tag{})
+		tag := &dst[len(dst)-1]
+		tail, err := tag.Unmarshal(src)
+		if err != nil {
+			return dst, fmt.Errorf("cannot unmarshal tag #%d: %w", i, err)
+		}
+		src = tail
+	}
+	if len(src) > 0 {
+		return dst, fmt.Errorf("unexpected non-empty tail left after unmarshaling tags; len(tail)=%d", len(src))
+	}
+	return dst, nil
+}
+
+func exemplarLabelSetLength(tags []Tag) int {
+	n := 0
+	for i := range tags {
+		tag := &tags[i]
+		n += utf8.RuneCount(tag.Key) + utf8.RuneCount(tag.Value)
+	}
+	return n
+}
+
+// AddExemplars adds the given exemplars to s.
+//
+// Exemplars for series, which don't exist in s, are ignored.
+// So exemplars must be added after the corresponding samples.
+func (s *Storage) AddExemplars(ers []ExemplarRow) error {
+	es := s.exemplars
+	if len(es.entries) == 0 || len(ers) == 0 {
+		return nil
+	}
+	minTimestamp := int64(fasttime.UnixTimestamp()*1000) - s.retentionMsecs
+	idb := s.idb()
+	var (
+		genTSID           generationTSID
+		tsid              TSID
+		mn                MetricName
+		metricName        []byte
+		tagsBuf           []byte
+		prevMetricNameRaw []byte
+		prevMetricID      uint64
+		prevFound         bool
+		firstWarn         error
+	)
+	for i := range ers {
+		er := &ers[i]
+		e := &er.Exemplar
+		if e.Timestamp < minTimestamp {
+			atomic.AddUint64(&s.exemplarsIgnored, 1)
+			continue
+		}
+		if exemplarLabelSetLength(e.Tags) > maxExemplarLabelSetLength {
+			atomic.AddUint64(&s.exemplarsIgnored, 1)
+			if firstWarn == nil {
+				firstWarn = fmt.Errorf("exemplar labels for %s exceed %d runes", getUserReadableMetricName(er.MetricNameRaw), maxExemplarLabelSetLength)
+			}
+			continue
+		}
+		if string(er.MetricNameRaw) != string(prevMetricNameRaw) {
+			prevMetricNameRaw = er.MetricNameRaw
+			prevFound = false
+			if s.getTSIDFromCache(&genTSID, er.MetricNameRaw) {
+				// Fast path - the series has been recently registered by AddRows.
+				prevMetricID = genTSID.TSID.MetricID
+				prevFound = true
+			} else {
+				// Slow path - search for the series in indexdb without creating it.
+				if err := mn.UnmarshalRaw(er.MetricNameRaw); err != nil {
+					if firstWarn == nil {
+						firstWarn = fmt.Errorf("cannot unmarshal MetricNameRaw %q: %w", er.MetricNameRaw, err)
+					}
+					atomic.AddUint64(&s.exemplarsIgnored, 1)
+					continue
+				}
+				mn.sortTags()
+				metricName = mn.Marshal(metricName[:0])
+				err := idb.getTSIDByNameNoCreate(&tsid, metricName)
+				if err == io.EOF {
+					idb.doExtDB(func(extDB *indexDB) {
+						err = extDB.getTSIDByNameNoCreate(&tsid, metricName)
+					})
+				}
+				if err != nil && err != io.EOF {
+					return fmt.Errorf("cannot search TSID for exemplar: %w", err)
+				}
+				if err == nil {
+					prevMetricID = tsid.MetricID
+					prevFound = true
+				}
+			}
+		}
+		if !prevFound {
+			atomic.AddUint64(&s.exemplarsIgnored, 1)
+			continue
+		}
+		tagsBuf = marshalExemplarTags(tagsBuf[:0], e.Tags)
+		if !es.add(prevMetricID, e.Timestamp, e.Value, tagsBuf) {
+			atomic.AddUint64(&s.exemplarsIgnored, 1)
+			continue
+		}
+		atomic.AddUint64(&s.exemplarsAdded, 1)
+	}
+	if firstWarn != nil {
+		logger.WithThrottler("storageAddExemplars", 5*time.Second).Warnf("warn occurred during exemplars addition: %s", firstWarn)
+	}
+	return nil
+}
+
+// SearchExemplars returns exemplars on the given tr for series matching the given tfss.
+func (s *Storage) SearchExemplars(qt *querytracer.Tracer, tfss []*TagFilters, tr TimeRange, maxMetrics int, deadline uint64) ([]SeriesExemplars, error) {
+	qt = qt.NewChild("search for exemplars: filters=%s, timeRange=%s", tfss, &tr)
+	defer qt.Done()
+	if s.exemplars.Len() == 0 {
+		return nil, nil
+	}
+	tsids, err := s.searchTSIDs(qt, tfss, tr, maxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+	if len(tsids) == 0 {
+		return nil, nil
+	}
+	var metricIDs uint64set.Set
+	for i := range tsids {
+		metricIDs.Add(tsids[i].MetricID)
+	}
+	minTimestamp := int64(fasttime.UnixTimestamp()*1000) - s.retentionMsecs
+
+	// Collect matching exemplars under the lock and unmarshal them afterwards
+	// in order to reduce the lock contention with concurrent AddExemplars calls.
+	type foundExemplar struct {
+		metricID  uint64
+		timestamp int64
+		value     float64
+		tags      []byte
+	}
+	var found []foundExemplar
+	var tagsBuf []byte
+	s.exemplars.forEach(func(e *exemplarEntry) {
+		if e.timestamp < tr.MinTimestamp || e.timestamp > tr.MaxTimestamp || e.timestamp < minTimestamp {
+			return
+		}
+		if !metricIDs.Has(e.metricID) {
+			return
+		}
+		tagsBufLen := len(tagsBuf)
+		tagsBuf = append(tagsBuf, e.tags...)
+		found = append(found, foundExemplar{
+			metricID:  e.metricID,
+			timestamp: e.timestamp,
+			value:     e.value,
+			tags:      tagsBuf[tagsBufLen:],
+		})
+	})
+	qt.Printf("found %d exemplars for %d series", len(found), len(tsids))
+	if len(found) == 0 {
+		return nil, nil
+	}
+
+	idb := s.idb()
+	var metricName []byte
+	var results []SeriesExemplars
+	resultIdx := make(map[uint64]int)
+	for i := range found {
+		if i&paceLimiterSlowIterationsMask == 0 {
+			if err := checkSearchDeadlineAndPace(deadline); err != nil {
+				return nil, err
+			}
+		}
+		fe := &found[i]
+		idx, ok := resultIdx[fe.metricID]
+		if !ok {
+			metricName, err = idb.searchMetricNameWithCache(metricName[:0], fe.metricID)
+			if err != nil {
+				if err == io.EOF {
+					// Skip missing metricName for metricID.
+					continue
+				}
+				return nil, fmt.Errorf("error when searching metricName for metricID=%d: %w", fe.metricID, err)
+			}
+			results = append(results, SeriesExemplars{})
+			idx = len(results) - 1
+			if err := results[idx].MetricName.Unmarshal(metricName); err != nil {
+				return nil, fmt.Errorf("cannot unmarshal metricName=%q: %w", metricName, err)
+			}
+			resultIdx[fe.metricID] = idx
+		}
+		tags, err := unmarshalExemplarTags(nil, fe.tags)
+		if err != nil {
+			return nil, fmt.Errorf("cannot unmarshal exemplar labels: %w", err)
+		}
+		results[idx].Exemplars = append(results[idx].Exemplars, Exemplar{
+			Tags:      tags,
+			Value:     fe.value,
+			Timestamp: fe.timestamp,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return string(results[i].MetricName.marshalRaw(nil)) < string(results[j].MetricName.marshalRaw(nil))
+	})
+	return results, nil
+}
+
+func (s *Storage) mustLoadExemplars() *exemplarStorage {
+	es := newExemplarStorage(maxExemplars)
+	name := "exemplars"
+	path := s.cachePath + "/" + name
+	logger.Infof("loading %s from %q...", name, path)
+	startTime := time.Now()
+	if !fs.IsPathExist(path) {
+		logger.Infof("nothing to load from %q", path)
+		return es
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read %s: %s", path, err)
+	}
+	srcOrigLen := len(src)
+	if len(src) < 8 {
+		logger.Errorf("discarding %s, since it has broken header; got %d bytes; want at least %d bytes", path, len(src), 8)
+		return es
+	}
+	n := encoding.UnmarshalUint64(src)
+	src = src[8:]
+	for i := uint64(0); i < n; i++ {
+		if len(src) < 24 {
+			logger.Errorf("discarding %s, since it has broken entry #%d; got %d bytes; want at least %d bytes", path, i, len(src), 24)
+			return newExemplarStorage(maxExemplars)
+		}
+		metricID := encoding.UnmarshalUint64(src)
+		timestamp := encoding.UnmarshalInt64(src[8:])
+		value := math.Float64frombits(encoding.UnmarshalUint64(src[16:]))
+		tail, tags, err := encoding.UnmarshalBytes(src[24:])
+		if err != nil {
+			logger.Errorf("discarding %s, since it has broken entry #%d: %s", path, i, err)
+			return newExemplarStorage(maxExemplars)
+		}
+		src = tail
+		es.add(metricID, timestamp, value, tags)
+	}
+	if len(src) > 0 {
+		logger.Errorf("discarding %s because non-empty tail left; len(tail)=%d", path, len(src))
+		return newExemplarStorage(maxExemplars)
+	}
+	logger.Infof("loaded %s from %q in %.3f seconds; entriesCount: %d; sizeBytes: %d", name, path, time.Since(startTime).Seconds(), es.Len(), srcOrigLen)
+	return es
+}
+
+func (s *Storage) mustSaveExemplars() {
+	name := "exemplars"
+	path := s.cachePath + "/" + name
+	logger.Infof("saving %s to %q...", name, path)
+	startTime := time.Now()
+	es := s.exemplars
+	dst := encoding.MarshalUint64(nil, uint64(es.Len()))
+	es.forEach(func(e *exemplarEntry) {
+		dst = encoding.MarshalUint64(dst, e.metricID)
+		dst = encoding.MarshalInt64(dst, e.timestamp)
+		dst = encoding.MarshalUint64(dst, math.Float64bits(e.value))
+		dst = encoding.MarshalBytes(dst, e.tags)
+	})
+	if err := ioutil.WriteFile(path, dst, 0644); err != nil {
+		logger.Panicf("FATAL: cannot write %d bytes to %q: %s", len(dst), path, err)
+	}
+	logger.Infof("saved %s to %q in %.3f seconds; entriesCount: %d; sizeBytes: %d", name, path, time.Since(startTime).Seconds(), es.Len(), len(dst))
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExemplarStorageAdd(t *testing.T) {
+	es := newExemplarStorage(3)
+	f := func(metricID uint64, timestamp int64, value float64, tags string, resultExpected bool) {
+		t.Helper()
+		result := es.add(metricID, timestamp, value, []byte(tags))
+		if result != resultExpected {
+			t.Fatalf("unexpected result for add(%d, %d, %v, %q); got %v; want %v", metricID, timestamp, value, tags, result, resultExpected)
+		}
+	}
+	f(1, 10, 1, "foo", true)
+	f(2, 10, 1, "foo", true)
+
+	// duplicate exemplar
+	f(1, 10, 1, "foo", false)
+
+	// out of order exemplar
+	f(1, 9, 2, "bar", false)
+
+	// the same timestamp with distinct value
+	f(1, 10, 2, "foo", true)
+
+	// the oldest exemplar must be evicted
+	f(3, 11, 1, "foo", true)
+	if n := es.Len(); n != 3 {
+		t.Fatalf("unexpected number of exemplars; got %d; want 3", n)
+	}
+	var metricIDs []uint64
+	es.forEach(func(e *exemplarEntry) {
+		metricIDs = append(metricIDs, e.metricID)
+	})
+	metricIDsExpected := []uint64{2, 1, 3}
+	if !reflect.DeepEqual(metricIDs, metricIDsExpected) {
+		t.Fatalf("unexpected metricIDs; got %v; want %v", metricIDs, metricIDsExpected)
+	}
+
+	// exemplars storage with zero capacity mustn't store anything
+	es = newExemplarStorage(0)
+	f(1, 10, 1, "foo", false)
+}
+
+func TestStorageAddSearchExemplars(t *testing.T) {
+	path := "TestStorageAddSearchExemplars"
+	s, err := OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	now := timestampFromTime(time.Now())
+	var mrs []MetricRow
+	var ers []ExemplarRow
+	for i := 0; i < 3; i++ {
+		var mn MetricName
+		mn.MetricGroup = []byte("http_requests_total")
+		mn.AddTag("instance", fmt.Sprintf("host-%d", i))
+		metricNameRaw := mn.marshalRaw(nil)
+		mrs = append(mrs, MetricRow{
+			MetricNameRaw: metricNameRaw,
+			Timestamp:     now,
+			Value:         float64(i),
+		})
+		ers = append(ers, ExemplarRow{
+			MetricNameRaw: metricNameRaw,
+			Exemplar: Exemplar{
+				Tags: []Tag{{
+					Key:   []byte("trace_id"),
+					Value: []byte(fmt.Sprintf("trace-%d", i)),
+				}},
+				Value:     float64(i) + 0.5,
+				Timestamp: now,
+			},
+		})
+	}
+	// An exemplar for unknown series must be ignored.
+	var mn MetricName
+	mn.MetricGroup = []byte("unknown_series")
+	ers = append(ers, ExemplarRow{
+		MetricNameRaw: mn.marshalRaw(nil),
+		Exemplar: Exemplar{
+			Value:     1,
+			Timestamp: now,
+		},
+	})
+	if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+		t.Fatalf("unexpected error in AddRows: %s", err)
+	}
+	if err := s.AddExemplars(ers); err != nil {
+		t.Fatalf("unexpected error in AddExemplars: %s", err)
+	}
+	s.DebugFlush()
+
+	checkExemplars := func(s *Storage) {
+		t.Helper()
+		tfs := NewTagFilters()
+		if err := tfs.Add(nil, []byte("http_requests_total"), false, false); err != nil {
+			t.Fatalf("cannot add tag filter: %s", err)
+		}
+		if err := tfs.Add([]byte("instance"), []byte("host-1"), false, false); err != nil {
+			t.Fatalf("cannot add tag filter: %s", err)
+		}
+		tr := TimeRange{
+			MinTimestamp: now - 3600*1000,
+			MaxTimestamp: now + 3600*1000,
+		}
+		ses, err := s.SearchExemplars(nil, []*TagFilters{tfs}, tr, 1e5, noDeadline)
+		if err != nil {
+			t.Fatalf("unexpected error in SearchExemplars: %s", err)
+		}
+		if len(ses) != 1 {
+			t.Fatalf("unexpected number of series found; got %d; want 1", len(ses))
+		}
+		se := &ses[0]
+		if string(se.MetricName.GetTagValue("instance")) != "host-1" {
+			t.Fatalf("unexpected series found: %s", se.MetricName.String())
+		}
+		exemplarsExpected := []Exemplar{{
+			Tags: []Tag{{
+				Key:   []byte("trace_id"),
+				Value: []byte("trace-1"),
+			}},
+			Value:     1.5,
+			Timestamp: now,
+		}}
+		if !reflect.DeepEqual(se.Exemplars, exemplarsExpected) {
+			t.Fatalf("unexpected exemplars\ngot\n%+v\nwant\n%+v", se.Exemplars, exemplarsExpected)
+		}
+	}
+	checkExemplars(s)
+
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if m.ExemplarsAdded != 3 {
+		t.Fatalf("unexpected ExemplarsAdded; got %d; want 3", m.ExemplarsAdded)
+	}
+	if m.ExemplarsIgnored != 1 {
+		t.Fatalf("unexpected ExemplarsIgnored; got %d; want 1", m.ExemplarsIgnored)
+	}
+	s.MustClose()
+
+	// Verify exemplars are persisted across restarts.
+	s, err = OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot re-open storage: %s", err)
+	}
+	checkExemplars(s)
+	s.MustClose()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
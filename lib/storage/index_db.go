@@ -1591,6 +1591,32 @@ func (db *indexDB) DeleteTSIDs(tfss []*TagFilters) (int, error) {
 	return deletedCount, nil
 }
 
+// searchAllMetricIDs returns metricIDs for all the series matching tfss in db and in extDB.
+func (db *indexDB) searchAllMetricIDs(tfss []*TagFilters) (*uint64set.Set, error) {
+	tr := TimeRange{
+		MinTimestamp: 0,
+		MaxTimestamp: (1 << 63) - 1,
+	}
+	is := db.getIndexSearch(noDeadline)
+	metricIDs, err := is.searchMetricIDsInternal(nil, tfss, tr, 2e9)
+	db.putIndexSearch(is)
+	if err != nil {
+		return nil, err
+	}
+	if db.doExtDB(func(extDB *indexDB) {
+		var extMetricIDs *uint64set.Set
+		extMetricIDs, err = extDB.searchAllMetricIDs(tfss)
+		if err == nil {
+			metricIDs.UnionMayOwn(extMetricIDs)
+		}
+	}) {
+		if err != nil {
+			return nil, fmt.Errorf("cannot search metricIDs in extDB: %w", err)
+		}
+	}
+	return metricIDs, nil
+}
+
 func (db *indexDB) deleteMetricIDs(metricIDs []uint64) error {
 	if len(metricIDs) == 0 {
 		// Nothing to delete
@@ -15,13 +15,16 @@ import (
 // mergeBlockStreams returns immediately if stopCh is closed.
 //
 // rowsMerged is atomically updated with the number of merged rows during the merge.
+//
+// rfds contain retention deadlines for series matching retention filters.
+// retentionDeadline is used for the rest of series.
 func mergeBlockStreams(ph *partHeader, bsw *blockStreamWriter, bsrs []*blockStreamReader, stopCh <-chan struct{},
-	dmis *uint64set.Set, retentionDeadline int64, rowsMerged, rowsDeleted *uint64) error {
+	dmis *uint64set.Set, retentionDeadline int64, rfds []retentionFilterDeadline, rowsMerged, rowsDeleted *uint64) error {
 	ph.Reset()
 
 	bsm := bsmPool.Get().(*blockStreamMerger)
 	bsm.Init(bsrs)
-	err := mergeBlockStreamsInternal(ph, bsw, bsm, stopCh, dmis, retentionDeadline, rowsMerged, rowsDeleted)
+	err := mergeBlockStreamsInternal(ph, bsw, bsm, stopCh, dmis, retentionDeadline, rfds, rowsMerged, rowsDeleted)
 	bsm.reset()
 	bsmPool.Put(bsm)
 	bsw.MustClose()
@@ -40,7 +43,7 @@ var bsmPool = &sync.Pool{
 var errForciblyStopped = fmt.Errorf("forcibly stopped")
 
 func mergeBlockStreamsInternal(ph *partHeader, bsw *blockStreamWriter, bsm *blockStreamMerger, stopCh <-chan struct{},
-	dmis *uint64set.Set, retentionDeadline int64, rowsMerged, rowsDeleted *uint64) error {
+	dmis *uint64set.Set, retentionDeadline int64, rfds []retentionFilterDeadline, rowsMerged, rowsDeleted *uint64) error {
 	pendingBlockIsEmpty := true
 	pendingBlock := getBlock()
 	defer putBlock(pendingBlock)
@@ -57,9 +60,10 @@ func mergeBlockStreamsInternal(ph *partHeader, bsw *blockStreamWriter, bsm *bloc
 			atomic.AddUint64(rowsDeleted, uint64(bsm.Block.bh.RowsCount))
 			continue
 		}
-		if bsm.Block.bh.MaxTimestamp < retentionDeadline {
+		deadline, rowsDeletedByFilter := getRetentionDeadline(rfds, bsm.Block.bh.TSID.MetricID, retentionDeadline)
+		if bsm.Block.bh.MaxTimestamp < deadline {
 			// Skip blocks out of the given retention.
-			atomic.AddUint64(rowsDeleted, uint64(bsm.Block.bh.RowsCount))
+			addRowsDeleted(rowsDeleted, rowsDeletedByFilter, uint64(bsm.Block.bh.RowsCount))
 			continue
 		}
 		if pendingBlockIsEmpty {
@@ -97,7 +101,7 @@ func mergeBlockStreamsInternal(ph *partHeader, bsw *blockStreamWriter, bsm *bloc
 		tmpBlock.bh.TSID = bsm.Block.bh.TSID
 		tmpBlock.bh.Scale = bsm.Block.bh.Scale
 		tmpBlock.bh.PrecisionBits = minUint8(pendingBlock.bh.PrecisionBits, bsm.Block.bh.PrecisionBits)
-		mergeBlocks(tmpBlock, pendingBlock, bsm.Block, retentionDeadline, rowsDeleted)
+		mergeBlocks(tmpBlock, pendingBlock, bsm.Block, deadline, rowsDeleted, rowsDeletedByFilter)
 		if len(tmpBlock.timestamps) <= maxRowsPerBlock {
 			// More entries may be added to tmpBlock. Swap it with pendingBlock,
 			// so more entries may be added to pendingBlock on the next iteration.
@@ -131,13 +135,15 @@ func mergeBlockStreamsInternal(ph *partHeader, bsw *blockStreamWriter, bsm *bloc
 }
 
 // mergeBlocks merges ib1 and ib2 to ob.
-func mergeBlocks(ob, ib1, ib2 *Block, retentionDeadline int64, rowsDeleted *uint64) {
+//
+// rowsDeletedByFilter may be nil if ib1 and ib2 don't match retention filters.
+func mergeBlocks(ob, ib1, ib2 *Block, retentionDeadline int64, rowsDeleted, rowsDeletedByFilter *uint64) {
 	ib1.assertMergeable(ib2)
 	ib1.assertUnmarshaled()
 	ib2.assertUnmarshaled()
 
-	skipSamplesOutsideRetention(ib1, retentionDeadline, rowsDeleted)
-	skipSamplesOutsideRetention(ib2, retentionDeadline, rowsDeleted)
+	skipSamplesOutsideRetention(ib1, retentionDeadline, rowsDeleted, rowsDeletedByFilter)
+	skipSamplesOutsideRetention(ib2, retentionDeadline, rowsDeleted, rowsDeletedByFilter)
 
 	if ib1.bh.MaxTimestamp < ib2.bh.MinTimestamp {
 		// Fast path - ib1 values have smaller timestamps than ib2 values.
@@ -176,7 +182,7 @@ func mergeBlocks(ob, ib1, ib2 *Block, retentionDeadline int64, rowsDeleted *uint
 	}
 }
 
-func skipSamplesOutsideRetention(b *Block, retentionDeadline int64, rowsDeleted *uint64) {
+func skipSamplesOutsideRetention(b *Block, retentionDeadline int64, rowsDeleted, rowsDeletedByFilter *uint64) {
 	timestamps := b.timestamps
 	nextIdx := b.nextIdx
 	nextIdxOrig := nextIdx
@@ -184,11 +190,18 @@ func skipSamplesOutsideRetention(b *Block, retentionDeadline int64, rowsDeleted
 		nextIdx++
 	}
 	if n := nextIdx - nextIdxOrig; n > 0 {
-		atomic.AddUint64(rowsDeleted, uint64(n))
+		addRowsDeleted(rowsDeleted, rowsDeletedByFilter, uint64(n))
 		b.nextIdx = nextIdx
 	}
 }
 
+func addRowsDeleted(rowsDeleted, rowsDeletedByFilter *uint64, n uint64) {
+	atomic.AddUint64(rowsDeleted, n)
+	if rowsDeletedByFilter != nil {
+		atomic.AddUint64(rowsDeletedByFilter, n)
+	}
+}
+
 func appendRows(ob, ib *Block) {
 	ob.timestamps = append(ob.timestamps, ib.timestamps[ib.nextIdx:]...)
 	ob.values = append(ob.values, ib.values[ib.nextIdx:]...)
@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// maxMetricMetadataPerMetric is the maximum number of distinct metadata entries to keep per each metric family name.
+//
+// Distinct scrape targets may expose distinct help strings for the same metric family.
+const maxMetricMetadataPerMetric = 10
+
+var maxMetricMetadataMetrics = 100000
+
+// SetMaxMetricMetadataMetrics sets the maximum number of metric family names to keep metadata for.
+//
+// This function must be called before OpenStorage.
+func SetMaxMetricMetadataMetrics(n int) {
+	maxMetricMetadataMetrics = n
+}
+
+// MetricMetadata contains metadata for the metric family.
+//
+// See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+type MetricMetadata struct {
+	MetricFamilyName string
+
+	// Type is the metric family type such as counter, gauge, histogram, summary, etc.
+	Type string
+
+	Help string
+	Unit string
+}
+
+type metricMetadataEntry struct {
+	typ  string
+	help string
+	unit string
+
+	// lastSeen is unix timestamp in seconds when the entry has been added last time.
+	lastSeen uint64
+}
+
+// metricMetadataStorage holds metric metadata per each metric family name.
+type metricMetadataStorage struct {
+	mu sync.Mutex
+	m  map[string][]metricMetadataEntry
+}
+
+func newMetricMetadataStorage() *metricMetadataStorage {
+	return &metricMetadataStorage{
+		m: make(map[string][]metricMetadataEntry),
+	}
+}
+
+func (mms *metricMetadataStorage) Len() int {
+	mms.mu.Lock()
+	n := len(mms.m)
+	mms.mu.Unlock()
+	return n
+}
+
+// add adds mm with the given lastSeen timestamp to mms.
+//
+// It returns false if mm cannot be added because of the limit on the number of metric family names.
+func (mms *metricMetadataStorage) add(mm *MetricMetadata, lastSeen uint64) bool {
+	mms.mu.Lock()
+	defer mms.mu.Unlock()
+
+	entries := mms.m[mm.MetricFamilyName]
+	if entries == nil && len(mms.m) >= maxMetricMetadataMetrics {
+		return false
+	}
+	for i := range entries {
+		e := &entries[i]
+		if e.typ == mm.Type && e.help == mm.Help && e.unit == mm.Unit {
+			if lastSeen > e.lastSeen {
+				e.lastSeen = lastSeen
+			}
+			return true
+		}
+	}
+	e := metricMetadataEntry{
+		// Make copies of strings, since mm may refer to byte slices, which can be modified by the caller.
+		typ:      string(append([]byte{}, mm.Type...)),
+		help:     string(append([]byte{}, mm.Help...)),
+		unit:     string(append([]byte{}, mm.Unit...)),
+		lastSeen: lastSeen,
+	}
+	if len(entries) < maxMetricMetadataPerMetric {
+		entries = append(entries, e)
+	} else {
+		// Replace the oldest entry.
+		oldestIdx := 0
+		for i := range entries {
+			if entries[i].lastSeen < entries[oldestIdx].lastSeen {
+				oldestIdx = i
+			}
+		}
+		entries[oldestIdx] = e
+	}
+	mms.m[string(append([]byte{}, mm.MetricFamilyName...))] = entries
+	return true
+}
+
+// removeStaleEntries removes entries with lastSeen smaller than minLastSeen.
+func (mms *metricMetadataStorage) removeStaleEntries(minLastSeen uint64) {
+	mms.mu.Lock()
+	defer mms.mu.Unlock()
+
+	for name, entries := range mms.m {
+		dst := entries[:0]
+		for _, e := range entries {
+			if e.lastSeen >= minLastSeen {
+				dst = append(dst, e)
+			}
+		}
+		if len(dst) == 0 {
+			delete(mms.m, name)
+		} else {
+			mms.m[name] = dst
+		}
+	}
+}
+
+// search returns metadata for the given metric family name seen after minLastSeen.
+//
+// Metadata for all the metric families is returned if metric is empty.
+// Up to limit metric families are returned if limit > 0.
+func (mms *metricMetadataStorage) search(metric string, limit int, minLastSeen uint64) []MetricMetadata {
+	mms.mu.Lock()
+	defer mms.mu.Unlock()
+
+	var names []string
+	if metric != "" {
+		if _, ok := mms.m[metric]; ok {
+			names = append(names, metric)
+		}
+	} else {
+		names = make([]string, 0, len(mms.m))
+		for name := range mms.m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	var result []MetricMetadata
+	metricsCount := 0
+	for _, name := range names {
+		if limit > 0 && metricsCount >= limit {
+			break
+		}
+		resultLen := len(result)
+		for _, e := range mms.m[name] {
+			if e.lastSeen < minLastSeen {
+				continue
+			}
+			result = append(result, MetricMetadata{
+				MetricFamilyName: name,
+				Type:             e.typ,
+				Help:             e.help,
+				Unit:             e.unit,
+			})
+		}
+		if len(result) > resultLen {
+			metricsCount++
+		}
+	}
+	return result
+}
+
+// AddMetricMetadata adds the given metric metadata to s.
+func (s *Storage) AddMetricMetadata(mms []MetricMetadata) error {
+	currentTime := fasttime.UnixTimestamp()
+	for i := range mms {
+		mm := &mms[i]
+		if len(mm.MetricFamilyName) == 0 {
+			continue
+		}
+		if !s.metricMetadata.add(mm, currentTime) {
+			atomic.AddUint64(&s.metricMetadataDropped, 1)
+		}
+	}
+	return nil
+}
+
+// SearchMetricMetadata returns metadata for the given metric family name.
+//
+// Metadata for all the metric families is returned if metric is empty.
+// Up to limit metric families are returned if limit > 0.
+// Metadata, which wasn't updated during the retention period, isn't returned.
+func (s *Storage) SearchMetricMetadata(metric string, limit int) []MetricMetadata {
+	return s.metricMetadata.search(metric, limit, s.getMinMetricMetadataLastSeen())
+}
+
+func (s *Storage) getMinMetricMetadataLastSeen() uint64 {
+	retentionSecs := uint64(s.retentionMsecs / 1000)
+	currentTime := fasttime.UnixTimestamp()
+	if currentTime < retentionSecs {
+		return 0
+	}
+	return currentTime - retentionSecs
+}
+
+func (s *Storage) mustLoadMetricMetadata(metadataDir string) *metricMetadataStorage {
+	mms := newMetricMetadataStorage()
+	name := "metricMetadata"
+	path := metadataDir + "/" + name
+	logger.Infof("loading %s from %q...", name, path)
+	startTime := time.Now()
+	if !fs.IsPathExist(path) {
+		logger.Infof("nothing to load from %q", path)
+		return mms
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read %s: %s", path, err)
+	}
+	srcOrigLen := len(src)
+	if err := mms.unmarshal(src); err != nil {
+		logger.Errorf("discarding %s: %s", path, err)
+		return newMetricMetadataStorage()
+	}
+	logger.Infof("loaded %s from %q in %.3f seconds; metricsCount: %d; sizeBytes: %d", name, path, time.Since(startTime).Seconds(), mms.Len(), srcOrigLen)
+	return mms
+}
+
+func (s *Storage) mustSaveMetricMetadata(metadataDir string) {
+	name := "metricMetadata"
+	path := metadataDir + "/" + name
+	logger.Infof("saving %s to %q...", name, path)
+	startTime := time.Now()
+	mms := s.metricMetadata
+	mms.removeStaleEntries(s.getMinMetricMetadataLastSeen())
+	dst := mms.marshal(nil)
+	if err := ioutil.WriteFile(path, dst, 0644); err != nil {
+		logger.Panicf("FATAL: cannot write %d bytes to %q: %s", len(dst), path, err)
+	}
+	logger.Infof("saved %s to %q in %.3f seconds; metricsCount: %d; sizeBytes: %d", name, path, time.Since(startTime).Seconds(), mms.Len(), len(dst))
+}
+
+func (mms *metricMetadataStorage) marshal(dst []byte) []byte {
+	mms.mu.Lock()
+	defer mms.mu.Unlock()
+
+	dst = encoding.MarshalVarUint64(dst, uint64(len(mms.m)))
+	for name, entries := range mms.m {
+		dst = encoding.MarshalBytes(dst, []byte(name))
+		dst = encoding.MarshalVarUint64(dst, uint64(len(entries)))
+		for _, e := range entries {
+			dst = encoding.MarshalBytes(dst, []byte(e.typ))
+			dst = encoding.MarshalBytes(dst, []byte(e.help))
+			dst = encoding.MarshalBytes(dst, []byte(e.unit))
+			dst = encoding.MarshalUint64(dst, e.lastSeen)
+		}
+	}
+	return dst
+}
+
+func (mms *metricMetadataStorage) unmarshal(src []byte) error {
+	tail, metricsCount, err := encoding.UnmarshalVarUint64(src)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal metrics count: %w", err)
+	}
+	src = tail
+	var mm MetricMetadata
+	for i := uint64(0); i < metricsCount; i++ {
+		tail, name, err := encoding.UnmarshalBytes(src)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal metric name: %w", err)
+		}
+		src = tail
+		tail, entriesCount, err := encoding.UnmarshalVarUint64(src)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal entries count for metric %q: %w", name, err)
+		}
+		src = tail
+		mm.MetricFamilyName = string(name)
+		for j := uint64(0); j < entriesCount; j++ {
+			var typ, help, unit []byte
+			if src, typ, err = encoding.UnmarshalBytes(src); err != nil {
+				return fmt.Errorf("cannot unmarshal type for metric %q: %w", name, err)
+			}
+			if src, help, err = encoding.UnmarshalBytes(src); err != nil {
+				return fmt.Errorf("cannot unmarshal help for metric %q: %w", name, err)
+			}
+			if src, unit, err = encoding.UnmarshalBytes(src); err != nil {
+				return fmt.Errorf("cannot unmarshal unit for metric %q: %w", name, err)
+			}
+			if len(src) < 8 {
+				return fmt.Errorf("cannot unmarshal lastSeen for metric %q; got %d bytes; want at least 8 bytes", name, len(src))
+			}
+			lastSeen := encoding.UnmarshalUint64(src)
+			src = src[8:]
+			mm.Type = string(typ)
+			mm.Help = string(help)
+			mm.Unit = string(unit)
+			mms.add(&mm, lastSeen)
+		}
+	}
+	if len(src) > 0 {
+		return fmt.Errorf("unexpected non-empty tail left; len(tail)=%d", len(src))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMetricMetadataStorageAddSearch(t *testing.T) {
+	mms := newMetricMetadataStorage()
+	add := func(name, typ, help string, lastSeen uint64) {
+		t.Helper()
+		mm := &MetricMetadata{
+			MetricFamilyName: name,
+			Type:             typ,
+			Help:             help,
+		}
+		if !mms.add(mm, lastSeen) {
+			t.Fatalf("cannot add metadata for %q", name)
+		}
+	}
+	add("foo", "counter", "foo help", 10)
+	add("bar", "gauge", "bar help", 10)
+
+	// duplicate entry must update lastSeen
+	add("foo", "counter", "foo help", 20)
+
+	// distinct help for the same metric
+	add("foo", "counter", "another foo help", 15)
+
+	f := func(metric string, limit int, minLastSeen uint64, resultExpected []MetricMetadata) {
+		t.Helper()
+		result := mms.search(metric, limit, minLastSeen)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result for search(%q, %d, %d)\ngot\n%v\nwant\n%v", metric, limit, minLastSeen, result, resultExpected)
+		}
+	}
+	fooEntries := []MetricMetadata{
+		{MetricFamilyName: "foo", Type: "counter", Help: "foo help"},
+		{MetricFamilyName: "foo", Type: "counter", Help: "another foo help"},
+	}
+	barEntries := []MetricMetadata{
+		{MetricFamilyName: "bar", Type: "gauge", Help: "bar help"},
+	}
+	f("", 0, 0, append(append([]MetricMetadata{}, barEntries...), fooEntries...))
+	f("foo", 0, 0, fooEntries)
+	f("", 1, 0, barEntries)
+	f("missing", 0, 0, nil)
+
+	// stale entries mustn't be returned
+	f("", 0, 16, fooEntries[:1])
+
+	// the limit on the number of entries per metric
+	for i := 0; i < 2*maxMetricMetadataPerMetric; i++ {
+		add("baz", "gauge", string(rune('a'+i)), uint64(100+i))
+	}
+	result := mms.search("baz", 0, 0)
+	if len(result) != maxMetricMetadataPerMetric {
+		t.Fatalf("unexpected number of entries for baz; got %d; want %d", len(result), maxMetricMetadataPerMetric)
+	}
+	for _, mm := range result {
+		if mm.Help < string(rune('a'+maxMetricMetadataPerMetric)) {
+			t.Fatalf("the oldest entry %q must be evicted", mm.Help)
+		}
+	}
+
+	// marshal/unmarshal
+	data := mms.marshal(nil)
+	mms2 := newMetricMetadataStorage()
+	if err := mms2.unmarshal(data); err != nil {
+		t.Fatalf("cannot unmarshal metric metadata: %s", err)
+	}
+	if !reflect.DeepEqual(mms2.m, mms.m) {
+		t.Fatalf("unexpected unmarshaled metric metadata\ngot\n%v\nwant\n%v", mms2.m, mms.m)
+	}
+
+	// stale entries removal
+	mms.removeStaleEntries(16)
+	if n := mms.Len(); n != 2 {
+		t.Fatalf("unexpected number of metrics after removing stale entries; got %d; want 2", n)
+	}
+}
+
+func TestStorageAddSearchMetricMetadata(t *testing.T) {
+	path := "TestStorageAddSearchMetricMetadata"
+	s, err := OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	mms := []MetricMetadata{
+		{MetricFamilyName: "foo", Type: "counter", Help: "foo help", Unit: "seconds"},
+		{MetricFamilyName: "", Type: "gauge"},
+	}
+	if err := s.AddMetricMetadata(mms); err != nil {
+		t.Fatalf("cannot add metric metadata: %s", err)
+	}
+	resultExpected := mms[:1]
+	result := s.SearchMetricMetadata("", 0)
+	if !reflect.DeepEqual(result, resultExpected) {
+		t.Fatalf("unexpected metric metadata\ngot\n%v\nwant\n%v", result, resultExpected)
+	}
+
+	// Metric metadata must survive storage restart.
+	s.MustClose()
+	s, err = OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot re-open storage: %s", err)
+	}
+	result = s.SearchMetricMetadata("foo", 0)
+	if !reflect.DeepEqual(result, resultExpected) {
+		t.Fatalf("unexpected metric metadata after restart\ngot\n%v\nwant\n%v", result, resultExpected)
+	}
+	s.MustClose()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
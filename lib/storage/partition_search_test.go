@@ -169,7 +169,7 @@ func testPartitionSearchEx(t *testing.T, ptt int64, tr TimeRange, partsCount, ma
 	// Create partition from rowss and test search on it.
 	retentionMsecs := timestampFromTime(time.Now()) - ptr.MinTimestamp + 3600*1000
 	var isReadOnly uint32
-	pt, err := createPartition(ptt, "./small-table", "./big-table", nilGetDeletedMetricIDs, retentionMsecs, &isReadOnly)
+	pt, err := createPartition(ptt, "./small-table", "./big-table", nilGetDeletedMetricIDs, nilGetRetentionFilters, retentionMsecs, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot create partition: %s", err)
 	}
@@ -193,7 +193,7 @@ func testPartitionSearchEx(t *testing.T, ptt int64, tr TimeRange, partsCount, ma
 	pt.MustClose()
 
 	// Open the created partition and test search on it.
-	pt, err = openPartition(smallPartsPath, bigPartsPath, nilGetDeletedMetricIDs, retentionMsecs, &isReadOnly)
+	pt, err = openPartition(smallPartsPath, bigPartsPath, nilGetDeletedMetricIDs, nilGetRetentionFilters, retentionMsecs, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open partition: %s", err)
 	}
@@ -282,3 +282,7 @@ func testPartitionSearchSerial(pt *partition, tsids []TSID, tr TimeRange, rbsExp
 func nilGetDeletedMetricIDs() *uint64set.Set {
 	return nil
 }
+
+func nilGetRetentionFilters() []*retentionFilter {
+	return nil
+}
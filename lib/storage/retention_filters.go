@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/uint64set"
+)
+
+// RetentionFilter applies RetentionMsecs to series matching TagFilters instead of the default retention.
+type RetentionFilter struct {
+	// Filter is a human-readable representation of TagFilters. It is used in logs and metrics.
+	Filter string
+
+	// TagFilters must match series for applying RetentionMsecs to them.
+	TagFilters *TagFilters
+
+	// RetentionMsecs is the retention in milliseconds for series matching TagFilters.
+	//
+	// It cannot exceed the retention passed to OpenStorage.
+	RetentionMsecs int64
+}
+
+// RetentionFilterMetrics contains metrics for RetentionFilter.
+type RetentionFilterMetrics struct {
+	// Filter is RetentionFilter.Filter.
+	Filter string
+
+	// SeriesCount is the number of series matching the filter.
+	SeriesCount uint64
+
+	// RowsDeleted is the number of rows deleted during background merges because of the filter.
+	RowsDeleted uint64
+}
+
+// retentionFilter holds the runtime state for RetentionFilter.
+type retentionFilter struct {
+	RetentionFilter
+
+	// metricIDs contains *uint64set.Set with metricIDs for series matching TagFilters.
+	//
+	// It is periodically updated by Storage.retentionFiltersWatcher.
+	metricIDs atomic.Value
+
+	// rowsDeleted is the number of rows deleted during background merges because of the filter.
+	rowsDeleted uint64
+}
+
+func (rf *retentionFilter) getMetricIDs() *uint64set.Set {
+	return rf.metricIDs.Load().(*uint64set.Set)
+}
+
+// SetRetentionFilters sets retention filters for s.
+//
+// The first matching filter is applied to every series. The retention passed to OpenStorage
+// is applied to series, which do not match any filter.
+//
+// Data outside the filters' retention is deleted during background merges.
+// Partitions containing such data are periodically force-merged.
+func (s *Storage) SetRetentionFilters(rfs []RetentionFilter) error {
+	a := make([]*retentionFilter, 0, len(rfs))
+	for i := range rfs {
+		rf := &rfs[i]
+		if rf.TagFilters == nil {
+			return fmt.Errorf("missing tag filters for retention filter %q", rf.Filter)
+		}
+		if rf.RetentionMsecs <= 0 {
+			return fmt.Errorf("retention for retention filter %q must be positive; got %d ms", rf.Filter, rf.RetentionMsecs)
+		}
+		if rf.RetentionMsecs > s.retentionMsecs {
+			return fmt.Errorf("retention for retention filter %q cannot exceed the storage retention; got %d ms; want up to %d ms",
+				rf.Filter, rf.RetentionMsecs, s.retentionMsecs)
+		}
+		rfState := &retentionFilter{
+			RetentionFilter: *rf,
+		}
+		if err := s.updateRetentionFilterMetricIDs(rfState); err != nil {
+			return err
+		}
+		a = append(a, rfState)
+	}
+	s.retentionFilters.Store(a)
+	return nil
+}
+
+func (s *Storage) getRetentionFilters() []*retentionFilter {
+	return s.retentionFilters.Load().([]*retentionFilter)
+}
+
+func (s *Storage) updateRetentionFilterMetricIDs(rf *retentionFilter) error {
+	metricIDs, err := s.idb().searchAllMetricIDs([]*TagFilters{rf.TagFilters})
+	if err != nil {
+		return fmt.Errorf("cannot search series for retention filter %q: %w", rf.Filter, err)
+	}
+	rf.metricIDs.Store(metricIDs)
+	return nil
+}
+
+func (s *Storage) startRetentionFiltersWatcher() {
+	s.retentionFiltersWatcherWG.Add(1)
+	go func() {
+		s.retentionFiltersWatcher()
+		s.retentionFiltersWatcherWG.Done()
+	}()
+}
+
+var retentionFiltersUpdateInterval = time.Hour
+
+// retentionFiltersWatcher periodically updates metricIDs for retention filters
+// and force-merges partitions with data outside the retention filters.
+func (s *Storage) retentionFiltersWatcher() {
+	// lastMerges contains the last force merge timestamp in milliseconds per each partition name.
+	lastMerges := make(map[string]int64)
+	ticker := time.NewTicker(retentionFiltersUpdateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		rfs := s.getRetentionFilters()
+		if len(rfs) == 0 {
+			continue
+		}
+		for _, rf := range rfs {
+			if err := s.updateRetentionFilterMetricIDs(rf); err != nil {
+				logger.Errorf("%s", err)
+			}
+		}
+		s.mergePartitionsForRetentionFilters(rfs, lastMerges)
+	}
+}
+
+func (s *Storage) mergePartitionsForRetentionFilters(rfs []*retentionFilter, lastMerges map[string]int64) {
+	ptws := s.tb.GetPartitions(nil)
+	defer s.tb.PutPartitions(ptws)
+
+	now := timestampFromTime(time.Now())
+	ptNames := make(map[string]bool, len(ptws))
+	for _, ptw := range ptws {
+		pt := ptw.pt
+		ptNames[pt.name] = true
+		// The last merge time isn't persisted, so it is unknown for partitions seen for the first time after the start.
+		// Zero lastMerge means that all the partitions with data outside retention filters must be force-merged,
+		// since retention filters could be added or their deadlines could pass while the storage was stopped.
+		lastMerge := lastMerges[pt.name]
+		if now-lastMerge < msecPerDay {
+			continue
+		}
+		if !needRetentionFiltersMerge(&pt.tr, rfs, lastMerge, now) {
+			continue
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		startTime := time.Now()
+		logger.Infof("starting force merge for partition %s in order to apply retention filters", pt.name)
+		if err := pt.ForceMergeAllParts(); err != nil {
+			logger.Errorf("cannot apply retention filters to partition %s: %s", pt.name, err)
+			continue
+		}
+		logger.Infof("retention filters have been applied to partition %s in %.3f seconds", pt.name, time.Since(startTime).Seconds())
+		lastMerges[pt.name] = now
+	}
+	for ptName := range lastMerges {
+		if !ptNames[ptName] {
+			delete(lastMerges, ptName)
+		}
+	}
+}
+
+// needRetentionFiltersMerge returns true if some of rfs started deleting data from the partition with the given tr
+// after the lastMerge timestamp.
+//
+// Zero lastMerge means the partition wasn't merged yet, so it must be merged if it contains data outside rfs.
+func needRetentionFiltersMerge(tr *TimeRange, rfs []*retentionFilter, lastMerge, currentTimestamp int64) bool {
+	for _, rf := range rfs {
+		if rf.getMetricIDs().Len() == 0 {
+			continue
+		}
+		deadline := currentTimestamp - rf.RetentionMsecs
+		prevDeadline := lastMerge - rf.RetentionMsecs
+		if tr.MinTimestamp < deadline && prevDeadline <= tr.MaxTimestamp {
+			return true
+		}
+	}
+	return false
+}
+
+// retentionFilterDeadline is the retention deadline for series matching the retention filter.
+type retentionFilterDeadline struct {
+	metricIDs   *uint64set.Set
+	deadline    int64
+	rowsDeleted *uint64
+}
+
+func getRetentionFilterDeadlines(rfs []*retentionFilter, currentTimestamp int64) []retentionFilterDeadline {
+	if len(rfs) == 0 {
+		return nil
+	}
+	rfds := make([]retentionFilterDeadline, len(rfs))
+	for i, rf := range rfs {
+		rfds[i] = retentionFilterDeadline{
+			metricIDs:   rf.getMetricIDs(),
+			deadline:    currentTimestamp - rf.RetentionMsecs,
+			rowsDeleted: &rf.rowsDeleted,
+		}
+	}
+	return rfds
+}
+
+// getRetentionDeadline returns the retention deadline for the given metricID according to rfds.
+//
+// It returns defaultDeadline if metricID doesn't match rfds. The returned counter must be updated
+// with the number of rows deleted because of the returned deadline. It is nil for defaultDeadline.
+func getRetentionDeadline(rfds []retentionFilterDeadline, metricID uint64, defaultDeadline int64) (int64, *uint64) {
+	for i := range rfds {
+		rfd := &rfds[i]
+		if rfd.metricIDs.Has(metricID) {
+			return rfd.deadline, rfd.rowsDeleted
+		}
+	}
+	return defaultDeadline, nil
+}
+
+func (s *Storage) updateRetentionFiltersMetrics(m *Metrics) {
+	for _, rf := range s.getRetentionFilters() {
+		m.RetentionFilters = append(m.RetentionFilters, RetentionFilterMetrics{
+			Filter:      rf.Filter,
+			SeriesCount: uint64(rf.getMetricIDs().Len()),
+			RowsDeleted: atomic.LoadUint64(&rf.rowsDeleted),
+		})
+	}
+}
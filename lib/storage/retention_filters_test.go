@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/uint64set"
+)
+
+func TestMergeBlockStreamsWithRetentionFilters(t *testing.T) {
+	// Spread rows for two series among two streams, so both full blocks skipping
+	// and per-sample skipping during blocks' merge are exercised.
+	tsids := make([]TSID, 2)
+	for i := range tsids {
+		initTestTSID(&tsids[i])
+		tsids[i].MetricID = uint64(i + 1)
+	}
+	var bsrs []*blockStreamReader
+	for i := 0; i < 2; i++ {
+		var rows []rawRow
+		for _, tsid := range tsids {
+			var r rawRow
+			r.TSID = tsid
+			r.PrecisionBits = defaultPrecisionBits
+			for ts := i; ts < 1000; ts += 2 {
+				r.Timestamp = int64(ts)
+				r.Value = float64(ts)
+				rows = append(rows, r)
+			}
+		}
+		bsrs = append(bsrs, newTestBlockStreamReader(t, rows))
+	}
+	// The block with metricID=3 must be dropped entirely by the retention filter.
+	var r rawRow
+	initTestTSID(&r.TSID)
+	r.TSID.MetricID = 3
+	r.PrecisionBits = defaultPrecisionBits
+	r.Timestamp = 200
+	bsrs = append(bsrs, newTestBlockStreamReader(t, []rawRow{r}))
+
+	var metricIDs uint64set.Set
+	metricIDs.Add(1)
+	metricIDs.Add(3)
+	var rowsDeletedByFilter uint64
+	rfds := []retentionFilterDeadline{{
+		metricIDs:   &metricIDs,
+		deadline:    500,
+		rowsDeleted: &rowsDeletedByFilter,
+	}}
+
+	var mp inmemoryPart
+	var bsw blockStreamWriter
+	bsw.InitFromInmemoryPart(&mp)
+	var rowsMerged, rowsDeleted uint64
+	if err := mergeBlockStreams(&mp.ph, &bsw, bsrs, nil, nil, 100, rfds, &rowsMerged, &rowsDeleted); err != nil {
+		t.Fatalf("unexpected error in mergeBlockStreams: %s", err)
+	}
+	if rowsDeletedByFilter != 501 {
+		t.Fatalf("unexpected rows deleted by filter; got %d; want %d", rowsDeletedByFilter, 501)
+	}
+	if rowsDeleted != 601 {
+		t.Fatalf("unexpected rowsDeleted; got %d; want %d", rowsDeleted, 601)
+	}
+	if mp.ph.RowsCount != 1400 {
+		t.Fatalf("unexpected rows count; got %d; want %d", mp.ph.RowsCount, 1400)
+	}
+	if rowsMerged != mp.ph.RowsCount {
+		t.Fatalf("unexpected rowsMerged; got %d; want %d", rowsMerged, mp.ph.RowsCount)
+	}
+	if mp.ph.MinTimestamp != 100 {
+		t.Fatalf("unexpected MinTimestamp; got %d; want %d", mp.ph.MinTimestamp, 100)
+	}
+}
+
+func TestNeedRetentionFiltersMerge(t *testing.T) {
+	var metricIDs uint64set.Set
+	metricIDs.Add(123)
+	rf := &retentionFilter{
+		RetentionFilter: RetentionFilter{
+			RetentionMsecs: 7 * msecPerDay,
+		},
+	}
+	rf.metricIDs.Store(&metricIDs)
+	rfs := []*retentionFilter{rf}
+
+	f := func(minTimestamp, maxTimestamp, lastMerge, currentTimestamp int64, resultExpected bool) {
+		t.Helper()
+		tr := &TimeRange{
+			MinTimestamp: minTimestamp,
+			MaxTimestamp: maxTimestamp,
+		}
+		result := needRetentionFiltersMerge(tr, rfs, lastMerge, currentTimestamp)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for tr=%s, lastMerge=%d, currentTimestamp=%d; got %v; want %v",
+				tr, lastMerge, currentTimestamp, result, resultExpected)
+		}
+	}
+	const day = msecPerDay
+
+	// The partition contains only data within the filter retention
+	f(10*day, 40*day, 15*day, 16*day, false)
+
+	// The filter deadline is inside the partition
+	f(10*day, 40*day, 20*day, 21*day, true)
+
+	// The filter deadline passed the partition end since the last merge
+	f(10*day, 40*day, 46*day, 48*day, true)
+
+	// The partition has been already cleaned up during the previous merge
+	f(10*day, 40*day, 48*day, 49*day, false)
+
+	// The filter is added to old data, which wasn't merged since the start
+	f(10*day, 40*day, 0, 100*day, true)
+
+	// The storage is restarted after long downtime, so the last merge is unknown
+	f(10*day, 40*day, 0, 48*day, true)
+
+	// The partition wasn't merged since the start, but it contains only data within the filter retention
+	f(100*day, 130*day, 0, 105*day, false)
+
+	// Empty filters are ignored
+	rf.metricIDs.Store(&uint64set.Set{})
+	f(10*day, 40*day, 20*day, 21*day, false)
+}
+
+func TestStorageSetRetentionFilters(t *testing.T) {
+	path := "TestStorageSetRetentionFilters"
+	s, err := OpenStorage(path, 31*msecPerDay, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	defer func() {
+		s.MustClose()
+		fs.MustRemoveAll(path)
+	}()
+
+	var mrs []MetricRow
+	timestamp := timestampFromTime(time.Now())
+	for i := 0; i < 10; i++ {
+		env := "dev"
+		if i%2 == 0 {
+			env = "prod"
+		}
+		var mn MetricName
+		mn.MetricGroup = []byte("metric")
+		mn.AddTag("env", env)
+		mn.AddTag("instance", fmt.Sprintf("host-%d", i))
+		mn.sortTags()
+		mrs = append(mrs, MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     timestamp,
+			Value:         float64(i),
+		})
+	}
+	if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+		t.Fatalf("unexpected error when adding rows: %s", err)
+	}
+	s.DebugFlush()
+
+	newRetentionFilter := func(filter string, retentionMsecs int64) RetentionFilter {
+		t.Helper()
+		tfs := NewTagFilters()
+		if err := tfs.Add([]byte("env"), []byte(filter), false, false); err != nil {
+			t.Fatalf("cannot add tag filter: %s", err)
+		}
+		return RetentionFilter{
+			Filter:         fmt.Sprintf(`{env=%q}`, filter),
+			TagFilters:     tfs,
+			RetentionMsecs: retentionMsecs,
+		}
+	}
+
+	// Invalid retention
+	for _, retentionMsecs := range []int64{0, -1, 32 * msecPerDay} {
+		if err := s.SetRetentionFilters([]RetentionFilter{newRetentionFilter("dev", retentionMsecs)}); err == nil {
+			t.Fatalf("expecting non-nil error for retentionMsecs=%d", retentionMsecs)
+		}
+	}
+
+	rfs := []RetentionFilter{
+		newRetentionFilter("dev", 7*msecPerDay),
+		newRetentionFilter("staging", msecPerDay),
+	}
+	if err := s.SetRetentionFilters(rfs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if len(m.RetentionFilters) != 2 {
+		t.Fatalf("unexpected number of retention filter metrics; got %d; want %d", len(m.RetentionFilters), 2)
+	}
+	if n := m.RetentionFilters[0].SeriesCount; n != 5 {
+		t.Fatalf("unexpected number of series for %s; got %d; want %d", m.RetentionFilters[0].Filter, n, 5)
+	}
+	if n := m.RetentionFilters[1].SeriesCount; n != 0 {
+		t.Fatalf("unexpected number of series for %s; got %d; want %d", m.RetentionFilters[1].Filter, n, 0)
+	}
+}
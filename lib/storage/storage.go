@@ -60,6 +60,11 @@ type Storage struct {
 	hourlySeriesLimitRowsDropped uint64
 	dailySeriesLimitRowsDropped  uint64
 
+	exemplarsAdded   uint64
+	exemplarsIgnored uint64
+
+	metricMetadataDropped uint64
+
 	path           string
 	cachePath      string
 	retentionMsecs int64
@@ -87,6 +92,12 @@ type Storage struct {
 	// dateMetricIDCache is (Date, MetricID) cache.
 	dateMetricIDCache *dateMetricIDCache
 
+	// exemplars contains the most recently added exemplars.
+	exemplars *exemplarStorage
+
+	// metricMetadata contains metric metadata such as TYPE, HELP and UNIT per each metric family name.
+	metricMetadata *metricMetadataStorage
+
 	// Fast cache for MetricID values occurred during the current hour.
 	currHourMetricIDs atomic.Value
 
@@ -121,6 +132,7 @@ type Storage struct {
 	currHourMetricIDsUpdaterWG sync.WaitGroup
 	nextDayMetricIDsUpdaterWG  sync.WaitGroup
 	retentionWatcherWG         sync.WaitGroup
+	retentionFiltersWatcherWG  sync.WaitGroup
 	freeDiskSpaceWatcherWG     sync.WaitGroup
 
 	// The snapshotLock prevents from concurrent creation of snapshots,
@@ -139,6 +151,9 @@ type Storage struct {
 	deletedMetricIDs           atomic.Value
 	deletedMetricIDsUpdateLock sync.Mutex
 
+	// retentionFilters contains []*retentionFilter set via SetRetentionFilters.
+	retentionFilters atomic.Value
+
 	isReadOnly uint32
 }
 
@@ -210,6 +225,7 @@ func OpenStorage(path string, retentionMsecs int64, maxHourlySeries, maxDailySer
 	s.metricIDCache = s.mustLoadCache("MetricID->TSID", "metricID_tsid", mem/16)
 	s.metricNameCache = s.mustLoadCache("MetricID->MetricName", "metricID_metricName", mem/10)
 	s.dateMetricIDCache = newDateMetricIDCache()
+	s.exemplars = s.mustLoadExemplars()
 
 	hour := fasttime.UnixHour()
 	hmCurr := s.mustLoadHourMetricIDs(hour, "curr_hour_metric_ids")
@@ -232,6 +248,7 @@ func OpenStorage(path string, retentionMsecs int64, maxHourlySeries, maxDailySer
 		return nil, fmt.Errorf("cannot create %q: %w", metadataDir, err)
 	}
 	s.minTimestampForCompositeIndex = mustGetMinTimestampForCompositeIndex(metadataDir, isEmptyDB)
+	s.metricMetadata = s.mustLoadMetricMetadata(metadataDir)
 
 	// Load indexdb
 	idbPath := path + "/indexdb"
@@ -260,7 +277,8 @@ func OpenStorage(path string, retentionMsecs int64, maxHourlySeries, maxDailySer
 
 	// Load data
 	tablePath := path + "/data"
-	tb, err := openTable(tablePath, s.getDeletedMetricIDs, retentionMsecs, &s.isReadOnly)
+	s.retentionFilters.Store([]*retentionFilter(nil))
+	tb, err := openTable(tablePath, s.getDeletedMetricIDs, s.getRetentionFilters, retentionMsecs, &s.isReadOnly)
 	if err != nil {
 		s.idb().MustClose()
 		return nil, fmt.Errorf("cannot open table at %q: %w", tablePath, err)
@@ -270,6 +288,7 @@ func OpenStorage(path string, retentionMsecs int64, maxHourlySeries, maxDailySer
 	s.startCurrHourMetricIDsUpdater()
 	s.startNextDayMetricIDsUpdater()
 	s.startRetentionWatcher()
+	s.startRetentionFiltersWatcher()
 	s.startFreeDiskSpaceWatcher()
 
 	return s, nil
@@ -472,6 +491,13 @@ type Metrics struct {
 	HourlySeriesLimitRowsDropped uint64
 	DailySeriesLimitRowsDropped  uint64
 
+	ExemplarsAdded   uint64
+	ExemplarsIgnored uint64
+	ExemplarsCount   uint64
+
+	MetricMetadataDropped uint64
+	MetricMetadataCount   uint64
+
 	TimestampsBlocksMerged uint64
 	TimestampsBytesSaved   uint64
 
@@ -510,6 +536,8 @@ type Metrics struct {
 	PrefetchedMetricIDsSize      uint64
 	PrefetchedMetricIDsSizeBytes uint64
 
+	RetentionFilters []RetentionFilterMetrics
+
 	IndexDBMetrics IndexDBMetrics
 	TableMetrics   TableMetrics
 }
@@ -547,6 +575,13 @@ func (s *Storage) UpdateMetrics(m *Metrics) {
 	m.HourlySeriesLimitRowsDropped += atomic.LoadUint64(&s.hourlySeriesLimitRowsDropped)
 	m.DailySeriesLimitRowsDropped += atomic.LoadUint64(&s.dailySeriesLimitRowsDropped)
 
+	m.ExemplarsAdded += atomic.LoadUint64(&s.exemplarsAdded)
+	m.ExemplarsIgnored += atomic.LoadUint64(&s.exemplarsIgnored)
+	m.ExemplarsCount += uint64(s.exemplars.Len())
+
+	m.MetricMetadataDropped += atomic.LoadUint64(&s.metricMetadataDropped)
+	m.MetricMetadataCount += uint64(s.metricMetadata.Len())
+
 	m.TimestampsBlocksMerged = atomic.LoadUint64(&timestampsBlocksMerged)
 	m.TimestampsBytesSaved = atomic.LoadUint64(&timestampsBytesSaved)
 
@@ -600,6 +635,8 @@ func (s *Storage) UpdateMetrics(m *Metrics) {
 	m.PrefetchedMetricIDsSize += uint64(prefetchedMetricIDs.Len())
 	m.PrefetchedMetricIDsSizeBytes += uint64(prefetchedMetricIDs.SizeBytes())
 
+	s.updateRetentionFiltersMetrics(m)
+
 	s.idb().UpdateMetrics(&m.IndexDBMetrics)
 	s.tb.UpdateMetrics(&m.TableMetrics)
 }
@@ -792,6 +829,7 @@ func (s *Storage) MustClose() {
 
 	s.freeDiskSpaceWatcherWG.Wait()
 	s.retentionWatcherWG.Wait()
+	s.retentionFiltersWatcherWG.Wait()
 	s.currHourMetricIDsUpdaterWG.Wait()
 	s.nextDayMetricIDsUpdaterWG.Wait()
 
@@ -814,6 +852,9 @@ func (s *Storage) MustClose() {
 	nextDayMetricIDs := s.nextDayMetricIDs.Load().(*byDateMetricIDEntry)
 	s.mustSaveNextDayMetricIDs(nextDayMetricIDs)
 
+	s.mustSaveExemplars()
+	s.mustSaveMetricMetadata(s.path + "/metadata")
+
 	// Release lock file.
 	if err := s.flockF.Close(); err != nil {
 		logger.Panicf("FATAL: cannot close lock file %q: %s", s.flockF.Name(), err)
@@ -182,7 +182,7 @@ func testTableSearchEx(t *testing.T, trData, trSearch TimeRange, partitionsCount
 
 	// Create a table from rowss and test search on it.
 	var isReadOnly uint32
-	tb, err := openTable("./test-table", nilGetDeletedMetricIDs, maxRetentionMsecs, &isReadOnly)
+	tb, err := openTable("./test-table", nilGetDeletedMetricIDs, nilGetRetentionFilters, maxRetentionMsecs, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot create table: %s", err)
 	}
@@ -203,7 +203,7 @@ func testTableSearchEx(t *testing.T, trData, trSearch TimeRange, partitionsCount
 	tb.MustClose()
 
 	// Open the created table and test search on it.
-	tb, err = openTable("./test-table", nilGetDeletedMetricIDs, maxRetentionMsecs, &isReadOnly)
+	tb, err = openTable("./test-table", nilGetDeletedMetricIDs, nilGetRetentionFilters, maxRetentionMsecs, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open table: %s", err)
 	}
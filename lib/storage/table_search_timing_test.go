@@ -49,7 +49,7 @@ func openBenchTable(b *testing.B, startTimestamp int64, rowsPerInsert, rowsCount
 		createdBenchTables[path] = true
 	}
 	var isReadOnly uint32
-	tb, err := openTable(path, nilGetDeletedMetricIDs, maxRetentionMsecs, &isReadOnly)
+	tb, err := openTable(path, nilGetDeletedMetricIDs, nilGetRetentionFilters, maxRetentionMsecs, &isReadOnly)
 	if err != nil {
 		b.Fatalf("cnanot open table %q: %s", path, err)
 	}
@@ -73,7 +73,7 @@ func createBenchTable(b *testing.B, path string, startTimestamp int64, rowsPerIn
 	b.Helper()
 
 	var isReadOnly uint32
-	tb, err := openTable(path, nilGetDeletedMetricIDs, maxRetentionMsecs, &isReadOnly)
+	tb, err := openTable(path, nilGetDeletedMetricIDs, nilGetRetentionFilters, maxRetentionMsecs, &isReadOnly)
 	if err != nil {
 		b.Fatalf("cannot open table %q: %s", path, err)
 	}
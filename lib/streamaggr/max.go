@@ -0,0 +1,73 @@
+package streamaggr
+
+import (
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+)
+
+// maxAggrState calculates output=max, e.g. the maximum sample value.
+type maxAggrState struct {
+	m sync.Map
+}
+
+type maxStateValue struct {
+	mu      sync.Mutex
+	max     float64
+	deleted bool
+}
+
+func newMaxAggrState() *maxAggrState {
+	return &maxAggrState{}
+}
+
+func (as *maxAggrState) pushSample(inputKey, outputKey string, value float64) {
+again:
+	v, ok := as.m.Load(outputKey)
+	if !ok {
+		// The entry is missing in the map. Try creating it.
+		v = &maxStateValue{
+			max: value,
+		}
+		vNew, loaded := as.m.LoadOrStore(outputKey, v)
+		if !loaded {
+			// The new entry has been successfully created.
+			return
+		}
+		// Use the entry created by a concurrent goroutine.
+		v = vNew
+	}
+	sv := v.(*maxStateValue)
+	sv.mu.Lock()
+	deleted := sv.deleted
+	if !deleted {
+		if value > sv.max {
+			sv.max = value
+		}
+	}
+	sv.mu.Unlock()
+	if deleted {
+		// The entry has been deleted by the concurrent call to appendSeriesForFlush
+		// Try obtaining and updating the entry again.
+		goto again
+	}
+}
+
+func (as *maxAggrState) appendSeriesForFlush(ctx *flushCtx) {
+	currentTimeMsec := int64(fasttime.UnixTimestamp()) * 1000
+	m := &as.m
+	m.Range(func(k, v interface{}) bool {
+		// Atomically delete the entry from the map, so new entry is created for the next flush.
+		m.Delete(k)
+
+		sv := v.(*maxStateValue)
+		sv.mu.Lock()
+		value := sv.max
+		// Mark the entry as deleted, so it won't be updated anymore by concurrent pushSample() calls.
+		sv.deleted = true
+		sv.mu.Unlock()
+		key := k.(string)
+		ctx.appendSeries(key, "max", currentTimeMsec, value)
+		return true
+	})
+}
@@ -0,0 +1,507 @@
+package streamaggr
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
+)
+
+func TestAggregatorsFailure(t *testing.T) {
+	f := func(config string) {
+		t.Helper()
+		pushFunc := func(tss []prompbmarshal.TimeSeries) {
+			panic(fmt.Errorf("pushFunc shouldn't be called"))
+		}
+		a, err := NewAggregatorsFromData([]byte(config), pushFunc)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if a != nil {
+			t.Fatalf("expecting nil a")
+		}
+	}
+
+	// Invalid config
+	f(`foobar`)
+
+	// Unknown option
+	f(`
+- interval: 1m
+  outputs: [total]
+  foobar: baz
+`)
+
+	// missing interval
+	f(`
+- outputs: [total]
+`)
+
+	// missing outputs
+	f(`
+- interval: 1m
+`)
+
+	// Invalid output
+	f(`
+- interval: 1m
+  outputs: [foobar]
+`)
+
+	// Negative interval
+	f(`- interval: -5m`)
+	// Too small interval
+	f(`- interval: 10ms`)
+
+	// bad quantiles()
+	f(`
+- interval: 1m
+  outputs: ["quantiles(1.5)"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["quantiles()"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["quantiles(0.5"]
+`)
+
+	// by and without at the same time
+	f(`
+- interval: 1m
+  outputs: [total]
+  by: [job]
+  without: [instance]
+`)
+
+	// Invalid input_relabel_configs
+	f(`
+- interval: 1m
+  outputs: [total]
+  input_relabel_configs:
+  - foo: bar
+`)
+	f(`
+- interval: 1m
+  outputs: [total]
+  input_relabel_configs:
+  - action: replace
+`)
+
+	// Invalid output_relabel_configs
+	f(`
+- interval: 1m
+  outputs: [total]
+  output_relabel_configs:
+  - foo: bar
+`)
+	f(`
+- interval: 1m
+  outputs: [total]
+  output_relabel_configs:
+  - action: replace
+`)
+
+	// Both by and without are non-empty
+	f(`
+- interval: 1m
+  outputs: [total]
+  by: [foo]
+  without: [bar]
+`)
+
+	// Invalid match expression
+	f(`
+- interval: 1m
+  outputs: [total]
+  match: ["foo"]
+`)
+	f(`
+- interval: 1m
+  outputs: [total]
+  match: "foo{"
+`)
+}
+
+func TestAggregatorsSuccess(t *testing.T) {
+	f := func(config, inputMetrics, outputMetricsExpected, matchIdxsStrExpected string) {
+		t.Helper()
+
+		// Initialize Aggregators
+		var tssOutput []prompbmarshal.TimeSeries
+		var tssOutputLock sync.Mutex
+		pushFunc := func(tss []prompbmarshal.TimeSeries) {
+			tssOutputLock.Lock()
+			for _, ts := range tss {
+				labelsCopy := append([]prompbmarshal.Label{}, ts.Labels...)
+				samplesCopy := append([]prompbmarshal.Sample{}, ts.Samples...)
+				tssOutput = append(tssOutput, prompbmarshal.TimeSeries{
+					Labels:  labelsCopy,
+					Samples: samplesCopy,
+				})
+			}
+			tssOutputLock.Unlock()
+		}
+		a, err := NewAggregatorsFromData([]byte(config), pushFunc)
+		if err != nil {
+			t.Fatalf("cannot initialize aggregators: %s", err)
+		}
+
+		// Push the inputMetrics to Aggregators
+		tssInput := mustParsePromMetrics(inputMetrics)
+		matchIdxs := make([]byte, len(tssInput))
+		a.Push(tssInput, matchIdxs)
+
+		// Flush the aggregated data and stop the aggregators.
+		if a != nil {
+			for _, ag := range a.as {
+				ag.flush()
+			}
+		}
+		a.MustStop()
+
+		// Verify matchIdxs equals to matchIdxsExpected
+		matchIdxsStr := ""
+		for _, v := range matchIdxs {
+			matchIdxsStr += strconv.Itoa(int(v))
+		}
+		if matchIdxsStr != matchIdxsStrExpected {
+			t.Fatalf("unexpected matchIdxs;\ngot\n%s\nwant\n%s", matchIdxsStr, matchIdxsStrExpected)
+		}
+
+		// Verify the tssOutput contains the expected metrics
+		tsStrings := make([]string, len(tssOutput))
+		for i, ts := range tssOutput {
+			tsStrings[i] = timeSeriesToString(ts)
+		}
+		sort.Strings(tsStrings)
+		outputMetrics := strings.Join(tsStrings, "")
+		if outputMetrics != outputMetricsExpected {
+			t.Fatalf("unexpected output metrics;\ngot\n%s\nwant\n%s", outputMetrics, outputMetricsExpected)
+		}
+	}
+
+	// Empty config
+	f(``, ``, ``, "")
+	f(``, `foo{bar="baz"} 1`, ``, "0")
+	f(``, "foo 1\nbaz 2", ``, "00")
+
+	// Empty by list - aggregate only by time
+	f(`
+- interval: 1m
+  outputs: [count_samples, sum_samples, count_series, last]
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="456",de="fg"} 8
+`, `bar:1m_count_samples 1
+bar:1m_count_series 1
+bar:1m_last 5
+bar:1m_sum_samples 5
+foo:1m_count_samples{abc="123"} 2
+foo:1m_count_samples{abc="456",de="fg"} 1
+foo:1m_count_series{abc="123"} 1
+foo:1m_count_series{abc="456",de="fg"} 1
+foo:1m_last{abc="123"} 8.5
+foo:1m_last{abc="456",de="fg"} 8
+foo:1m_sum_samples{abc="123"} 12.5
+foo:1m_sum_samples{abc="456",de="fg"} 8
+`, "1111")
+
+	// Special case: __name__ in `by` list - this is the same as empty `by` list
+	f(`
+- interval: 1m
+  by: [__name__]
+  outputs: [count_samples, sum_samples, count_series]
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="456",de="fg"} 8
+`, `bar:1m_count_samples 1
+bar:1m_count_series 1
+bar:1m_sum_samples 5
+foo:1m_count_samples 3
+foo:1m_count_series 2
+foo:1m_sum_samples 20.5
+`, "1111")
+
+	// Non-empty `by` list with non-existing labels
+	f(`
+- interval: 1m
+  by: [foo, bar]
+  outputs: [count_samples, sum_samples, count_series]
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="456",de="fg"} 8
+`, `bar:1m_by_bar_foo_count_samples 1
+bar:1m_by_bar_foo_count_series 1
+bar:1m_by_bar_foo_sum_samples 5
+foo:1m_by_bar_foo_count_samples 3
+foo:1m_by_bar_foo_count_series 2
+foo:1m_by_bar_foo_sum_samples 20.5
+`, "1111")
+
+	// Non-empty `by` list with existing labels
+	f(`
+- interval: 1m
+  by: [abc]
+  outputs: [count_samples, sum_samples, count_series]
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="456",de="fg"} 8
+`, `bar:1m_by_abc_count_samples 1
+bar:1m_by_abc_count_series 1
+bar:1m_by_abc_sum_samples 5
+foo:1m_by_abc_count_samples{abc="123"} 2
+foo:1m_by_abc_count_samples{abc="456"} 1
+foo:1m_by_abc_count_series{abc="123"} 1
+foo:1m_by_abc_count_series{abc="456"} 1
+foo:1m_by_abc_sum_samples{abc="123"} 12.5
+foo:1m_by_abc_sum_samples{abc="456"} 8
+`, "1111")
+
+	// Non-empty `without` list with non-existing labels
+	f(`
+- interval: 1m
+  without: [foo]
+  outputs: [count_samples, sum_samples, count_series]
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="456",de="fg"} 8
+`, `bar:1m_without_foo_count_samples 1
+bar:1m_without_foo_count_series 1
+bar:1m_without_foo_sum_samples 5
+foo:1m_without_foo_count_samples{abc="123"} 2
+foo:1m_without_foo_count_samples{abc="456",de="fg"} 1
+foo:1m_without_foo_count_series{abc="123"} 1
+foo:1m_without_foo_count_series{abc="456",de="fg"} 1
+foo:1m_without_foo_sum_samples{abc="123"} 12.5
+foo:1m_without_foo_sum_samples{abc="456",de="fg"} 8
+`, "1111")
+
+	// Non-empty `without` list with existing labels
+	f(`
+- interval: 1m
+  without: [abc]
+  outputs: [count_samples, sum_samples, count_series]
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="456",de="fg"} 8
+`, `bar:1m_without_abc_count_samples 1
+bar:1m_without_abc_count_series 1
+bar:1m_without_abc_sum_samples 5
+foo:1m_without_abc_count_samples 2
+foo:1m_without_abc_count_samples{de="fg"} 1
+foo:1m_without_abc_count_series 1
+foo:1m_without_abc_count_series{de="fg"} 1
+foo:1m_without_abc_sum_samples 12.5
+foo:1m_without_abc_sum_samples{de="fg"} 8
+`, "1111")
+
+	// Special case: __name__ in `without` list
+	f(`
+- interval: 1m
+  without: [__name__]
+  outputs: [count_samples, sum_samples, count_series]
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="456",de="fg"} 8
+`, `:1m_count_samples 1
+:1m_count_samples{abc="123"} 2
+:1m_count_samples{abc="456",de="fg"} 1
+:1m_count_series 1
+:1m_count_series{abc="123"} 1
+:1m_count_series{abc="456",de="fg"} 1
+:1m_sum_samples 5
+:1m_sum_samples{abc="123"} 12.5
+:1m_sum_samples{abc="456",de="fg"} 8
+`, "1111")
+
+	// Multiple aggregations and match filters
+	f(`
+- interval: 1m
+  match: 'foo{abc="123"}'
+  outputs: [count_samples]
+- interval: 1m
+  match: bar
+  outputs: [sum_samples]
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="456",de="fg"} 8
+`, `bar:1m_sum_samples 5
+foo:1m_count_samples{abc="123"} 2
+`, "1110")
+
+	// Input and output relabeling
+	f(`
+- interval: 1m
+  without: [abc]
+  outputs: [count_samples, sum_samples, count_series]
+  input_relabel_configs:
+  - source_labels: [de]
+    target_label: de
+    regex: '(.+)'
+    replacement: 'x-$1'
+  output_relabel_configs:
+  - action: drop
+    source_labels: [__name__]
+    regex: '.+count_series'
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="456",de="fg"} 8
+`, `bar:1m_without_abc_count_samples 1
+bar:1m_without_abc_sum_samples 5
+foo:1m_without_abc_count_samples 2
+foo:1m_without_abc_count_samples{de="x-fg"} 1
+foo:1m_without_abc_sum_samples 12.5
+foo:1m_without_abc_sum_samples{de="x-fg"} 8
+`, "1111")
+
+	// total and increase outputs
+	f(`
+- interval: 1m
+  without: [de]
+  outputs: [total, increase]
+`, `
+foo{abc="123"} 4
+bar 5
+foo{abc="123"} 8.5
+foo{abc="123"} 2
+foo{abc="456",de="fg"} 8
+foo{abc="456",de="fg"} 10
+`, `bar:1m_without_de_increase 0
+bar:1m_without_de_total 0
+foo:1m_without_de_increase{abc="123"} 6.5
+foo:1m_without_de_increase{abc="456"} 2
+foo:1m_without_de_total{abc="123"} 6.5
+foo:1m_without_de_total{abc="456"} 2
+`, "111111")
+
+	// min, max, avg, stddev and stdvar outputs
+	f(`
+- interval: 1m
+  outputs: [min, max, avg, stddev, stdvar]
+`, `
+foo 2
+foo 4
+foo 4
+foo 4
+foo 5
+foo 5
+foo 7
+foo 9
+`, `foo:1m_avg 5
+foo:1m_max 9
+foo:1m_min 2
+foo:1m_stddev 2
+foo:1m_stdvar 4
+`, "11111111")
+
+	// quantiles output
+	f(`
+- interval: 1m
+  outputs: ["quantiles(0, 0.5, 1)"]
+`, `
+foo 4
+foo 2
+foo 8
+`, `foo:1m_quantiles{quantile="0"} 2
+foo:1m_quantiles{quantile="0.5"} 4
+foo:1m_quantiles{quantile="1"} 8
+`, "111")
+
+	// histogram_bucket output
+	f(`
+- interval: 1m
+  without: [abc]
+  outputs: [histogram_bucket]
+`, `
+foo{abc="1"} 4
+foo{abc="2"} 4.1
+foo{abc="3"} 20
+`, `foo:1m_without_abc_histogram_bucket{vmrange="1.896e+01...2.154e+01"} 1
+foo:1m_without_abc_histogram_bucket{vmrange="3.594e+00...4.084e+00"} 1
+foo:1m_without_abc_histogram_bucket{vmrange="4.084e+00...4.642e+00"} 1
+`, "111")
+}
+
+func timeSeriesToString(ts prompbmarshal.TimeSeries) string {
+	labelsString := labelsToString(ts.Labels)
+	if len(ts.Samples) != 1 {
+		panic(fmt.Errorf("unexpected number of samples for %s: %d; want 1", labelsString, len(ts.Samples)))
+	}
+	return fmt.Sprintf("%s %v\n", labelsString, ts.Samples[0].Value)
+}
+
+func labelsToString(labels []prompbmarshal.Label) string {
+	labelsCopy := append([]prompbmarshal.Label{}, labels...)
+	promrelabel.SortLabels(labelsCopy)
+	var name string
+	var a []string
+	for _, label := range labelsCopy {
+		if label.Name == "__name__" {
+			name = label.Value
+			continue
+		}
+		a = append(a, fmt.Sprintf("%s=%q", label.Name, label.Value))
+	}
+	if len(a) == 0 {
+		return name
+	}
+	return name + "{" + strings.Join(a, ",") + "}"
+}
+
+func mustParsePromMetrics(s string) []prompbmarshal.TimeSeries {
+	var rows prometheus.Rows
+	errLogger := func(s string) {
+		panic(fmt.Errorf("unexpected error when parsing Prometheus metrics: %s", s))
+	}
+	rows.UnmarshalWithErrLogger(s, errLogger)
+	var tss []prompbmarshal.TimeSeries
+	samples := make([]prompbmarshal.Sample, 0, len(rows.Rows))
+	for _, row := range rows.Rows {
+		labels := []prompbmarshal.Label{{
+			Name:  "__name__",
+			Value: row.Metric,
+		}}
+		for _, tag := range row.Tags {
+			labels = append(labels, prompbmarshal.Label{
+				Name:  tag.Key,
+				Value: tag.Value,
+			})
+		}
+		samples = append(samples, prompbmarshal.Sample{
+			Value:     row.Value,
+			Timestamp: row.Timestamp,
+		})
+		ts := prompbmarshal.TimeSeries{
+			Labels:  labels,
+			Samples: samples[len(samples)-1:],
+		}
+		tss = append(tss, ts)
+	}
+	return tss
+}
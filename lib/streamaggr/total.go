@@ -0,0 +1,156 @@
+package streamaggr
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+)
+
+// totalAggrState calculates output=total, e.g. the summary counter over input counters.
+type totalAggrState struct {
+	m sync.Map
+
+	suffix string
+
+	// resetTotalOnFlush is set for output=increase, since it must be calculated per each interval.
+	resetTotalOnFlush bool
+
+	// Input series which didn't receive new samples during stalenessSecs are dropped from the state.
+	stalenessSecs uint64
+}
+
+type totalStateValue struct {
+	mu             sync.Mutex
+	lastValues     map[string]*lastValueState
+	total          float64
+	deleteDeadline uint64
+	deleted        bool
+}
+
+type lastValueState struct {
+	value          float64
+	deleteDeadline uint64
+}
+
+func newTotalAggrState(interval time.Duration) *totalAggrState {
+	return &totalAggrState{
+		suffix:        "total",
+		stalenessSecs: getStalenessSecs(interval),
+	}
+}
+
+func newIncreaseAggrState(interval time.Duration) *totalAggrState {
+	return &totalAggrState{
+		suffix:            "increase",
+		resetTotalOnFlush: true,
+		stalenessSecs:     getStalenessSecs(interval),
+	}
+}
+
+func (as *totalAggrState) pushSample(inputKey, outputKey string, value float64) {
+	currentTime := fasttime.UnixTimestamp()
+	deleteDeadline := currentTime + as.stalenessSecs
+
+again:
+	v, ok := as.m.Load(outputKey)
+	if !ok {
+		// The entry is missing in the map. Try creating it.
+		v = &totalStateValue{
+			lastValues: make(map[string]*lastValueState),
+		}
+		vNew, loaded := as.m.LoadOrStore(outputKey, v)
+		if loaded {
+			// Use the entry created by a concurrent goroutine.
+			v = vNew
+		}
+	}
+	sv := v.(*totalStateValue)
+	sv.mu.Lock()
+	deleted := sv.deleted
+	if !deleted {
+		lv, ok := sv.lastValues[inputKey]
+		if !ok {
+			lv = &lastValueState{}
+			sv.lastValues[inputKey] = lv
+		} else {
+			if value >= lv.value {
+				sv.total += value - lv.value
+			} else {
+				// counter reset
+				sv.total += value
+			}
+		}
+		lv.value = value
+		lv.deleteDeadline = deleteDeadline
+		sv.deleteDeadline = deleteDeadline
+	}
+	sv.mu.Unlock()
+	if deleted {
+		// The entry has been deleted by the concurrent call to appendSeriesForFlush
+		// Try obtaining and updating the entry again.
+		goto again
+	}
+}
+
+func (as *totalAggrState) removeOldEntries(currentTime uint64) {
+	m := &as.m
+	m.Range(func(k, v interface{}) bool {
+		sv := v.(*totalStateValue)
+
+		sv.mu.Lock()
+		deleted := currentTime > sv.deleteDeadline
+		if deleted {
+			// Mark the current entry as deleted
+			sv.deleted = deleted
+		} else {
+			// Delete outdated entries in sv.lastValues
+			lvs := sv.lastValues
+			for k1, v1 := range lvs {
+				if currentTime > v1.deleteDeadline {
+					delete(lvs, k1)
+				}
+			}
+		}
+		sv.mu.Unlock()
+
+		if deleted {
+			m.Delete(k)
+		}
+		return true
+	})
+}
+
+func (as *totalAggrState) appendSeriesForFlush(ctx *flushCtx) {
+	currentTime := fasttime.UnixTimestamp()
+	currentTimeMsec := int64(currentTime) * 1000
+
+	as.removeOldEntries(currentTime)
+
+	m := &as.m
+	m.Range(func(k, v interface{}) bool {
+		sv := v.(*totalStateValue)
+		sv.mu.Lock()
+		total := sv.total
+		if as.resetTotalOnFlush {
+			sv.total = 0
+		} else if math.Abs(sv.total) >= (1 << 53) {
+			// It is time to reset the entry, since it starts losing float64 precision
+			sv.total = 0
+		}
+		deleted := sv.deleted
+		sv.mu.Unlock()
+		if !deleted {
+			key := k.(string)
+			ctx.appendSeries(key, as.suffix, currentTimeMsec, total)
+		}
+		return true
+	})
+}
+
+// getStalenessSecs returns the duration after which the input series without new samples
+// are removed from the state of the output with the given aggregation interval.
+func getStalenessSecs(interval time.Duration) uint64 {
+	return roundDurationToSecs(2 * interval)
+}